@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"statusframe/db"
+	"time"
+)
+
+// ImpersonationTTL limits how long an admin can stay signed in as another user.
+// After it the session falls back to the admin.
+const ImpersonationTTL = time.Hour
+
+// accountConn is used to check that session users haven't been disabled.
+// Sessions are trusted as-is until EnableAccountChecks is called.
+var accountConn *sql.DB
+
+// EnableAccountChecks makes AuthMiddleware reject sessions of disabled accounts
+func EnableAccountChecks(conn *sql.DB) {
+	accountConn = conn
+}
+
+// StartImpersonation switches the session to targetId while remembering the admin,
+// so StopImpersonation can switch back. The impersonated session is read-only.
+func StartImpersonation(w http.ResponseWriter, r *http.Request, targetId int, targetName string) error {
+	session, err := Store.Get(r, "auth-session")
+	if err != nil {
+		return err
+	}
+
+	// Starting again while impersonating keeps the original admin
+	if _, ok := session.Values["impersonatorId"].(int); !ok {
+		session.Values["impersonatorId"] = session.Values["userId"]
+		session.Values["impersonatorName"] = session.Values["user"]
+	}
+	session.Values["impersonationStartedAt"] = time.Now().Unix()
+	session.Values["userId"] = targetId
+	session.Values["user"] = targetName
+	delete(session.Values, "activeOrgId")
+	return session.Save(r, w)
+}
+
+// StopImpersonation restores the admin's session. It returns the admin and the user
+// they were impersonating, and ok is false if the session wasn't impersonating anyone.
+func StopImpersonation(w http.ResponseWriter, r *http.Request) (adminId, targetId int, ok bool, err error) {
+	session, err := Store.Get(r, "auth-session")
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	adminId, ok = session.Values["impersonatorId"].(int)
+	if !ok {
+		return 0, 0, false, nil
+	}
+	targetId, _ = session.Values["userId"].(int)
+	endImpersonation(session.Values)
+	return adminId, targetId, true, session.Save(r, w)
+}
+
+// endImpersonation puts the admin back into the session values
+func endImpersonation(values map[interface{}]interface{}) {
+	values["userId"] = values["impersonatorId"]
+	values["user"] = values["impersonatorName"]
+	delete(values, "impersonatorId")
+	delete(values, "impersonatorName")
+	delete(values, "impersonationStartedAt")
+	delete(values, "activeOrgId")
+}
+
+// checkSessionAccount applies impersonation and disabled-account rules to a session
+// request. It returns the request to continue with, or false if a response was written.
+// When an impersonation has expired the values are switched back to the admin.
+func checkSessionAccount(w http.ResponseWriter, r *http.Request, values map[interface{}]interface{}, save func() error) (*http.Request, bool) {
+	if adminId, ok := values["impersonatorId"].(int); ok {
+		startedAt, _ := values["impersonationStartedAt"].(int64)
+		if time.Since(time.Unix(startedAt, 0)) > ImpersonationTTL {
+			log.Printf("⏱️ Impersonation by admin %d expired", adminId)
+			endImpersonation(values)
+			if err := save(); err != nil {
+				log.Printf("Error saving session: %v", err)
+			}
+		} else {
+			// Support can look around but must not change anything on the user's behalf
+			if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions {
+				http.Error(w, "Forbidden - impersonation is read-only", http.StatusForbidden)
+				return nil, false
+			}
+			r = r.WithContext(context.WithValue(r.Context(), "impersonatorId", adminId))
+		}
+	}
+
+	if accountConn == nil {
+		return r, true
+	}
+
+	// The person actually at the keyboard is the one who must not be disabled
+	actorId, _ := values["userId"].(int)
+	if adminId, ok := values["impersonatorId"].(int); ok {
+		actorId = adminId
+	}
+
+	disabled, err := db.IsUserDisabled(accountConn, actorId)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error checking account %d: %v", actorId, err)
+		http.Error(w, "Authentication error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if disabled {
+		http.Error(w, "Forbidden - this account has been disabled", http.StatusForbidden)
+		return nil, false
+	}
+	return r, true
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"statusframe/db"
+	"strings"
+)
+
+// APIKeyPrefix starts every personal API key so they are easy to spot in logs and secret scanners
+const APIKeyPrefix = "sf_"
+
+// Auth methods stored in the request context under "authMethod"
+const (
+	AuthMethodSession = "session"
+	AuthMethodAPIKey  = "api_key"
+)
+
+// apiKeyConn is used to look up API keys. Keys are rejected until EnableAPIKeys is called.
+var apiKeyConn *sql.DB
+
+// EnableAPIKeys lets AuthMiddleware accept personal API keys stored in conn
+func EnableAPIKeys(conn *sql.DB) {
+	apiKeyConn = conn
+}
+
+// GenerateAPIKey creates a new random key. It returns the key to show the user once,
+// the prefix to display afterwards and the hash to store.
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+
+	key = APIKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	prefix = key[:len(APIKeyPrefix)+8]
+	return key, prefix, HashAPIKey(key), nil
+}
+
+// HashAPIKey returns the value stored for key. Keys are long random strings,
+// so a plain SHA-256 is enough and keeps lookups to a single indexed query.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// authenticateAPIKey resolves a Bearer key to its owner and adds them to the context
+func authenticateAPIKey(w http.ResponseWriter, r *http.Request, key string) (*http.Request, bool) {
+	if apiKeyConn == nil || !strings.HasPrefix(key, APIKeyPrefix) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	owner, err := db.GetAPIKeyOwner(apiKeyConn, HashAPIKey(key))
+	if err != nil {
+		log.Printf("Error looking up API key: %v", err)
+		http.Error(w, "Authentication error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if owner == nil {
+		http.Error(w, "Unauthorized - invalid or revoked API key", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	// Read-only keys can only be used for safe methods
+	if owner.Scope != "write" && r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions {
+		http.Error(w, "Forbidden - this API key is read-only", http.StatusForbidden)
+		return nil, false
+	}
+
+	if err := db.TouchAPIKey(apiKeyConn, owner.KeyID); err != nil {
+		log.Printf("⚠️ Error updating last use of API key %d: %v", owner.KeyID, err)
+	}
+
+	ctx := context.WithValue(r.Context(), "userId", owner.UserID)
+	ctx = context.WithValue(ctx, "user", owner.Username)
+	ctx = context.WithValue(ctx, "authMethod", AuthMethodAPIKey)
+	return r.WithContext(ctx), true
+}
+
+// RequireSession rejects requests authenticated with an API key. Use it after
+// AuthMiddleware on routes a leaked key must not reach, like managing keys.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if method, _ := r.Context().Value("authMethod").(string); method != AuthMethodSession {
+			http.Error(w, "Forbidden - sign in to use this endpoint", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,316 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"statusframe/backend/config"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Identity is a user as reported by a login method
+type Identity struct {
+	Provider      string
+	Subject       string // the provider's id for the user, stable across email changes
+	Email         string
+	EmailVerified bool
+	Name          string
+	AvatarURL     string
+}
+
+// oauthProvider is an OAuth 2.0 authorization code flow with its own way to look up the user
+type oauthProvider struct {
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	authURL      string
+	tokenURL     string
+	scopes       []string
+	authParams   url.Values // extra parameters for the authorization URL
+	identity     func(accessToken string) (*Identity, error)
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	Scope            string `json:"scope"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// providers holds the login providers that are configured, by name
+var providers = map[string]*oauthProvider{}
+
+// oauthClient calls the providers. They're outside our control, so don't wait forever.
+var oauthClient = &http.Client{Timeout: 10 * time.Second}
+
+// configureProviders registers every provider with credentials in cfg
+func configureProviders(cfg config.AuthConfig) {
+	providers = map[string]*oauthProvider{}
+
+	if cfg.GoogleEnabled() {
+		userInfoURL := cfg.GoogleUserInfoURL
+		providers["google"] = &oauthProvider{
+			clientID:     cfg.GoogleClientID,
+			clientSecret: cfg.GoogleClientSecret,
+			redirectURL:  cfg.GoogleRedirectURL,
+			authURL:      cfg.GoogleAuthURL,
+			tokenURL:     cfg.GoogleTokenURL,
+			scopes:       []string{"email", "profile"},
+			authParams:   url.Values{"access_type": {"offline"}},
+			identity: func(accessToken string) (*Identity, error) {
+				return googleIdentity(userInfoURL, accessToken)
+			},
+		}
+	}
+
+	if cfg.GitHubEnabled() {
+		apiURL := strings.TrimRight(cfg.GitHubAPIURL, "/")
+		providers["github"] = &oauthProvider{
+			clientID:     cfg.GitHubClientID,
+			clientSecret: cfg.GitHubClientSecret,
+			redirectURL:  cfg.GitHubRedirectURL,
+			authURL:      cfg.GitHubAuthURL,
+			tokenURL:     cfg.GitHubTokenURL,
+			scopes:       []string{"read:user", "user:email"},
+			identity: func(accessToken string) (*Identity, error) {
+				return githubIdentity(apiURL, accessToken)
+			},
+		}
+	}
+}
+
+// Providers lists the OAuth providers users can sign in with
+func Providers() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Generate a random state parameter for OAuth security
+func generateState() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(b), nil
+}
+
+// BeginOAuth redirects to the provider's consent screen. It returns false without
+// writing a response if the provider isn't configured.
+func BeginOAuth(w http.ResponseWriter, r *http.Request, name string) bool {
+	provider, ok := providers[name]
+	if !ok {
+		return false
+	}
+
+	state, err := generateState()
+	if err != nil {
+		http.Error(w, "Failed to generate state", http.StatusInternalServerError)
+		return true
+	}
+
+	// Store state in session for verification, along with the provider it is for
+	session, _ := Store.Get(r, "auth-session")
+	session.Values["oauth_state"] = state
+	session.Values["oauth_provider"] = name
+	session.Save(r, w)
+
+	// Build authorization URL
+	params := url.Values{}
+	params.Add("client_id", provider.clientID)
+	params.Add("redirect_uri", provider.redirectURL)
+	params.Add("scope", strings.Join(provider.scopes, " "))
+	params.Add("response_type", "code")
+	params.Add("state", state)
+	for key, values := range provider.authParams {
+		for _, value := range values {
+			params.Add(key, value)
+		}
+	}
+
+	http.Redirect(w, r, provider.authURL+"?"+params.Encode(), http.StatusTemporaryRedirect)
+	return true
+}
+
+// HandleOAuthCallback checks the state, exchanges the code and looks up who signed in
+func HandleOAuthCallback(w http.ResponseWriter, r *http.Request, name string) (*Identity, error) {
+	provider, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider %q", name)
+	}
+
+	// Verify state parameter
+	session, err := Store.Get(r, "auth-session")
+	if err != nil {
+		return nil, fmt.Errorf("session error: %v", err)
+	}
+
+	storedState, ok := session.Values["oauth_state"].(string)
+	storedProvider, _ := session.Values["oauth_provider"].(string)
+	if !ok || storedState != r.URL.Query().Get("state") || storedProvider != name {
+		return nil, fmt.Errorf("invalid state parameter")
+	}
+
+	// The state is single use
+	delete(session.Values, "oauth_state")
+	delete(session.Values, "oauth_provider")
+	session.Save(r, w)
+
+	// Get authorization code
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		return nil, fmt.Errorf("no authorization code received")
+	}
+
+	// Exchange code for token
+	token, err := provider.exchangeCode(code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code for token: %v", err)
+	}
+
+	// Get user info
+	identity, err := provider.identity(token.AccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user info: %v", err)
+	}
+	identity.Provider = name
+	return identity, nil
+}
+
+// exchangeCode exchanges an authorization code for an access token
+func (p *oauthProvider) exchangeCode(code string) (*tokenResponse, error) {
+	data := url.Values{}
+	data.Set("client_id", p.clientID)
+	data.Set("client_secret", p.clientSecret)
+	data.Set("code", code)
+	data.Set("grant_type", "authorization_code")
+	data.Set("redirect_uri", p.redirectURL)
+
+	req, err := http.NewRequest(http.MethodPost, p.tokenURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// GitHub answers with a query string unless asked for JSON
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := oauthClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("token exchange failed: %s", string(body))
+	}
+
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+	// GitHub reports errors with a 200 status
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("token exchange failed: %s %s", token.Error, token.ErrorDescription)
+	}
+
+	return &token, nil
+}
+
+// getJSON fetches a provider API URL with the user's access token and decodes it into v
+func getJSON(apiURL, accessToken string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := oauthClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("GET %s failed: %s", apiURL, string(body))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// googleIdentity fetches the user from Google's userinfo endpoint
+func googleIdentity(userInfoURL, accessToken string) (*Identity, error) {
+	var info struct {
+		ID            string `json:"id"`
+		Email         string `json:"email"`
+		VerifiedEmail bool   `json:"verified_email"`
+		Name          string `json:"name"`
+		Picture       string `json:"picture"`
+	}
+	if err := getJSON(userInfoURL, accessToken, &info); err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		Subject:       info.ID,
+		Email:         info.Email,
+		EmailVerified: info.VerifiedEmail,
+		Name:          info.Name,
+		AvatarURL:     info.Picture,
+	}, nil
+}
+
+// githubIdentity fetches the user and their primary verified email from the GitHub API.
+// The profile email is public and unverified, so it is only a fallback.
+func githubIdentity(apiURL, accessToken string) (*Identity, error) {
+	var user struct {
+		ID        int64  `json:"id"`
+		Login     string `json:"login"`
+		Name      string `json:"name"`
+		Email     string `json:"email"`
+		AvatarURL string `json:"avatar_url"`
+	}
+	if err := getJSON(apiURL+"/user", accessToken, &user); err != nil {
+		return nil, err
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(apiURL+"/user/emails", accessToken, &emails); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		Subject:   strconv.FormatInt(user.ID, 10),
+		Email:     user.Email,
+		Name:      user.Name,
+		AvatarURL: user.AvatarURL,
+	}
+	if identity.Name == "" {
+		identity.Name = user.Login
+	}
+	for _, email := range emails {
+		if email.Primary && email.Verified {
+			identity.Email = email.Email
+			identity.EmailVerified = true
+			break
+		}
+	}
+	return identity, nil
+}
@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"statusframe/backend/utils"
+	"statusframe/db"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// anonymousSessionTTL caps sessions nobody has signed in to, like the ones holding
+// OAuth state, so abandoned sign ins don't pile up for the full session lifetime
+const anonymousSessionTTL = time.Hour
+
+// DBStore keeps session values in the user_sessions table. The cookie only holds a
+// signed random token, so deleting the row signs the browser out for good.
+type DBStore struct {
+	conn    *sql.DB
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For is believed when
+	// recording the address a session was used from
+	TrustedProxies []string
+}
+
+// NewDBStore returns a store signing its cookies and values with keyPairs, like
+// sessions.NewCookieStore
+func NewDBStore(conn *sql.DB, options sessions.Options, keyPairs ...[]byte) *DBStore {
+	codecs := securecookie.CodecsFromPairs(keyPairs...)
+	for _, codec := range codecs {
+		// Values live in the database, so they aren't limited to the size of a cookie
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxLength(0)
+		}
+	}
+	return &DBStore{conn: conn, Codecs: codecs, Options: &options}
+}
+
+// UseDBSessions moves sessions from cookies to the database, keeping the cookie options.
+// Existing cookie sessions are signed out.
+func UseDBSessions(conn *sql.DB, secret string, trustedProxies []string) {
+	options := sessions.Options{Path: "/", MaxAge: 86400 * 30, HttpOnly: true, SameSite: http.SameSiteLaxMode}
+	if cookieStore, ok := Store.(*sessions.CookieStore); ok && cookieStore.Options != nil {
+		options = *cookieStore.Options
+	}
+	store := NewDBStore(conn, options, []byte(secret))
+	store.TrustedProxies = trustedProxies
+	Store = store
+}
+
+// Get returns the named session, cached for the rest of the request
+func (s *DBStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New loads the session named by the request's cookie, or starts an empty one when
+// there is no cookie or its session has expired or been revoked
+func (s *DBStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	options := *s.Options
+	session.Options = &options
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+
+	var token string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &token, s.Codecs...); err != nil {
+		// A cookie from another key or from the old cookie store, start over
+		return session, nil
+	}
+
+	stored, err := db.GetSession(s.conn, HashAPIKey(token))
+	if err != nil {
+		return session, err
+	}
+	if stored == nil {
+		return session, nil
+	}
+	if err := securecookie.DecodeMulti(name, stored.Data, &session.Values, s.Codecs...); err != nil {
+		log.Printf("⚠️ Error decoding session %d: %v", stored.ID, err)
+		return session, nil
+	}
+
+	if time.Since(stored.LastSeenAt) > time.Minute {
+		if err := db.TouchSession(s.conn, stored.TokenHash, utils.ClientIPString(r, s.TrustedProxies)); err != nil {
+			log.Printf("Error updating session %d: %v", stored.ID, err)
+		}
+	}
+
+	session.ID = token
+	session.IsNew = false
+	return session, nil
+}
+
+// Save stores the session and sets its cookie. A negative MaxAge deletes it.
+func (s *DBStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := db.DeleteSession(s.conn, HashAPIKey(session.ID)); err != nil {
+				return err
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	isNew := session.ID == ""
+	if isNew {
+		token, err := generateSessionToken()
+		if err != nil {
+			return err
+		}
+		session.ID = token
+	}
+
+	data, err := securecookie.EncodeMulti(session.Name(), session.Values, s.Codecs...)
+	if err != nil {
+		return err
+	}
+
+	stored := db.UserSession{
+		TokenHash: HashAPIKey(session.ID),
+		Data:      data,
+		UserAgent: r.UserAgent(),
+		IPAddress: utils.ClientIPString(r, s.TrustedProxies),
+		ExpiresAt: time.Now().Add(time.Duration(session.Options.MaxAge) * time.Second),
+	}
+	if userId, ok := sessionOwner(session.Values); ok {
+		stored.UserID = &userId
+	} else if limit := time.Now().Add(anonymousSessionTTL); stored.ExpiresAt.After(limit) {
+		stored.ExpiresAt = limit
+	}
+	if isNew {
+		if err := db.CreateSession(s.conn, stored); err != nil {
+			return err
+		}
+	} else {
+		updated, err := db.UpdateSession(s.conn, stored)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			// Revoked while this request was running, keep it signed out
+			http.SetCookie(w, sessions.NewCookie(session.Name(), "", &sessions.Options{Path: session.Options.Path, MaxAge: -1}))
+			return nil
+		}
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// RenewSession gives the session a new token when its user signs in, so a token
+// planted in the browser beforehand can't be used to ride on the new login.
+// The caller saves the session as usual.
+func RenewSession(session *sessions.Session) error {
+	store, ok := session.Store().(*DBStore)
+	if !ok || session.ID == "" {
+		return nil
+	}
+	if err := db.DeleteSession(store.conn, HashAPIKey(session.ID)); err != nil {
+		return err
+	}
+	session.ID = ""
+	return nil
+}
+
+// SessionTokenHash identifies the request's session in user_sessions. It is empty
+// when sessions aren't stored in the database or the request has none.
+func SessionTokenHash(r *http.Request) string {
+	session, err := Store.Get(r, "auth-session")
+	if err != nil || session.ID == "" {
+		return ""
+	}
+	if _, ok := session.Store().(*DBStore); !ok {
+		return ""
+	}
+	return HashAPIKey(session.ID)
+}
+
+// sessionOwner is who a session belongs to. An impersonating session belongs to the
+// admin, so it shows up in their device list and not the user's.
+func sessionOwner(values map[interface{}]interface{}) (int, bool) {
+	if adminId, ok := values["impersonatorId"].(int); ok {
+		return adminId, true
+	}
+	userId, ok := values["userId"].(int)
+	return userId, ok
+}
+
+func generateSessionToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
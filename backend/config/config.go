@@ -0,0 +1,678 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds every setting the server needs. It is loaded once at startup
+// and passed explicitly to the subsystems that need it.
+type Config struct {
+	Server   ServerConfig          `json:"server"`
+	Database DatabaseConfig        `json:"database"`
+	Worker   WorkerConfig          `json:"worker"`
+	Auth     AuthConfig            `json:"auth"`
+	Admin    AdminConfig           `json:"admin"`
+	Stripe   StripeConfig          `json:"stripe"`
+	AWS      AWSConfig             `json:"aws"`
+	SES      SESConfig             `json:"ses"`
+	SMTP     SMTPConfig            `json:"smtp"`
+	Slack    SlackConfig           `json:"slack"`
+	Discord  DiscordConfig         `json:"discord"`
+	Probe    ProbeConfig           `json:"probe"`
+	Public   PublicAPIConfig       `json:"public"`
+	Metrics  MetricsConfig         `json:"metrics"`
+	Plans    map[string]PlanConfig `json:"plans"`
+}
+
+type ServerConfig struct {
+	Addr           string   `json:"addr"`
+	PublicURL      string   `json:"public_url"`
+	AllowedOrigins []string `json:"allowed_origins"`
+
+	// TrustedProxies are the addresses or CIDR ranges of reverse proxies in front of
+	// the server, like Caddy. Requests from them are taken to come from the address
+	// they add to X-Forwarded-For.
+	TrustedProxies []string `json:"trusted_proxies"`
+}
+
+type DatabaseConfig struct {
+	URL      string `json:"url"`
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+	SSLMode  string `json:"sslmode"`
+
+	// AutoMigrate applies pending migrations at boot. When disabled the server
+	// refuses to start until `statusframe migrate up` has been run.
+	AutoMigrate bool `json:"auto_migrate"`
+}
+
+type WorkerConfig struct {
+	CheckInterval Duration `json:"check_interval"`
+}
+
+type AuthConfig struct {
+	GoogleClientID     string `json:"google_client_id"`
+	GoogleClientSecret string `json:"google_client_secret"`
+	GoogleRedirectURL  string `json:"google_redirect_url"`
+	SessionSecret      string `json:"session_secret"`
+	SecureCookies      bool   `json:"secure_cookies"`
+
+	GitHubClientID     string `json:"github_client_id"`
+	GitHubClientSecret string `json:"github_client_secret"`
+	GitHubRedirectURL  string `json:"github_redirect_url"`
+
+	// Provider endpoints default to the real ones. Point them at a local fake
+	// OAuth server to test the login flows.
+	GoogleAuthURL     string `json:"google_auth_url"`
+	GoogleTokenURL    string `json:"google_token_url"`
+	GoogleUserInfoURL string `json:"google_userinfo_url"`
+	GitHubAuthURL     string `json:"github_auth_url"`
+	GitHubTokenURL    string `json:"github_token_url"`
+	GitHubAPIURL      string `json:"github_api_url"`
+
+	// MagicLinks enables passwordless sign in with a link sent by email. Without
+	// SES the link is written to the server log, which is only useful locally.
+	MagicLinks   bool     `json:"magic_links"`
+	MagicLinkTTL Duration `json:"magic_link_ttl"`
+}
+
+type AdminConfig struct {
+	Emails []string `json:"emails"`
+}
+
+type StripeConfig struct {
+	SecretKey              string `json:"secret_key"`
+	PublishableKey         string `json:"publishable_key"`
+	WebhookSecret          string `json:"webhook_secret"`
+	ProMonthlyPriceID      string `json:"pro_monthly_price_id"`
+	ProYearlyPriceID       string `json:"pro_yearly_price_id"`
+	BusinessMonthlyPriceID string `json:"business_monthly_price_id"`
+	BusinessYearlyPriceID  string `json:"business_yearly_price_id"`
+}
+
+type AWSConfig struct {
+	Region          string `json:"region"`
+	AccessKeyID     string `json:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key"`
+	S3BucketName    string `json:"s3_bucket_name"`
+}
+
+type SESConfig struct {
+	SenderEmail string `json:"sender_email"`
+}
+
+// SMTPConfig sends email through a plain SMTP server when SES isn't configured, like
+// a local Mailpit that catches every message
+type SMTPConfig struct {
+	Addr        string `json:"addr"` // host:port
+	SenderEmail string `json:"sender_email"`
+}
+
+type SlackConfig struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RedirectURI  string `json:"redirect_uri"`
+}
+
+type DiscordConfig struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RedirectURI  string `json:"redirect_uri"`
+	BotToken     string `json:"bot_token"`
+}
+
+type ProbeConfig struct {
+	Token     string `json:"token"`
+	Region    string `json:"region"`
+	Quorum    int    `json:"quorum"`
+	ServerURL string `json:"server_url"`
+}
+
+// PublicAPIConfig protects the unauthenticated API and badges from being flooded, or
+// used to flood the health URLs that the ping endpoint measures
+type PublicAPIConfig struct {
+	RateLimitPerIP   int      `json:"rate_limit_per_ip"`   // requests a minute from one address, 0 for no limit
+	RateLimitPerSlug int      `json:"rate_limit_per_slug"` // new live pings a minute for one status page, 0 for no limit
+	PingCacheTTL     Duration `json:"ping_cache_ttl"`      // how long one live ping is shared between visitors
+}
+
+type MetricsConfig struct {
+	// Token must be sent as a Bearer token to scrape /metrics. The endpoint is disabled without it.
+	Token string `json:"token"`
+}
+
+// PlanConfig mirrors db.PlanFeatures so plan limits can be tuned without a rebuild
+type PlanConfig struct {
+	MaxMonitors       int  `json:"max_monitors"`
+	MinCheckInterval  int  `json:"min_check_interval"` // in seconds
+	DataRetentionDays int  `json:"data_retention_days"`
+	CustomBranding    bool `json:"custom_branding"` // logos and custom domains on status pages
+}
+
+// Duration is a time.Duration that reads "30s" style strings (or plain seconds) from JSON
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	switch value := v.(type) {
+	case float64:
+		d.Duration = time.Duration(value * float64(time.Second))
+	case string:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		d.Duration = parsed
+	default:
+		return fmt.Errorf("invalid duration %s", string(b))
+	}
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Default returns the configuration used when nothing is overridden
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Addr:           ":8080",
+			PublicURL:      "http://localhost:8080",
+			AllowedOrigins: []string{"http://localhost:8080", "http://localhost:3000", "http://localhost:5173"},
+		},
+		Database: DatabaseConfig{
+			Host:        "db",
+			Port:        5432,
+			User:        "postgres",
+			Name:        "statusframe",
+			SSLMode:     "disable",
+			AutoMigrate: true,
+		},
+		Worker: WorkerConfig{
+			CheckInterval: Duration{30 * time.Second},
+		},
+		Auth: AuthConfig{
+			GoogleAuthURL:     "https://accounts.google.com/o/oauth2/auth",
+			GoogleTokenURL:    "https://oauth2.googleapis.com/token",
+			GoogleUserInfoURL: "https://www.googleapis.com/oauth2/v2/userinfo",
+			GitHubAuthURL:     "https://github.com/login/oauth/authorize",
+			GitHubTokenURL:    "https://github.com/login/oauth/access_token",
+			GitHubAPIURL:      "https://api.github.com",
+			MagicLinkTTL:      Duration{15 * time.Minute},
+		},
+		Probe: ProbeConfig{
+			Quorum: 1,
+		},
+		Public: PublicAPIConfig{
+			RateLimitPerIP:   120,
+			RateLimitPerSlug: 1200,
+			PingCacheTTL:     Duration{15 * time.Second},
+		},
+		Plans: map[string]PlanConfig{
+			"free": {
+				MaxMonitors:       1,
+				MinCheckInterval:  300, // 5 minutes
+				DataRetentionDays: 7,
+			},
+			"pro": {
+				MaxMonitors:       25,
+				MinCheckInterval:  60, // 1 minute
+				DataRetentionDays: 30,
+				CustomBranding:    true,
+			},
+			"business": {
+				MaxMonitors:       100,
+				MinCheckInterval:  30, // 30 seconds
+				DataRetentionDays: 90,
+				CustomBranding:    true,
+			},
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the optional JSON file at path
+// and finally the environment. Environment variables always win over the file.
+// The result is not validated - call Validate before using it.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading config file: %w", err)
+		}
+		defer file.Close()
+
+		decoder := json.NewDecoder(file)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// applyEnv overrides settings with any environment variables that are set
+func (c *Config) applyEnv() error {
+	var errs []string
+
+	if port := os.Getenv("PORT"); port != "" {
+		c.Server.Addr = ":" + port
+	}
+	setString(&c.Server.Addr, "SERVER_ADDR")
+	setString(&c.Server.PublicURL, "APP_URL")
+	setList(&c.Server.AllowedOrigins, "CORS_ALLOWED_ORIGINS")
+	setList(&c.Server.TrustedProxies, "TRUSTED_PROXIES")
+
+	// POSTGRES_* are shared with the postgres container through .env
+	setString(&c.Database.User, "POSTGRES_USER")
+	setString(&c.Database.Password, "POSTGRES_PASSWORD")
+	setString(&c.Database.Name, "POSTGRES_DB")
+	setString(&c.Database.URL, "DATABASE_URL")
+	setString(&c.Database.Host, "DB_HOST")
+	setString(&c.Database.User, "DB_USER")
+	setString(&c.Database.Password, "DB_PASSWORD")
+	setString(&c.Database.Name, "DB_NAME")
+	setString(&c.Database.SSLMode, "DB_SSLMODE")
+	if err := setInt(&c.Database.Port, "DB_PORT"); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if err := setBool(&c.Database.AutoMigrate, "DB_AUTO_MIGRATE"); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if value := os.Getenv("CHECK_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("CHECK_INTERVAL: %v", err))
+		} else {
+			c.Worker.CheckInterval = Duration{interval}
+		}
+	}
+
+	setString(&c.Auth.GoogleClientID, "GOOGLE_CLIENT_ID")
+	setString(&c.Auth.GoogleClientSecret, "GOOGLE_CLIENT_SECRET")
+	setString(&c.Auth.GoogleRedirectURL, "GOOGLE_REDIRECT_URL")
+	setString(&c.Auth.SessionSecret, "SESSION_SECRET")
+	if err := setBool(&c.Auth.SecureCookies, "SESSION_SECURE_COOKIES"); err != nil {
+		errs = append(errs, err.Error())
+	}
+	setString(&c.Auth.GitHubClientID, "GITHUB_CLIENT_ID")
+	setString(&c.Auth.GitHubClientSecret, "GITHUB_CLIENT_SECRET")
+	setString(&c.Auth.GitHubRedirectURL, "GITHUB_REDIRECT_URL")
+	setString(&c.Auth.GoogleAuthURL, "GOOGLE_AUTH_URL")
+	setString(&c.Auth.GoogleTokenURL, "GOOGLE_TOKEN_URL")
+	setString(&c.Auth.GoogleUserInfoURL, "GOOGLE_USERINFO_URL")
+	setString(&c.Auth.GitHubAuthURL, "GITHUB_AUTH_URL")
+	setString(&c.Auth.GitHubTokenURL, "GITHUB_TOKEN_URL")
+	setString(&c.Auth.GitHubAPIURL, "GITHUB_API_URL")
+	if err := setBool(&c.Auth.MagicLinks, "MAGIC_LINKS_ENABLED"); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if value := os.Getenv("MAGIC_LINK_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("MAGIC_LINK_TTL: %v", err))
+		} else {
+			c.Auth.MagicLinkTTL = Duration{ttl}
+		}
+	}
+
+	setList(&c.Admin.Emails, "ADMIN_EMAILS")
+
+	setString(&c.Stripe.SecretKey, "STRIPE_SECRET_KEY")
+	setString(&c.Stripe.PublishableKey, "STRIPE_PUBLISHABLE_KEY")
+	setString(&c.Stripe.WebhookSecret, "STRIPE_WEBHOOK_SECRET")
+	setString(&c.Stripe.ProMonthlyPriceID, "STRIPE_PRO_MONTHLY_PRICE_ID")
+	setString(&c.Stripe.ProYearlyPriceID, "STRIPE_PRO_YEARLY_PRICE_ID")
+	setString(&c.Stripe.BusinessMonthlyPriceID, "STRIPE_BUSINESS_MONTHLY_PRICE_ID")
+	setString(&c.Stripe.BusinessYearlyPriceID, "STRIPE_BUSINESS_YEARLY_PRICE_ID")
+
+	setString(&c.AWS.Region, "AWS_REGION")
+	setString(&c.AWS.AccessKeyID, "AWS_ACCESS_KEY_ID")
+	setString(&c.AWS.SecretAccessKey, "AWS_SECRET_ACCESS_KEY")
+	setString(&c.AWS.S3BucketName, "AWS_S3_BUCKET_NAME")
+	setString(&c.SES.SenderEmail, "SES_SENDER_EMAIL")
+	setString(&c.SMTP.Addr, "SMTP_ADDR")
+	setString(&c.SMTP.SenderEmail, "SMTP_SENDER_EMAIL")
+
+	setString(&c.Slack.ClientID, "SLACK_CLIENT_ID")
+	setString(&c.Slack.ClientSecret, "SLACK_CLIENT_SECRET")
+	setString(&c.Slack.RedirectURI, "SLACK_REDIRECT_URI")
+
+	setString(&c.Discord.ClientID, "DISCORD_CLIENT_ID")
+	setString(&c.Discord.ClientSecret, "DISCORD_CLIENT_SECRET")
+	setString(&c.Discord.RedirectURI, "DISCORD_REDIRECT_URI")
+	setString(&c.Discord.BotToken, "DISCORD_BOT_TOKEN")
+
+	setString(&c.Probe.Token, "PROBE_TOKEN")
+	setString(&c.Probe.Region, "PROBE_REGION")
+	setString(&c.Probe.ServerURL, "PROBE_SERVER_URL")
+	if err := setInt(&c.Probe.Quorum, "PROBE_QUORUM"); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if err := setInt(&c.Public.RateLimitPerIP, "PUBLIC_RATE_LIMIT_PER_IP"); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if err := setInt(&c.Public.RateLimitPerSlug, "PUBLIC_RATE_LIMIT_PER_SLUG"); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if value := os.Getenv("PING_CACHE_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("PING_CACHE_TTL: %v", err))
+		} else {
+			c.Public.PingCacheTTL = Duration{ttl}
+		}
+	}
+
+	setString(&c.Metrics.Token, "METRICS_TOKEN")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid environment:\n  - %s", strings.Join(errs, "\n  - "))
+	}
+	return nil
+}
+
+// Validate checks the configuration and reports every problem at once
+func (c *Config) Validate() error {
+	var errs []string
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+
+	if c.Server.Addr == "" {
+		add("server.addr is required (set PORT or SERVER_ADDR)")
+	}
+	if c.Server.PublicURL != "" && !isAbsoluteURL(c.Server.PublicURL) {
+		add("server.public_url %q must be an absolute http(s) URL (APP_URL)", c.Server.PublicURL)
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			add("server.trusted_proxies contains %q, which is neither an address nor a CIDR range (TRUSTED_PROXIES)", proxy)
+		}
+	}
+
+	errs = append(errs, c.Database.problems()...)
+
+	if c.Worker.CheckInterval.Duration < 5*time.Second {
+		add("worker.check_interval must be at least 5s, got %s (CHECK_INTERVAL)", c.Worker.CheckInterval)
+	}
+
+	if !c.Auth.GoogleEnabled() && !c.Auth.GitHubEnabled() && !c.Auth.MagicLinks {
+		add("a login method is required: set auth.google_client_id and auth.google_client_secret (GOOGLE_CLIENT_ID, GOOGLE_CLIENT_SECRET), " +
+			"auth.github_client_id and auth.github_client_secret (GITHUB_CLIENT_ID, GITHUB_CLIENT_SECRET) or enable auth.magic_links (MAGIC_LINKS_ENABLED)")
+	}
+	if c.Auth.GoogleClientID != "" && c.Auth.GoogleClientSecret == "" {
+		add("auth.google_client_secret is required when auth.google_client_id is set (GOOGLE_CLIENT_SECRET)")
+	}
+	if c.Auth.GitHubClientID != "" && c.Auth.GitHubClientSecret == "" {
+		add("auth.github_client_secret is required when auth.github_client_id is set (GITHUB_CLIENT_SECRET)")
+	}
+	for _, endpoint := range []struct{ name, url string }{
+		{"google_auth_url", c.Auth.GoogleAuthURL},
+		{"google_token_url", c.Auth.GoogleTokenURL},
+		{"google_userinfo_url", c.Auth.GoogleUserInfoURL},
+		{"github_auth_url", c.Auth.GitHubAuthURL},
+		{"github_token_url", c.Auth.GitHubTokenURL},
+		{"github_api_url", c.Auth.GitHubAPIURL},
+	} {
+		if !isAbsoluteURL(endpoint.url) {
+			add("auth.%s %q must be an absolute http(s) URL", endpoint.name, endpoint.url)
+		}
+	}
+	if c.Auth.MagicLinks && c.Auth.MagicLinkTTL.Duration < time.Minute {
+		add("auth.magic_link_ttl must be at least 1m, got %s (MAGIC_LINK_TTL)", c.Auth.MagicLinkTTL)
+	}
+	if c.Auth.SessionSecret == "" {
+		add("auth.session_secret is required (SESSION_SECRET)")
+	}
+
+	for _, email := range c.Admin.Emails {
+		if !strings.Contains(email, "@") {
+			add("admin.emails contains an invalid email %q (ADMIN_EMAILS)", email)
+		}
+	}
+
+	if c.Stripe.Enabled() {
+		if c.Stripe.WebhookSecret == "" {
+			add("stripe.webhook_secret is required when Stripe is enabled (STRIPE_WEBHOOK_SECRET)")
+		}
+		if c.Server.PublicURL == "" {
+			add("server.public_url is required when Stripe is enabled (APP_URL)")
+		}
+	}
+
+	if c.SES.SenderEmail != "" && !c.AWS.HasCredentials() {
+		add("aws.region, aws.access_key_id and aws.secret_access_key are required to send email with SES")
+	}
+	if c.SMTP.Addr != "" && c.SMTP.SenderEmail == "" {
+		add("smtp.sender_email is required when smtp.addr is set (SMTP_SENDER_EMAIL)")
+	}
+	if c.AWS.S3BucketName != "" && !c.AWS.HasCredentials() {
+		add("aws.region, aws.access_key_id and aws.secret_access_key are required to upload to S3")
+	}
+
+	if c.Slack.ClientID != "" && c.Slack.ClientSecret == "" {
+		add("slack.client_secret is required when slack.client_id is set (SLACK_CLIENT_SECRET)")
+	}
+	if c.Discord.ClientID != "" && c.Discord.ClientSecret == "" {
+		add("discord.client_secret is required when discord.client_id is set (DISCORD_CLIENT_SECRET)")
+	}
+
+	if c.Probe.Quorum < 1 {
+		add("probe.quorum must be at least 1 (PROBE_QUORUM)")
+	}
+
+	if c.Public.RateLimitPerIP < 0 {
+		add("public.rate_limit_per_ip must not be negative (PUBLIC_RATE_LIMIT_PER_IP)")
+	}
+	if c.Public.RateLimitPerSlug < 0 {
+		add("public.rate_limit_per_slug must not be negative (PUBLIC_RATE_LIMIT_PER_SLUG)")
+	}
+	if c.Public.PingCacheTTL.Duration < time.Second {
+		add("public.ping_cache_ttl must be at least 1s, got %s (PING_CACHE_TTL)", c.Public.PingCacheTTL)
+	}
+
+	if _, ok := c.Plans["free"]; !ok {
+		add("plans.free is required - it is the fallback for unknown plans")
+	}
+	for name, plan := range c.Plans {
+		if plan.MaxMonitors < 1 {
+			add("plans.%s.max_monitors must be at least 1", name)
+		}
+		if plan.MinCheckInterval < 1 {
+			add("plans.%s.min_check_interval must be at least 1 second", name)
+		}
+		if plan.DataRetentionDays < 1 {
+			add("plans.%s.data_retention_days must be at least 1", name)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New("invalid configuration:\n  - " + strings.Join(errs, "\n  - "))
+	}
+	return nil
+}
+
+// Validate checks only the database settings, for commands that need nothing else
+func (d DatabaseConfig) Validate() error {
+	if errs := d.problems(); len(errs) > 0 {
+		return errors.New("invalid database configuration:\n  - " + strings.Join(errs, "\n  - "))
+	}
+	return nil
+}
+
+func (d DatabaseConfig) problems() []string {
+	if d.URL != "" {
+		return nil
+	}
+
+	var errs []string
+	if d.Host == "" {
+		errs = append(errs, "database.host is required (DB_HOST)")
+	}
+	if d.User == "" {
+		errs = append(errs, "database.user is required (DB_USER)")
+	}
+	if d.Password == "" {
+		errs = append(errs, "database.password is required (DB_PASSWORD or POSTGRES_PASSWORD)")
+	}
+	if d.Name == "" {
+		errs = append(errs, "database.name is required (DB_NAME)")
+	}
+	if d.Port < 1 || d.Port > 65535 {
+		errs = append(errs, fmt.Sprintf("database.port %d is out of range (DB_PORT)", d.Port))
+	}
+	return errs
+}
+
+// DSN returns the connection string for lib/pq
+func (d DatabaseConfig) DSN() string {
+	if d.URL != "" {
+		return d.URL
+	}
+	return fmt.Sprintf("user=%s dbname=%s password=%s host=%s port=%d sslmode=%s",
+		quoteDSN(d.User), quoteDSN(d.Name), quoteDSN(d.Password), quoteDSN(d.Host), d.Port, quoteDSN(d.SSLMode))
+}
+
+// GoogleEnabled reports whether users can sign in with Google
+func (a AuthConfig) GoogleEnabled() bool {
+	return a.GoogleClientID != "" && a.GoogleClientSecret != ""
+}
+
+// GitHubEnabled reports whether users can sign in with GitHub
+func (a AuthConfig) GitHubEnabled() bool {
+	return a.GitHubClientID != "" && a.GitHubClientSecret != ""
+}
+
+// Enabled reports whether payments are configured
+func (s StripeConfig) Enabled() bool {
+	return s.SecretKey != ""
+}
+
+// HasCredentials reports whether static AWS credentials are configured
+func (a AWSConfig) HasCredentials() bool {
+	return a.Region != "" && a.AccessKeyID != "" && a.SecretAccessKey != ""
+}
+
+// Enabled reports whether the Slack integration is configured
+func (s SlackConfig) Enabled() bool {
+	return s.ClientID != "" && s.ClientSecret != ""
+}
+
+// Enabled reports whether the Discord integration is configured
+func (d DiscordConfig) Enabled() bool {
+	return d.ClientID != "" && d.ClientSecret != ""
+}
+
+// Enabled reports whether probe agents may connect
+func (p ProbeConfig) Enabled() bool {
+	return p.Token != ""
+}
+
+// LocalRegion is the region the main server records its own checks under
+func (p ProbeConfig) LocalRegion() string {
+	if p.Region == "" {
+		return "primary"
+	}
+	return p.Region
+}
+
+// IsAdminEmail reports whether email belongs to a configured administrator
+func (a AdminConfig) IsAdminEmail(email string) bool {
+	for _, adminEmail := range a.Emails {
+		if email != "" && strings.EqualFold(adminEmail, email) {
+			return true
+		}
+	}
+	return false
+}
+
+func setString(target *string, key string) {
+	if value := os.Getenv(key); value != "" {
+		*target = value
+	}
+}
+
+func setList(target *[]string, key string) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*target = items
+}
+
+func setInt(target *int, key string) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("%s must be a number, got %q", key, value)
+	}
+	*target = parsed
+	return nil
+}
+
+func setBool(target *bool, key string) error {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("%s must be true or false, got %q", key, value)
+	}
+	*target = parsed
+	return nil
+}
+
+func isAbsoluteURL(raw string) bool {
+	parsed, err := url.Parse(raw)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// quoteDSN quotes a key/value connection string value when it needs it
+func quoteDSN(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
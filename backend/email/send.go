@@ -0,0 +1,42 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+)
+
+// Send delivers a plain transactional email with an HTML and a text body
+func (s *SESClient) Send(to, subject, htmlBody, textBody string) error {
+	input := &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(s.sender),
+		Destination: &types.Destination{
+			ToAddresses: []string{to},
+		},
+		Content: &types.EmailContent{
+			Simple: &types.Message{
+				Subject: &types.Content{Data: aws.String(subject), Charset: aws.String("UTF-8")},
+				Body: &types.Body{
+					Html: &types.Content{Data: aws.String(htmlBody), Charset: aws.String("UTF-8")},
+					Text: &types.Content{Data: aws.String(textBody), Charset: aws.String("UTF-8")},
+				},
+			},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := s.client.SendEmail(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	log.Printf("📧 Email sent successfully to %s (MessageId: %s)", to, *result.MessageId)
+	return nil
+}
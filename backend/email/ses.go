@@ -4,11 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
+	"statusframe/backend/config"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/sesv2"
 	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
@@ -20,19 +20,14 @@ type SESClient struct {
 }
 
 // NewSESClient creates a new AWS SES client
-func NewSESClient() (*SESClient, error) {
-	region := os.Getenv("AWS_REGION")
-	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
-	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
-	sender := os.Getenv("SES_SENDER_EMAIL")
-
-	if region == "" || accessKey == "" || secretKey == "" || sender == "" {
-		return nil, fmt.Errorf("missing AWS SES configuration in environment variables")
+func NewSESClient(awsCfg config.AWSConfig, sesCfg config.SESConfig) (*SESClient, error) {
+	if !awsCfg.HasCredentials() || sesCfg.SenderEmail == "" {
+		return nil, fmt.Errorf("missing AWS SES configuration")
 	}
 
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion(region),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessKey, secretKey, "")),
+	cfg, err := awsconfig.LoadDefaultConfig(context.TODO(),
+		awsconfig.WithRegion(awsCfg.Region),
+		awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(awsCfg.AccessKeyID, awsCfg.SecretAccessKey, "")),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("unable to load AWS SDK config: %w", err)
@@ -40,7 +35,7 @@ func NewSESClient() (*SESClient, error) {
 
 	return &SESClient{
 		client: sesv2.NewFromConfig(cfg),
-		sender: sender,
+		sender: sesCfg.SenderEmail,
 	}, nil
 }
 
@@ -132,7 +127,7 @@ func (s *SESClient) generateHTMLEmail(alert AlertEmail) string {
                 <table role="presentation" style="width: 600px; max-width: 100%%; border-collapse: collapse; background-color: #ffffff; border-radius: 8px; box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1);">
                     <!-- Header -->
                     <tr>
-                        <td style="padding: 40px 40px 20px; text-align: center; background: linear-gradient(135deg, #667eea 0%%, #764ba2 100%); border-radius: 8px 8px 0 0;">
+                        <td style="padding: 40px 40px 20px; text-align: center; background: linear-gradient(135deg, #667eea 0%%, #764ba2 100%%); border-radius: 8px 8px 0 0;">
                             <h1 style="margin: 0; color: #ffffff; font-size: 28px; font-weight: bold;">UpLitycs</h1>
                             <p style="margin: 8px 0 0; color: #e0e7ff; font-size: 14px;">Service Monitoring Alert</p>
                         </td>
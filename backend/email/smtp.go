@@ -0,0 +1,66 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"mime"
+	"mime/quotedprintable"
+	"net/smtp"
+	"statusframe/backend/config"
+	"time"
+)
+
+// SMTPClient sends email through a plain SMTP server without authentication. It is
+// meant for local development with a server like Mailpit that catches every message.
+type SMTPClient struct {
+	addr   string
+	sender string
+}
+
+// NewSMTPClient creates a client for the SMTP server in cfg
+func NewSMTPClient(cfg config.SMTPConfig) (*SMTPClient, error) {
+	if cfg.Addr == "" || cfg.SenderEmail == "" {
+		return nil, fmt.Errorf("missing SMTP configuration")
+	}
+	return &SMTPClient{addr: cfg.Addr, sender: cfg.SenderEmail}, nil
+}
+
+// Send delivers a plain transactional email with an HTML and a text body
+func (s *SMTPClient) Send(to, subject, htmlBody, textBody string) error {
+	boundary := make([]byte, 12)
+	if _, err := rand.Read(boundary); err != nil {
+		return fmt.Errorf("failed to build email: %w", err)
+	}
+	b := hex.EncodeToString(boundary)
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.sender)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", b)
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", textBody},
+		{"text/html", htmlBody},
+	} {
+		fmt.Fprintf(&msg, "--%s\r\n", b)
+		fmt.Fprintf(&msg, "Content-Type: %s; charset=UTF-8\r\n", part.contentType)
+		fmt.Fprintf(&msg, "Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		qp := quotedprintable.NewWriter(&msg)
+		qp.Write([]byte(part.body))
+		qp.Close()
+		fmt.Fprintf(&msg, "\r\n")
+	}
+	fmt.Fprintf(&msg, "--%s--\r\n", b)
+
+	if err := smtp.SendMail(s.addr, nil, s.sender, []string{to}, msg.Bytes()); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	log.Printf("📧 Email sent successfully to %s through %s", to, s.addr)
+	return nil
+}
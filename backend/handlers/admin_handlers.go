@@ -2,37 +2,39 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"path/filepath"
 	"statusframe/backend/auth"
 	"statusframe/backend/utils"
 	"statusframe/db"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/s3"
-)
-
-const (
-	ADMIN_EMAIL = "ricardoceia.sete@gmail.com"
+	"github.com/go-chi/chi/v5"
 )
 
 type AdminUser struct {
-	ID                   int       `json:"id"`
-	Username             string    `json:"username"`
-	Email                string    `json:"email"`
-	AvatarURL            string    `json:"avatar_url"`
-	Plan                 string    `json:"plan"`
-	PlanStartedAt        time.Time `json:"plan_started_at"`
-	StripeCustomerID     *string   `json:"stripe_customer_id"`
-	StripeSubscriptionID *string   `json:"stripe_subscription_id"`
-	CreatedAt            time.Time `json:"created_at"`
-	AppCount             int       `json:"app_count"`
-	TotalChecks          int       `json:"total_checks"`
+	ID                   int        `json:"id"`
+	Username             string     `json:"username"`
+	Email                string     `json:"email"`
+	AvatarURL            string     `json:"avatar_url"`
+	Plan                 string     `json:"plan"`
+	PlanStartedAt        time.Time  `json:"plan_started_at"`
+	StripeCustomerID     *string    `json:"stripe_customer_id"`
+	StripeSubscriptionID *string    `json:"stripe_subscription_id"`
+	CreatedAt            time.Time  `json:"created_at"`
+	AppCount             int        `json:"app_count"`
+	TotalChecks          int        `json:"total_checks"`
+	IsAdmin              bool       `json:"is_admin"`
+	DisabledAt           *time.Time `json:"disabled_at"`
+	DisabledReason       *string    `json:"disabled_reason"`
 }
 
 type AdminStats struct {
@@ -45,7 +47,28 @@ type AdminStats struct {
 	ActiveSubscribers int `json:"active_subscribers"`
 }
 
-// AdminMiddleware checks if the logged-in user is the admin
+// AdminPlanRequest is the body of the admin endpoints that change a plan
+type AdminPlanRequest struct {
+	Plan string `json:"plan"`
+}
+
+// AdminRoleRequest is the body of PUT /api/admin/users/{userId}/admin
+type AdminRoleRequest struct {
+	IsAdmin bool `json:"is_admin"`
+}
+
+// DisableUserRequest is the body of POST /api/admin/users/{userId}/disable
+type DisableUserRequest struct {
+	Reason string `json:"reason"`
+}
+
+// isAdmin reports whether the user has the admin role in the database or is granted it
+// through the configured admin emails
+func (h *Handler) isAdmin(user db.User) bool {
+	return user.IsAdmin || h.cfg.Admin.IsAdminEmail(user.Email)
+}
+
+// AdminMiddleware checks if the logged-in user is an admin
 func (h *Handler) AdminMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		session, err := auth.Store.Get(r, "auth-session")
@@ -60,15 +83,20 @@ func (h *Handler) AdminMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		// Get user from database to check email
+		// The admin panel isn't available while looking at the site as someone else
+		if _, impersonating := session.Values["impersonatorId"].(int); impersonating {
+			http.Error(w, "Forbidden - stop impersonating to use the admin panel", http.StatusForbidden)
+			return
+		}
+
+		// Get user from database to check the admin role
 		user, err := db.GetUserById(h.conn, userId)
 		if err != nil {
 			http.Error(w, "Unauthorized - User not found", http.StatusUnauthorized)
 			return
 		}
 
-		// Check if user email matches admin email
-		if user.Email != ADMIN_EMAIL {
+		if !h.isAdmin(user) || user.DisabledAt != nil {
 			log.Printf("⚠️  Non-admin user attempted to access admin panel: %s", user.Email)
 			http.Error(w, "Forbidden - Admin access required", http.StatusForbidden)
 			return
@@ -85,18 +113,14 @@ func (h *Handler) AdminCheckSessionHandler(w http.ResponseWriter, r *http.Reques
 	session, err := auth.Store.Get(r, "auth-session")
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"authenticated": false,
-		})
+		json.NewEncoder(w).Encode(AdminSessionResponse{})
 		return
 	}
 
 	userId, ok := session.Values["userId"].(int)
 	if !ok {
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"authenticated": false,
-		})
+		json.NewEncoder(w).Encode(AdminSessionResponse{})
 		return
 	}
 
@@ -104,20 +128,21 @@ func (h *Handler) AdminCheckSessionHandler(w http.ResponseWriter, r *http.Reques
 	user, err := db.GetUserById(h.conn, userId)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"authenticated": false,
-		})
+		json.NewEncoder(w).Encode(AdminSessionResponse{})
 		return
 	}
 
 	// Check if user is admin
-	isAdmin := user.Email == ADMIN_EMAIL
+	isAdmin := h.isAdmin(user) && user.DisabledAt == nil
+	impersonatorId, impersonating := session.Values["impersonatorId"].(int)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"authenticated": isAdmin,
-		"email":         user.Email,
-		"username":      user.Name,
+	json.NewEncoder(w).Encode(AdminSessionResponse{
+		Authenticated:  isAdmin && !impersonating,
+		Email:          user.Email,
+		Username:       user.Name,
+		Impersonating:  impersonating,
+		ImpersonatorID: impersonatorId,
 	})
 }
 
@@ -129,18 +154,23 @@ func (h *Handler) GetAllUsersHandler(w http.ResponseWriter, r *http.Request) {
 			u.username,
 			u.email,
 			COALESCE(u.avatar_url, '') as avatar_url,
-			u.plan,
-			u.plan_started_at,
-			u.stripe_customer_id,
-			u.stripe_subscription_id,
+			COALESCE(o.plan, 'free') as plan,
+			COALESCE(o.plan_started_at, u.created_at) as plan_started_at,
+			o.stripe_customer_id,
+			o.stripe_subscription_id,
 			u.created_at,
 			COUNT(DISTINCT a.id) as app_count,
-			COUNT(us.id) as total_checks
+			COUNT(us.id) as total_checks,
+			u.is_admin,
+			u.disabled_at,
+			u.disabled_reason
 		FROM users u
+		LEFT JOIN organizations o ON o.personal_user_id = u.id
 		LEFT JOIN apps a ON u.id = a.user_id
 		LEFT JOIN user_status us ON a.id = us.app_id
-		GROUP BY u.id, u.username, u.email, u.avatar_url, u.plan, u.plan_started_at, 
-		         u.stripe_customer_id, u.stripe_subscription_id, u.created_at
+		GROUP BY u.id, u.username, u.email, u.avatar_url, o.plan, o.plan_started_at, 
+		         o.stripe_customer_id, o.stripe_subscription_id, u.created_at,
+		         u.is_admin, u.disabled_at, u.disabled_reason
 		ORDER BY u.created_at DESC
 	`
 
@@ -167,6 +197,9 @@ func (h *Handler) GetAllUsersHandler(w http.ResponseWriter, r *http.Request) {
 			&user.CreatedAt,
 			&user.AppCount,
 			&user.TotalChecks,
+			&user.IsAdmin,
+			&user.DisabledAt,
+			&user.DisabledReason,
 		)
 		if err != nil {
 			log.Printf("Error scanning user: %v", err)
@@ -217,17 +250,16 @@ func (h *Handler) UploadLogoHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create AWS session
-	sess, err := utils.CreateAWSSession()
+	sess, err := utils.CreateAWSSession(h.cfg.AWS)
 	if err != nil {
 		log.Printf("Failed to create AWS session: %v", err)
 		http.Error(w, "Failed to create AWS session", http.StatusInternalServerError)
 		return
 	}
 
-	// Get bucket name from environment
-	bucketName := os.Getenv("AWS_S3_BUCKET_NAME")
+	bucketName := h.cfg.AWS.S3BucketName
 	if bucketName == "" {
-		log.Printf("AWS_S3_BUCKET_NAME not configured")
+		log.Printf("AWS S3 bucket name not configured")
 		http.Error(w, "S3 bucket name not configured", http.StatusInternalServerError)
 		return
 	}
@@ -261,30 +293,28 @@ func (h *Handler) UploadLogoHandler(w http.ResponseWriter, r *http.Request) {
 	// Construct the public file URL
 	fileURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s",
 		bucketName,
-		os.Getenv("AWS_REGION"),
+		h.cfg.AWS.Region,
 		s3Path,
 	)
 
 	// Return the URL
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"url": fileURL,
-	})
+	json.NewEncoder(w).Encode(LogoUploadResponse{URL: fileURL})
 }
 
 // GetAdminStatsHandler returns overall platform statistics
 func (h *Handler) GetAdminStatsHandler(w http.ResponseWriter, r *http.Request) {
 	var stats AdminStats
 
-	// Get user counts by plan
+	// Get user count and organization counts by plan, since plans belong to organizations
 	err := h.conn.QueryRow(`
 		SELECT 
-			COUNT(*) as total,
+			(SELECT COUNT(*) FROM users) as total,
 			COUNT(*) FILTER (WHERE plan = 'free') as free,
 			COUNT(*) FILTER (WHERE plan = 'pro') as pro,
 			COUNT(*) FILTER (WHERE plan = 'business') as business,
 			COUNT(*) FILTER (WHERE stripe_subscription_id IS NOT NULL) as active_subscribers
-		FROM users
+		FROM organizations
 	`).Scan(&stats.TotalUsers, &stats.FreeUsers, &stats.ProUsers, &stats.BusinessUsers, &stats.ActiveSubscribers)
 
 	if err != nil {
@@ -308,3 +338,337 @@ func (h *Handler) GetAdminStatsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(stats)
 }
+
+// adminTargetId parses a numeric URL parameter, writing a 400 if it isn't one
+func adminTargetId(w http.ResponseWriter, r *http.Request, param string) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, param))
+	if err != nil {
+		http.Error(w, "Invalid "+param, http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// adminSetPlan overrides an organization's plan and records the change
+func (h *Handler) adminSetPlan(w http.ResponseWriter, r *http.Request, orgId int) {
+	var req AdminPlanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if _, ok := h.cfg.Plans[req.Plan]; !ok {
+		http.Error(w, "Unknown plan", http.StatusBadRequest)
+		return
+	}
+
+	org, err := db.GetOrg(h.conn, orgId)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Organization not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading organization %d: %v", orgId, err)
+		http.Error(w, "Failed to change plan", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := db.SetOrgPlan(h.conn, orgId, req.Plan); err != nil {
+		log.Printf("Error changing plan of organization %d: %v", orgId, err)
+		http.Error(w, "Failed to change plan", http.StatusInternalServerError)
+		return
+	}
+
+	h.audit(r, auditEvent{
+		Action:     AuditPlanChange,
+		TargetType: "organization",
+		TargetID:   orgId,
+		OrgID:      orgId,
+		Before:     map[string]string{"plan": org.Plan},
+		After:      map[string]string{"plan": req.Plan},
+	})
+	log.Printf("💳 Admin changed plan of organization %d from %s to %s", orgId, org.Plan, req.Plan)
+	respondJSON(w, http.StatusOK, AdminPlanResponse{OrgID: orgId, Plan: req.Plan})
+}
+
+// AdminSetUserPlanHandler changes the plan of a user's personal organization.
+// It doesn't touch Stripe, so a later subscription event can change it again.
+func (h *Handler) AdminSetUserPlanHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := adminTargetId(w, r, "userId")
+	if !ok {
+		return
+	}
+
+	orgId, err := db.GetPersonalOrgId(h.conn, userId)
+	if err == sql.ErrNoRows {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error loading personal organization of user %d: %v", userId, err)
+		http.Error(w, "Failed to change plan", http.StatusInternalServerError)
+		return
+	}
+
+	h.adminSetPlan(w, r, orgId)
+}
+
+// AdminSetOrgPlanHandler changes the plan of any organization
+func (h *Handler) AdminSetOrgPlanHandler(w http.ResponseWriter, r *http.Request) {
+	orgId, ok := adminTargetId(w, r, "orgId")
+	if !ok {
+		return
+	}
+	h.adminSetPlan(w, r, orgId)
+}
+
+// AdminSetUserAdminHandler grants or revokes the admin role stored in the database.
+// Admins granted through ADMIN_EMAILS keep access regardless.
+func (h *Handler) AdminSetUserAdminHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := adminTargetId(w, r, "userId")
+	if !ok {
+		return
+	}
+	if userId == r.Context().Value("userId").(int) {
+		http.Error(w, "You can't change your own admin role", http.StatusBadRequest)
+		return
+	}
+
+	var req AdminRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	found, err := db.SetUserAdmin(h.conn, userId, req.IsAdmin)
+	if err != nil {
+		log.Printf("Error changing admin role of user %d: %v", userId, err)
+		http.Error(w, "Failed to change admin role", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	action := AuditRevokeAdmin
+	if req.IsAdmin {
+		action = AuditGrantAdmin
+	}
+	h.audit(r, auditEvent{
+		Action:     action,
+		TargetType: "user",
+		TargetID:   userId,
+		Before:     map[string]bool{"is_admin": !req.IsAdmin},
+		After:      map[string]bool{"is_admin": req.IsAdmin},
+	})
+	respondJSON(w, http.StatusOK, AdminRoleResponse{UserID: userId, IsAdmin: req.IsAdmin})
+}
+
+// AdminDisableUserHandler blocks an account from signing in and using its API keys
+func (h *Handler) AdminDisableUserHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := adminTargetId(w, r, "userId")
+	if !ok {
+		return
+	}
+	if userId == r.Context().Value("userId").(int) {
+		http.Error(w, "You can't disable your own account", http.StatusBadRequest)
+		return
+	}
+
+	var req DisableUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		http.Error(w, "A reason is required", http.StatusBadRequest)
+		return
+	}
+
+	changed, err := db.DisableUser(h.conn, userId, req.Reason)
+	if err != nil {
+		log.Printf("Error disabling user %d: %v", userId, err)
+		http.Error(w, "Failed to disable user", http.StatusInternalServerError)
+		return
+	}
+	if !changed {
+		http.Error(w, "User not found or already disabled", http.StatusNotFound)
+		return
+	}
+
+	h.audit(r, auditEvent{
+		Action:     AuditDisableUser,
+		TargetType: "user",
+		TargetID:   userId,
+		Details:    map[string]interface{}{"reason": req.Reason},
+	})
+	log.Printf("🚫 Admin disabled user %d: %s", userId, req.Reason)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdminEnableUserHandler lets a disabled account sign in again
+func (h *Handler) AdminEnableUserHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := adminTargetId(w, r, "userId")
+	if !ok {
+		return
+	}
+
+	changed, err := db.EnableUser(h.conn, userId)
+	if err != nil {
+		log.Printf("Error enabling user %d: %v", userId, err)
+		http.Error(w, "Failed to enable user", http.StatusInternalServerError)
+		return
+	}
+	if !changed {
+		http.Error(w, "User not found or not disabled", http.StatusNotFound)
+		return
+	}
+
+	h.audit(r, auditEvent{Action: AuditEnableUser, TargetType: "user", TargetID: userId})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdminImpersonateHandler signs the admin in as another user, read-only, so support
+// can see what they see. The session falls back to the admin after auth.ImpersonationTTL.
+func (h *Handler) AdminImpersonateHandler(w http.ResponseWriter, r *http.Request) {
+	userId, ok := adminTargetId(w, r, "userId")
+	if !ok {
+		return
+	}
+
+	target, err := db.GetUserById(h.conn, userId)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if h.isAdmin(target) {
+		http.Error(w, "Admins can't be impersonated", http.StatusForbidden)
+		return
+	}
+
+	if err := auth.StartImpersonation(w, r, target.Id, target.Name); err != nil {
+		log.Printf("Error starting impersonation of user %d: %v", userId, err)
+		http.Error(w, "Failed to start impersonation", http.StatusInternalServerError)
+		return
+	}
+
+	h.audit(r, auditEvent{Action: AuditImpersonateStart, TargetType: "user", TargetID: userId})
+	log.Printf("🕵️ Admin %d is impersonating user %d", r.Context().Value("userId").(int), userId)
+	respondJSON(w, http.StatusOK, ImpersonationResponse{
+		UserID:    target.Id,
+		Username:  target.Name,
+		ExpiresAt: time.Now().Add(auth.ImpersonationTTL),
+	})
+}
+
+// StopImpersonationHandler switches an impersonated session back to the admin.
+// It sits outside AdminMiddleware because the session belongs to the impersonated user.
+func (h *Handler) StopImpersonationHandler(w http.ResponseWriter, r *http.Request) {
+	adminId, targetId, ok, err := auth.StopImpersonation(w, r)
+	if err != nil {
+		log.Printf("Error stopping impersonation: %v", err)
+		http.Error(w, "Failed to stop impersonation", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "Not impersonating anyone", http.StatusBadRequest)
+		return
+	}
+
+	h.writeAudit(&adminId, h.clientIP(r), auditEvent{Action: AuditImpersonateStop, TargetType: "user", TargetID: targetId})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// adminSetAppPaused pauses or resumes any app, recording the admin as the actor
+func (h *Handler) adminSetAppPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	appId, ok := adminTargetId(w, r, "appId")
+	if !ok {
+		return
+	}
+
+	app, err := db.GetAppById(h.conn, appId)
+	if err != nil {
+		http.Error(w, "App not found", http.StatusNotFound)
+		return
+	}
+
+	adminId := r.Context().Value("userId").(int)
+	if _, err := db.SetAppPaused(h.conn, app.Id, app.OrgId, paused, &adminId, db.PauseViaAdmin); err != nil {
+		log.Printf("Error setting paused=%t on app %d: %v", paused, appId, err)
+		http.Error(w, "Failed to update app", http.StatusInternalServerError)
+		return
+	}
+
+	action := AuditForceResume
+	if paused {
+		action = AuditForcePause
+	}
+	h.audit(r, auditEvent{
+		Action:     action,
+		TargetType: "app",
+		TargetID:   appId,
+		OrgID:      app.OrgId,
+		Before:     map[string]bool{"paused": app.Paused},
+		After:      map[string]bool{"paused": paused},
+	})
+	respondJSON(w, http.StatusOK, AdminPauseResponse{AppID: appId, Paused: paused})
+}
+
+// AdminPauseAppHandler stops monitoring an app regardless of who owns it
+func (h *Handler) AdminPauseAppHandler(w http.ResponseWriter, r *http.Request) {
+	h.adminSetAppPaused(w, r, true)
+}
+
+// AdminResumeAppHandler resumes monitoring an app regardless of who owns it
+func (h *Handler) AdminResumeAppHandler(w http.ResponseWriter, r *http.Request) {
+	h.adminSetAppPaused(w, r, false)
+}
+
+// AdminGetProbeAgentsHandler lists the registered probe agents
+func (h *Handler) AdminGetProbeAgentsHandler(w http.ResponseWriter, r *http.Request) {
+	agents, err := db.GetProbeAgents(h.conn)
+	if err != nil {
+		log.Printf("Error fetching probe agents: %v", err)
+		http.Error(w, "Failed to fetch probe agents", http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, http.StatusOK, agents)
+}
+
+// adminSetProbeDisabled stops or restarts a probe agent getting checks and reporting results
+func (h *Handler) adminSetProbeDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	agentId, ok := adminTargetId(w, r, "agentId")
+	if !ok {
+		return
+	}
+
+	changed, err := db.SetProbeAgentDisabled(h.conn, agentId, disabled)
+	if err != nil {
+		log.Printf("Error setting disabled=%t on probe agent %d: %v", disabled, agentId, err)
+		http.Error(w, "Failed to update probe agent", http.StatusInternalServerError)
+		return
+	}
+	if !changed {
+		http.Error(w, "Probe agent not found or already in that state", http.StatusNotFound)
+		return
+	}
+
+	action := AuditEnableProbe
+	if disabled {
+		action = AuditDisableProbe
+	}
+	h.audit(r, auditEvent{Action: action, TargetType: "probe_agent", TargetID: agentId})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdminDisableProbeHandler refuses a probe agent's polls and results, for example when
+// its token leaked or its region gives bad results
+func (h *Handler) AdminDisableProbeHandler(w http.ResponseWriter, r *http.Request) {
+	h.adminSetProbeDisabled(w, r, true)
+}
+
+// AdminEnableProbeHandler lets a disabled probe agent poll and report again
+func (h *Handler) AdminEnableProbeHandler(w http.ResponseWriter, r *http.Request) {
+	h.adminSetProbeDisabled(w, r, false)
+}
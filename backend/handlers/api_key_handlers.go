@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"statusframe/backend/auth"
+	"statusframe/db"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxActiveAPIKeys limits how many unrevoked keys a user can hold
+const maxActiveAPIKeys = 20
+
+type CreateAPIKeyRequest struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope,omitempty"` // "read" (the default) or "write"
+}
+
+// GetAPIKeysHandler lists the user's API keys without the secret part
+func (h *Handler) GetAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	keys, err := db.GetAPIKeys(h.conn, userId)
+	if err != nil {
+		log.Printf("Error fetching API keys for user %d: %v", userId, err)
+		http.Error(w, "Failed to fetch API keys", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, APIKeyListResponse{APIKeys: keys})
+}
+
+// CreateAPIKeyHandler creates a key and returns it. This is the only time the full key is shown.
+func (h *Handler) CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		http.Error(w, "Name is required and must be at most 100 characters", http.StatusBadRequest)
+		return
+	}
+	if req.Scope == "" {
+		req.Scope = "read"
+	}
+	if req.Scope != "read" && req.Scope != "write" {
+		http.Error(w, "Invalid scope. Must be 'read' or 'write'", http.StatusBadRequest)
+		return
+	}
+
+	keys, err := db.GetAPIKeys(h.conn, userId)
+	if err != nil {
+		log.Printf("Error fetching API keys for user %d: %v", userId, err)
+		http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+	active := 0
+	for _, key := range keys {
+		if key.RevokedAt == nil {
+			active++
+		}
+	}
+	if active >= maxActiveAPIKeys {
+		http.Error(w, "API key limit reached. Revoke an unused key first", http.StatusForbidden)
+		return
+	}
+
+	plaintext, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		log.Printf("Error generating API key: %v", err)
+		http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	key, err := db.CreateAPIKey(h.conn, userId, req.Name, prefix, hash, req.Scope)
+	if err != nil {
+		log.Printf("Error storing API key for user %d: %v", userId, err)
+		http.Error(w, "Failed to create API key", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("🔑 API key %s (%s) created for user %d", key.Prefix, key.Scope, userId)
+
+	respondJSON(w, http.StatusCreated, CreateAPIKeyResponse{
+		APIKey: key,
+		Key:    plaintext,
+	})
+}
+
+// RevokeAPIKeyHandler revokes one of the user's API keys
+func (h *Handler) RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	keyId, err := strconv.Atoi(chi.URLParam(r, "keyId"))
+	if err != nil {
+		http.Error(w, "Invalid key ID", http.StatusBadRequest)
+		return
+	}
+
+	revoked, err := db.RevokeAPIKey(h.conn, userId, keyId)
+	if err != nil {
+		log.Printf("Error revoking API key %d: %v", keyId, err)
+		http.Error(w, "Failed to revoke API key", http.StatusInternalServerError)
+		return
+	}
+	if !revoked {
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	}
+
+	log.Printf("🔑 API key %d revoked by user %d", keyId, userId)
+
+	respondJSON(w, http.StatusOK, SuccessResponse{Success: true})
+}
@@ -0,0 +1,485 @@
+package handlers
+
+import (
+	"statusframe/db"
+	"time"
+)
+
+// Response bodies of the JSON API. Handlers write these instead of ad-hoc maps so the
+// OpenAPI document in openapi.go describes exactly what is sent.
+
+// ErrorResponse is the body of every error returned by the /api/v1 endpoints
+type ErrorResponse struct {
+	Error APIError `json:"error"`
+}
+
+// SuccessResponse acknowledges a change that has nothing else to return
+type SuccessResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+}
+
+// AppResponse wraps a single app
+type AppResponse struct {
+	App *db.App `json:"app"`
+}
+
+// AppListResponse is the body of GET /api/v1/apps
+type AppListResponse struct {
+	Apps []db.AppWithStatus `json:"apps"`
+}
+
+// PauseEventListResponse is the body of GET /api/v1/apps/{appId}/pause-events
+type PauseEventListResponse struct {
+	Events []db.AppPauseEvent `json:"events"`
+}
+
+// CheckListResponse is the body of GET /api/v1/apps/{appId}/checks
+type CheckListResponse struct {
+	Checks []db.CheckResult `json:"checks"`
+}
+
+// IncidentListResponse is the body of GET /api/v1/apps/{appId}/incidents
+type IncidentListResponse struct {
+	Incidents []db.Incident `json:"incidents"`
+}
+
+// IncidentUpdateResponse is the body of POST /api/v1/apps/{appId}/incident-updates
+type IncidentUpdateResponse struct {
+	IncidentUpdate *db.IncidentUpdate `json:"incident_update"`
+}
+
+// IncidentUpdateListResponse is the body of GET /api/v1/apps/{appId}/incident-updates
+type IncidentUpdateListResponse struct {
+	IncidentUpdates []db.IncidentUpdate `json:"incident_updates"`
+}
+
+// UserAppsResponse is the dashboard's view of the active organization's apps
+type UserAppsResponse struct {
+	Apps      []db.AppWithStatus `json:"apps"`
+	Plan      string             `json:"plan"`
+	PlanLimit int                `json:"plan_limit"`
+	AppCount  int                `json:"app_count"`
+	OrgID     int                `json:"org_id"`
+	Role      string             `json:"role"`
+}
+
+// UserStatusResponse describes the signed-in user and their first app. The camelCase
+// keys predate the rest of the API and are kept for the frontend.
+type UserStatusResponse struct {
+	Authenticated bool     `json:"authenticated"`
+	UserID        int      `json:"userId"`
+	UserName      string   `json:"userName"`
+	Theme         string   `json:"theme"`
+	Homepage      string   `json:"homepage"`
+	Slug          string   `json:"slug"`
+	AppName       string   `json:"appName"`
+	Apps          []db.App `json:"apps"`
+	OrgID         int      `json:"orgId"`
+	OrgRole       string   `json:"orgRole"`
+}
+
+// PlanLimitResponse tells whether the active organization can add another app
+type PlanLimitResponse struct {
+	CanAdd    bool   `json:"can_add"`
+	Plan      string `json:"plan"`
+	PlanLimit int    `json:"plan_limit"`
+	AppCount  int    `json:"app_count"`
+	Remaining int    `json:"remaining"`
+}
+
+// PlanFeaturesResponse lists the limits of the active organization's plan
+type PlanFeaturesResponse struct {
+	Plan              string `json:"plan"`
+	MaxMonitors       int    `json:"max_monitors"`
+	MinCheckInterval  int    `json:"min_check_interval"`
+	DataRetentionDays int    `json:"data_retention_days"`
+	CurrentAppCount   int    `json:"current_app_count"`
+	RemainingMonitors int    `json:"remaining_monitors"`
+}
+
+// PublicStatusResponse is what a public status page shows. It is built for visitors
+// field by field, so the health URL and the owner never reach them. Before the first
+// check there is a message instead of a check time and uptime, and details the page
+// hides are left out; Fields says which ones it shows.
+type PublicStatusResponse struct {
+	AppName           string           `json:"app_name"`
+	Slug              string           `json:"slug"`
+	Theme             string           `json:"theme"`
+	LogoURL           *string          `json:"logo_url"`
+	Status            string           `json:"status"`
+	StatusCode        int              `json:"status_code,omitempty"`
+	CheckedAt         string           `json:"checked_at,omitempty"`
+	Uptime24h         *float64         `json:"uptime_24h,omitempty"`
+	UptimeHistory     []db.DailyUptime `json:"uptime_history,omitempty"`
+	DataRetentionDays int              `json:"data_retention_days,omitempty"`
+	Paused            bool             `json:"paused"`
+	Message           string           `json:"message,omitempty"`
+	Fields            db.PublicFields  `json:"fields"`
+}
+
+// StatusViewerResponse is the body of GET /api/public/status/{slug}/viewer. Owner is
+// true when the signed-in visitor can change the app.
+type StatusViewerResponse struct {
+	Owner bool `json:"owner"`
+}
+
+// PingResponse is a live measurement of an app's health URL. ResponseTime is null
+// while the app is paused.
+type PingResponse struct {
+	ResponseTime *int64    `json:"response_time"`
+	StatusCode   int       `json:"status_code,omitempty"` // left out when the page hides status codes
+	Timestamp    time.Time `json:"timestamp"`
+	Paused       bool      `json:"paused,omitempty"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// VisibilityResponse is the body of GET and PUT /api/v1/apps/{appId}/visibility
+type VisibilityResponse struct {
+	Visibility *db.AppAccess `json:"visibility"`
+}
+
+// PublicFieldsResponse is the body of GET and PATCH /api/v1/apps/{appId}/public-fields
+type PublicFieldsResponse struct {
+	PublicFields db.PublicFields `json:"public_fields"`
+}
+
+// ShareLinkResponse is a signed link to a private status page
+type ShareLinkResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SubscribeResponse is the body of POST /api/public/status/{slug}/subscribe. Status
+// is pending_confirmation for an email address and confirmed for a webhook, which
+// also gets its unsubscribe link.
+type SubscribeResponse struct {
+	Status         string `json:"status"`
+	Message        string `json:"message"`
+	UnsubscribeURL string `json:"unsubscribe_url,omitempty"`
+}
+
+// OrgListResponse is the body of GET /api/orgs
+type OrgListResponse struct {
+	Organizations []db.Organization `json:"organizations"`
+	ActiveOrgID   int               `json:"active_org_id"`
+}
+
+// OrgResponse wraps a single organization
+type OrgResponse struct {
+	Organization *db.Organization `json:"organization"`
+}
+
+// SwitchOrgResponse is the body of POST /api/orgs/{orgId}/switch
+type SwitchOrgResponse struct {
+	ActiveOrgID int    `json:"active_org_id"`
+	Role        string `json:"role"`
+}
+
+// MemberListResponse is the body of GET /api/orgs/{orgId}/members
+type MemberListResponse struct {
+	Members []db.OrgMember `json:"members"`
+}
+
+// MemberRoleResponse is a member's role after it was changed
+type MemberRoleResponse struct {
+	UserID int    `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// InvitationListResponse is the body of GET /api/orgs/{orgId}/invitations
+type InvitationListResponse struct {
+	Invitations []db.OrgInvitation `json:"invitations"`
+}
+
+// InvitationResponse wraps a single invitation
+type InvitationResponse struct {
+	Invitation *db.OrgInvitation `json:"invitation"`
+}
+
+// OrgSecurityResponse holds an organization's security settings. The members without
+// two-factor authentication are only listed when reading them, and null after a change.
+type OrgSecurityResponse struct {
+	Require2FA        bool           `json:"require_2fa"`
+	MembersWithout2FA []db.OrgMember `json:"members_without_2fa"`
+}
+
+// APIKeyListResponse is the body of GET /api/api-keys
+type APIKeyListResponse struct {
+	APIKeys []db.APIKey `json:"api_keys"`
+}
+
+// CreateAPIKeyResponse carries the full key. It is only ever returned here.
+type CreateAPIKeyResponse struct {
+	APIKey *db.APIKey `json:"api_key"`
+	Key    string     `json:"key"`
+}
+
+// SessionListResponse is the body of GET /api/sessions
+type SessionListResponse struct {
+	Sessions []db.UserSession `json:"sessions"`
+}
+
+// RevokeSessionsResponse says how many sessions were signed out
+type RevokeSessionsResponse struct {
+	Success bool  `json:"success"`
+	Revoked int64 `json:"revoked"`
+}
+
+// TwoFactorStatusResponse is the body of GET /api/2fa
+type TwoFactorStatusResponse struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+// TwoFactorSetupResponse holds a new secret for the user's authenticator app
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+// TwoFactorChangeResponse is returned when two-factor authentication is turned on or
+// off. Turning it on returns the recovery codes, which are never shown again.
+type TwoFactorChangeResponse struct {
+	Enabled       bool     `json:"enabled"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+// RecoveryCodesResponse holds newly generated recovery codes
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// ConfigApplyResponse lists what applying a configuration changed, or would change on
+// a dry run. Deletes come first, then updates, then creates.
+type ConfigApplyResponse struct {
+	DryRun  bool           `json:"dry_run"`
+	Changes []ConfigChange `json:"changes"`
+}
+
+// ConfigChange is one app a configuration creates, updates or deletes, with the fields
+// that change. A delete has no fields.
+type ConfigChange struct {
+	Action string                       `json:"action"`
+	Slug   string                       `json:"slug"`
+	Diff   map[string]ConfigFieldChange `json:"diff,omitempty"`
+}
+
+// ConfigFieldChange is a field's value before and after. From is null for a new app.
+type ConfigFieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// StatusPageResponse wraps a single status page
+type StatusPageResponse struct {
+	StatusPage *db.StatusPage `json:"status_page"`
+}
+
+// StatusPageListResponse is the body of GET /api/v1/status-pages
+type StatusPageListResponse struct {
+	StatusPages []db.StatusPage `json:"status_pages"`
+}
+
+// PublicStatusPageResponse is what a public multi-app status page shows. Status is
+// operational, degraded, partial_outage, major_outage, maintenance or pending.
+type PublicStatusPageResponse struct {
+	Slug              string            `json:"slug"`
+	Title             string            `json:"title"`
+	Description       string            `json:"description,omitempty"`
+	Theme             string            `json:"theme"`
+	LogoURL           *string           `json:"logo_url"`
+	Status            string            `json:"status"`
+	Components        []PublicComponent `json:"components"`
+	Sections          []PublicSection   `json:"sections"`
+	DataRetentionDays int               `json:"data_retention_days"`
+	GeneratedAt       time.Time         `json:"generated_at"`
+}
+
+// PublicSection is a named group of components with their combined status
+type PublicSection struct {
+	Name       string            `json:"name"`
+	Status     string            `json:"status"`
+	Components []PublicComponent `json:"components"`
+}
+
+// PublicComponent is one app on a public status page. Status is up, degraded, down,
+// client_error, error, maintenance, paused or pending.
+type PublicComponent struct {
+	Name          string           `json:"name"`
+	Status        string           `json:"status"`
+	CheckedAt     *time.Time       `json:"checked_at,omitempty"`
+	Uptime24h     *float64         `json:"uptime_24h,omitempty"`
+	UptimeHistory []db.DailyUptime `json:"uptime_history"`
+}
+
+// OnboardingAppResponse is the body of POST /api/go-to-dashboard, which creates the
+// app from the onboarding form
+type OnboardingAppResponse struct {
+	Success bool    `json:"success"`
+	Message string  `json:"message"`
+	AppID   int     `json:"app_id"`
+	Slug    string  `json:"slug"`
+	LogoURL *string `json:"logo_url"`
+}
+
+// PlanLimitReachedResponse is the 403 of POST /api/go-to-dashboard when the plan has
+// no room for another app. Error is always "plan_limit_reached".
+type PlanLimitReachedResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error"`
+	Message string `json:"message"`
+	Plan    string `json:"plan"`
+	Limit   int    `json:"limit"`
+}
+
+// LatestStatusResponse is the newest check of the user's first app. Before the first
+// check there is a message instead of the uptime.
+type LatestStatusResponse struct {
+	Status     string   `json:"status"`
+	StatusCode int      `json:"status_code"`
+	CheckedAt  string   `json:"checked_at"`
+	Uptime24h  *float64 `json:"uptime_24h,omitempty"`
+	Message    string   `json:"message,omitempty"`
+}
+
+// ThemeResponse is the body of POST /api/update-theme
+type ThemeResponse struct {
+	Success bool   `json:"success"`
+	Theme   string `json:"theme"`
+}
+
+// CheckSessionResponse is the body of GET /auth/check-session
+type CheckSessionResponse struct {
+	Authenticated bool `json:"authenticated"`
+	UserID        int  `json:"userId"`
+}
+
+// LoginMethodsResponse lists the OAuth providers the sign-in page offers and whether
+// it can email a sign-in link
+type LoginMethodsResponse struct {
+	Providers  []string `json:"providers"`
+	MagicLinks bool     `json:"magic_links"`
+}
+
+// RedirectURLResponse holds a Stripe page to send the browser to, for checkout or the
+// billing portal
+type RedirectURLResponse struct {
+	URL string `json:"url"`
+}
+
+// OAuthURLResponse holds the consent screen that starts connecting Slack or Discord
+type OAuthURLResponse struct {
+	OAuthURL string `json:"oauth_url"`
+}
+
+// IntegrationErrorResponse is the body of the errors the Slack and Discord handlers
+// write themselves
+type IntegrationErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// SlackIntegrationResponse wraps the organization's Slack integration. Integration is
+// null, with a message, when Slack isn't connected.
+type SlackIntegrationResponse struct {
+	Success     bool                 `json:"success,omitempty"`
+	Integration *db.SlackIntegration `json:"integration"`
+	Message     string               `json:"message,omitempty"`
+}
+
+// DiscordIntegrationResponse wraps the organization's Discord integration.
+// Integration is null, with a message, when Discord isn't connected.
+type DiscordIntegrationResponse struct {
+	Integration *db.DiscordIntegration `json:"integration"`
+	Message     string                 `json:"message,omitempty"`
+}
+
+// AdminSessionResponse tells the admin console whether the session may use it.
+// Authenticated is false for non-admins and while impersonating.
+type AdminSessionResponse struct {
+	Authenticated  bool   `json:"authenticated"`
+	Email          string `json:"email,omitempty"`
+	Username       string `json:"username,omitempty"`
+	Impersonating  bool   `json:"impersonating"`
+	ImpersonatorID int    `json:"impersonatorId"`
+}
+
+// AdminPlanResponse is returned when an admin changes an organization's plan
+type AdminPlanResponse struct {
+	OrgID int    `json:"org_id"`
+	Plan  string `json:"plan"`
+}
+
+// AdminRoleResponse is returned when an admin grants or revokes the admin role
+type AdminRoleResponse struct {
+	UserID  int  `json:"user_id"`
+	IsAdmin bool `json:"is_admin"`
+}
+
+// ImpersonationResponse is the user an admin now browses as, until ExpiresAt
+type ImpersonationResponse struct {
+	UserID    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AdminPauseResponse is returned when an admin pauses or resumes an app
+type AdminPauseResponse struct {
+	AppID  int  `json:"app_id"`
+	Paused bool `json:"paused"`
+}
+
+// LogoUploadResponse holds the address of an uploaded logo
+type LogoUploadResponse struct {
+	URL string `json:"url"`
+}
+
+// ProbeResultsResponse is the body of POST /api/probe/results. Accepted counts the
+// results that were stored, Rejected the ones for apps the agent isn't assigned.
+type ProbeResultsResponse struct {
+	Accepted int    `json:"accepted"`
+	Rejected int    `json:"rejected"`
+	Region   string `json:"region"`
+}
+
+// LivenessResponse is the body of GET /healthz
+type LivenessResponse struct {
+	Status string `json:"status"`
+}
+
+// ReadinessResponse is the body of GET /readyz. Status is ready, not_ready or
+// shutting_down; Database is ok or unreachable.
+type ReadinessResponse struct {
+	Status   string         `json:"status"`
+	Database string         `json:"database"`
+	Workers  []WorkerStatus `json:"workers"`
+}
+
+// OpenAPIDocument is the OpenAPI 3 document served at GET /api/openapi.json. The
+// operations and schemas in it are free-form, since they describe the rest of the API.
+type OpenAPIDocument struct {
+	OpenAPI    string                            `json:"openapi"`
+	Info       OpenAPIInfo                       `json:"info"`
+	Servers    []OpenAPIServer                   `json:"servers,omitempty"`
+	Paths      map[string]map[string]interface{} `json:"paths"`
+	Components OpenAPIComponents                 `json:"components"`
+}
+
+// OpenAPIInfo names the API and its version
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+// OpenAPIServer is the base URL the API is served from
+type OpenAPIServer struct {
+	URL string `json:"url"`
+}
+
+// OpenAPIComponents holds the schemas the operations refer to and how to authenticate
+type OpenAPIComponents struct {
+	Schemas         map[string]interface{} `json:"schemas"`
+	SecuritySchemes map[string]interface{} `json:"securitySchemes"`
+}
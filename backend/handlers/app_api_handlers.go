@@ -0,0 +1,523 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"statusframe/backend/auth"
+	"statusframe/backend/utils"
+	"statusframe/db"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Error codes returned by the /api/v1 endpoints
+const (
+	errCodeInvalidRequest = "invalid_request"
+	errCodeValidation     = "validation_failed"
+	errCodeNotFound       = "not_found"
+	errCodeConflict       = "conflict"
+	errCodeForbidden      = "forbidden"
+	errCodePlanLimit      = "plan_limit_reached"
+	errCodeInternal       = "internal_error"
+)
+
+// APIError is the body of every error returned by the /api/v1 endpoints
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// respondError writes {"error": {"code": ..., "message": ...}}
+func respondError(w http.ResponseWriter, status int, code, message string) {
+	respondJSON(w, status, ErrorResponse{
+		Error: APIError{Code: code, Message: message},
+	})
+}
+
+// CreateAppRequest is the body of POST /api/v1/apps. Theme defaults to "cyberpunk"
+// and alerts to "n".
+type CreateAppRequest struct {
+	AppName   string  `json:"app_name"`
+	Slug      string  `json:"slug"`
+	HealthUrl string  `json:"health_url"`
+	Theme     string  `json:"theme,omitempty"`
+	Alerts    string  `json:"alerts,omitempty"`
+	LogoURL   *string `json:"logo_url,omitempty"`
+}
+
+// UpdateAppRequest is the body of PATCH /api/v1/apps/{appId}. Omitted fields are
+// left unchanged and an empty logo_url removes the logo.
+type UpdateAppRequest struct {
+	AppName   *string `json:"app_name"`
+	Slug      *string `json:"slug"`
+	HealthUrl *string `json:"health_url"`
+	Theme     *string `json:"theme"`
+	Alerts    *string `json:"alerts"`
+	LogoURL   *string `json:"logo_url"`
+}
+
+// decodeAPIRequest decodes a JSON body, rejecting unknown fields so typos don't silently do nothing
+func decodeAPIRequest(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "Invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// validateAppFields checks the fields shared by create and update. Nil fields are skipped.
+func validateAppFields(appName, slug, healthUrl, theme, alerts, logoURL *string) string {
+	if appName != nil && !utils.CheckAppName(*appName) {
+		return "app_name is required and must be at most 100 characters"
+	}
+	if slug != nil && !utils.CheckSlug(*slug) {
+		return "slug may only contain lowercase letters, numbers and hyphens"
+	}
+	if healthUrl != nil && !utils.CheckURLFormat(*healthUrl) {
+		return "health_url must start with http:// or https://"
+	}
+	if theme != nil && !utils.CheckTheme(*theme) {
+		return "theme must be one of: " + strings.Join(utils.ValidThemes, ", ")
+	}
+	if alerts != nil && !utils.CheckAlerts(*alerts) {
+		return "alerts must be one of: y, n, yes, no"
+	}
+	if logoURL != nil && *logoURL != "" && !utils.CheckURLFormat(*logoURL) {
+		return "logo_url must start with http:// or https://"
+	}
+	return ""
+}
+
+// canUseCustomLogo reports whether the organization's plan allows a logo, like logo uploads in onboarding
+func (h *Handler) canUseCustomLogo(orgId int) bool {
+	plan, _ := db.GetOrgPlan(h.conn, orgId)
+	return planAllowsCustomBranding(plan)
+}
+
+// planAllowsCustomBranding reports whether a plan allows logos and custom domains
+func planAllowsCustomBranding(plan string) bool {
+	return db.GetPlanFeatures(plan).CustomBranding
+}
+
+// requireAPIRole writes a 403 error body and returns false unless the caller has at least
+// the given role in the active organization
+func requireAPIRole(w http.ResponseWriter, r *http.Request, min string) bool {
+	if !hasOrgRole(r, min) {
+		respondError(w, http.StatusForbidden, errCodeForbidden, "This action requires the "+min+" role in the organization")
+		return false
+	}
+	return true
+}
+
+// appFromRequest loads the app named by the appId URL parameter. Apps of other
+// organizations are reported as not found so their IDs can't be probed.
+func (h *Handler) appFromRequest(w http.ResponseWriter, r *http.Request) (*db.App, bool) {
+	orgId, _ := orgFromContext(r)
+
+	appId, err := strconv.Atoi(chi.URLParam(r, "appId"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "Invalid app ID")
+		return nil, false
+	}
+
+	app, err := db.GetAppById(h.conn, appId)
+	if err == sql.ErrNoRows || (err == nil && app.OrgId != orgId) {
+		respondError(w, http.StatusNotFound, errCodeNotFound, "App not found")
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error fetching app %d: %v", appId, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to fetch app")
+		return nil, false
+	}
+	return app, true
+}
+
+// respondWithApp reloads an app after a change and writes it as {"app": ...}
+func (h *Handler) respondWithApp(w http.ResponseWriter, status, appId int) {
+	app, err := db.GetAppById(h.conn, appId)
+	if err != nil {
+		log.Printf("Error reloading app %d: %v", appId, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to fetch app")
+		return
+	}
+	respondJSON(w, status, AppResponse{App: app})
+}
+
+// ListAppsV1Handler returns all of the organization's apps with their current status
+func (h *Handler) ListAppsV1Handler(w http.ResponseWriter, r *http.Request) {
+	orgId, _ := orgFromContext(r)
+
+	apps, err := db.GetOrgAppsWithStatus(h.conn, orgId)
+	if err != nil {
+		log.Printf("Error fetching apps for org %d: %v", orgId, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to fetch apps")
+		return
+	}
+	if apps == nil {
+		apps = []db.AppWithStatus{}
+	}
+
+	respondJSON(w, http.StatusOK, AppListResponse{Apps: apps})
+}
+
+// GetAppV1Handler returns a single app
+func (h *Handler) GetAppV1Handler(w http.ResponseWriter, r *http.Request) {
+	app, ok := h.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+	respondJSON(w, http.StatusOK, AppResponse{App: app})
+}
+
+// CreateAppV1Handler creates an app within the organization's plan limit
+func (h *Handler) CreateAppV1Handler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+	orgId, _ := orgFromContext(r)
+	if !requireAPIRole(w, r, db.RoleEditor) {
+		return
+	}
+
+	var req CreateAppRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+
+	req.AppName = strings.TrimSpace(req.AppName)
+	if req.Theme == "" {
+		req.Theme = "cyberpunk"
+	}
+	if req.Alerts == "" {
+		req.Alerts = "n"
+	}
+	if msg := validateAppFields(&req.AppName, &req.Slug, &req.HealthUrl, &req.Theme, &req.Alerts, req.LogoURL); msg != "" {
+		respondError(w, http.StatusBadRequest, errCodeValidation, msg)
+		return
+	}
+	if req.LogoURL != nil && *req.LogoURL == "" {
+		req.LogoURL = nil
+	}
+	if req.LogoURL != nil && !h.canUseCustomLogo(orgId) {
+		respondError(w, http.StatusForbidden, errCodeForbidden, "Custom logos require Pro or Business plan")
+		return
+	}
+
+	plan, _ := db.GetOrgPlan(h.conn, orgId)
+	planLimit := db.GetPlanLimit(plan)
+	appCount, err := db.GetAppCount(h.conn, orgId)
+	if err != nil {
+		log.Printf("Error getting app count for org %d: %v", orgId, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to create app")
+		return
+	}
+	if appCount >= planLimit {
+		respondError(w, http.StatusForbidden, errCodePlanLimit,
+			fmt.Sprintf("You've reached your %s plan limit (%d apps)", plan, planLimit))
+		return
+	}
+
+	appId, err := db.CreateAppWithLogo(h.conn, orgId, userId, req.AppName, req.Slug, req.HealthUrl, req.Theme, req.Alerts, req.LogoURL)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate") {
+			respondError(w, http.StatusConflict, errCodeConflict, "An app with this slug or name already exists")
+			return
+		}
+		log.Printf("Error creating app for user %d: %v", userId, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to create app")
+		return
+	}
+
+	log.Printf("📦 App %d (%s) created via API by user %d in org %d", appId, req.Slug, userId, orgId)
+
+	if h.sslChecker != nil && strings.HasPrefix(req.HealthUrl, "https://") {
+		go h.sslChecker.CheckAppSSL(appId)
+	}
+
+	h.respondWithApp(w, http.StatusCreated, appId)
+}
+
+// UpdateAppV1Handler changes any of an app's settings. A new health URL is
+// checked on the next worker tick and its certificate is re-checked right away.
+func (h *Handler) UpdateAppV1Handler(w http.ResponseWriter, r *http.Request) {
+	app, ok := h.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if !requireAPIRole(w, r, db.RoleEditor) {
+		return
+	}
+
+	var req UpdateAppRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+
+	if req.AppName != nil {
+		trimmed := strings.TrimSpace(*req.AppName)
+		req.AppName = &trimmed
+	}
+	if msg := validateAppFields(req.AppName, req.Slug, req.HealthUrl, req.Theme, req.Alerts, req.LogoURL); msg != "" {
+		respondError(w, http.StatusBadRequest, errCodeValidation, msg)
+		return
+	}
+	if req.LogoURL != nil && *req.LogoURL != "" && !h.canUseCustomLogo(app.OrgId) {
+		respondError(w, http.StatusForbidden, errCodeForbidden, "Custom logos require Pro or Business plan")
+		return
+	}
+
+	update := db.AppUpdate{
+		AppName:   req.AppName,
+		Slug:      req.Slug,
+		HealthUrl: req.HealthUrl,
+		Theme:     req.Theme,
+		Alerts:    req.Alerts,
+		LogoURL:   req.LogoURL,
+	}
+	healthUrlChanged := req.HealthUrl != nil && *req.HealthUrl != app.HealthUrl
+	if !healthUrlChanged {
+		update.HealthUrl = nil
+	}
+
+	updated, err := db.UpdateApp(h.conn, app.Id, app.OrgId, update)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate") {
+			respondError(w, http.StatusConflict, errCodeConflict, "An app with this slug or name already exists")
+			return
+		}
+		log.Printf("Error updating app %d: %v", app.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to update app")
+		return
+	}
+	if !updated {
+		respondError(w, http.StatusNotFound, errCodeNotFound, "App not found")
+		return
+	}
+
+	after := *app
+	applyAppUpdate(&after, update)
+	h.audit(r, auditEvent{
+		Action:     AuditAppUpdate,
+		TargetType: "app",
+		TargetID:   app.Id,
+		OrgID:      app.OrgId,
+		Before:     appAuditValues(*app),
+		After:      appAuditValues(after),
+	})
+
+	if healthUrlChanged {
+		h.healthUrlChanged(app.Id, *req.HealthUrl)
+	}
+
+	h.respondWithApp(w, http.StatusOK, app.Id)
+}
+
+// healthUrlChanged re-checks the certificate of an app that has a new health URL
+func (h *Handler) healthUrlChanged(appId int, healthUrl string) {
+	log.Printf("🔗 Health URL of app %d changed to %s", appId, healthUrl)
+	if strings.HasPrefix(healthUrl, "https://") {
+		if h.sslChecker != nil {
+			go h.sslChecker.CheckAppSSL(appId)
+		}
+	} else if err := db.UpdateSSLInfo(h.conn, appId, nil, nil, nil); err != nil {
+		// The old certificate no longer applies to a plain HTTP URL
+		log.Printf("⚠️ Error clearing SSL info for app %d: %v", appId, err)
+	}
+}
+
+// DeleteAppV1Handler deletes an app and its history
+func (h *Handler) DeleteAppV1Handler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	app, ok := h.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if !requireAPIRole(w, r, db.RoleEditor) {
+		return
+	}
+
+	if err := db.DeleteApp(h.conn, app.Id, app.OrgId); err != nil {
+		log.Printf("Error deleting app %d: %v", app.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to delete app")
+		return
+	}
+
+	h.audit(r, auditEvent{
+		Action:     AuditAppDelete,
+		TargetType: "app",
+		TargetID:   app.Id,
+		OrgID:      app.OrgId,
+		Before:     appAuditValues(*app),
+	})
+	log.Printf("🗑️ App %d (%s) deleted via API by user %d", app.Id, app.Slug, userId)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PauseAppV1Handler stops health checks for an app without deleting it
+func (h *Handler) PauseAppV1Handler(w http.ResponseWriter, r *http.Request) {
+	h.setAppPaused(w, r, true)
+}
+
+// ResumeAppV1Handler restarts health checks for a paused app
+func (h *Handler) ResumeAppV1Handler(w http.ResponseWriter, r *http.Request) {
+	h.setAppPaused(w, r, false)
+}
+
+func (h *Handler) setAppPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	userId := r.Context().Value("userId").(int)
+
+	app, ok := h.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if !requireAPIRole(w, r, db.RoleEditor) {
+		return
+	}
+
+	if app.Paused != paused {
+		via := pauseVia(r)
+		changed, err := db.SetAppPaused(h.conn, app.Id, app.OrgId, paused, &userId, via)
+		if errors.Is(err, db.ErrAppLocked) {
+			respondError(w, http.StatusForbidden, errCodeForbidden, "An admin paused this app; only an admin can resume it")
+			return
+		}
+		if err != nil {
+			log.Printf("Error setting paused=%t on app %d: %v", paused, app.Id, err)
+			respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to update app")
+			return
+		}
+		if changed && paused {
+			log.Printf("⏸️ App %d (%s) paused by user %d via %s", app.Id, app.Slug, userId, via)
+		} else if changed {
+			log.Printf("▶️ App %d (%s) resumed by user %d via %s", app.Id, app.Slug, userId, via)
+		}
+	}
+
+	h.respondWithApp(w, http.StatusOK, app.Id)
+}
+
+// pauseVia is how a pause or resume in this request is recorded
+func pauseVia(r *http.Request) string {
+	if method, _ := r.Context().Value("authMethod").(string); method == auth.AuthMethodAPIKey {
+		return db.PauseViaAPIKey
+	}
+	return db.PauseViaSession
+}
+
+// maxPauseEvents caps how much pause history is returned at once
+const maxPauseEvents = 100
+
+// GetAppPauseEventsV1Handler returns who paused and resumed an app and when
+func (h *Handler) GetAppPauseEventsV1Handler(w http.ResponseWriter, r *http.Request) {
+	app, ok := h.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	events, err := db.GetAppPauseEvents(h.conn, app.Id, maxPauseEvents)
+	if err != nil {
+		log.Printf("Error fetching pause events for app %d: %v", app.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to fetch pause history")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, PauseEventListResponse{Events: events})
+}
+
+// Caps on how much check history is returned at once
+const (
+	defaultChecks = 100
+	maxChecks     = 500
+	maxIncidents  = 100
+)
+
+// GetAppChecksV1Handler returns an app's latest check results, oldest first. Passing
+// the ID of the last one seen as ?after= returns only newer checks, for polling.
+func (h *Handler) GetAppChecksV1Handler(w http.ResponseWriter, r *http.Request) {
+	app, ok := h.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	limit := defaultChecks
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "limit must be a positive number")
+			return
+		}
+		limit = min(n, maxChecks)
+	}
+	after := 0
+	if raw := r.URL.Query().Get("after"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "after must be a check ID")
+			return
+		}
+		after = n
+	}
+
+	checks, err := db.GetAppChecks(h.conn, app.Id, after, limit)
+	if err != nil {
+		log.Printf("Error fetching checks for app %d: %v", app.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to fetch checks")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, CheckListResponse{Checks: checks})
+}
+
+// GetAppIncidentsV1Handler returns an app's incidents within the plan's data retention, newest first
+func (h *Handler) GetAppIncidentsV1Handler(w http.ResponseWriter, r *http.Request) {
+	app, ok := h.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	plan, _ := db.GetOrgPlan(h.conn, app.OrgId)
+	incidents, err := db.GetAppIncidents(h.conn, app.Id, db.GetPlanFeatures(plan).DataRetentionDays, maxIncidents)
+	if err != nil {
+		log.Printf("Error fetching incidents for app %d: %v", app.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to fetch incidents")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, IncidentListResponse{Incidents: incidents})
+}
+
+// CheckAppSSLV1Handler re-checks an app's certificate now rather than on the daily
+// run. The check runs in the background and shows up in the app's SSL fields.
+func (h *Handler) CheckAppSSLV1Handler(w http.ResponseWriter, r *http.Request) {
+	app, ok := h.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if !requireAPIRole(w, r, db.RoleEditor) {
+		return
+	}
+
+	if plan, _ := db.GetOrgPlan(h.conn, app.OrgId); plan != "pro" && plan != "business" {
+		respondError(w, http.StatusForbidden, errCodeForbidden, "SSL monitoring requires Pro or Business plan")
+		return
+	}
+	if !strings.HasPrefix(app.HealthUrl, "https://") {
+		respondError(w, http.StatusBadRequest, errCodeValidation, "Only apps with an https:// health URL have a certificate to check")
+		return
+	}
+	if h.sslChecker == nil {
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "SSL checks are not available")
+		return
+	}
+
+	go h.sslChecker.CheckAppSSL(app.Id)
+	respondJSON(w, http.StatusAccepted, SuccessResponse{Success: true, Message: "SSL check started"})
+}
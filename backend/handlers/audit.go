@@ -0,0 +1,349 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"statusframe/backend/utils"
+	"statusframe/db"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Audit log actions
+const (
+	AuditPlanChange       = "admin.plan_change"
+	AuditGrantAdmin       = "admin.grant_admin"
+	AuditRevokeAdmin      = "admin.revoke_admin"
+	AuditDisableUser      = "admin.disable_user"
+	AuditEnableUser       = "admin.enable_user"
+	AuditImpersonateStart = "admin.impersonate_start"
+	AuditImpersonateStop  = "admin.impersonate_stop"
+	AuditForcePause       = "admin.force_pause"
+	AuditForceResume      = "admin.force_resume"
+	AuditDisableProbe     = "admin.disable_probe"
+	AuditEnableProbe      = "admin.enable_probe"
+
+	AuditAppUpdate           = "app.update"
+	AuditAppThemeChange      = "app.theme_change"
+	AuditAppDelete           = "app.delete"
+	AuditAppVisibility       = "app.visibility_change"
+	AuditAppShareLink        = "app.share_link_create"
+	AuditAppShareLinksRevoke = "app.share_links_revoke"
+	AuditAppPublicFields     = "app.public_fields_change"
+	AuditConfigApply         = "config.apply"
+	AuditSlackConnect        = "slack.connect"
+	AuditSlackDisable        = "slack.disable"
+	AuditDiscordConnect      = "discord.connect"
+	AuditDiscordWebhook      = "discord.webhook_change"
+	AuditDiscordDisable      = "discord.disable"
+	AuditBillingPlanChange   = "billing.plan_change"
+	AuditStatusPageCreate    = "status_page.create"
+	AuditStatusPageUpdate    = "status_page.update"
+	AuditStatusPageDelete    = "status_page.delete"
+	AuditStatusPageDomain    = "status_page.domain_change"
+
+	AuditIncidentUpdatePost   = "incident.update_post"
+	AuditIncidentUpdateDelete = "incident.update_delete"
+
+	AuditTwoFactorEnable         = "user.2fa_enable"
+	AuditTwoFactorDisable        = "user.2fa_disable"
+	AuditRecoveryCodesRegenerate = "user.recovery_codes_regenerate"
+	AuditOrgRequire2FA           = "org.require_2fa"
+)
+
+// auditEvent describes one change for the audit log
+type auditEvent struct {
+	Action     string
+	TargetType string
+	TargetID   int
+	OrgID      int         // 0 when the change isn't tied to an organization
+	Before     interface{} // nil when there was nothing before, like a new integration
+	After      interface{} // nil when nothing is left, like a deleted app
+	Details    map[string]interface{}
+}
+
+// audit records a change made by the user in the request context. A failed write is
+// logged but doesn't fail the request, since the change itself already happened.
+func (h *Handler) audit(r *http.Request, ev auditEvent) {
+	var actor *int
+	if userId, ok := r.Context().Value("userId").(int); ok {
+		actor = &userId
+	}
+	h.writeAudit(actor, h.clientIP(r), ev)
+}
+
+// writeAudit records a change by actor, which is nil for changes nobody made directly
+// like Stripe webhooks
+func (h *Handler) writeAudit(actor *int, ip string, ev auditEvent) {
+	entry := db.AuditEntry{
+		ActorUserID: actor,
+		Action:      ev.Action,
+		TargetType:  ev.TargetType,
+		TargetID:    &ev.TargetID,
+		IPAddress:   ip,
+		Before:      auditJSON(ev.Action, ev.Before),
+		After:       auditJSON(ev.Action, ev.After),
+		Details:     auditJSON(ev.Action, ev.Details),
+	}
+	if ev.OrgID != 0 {
+		entry.OrgID = &ev.OrgID
+	}
+
+	if err := db.InsertAuditEntry(h.conn, entry); err != nil {
+		log.Printf("⚠️ Error writing audit log entry %s on %s %d: %v", ev.Action, ev.TargetType, ev.TargetID, err)
+	}
+}
+
+// auditJSON encodes a value for the log, leaving nil values empty
+func auditJSON(action string, v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	if m, ok := v.(map[string]interface{}); ok && m == nil {
+		return nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("⚠️ Error encoding audit values for %s: %v", action, err)
+		return nil
+	}
+	return raw
+}
+
+// appAuditValues is what the log keeps of an app before and after a change
+func appAuditValues(app db.App) map[string]interface{} {
+	return map[string]interface{}{
+		"app_name":   app.AppName,
+		"slug":       app.Slug,
+		"health_url": app.HealthUrl,
+		"theme":      app.Theme,
+		"alerts":     app.Alerts,
+		"logo_url":   app.LogoURL,
+	}
+}
+
+// applyAppUpdate sets the fields of a partial update on app
+func applyAppUpdate(app *db.App, upd db.AppUpdate) {
+	if upd.AppName != nil {
+		app.AppName = *upd.AppName
+	}
+	if upd.Slug != nil {
+		app.Slug = *upd.Slug
+	}
+	if upd.HealthUrl != nil {
+		app.HealthUrl = *upd.HealthUrl
+	}
+	if upd.Theme != nil {
+		app.Theme = *upd.Theme
+	}
+	if upd.Alerts != nil {
+		app.Alerts = *upd.Alerts
+	}
+	if upd.LogoURL != nil && *upd.LogoURL == "" {
+		app.LogoURL = nil
+	} else if upd.LogoURL != nil {
+		app.LogoURL = upd.LogoURL
+	}
+}
+
+// statusPageAuditValues is what the log keeps of a status page: its settings and
+// the apps it shows
+func statusPageAuditValues(page *db.StatusPage) map[string]interface{} {
+	sections := []string{}
+	for _, s := range page.Sections {
+		sections = append(sections, s.Name)
+	}
+	return map[string]interface{}{
+		"slug":     page.Slug,
+		"title":    page.Title,
+		"theme":    page.Theme,
+		"logo_url": page.LogoURL,
+		"sections": sections,
+		"app_ids":  page.AppIds(),
+	}
+}
+
+// incidentUpdateAuditValues is what the log keeps of an incident update
+func incidentUpdateAuditValues(u *db.IncidentUpdate) map[string]interface{} {
+	return map[string]interface{}{
+		"app_id":  u.AppId,
+		"status":  u.Status,
+		"message": u.Message,
+	}
+}
+
+// visibilityAuditValues is what the log keeps of a page's visibility. The password
+// and the key that signs share links are left out.
+func visibilityAuditValues(a *db.AppAccess) map[string]interface{} {
+	return map[string]interface{}{
+		"visibility":   a.Visibility,
+		"ip_allowlist": a.IPAllowlist,
+		"password_set": a.PasswordHash != "",
+	}
+}
+
+// slackAuditValues is what the log keeps of a Slack integration. The bot token is left out.
+func slackAuditValues(i *db.SlackIntegration) map[string]interface{} {
+	if i == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"team_name":    i.SlackTeamName,
+		"channel_name": i.SlackChannelName,
+		"is_enabled":   i.IsEnabled,
+	}
+}
+
+// discordAuditValues is what the log keeps of a Discord integration. The webhook URL is left out.
+func discordAuditValues(i *db.DiscordIntegration) map[string]interface{} {
+	if i == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"server_name":  i.ServerName,
+		"channel_name": i.ChannelName,
+		"is_enabled":   i.IsEnabled,
+	}
+}
+
+// clientIP is the address the request came from as recorded in the audit log
+func (h *Handler) clientIP(r *http.Request) string {
+	return utils.ClientIPString(r, h.cfg.Server.TrustedProxies)
+}
+
+// GetAuditLogHandler returns the audit log of the active organization. Organization
+// admins see every entry; other members only see the changes they made themselves.
+func (h *Handler) GetAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	filter, ok := auditFilterFromQuery(w, r)
+	if !ok {
+		return
+	}
+	filter.OrgID, _ = orgFromContext(r)
+	if !hasOrgRole(r, db.RoleAdmin) {
+		filter.ActorUserID = r.Context().Value("userId").(int)
+	}
+
+	h.respondAuditLog(w, r, filter, fmt.Sprintf("audit-log-org-%d", filter.OrgID))
+}
+
+// GetPersonalAuditLogHandler returns the changes the user made to their own account,
+// like turning two-factor authentication on or off, which belong to no organization
+func (h *Handler) GetPersonalAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	filter, ok := auditFilterFromQuery(w, r)
+	if !ok {
+		return
+	}
+	filter.ActorUserID = r.Context().Value("userId").(int)
+	filter.Personal = true
+
+	h.respondAuditLog(w, r, filter, fmt.Sprintf("audit-log-user-%d", filter.ActorUserID))
+}
+
+// AdminGetAuditLogHandler returns the audit log of every organization and admin action.
+// It can be narrowed down with ?org_id=, ?actor_id= and ?action=.
+func (h *Handler) AdminGetAuditLogHandler(w http.ResponseWriter, r *http.Request) {
+	filter, ok := auditFilterFromQuery(w, r)
+	if !ok {
+		return
+	}
+
+	for param, dest := range map[string]*int{"org_id": &filter.OrgID, "actor_id": &filter.ActorUserID} {
+		if raw := r.URL.Query().Get(param); raw != "" {
+			id, err := strconv.Atoi(raw)
+			if err != nil {
+				http.Error(w, "Invalid "+param, http.StatusBadRequest)
+				return
+			}
+			*dest = id
+		}
+	}
+
+	h.respondAuditLog(w, r, filter, "audit-log")
+}
+
+// auditFilterFromQuery reads ?limit= and ?action=. CSV exports default to a larger limit.
+func auditFilterFromQuery(w http.ResponseWriter, r *http.Request) (db.AuditFilter, bool) {
+	filter := db.AuditFilter{Limit: 100, Action: r.URL.Query().Get("action")}
+	maxLimit := 1000
+	if r.URL.Query().Get("format") == "csv" {
+		filter.Limit = 10000
+		maxLimit = 10000
+	}
+
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxLimit), http.StatusBadRequest)
+			return filter, false
+		}
+		filter.Limit = n
+	}
+	return filter, true
+}
+
+// respondAuditLog writes the matching entries as JSON, or as a CSV download with ?format=csv
+func (h *Handler) respondAuditLog(w http.ResponseWriter, r *http.Request, filter db.AuditFilter, filename string) {
+	entries, err := db.GetAuditLog(h.conn, filter)
+	if err != nil {
+		log.Printf("Error fetching audit log: %v", err)
+		http.Error(w, "Failed to fetch audit log", http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") != "csv" {
+		respondJSON(w, http.StatusOK, entries)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.csv"`, filename, time.Now().UTC().Format("2006-01-02")))
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "created_at", "actor_user_id", "actor_name", "action", "target_type", "target_id", "org_id", "before", "after", "details", "ip_address"})
+	for _, e := range entries {
+		cw.Write([]string{
+			strconv.FormatInt(e.ID, 10),
+			e.CreatedAt.UTC().Format(time.RFC3339),
+			optionalInt(e.ActorUserID),
+			csvSafe(optionalString(e.ActorName)),
+			e.Action,
+			e.TargetType,
+			optionalInt(e.TargetID),
+			optionalInt(e.OrgID),
+			string(e.Before),
+			string(e.After),
+			string(e.Details),
+			e.IPAddress,
+		})
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Printf("Error writing audit log CSV: %v", err)
+	}
+}
+
+func optionalInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+func optionalString(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
+
+// csvSafe keeps spreadsheet apps from running user-chosen names as formulas
+func csvSafe(v string) string {
+	if v != "" && strings.ContainsAny(v[:1], "=+-@") {
+		return "'" + v
+	}
+	return v
+}
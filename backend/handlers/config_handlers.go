@@ -0,0 +1,568 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"statusframe/db"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// An organization's monitors can be described in one YAML or JSON file, kept in version
+// control and applied like Terraform: a dry run shows what would change, then the same
+// file is applied for real. Applying a file twice changes nothing the second time.
+
+const (
+	monitorConfigVersion  = 1
+	maxMonitorConfigSize  = 1 << 20
+	maxCheckInterval      = 24 * 60 * 60
+	maxMaintenanceWindows = 20
+)
+
+// MonitorConfig is the declarative configuration of an organization's apps
+type MonitorConfig struct {
+	Version int           `json:"version" yaml:"version"`
+	Apps    []MonitorSpec `json:"apps" yaml:"apps"`
+}
+
+// MonitorSpec describes one app. Apps are matched by slug, and optional fields that are
+// left out mean their default rather than "leave unchanged".
+type MonitorSpec struct {
+	Slug        string            `json:"slug" yaml:"slug"`
+	AppName     string            `json:"app_name" yaml:"app_name"`
+	HealthUrl   string            `json:"health_url" yaml:"health_url"`
+	Theme       string            `json:"theme,omitempty" yaml:"theme,omitempty"` // defaults to cyberpunk
+	LogoURL     string            `json:"logo_url,omitempty" yaml:"logo_url,omitempty"`
+	Paused      bool              `json:"paused,omitempty" yaml:"paused,omitempty"`
+	Check       *CheckSpec        `json:"check,omitempty" yaml:"check,omitempty"`
+	Alerts      *AlertSpec        `json:"alerts,omitempty" yaml:"alerts,omitempty"`
+	Maintenance []MaintenanceSpec `json:"maintenance,omitempty" yaml:"maintenance,omitempty"`
+}
+
+// CheckSpec sets how often an app is checked
+type CheckSpec struct {
+	Interval int `json:"interval,omitempty" yaml:"interval,omitempty"` // seconds; 0 checks as often as the plan allows
+}
+
+// AlertSpec routes an app's incident alerts. Without it alerts go to every connected
+// integration; an empty list of channels turns them off.
+type AlertSpec struct {
+	Channels []string `json:"channels" yaml:"channels"`
+}
+
+// MaintenanceSpec is planned downtime during which no alerts are sent. Windows that
+// have already ended are ignored.
+type MaintenanceSpec struct {
+	StartsAt time.Time `json:"starts_at" yaml:"starts_at"`
+	EndsAt   time.Time `json:"ends_at" yaml:"ends_at"`
+	Reason   string    `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// Actions of a ConfigChange
+const (
+	configCreate = "create"
+	configUpdate = "update"
+	configDelete = "delete"
+)
+
+// normalize fills in defaults and puts the optional fields in one canonical form, so
+// that a spec read from a file compares equal to the same spec read from the database
+func (s MonitorSpec) normalize(now time.Time) MonitorSpec {
+	s.AppName = strings.TrimSpace(s.AppName)
+	if s.Theme == "" {
+		s.Theme = "cyberpunk"
+	}
+	if s.Check != nil && s.Check.Interval == 0 {
+		s.Check = nil
+	}
+	if s.Alerts != nil {
+		if s.Alerts.Channels == nil {
+			s.Alerts = nil
+		} else {
+			channels := append([]string{}, s.Alerts.Channels...)
+			sort.Strings(channels)
+			s.Alerts = &AlertSpec{Channels: channels}
+		}
+	}
+
+	var windows []MaintenanceSpec
+	for _, m := range s.Maintenance {
+		if !m.EndsAt.After(now) {
+			continue
+		}
+		// Postgres keeps microseconds
+		m.StartsAt = m.StartsAt.UTC().Truncate(time.Microsecond)
+		m.EndsAt = m.EndsAt.UTC().Truncate(time.Microsecond)
+		windows = append(windows, m)
+	}
+	sort.SliceStable(windows, func(i, j int) bool { return windows[i].StartsAt.Before(windows[j].StartsAt) })
+	s.Maintenance = windows
+	return s
+}
+
+func (s MonitorSpec) checkSettings() db.AppCheckSettings {
+	var settings db.AppCheckSettings
+	if s.Check != nil {
+		interval := s.Check.Interval
+		settings.CheckInterval = &interval
+	}
+	if s.Alerts != nil {
+		settings.AlertChannels = s.Alerts.Channels
+	}
+	return settings
+}
+
+func (s MonitorSpec) maintenanceWindows() []db.MaintenanceWindow {
+	var windows []db.MaintenanceWindow
+	for _, m := range s.Maintenance {
+		windows = append(windows, db.MaintenanceWindow{StartsAt: m.StartsAt, EndsAt: m.EndsAt, Reason: m.Reason})
+	}
+	return windows
+}
+
+// configFields lists the values a diff compares, in the order they are shown
+func (s MonitorSpec) configFields() []configField {
+	var interval, channels interface{}
+	if s.Check != nil {
+		interval = s.Check.Interval
+	}
+	if s.Alerts != nil {
+		channels = s.Alerts.Channels
+	}
+	var maintenance interface{}
+	if s.Maintenance != nil {
+		maintenance = s.Maintenance
+	}
+	return []configField{
+		{"app_name", s.AppName},
+		{"health_url", s.HealthUrl},
+		{"theme", s.Theme},
+		{"logo_url", s.LogoURL},
+		{"paused", s.Paused},
+		{"check.interval", interval},
+		{"alerts.channels", channels},
+		{"maintenance", maintenance},
+	}
+}
+
+type configField struct {
+	name  string
+	value interface{}
+}
+
+// diffMonitorSpecs returns the fields that differ between two normalized specs.
+// A nil from is a new app.
+func diffMonitorSpecs(from *MonitorSpec, to MonitorSpec) map[string]ConfigFieldChange {
+	diff := map[string]ConfigFieldChange{}
+	toFields := to.configFields()
+	if from == nil {
+		for _, f := range toFields {
+			diff[f.name] = ConfigFieldChange{To: f.value}
+		}
+		return diff
+	}
+	for i, f := range from.configFields() {
+		// Comparing the JSON encoding compares times by instant and nil with nil
+		a, _ := json.Marshal(f.value)
+		b, _ := json.Marshal(toFields[i].value)
+		if !bytes.Equal(a, b) {
+			diff[f.name] = ConfigFieldChange{From: f.value, To: toFields[i].value}
+		}
+	}
+	return diff
+}
+
+// validateMonitorConfig returns everything wrong with a configuration, each problem
+// prefixed with where it is, like "apps[2].check.interval"
+func validateMonitorConfig(cfg MonitorConfig, plan string, canUseLogo bool) []string {
+	var problems []string
+	if cfg.Version != monitorConfigVersion {
+		problems = append(problems, fmt.Sprintf("version: must be %d", monitorConfigVersion))
+	}
+
+	slugs := map[string]int{}
+	for i, spec := range cfg.Apps {
+		at := fmt.Sprintf("apps[%d]", i)
+		theme := spec.Theme
+		if theme == "" {
+			theme = "cyberpunk"
+		}
+		appName := strings.TrimSpace(spec.AppName)
+		if msg := validateAppFields(&appName, &spec.Slug, &spec.HealthUrl, &theme, nil, &spec.LogoURL); msg != "" {
+			problems = append(problems, at+": "+msg)
+		}
+		if first, ok := slugs[spec.Slug]; ok {
+			problems = append(problems, fmt.Sprintf("%s.slug: %q is already used by apps[%d]", at, spec.Slug, first))
+		} else {
+			slugs[spec.Slug] = i
+		}
+		if spec.LogoURL != "" && !canUseLogo {
+			problems = append(problems, at+".logo_url: custom logos require Pro or Business plan")
+		}
+
+		if spec.Check != nil && spec.Check.Interval != 0 {
+			if err := db.ValidateCheckInterval(plan, spec.Check.Interval); err != nil {
+				problems = append(problems, at+".check.interval: "+err.Error())
+			} else if spec.Check.Interval > maxCheckInterval {
+				problems = append(problems, fmt.Sprintf("%s.check.interval: must be at most %d seconds", at, maxCheckInterval))
+			}
+		}
+
+		if spec.Alerts != nil {
+			seen := map[string]bool{}
+			for j, channel := range spec.Alerts.Channels {
+				if !containsString(db.AlertChannels, channel) {
+					problems = append(problems, fmt.Sprintf("%s.alerts.channels[%d]: must be one of: %s", at, j, strings.Join(db.AlertChannels, ", ")))
+				} else if seen[channel] {
+					problems = append(problems, fmt.Sprintf("%s.alerts.channels[%d]: %s is listed twice", at, j, channel))
+				}
+				seen[channel] = true
+			}
+		}
+
+		if len(spec.Maintenance) > maxMaintenanceWindows {
+			problems = append(problems, fmt.Sprintf("%s.maintenance: at most %d windows are allowed", at, maxMaintenanceWindows))
+		}
+		for j, m := range spec.Maintenance {
+			if m.StartsAt.IsZero() || m.EndsAt.IsZero() {
+				problems = append(problems, fmt.Sprintf("%s.maintenance[%d]: starts_at and ends_at are required", at, j))
+			} else if !m.EndsAt.After(m.StartsAt) {
+				problems = append(problems, fmt.Sprintf("%s.maintenance[%d]: ends_at must be after starts_at", at, j))
+			}
+		}
+	}
+	return problems
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// currentMonitorConfig reads the organization's apps as specs, keyed by slug
+func (h *Handler) currentMonitorConfig(orgId int) (map[string]db.App, map[string]MonitorSpec, error) {
+	apps, err := db.GetOrgApps(h.conn, orgId)
+	if err != nil {
+		return nil, nil, err
+	}
+	settings, err := db.GetOrgAppCheckSettings(h.conn, orgId)
+	if err != nil {
+		return nil, nil, err
+	}
+	windows, err := db.GetOrgMaintenanceWindows(h.conn, orgId)
+	if err != nil {
+		return nil, nil, err
+	}
+	windowsByApp := map[int][]MaintenanceSpec{}
+	for _, w := range windows {
+		windowsByApp[w.AppID] = append(windowsByApp[w.AppID], MaintenanceSpec{StartsAt: w.StartsAt, EndsAt: w.EndsAt, Reason: w.Reason})
+	}
+
+	now := time.Now()
+	appsBySlug := map[string]db.App{}
+	specs := map[string]MonitorSpec{}
+	for _, app := range apps {
+		spec := MonitorSpec{
+			Slug:        app.Slug,
+			AppName:     app.AppName,
+			HealthUrl:   app.HealthUrl,
+			Theme:       app.Theme,
+			Paused:      app.Paused,
+			Maintenance: windowsByApp[app.Id],
+		}
+		if app.LogoURL != nil {
+			spec.LogoURL = *app.LogoURL
+		}
+		s := settings[app.Id]
+		if s.CheckInterval != nil {
+			spec.Check = &CheckSpec{Interval: *s.CheckInterval}
+		}
+		if s.AlertChannels != nil {
+			spec.Alerts = &AlertSpec{Channels: s.AlertChannels}
+		}
+		appsBySlug[app.Slug] = app
+		specs[app.Slug] = spec.normalize(now)
+	}
+	return appsBySlug, specs, nil
+}
+
+// ExportConfigV1Handler returns the organization's apps as a configuration file that
+// applies without changes. ?format=yaml returns YAML instead of JSON.
+func (h *Handler) ExportConfigV1Handler(w http.ResponseWriter, r *http.Request) {
+	orgId, _ := orgFromContext(r)
+
+	_, specs, err := h.currentMonitorConfig(orgId)
+	if err != nil {
+		log.Printf("Error exporting configuration of org %d: %v", orgId, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to export configuration")
+		return
+	}
+
+	cfg := MonitorConfig{Version: monitorConfigVersion, Apps: []MonitorSpec{}}
+	for _, spec := range specs {
+		cfg.Apps = append(cfg.Apps, spec)
+	}
+	sort.Slice(cfg.Apps, func(i, j int) bool { return cfg.Apps[i].Slug < cfg.Apps[j].Slug })
+
+	if r.URL.Query().Get("format") != "yaml" {
+		respondJSON(w, http.StatusOK, cfg)
+		return
+	}
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		log.Printf("Error encoding configuration of org %d: %v", orgId, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to export configuration")
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(out)
+}
+
+// ApplyConfigV1Handler makes the organization's apps match a configuration file sent as
+// YAML, or as JSON with a JSON content type. Everything is validated before anything
+// changes. ?dry_run=true only returns the changes, and ?prune=true also deletes apps the
+// file doesn't mention. The changes are made in one transaction, so either all of them
+// are applied or, when one fails, none are.
+func (h *Handler) ApplyConfigV1Handler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+	orgId, _ := orgFromContext(r)
+	if !requireAPIRole(w, r, db.RoleEditor) {
+		return
+	}
+
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	prune := r.URL.Query().Get("prune") == "true"
+
+	var cfg MonitorConfig
+	r.Body = http.MaxBytesReader(w, r.Body, maxMonitorConfigSize)
+	if strings.Contains(r.Header.Get("Content-Type"), "json") {
+		if !decodeAPIRequest(w, r, &cfg) {
+			return
+		}
+	} else {
+		decoder := yaml.NewDecoder(r.Body)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&cfg); err != nil {
+			respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "Invalid configuration: "+err.Error())
+			return
+		}
+	}
+
+	plan, _ := db.GetOrgPlan(h.conn, orgId)
+	if problems := validateMonitorConfig(cfg, plan, planAllowsCustomBranding(plan)); len(problems) > 0 {
+		respondError(w, http.StatusBadRequest, errCodeValidation, strings.Join(problems, "; "))
+		return
+	}
+
+	apps, current, err := h.currentMonitorConfig(orgId)
+	if err != nil {
+		log.Printf("Error reading configuration of org %d: %v", orgId, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to read current configuration")
+		return
+	}
+
+	// Deletes go first to make room under the plan limit, then updates, then creates
+	now := time.Now()
+	desired := map[string]MonitorSpec{}
+	var deletes, updates, creates []ConfigChange
+	for _, spec := range cfg.Apps {
+		spec = spec.normalize(now)
+		desired[spec.Slug] = spec
+		if existing, ok := current[spec.Slug]; ok {
+			if diff := diffMonitorSpecs(&existing, spec); len(diff) > 0 {
+				updates = append(updates, ConfigChange{Action: configUpdate, Slug: spec.Slug, Diff: diff})
+			}
+			continue
+		}
+
+		// Slugs are unique across organizations
+		if _, err := db.GetAppBySlug(h.conn, spec.Slug); err == nil {
+			respondError(w, http.StatusConflict, errCodeConflict, fmt.Sprintf("The slug %q is already taken", spec.Slug))
+			return
+		} else if err != sql.ErrNoRows {
+			log.Printf("Error looking up slug %s: %v", spec.Slug, err)
+			respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to read current configuration")
+			return
+		}
+		creates = append(creates, ConfigChange{Action: configCreate, Slug: spec.Slug, Diff: diffMonitorSpecs(nil, spec)})
+	}
+	if prune {
+		for slug := range current {
+			if _, ok := desired[slug]; !ok {
+				deletes = append(deletes, ConfigChange{Action: configDelete, Slug: slug})
+			}
+		}
+	}
+	bySlug := func(changes []ConfigChange) {
+		sort.Slice(changes, func(i, j int) bool { return changes[i].Slug < changes[j].Slug })
+	}
+	bySlug(deletes)
+	bySlug(updates)
+	changes := append(append(append([]ConfigChange{}, deletes...), updates...), creates...)
+
+	planLimit := db.GetPlanLimit(plan)
+	if total := len(current) - len(deletes) + len(creates); len(creates) > 0 && total > planLimit {
+		respondError(w, http.StatusForbidden, errCodePlanLimit,
+			fmt.Sprintf("This configuration has %d apps but your %s plan allows %d", total, plan, planLimit))
+		return
+	}
+
+	if dryRun || len(changes) == 0 {
+		respondJSON(w, http.StatusOK, ConfigApplyResponse{DryRun: dryRun, Changes: changes})
+		return
+	}
+
+	tx, err := h.conn.Begin()
+	if err != nil {
+		log.Printf("Error starting configuration apply in org %d: %v", orgId, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to apply configuration")
+		return
+	}
+	defer tx.Rollback()
+
+	appIds := make([]int, len(changes))
+	for i, change := range changes {
+		var app *db.App
+		if existing, ok := apps[change.Slug]; ok {
+			app = &existing
+		}
+		appId, err := applyConfigChange(tx, r, userId, orgId, app, desired[change.Slug], change)
+		if err != nil {
+			if errors.Is(err, db.ErrAppLocked) {
+				respondError(w, http.StatusForbidden, errCodeForbidden,
+					fmt.Sprintf("Could not update %s: an admin paused it and only an admin can resume it", change.Slug))
+				return
+			}
+			if strings.Contains(err.Error(), "duplicate") {
+				respondError(w, http.StatusConflict, errCodeConflict,
+					fmt.Sprintf("Could not %s %s: an app with this slug or name already exists", change.Action, change.Slug))
+				return
+			}
+			log.Printf("Error applying %s of %s in org %d: %v", change.Action, change.Slug, orgId, err)
+			respondError(w, http.StatusInternalServerError, errCodeInternal,
+				fmt.Sprintf("Failed to %s %s; no changes were applied", change.Action, change.Slug))
+			return
+		}
+		appIds[i] = appId
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing configuration apply in org %d: %v", orgId, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to apply configuration; no changes were applied")
+		return
+	}
+
+	// Checks of new health URLs only start once the apps are committed
+	for i, change := range changes {
+		spec := desired[change.Slug]
+		_, healthUrlChanged := change.Diff["health_url"]
+		switch {
+		case change.Action == configCreate:
+			log.Printf("📦 App %d (%s) created from configuration by user %d in org %d", appIds[i], spec.Slug, userId, orgId)
+			if h.sslChecker != nil && strings.HasPrefix(spec.HealthUrl, "https://") {
+				go h.sslChecker.CheckAppSSL(appIds[i])
+			}
+		case change.Action == configUpdate && healthUrlChanged:
+			h.healthUrlChanged(appIds[i], spec.HealthUrl)
+		}
+	}
+
+	h.auditConfigApply(r, orgId, prune, changes)
+	log.Printf("📋 Configuration applied to org %d by user %d: %d changes", orgId, userId, len(changes))
+	respondJSON(w, http.StatusOK, ConfigApplyResponse{DryRun: false, Changes: changes})
+}
+
+// applyConfigChange makes one app match its spec within tx and returns its id. app is
+// nil for a create.
+func applyConfigChange(tx *sql.Tx, r *http.Request, userId, orgId int, app *db.App, spec MonitorSpec, change ConfigChange) (int, error) {
+	if change.Action == configDelete {
+		return app.Id, db.DeleteApp(tx, app.Id, orgId)
+	}
+
+	changed := func(field string) bool {
+		_, ok := change.Diff[field]
+		return ok
+	}
+
+	var appId int
+	if change.Action == configCreate {
+		var logoURL *string
+		if spec.LogoURL != "" {
+			logoURL = &spec.LogoURL
+		}
+		id, err := db.CreateAppWithLogo(tx, orgId, userId, spec.AppName, spec.Slug, spec.HealthUrl, spec.Theme, "n", logoURL)
+		if err != nil {
+			return 0, err
+		}
+		appId = id
+	} else {
+		appId = app.Id
+		var update db.AppUpdate
+		if changed("app_name") {
+			update.AppName = &spec.AppName
+		}
+		if changed("health_url") {
+			update.HealthUrl = &spec.HealthUrl
+		}
+		if changed("theme") {
+			update.Theme = &spec.Theme
+		}
+		if changed("logo_url") {
+			update.LogoURL = &spec.LogoURL
+		}
+		if update != (db.AppUpdate{}) {
+			if _, err := db.UpdateApp(tx, appId, orgId, update); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	setSettings := changed("check.interval") || changed("alerts.channels")
+	setPaused := changed("paused")
+	setMaintenance := changed("maintenance")
+	if app == nil {
+		// A new app starts with the defaults, so only what differs from them is set
+		setSettings = spec.Check != nil || spec.Alerts != nil
+		setPaused = spec.Paused
+		setMaintenance = spec.Maintenance != nil
+	}
+
+	if setSettings {
+		if _, err := db.SetAppCheckSettings(tx, appId, orgId, spec.checkSettings()); err != nil {
+			return 0, err
+		}
+	}
+	if setPaused {
+		if _, err := db.SetAppPausedTx(tx, appId, orgId, spec.Paused, &userId, pauseVia(r)); err != nil {
+			return 0, err
+		}
+	}
+	if setMaintenance {
+		if err := db.ReplaceMaintenanceWindows(tx, appId, spec.maintenanceWindows()); err != nil {
+			return 0, err
+		}
+	}
+	return appId, nil
+}
+
+// auditConfigApply records the changes an apply made as one entry
+func (h *Handler) auditConfigApply(r *http.Request, orgId int, prune bool, changes []ConfigChange) {
+	h.audit(r, auditEvent{
+		Action:     AuditConfigApply,
+		TargetType: "organization",
+		TargetID:   orgId,
+		OrgID:      orgId,
+		Details:    map[string]interface{}{"changes": changes, "prune": prune},
+	})
+}
@@ -10,8 +10,8 @@ import (
 	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"statusframe/backend/auth"
+	"statusframe/backend/metrics"
 	"statusframe/db"
 	"strings"
 	"time"
@@ -51,6 +51,11 @@ type DiscordUser struct {
 	Username string `json:"username"`
 }
 
+// DiscordWebhookRequest is the body of POST /api/discord/webhook
+type DiscordWebhookRequest struct {
+	WebhookURL string `json:"webhook_url"`
+}
+
 // DiscordGuild represents a Discord server
 type DiscordGuild struct {
 	ID          string `json:"id"`
@@ -73,30 +78,35 @@ func (h *Handler) StartDiscordAuthHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Check if user has Pro or Business plan
-	plan, err := db.GetUserPlan(h.conn, user.Id)
+	if !hasOrgRole(r, db.RoleAdmin) {
+		respondJSON(w, http.StatusForbidden, IntegrationErrorResponse{
+			Error: "Only organization admins can manage the Discord integration",
+		})
+		return
+	}
+
+	// Check if the organization has Pro or Business plan
+	orgId, _ := orgFromContext(r)
+	plan, err := db.GetOrgPlan(h.conn, orgId)
 	if err != nil {
-		log.Printf("Error fetching plan for user %d during Discord auth start: %v", user.Id, err)
+		log.Printf("Error fetching plan for org %d during Discord auth start: %v", orgId, err)
 		http.Error(w, "Unable to verify subscription for Discord integration", http.StatusInternalServerError)
 		return
 	}
 	if plan != "pro" && plan != "business" {
-		respondJSON(w, http.StatusForbidden, map[string]interface{}{
-			"error": "Discord integration requires Pro or Business plan",
+		respondJSON(w, http.StatusForbidden, IntegrationErrorResponse{
+			Error: "Discord integration requires Pro or Business plan",
 		})
 		return
 	}
 
-	clientID := os.Getenv("DISCORD_CLIENT_ID")
-	redirectURI := os.Getenv("DISCORD_REDIRECT_URI")
-	if redirectURI == "" {
-		redirectURI = "http://localhost:8080/api/discord/callback"
-	}
+	clientID := h.cfg.Discord.ClientID
+	redirectURI := h.discordRedirectURI()
 
 	if clientID == "" {
-		log.Printf("Error: DISCORD_CLIENT_ID environment variable not set")
-		respondJSON(w, http.StatusInternalServerError, map[string]interface{}{
-			"error": "Discord client ID not configured. Please set DISCORD_CLIENT_ID environment variable.",
+		log.Printf("Error: Discord client ID not configured")
+		respondJSON(w, http.StatusInternalServerError, IntegrationErrorResponse{
+			Error: "Discord client ID not configured. Please set DISCORD_CLIENT_ID.",
 		})
 		return
 	}
@@ -119,6 +129,7 @@ func (h *Handler) StartDiscordAuthHandler(w http.ResponseWriter, r *http.Request
 
 	session.Values["discord_oauth_state"] = state
 	session.Values["discord_oauth_user_id"] = user.Id
+	session.Values["discord_oauth_org_id"] = orgId
 	if err := session.Save(r, w); err != nil {
 		log.Printf("Error saving Discord OAuth state to session: %v", err)
 		http.Error(w, "Failed to start Discord authentication", http.StatusInternalServerError)
@@ -136,9 +147,7 @@ func (h *Handler) StartDiscordAuthHandler(w http.ResponseWriter, r *http.Request
 
 	log.Printf("🔗 Discord OAuth URL: %s", oauthURL)
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"oauth_url": oauthURL,
-	})
+	respondJSON(w, http.StatusOK, OAuthURLResponse{OAuthURL: oauthURL})
 }
 
 // DiscordCallbackHandler handles Discord OAuth callback
@@ -180,7 +189,8 @@ func (h *Handler) DiscordCallbackHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	userID, ok := session.Values["discord_oauth_user_id"].(int)
-	if !ok {
+	orgID, orgOk := session.Values["discord_oauth_org_id"].(int)
+	if !ok || !orgOk {
 		log.Printf("Discord OAuth missing user ID in session")
 		redirectToSettings(w, r, map[string]string{
 			"error": "Session missing user information. Please try again",
@@ -188,9 +198,9 @@ func (h *Handler) DiscordCallbackHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	plan, err := db.GetUserPlan(h.conn, userID)
+	plan, err := db.GetOrgPlan(h.conn, orgID)
 	if err != nil {
-		log.Printf("Error fetching plan for user %d during Discord callback: %v", userID, err)
+		log.Printf("Error fetching plan for org %d during Discord callback: %v", orgID, err)
 		redirectToSettings(w, r, map[string]string{
 			"error": "Unable to verify subscription for Discord integration",
 		})
@@ -221,18 +231,30 @@ func (h *Handler) DiscordCallbackHandler(w http.ResponseWriter, r *http.Request)
 		log.Printf("⚠️  Webhook URL not yet provided, user will need to set it up separately")
 	}
 
-	log.Printf("💾 Saving Discord integration for user %d: user=%s, server=%s, channel=%s", userID, discordUser.Username, serverName, channelName)
-	if _, err := db.SaveDiscordIntegration(h.conn, userID, discordUser.ID, discordUser.Username, webhookURL, serverID, serverName, channelID, channelName); err != nil {
-		log.Printf("❌ Error saving Discord integration for user %d: %v", userID, err)
+	log.Printf("💾 Saving Discord integration for org %d: user=%s, server=%s, channel=%s", orgID, discordUser.Username, serverName, channelName)
+	previous, _ := db.GetDiscordIntegration(h.conn, orgID)
+	integration, err := db.SaveDiscordIntegration(h.conn, orgID, userID, discordUser.ID, discordUser.Username, webhookURL, serverID, serverName, channelID, channelName)
+	if err != nil {
+		log.Printf("❌ Error saving Discord integration for org %d: %v", orgID, err)
 		redirectToSettings(w, r, map[string]string{
 			"error": "Failed to save Discord integration",
 		})
 		return
 	}
-	log.Printf("✅ Discord integration saved successfully for user %d", userID)
+	// The callback runs outside AuthMiddleware, so the user comes from the OAuth session
+	h.writeAudit(&userID, h.clientIP(r), auditEvent{
+		Action:     AuditDiscordConnect,
+		TargetType: "discord_integration",
+		TargetID:   integration.ID,
+		OrgID:      orgID,
+		Before:     discordAuditValues(previous),
+		After:      discordAuditValues(integration),
+	})
+	log.Printf("✅ Discord integration saved successfully for org %d", orgID)
 
 	delete(session.Values, "discord_oauth_state")
 	delete(session.Values, "discord_oauth_user_id")
+	delete(session.Values, "discord_oauth_org_id")
 	if err := session.Save(r, w); err != nil {
 		log.Printf("Error clearing Discord OAuth session values: %v", err)
 	}
@@ -241,41 +263,37 @@ func (h *Handler) DiscordCallbackHandler(w http.ResponseWriter, r *http.Request)
 	http.Redirect(w, r, "/dashboard", http.StatusFound)
 }
 
-// GetDiscordIntegrationHandler retrieves user's Discord integration
+// GetDiscordIntegrationHandler retrieves the organization's Discord integration
 func (h *Handler) GetDiscordIntegrationHandler(w http.ResponseWriter, r *http.Request) {
-	user, err := db.GetUserFromContext(h.conn, r.Context())
+	_, err := db.GetUserFromContext(h.conn, r.Context())
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	plan, err := db.GetUserPlan(h.conn, user.Id)
+	orgId, _ := orgFromContext(r)
+	plan, err := db.GetOrgPlan(h.conn, orgId)
 	if err != nil {
-		log.Printf("Error fetching plan for user %d while loading Discord integration: %v", user.Id, err)
-		respondJSON(w, http.StatusInternalServerError, map[string]interface{}{
-			"error": "Unable to verify subscription for Discord integration",
+		log.Printf("Error fetching plan for org %d while loading Discord integration: %v", orgId, err)
+		respondJSON(w, http.StatusInternalServerError, IntegrationErrorResponse{
+			Error: "Unable to verify subscription for Discord integration",
 		})
 		return
 	}
 	if plan != "pro" && plan != "business" {
-		respondJSON(w, http.StatusForbidden, map[string]interface{}{
-			"error": "Discord integration requires Pro or Business plan",
+		respondJSON(w, http.StatusForbidden, IntegrationErrorResponse{
+			Error: "Discord integration requires Pro or Business plan",
 		})
 		return
 	}
 
-	integration, err := db.GetDiscordIntegration(h.conn, user.Id)
+	integration, err := db.GetDiscordIntegration(h.conn, orgId)
 	if err != nil {
-		respondJSON(w, http.StatusOK, map[string]interface{}{
-			"integration": nil,
-			"message":     "No Discord integration found",
-		})
+		respondJSON(w, http.StatusOK, DiscordIntegrationResponse{Message: "No Discord integration found"})
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"integration": integration,
-	})
+	respondJSON(w, http.StatusOK, DiscordIntegrationResponse{Integration: integration})
 }
 
 // DisableDiscordIntegrationHandler disables Discord integration
@@ -286,30 +304,49 @@ func (h *Handler) DisableDiscordIntegrationHandler(w http.ResponseWriter, r *htt
 		return
 	}
 
-	plan, planErr := db.GetUserPlan(h.conn, user.Id)
+	if !hasOrgRole(r, db.RoleAdmin) {
+		respondJSON(w, http.StatusForbidden, IntegrationErrorResponse{
+			Error: "Only organization admins can manage the Discord integration",
+		})
+		return
+	}
+
+	orgId, _ := orgFromContext(r)
+	plan, planErr := db.GetOrgPlan(h.conn, orgId)
 	if planErr != nil {
-		log.Printf("Error fetching plan for user %d while disabling Discord integration: %v", user.Id, planErr)
+		log.Printf("Error fetching plan for org %d while disabling Discord integration: %v", orgId, planErr)
 	}
 	allowedPlan := plan == "pro" || plan == "business"
 
-	err = db.DisableDiscordIntegration(h.conn, user.Id)
+	previous, _ := db.GetDiscordIntegration(h.conn, orgId)
+	err = db.DisableDiscordIntegration(h.conn, orgId)
 	if err != nil {
 		log.Printf("Error disabling Discord integration: %v", err)
-		respondJSON(w, http.StatusInternalServerError, map[string]interface{}{
-			"error": "Failed to disable integration",
+		respondJSON(w, http.StatusInternalServerError, IntegrationErrorResponse{
+			Error: "Failed to disable integration",
 		})
 		return
 	}
+	if previous != nil {
+		after := *previous
+		after.IsEnabled = false
+		h.audit(r, auditEvent{
+			Action:     AuditDiscordDisable,
+			TargetType: "discord_integration",
+			TargetID:   previous.ID,
+			OrgID:      orgId,
+			Before:     discordAuditValues(previous),
+			After:      discordAuditValues(&after),
+		})
+	}
+	log.Printf("🔕 Discord integration disabled for org %d by user %d", orgId, user.Id)
 
 	message := "Discord integration disabled"
 	if !allowedPlan {
 		message = "Discord integration disabled. Upgrade to reconnect."
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"success": true,
-		"message": message,
-	})
+	respondJSON(w, http.StatusOK, SuccessResponse{Success: true, Message: message})
 }
 
 // UpdateDiscordWebhookHandler updates the Discord webhook URL
@@ -320,72 +357,82 @@ func (h *Handler) UpdateDiscordWebhookHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	plan, err := db.GetUserPlan(h.conn, user.Id)
+	if !hasOrgRole(r, db.RoleAdmin) {
+		respondJSON(w, http.StatusForbidden, IntegrationErrorResponse{
+			Error: "Only organization admins can manage the Discord integration",
+		})
+		return
+	}
+
+	orgId, _ := orgFromContext(r)
+	plan, err := db.GetOrgPlan(h.conn, orgId)
 	if err != nil {
-		log.Printf("Error fetching plan for user %d while updating Discord webhook: %v", user.Id, err)
-		respondJSON(w, http.StatusInternalServerError, map[string]interface{}{
-			"error": "Unable to verify subscription for Discord integration",
+		log.Printf("Error fetching plan for org %d while updating Discord webhook: %v", orgId, err)
+		respondJSON(w, http.StatusInternalServerError, IntegrationErrorResponse{
+			Error: "Unable to verify subscription for Discord integration",
 		})
 		return
 	}
 	if plan != "pro" && plan != "business" {
-		respondJSON(w, http.StatusForbidden, map[string]interface{}{
-			"error": "Discord integration requires Pro or Business plan",
+		respondJSON(w, http.StatusForbidden, IntegrationErrorResponse{
+			Error: "Discord integration requires Pro or Business plan",
 		})
 		return
 	}
 
 	// Parse webhook URL from request body
-	var body struct {
-		WebhookURL string `json:"webhook_url"`
-	}
+	var body DiscordWebhookRequest
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
-			"error": "Invalid request body",
+		respondJSON(w, http.StatusBadRequest, IntegrationErrorResponse{
+			Error: "Invalid request body",
 		})
 		return
 	}
 
 	// Validate webhook URL format
 	if body.WebhookURL == "" || !strings.HasPrefix(body.WebhookURL, "https://discord.com/api/webhooks/") {
-		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
-			"error": "Invalid Discord webhook URL format",
+		respondJSON(w, http.StatusBadRequest, IntegrationErrorResponse{
+			Error: "Invalid Discord webhook URL format",
 		})
 		return
 	}
 
 	// Get existing integration
-	integration, err := db.GetDiscordIntegration(h.conn, user.Id)
+	integration, err := db.GetDiscordIntegration(h.conn, orgId)
 	if err != nil || integration == nil {
-		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
-			"error": "No Discord integration found. Please connect Discord first.",
+		respondJSON(w, http.StatusBadRequest, IntegrationErrorResponse{
+			Error: "No Discord integration found. Please connect Discord first.",
 		})
 		return
 	}
 
 	// Update webhook URL in database
 	_, err = h.conn.Exec(
-		"UPDATE discord_integrations SET webhook_url = $1, updated_at = NOW() WHERE user_id = $2",
+		"UPDATE discord_integrations SET webhook_url = $1, updated_at = NOW() WHERE org_id = $2",
 		body.WebhookURL,
-		user.Id,
+		orgId,
 	)
 	if err != nil {
-		log.Printf("Error updating Discord webhook for user %d: %v", user.Id, err)
-		respondJSON(w, http.StatusInternalServerError, map[string]interface{}{
-			"error": "Failed to update webhook URL",
+		log.Printf("Error updating Discord webhook for org %d: %v", orgId, err)
+		respondJSON(w, http.StatusInternalServerError, IntegrationErrorResponse{
+			Error: "Failed to update webhook URL",
 		})
 		return
 	}
 
-	log.Printf("✅ Discord webhook URL updated for user %d", user.Id)
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"success": true,
-		"message": "Discord webhook URL updated successfully",
+	// Only record that the webhook changed - its URL works as a password
+	h.audit(r, auditEvent{
+		Action:     AuditDiscordWebhook,
+		TargetType: "discord_integration",
+		TargetID:   integration.ID,
+		OrgID:      orgId,
 	})
+	log.Printf("✅ Discord webhook URL updated for org %d by user %d", orgId, user.Id)
+	respondJSON(w, http.StatusOK, SuccessResponse{Success: true, Message: "Discord webhook URL updated successfully"})
 }
 
 // SendDiscordAlert sends an incident alert to Discord via direct message
-func (h *Handler) SendDiscordAlert(alert IncidentAlert) error {
+func (h *Handler) SendDiscordAlert(alert IncidentAlert) (err error) {
 	// Get user's Discord integration
 	integration, err := db.GetDiscordIntegrationByAppID(h.conn, alert.AppID)
 	if err != nil || integration == nil || !integration.IsEnabled {
@@ -399,9 +446,11 @@ func (h *Handler) SendDiscordAlert(alert IncidentAlert) error {
 		return nil // Not an error, just not configured yet
 	}
 
-	botToken := os.Getenv("DISCORD_BOT_TOKEN")
+	defer func() { metrics.ObserveNotification("discord", err) }()
+
+	botToken := h.cfg.Discord.BotToken
 	if botToken == "" {
-		log.Printf("❌ DISCORD_BOT_TOKEN environment variable not set, cannot send DMs")
+		log.Printf("❌ Discord bot token not configured, cannot send DMs")
 		return fmt.Errorf("Discord bot token not configured")
 	}
 
@@ -509,14 +558,19 @@ func (h *Handler) SendDiscordAlert(alert IncidentAlert) error {
 	return nil
 }
 
+// discordRedirectURI returns the configured OAuth callback, defaulting to this server's callback route
+func (h *Handler) discordRedirectURI() string {
+	if h.cfg.Discord.RedirectURI != "" {
+		return h.cfg.Discord.RedirectURI
+	}
+	return strings.TrimRight(h.cfg.Server.PublicURL, "/") + "/api/discord/callback"
+}
+
 // exchangeDiscordCode exchanges auth code for token and gets user info
 func (h *Handler) exchangeDiscordCode(code string) (*DiscordUser, string, string, string, string, string, error) {
-	clientID := os.Getenv("DISCORD_CLIENT_ID")
-	clientSecret := os.Getenv("DISCORD_CLIENT_SECRET")
-	redirectURI := os.Getenv("DISCORD_REDIRECT_URI")
-	if redirectURI == "" {
-		redirectURI = "http://localhost:8080/api/discord/callback"
-	}
+	clientID := h.cfg.Discord.ClientID
+	clientSecret := h.cfg.Discord.ClientSecret
+	redirectURI := h.discordRedirectURI()
 
 	if clientID == "" || clientSecret == "" {
 		return nil, "", "", "", "", "", fmt.Errorf("Discord credentials not configured")
@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"statusframe/backend/utils"
+	"statusframe/db"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Custom domains are verified with a TXT record at txtRecordPrefix + the domain
+// holding txtValuePrefix + the page's token
+const (
+	txtRecordPrefix = "_uplitycs-challenge."
+	txtValuePrefix  = "uplitycs-verification="
+)
+
+// verifiedDomainsTTL is how long CustomDomainMiddleware keeps the verified domains
+// before loading them again. Changes made on this server are seen right away, changes
+// made on another one within verifiedDomainsTTL.
+const verifiedDomainsTTL = 30 * time.Second
+
+// SetDomainRequest is the body of PUT /api/v1/status-pages/{pageId}/domain
+type SetDomainRequest struct {
+	Domain string `json:"domain"`
+}
+
+// DomainResponse is a status page's custom domain with the DNS records that serve it.
+// The page is served on the domain once it is verified.
+type DomainResponse struct {
+	Domain      string     `json:"domain"`
+	Verified    bool       `json:"verified"`
+	VerifiedAt  *time.Time `json:"verified_at,omitempty"`
+	TXTName     string     `json:"txt_name"`
+	TXTValue    string     `json:"txt_value"`
+	CNAMETarget string     `json:"cname_target"`
+}
+
+// SetTXTResolver replaces the DNS lookup used to verify domains, for tests
+func (h *Handler) SetTXTResolver(lookup func(ctx context.Context, name string) ([]string, error)) {
+	h.lookupTXT = lookup
+}
+
+// primaryHost is the host the app itself is served on, which is never a custom domain
+func (h *Handler) primaryHost() string {
+	u, err := url.Parse(h.cfg.Server.PublicURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+func (h *Handler) domainResponse(d *db.CustomDomain) DomainResponse {
+	return DomainResponse{
+		Domain:      d.Domain,
+		Verified:    d.VerifiedAt != nil,
+		VerifiedAt:  d.VerifiedAt,
+		TXTName:     txtRecordPrefix + d.Domain,
+		TXTValue:    txtValuePrefix + d.Token,
+		CNAMETarget: h.primaryHost(),
+	}
+}
+
+// pageDomain loads the domain of a status page, writing a 404 if it has none
+func (h *Handler) pageDomain(w http.ResponseWriter, page *db.StatusPage) (*db.CustomDomain, bool) {
+	domain, err := db.GetPageDomain(h.conn, page.Id)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, errCodeNotFound, "This status page has no custom domain")
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error fetching domain of status page %d: %v", page.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to fetch domain")
+		return nil, false
+	}
+	return domain, true
+}
+
+// GetStatusPageDomainV1Handler returns a status page's domain and how to verify it
+func (h *Handler) GetStatusPageDomainV1Handler(w http.ResponseWriter, r *http.Request) {
+	page, ok := h.statusPageFromURL(w, r)
+	if !ok {
+		return
+	}
+	domain, ok := h.pageDomain(w, page)
+	if !ok {
+		return
+	}
+	respondJSON(w, http.StatusOK, h.domainResponse(domain))
+}
+
+// SetStatusPageDomainV1Handler gives a status page a custom domain. A new domain
+// starts unverified with a new token; setting the same domain again changes nothing.
+func (h *Handler) SetStatusPageDomainV1Handler(w http.ResponseWriter, r *http.Request) {
+	page, ok := h.statusPageFromURL(w, r)
+	if !ok {
+		return
+	}
+	if !requireAPIRole(w, r, db.RoleEditor) {
+		return
+	}
+
+	var req SetDomainRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+	domain := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(req.Domain)), ".")
+	if !utils.CheckDomain(domain) {
+		respondError(w, http.StatusBadRequest, errCodeValidation, "domain must be a hostname like status.example.com")
+		return
+	}
+	if primary := h.primaryHost(); domain == primary || strings.HasSuffix(domain, "."+primary) {
+		respondError(w, http.StatusBadRequest, errCodeValidation, "domain must be one of your own, not "+primary)
+		return
+	}
+
+	plan, _ := db.GetOrgPlan(h.conn, page.OrgId)
+	if !planAllowsCustomBranding(plan) {
+		respondError(w, http.StatusForbidden, errCodeForbidden, "Custom domains require Pro or Business plan")
+		return
+	}
+
+	current, err := db.GetPageDomain(h.conn, page.Id)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error fetching domain of status page %d: %v", page.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to set domain")
+		return
+	}
+	if current != nil && current.Domain == domain {
+		respondJSON(w, http.StatusOK, h.domainResponse(current))
+		return
+	}
+
+	token, err := generateDomainToken()
+	if err != nil {
+		log.Printf("Error generating domain token: %v", err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to set domain")
+		return
+	}
+	if err := db.SetPageDomain(h.conn, page.Id, domain, token); err != nil {
+		log.Printf("Error setting domain of status page %d: %v", page.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to set domain")
+		return
+	}
+	h.domains.reset()
+
+	ev := auditEvent{
+		Action:     AuditStatusPageDomain,
+		TargetType: "status_page",
+		TargetID:   page.Id,
+		OrgID:      page.OrgId,
+		After:      map[string]interface{}{"domain": domain},
+	}
+	if current != nil {
+		ev.Before = map[string]interface{}{"domain": current.Domain}
+	}
+	h.audit(r, ev)
+
+	respondJSON(w, http.StatusOK, h.domainResponse(&db.CustomDomain{PageId: page.Id, Domain: domain, Token: token}))
+}
+
+// VerifyStatusPageDomainV1Handler looks up the domain's TXT record and, if it holds
+// the page's token, starts serving the page on the domain
+func (h *Handler) VerifyStatusPageDomainV1Handler(w http.ResponseWriter, r *http.Request) {
+	page, ok := h.statusPageFromURL(w, r)
+	if !ok {
+		return
+	}
+	if !requireAPIRole(w, r, db.RoleEditor) {
+		return
+	}
+	domain, ok := h.pageDomain(w, page)
+	if !ok {
+		return
+	}
+	if domain.VerifiedAt != nil {
+		respondJSON(w, http.StatusOK, h.domainResponse(domain))
+		return
+	}
+
+	lookup := h.lookupTXT
+	if lookup == nil {
+		lookup = net.DefaultResolver.LookupTXT
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	resp := h.domainResponse(domain)
+	records, err := lookup(ctx, resp.TXTName)
+	found := false
+	for _, record := range records {
+		if strings.TrimSpace(record) == resp.TXTValue {
+			found = true
+			break
+		}
+	}
+	if !found {
+		msg := fmt.Sprintf("No TXT record %q found at %s", resp.TXTValue, resp.TXTName)
+		if err != nil {
+			msg += ": " + err.Error()
+		}
+		respondError(w, http.StatusBadRequest, errCodeValidation, msg+". DNS changes can take a while to show up.")
+		return
+	}
+
+	if err := db.VerifyPageDomain(h.conn, page.Id); err != nil {
+		if strings.Contains(err.Error(), "duplicate") {
+			respondError(w, http.StatusConflict, errCodeConflict, "Another status page already uses "+domain.Domain)
+			return
+		}
+		log.Printf("Error verifying domain of status page %d: %v", page.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to verify domain")
+		return
+	}
+	h.domains.reset()
+	log.Printf("🌐 Domain %s verified for status page %d", domain.Domain, page.Id)
+
+	now := time.Now()
+	domain.VerifiedAt = &now
+	respondJSON(w, http.StatusOK, h.domainResponse(domain))
+}
+
+// DeleteStatusPageDomainV1Handler stops serving a status page on its domain
+func (h *Handler) DeleteStatusPageDomainV1Handler(w http.ResponseWriter, r *http.Request) {
+	page, ok := h.statusPageFromURL(w, r)
+	if !ok {
+		return
+	}
+	if !requireAPIRole(w, r, db.RoleEditor) {
+		return
+	}
+	domain, ok := h.pageDomain(w, page)
+	if !ok {
+		return
+	}
+
+	if _, err := db.DeletePageDomain(h.conn, page.Id); err != nil {
+		log.Printf("Error removing domain of status page %d: %v", page.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to remove domain")
+		return
+	}
+	h.domains.reset()
+
+	h.audit(r, auditEvent{
+		Action:     AuditStatusPageDomain,
+		TargetType: "status_page",
+		TargetID:   page.Id,
+		OrgID:      page.OrgId,
+		Before:     map[string]interface{}{"domain": domain.Domain},
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func generateDomainToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// TLSAskHandler answers Caddy's on-demand TLS "ask" request: 200 if a certificate may
+// be issued for ?domain=, which is only for verified custom domains (NO AUTH REQUIRED)
+func (h *Handler) TLSAskHandler(w http.ResponseWriter, r *http.Request) {
+	domain := strings.ToLower(r.URL.Query().Get("domain"))
+	if domain == "" {
+		http.Error(w, "domain required", http.StatusBadRequest)
+		return
+	}
+
+	_, err := db.GetStatusPageSlugByDomain(h.conn, domain)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Unknown domain", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error looking up domain %s: %v", domain, err)
+		http.Error(w, "Error looking up domain", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// domainCache holds every verified custom domain in memory, so requests for any other
+// host, which can name whatever it likes, are routed without a query
+type domainCache struct {
+	mu     sync.Mutex
+	slugs  map[string]string // nil until loaded
+	loaded time.Time
+}
+
+// slug returns the slug of the status page served on host, and false if host isn't a
+// verified domain. If the domains can't be loaded again, the ones loaded before are
+// kept for another verifiedDomainsTTL.
+func (c *domainCache) slug(conn *sql.DB, host string) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.slugs == nil || time.Since(c.loaded) >= verifiedDomainsTTL {
+		slugs, err := db.GetVerifiedDomains(conn)
+		if err != nil && c.slugs == nil {
+			return "", false, err
+		}
+		if err != nil {
+			log.Printf("⚠️ Error loading custom domains, keeping the ones loaded before: %v", err)
+		} else {
+			c.slugs = slugs
+		}
+		c.loaded = time.Now()
+	}
+	slug, ok := c.slugs[host]
+	return slug, ok, nil
+}
+
+// reset has the domains loaded again on the next request, after one of them or the
+// slug of its page changed
+func (c *domainCache) reset() {
+	c.mu.Lock()
+	c.slugs = nil
+	c.mu.Unlock()
+}
+
+// customDomainHost returns the request's host without the port, and whether it could
+// be a custom domain. Our own host, hosts without a dot like localhost or the
+// container name, and IP addresses never are.
+func (h *Handler) customDomainHost(r *http.Request) (string, bool) {
+	host := strings.ToLower(r.Host)
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	if host == h.primaryHost() || !strings.Contains(host, ".") || net.ParseIP(host) != nil {
+		return host, false
+	}
+	return host, true
+}
+
+// CustomDomainMiddleware serves status pages on their verified custom domains. On
+// such a domain, / redirects to the page and only the page, its API and the
+// frontend's assets are served. Requests for any other host pass through.
+func (h *Handler) CustomDomainMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, ok := h.customDomainHost(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		slug, ok, err := h.domains.slug(h.conn, host)
+		if err != nil {
+			log.Printf("Error looking up domain %s: %v", host, err)
+			http.Error(w, "Error looking up domain", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		switch path := r.URL.Path; {
+		case path == "/":
+			http.Redirect(w, r, "/pages/"+slug, http.StatusFound)
+		case path == "/pages/"+slug, path == "/api/public/pages/"+slug,
+			strings.HasPrefix(path, "/assets/"), path == "/favicon.ico", path == "/vite.svg":
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), "customDomain", host)))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
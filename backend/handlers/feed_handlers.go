@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"statusframe/db"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Feeds of an app's status changes and incident updates, for feed readers and
+// Slack's RSS app. Each entry's ID is the status page URL with a fragment naming the
+// check or update it came from, so it stays the same between requests.
+
+// maxFeedItems caps how many entries a feed lists
+const maxFeedItems = 50
+
+// Content types of the feeds
+const (
+	rssContentType      = "application/rss+xml; charset=utf-8"
+	atomContentType     = "application/atom+xml; charset=utf-8"
+	jsonFeedContentType = "application/feed+json; charset=utf-8"
+)
+
+// feed is what every format is made from
+type feed struct {
+	Title   string
+	Link    string // the status page
+	Updated time.Time
+	Items   []feedItem
+}
+
+type feedItem struct {
+	ID        string
+	Title     string
+	Text      string
+	Published time.Time
+}
+
+// appFeed loads the status changes of an app within its plan's data retention and
+// its incident updates, newest first. It writes an error and returns false if the
+// app doesn't exist or can't be read.
+func (h *Handler) appFeed(w http.ResponseWriter, r *http.Request) (*feed, bool) {
+	slug := chi.URLParam(r, "slug")
+
+	app, err := db.GetAppBySlug(h.conn, slug)
+	if err != nil {
+		http.Error(w, "Status page not found", http.StatusNotFound)
+		return nil, false
+	}
+	if !h.requireAppAccess(w, r, app) {
+		return nil, false
+	}
+	fields, err := db.GetPublicFields(h.conn, app.Id)
+	if err != nil {
+		log.Printf("Error getting public fields of app %s: %v", slug, err)
+		http.Error(w, "Error fetching feed", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	plan, err := db.GetOrgPlan(h.conn, app.OrgId)
+	if err != nil {
+		log.Printf("Error getting org plan: %v", err)
+		plan = "free"
+	}
+	changes, err := db.GetAppStatusChanges(h.conn, app.Id, db.GetPlanFeatures(plan).DataRetentionDays, maxFeedItems)
+	if err != nil {
+		log.Printf("Error getting status changes for app %s: %v", slug, err)
+		http.Error(w, "Error fetching feed", http.StatusInternalServerError)
+		return nil, false
+	}
+	updates, err := db.GetAppIncidentUpdates(h.conn, app.Id, maxFeedItems)
+	if err != nil {
+		log.Printf("Error getting incident updates for app %s: %v", slug, err)
+		http.Error(w, "Error fetching feed", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	f := &feed{
+		Title: app.AppName + " Status",
+		Link:  strings.TrimRight(h.cfg.Server.PublicURL, "/") + "/status/" + app.Slug,
+	}
+	for _, c := range changes {
+		f.Items = append(f.Items, feedItem{
+			ID:        fmt.Sprintf("%s#check-%d", f.Link, c.CheckId),
+			Title:     statusChangeTitle(app.AppName, c.Status),
+			Text:      statusChangeText(app.AppName, c, fields.StatusCode),
+			Published: c.CheckedAt,
+		})
+	}
+	for _, u := range updates {
+		f.Items = append(f.Items, feedItem{
+			ID:        fmt.Sprintf("%s#update-%d", f.Link, u.Id),
+			Title:     incidentUpdateTitle(app.AppName, u.Status),
+			Text:      u.Message,
+			Published: u.CreatedAt,
+		})
+	}
+	sort.SliceStable(f.Items, func(i, j int) bool {
+		return f.Items[i].Published.After(f.Items[j].Published)
+	})
+	if len(f.Items) > maxFeedItems {
+		f.Items = f.Items[:maxFeedItems]
+	}
+
+	// Without entries the feed is as old as the app, so it doesn't change between requests
+	if len(f.Items) > 0 {
+		f.Updated = f.Items[0].Published
+	} else if created, err := time.Parse(time.RFC3339Nano, app.CreatedAt); err == nil {
+		f.Updated = created
+	}
+	return f, true
+}
+
+// statusChangeTitle headlines a change to status
+func statusChangeTitle(appName, status string) string {
+	switch status {
+	case "up":
+		return appName + " is back up"
+	case "degraded":
+		return appName + " is degraded"
+	}
+	return appName + " is down"
+}
+
+// incidentUpdateTitle headlines an incident update at stage status
+func incidentUpdateTitle(appName, status string) string {
+	return fmt.Sprintf("%s incident: %s", appName, strings.ToUpper(status[:1])+status[1:])
+}
+
+// statusChangeText describes the check that changed the status, with what it got
+// unless the page hides status codes
+func statusChangeText(appName string, c db.StatusChange, showCode bool) string {
+	checkedAt := c.CheckedAt.UTC().Format("Jan 2, 2006 15:04 MST")
+	if !showCode {
+		return fmt.Sprintf("%s went from %s to %s at %s.", appName, c.Previous, c.Status, checkedAt)
+	}
+	got := "no response"
+	if c.StatusCode != 0 {
+		got = fmt.Sprintf("HTTP %d", c.StatusCode)
+	}
+	return fmt.Sprintf("%s went from %s to %s: the check at %s got %s.", appName, c.Previous, c.Status, checkedAt, got)
+}
+
+// writeFeed writes a feed with the caching headers of status pages, and the time of
+// its newest entry as Last-Modified
+func writeFeed(w http.ResponseWriter, r *http.Request, contentType string, f *feed, body []byte) {
+	if !f.Updated.IsZero() {
+		w.Header().Set("Last-Modified", f.Updated.UTC().Format(http.TimeFormat))
+	}
+	writeCached(w, r, contentType, http.StatusOK, body)
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	TTL           int       `xml:"ttl"` // minutes
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// GetStatusFeedRSSHandler returns an app's status changes and incident updates as RSS 2.0
+func (h *Handler) GetStatusFeedRSSHandler(w http.ResponseWriter, r *http.Request) {
+	f, ok := h.appFeed(w, r)
+	if !ok {
+		return
+	}
+
+	rss := rssFeed{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       f.Title,
+			Link:        f.Link,
+			Description: "Status changes and incident updates",
+			Self:        atomLink{Href: h.pageURL(r), Rel: "self", Type: strings.Split(rssContentType, ";")[0]},
+			TTL:         5,
+		},
+	}
+	if !f.Updated.IsZero() {
+		rss.Channel.LastBuildDate = f.Updated.UTC().Format(time.RFC1123Z)
+	}
+	for _, item := range f.Items {
+		rss.Channel.Items = append(rss.Channel.Items, rssItem{
+			Title:       item.Title,
+			Link:        item.ID,
+			Description: item.Text,
+			GUID:        rssGUID{Value: item.ID},
+			PubDate:     item.Published.UTC().Format(time.RFC1123Z),
+		})
+	}
+
+	body, err := xml.MarshalIndent(rss, "", "  ")
+	if err != nil {
+		log.Printf("Error encoding RSS feed: %v", err)
+		http.Error(w, "Error encoding feed", http.StatusInternalServerError)
+		return
+	}
+	writeFeed(w, r, rssContentType, f, append([]byte(xml.Header), body...))
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string   `xml:"id"`
+	Title     string   `xml:"title"`
+	Updated   string   `xml:"updated"`
+	Published string   `xml:"published"`
+	Link      atomLink `xml:"link"`
+	Content   atomText `xml:"content"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// GetStatusFeedAtomHandler returns an app's status changes and incident updates as Atom
+func (h *Handler) GetStatusFeedAtomHandler(w http.ResponseWriter, r *http.Request) {
+	f, ok := h.appFeed(w, r)
+	if !ok {
+		return
+	}
+
+	atom := atomFeed{
+		ID:      f.Link,
+		Title:   f.Title,
+		Updated: f.Updated.UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: f.Title},
+		Links: []atomLink{
+			{Href: f.Link, Rel: "alternate", Type: "text/html"},
+			{Href: h.pageURL(r), Rel: "self", Type: strings.Split(atomContentType, ";")[0]},
+		},
+	}
+	for _, item := range f.Items {
+		published := item.Published.UTC().Format(time.RFC3339)
+		atom.Entries = append(atom.Entries, atomEntry{
+			ID:        item.ID,
+			Title:     item.Title,
+			Updated:   published,
+			Published: published,
+			Link:      atomLink{Href: item.ID, Rel: "alternate", Type: "text/html"},
+			Content:   atomText{Type: "text", Value: item.Text},
+		})
+	}
+
+	body, err := xml.MarshalIndent(atom, "", "  ")
+	if err != nil {
+		log.Printf("Error encoding Atom feed: %v", err)
+		http.Error(w, "Error encoding feed", http.StatusInternalServerError)
+		return
+	}
+	writeFeed(w, r, atomContentType, f, append([]byte(xml.Header), body...))
+}
+
+// JSONFeed is the body of GET /api/public/status/{slug}/feed.json, in JSON Feed 1.1
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+// JSONFeedItem is a status change or incident update in a JSON Feed
+type JSONFeedItem struct {
+	ID            string    `json:"id"`
+	URL           string    `json:"url"`
+	Title         string    `json:"title"`
+	ContentText   string    `json:"content_text"`
+	DatePublished time.Time `json:"date_published"`
+}
+
+// GetStatusFeedJSONHandler returns an app's status changes and incident updates as a JSON Feed
+func (h *Handler) GetStatusFeedJSONHandler(w http.ResponseWriter, r *http.Request) {
+	f, ok := h.appFeed(w, r)
+	if !ok {
+		return
+	}
+
+	jf := JSONFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: f.Link,
+		FeedURL:     h.pageURL(r),
+		Items:       []JSONFeedItem{},
+	}
+	for _, item := range f.Items {
+		jf.Items = append(jf.Items, JSONFeedItem{
+			ID:            item.ID,
+			URL:           item.ID,
+			Title:         item.Title,
+			ContentText:   item.Text,
+			DatePublished: item.Published.UTC(),
+		})
+	}
+
+	body, _ := json.Marshal(jf)
+	writeFeed(w, r, jsonFeedContentType, f, body)
+}
@@ -1,18 +1,23 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"html/template"
 	"log"
+	"math"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
 	"statusframe/backend/auth"
+	"statusframe/backend/config"
 	"statusframe/backend/utils"
 	"statusframe/db"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -30,18 +35,67 @@ type OnboardingRequest struct {
 	LogoURL  string `json:"logo_url,omitempty"` // Optional logo URL
 }
 
+// UpdateThemeRequest is the body of POST /api/update-theme. The app is picked by slug
+// or, without one, by app_id.
+type UpdateThemeRequest struct {
+	Theme string `json:"theme"`
+	Slug  string `json:"slug,omitempty"`
+	AppId int    `json:"app_id,omitempty"`
+}
+
 // SSLCheckerInterface defines the methods we need from the SSL checker
 type SSLCheckerInterface interface {
 	CheckAppSSL(appID int)
 }
 
 type Handler struct {
-	conn       *sql.DB
-	sslChecker SSLCheckerInterface
+	conn          *sql.DB
+	cfg           *config.Config
+	sslChecker    SSLCheckerInterface
+	mailer        Mailer
+	workers       []WorkerStatusReporter
+	shuttingDown  atomic.Bool
+	lookupTXT     func(ctx context.Context, name string) ([]string, error) // nil uses the system resolver
+	webhookClient *http.Client                                             // nil uses publicWebhookClient
+
+	// The encoded OpenAPI document, built on first request
+	openAPIOnce sync.Once
+	openAPIDoc  []byte
+
+	// The React app's scripts and styles for server-rendered pages, see SetSPAIndex
+	spaIndexPath string
+	spaHeadOnce  sync.Once
+	spaHeadHTML  template.HTML
+
+	// Limits on the public API and the live pings shared between its visitors
+	ipLimiter   *rateLimiter
+	slugLimiter *rateLimiter
+	pings       pingCache
+
+	// Verified custom domains, for routing requests by host
+	domains domainCache
+
+	// Limits sign-in links per visitor, since each one sends an email
+	magicLinkLimiter *rateLimiter
+
+	// Limit wrong status page passwords per visitor and per page, since each one is
+	// checked with a slow hash
+	unlockVisitorLimiter *rateLimiter
+	unlockPageLimiter    *rateLimiter
 }
 
-func NewHandler(conn *sql.DB) *Handler {
-	return &Handler{conn: conn}
+func NewHandler(conn *sql.DB, cfg *config.Config) *Handler {
+	return &Handler{
+		conn:        conn,
+		cfg:         cfg,
+		ipLimiter:   newRateLimiter(cfg.Public.RateLimitPerIP),
+		slugLimiter: newRateLimiter(cfg.Public.RateLimitPerSlug),
+
+		magicLinkLimiter: newRateLimiter(magicLinkRequestsPerIP),
+
+		unlockVisitorLimiter: newRateLimiter(unlockFailuresPerIP),
+		unlockPageLimiter:    newRateLimiter(unlockFailuresPerPage),
+	}
 }
 
 func (h *Handler) SetSSLChecker(sslChecker SSLCheckerInterface) {
@@ -95,8 +149,9 @@ func (h *Handler) GoToDashboardHandler(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			// Check if user has Pro or Business plan
-			plan, _ := db.GetUserPlan(h.conn, user.Id)
+			// Check if the organization has Pro or Business plan
+			orgId, _ := orgFromContext(r)
+			plan, _ := db.GetOrgPlan(h.conn, orgId)
 			if plan != "pro" && plan != "business" {
 				http.Error(w, "Logo upload requires Pro or Business plan", http.StatusForbidden)
 				return
@@ -160,14 +215,13 @@ func (h *Handler) GoToDashboardHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate app name and slug are not empty
-	if req.AppName == "" {
-		http.Error(w, "App name is required", http.StatusBadRequest)
+	if !utils.CheckAppName(req.AppName) {
+		http.Error(w, "App name is required and must be at most 100 characters", http.StatusBadRequest)
 		return
 	}
 
-	if req.Slug == "" {
-		http.Error(w, "Slug is required", http.StatusBadRequest)
+	if !utils.CheckSlug(req.Slug) {
+		http.Error(w, "Slug may only contain lowercase letters, numbers and hyphens", http.StatusBadRequest)
 		return
 	}
 
@@ -175,6 +229,10 @@ func (h *Handler) GoToDashboardHandler(w http.ResponseWriter, r *http.Request) {
 	if req.Theme == "" {
 		req.Theme = "cyberpunk"
 	}
+	if !utils.CheckTheme(req.Theme) {
+		http.Error(w, "Invalid theme. Valid themes: "+strings.Join(utils.ValidThemes, ", "), http.StatusBadRequest)
+		return
+	}
 
 	conn := h.conn
 
@@ -185,10 +243,15 @@ func (h *Handler) GoToDashboardHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check plan limit before creating app
-	plan, _ := db.GetUserPlan(conn, user.Id)
+	if !requireOrgRole(w, r, db.RoleEditor) {
+		return
+	}
+
+	// Check the organization's plan limit before creating app
+	orgId, _ := orgFromContext(r)
+	plan, _ := db.GetOrgPlan(conn, orgId)
 	planLimit := db.GetPlanLimit(plan)
-	appCount, err := db.GetAppCount(conn, user.Id)
+	appCount, err := db.GetAppCount(conn, orgId)
 	if err != nil {
 		log.Println("Error getting app count", err)
 		http.Error(w, "Database error", http.StatusInternalServerError)
@@ -198,21 +261,20 @@ func (h *Handler) GoToDashboardHandler(w http.ResponseWriter, r *http.Request) {
 	if appCount >= planLimit {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusForbidden)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": false,
-			"error":   "plan_limit_reached",
-			"message": fmt.Sprintf("You've reached your %s plan limit (%d apps)", plan, planLimit),
-			"plan":    plan,
-			"limit":   planLimit,
+		json.NewEncoder(w).Encode(PlanLimitReachedResponse{
+			Error:   "plan_limit_reached",
+			Message: fmt.Sprintf("You've reached your %s plan limit (%d apps)", plan, planLimit),
+			Plan:    plan,
+			Limit:   planLimit,
 		})
 		return
 	}
 
 	// Create new app with logo URL
-	log.Printf("Creating app: user_id=%d, app_name=%s, slug=%s, health_url=%s, theme=%s, alerts=%s, logo_url=%v",
-		user.Id, req.AppName, req.Slug, req.Homepage, req.Theme, req.Alerts, logoURL)
+	log.Printf("Creating app: org_id=%d, user_id=%d, app_name=%s, slug=%s, health_url=%s, theme=%s, alerts=%s, logo_url=%v",
+		orgId, user.Id, req.AppName, req.Slug, req.Homepage, req.Theme, req.Alerts, logoURL)
 
-	appId, err := db.CreateAppWithLogo(conn, user.Id, req.AppName, req.Slug, req.Homepage, req.Theme, req.Alerts, logoURL)
+	appId, err := db.CreateAppWithLogo(conn, orgId, user.Id, req.AppName, req.Slug, req.Homepage, req.Theme, req.Alerts, logoURL)
 	if err != nil {
 		log.Println("Error creating app in GoToDashboardHandler", err)
 		if strings.Contains(err.Error(), "duplicate") {
@@ -233,27 +295,26 @@ func (h *Handler) GoToDashboardHandler(w http.ResponseWriter, r *http.Request) {
 	// Return success JSON
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success":  true,
-		"message":  "App created successfully",
-		"app_id":   appId,
-		"slug":     req.Slug,
-		"logo_url": logoURL,
+	json.NewEncoder(w).Encode(OnboardingAppResponse{
+		Success: true,
+		Message: "App created successfully",
+		AppID:   appId,
+		Slug:    req.Slug,
+		LogoURL: logoURL,
 	})
 }
 
 // uploadLogoToS3 streams the logo file directly to S3 and returns the URL
 func (h *Handler) uploadLogoToS3(file multipart.File, fileHeader *multipart.FileHeader, userId int) (string, error) {
 	// Create AWS session
-	sess, err := utils.CreateAWSSession()
+	sess, err := utils.CreateAWSSession(h.cfg.AWS)
 	if err != nil {
 		return "", fmt.Errorf("failed to create AWS session: %w", err)
 	}
 
-	// Get bucket name from environment
-	bucketName := os.Getenv("AWS_S3_BUCKET_NAME")
+	bucketName := h.cfg.AWS.S3BucketName
 	if bucketName == "" {
-		return "", fmt.Errorf("AWS_S3_BUCKET_NAME not configured")
+		return "", fmt.Errorf("AWS S3 bucket name not configured")
 	}
 
 	// Create S3 client
@@ -287,7 +348,7 @@ func (h *Handler) uploadLogoToS3(file multipart.File, fileHeader *multipart.File
 	// Construct the public file URL
 	fileURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s",
 		bucketName,
-		os.Getenv("AWS_REGION"),
+		h.cfg.AWS.Region,
 		s3Path,
 	)
 
@@ -311,15 +372,12 @@ func BeginAuthHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Currently only supporting Google
-	if provider == "google" {
-		auth.BeginGoogleAuth(w, r)
-	} else {
+	if !auth.BeginOAuth(w, r, provider) {
 		http.Error(w, "unsupported provider", http.StatusBadRequest)
 	}
 }
 
-// GetAuthHandler handles the OAuth callback
+// GetAuthHandler handles the OAuth callback, and the magic link callback for the "email" provider
 func (h *Handler) GetAuthHandler(w http.ResponseWriter, r *http.Request) {
 	// Get provider from URL
 	provider := chi.URLParam(r, "provider")
@@ -330,74 +388,19 @@ func (h *Handler) GetAuthHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if provider != "google" {
-		log.Printf("Unsupported provider: %s", provider)
-		http.Redirect(w, r, "/auth?error=unsupported_provider", http.StatusTemporaryRedirect)
+	if provider == magicLinkProvider {
+		h.magicLinkCallback(w, r)
 		return
 	}
 
-	// Handle Google OAuth callback
-	userInfo, err := auth.HandleGoogleCallback(w, r)
+	identity, err := auth.HandleOAuthCallback(w, r, provider)
 	if err != nil {
-		log.Printf("Google authentication failed: %v", err)
+		log.Printf("%s authentication failed: %v", provider, err)
 		http.Redirect(w, r, "/auth?error=auth_failed", http.StatusTemporaryRedirect)
 		return
 	}
 
-	conn := h.conn
-
-	// Try to get existing user by email
-	existingUser, err := db.GetUserByEmail(conn, userInfo.Email)
-
-	var id int
-	var needsOnboarding bool
-
-	if err != nil {
-		// User doesn't exist, create new user
-		log.Printf("User not found, creating new user: %s", userInfo.Email)
-		id, err = db.InsertUser(conn, userInfo.Name, userInfo.Picture, userInfo.Email)
-		if err != nil {
-			log.Printf("Error creating user: %v", err)
-			http.Redirect(w, r, "/auth?error=signup_failed", http.StatusTemporaryRedirect)
-			return
-		}
-		needsOnboarding = true // New users always need onboarding
-		log.Printf("Created new user with ID: %d", id)
-	} else {
-		// User exists, use existing ID
-		id = existingUser.Id
-		// Check if existing user needs onboarding (no apps created yet)
-		appCount, err := db.GetAppCount(conn, id)
-		if err != nil {
-			appCount = 0
-		}
-		needsOnboarding = (appCount == 0)
-		log.Printf("Existing user found: ID=%d, AppCount=%d, NeedsOnboarding=%t", id, appCount, needsOnboarding)
-	}
-
-	// Set session for both new and existing users
-	session, _ := auth.Store.Get(r, "auth-session")
-	session.Values["user"] = userInfo.Name
-	session.Values["userId"] = id
-
-	// Ensure the session cookie persists (30 days)
-	session.Options.MaxAge = 86400 * 30 // 30 days
-
-	err = session.Save(r, w)
-	if err != nil {
-		log.Printf("Error saving session: %v", err)
-	}
-
-	// NEW FLOW:
-	// - New users (no apps) → /pricing (to select a plan)
-	// - Existing users (have apps) → /dashboard
-	if needsOnboarding {
-		log.Printf("Redirecting new user to pricing page: user ID %d", id)
-		http.Redirect(w, r, "/pricing", http.StatusFound)
-	} else {
-		log.Printf("Redirecting existing user to dashboard: user ID %d", id)
-		http.Redirect(w, r, "/dashboard", http.StatusFound)
-	}
+	h.completeLogin(w, r, identity)
 }
 
 // GetUserStatusHandler checks if user is authenticated
@@ -421,8 +424,9 @@ func (h *Handler) GetUserStatusHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Fetch user apps to determine default slug/app info in multi-app world
-	apps, err := db.GetUserApps(conn, userID.(int))
+	// Fetch the active organization's apps to determine default slug/app info in multi-app world
+	orgId, role := orgFromContext(r)
+	apps, err := db.GetOrgApps(conn, orgId)
 	if err != nil {
 		log.Printf("Error getting user apps: %v", err)
 		http.Error(w, "Failed to load apps", http.StatusInternalServerError)
@@ -446,16 +450,18 @@ func (h *Handler) GetUserStatusHandler(w http.ResponseWriter, r *http.Request) {
 		defaultTheme = "cyberpunk"
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"authenticated": true,
-		"userId":        userID,
-		"userName":      userName,
-		"theme":         defaultTheme,
-		"homepage":      defaultHomepage,
-		"slug":          defaultSlug,
-		"appName":       defaultAppName,
-		"apps":          apps,
+	name, _ := userName.(string)
+	respondJSON(w, http.StatusOK, UserStatusResponse{
+		Authenticated: true,
+		UserID:        userID.(int),
+		UserName:      name,
+		Theme:         defaultTheme,
+		Homepage:      defaultHomepage,
+		Slug:          defaultSlug,
+		AppName:       defaultAppName,
+		Apps:          apps,
+		OrgID:         orgId,
+		OrgRole:       role,
 	})
 }
 
@@ -473,10 +479,7 @@ func CheckSessionHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"authenticated": true,
-		"userId":        userId,
-	})
+	json.NewEncoder(w).Encode(CheckSessionResponse{Authenticated: true, UserID: userId})
 }
 
 // GetCurrentResponseTimeHandler pings an endpoint and returns real-time response time
@@ -494,37 +497,75 @@ func (h *Handler) GetCurrentResponseTimeHandler(w http.ResponseWriter, r *http.R
 		http.Error(w, "App not found", http.StatusNotFound)
 		return
 	}
+	if !h.requireAppAccess(w, r, app) {
+		return
+	}
+	fields, err := db.GetPublicFields(h.conn, app.Id)
+	if err != nil {
+		log.Printf("Error getting public fields of app %d: %v", app.Id, err)
+		http.Error(w, "Error measuring response time", http.StatusInternalServerError)
+		return
+	}
+	if !fields.ResponseTime {
+		http.Error(w, "This status page doesn't show response times", http.StatusForbidden)
+		return
+	}
 
 	if app.HealthUrl == "" {
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"error":         "Health URL not configured",
-			"response_time": 0,
+		var zero int64
+		respondJSON(w, http.StatusOK, PingResponse{
+			Error:        "Health URL not configured",
+			ResponseTime: &zero,
+			Timestamp:    time.Now().UTC(),
 		})
 		return
 	}
 
-	// Ping the endpoint and measure response time
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	// Don't hit endpoints the owner asked us to stop checking
+	if app.Paused {
+		respondJSON(w, http.StatusOK, PingResponse{
+			Paused:    true,
+			Timestamp: time.Now().UTC(),
+		})
+		return
 	}
 
-	startTime := time.Now()
-	resp, err := client.Get(app.HealthUrl)
-	responseTime := time.Since(startTime).Milliseconds()
+	// Ping the endpoint and measure response time, once for everyone watching the page
+	allow := func() (bool, time.Duration) { return h.slugLimiter.allow(app.Slug, time.Now()) }
+	ping, expires, ok, wait := h.pings.get(app.Id, h.cfg.Public.PingCacheTTL.Duration, allow, func() PingResponse {
+		client := &http.Client{
+			Timeout: 10 * time.Second,
+		}
+
+		startTime := time.Now()
+		resp, err := client.Get(app.HealthUrl)
+		responseTime := time.Since(startTime).Milliseconds()
 
-	statusCode := 0
-	if err == nil {
-		defer resp.Body.Close()
-		statusCode = resp.StatusCode
+		statusCode := 0
+		if err == nil {
+			resp.Body.Close()
+			statusCode = resp.StatusCode
+		}
+		return PingResponse{
+			ResponseTime: &responseTime,
+			StatusCode:   statusCode,
+			Timestamp:    time.Now().UTC(),
+		}
+	})
+	if !ok {
+		tooManyRequests(w, wait)
+		return
+	}
+	if !fields.StatusCode {
+		ping.StatusCode = 0
 	}
 
 	// Return real-time response data (not stored in database)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"response_time": responseTime,
-		"status_code":   statusCode,
-		"timestamp":     time.Now().UTC(),
-	})
+	if w.Header().Get("Cache-Control") == "" {
+		maxAge := int(math.Ceil(time.Until(expires).Seconds()))
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", max(maxAge, 0)))
+	}
+	respondJSON(w, http.StatusOK, ping)
 }
 
 // LogoutHandler clears the user session
@@ -564,11 +605,9 @@ func (h *Handler) GetLatestStatusHandler(w http.ResponseWriter, r *http.Request)
 	if latestStatus == nil {
 		// No status checks yet - return pending state
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":      "pending",
-			"status_code": 0,
-			"checked_at":  "",
-			"message":     "Waiting for first health check (runs every 30 seconds)",
+		json.NewEncoder(w).Encode(LatestStatusResponse{
+			Status:  "pending",
+			Message: "Waiting for first health check (runs every 30 seconds)",
 		})
 		return
 	}
@@ -580,15 +619,13 @@ func (h *Handler) GetLatestStatusHandler(w http.ResponseWriter, r *http.Request)
 		uptime = 0
 	}
 
-	response := map[string]interface{}{
-		"status_code": latestStatus.StatusCode,
-		"status":      latestStatus.Status,
-		"checked_at":  latestStatus.CheckedAt,
-		"uptime_24h":  uptime,
-	}
-
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	json.NewEncoder(w).Encode(LatestStatusResponse{
+		Status:     latestStatus.Status,
+		StatusCode: latestStatus.StatusCode,
+		CheckedAt:  latestStatus.CheckedAt,
+		Uptime24h:  &uptime,
+	})
 }
 
 // GetPublicStatusHandler returns public status page data by slug (NO AUTH REQUIRED)
@@ -600,15 +637,44 @@ func (h *Handler) GetPublicStatusHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	conn := h.conn
-
 	// Get app by slug (now using apps table)
-	app, err := db.GetAppBySlug(conn, slug)
+	app, err := db.GetAppBySlug(h.conn, slug)
 	if err != nil {
 		log.Printf("App not found for slug %s: %v", slug, err)
 		http.Error(w, "Status page not found", http.StatusNotFound)
 		return
 	}
+	if !h.requireAppAccess(w, r, app) {
+		return
+	}
+
+	status, err := h.publicStatus(app)
+	if err != nil {
+		log.Printf("Error getting status for app %s: %v", slug, err)
+		http.Error(w, "Error fetching status", http.StatusInternalServerError)
+		return
+	}
+
+	respondCachedJSON(w, r, status)
+}
+
+// publicStatus loads what an app's public status page shows. Only failing to read the
+// last check or the page's settings is an error; uptime that can't be read is left at zero.
+func (h *Handler) publicStatus(app *db.App) (*PublicStatusResponse, error) {
+	conn := h.conn
+
+	fields, err := db.GetPublicFields(conn, app.Id)
+	if err != nil {
+		return nil, err
+	}
+	status := &PublicStatusResponse{
+		AppName: app.AppName,
+		Slug:    app.Slug,
+		Theme:   app.Theme,
+		LogoURL: app.LogoURL,
+		Paused:  app.Paused,
+		Fields:  fields,
+	}
 
 	// Get latest status check from database using app_id
 	query := `
@@ -619,67 +685,74 @@ func (h *Handler) GetPublicStatusHandler(w http.ResponseWriter, r *http.Request)
 		LIMIT 1
 	`
 	var statusCode int
-	var checkedAt string
-	err = conn.QueryRow(query, app.Id).Scan(&statusCode, &checkedAt)
+	err = conn.QueryRow(query, app.Id).Scan(&statusCode, &status.CheckedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// No status checks yet - return pending state
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"app_name":    app.AppName,
-				"slug":        app.Slug,
-				"theme":       app.Theme,
-				"logo_url":    app.LogoURL,
-				"endpoint":    app.HealthUrl,
-				"status":      "pending",
-				"status_code": 0,
-				"message":     "Waiting for first health check",
-				"user_id":     app.UserId,
-			})
-			return
+			status.Status, status.Message = "pending", "Waiting for first health check"
+			if app.Paused {
+				status.Status, status.Message = "paused", "Monitoring is paused"
+			}
+			return status, nil
 		}
-		log.Printf("Error getting status for app %s: %v", slug, err)
-		http.Error(w, "Error fetching status", http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
-	// Derive status from status code
-	status := db.GetStatusFromCode(statusCode)
+	// Derive status from status code. The last check is stale while paused.
+	status.Status = db.GetStatusFromCode(statusCode)
+	if app.Paused {
+		status.Status = "paused"
+	} else if fields.StatusCode {
+		status.StatusCode = statusCode
+	}
 
-	// Get uptime percentage for this app
-	uptimeQuery := `
-		SELECT 
-			ROUND(
-				CAST(COUNT(*) FILTER (WHERE status_code >= 200 AND status_code < 300) AS NUMERIC) / 
-				NULLIF(COUNT(*), 0) * 100, 
-				2
-			) as uptime_24h
-		FROM user_status
-		WHERE app_id = $1 AND checked_at > NOW() - INTERVAL '24 hours'
-	`
-	var uptime float64
-	err = conn.QueryRow(uptimeQuery, app.Id).Scan(&uptime)
-	if err != nil {
-		log.Printf("Error calculating uptime: %v", err)
-		uptime = 0
+	if fields.Uptime {
+		// Get uptime percentage for this app
+		uptimeQuery := `
+			SELECT 
+				ROUND(
+					CAST(COUNT(*) FILTER (WHERE status_code >= 200 AND status_code < 300) AS NUMERIC) / 
+					NULLIF(COUNT(*), 0) * 100, 
+					2
+				) as uptime_24h
+			FROM user_status_unpaused
+			WHERE app_id = $1 AND checked_at > NOW() - INTERVAL '24 hours'
+		`
+		var uptime float64
+		err = conn.QueryRow(uptimeQuery, app.Id).Scan(&uptime)
+		if err != nil {
+			log.Printf("Error calculating uptime: %v", err)
+			uptime = 0
+		}
+		status.Uptime24h = &uptime
+	}
+
+	if fields.UptimeHistory {
+		status.DataRetentionDays, status.UptimeHistory = h.uptimeHistory(app)
 	}
+	return status, nil
+}
+
+// uptimeHistory returns an app's daily uptime over its organization's plan retention
+// (7, 30, or 90 days), newest first. History that can't be read is left empty.
+func (h *Handler) uptimeHistory(app *db.App) (int, []db.DailyUptime) {
+	conn := h.conn
 
-	// Get user's plan to determine data retention period
-	userPlan, err := db.GetUserPlan(conn, app.UserId)
+	// Get the owning organization's plan to determine data retention period
+	userPlan, err := db.GetOrgPlan(conn, app.OrgId)
 	if err != nil {
-		log.Printf("Error getting user plan: %v", err)
+		log.Printf("Error getting org plan: %v", err)
 		userPlan = "free" // Default to free plan
 	}
 	dataRetentionDays := db.GetPlanFeatures(userPlan).DataRetentionDays
 
-	// Get uptime history based on plan's data retention (7, 30, or 90 days)
 	historyQuery := `
 		SELECT 
 			DATE(checked_at) as date,
 			COUNT(*) as total_checks,
 			COUNT(*) FILTER (WHERE status_code >= 200 AND status_code < 300) as successful_checks
-		FROM user_status
+		FROM user_status_unpaused
 		WHERE app_id = $1
 		AND checked_at > NOW() - INTERVAL '1 day' * $2
 		GROUP BY DATE(checked_at)
@@ -688,42 +761,24 @@ func (h *Handler) GetPublicStatusHandler(w http.ResponseWriter, r *http.Request)
 	rows, err := conn.Query(historyQuery, app.Id, dataRetentionDays)
 	if err != nil {
 		log.Printf("Error getting uptime history: %v", err)
+		return dataRetentionDays, nil
 	}
 	defer rows.Close()
 
 	var uptimeHistory []db.DailyUptime
-	if rows != nil {
-		for rows.Next() {
-			var daily db.DailyUptime
-			err := rows.Scan(&daily.Date, &daily.TotalChecks, &daily.SuccessfulChecks)
-			if err != nil {
-				log.Printf("Error scanning uptime history: %v", err)
-				continue
-			}
-			if daily.TotalChecks > 0 {
-				daily.UptimePercentage = float64(daily.SuccessfulChecks) / float64(daily.TotalChecks) * 100
-			}
-			uptimeHistory = append(uptimeHistory, daily)
+	for rows.Next() {
+		var daily db.DailyUptime
+		err := rows.Scan(&daily.Date, &daily.TotalChecks, &daily.SuccessfulChecks)
+		if err != nil {
+			log.Printf("Error scanning uptime history: %v", err)
+			continue
 		}
+		if daily.TotalChecks > 0 {
+			daily.UptimePercentage = float64(daily.SuccessfulChecks) / float64(daily.TotalChecks) * 100
+		}
+		uptimeHistory = append(uptimeHistory, daily)
 	}
-
-	// Return public status data (no sensitive info)
-	response := map[string]interface{}{
-		"app_name":            app.AppName,
-		"slug":                app.Slug,
-		"theme":               app.Theme,
-		"logo_url":            app.LogoURL, // Include logo URL
-		"status_code":         statusCode,
-		"status":              status,
-		"checked_at":          checkedAt,
-		"uptime_24h":          uptime,
-		"uptime_history":      uptimeHistory,
-		"user_id":             app.UserId, // Include user ID for owner detection
-		"data_retention_days": dataRetentionDays,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	return dataRetentionDays, uptimeHistory
 }
 
 // UpdateThemeHandler allows authenticated users to update their app theme
@@ -739,11 +794,7 @@ func (h *Handler) UpdateThemeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
-		Theme string `json:"theme"`
-		Slug  string `json:"slug"`   // Add slug to identify which app to update
-		AppId int    `json:"app_id"` // Alternative: use app_id
-	}
+	var req UpdateThemeRequest
 
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
@@ -752,17 +803,8 @@ func (h *Handler) UpdateThemeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate theme
-	validThemes := []string{"cyberpunk", "matrix", "retro", "minimal"}
-	isValid := false
-	for _, t := range validThemes {
-		if req.Theme == t {
-			isValid = true
-			break
-		}
-	}
-
-	if !isValid {
-		http.Error(w, "Invalid theme. Valid themes: cyberpunk, matrix, retro, minimal", http.StatusBadRequest)
+	if !utils.CheckTheme(req.Theme) {
+		http.Error(w, "Invalid theme. Valid themes: "+strings.Join(utils.ValidThemes, ", "), http.StatusBadRequest)
 		return
 	}
 
@@ -787,9 +829,12 @@ func (h *Handler) UpdateThemeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Verify the user owns this app
-	if app.UserId != userId.(int) {
-		http.Error(w, "Unauthorized - you don't own this app", http.StatusForbidden)
+	// Verify the app belongs to the active organization and the user may edit it
+	if orgId, _ := orgFromContext(r); app.OrgId != orgId {
+		http.Error(w, "Unauthorized - this app belongs to another organization", http.StatusForbidden)
+		return
+	}
+	if !requireOrgRole(w, r, db.RoleEditor) {
 		return
 	}
 
@@ -800,41 +845,48 @@ func (h *Handler) UpdateThemeHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to update theme", http.StatusInternalServerError)
 		return
 	}
+	h.audit(r, auditEvent{
+		Action:     AuditAppThemeChange,
+		TargetType: "app",
+		TargetID:   app.Id,
+		OrgID:      app.OrgId,
+		Before:     map[string]string{"theme": app.Theme},
+		After:      map[string]string{"theme": req.Theme},
+	})
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"theme":   req.Theme,
-	})
+	json.NewEncoder(w).Encode(ThemeResponse{Success: true, Theme: req.Theme})
 }
 
 // ========== MULTI-APP DASHBOARD HANDLERS ==========
 
-// GetUserAppsHandler returns all apps for the authenticated user with their status
+// GetUserAppsHandler returns all apps of the active organization with their status
 func (h *Handler) GetUserAppsHandler(w http.ResponseWriter, r *http.Request) {
-	user, err := db.GetUserFromContext(h.conn, r.Context())
+	_, err := db.GetUserFromContext(h.conn, r.Context())
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	apps, err := db.GetUserAppsWithStatus(h.conn, user.Id)
+	orgId, role := orgFromContext(r)
+	apps, err := db.GetOrgAppsWithStatus(h.conn, orgId)
 	if err != nil {
-		log.Printf("Error fetching user apps: %v", err)
+		log.Printf("Error fetching org apps: %v", err)
 		http.Error(w, "Failed to fetch apps", http.StatusInternalServerError)
 		return
 	}
 
-	// Get user's plan info
-	plan, _ := db.GetUserPlan(h.conn, user.Id)
+	// Get the organization's plan info
+	plan, _ := db.GetOrgPlan(h.conn, orgId)
 	planLimit := db.GetPlanLimit(plan)
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"apps":       apps,
-		"plan":       plan,
-		"plan_limit": planLimit,
-		"app_count":  len(apps),
+	respondJSON(w, http.StatusOK, UserAppsResponse{
+		Apps:      apps,
+		Plan:      plan,
+		PlanLimit: planLimit,
+		AppCount:  len(apps),
+		OrgID:     orgId,
+		Role:      role,
 	})
 }
 
@@ -859,31 +911,51 @@ func (h *Handler) DeleteAppHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = db.DeleteApp(h.conn, id, user.Id)
+	if !requireOrgRole(w, r, db.RoleEditor) {
+		return
+	}
+
+	// Load the app first so the audit log can say what was deleted
+	orgId, _ := orgFromContext(r)
+	app, err := db.GetAppById(h.conn, id)
+	if err != nil || app.OrgId != orgId {
+		http.Error(w, "App not found", http.StatusNotFound)
+		return
+	}
+
+	err = db.DeleteApp(h.conn, id, orgId)
 	if err != nil {
 		log.Printf("Error deleting app: %v", err)
 		http.Error(w, "Failed to delete app", http.StatusInternalServerError)
 		return
 	}
+	h.audit(r, auditEvent{
+		Action:     AuditAppDelete,
+		TargetType: "app",
+		TargetID:   id,
+		OrgID:      orgId,
+		Before:     appAuditValues(*app),
+	})
+	log.Printf("🗑️ App %d deleted from org %d by user %d", id, orgId, user.Id)
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"message": "App deleted successfully",
+	respondJSON(w, http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "App deleted successfully",
 	})
 }
 
-// CheckPlanLimitHandler checks if user can add more apps
+// CheckPlanLimitHandler checks if the active organization can add more apps
 func (h *Handler) CheckPlanLimitHandler(w http.ResponseWriter, r *http.Request) {
-	user, err := db.GetUserFromContext(h.conn, r.Context())
+	_, err := db.GetUserFromContext(h.conn, r.Context())
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	plan, _ := db.GetUserPlan(h.conn, user.Id)
+	orgId, _ := orgFromContext(r)
+	plan, _ := db.GetOrgPlan(h.conn, orgId)
 	planLimit := db.GetPlanLimit(plan)
-	appCount, err := db.GetAppCount(h.conn, user.Id)
+	appCount, err := db.GetAppCount(h.conn, orgId)
 	if err != nil {
 		log.Printf("Error getting app count: %v", err)
 		http.Error(w, "Failed to check limit", http.StatusInternalServerError)
@@ -892,36 +964,35 @@ func (h *Handler) CheckPlanLimitHandler(w http.ResponseWriter, r *http.Request)
 
 	canAdd := appCount < planLimit
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"can_add":    canAdd,
-		"plan":       plan,
-		"plan_limit": planLimit,
-		"app_count":  appCount,
-		"remaining":  planLimit - appCount,
+	respondJSON(w, http.StatusOK, PlanLimitResponse{
+		CanAdd:    canAdd,
+		Plan:      plan,
+		PlanLimit: planLimit,
+		AppCount:  appCount,
+		Remaining: planLimit - appCount,
 	})
 }
 
-// GetPlanFeaturesHandler returns all features for user's current plan
+// GetPlanFeaturesHandler returns all features for the active organization's current plan
 func (h *Handler) GetPlanFeaturesHandler(w http.ResponseWriter, r *http.Request) {
-	user, err := db.GetUserFromContext(h.conn, r.Context())
+	_, err := db.GetUserFromContext(h.conn, r.Context())
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	plan, _ := db.GetUserPlan(h.conn, user.Id)
+	orgId, _ := orgFromContext(r)
+	plan, _ := db.GetOrgPlan(h.conn, orgId)
 	features := db.GetPlanFeatures(plan)
-	appCount, _ := db.GetAppCount(h.conn, user.Id)
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"plan":                plan,
-		"max_monitors":        features.MaxMonitors,
-		"min_check_interval":  features.MinCheckInterval,
-		"data_retention_days": features.DataRetentionDays,
-		"current_app_count":   appCount,
-		"remaining_monitors":  features.MaxMonitors - appCount,
+	appCount, _ := db.GetAppCount(h.conn, orgId)
+
+	respondJSON(w, http.StatusOK, PlanFeaturesResponse{
+		Plan:              plan,
+		MaxMonitors:       features.MaxMonitors,
+		MinCheckInterval:  features.MinCheckInterval,
+		DataRetentionDays: features.DataRetentionDays,
+		CurrentAppCount:   appCount,
+		RemainingMonitors: features.MaxMonitors - appCount,
 	})
 }
 
@@ -965,6 +1036,38 @@ func (h *Handler) GetUptimeBadgeHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Badges of private pages need a share link, e.g. for an internal wiki
+	access, err := h.appPageAccess(w, r, app)
+	if err != nil || !access.Allowed {
+		if err != nil {
+			log.Printf("Error checking access to app %d: %v", app.Id, err)
+		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		w.Write([]byte(generateErrorBadge("private")))
+		return
+	}
+
+	if app.Paused {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Header().Set("Cache-Control", "max-age=300")
+		w.Write([]byte(generateErrorBadge("paused")))
+		return
+	}
+
+	// Owners who keep uptime off their page don't want it on badges either
+	if fields, err := db.GetPublicFields(h.conn, app.Id); err != nil || !fields.Uptime {
+		message := "hidden"
+		if err != nil {
+			log.Printf("Error getting public fields of app %d: %v", app.Id, err)
+			message = "error"
+		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		w.Write([]byte(generateErrorBadge(message)))
+		return
+	}
+
 	// Calculate uptime percentage based on period
 	uptimeQuery := `
 		SELECT 
@@ -976,7 +1079,7 @@ func (h *Handler) GetUptimeBadgeHandler(w http.ResponseWriter, r *http.Request)
 				),
 				0
 			) as uptime
-		FROM user_status
+		FROM user_status_unpaused
 		WHERE app_id = $1 AND checked_at > NOW() - INTERVAL '1 day' * $2
 	`
 
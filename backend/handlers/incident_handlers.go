@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"slices"
+	"statusframe/db"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Limits on incident updates
+const (
+	maxIncidentMessage = 5000 // characters
+	maxIncidentUpdates = 100
+)
+
+// IncidentUpdateRequest is the body of POST /api/v1/apps/{appId}/incident-updates
+type IncidentUpdateRequest struct {
+	Status  string `json:"status"` // investigating, identified, monitoring or resolved
+	Message string `json:"message"`
+}
+
+// ListIncidentUpdatesV1Handler returns the updates posted about an app's incidents, newest first
+func (h *Handler) ListIncidentUpdatesV1Handler(w http.ResponseWriter, r *http.Request) {
+	app, ok := h.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	updates, err := db.GetAppIncidentUpdates(h.conn, app.Id, maxIncidentUpdates)
+	if err != nil {
+		log.Printf("Error fetching incident updates for app %d: %v", app.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to fetch incident updates")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, IncidentUpdateListResponse{IncidentUpdates: updates})
+}
+
+// CreateIncidentUpdateV1Handler posts an update about an app's incident. It shows up
+// in the app's public feeds right away.
+func (h *Handler) CreateIncidentUpdateV1Handler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	app, ok := h.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if !requireAPIRole(w, r, db.RoleEditor) {
+		return
+	}
+
+	var req IncidentUpdateRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+	req.Message = strings.TrimSpace(req.Message)
+	var problems []string
+	if !slices.Contains(db.IncidentStatuses, req.Status) {
+		problems = append(problems, "status must be one of "+strings.Join(db.IncidentStatuses, ", "))
+	}
+	if req.Message == "" {
+		problems = append(problems, "message is required")
+	} else if utf8.RuneCountInString(req.Message) > maxIncidentMessage {
+		problems = append(problems, "message may be at most "+strconv.Itoa(maxIncidentMessage)+" characters")
+	}
+	if len(problems) > 0 {
+		respondError(w, http.StatusBadRequest, errCodeValidation, strings.Join(problems, "; "))
+		return
+	}
+
+	update, err := db.CreateIncidentUpdate(h.conn, app.Id, userId, req.Status, req.Message)
+	if err != nil {
+		log.Printf("Error posting incident update for app %d: %v", app.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to post incident update")
+		return
+	}
+
+	h.audit(r, auditEvent{
+		Action:     AuditIncidentUpdatePost,
+		TargetType: "incident_update",
+		TargetID:   update.Id,
+		OrgID:      app.OrgId,
+		After:      incidentUpdateAuditValues(update),
+	})
+	log.Printf("📣 Incident update %d (%s) posted for app %d by user %d", update.Id, update.Status, app.Id, userId)
+	respondJSON(w, http.StatusCreated, IncidentUpdateResponse{IncidentUpdate: update})
+}
+
+// DeleteIncidentUpdateV1Handler removes an incident update, e.g. one posted by mistake
+func (h *Handler) DeleteIncidentUpdateV1Handler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	app, ok := h.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if !requireAPIRole(w, r, db.RoleEditor) {
+		return
+	}
+
+	updateId, err := strconv.Atoi(chi.URLParam(r, "updateId"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "Invalid incident update ID")
+		return
+	}
+
+	update, err := db.DeleteIncidentUpdate(h.conn, app.Id, updateId)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, errCodeNotFound, "Incident update not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error deleting incident update %d: %v", updateId, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to delete incident update")
+		return
+	}
+
+	h.audit(r, auditEvent{
+		Action:     AuditIncidentUpdateDelete,
+		TargetType: "incident_update",
+		TargetID:   update.Id,
+		OrgID:      app.OrgId,
+		Before:     incidentUpdateAuditValues(update),
+	})
+	log.Printf("🗑️ Incident update %d of app %d deleted by user %d", update.Id, app.Id, userId)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// WorkerStatus describes the state of a background worker
+type WorkerStatus struct {
+	Name       string     `json:"name"`
+	Running    bool       `json:"running"`
+	LastRunAt  *time.Time `json:"last_run_at,omitempty"`
+	InFlight   int        `json:"in_flight"`
+	QueueDepth int        `json:"queue_depth"`
+}
+
+// WorkerStatusReporter is implemented by background workers that report to the readiness endpoint
+type WorkerStatusReporter interface {
+	Status() WorkerStatus
+}
+
+// AddWorker registers a background worker whose status is included in readiness checks
+func (h *Handler) AddWorker(worker WorkerStatusReporter) {
+	h.workers = append(h.workers, worker)
+}
+
+// MarkShuttingDown makes the readiness endpoint fail so no new traffic is routed here
+func (h *Handler) MarkShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+// LivenessHandler reports that the process is up
+func LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, LivenessResponse{Status: "ok"})
+}
+
+// ReadinessHandler reports whether the server can take traffic, including database and worker status
+func (h *Handler) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	ready := true
+
+	if h.shuttingDown.Load() {
+		ready = false
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	database := "ok"
+	if err := h.conn.PingContext(ctx); err != nil {
+		database = "unreachable"
+		ready = false
+	}
+
+	workers := make([]WorkerStatus, 0, len(h.workers))
+	for _, worker := range h.workers {
+		status := worker.Status()
+		if !status.Running {
+			ready = false
+		}
+		workers = append(workers, status)
+	}
+
+	status := http.StatusOK
+	state := "ready"
+	if !ready {
+		status = http.StatusServiceUnavailable
+		state = "not_ready"
+	}
+	if h.shuttingDown.Load() {
+		state = "shutting_down"
+	}
+
+	respondJSON(w, status, ReadinessResponse{Status: state, Database: database, Workers: workers})
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/mail"
+	"statusframe/backend/auth"
+	"statusframe/db"
+	"strings"
+	"time"
+
+	"github.com/gorilla/sessions"
+)
+
+// magicLinkProvider is the identity provider name for email magic links.
+// The callback lives at /auth/email/callback next to the OAuth ones.
+const magicLinkProvider = "email"
+
+// magicLinksPerWindow limits how many links one address can be sent in magicLinkWindow,
+// so the endpoint can't be used to flood someone's inbox
+const (
+	magicLinksPerWindow = 5
+	magicLinkWindow     = 15 * time.Minute
+)
+
+// magicLinkRequestsPerIP limits the sign-in links one visitor address can ask for a
+// minute, so it can't send mail to many different addresses either
+const magicLinkRequestsPerIP = 10
+
+// MagicLinkRequest is the body of POST /auth/email
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+// GetLoginMethodsHandler tells the sign-in page which buttons to show
+func (h *Handler) GetLoginMethodsHandler(w http.ResponseWriter, r *http.Request) {
+	respondJSON(w, http.StatusOK, LoginMethodsResponse{
+		Providers:  auth.Providers(),
+		MagicLinks: h.cfg.Auth.MagicLinks,
+	})
+}
+
+// RequestMagicLinkHandler emails a one-time sign-in link. It answers the same way
+// whether or not the address has an account, so it can't be used to find users.
+func (h *Handler) RequestMagicLinkHandler(w http.ResponseWriter, r *http.Request) {
+	if !h.cfg.Auth.MagicLinks {
+		http.Error(w, "Email sign in is not enabled", http.StatusNotFound)
+		return
+	}
+	if ok, wait := h.magicLinkLimiter.allow(h.visitorIP(r).String(), time.Now()); !ok {
+		tooManyRequests(w, wait)
+		return
+	}
+
+	var req MagicLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	address, err := mail.ParseAddress(strings.TrimSpace(req.Email))
+	if err != nil {
+		http.Error(w, "Invalid email address", http.StatusBadRequest)
+		return
+	}
+	email := strings.ToLower(address.Address)
+
+	accepted := SuccessResponse{
+		Success: true,
+		Message: "If the address can sign in, a link is on its way",
+	}
+
+	recent, err := db.CountRecentMagicLinks(h.conn, email, time.Now().Add(-magicLinkWindow))
+	if err != nil {
+		log.Printf("Error counting magic links: %v", err)
+		http.Error(w, "Failed to send sign-in link", http.StatusInternalServerError)
+		return
+	}
+	if recent >= magicLinksPerWindow {
+		log.Printf("⚠️ Too many magic links requested for %s", email)
+		respondJSON(w, http.StatusAccepted, accepted)
+		return
+	}
+
+	token, err := generateInvitationToken()
+	if err != nil {
+		log.Printf("Error generating magic link token: %v", err)
+		http.Error(w, "Failed to send sign-in link", http.StatusInternalServerError)
+		return
+	}
+
+	ttl := h.cfg.Auth.MagicLinkTTL.Duration
+	if err := db.CreateMagicLink(h.conn, email, auth.HashAPIKey(token), time.Now().Add(ttl)); err != nil {
+		log.Printf("Error storing magic link: %v", err)
+		http.Error(w, "Failed to send sign-in link", http.StatusInternalServerError)
+		return
+	}
+
+	h.sendMagicLink(email, token, ttl)
+	respondJSON(w, http.StatusAccepted, accepted)
+}
+
+// magicLinkCallback signs in whoever opened a valid link. Opening the link proves
+// they own the address, so the email counts as verified.
+func (h *Handler) magicLinkCallback(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" || !h.cfg.Auth.MagicLinks {
+		http.Redirect(w, r, "/auth?error=invalid_link", http.StatusTemporaryRedirect)
+		return
+	}
+
+	email, err := db.ConsumeMagicLink(h.conn, auth.HashAPIKey(token))
+	if err != nil {
+		log.Printf("Error consuming magic link: %v", err)
+		http.Redirect(w, r, "/auth?error=auth_failed", http.StatusTemporaryRedirect)
+		return
+	}
+	if email == "" {
+		http.Redirect(w, r, "/auth?error=invalid_link", http.StatusTemporaryRedirect)
+		return
+	}
+
+	h.completeLogin(w, r, &auth.Identity{
+		Provider:      magicLinkProvider,
+		Subject:       email,
+		Email:         email,
+		EmailVerified: true,
+		Name:          strings.SplitN(email, "@", 2)[0],
+	})
+}
+
+// sendMagicLink emails the sign-in link. Without a mailer it is dropped; the link
+// signs in as its owner, so it is never written to the log.
+func (h *Handler) sendMagicLink(to, token string, ttl time.Duration) {
+	if h.mailer == nil {
+		log.Printf("⚠️ No mailer configured - not sending the sign-in link for %s", to)
+		return
+	}
+
+	link := strings.TrimRight(h.cfg.Server.PublicURL, "/") + "/auth/" + magicLinkProvider + "/callback?token=" + token
+
+	minutes := int(ttl.Minutes())
+	subject := "Your UpLitycs sign-in link"
+	textBody := fmt.Sprintf("Sign in to UpLitycs: %s\n\nThis link expires in %d minutes and works once. If you didn't ask for it, ignore this email.", link, minutes)
+	htmlBody := fmt.Sprintf(`<p><a href="%s">Sign in to UpLitycs</a></p><p>This link expires in %d minutes and works once. If you didn't ask for it, ignore this email.</p>`,
+		html.EscapeString(link), minutes)
+
+	// Sending is slow and the link is already stored, so don't hold up the response
+	go func() {
+		if err := h.mailer.Send(to, subject, htmlBody, textBody); err != nil {
+			log.Printf("❌ Error sending sign-in link to %s: %v", to, err)
+		}
+	}()
+}
+
+// completeLogin signs in the user behind an identity from any login method. An identity
+// seen before signs in its user; a new one is linked to the user with the same verified
+// email, or creates a user. Unverified emails are never linked, so nobody can take over
+// an account by adding its address to a provider profile.
+func (h *Handler) completeLogin(w http.ResponseWriter, r *http.Request, identity *auth.Identity) {
+	conn := h.conn
+
+	id, err := db.GetUserIdByIdentity(conn, identity.Provider, identity.Subject)
+	if err != nil {
+		log.Printf("Error looking up %s identity: %v", identity.Provider, err)
+		http.Redirect(w, r, "/auth?error=auth_failed", http.StatusTemporaryRedirect)
+		return
+	}
+
+	var user db.User
+	var needsOnboarding bool
+
+	if id != 0 {
+		user, err = db.GetUserById(conn, id)
+	} else {
+		if identity.Email == "" || !identity.EmailVerified {
+			log.Printf("Refusing %s sign in without a verified email", identity.Provider)
+			http.Redirect(w, r, "/auth?error=email_unverified", http.StatusTemporaryRedirect)
+			return
+		}
+		user, err = db.GetUserByEmail(conn, identity.Email)
+		if err == sql.ErrNoRows {
+			// User doesn't exist, create new user
+			log.Printf("User not found, creating new user: %s", identity.Email)
+			user.Id, err = db.InsertUser(conn, identity.Name, identity.AvatarURL, identity.Email)
+			if err != nil {
+				log.Printf("Error creating user: %v", err)
+				http.Redirect(w, r, "/auth?error=signup_failed", http.StatusTemporaryRedirect)
+				return
+			}
+			user.Name = identity.Name
+			needsOnboarding = true // New users always need onboarding
+			log.Printf("Created new user with ID: %d", user.Id)
+		}
+	}
+	if err != nil {
+		log.Printf("Error loading user for %s sign in: %v", identity.Provider, err)
+		http.Redirect(w, r, "/auth?error=auth_failed", http.StatusTemporaryRedirect)
+		return
+	}
+
+	if user.DisabledAt != nil {
+		log.Printf("🚫 Disabled user tried to sign in: ID=%d", user.Id)
+		http.Redirect(w, r, "/auth?error=account_disabled", http.StatusTemporaryRedirect)
+		return
+	}
+
+	if err := db.LinkUserIdentity(conn, user.Id, identity.Provider, identity.Subject, identity.Email); err != nil {
+		log.Printf("⚠️ Error linking %s identity to user %d: %v", identity.Provider, user.Id, err)
+	}
+
+	if !needsOnboarding {
+		// Check if existing user needs onboarding (no apps in any of their organizations)
+		appCount, err := db.GetAccessibleAppCount(conn, user.Id)
+		if err != nil {
+			appCount = 0
+		}
+		needsOnboarding = (appCount == 0)
+		log.Printf("Existing user found: ID=%d, AppCount=%d, NeedsOnboarding=%t", user.Id, appCount, needsOnboarding)
+	}
+
+	twoFactor, err := db.GetTOTPState(conn, user.Id)
+	if err != nil {
+		log.Printf("Error loading two-factor state of user %d: %v", user.Id, err)
+		http.Redirect(w, r, "/auth?error=auth_failed", http.StatusTemporaryRedirect)
+		return
+	}
+
+	// Set session for both new and existing users, under a new token
+	session, _ := auth.Store.Get(r, "auth-session")
+	if err := auth.RenewSession(session); err != nil {
+		log.Printf("Error renewing session: %v", err)
+	}
+	delete(session.Values, "user")
+	delete(session.Values, "userId")
+	delete(session.Values, "activeOrgId") // start in the personal organization
+	delete(session.Values, "impersonatorId")
+	delete(session.Values, "impersonatorName")
+	delete(session.Values, "impersonationStartedAt")
+
+	// With two-factor authentication on, the session only gets its userId once a code checks out
+	if twoFactor.EnabledAt != nil {
+		session.Values["pending2faUserId"] = user.Id
+		session.Values["pending2faName"] = user.Name
+		session.Values["pending2faOnboarding"] = needsOnboarding
+		session.Values["pending2faStartedAt"] = time.Now().Unix()
+		session.Values["pending2faAttempts"] = 0
+		if err := session.Save(r, w); err != nil {
+			log.Printf("Error saving session: %v", err)
+		}
+		log.Printf("🔐 Asking user %d for a second factor", user.Id)
+		http.Redirect(w, r, "/two-factor", http.StatusFound)
+		return
+	}
+
+	h.finishLogin(w, r, session, user.Id, user.Name, needsOnboarding)
+}
+
+// finishLogin signs the user into the session and sends them on to the app
+func (h *Handler) finishLogin(w http.ResponseWriter, r *http.Request, session *sessions.Session, userId int, name string, needsOnboarding bool) {
+	session.Values["user"] = name
+	session.Values["userId"] = userId
+	clearPendingTwoFactor(session.Values)
+
+	// Ensure the session cookie persists (30 days)
+	session.Options.MaxAge = 86400 * 30 // 30 days
+
+	err := session.Save(r, w)
+	if err != nil {
+		log.Printf("Error saving session: %v", err)
+	}
+
+	// Users who signed in from an invitation link join that organization first
+	if token, ok := session.Values["pendingInvitation"].(string); ok && token != "" {
+		h.joinInvitedOrg(w, r, userId, token)
+		return
+	}
+
+	// NEW FLOW:
+	// - New users (no apps) → /pricing (to select a plan)
+	// - Existing users (have apps) → /dashboard
+	if needsOnboarding {
+		log.Printf("Redirecting new user to pricing page: user ID %d", userId)
+		http.Redirect(w, r, "/pricing", http.StatusFound)
+	} else {
+		log.Printf("Redirecting existing user to dashboard: user ID %d", userId)
+		http.Redirect(w, r, "/dashboard", http.StatusFound)
+	}
+}
@@ -0,0 +1,633 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"statusframe/backend/probe"
+	"statusframe/db"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Who may call an operation in the OpenAPI document
+const (
+	apiAuthPublic  = ""        // anyone
+	apiAuthSession = "session" // a signed-in browser session only
+	apiAuthAny     = "any"     // a session or an API key
+	apiAuthAdmin   = "admin"   // a session of a platform admin who isn't impersonating anyone
+	apiAuthProbe   = "probe"   // the token shared with probe agents
+)
+
+// apiOperation describes one endpoint of the JSON API. Request and Response hold a
+// zero value of the body type; the schemas are read from the types with reflection,
+// so the document can't drift from what the handlers encode.
+type apiOperation struct {
+	Method   string
+	Path     string
+	Summary  string
+	Tag      string
+	Auth     string
+	Org      bool // acts on the active organization, which X-Org-Id can pick
+	Query    []apiParam
+	Request  interface{}
+	Status   int
+	Response interface{} // nil when the response has no body
+	Errors   []int
+	// JSON bodies of errors that aren't ErrorResponse, like the plan limit of
+	// POST /api/go-to-dashboard; other errors outside /api/v1 are plain text
+	ErrorBodies map[int]interface{}
+}
+
+type apiParam struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+// apiOperations lists the documented endpoints. Endpoints that only redirect the
+// browser (OAuth and Stripe callbacks, sign-in, the start of onboarding), the Stripe
+// webhook and the Prometheus /metrics are left out.
+var apiOperations = []apiOperation{
+	// Monitors
+	{Method: "GET", Path: "/api/v1/apps", Summary: "List apps with their current status", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: AppListResponse{}, Errors: []int{401, 403}},
+	{Method: "POST", Path: "/api/v1/apps", Summary: "Create an app", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Request: CreateAppRequest{}, Status: 201, Response: AppResponse{}, Errors: []int{400, 401, 403, 409}},
+	{Method: "GET", Path: "/api/v1/apps/{appId}", Summary: "Get an app", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: AppResponse{}, Errors: []int{401, 404}},
+	{Method: "PATCH", Path: "/api/v1/apps/{appId}", Summary: "Change an app's settings", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Request: UpdateAppRequest{}, Status: 200, Response: AppResponse{}, Errors: []int{400, 401, 403, 404, 409}},
+	{Method: "DELETE", Path: "/api/v1/apps/{appId}", Summary: "Delete an app and its history", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Status: 204, Errors: []int{401, 403, 404}},
+	{Method: "POST", Path: "/api/v1/apps/{appId}/pause", Summary: "Pause health checks", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: AppResponse{}, Errors: []int{401, 403, 404}},
+	{Method: "POST", Path: "/api/v1/apps/{appId}/resume", Summary: "Resume health checks", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: AppResponse{}, Errors: []int{401, 403, 404}},
+	{Method: "GET", Path: "/api/v1/apps/{appId}/pause-events", Summary: "List when an app was paused and resumed", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: PauseEventListResponse{}, Errors: []int{401, 404}},
+	{Method: "GET", Path: "/api/v1/apps/{appId}/checks", Summary: "List an app's latest check results, oldest first", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Query: []apiParam{
+			{Name: "after", Type: "integer", Description: "only checks after this check ID, for polling"},
+			{Name: "limit", Type: "integer", Description: "at most this many checks (default 100, up to 500)"},
+		},
+		Status: 200, Response: CheckListResponse{}, Errors: []int{400, 401, 404}},
+	{Method: "GET", Path: "/api/v1/apps/{appId}/incidents", Summary: "List an app's incidents, newest first", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: IncidentListResponse{}, Errors: []int{401, 404}},
+	{Method: "GET", Path: "/api/v1/apps/{appId}/incident-updates", Summary: "List the updates posted about an app's incidents, newest first", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: IncidentUpdateListResponse{}, Errors: []int{401, 404}},
+	{Method: "POST", Path: "/api/v1/apps/{appId}/incident-updates", Summary: "Post an update about an app's incident", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Request: IncidentUpdateRequest{}, Status: 201, Response: IncidentUpdateResponse{}, Errors: []int{400, 401, 403, 404}},
+	{Method: "DELETE", Path: "/api/v1/apps/{appId}/incident-updates/{updateId}", Summary: "Delete an incident update", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Status: 204, Errors: []int{400, 401, 403, 404}},
+	{Method: "GET", Path: "/api/v1/apps/{appId}/visibility", Summary: "Get who can see an app's status page", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: VisibilityResponse{}, Errors: []int{401, 404}},
+	{Method: "PUT", Path: "/api/v1/apps/{appId}/visibility", Summary: "Make an app's status page public, password protected, IP restricted or members only", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Request: VisibilityRequest{}, Status: 200, Response: VisibilityResponse{}, Errors: []int{400, 401, 403, 404}},
+	{Method: "POST", Path: "/api/v1/apps/{appId}/share-links", Summary: "Create a signed link that opens a private status page until it expires", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Request: ShareLinkRequest{}, Status: 201, Response: ShareLinkResponse{}, Errors: []int{400, 401, 403, 404, 409}},
+	{Method: "DELETE", Path: "/api/v1/apps/{appId}/share-links", Summary: "Revoke every share link of a private status page", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Status: 204, Errors: []int{401, 403, 404, 409}},
+	{Method: "GET", Path: "/api/v1/apps/{appId}/public-fields", Summary: "Get which details an app's status page shows", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: PublicFieldsResponse{}, Errors: []int{401, 404}},
+	{Method: "PATCH", Path: "/api/v1/apps/{appId}/public-fields", Summary: "Show or hide the status code, response time, uptime and uptime history on an app's status page", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Request: PublicFieldsRequest{}, Status: 200, Response: PublicFieldsResponse{}, Errors: []int{400, 401, 403, 404}},
+	{Method: "POST", Path: "/api/v1/apps/{appId}/ssl-check", Summary: "Re-check an app's SSL certificate now", Tag: "apps", Auth: apiAuthAny, Org: true,
+		Status: 202, Response: SuccessResponse{}, Errors: []int{400, 401, 403, 404}},
+	{Method: "GET", Path: "/api/v1/config", Summary: "Export the organization's apps as a configuration file", Tag: "config", Auth: apiAuthAny, Org: true,
+		Query:  []apiParam{{Name: "format", Type: "string", Description: "yaml for YAML instead of JSON"}},
+		Status: 200, Response: MonitorConfig{}, Errors: []int{401}},
+	{Method: "POST", Path: "/api/v1/config/apply", Summary: "Make the organization's apps match a configuration file", Tag: "config", Auth: apiAuthAny, Org: true,
+		Query: []apiParam{
+			{Name: "dry_run", Type: "boolean", Description: "true returns the changes without making them"},
+			{Name: "prune", Type: "boolean", Description: "true also deletes apps the file doesn't list"},
+		},
+		Request: MonitorConfig{}, Status: 200, Response: ConfigApplyResponse{}, Errors: []int{400, 401, 403, 409}},
+	{Method: "GET", Path: "/api/v1/status-pages", Summary: "List status pages", Tag: "status-pages", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: StatusPageListResponse{}, Errors: []int{401}},
+	{Method: "POST", Path: "/api/v1/status-pages", Summary: "Create a status page for several apps", Tag: "status-pages", Auth: apiAuthAny, Org: true,
+		Request: StatusPageRequest{}, Status: 201, Response: StatusPageResponse{}, Errors: []int{400, 401, 403, 409}},
+	{Method: "GET", Path: "/api/v1/status-pages/{pageId}", Summary: "Get a status page", Tag: "status-pages", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: StatusPageResponse{}, Errors: []int{401, 404}},
+	{Method: "PUT", Path: "/api/v1/status-pages/{pageId}", Summary: "Replace a status page's settings and layout", Tag: "status-pages", Auth: apiAuthAny, Org: true,
+		Request: StatusPageRequest{}, Status: 200, Response: StatusPageResponse{}, Errors: []int{400, 401, 403, 404, 409}},
+	{Method: "DELETE", Path: "/api/v1/status-pages/{pageId}", Summary: "Delete a status page", Tag: "status-pages", Auth: apiAuthAny, Org: true,
+		Status: 204, Errors: []int{401, 403, 404}},
+	{Method: "GET", Path: "/api/v1/status-pages/{pageId}/domain", Summary: "Get a status page's custom domain and its DNS records", Tag: "status-pages", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: DomainResponse{}, Errors: []int{401, 404}},
+	{Method: "PUT", Path: "/api/v1/status-pages/{pageId}/domain", Summary: "Set a status page's custom domain", Tag: "status-pages", Auth: apiAuthAny, Org: true,
+		Request: SetDomainRequest{}, Status: 200, Response: DomainResponse{}, Errors: []int{400, 401, 403, 404}},
+	{Method: "POST", Path: "/api/v1/status-pages/{pageId}/domain/verify", Summary: "Verify a custom domain by its DNS TXT record", Tag: "status-pages", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: DomainResponse{}, Errors: []int{400, 401, 403, 404, 409}},
+	{Method: "DELETE", Path: "/api/v1/status-pages/{pageId}/domain", Summary: "Remove a status page's custom domain", Tag: "status-pages", Auth: apiAuthAny, Org: true,
+		Status: 204, Errors: []int{401, 403, 404}},
+
+	// Dashboard
+	{Method: "GET", Path: "/api/user-status", Summary: "Get the signed-in user", Tag: "dashboard", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: UserStatusResponse{}, Errors: []int{401}},
+	{Method: "GET", Path: "/api/user-apps", Summary: "List apps with plan usage", Tag: "dashboard", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: UserAppsResponse{}, Errors: []int{401}},
+	{Method: "DELETE", Path: "/api/apps/{appId}", Summary: "Delete an app (use DELETE /api/v1/apps/{appId} instead)", Tag: "dashboard", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: SuccessResponse{}, Errors: []int{400, 401, 403, 404}},
+	{Method: "GET", Path: "/api/check-plan-limit", Summary: "Check whether another app fits the plan", Tag: "dashboard", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: PlanLimitResponse{}, Errors: []int{401}},
+	{Method: "GET", Path: "/api/plan-features", Summary: "Get the limits of the plan", Tag: "dashboard", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: PlanFeaturesResponse{}, Errors: []int{401}},
+	{Method: "GET", Path: "/api/audit-log", Summary: "List the organization's audit log", Tag: "dashboard", Auth: apiAuthAny, Org: true,
+		Query: []apiParam{
+			{Name: "limit", Type: "integer", Description: "At most this many entries, 100 by default"},
+			{Name: "action", Type: "string", Description: "Only entries with this action"},
+			{Name: "format", Type: "string", Description: "csv for a CSV download"},
+		},
+		Status: 200, Response: []auditEntrySchema{}, Errors: []int{400, 401}},
+	{Method: "POST", Path: "/api/go-to-dashboard", Summary: "Create the first app from the onboarding form, as JSON or as a multipart form with a logo", Tag: "dashboard", Auth: apiAuthAny, Org: true,
+		Request: OnboardingRequest{}, Status: 200, Response: OnboardingAppResponse{}, Errors: []int{400, 401, 403, 409},
+		ErrorBodies: map[int]interface{}{403: PlanLimitReachedResponse{}}},
+	{Method: "GET", Path: "/api/latest-status", Summary: "Get the newest check of the user's first app (use GET /api/v1/apps/{appId}/checks instead)", Tag: "dashboard", Auth: apiAuthAny,
+		Status: 200, Response: LatestStatusResponse{}, Errors: []int{401, 404}},
+	{Method: "POST", Path: "/api/update-theme", Summary: "Change an app's theme", Tag: "dashboard", Auth: apiAuthAny, Org: true,
+		Request: UpdateThemeRequest{}, Status: 200, Response: ThemeResponse{}, Errors: []int{400, 401, 403, 404}},
+
+	// Billing, owners only; 503 when Stripe isn't configured
+	{Method: "POST", Path: "/api/create-checkout-session", Summary: "Start a Stripe checkout for a paid plan", Tag: "billing", Auth: apiAuthAny, Org: true,
+		Request: CheckoutRequest{}, Status: 200, Response: RedirectURLResponse{}, Errors: []int{400, 401, 403, 503}},
+	{Method: "POST", Path: "/api/create-portal-session", Summary: "Open the Stripe billing portal to change or cancel the subscription", Tag: "billing", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: RedirectURLResponse{}, Errors: []int{401, 403, 404, 503}},
+
+	// Integrations, on the Pro and Business plans; organization admins make changes
+	{Method: "GET", Path: "/api/slack/start-auth", Summary: "Start connecting Slack", Tag: "integrations", Auth: apiAuthSession, Org: true,
+		Status: 200, Response: OAuthURLResponse{}, Errors: []int{401, 403},
+		ErrorBodies: map[int]interface{}{403: IntegrationErrorResponse{}}},
+	{Method: "POST", Path: "/api/slack/save-integration", Summary: "Save the Slack workspace and channel alerts go to", Tag: "integrations", Auth: apiAuthAny, Org: true,
+		Request: SaveSlackIntegrationRequest{}, Status: 200, Response: SlackIntegrationResponse{}, Errors: []int{400, 401, 403},
+		ErrorBodies: map[int]interface{}{400: IntegrationErrorResponse{}, 403: IntegrationErrorResponse{}}},
+	{Method: "GET", Path: "/api/slack/integration", Summary: "Get the Slack integration", Tag: "integrations", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: SlackIntegrationResponse{}, Errors: []int{401, 403},
+		ErrorBodies: map[int]interface{}{403: IntegrationErrorResponse{}}},
+	{Method: "POST", Path: "/api/slack/disable", Summary: "Stop sending alerts to Slack", Tag: "integrations", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: SuccessResponse{}, Errors: []int{401, 403},
+		ErrorBodies: map[int]interface{}{403: IntegrationErrorResponse{}}},
+	{Method: "GET", Path: "/api/discord/start-auth", Summary: "Start connecting Discord", Tag: "integrations", Auth: apiAuthSession, Org: true,
+		Status: 200, Response: OAuthURLResponse{}, Errors: []int{401, 403},
+		ErrorBodies: map[int]interface{}{403: IntegrationErrorResponse{}}},
+	{Method: "GET", Path: "/api/discord/integration", Summary: "Get the Discord integration", Tag: "integrations", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: DiscordIntegrationResponse{}, Errors: []int{401, 403},
+		ErrorBodies: map[int]interface{}{403: IntegrationErrorResponse{}}},
+	{Method: "POST", Path: "/api/discord/webhook", Summary: "Send alerts to a Discord channel webhook", Tag: "integrations", Auth: apiAuthAny, Org: true,
+		Request: DiscordWebhookRequest{}, Status: 200, Response: SuccessResponse{}, Errors: []int{400, 401, 403},
+		ErrorBodies: map[int]interface{}{400: IntegrationErrorResponse{}, 403: IntegrationErrorResponse{}}},
+	{Method: "POST", Path: "/api/discord/disable", Summary: "Stop sending alerts to Discord", Tag: "integrations", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: SuccessResponse{}, Errors: []int{401, 403},
+		ErrorBodies: map[int]interface{}{403: IntegrationErrorResponse{}}},
+
+	// Organizations
+	{Method: "GET", Path: "/api/orgs", Summary: "List the user's organizations", Tag: "orgs", Auth: apiAuthAny, Org: true,
+		Status: 200, Response: OrgListResponse{}, Errors: []int{401}},
+	{Method: "POST", Path: "/api/orgs", Summary: "Create a team organization", Tag: "orgs", Auth: apiAuthAny,
+		Request: CreateOrgRequest{}, Status: 201, Response: OrgResponse{}, Errors: []int{400, 401}},
+	{Method: "POST", Path: "/api/orgs/{orgId}/switch", Summary: "Make an organization the active one", Tag: "orgs", Auth: apiAuthSession,
+		Status: 200, Response: SwitchOrgResponse{}, Errors: []int{401, 403, 404}},
+	{Method: "GET", Path: "/api/orgs/{orgId}/members", Summary: "List members", Tag: "orgs", Auth: apiAuthAny,
+		Status: 200, Response: MemberListResponse{}, Errors: []int{401, 404}},
+	{Method: "PATCH", Path: "/api/orgs/{orgId}/members/{userId}", Summary: "Change a member's role", Tag: "orgs", Auth: apiAuthAny,
+		Request: UpdateMemberRequest{}, Status: 200, Response: MemberRoleResponse{}, Errors: []int{400, 401, 403, 404, 409}},
+	{Method: "DELETE", Path: "/api/orgs/{orgId}/members/{userId}", Summary: "Remove a member", Tag: "orgs", Auth: apiAuthAny,
+		Status: 204, Errors: []int{401, 403, 404, 409}},
+	{Method: "GET", Path: "/api/orgs/{orgId}/invitations", Summary: "List pending invitations", Tag: "orgs", Auth: apiAuthAny,
+		Status: 200, Response: InvitationListResponse{}, Errors: []int{401, 403, 404}},
+	{Method: "POST", Path: "/api/orgs/{orgId}/invitations", Summary: "Invite someone by email", Tag: "orgs", Auth: apiAuthAny,
+		Request: CreateInvitationRequest{}, Status: 201, Response: InvitationResponse{}, Errors: []int{400, 401, 403, 404}},
+	{Method: "DELETE", Path: "/api/orgs/{orgId}/invitations/{invitationId}", Summary: "Revoke an invitation", Tag: "orgs", Auth: apiAuthAny,
+		Status: 204, Errors: []int{401, 403, 404}},
+	{Method: "GET", Path: "/api/orgs/{orgId}/security", Summary: "Get security settings", Tag: "orgs", Auth: apiAuthAny,
+		Status: 200, Response: OrgSecurityResponse{}, Errors: []int{401, 403, 404}},
+	{Method: "PUT", Path: "/api/orgs/{orgId}/security", Summary: "Change security settings", Tag: "orgs", Auth: apiAuthSession,
+		Request: UpdateOrgSecurityRequest{}, Status: 200, Response: OrgSecurityResponse{}, Errors: []int{400, 401, 403, 404}},
+	{Method: "POST", Path: "/api/invitations/accept", Summary: "Accept an invitation", Tag: "orgs", Auth: apiAuthSession,
+		Request: AcceptInvitationRequest{}, Status: 200, Response: OrgResponse{}, Errors: []int{400, 401, 403, 404, 410}},
+
+	// Account
+	{Method: "GET", Path: "/api/audit-log/personal", Summary: "List changes to your own account, like two-factor authentication", Tag: "account", Auth: apiAuthAny,
+		Query: []apiParam{
+			{Name: "limit", Type: "integer", Description: "At most this many entries, 100 by default"},
+			{Name: "action", Type: "string", Description: "Only entries with this action"},
+			{Name: "format", Type: "string", Description: "csv for a CSV download"},
+		},
+		Status: 200, Response: []auditEntrySchema{}, Errors: []int{400, 401}},
+	{Method: "GET", Path: "/api/api-keys", Summary: "List API keys", Tag: "account", Auth: apiAuthSession,
+		Status: 200, Response: APIKeyListResponse{}, Errors: []int{401}},
+	{Method: "POST", Path: "/api/api-keys", Summary: "Create an API key", Tag: "account", Auth: apiAuthSession,
+		Request: CreateAPIKeyRequest{}, Status: 201, Response: CreateAPIKeyResponse{}, Errors: []int{400, 401, 403}},
+	{Method: "DELETE", Path: "/api/api-keys/{keyId}", Summary: "Revoke an API key", Tag: "account", Auth: apiAuthSession,
+		Status: 200, Response: SuccessResponse{}, Errors: []int{400, 401, 404}},
+	{Method: "GET", Path: "/api/2fa", Summary: "Get two-factor authentication status", Tag: "account", Auth: apiAuthSession,
+		Status: 200, Response: TwoFactorStatusResponse{}, Errors: []int{401}},
+	{Method: "POST", Path: "/api/2fa/setup", Summary: "Start two-factor setup with a new secret", Tag: "account", Auth: apiAuthSession,
+		Status: 200, Response: TwoFactorSetupResponse{}, Errors: []int{401, 409}},
+	{Method: "POST", Path: "/api/2fa/enable", Summary: "Turn on two-factor authentication", Tag: "account", Auth: apiAuthSession,
+		Request: TwoFactorCodeRequest{}, Status: 200, Response: TwoFactorChangeResponse{}, Errors: []int{400, 401, 409}},
+	{Method: "POST", Path: "/api/2fa/disable", Summary: "Turn off two-factor authentication", Tag: "account", Auth: apiAuthSession,
+		Request: TwoFactorCodeRequest{}, Status: 200, Response: TwoFactorChangeResponse{}, Errors: []int{400, 401, 403}},
+	{Method: "POST", Path: "/api/2fa/recovery-codes", Summary: "Replace the recovery codes", Tag: "account", Auth: apiAuthSession,
+		Request: TwoFactorCodeRequest{}, Status: 200, Response: RecoveryCodesResponse{}, Errors: []int{400, 401}},
+	{Method: "GET", Path: "/api/sessions", Summary: "List signed-in browsers", Tag: "account", Auth: apiAuthSession,
+		Status: 200, Response: SessionListResponse{}, Errors: []int{401}},
+	{Method: "DELETE", Path: "/api/sessions", Summary: "Sign out everywhere", Tag: "account", Auth: apiAuthSession,
+		Query:  []apiParam{{Name: "keep_current", Type: "boolean", Description: "true keeps this browser signed in"}},
+		Status: 200, Response: RevokeSessionsResponse{}, Errors: []int{401}},
+	{Method: "DELETE", Path: "/api/sessions/{sessionId}", Summary: "Sign out one browser", Tag: "account", Auth: apiAuthSession,
+		Status: 200, Response: SuccessResponse{}, Errors: []int{400, 401, 404}},
+
+	// Signing in
+	{Method: "GET", Path: "/auth/providers", Summary: "List the ways to sign in", Tag: "auth",
+		Status: 200, Response: LoginMethodsResponse{}},
+	{Method: "POST", Path: "/auth/email", Summary: "Email a one-time sign-in link; the answer is the same for unknown addresses", Tag: "auth",
+		Request: MagicLinkRequest{}, Status: 202, Response: SuccessResponse{}, Errors: []int{400, 404}},
+	{Method: "GET", Path: "/auth/check-session", Summary: "Find out whether the browser is signed in", Tag: "auth", Auth: apiAuthSession,
+		Status: 200, Response: CheckSessionResponse{}, Errors: []int{401}},
+
+	// Admin console
+	{Method: "GET", Path: "/api/admin/check-session", Summary: "Find out whether the session may use the admin console", Tag: "admin",
+		Status: 200, Response: AdminSessionResponse{}},
+	{Method: "POST", Path: "/api/admin/impersonation/stop", Summary: "Switch an impersonated session back to the admin", Tag: "admin", Auth: apiAuthSession,
+		Status: 204, Errors: []int{400}},
+	{Method: "GET", Path: "/api/admin/users", Summary: "List every user with their plan and usage", Tag: "admin", Auth: apiAuthAdmin,
+		Status: 200, Response: []AdminUser{}, Errors: []int{401, 403}},
+	{Method: "GET", Path: "/api/admin/stats", Summary: "Get platform totals", Tag: "admin", Auth: apiAuthAdmin,
+		Status: 200, Response: AdminStats{}, Errors: []int{401, 403}},
+	{Method: "PUT", Path: "/api/admin/users/{userId}/plan", Summary: "Change the plan of a user's personal organization without Stripe", Tag: "admin", Auth: apiAuthAdmin,
+		Request: AdminPlanRequest{}, Status: 200, Response: AdminPlanResponse{}, Errors: []int{400, 401, 403, 404}},
+	{Method: "PUT", Path: "/api/admin/orgs/{orgId}/plan", Summary: "Change an organization's plan without Stripe", Tag: "admin", Auth: apiAuthAdmin,
+		Request: AdminPlanRequest{}, Status: 200, Response: AdminPlanResponse{}, Errors: []int{400, 401, 403, 404}},
+	{Method: "PUT", Path: "/api/admin/users/{userId}/admin", Summary: "Grant or revoke the admin role", Tag: "admin", Auth: apiAuthAdmin,
+		Request: AdminRoleRequest{}, Status: 200, Response: AdminRoleResponse{}, Errors: []int{400, 401, 403, 404}},
+	{Method: "POST", Path: "/api/admin/users/{userId}/disable", Summary: "Block an account from signing in and using its API keys", Tag: "admin", Auth: apiAuthAdmin,
+		Request: DisableUserRequest{}, Status: 204, Errors: []int{400, 401, 403, 404}},
+	{Method: "POST", Path: "/api/admin/users/{userId}/enable", Summary: "Let a disabled account sign in again", Tag: "admin", Auth: apiAuthAdmin,
+		Status: 204, Errors: []int{400, 401, 403, 404}},
+	{Method: "POST", Path: "/api/admin/users/{userId}/impersonate", Summary: "Browse as a user, read-only, for a limited time", Tag: "admin", Auth: apiAuthAdmin,
+		Status: 200, Response: ImpersonationResponse{}, Errors: []int{400, 401, 403, 404}},
+	{Method: "POST", Path: "/api/admin/apps/{appId}/pause", Summary: "Pause any app's health checks", Tag: "admin", Auth: apiAuthAdmin,
+		Status: 200, Response: AdminPauseResponse{}, Errors: []int{400, 401, 403, 404}},
+	{Method: "POST", Path: "/api/admin/apps/{appId}/resume", Summary: "Resume any app's health checks", Tag: "admin", Auth: apiAuthAdmin,
+		Status: 200, Response: AdminPauseResponse{}, Errors: []int{400, 401, 403, 404}},
+	{Method: "GET", Path: "/api/admin/probes", Summary: "List the probe agents", Tag: "admin", Auth: apiAuthAdmin,
+		Status: 200, Response: []db.ProbeAgent{}, Errors: []int{401, 403}},
+	{Method: "POST", Path: "/api/admin/probes/{agentId}/disable", Summary: "Refuse a probe agent's polls and results", Tag: "admin", Auth: apiAuthAdmin,
+		Status: 204, Errors: []int{400, 401, 403, 404}},
+	{Method: "POST", Path: "/api/admin/probes/{agentId}/enable", Summary: "Let a disabled probe agent poll and report again", Tag: "admin", Auth: apiAuthAdmin,
+		Status: 204, Errors: []int{400, 401, 403, 404}},
+	{Method: "GET", Path: "/api/admin/audit-log", Summary: "List the audit log of every organization and admin action", Tag: "admin", Auth: apiAuthAdmin,
+		Query: []apiParam{
+			{Name: "limit", Type: "integer", Description: "At most this many entries, 100 by default"},
+			{Name: "action", Type: "string", Description: "Only entries with this action"},
+			{Name: "org_id", Type: "integer", Description: "Only entries of this organization"},
+			{Name: "actor_id", Type: "integer", Description: "Only entries by this user"},
+			{Name: "format", Type: "string", Description: "csv for a CSV download"},
+		},
+		Status: 200, Response: []auditEntrySchema{}, Errors: []int{400, 401, 403}},
+
+	// Public, limited per visitor, and live pings per status page too
+	{Method: "GET", Path: "/api/public/status/{slug}", Summary: "Get a public status page", Tag: "public",
+		Status: 200, Response: PublicStatusResponse{}, Errors: []int{401, 403, 404, 429}},
+	{Method: "GET", Path: "/api/public/status/{slug}/feed.rss", Summary: "Get an app's status changes and incident updates as RSS", Tag: "public",
+		Status: 200, Response: feedDocument(strings.Split(rssContentType, ";")[0]), Errors: []int{401, 403, 404, 429}},
+	{Method: "GET", Path: "/api/public/status/{slug}/feed.atom", Summary: "Get an app's status changes and incident updates as Atom", Tag: "public",
+		Status: 200, Response: feedDocument(strings.Split(atomContentType, ";")[0]), Errors: []int{401, 403, 404, 429}},
+	{Method: "GET", Path: "/api/public/status/{slug}/feed.json", Summary: "Get an app's status changes and incident updates as a JSON Feed", Tag: "public",
+		Status: 200, Response: JSONFeed{}, Errors: []int{401, 403, 404, 429}},
+	{Method: "POST", Path: "/api/public/status/{slug}/subscribe", Summary: "Subscribe an email address or webhook to an app's status changes (a verified webhook gets 201)", Tag: "public",
+		Request: SubscribeRequest{}, Status: 202, Response: SubscribeResponse{}, Errors: []int{400, 403, 404, 429}},
+	{Method: "POST", Path: "/api/public/status/{slug}/unlock", Summary: "Unlock a password protected status page with an access cookie", Tag: "public",
+		Request: UnlockRequest{}, Status: 204, Errors: []int{400, 401, 404, 429}},
+	{Method: "GET", Path: "/api/public/status/{slug}/viewer", Summary: "Find out from the session whether the visitor can change the app", Tag: "public",
+		Status: 200, Response: StatusViewerResponse{}, Errors: []int{404, 429}},
+	{Method: "POST", Path: "/api/public/subscriptions/{token}/unsubscribe", Summary: "End a subscription", Tag: "public",
+		Status: 204, Errors: []int{404, 429}},
+	{Method: "GET", Path: "/api/public/pages/{slug}", Summary: "Get a public multi-app status page", Tag: "public",
+		Status: 200, Response: PublicStatusPageResponse{}, Errors: []int{404, 429}},
+	{Method: "GET", Path: "/api/public/ping/{slug}", Summary: "Measure an app's response time now", Tag: "public",
+		Status: 200, Response: PingResponse{}, Errors: []int{401, 403, 404, 429}},
+	{Method: "GET", Path: "/api/badge/{slug}", Summary: "Get an SVG uptime badge", Tag: "public",
+		Status: 200, Response: svgBadge{}, Errors: []int{429}},
+	{Method: "GET", Path: "/api/openapi.json", Summary: "Get this document", Tag: "public",
+		Status: 200, Response: OpenAPIDocument{}},
+
+	// Probe agents in other regions; 404 when PROBE_TOKEN isn't set
+	{Method: "POST", Path: "/api/probe/register", Summary: "Register a probe agent for a region", Tag: "probes", Auth: apiAuthProbe,
+		Request: probe.RegisterRequest{}, Status: 200, Response: probe.RegisterResponse{}, Errors: []int{400, 401, 403, 404}},
+	{Method: "GET", Path: "/api/probe/checks", Summary: "List the checks a probe agent should run", Tag: "probes", Auth: apiAuthProbe,
+		Query:  []apiParam{{Name: "agent_id", Type: "integer", Description: "The ID the agent got when it registered"}},
+		Status: 200, Response: []probe.Assignment{}, Errors: []int{400, 401, 403, 404}},
+	{Method: "POST", Path: "/api/probe/results", Summary: "Report check results from a probe agent's region", Tag: "probes", Auth: apiAuthProbe,
+		Request: probe.ResultsRequest{}, Status: 200, Response: ProbeResultsResponse{}, Errors: []int{400, 401, 403, 404}},
+
+	// Liveness and readiness for load balancers and orchestrators
+	{Method: "GET", Path: "/healthz", Summary: "Find out whether the process is up", Tag: "operations",
+		Status: 200, Response: LivenessResponse{}},
+	{Method: "GET", Path: "/readyz", Summary: "Find out whether the server can take traffic; 503 with the same body when it can't", Tag: "operations",
+		Status: 200, Response: ReadinessResponse{}, Errors: []int{503},
+		ErrorBodies: map[int]interface{}{503: ReadinessResponse{}}},
+}
+
+// svgBadge marks a response that is an SVG image rather than JSON
+type svgBadge struct{}
+
+// feedDocument marks a response that is an XML feed of the given media type
+type feedDocument string
+
+// auditEntrySchema documents db.AuditEntry, whose before, after and details are free-form JSON
+type auditEntrySchema struct {
+	ID          int64       `json:"id"`
+	ActorUserID *int        `json:"actor_user_id"`
+	ActorName   *string     `json:"actor_name,omitempty"`
+	Action      string      `json:"action"`
+	TargetType  string      `json:"target_type"`
+	TargetID    *int        `json:"target_id"`
+	OrgID       *int        `json:"org_id"`
+	Before      interface{} `json:"before"`
+	After       interface{} `json:"after"`
+	Details     interface{} `json:"details"`
+	IPAddress   string      `json:"ip_address"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// OpenAPIHandler serves the OpenAPI 3 document of the JSON API
+func (h *Handler) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	h.openAPIOnce.Do(func() {
+		var err error
+		h.openAPIDoc, err = json.Marshal(OpenAPISpec(h.cfg.Server.PublicURL))
+		if err != nil {
+			log.Printf("Error encoding OpenAPI document: %v", err)
+		}
+	})
+	if h.openAPIDoc == nil {
+		http.Error(w, "Failed to build API document", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*") // so hosted API explorers can load it
+	w.Write(h.openAPIDoc)
+}
+
+// OpenAPISpec builds the OpenAPI 3 document for the endpoints in apiOperations
+func OpenAPISpec(serverURL string) OpenAPIDocument {
+	schemas := &schemaRegistry{schemas: map[string]interface{}{}}
+	paths := map[string]map[string]interface{}{}
+
+	for _, op := range apiOperations {
+		if paths[op.Path] == nil {
+			paths[op.Path] = map[string]interface{}{}
+		}
+		paths[op.Path][strings.ToLower(op.Method)] = op.document(schemas)
+	}
+
+	spec := OpenAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: OpenAPIInfo{
+			Title:   "UpLitycs API",
+			Version: "1.0.0",
+			Description: "Monitors, organizations and status pages. Send an API key as " +
+				"\"Authorization: Bearer <key>\"; managing keys, sessions and two-factor " +
+				"authentication needs a signed-in browser session.",
+		},
+		Paths: paths,
+		Components: OpenAPIComponents{
+			Schemas: schemas.schemas,
+			SecuritySchemes: map[string]interface{}{
+				"apiKey":     map[string]interface{}{"type": "http", "scheme": "bearer", "description": "A personal API key"},
+				"session":    map[string]interface{}{"type": "apiKey", "in": "cookie", "name": "auth-session"},
+				"probeToken": map[string]interface{}{"type": "http", "scheme": "bearer", "description": "The PROBE_TOKEN shared with probe agents"},
+			},
+		},
+	}
+	if serverURL != "" {
+		spec.Servers = []OpenAPIServer{{URL: strings.TrimRight(serverURL, "/")}}
+	}
+	return spec
+}
+
+func (op apiOperation) document(schemas *schemaRegistry) map[string]interface{} {
+	doc := map[string]interface{}{
+		"summary":     op.Summary,
+		"tags":        []string{op.Tag},
+		"operationId": operationID(op.Method, op.Path),
+	}
+
+	var params []interface{}
+	for _, segment := range strings.Split(op.Path, "/") {
+		if !strings.HasPrefix(segment, "{") {
+			continue
+		}
+		name := strings.Trim(segment, "{}")
+		kind := "string"
+		if strings.HasSuffix(name, "Id") {
+			kind = "integer"
+		}
+		params = append(params, map[string]interface{}{
+			"name": name, "in": "path", "required": true, "schema": map[string]interface{}{"type": kind},
+		})
+	}
+	for _, q := range op.Query {
+		params = append(params, map[string]interface{}{
+			"name": q.Name, "in": "query", "description": q.Description, "schema": map[string]interface{}{"type": q.Type},
+		})
+	}
+	if op.Org {
+		params = append(params, map[string]interface{}{
+			"name": "X-Org-Id", "in": "header", "description": "The organization to act on, instead of the active one",
+			"schema": map[string]interface{}{"type": "integer"},
+		})
+	}
+	if params != nil {
+		doc["parameters"] = params
+	}
+
+	if op.Request != nil {
+		doc["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  jsonContent(schemas, op.Request),
+		}
+	}
+
+	responses := map[string]interface{}{}
+	success := map[string]interface{}{"description": http.StatusText(op.Status)}
+	switch op.Response.(type) {
+	case nil:
+	case svgBadge:
+		success["content"] = map[string]interface{}{
+			"image/svg+xml": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+		}
+	case feedDocument:
+		success["content"] = map[string]interface{}{
+			string(op.Response.(feedDocument)): map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+		}
+	default:
+		success["content"] = jsonContent(schemas, op.Response)
+	}
+	responses[strconv.Itoa(op.Status)] = success
+
+	// The v1 handlers answer errors with a JSON body. The older ones, and AuthMiddleware
+	// in front of all of them, answer with plain text.
+	textError := map[string]interface{}{
+		"text/plain": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+	}
+	jsonError := map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schemas.schema(reflect.TypeOf(ErrorResponse{}))},
+	}
+	for _, status := range op.Errors {
+		content := textError
+		if body, ok := op.ErrorBodies[status]; ok {
+			content = jsonContent(schemas, body)
+		} else if strings.HasPrefix(op.Path, "/api/v1/") && status != http.StatusUnauthorized {
+			content = jsonError
+		}
+		responses[strconv.Itoa(status)] = map[string]interface{}{
+			"description": http.StatusText(status),
+			"content":     content,
+		}
+	}
+	doc["responses"] = responses
+
+	switch op.Auth {
+	case apiAuthSession, apiAuthAdmin:
+		doc["security"] = []interface{}{map[string]interface{}{"session": []string{}}}
+		if op.Auth == apiAuthAdmin {
+			doc["description"] = "Only for platform admins, and not while impersonating someone."
+		}
+	case apiAuthProbe:
+		doc["security"] = []interface{}{map[string]interface{}{"probeToken": []string{}}}
+	case apiAuthAny:
+		doc["security"] = []interface{}{
+			map[string]interface{}{"apiKey": []string{}},
+			map[string]interface{}{"session": []string{}},
+		}
+	default:
+		doc["security"] = []interface{}{}
+	}
+	return doc
+}
+
+// operationID turns "GET /api/v1/apps/{appId}" into "getV1AppsAppId"
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, segment := range strings.Split(strings.TrimPrefix(path, "/api/"), "/") {
+		segment = strings.Trim(segment, "{}")
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '.' }) {
+			id += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return id
+}
+
+// jsonContent describes a JSON body. Monitor configuration files can also be YAML.
+func jsonContent(schemas *schemaRegistry, body interface{}) map[string]interface{} {
+	schema := map[string]interface{}{"schema": schemas.schema(reflect.TypeOf(body))}
+	content := map[string]interface{}{"application/json": schema}
+	if _, ok := body.(MonitorConfig); ok {
+		content["application/yaml"] = schema
+	}
+	return content
+}
+
+// schemaRegistry turns Go types into JSON schemas. Named structs become components
+// that are referenced by name.
+type schemaRegistry struct {
+	schemas map[string]interface{}
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+func (s *schemaRegistry) schema(t reflect.Type) map[string]interface{} {
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		inner := s.schema(t.Elem())
+		if _, isRef := inner["$ref"]; isRef {
+			return map[string]interface{}{"allOf": []interface{}{inner}, "nullable": true}
+		}
+		inner["nullable"] = true
+		return inner
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Uint, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		name := strings.TrimSuffix(t.Name(), "Schema")
+		if name == "" {
+			return s.object(t)
+		}
+		name = strings.ToUpper(name[:1]) + name[1:]
+		if _, seen := s.schemas[name]; !seen {
+			s.schemas[name] = map[string]interface{}{} // placeholder for self-references
+			s.schemas[name] = s.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	default:
+		// interface{} and anything else holds free-form JSON
+		return map[string]interface{}{}
+	}
+}
+
+// object describes a struct by its JSON fields. Fields without omitempty are required.
+func (s *schemaRegistry) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	s.addFields(t, properties, &required)
+
+	obj := map[string]interface{}{"type": "object", "properties": properties}
+	if required != nil {
+		obj["required"] = required
+	}
+	return obj
+}
+
+func (s *schemaRegistry) addFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		// Embedded structs without a name of their own are flattened, like encoding/json does
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			s.addFields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = s.schema(field.Type)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,615 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/mail"
+	"statusframe/backend/auth"
+	"statusframe/db"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// invitationTTL is how long an emailed invitation link stays valid
+const invitationTTL = 7 * 24 * time.Hour
+
+// Mailer sends transactional email such as invitations
+type Mailer interface {
+	Send(to, subject, htmlBody, textBody string) error
+}
+
+// SetMailer enables sending invitation emails. Without one, invitation links are only logged.
+func (h *Handler) SetMailer(mailer Mailer) {
+	h.mailer = mailer
+}
+
+type CreateOrgRequest struct {
+	Name string `json:"name"`
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role"`
+}
+
+type CreateInvitationRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+// OrgMiddleware resolves the organization a request acts on and the caller's role in it.
+// The X-Org-Id header wins, then the organization picked with /api/orgs/{orgId}/switch,
+// then the user's personal organization. Use it after AuthMiddleware.
+func (h *Handler) OrgMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value("userId").(int)
+
+		orgId := 0
+		explicit := false
+		if header := r.Header.Get("X-Org-Id"); header != "" {
+			id, err := strconv.Atoi(header)
+			if err != nil {
+				http.Error(w, "Invalid X-Org-Id header", http.StatusBadRequest)
+				return
+			}
+			orgId, explicit = id, true
+		} else if method, _ := r.Context().Value("authMethod").(string); method == auth.AuthMethodSession {
+			if session, err := auth.Store.Get(r, "auth-session"); err == nil {
+				orgId, _ = session.Values["activeOrgId"].(int)
+			}
+		}
+
+		role := ""
+		if orgId != 0 {
+			var needs2FA bool
+			var err error
+			role, needs2FA, err = db.GetOrgAccess(h.conn, orgId, userId)
+			if err != nil {
+				log.Printf("Error checking membership of user %d in org %d: %v", userId, orgId, err)
+				http.Error(w, "Failed to load organization", http.StatusInternalServerError)
+				return
+			}
+			if role == "" && explicit {
+				http.Error(w, "Forbidden - you are not a member of this organization", http.StatusForbidden)
+				return
+			}
+			if needs2FA {
+				if explicit {
+					http.Error(w, errTwoFactorRequired, http.StatusForbidden)
+					return
+				}
+				// The organization started requiring 2FA after the user switched to it
+				role = ""
+			}
+		}
+
+		// The switched-to organization may have removed the user since; fall back to their own
+		if role == "" {
+			user, err := db.GetUserById(h.conn, userId)
+			if err != nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			orgId, err = db.EnsurePersonalOrg(h.conn, userId, user.Name)
+			if err != nil {
+				log.Printf("Error loading personal org of user %d: %v", userId, err)
+				http.Error(w, "Failed to load organization", http.StatusInternalServerError)
+				return
+			}
+			role = db.RoleOwner
+		}
+
+		ctx := context.WithValue(r.Context(), "orgId", orgId)
+		ctx = context.WithValue(ctx, "orgRole", role)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// orgFromContext returns the organization and role set by OrgMiddleware
+func orgFromContext(r *http.Request) (int, string) {
+	orgId, _ := r.Context().Value("orgId").(int)
+	role, _ := r.Context().Value("orgRole").(string)
+	return orgId, role
+}
+
+// hasOrgRole reports whether the caller has at least the given role in the active organization
+func hasOrgRole(r *http.Request, min string) bool {
+	_, role := orgFromContext(r)
+	return db.RoleAtLeast(role, min)
+}
+
+// requireOrgRole writes a 403 and returns false unless the caller has at least the given role
+func requireOrgRole(w http.ResponseWriter, r *http.Request, min string) bool {
+	if !hasOrgRole(r, min) {
+		http.Error(w, fmt.Sprintf("Forbidden - requires the %s role in this organization", min), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// pathOrgRole loads the caller's role in the organization named in the URL. Non-members get a 404.
+func (h *Handler) pathOrgRole(w http.ResponseWriter, r *http.Request) (int, string, bool) {
+	userId := r.Context().Value("userId").(int)
+
+	orgId, err := strconv.Atoi(chi.URLParam(r, "orgId"))
+	if err != nil {
+		http.Error(w, "Invalid organization ID", http.StatusBadRequest)
+		return 0, "", false
+	}
+
+	role, err := db.GetOrgRole(h.conn, orgId, userId)
+	if err != nil {
+		log.Printf("Error checking membership of user %d in org %d: %v", userId, orgId, err)
+		http.Error(w, "Failed to load organization", http.StatusInternalServerError)
+		return 0, "", false
+	}
+	if role == "" {
+		http.Error(w, "Organization not found", http.StatusNotFound)
+		return 0, "", false
+	}
+	return orgId, role, true
+}
+
+// GetOrgsHandler lists the caller's organizations and which one is active
+func (h *Handler) GetOrgsHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+	activeOrgId, _ := orgFromContext(r)
+
+	orgs, err := db.GetUserOrgs(h.conn, userId)
+	if err != nil {
+		log.Printf("Error fetching orgs for user %d: %v", userId, err)
+		http.Error(w, "Failed to fetch organizations", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, OrgListResponse{
+		Organizations: orgs,
+		ActiveOrgID:   activeOrgId,
+	})
+}
+
+// CreateOrgHandler creates a team organization owned by the caller
+func (h *Handler) CreateOrgHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	var req CreateOrgRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 100 {
+		http.Error(w, "Name is required and must be at most 100 characters", http.StatusBadRequest)
+		return
+	}
+
+	org, err := db.CreateOrg(h.conn, req.Name, userId)
+	if err != nil {
+		log.Printf("Error creating org for user %d: %v", userId, err)
+		http.Error(w, "Failed to create organization", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("🏢 Organization %d (%s) created by user %d", org.Id, org.Name, userId)
+	respondJSON(w, http.StatusCreated, OrgResponse{Organization: org})
+}
+
+// SwitchOrgHandler makes an organization the active one for the browser session
+func (h *Handler) SwitchOrgHandler(w http.ResponseWriter, r *http.Request) {
+	orgId, role, ok := h.pathOrgRole(w, r)
+	if !ok {
+		return
+	}
+
+	_, needs2FA, err := db.GetOrgAccess(h.conn, orgId, r.Context().Value("userId").(int))
+	if err != nil {
+		log.Printf("Error checking access to org %d: %v", orgId, err)
+		http.Error(w, "Failed to switch organization", http.StatusInternalServerError)
+		return
+	}
+	if needs2FA {
+		http.Error(w, errTwoFactorRequired, http.StatusForbidden)
+		return
+	}
+
+	session, err := auth.Store.Get(r, "auth-session")
+	if err != nil {
+		http.Error(w, "Failed to switch organization", http.StatusInternalServerError)
+		return
+	}
+	session.Values["activeOrgId"] = orgId
+	if err := session.Save(r, w); err != nil {
+		log.Printf("Error saving session: %v", err)
+		http.Error(w, "Failed to switch organization", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, SwitchOrgResponse{
+		ActiveOrgID: orgId,
+		Role:        role,
+	})
+}
+
+// GetOrgMembersHandler lists the members of an organization
+func (h *Handler) GetOrgMembersHandler(w http.ResponseWriter, r *http.Request) {
+	orgId, _, ok := h.pathOrgRole(w, r)
+	if !ok {
+		return
+	}
+
+	members, err := db.GetOrgMembers(h.conn, orgId)
+	if err != nil {
+		log.Printf("Error fetching members of org %d: %v", orgId, err)
+		http.Error(w, "Failed to fetch members", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, MemberListResponse{Members: members})
+}
+
+// memberTarget parses the member in the URL and loads their current role
+func (h *Handler) memberTarget(w http.ResponseWriter, r *http.Request, orgId int) (int, string, bool) {
+	memberId, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return 0, "", false
+	}
+
+	memberRole, err := db.GetOrgRole(h.conn, orgId, memberId)
+	if err != nil {
+		log.Printf("Error loading role of user %d in org %d: %v", memberId, orgId, err)
+		http.Error(w, "Failed to load member", http.StatusInternalServerError)
+		return 0, "", false
+	}
+	if memberRole == "" {
+		http.Error(w, "Member not found", http.StatusNotFound)
+		return 0, "", false
+	}
+	return memberId, memberRole, true
+}
+
+// UpdateOrgMemberHandler changes a member's role. Admins manage members; only owners
+// can grant the owner role or change another owner.
+func (h *Handler) UpdateOrgMemberHandler(w http.ResponseWriter, r *http.Request) {
+	orgId, role, ok := h.pathOrgRole(w, r)
+	if !ok {
+		return
+	}
+	if !db.RoleAtLeast(role, db.RoleAdmin) {
+		http.Error(w, "Forbidden - requires the admin role in this organization", http.StatusForbidden)
+		return
+	}
+
+	memberId, memberRole, ok := h.memberTarget(w, r, orgId)
+	if !ok {
+		return
+	}
+
+	var req UpdateMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !db.ValidRole(req.Role) {
+		http.Error(w, "Invalid role. Must be 'owner', 'admin', 'editor' or 'viewer'", http.StatusBadRequest)
+		return
+	}
+	if (req.Role == db.RoleOwner || memberRole == db.RoleOwner) && role != db.RoleOwner {
+		http.Error(w, "Forbidden - only owners can grant or change the owner role", http.StatusForbidden)
+		return
+	}
+
+	updated, err := db.UpdateOrgMemberRole(h.conn, orgId, memberId, req.Role)
+	if errors.Is(err, db.ErrLastOwner) {
+		http.Error(w, "An organization must keep at least one owner", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating role of user %d in org %d: %v", memberId, orgId, err)
+		http.Error(w, "Failed to update member", http.StatusInternalServerError)
+		return
+	}
+	if !updated {
+		http.Error(w, "Member not found", http.StatusNotFound)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, MemberRoleResponse{
+		UserID: memberId,
+		Role:   req.Role,
+	})
+}
+
+// RemoveOrgMemberHandler removes a member. Anyone can leave; admins can remove others,
+// and only owners can remove an owner.
+func (h *Handler) RemoveOrgMemberHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	orgId, role, ok := h.pathOrgRole(w, r)
+	if !ok {
+		return
+	}
+
+	memberId, memberRole, ok := h.memberTarget(w, r, orgId)
+	if !ok {
+		return
+	}
+	if memberId != userId {
+		if !db.RoleAtLeast(role, db.RoleAdmin) {
+			http.Error(w, "Forbidden - requires the admin role in this organization", http.StatusForbidden)
+			return
+		}
+		if memberRole == db.RoleOwner && role != db.RoleOwner {
+			http.Error(w, "Forbidden - only owners can remove an owner", http.StatusForbidden)
+			return
+		}
+	}
+
+	removed, err := db.RemoveOrgMember(h.conn, orgId, memberId)
+	if errors.Is(err, db.ErrLastOwner) {
+		http.Error(w, "An organization must keep at least one owner", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Error removing user %d from org %d: %v", memberId, orgId, err)
+		http.Error(w, "Failed to remove member", http.StatusInternalServerError)
+		return
+	}
+	if !removed {
+		http.Error(w, "Member not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetOrgInvitationsHandler lists pending invitations
+func (h *Handler) GetOrgInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	orgId, role, ok := h.pathOrgRole(w, r)
+	if !ok {
+		return
+	}
+	if !db.RoleAtLeast(role, db.RoleAdmin) {
+		http.Error(w, "Forbidden - requires the admin role in this organization", http.StatusForbidden)
+		return
+	}
+
+	invitations, err := db.GetOrgInvitations(h.conn, orgId)
+	if err != nil {
+		log.Printf("Error fetching invitations of org %d: %v", orgId, err)
+		http.Error(w, "Failed to fetch invitations", http.StatusInternalServerError)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, InvitationListResponse{Invitations: invitations})
+}
+
+// CreateOrgInvitationHandler invites someone by email. Inviting the same address again
+// replaces the pending invitation and sends a new link.
+func (h *Handler) CreateOrgInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	orgId, role, ok := h.pathOrgRole(w, r)
+	if !ok {
+		return
+	}
+	if !db.RoleAtLeast(role, db.RoleAdmin) {
+		http.Error(w, "Forbidden - requires the admin role in this organization", http.StatusForbidden)
+		return
+	}
+
+	var req CreateInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	address, err := mail.ParseAddress(strings.TrimSpace(req.Email))
+	if err != nil {
+		http.Error(w, "Invalid email address", http.StatusBadRequest)
+		return
+	}
+	if !db.ValidRole(req.Role) {
+		http.Error(w, "Invalid role. Must be 'owner', 'admin', 'editor' or 'viewer'", http.StatusBadRequest)
+		return
+	}
+	if req.Role == db.RoleOwner && role != db.RoleOwner {
+		http.Error(w, "Forbidden - only owners can invite owners", http.StatusForbidden)
+		return
+	}
+
+	org, err := db.GetOrg(h.conn, orgId)
+	if err != nil {
+		log.Printf("Error loading org %d: %v", orgId, err)
+		http.Error(w, "Failed to create invitation", http.StatusInternalServerError)
+		return
+	}
+	if org.Personal {
+		http.Error(w, "Create a team organization to invite members", http.StatusBadRequest)
+		return
+	}
+
+	token, err := generateInvitationToken()
+	if err != nil {
+		log.Printf("Error generating invitation token: %v", err)
+		http.Error(w, "Failed to create invitation", http.StatusInternalServerError)
+		return
+	}
+
+	invitation, err := db.CreateOrgInvitation(h.conn, orgId, address.Address, req.Role, auth.HashAPIKey(token), userId, time.Now().Add(invitationTTL))
+	if err != nil {
+		log.Printf("Error storing invitation for org %d: %v", orgId, err)
+		http.Error(w, "Failed to create invitation", http.StatusInternalServerError)
+		return
+	}
+
+	h.sendInvitation(org.Name, address.Address, req.Role, token)
+	log.Printf("✉️ User %d invited %s to org %d as %s", userId, address.Address, orgId, req.Role)
+
+	respondJSON(w, http.StatusCreated, InvitationResponse{Invitation: invitation})
+}
+
+// DeleteOrgInvitationHandler revokes a pending invitation
+func (h *Handler) DeleteOrgInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	orgId, role, ok := h.pathOrgRole(w, r)
+	if !ok {
+		return
+	}
+	if !db.RoleAtLeast(role, db.RoleAdmin) {
+		http.Error(w, "Forbidden - requires the admin role in this organization", http.StatusForbidden)
+		return
+	}
+
+	invitationId, err := strconv.Atoi(chi.URLParam(r, "invitationId"))
+	if err != nil {
+		http.Error(w, "Invalid invitation ID", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := db.DeleteOrgInvitation(h.conn, orgId, invitationId)
+	if err != nil {
+		log.Printf("Error deleting invitation %d: %v", invitationId, err)
+		http.Error(w, "Failed to revoke invitation", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AcceptInvitationHandler joins the organization of an invitation sent to the caller's email
+func (h *Handler) AcceptInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	var req AcceptInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	org, err := h.acceptInvitation(userId, req.Token)
+	switch {
+	case errors.Is(err, db.ErrInvitationExpired):
+		http.Error(w, "This invitation has expired. Ask for a new one", http.StatusGone)
+		return
+	case errors.Is(err, db.ErrInvitationEmail):
+		http.Error(w, "This invitation was sent to a different email address", http.StatusForbidden)
+		return
+	case err != nil:
+		log.Printf("Error accepting invitation for user %d: %v", userId, err)
+		http.Error(w, "Failed to accept invitation", http.StatusInternalServerError)
+		return
+	case org == nil:
+		http.Error(w, "Invitation not found or already used", http.StatusNotFound)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, OrgResponse{Organization: org})
+}
+
+// InvitationLinkHandler is where emailed links land. Signed-in users join right away;
+// everyone else signs in first and joins in the auth callback.
+func (h *Handler) InvitationLinkHandler(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	session, _ := auth.Store.Get(r, "auth-session")
+	userId, ok := session.Values["userId"].(int)
+	if !ok {
+		session.Values["pendingInvitation"] = token
+		if err := session.Save(r, w); err != nil {
+			log.Printf("Error saving session: %v", err)
+		}
+		http.Redirect(w, r, "/auth?invitation=1", http.StatusFound)
+		return
+	}
+
+	h.joinInvitedOrg(w, r, userId, token)
+}
+
+// joinInvitedOrg accepts an invitation for a browser session, makes its organization
+// active and redirects to the dashboard
+func (h *Handler) joinInvitedOrg(w http.ResponseWriter, r *http.Request, userId int, token string) {
+	org, err := h.acceptInvitation(userId, token)
+	switch {
+	case errors.Is(err, db.ErrInvitationExpired):
+		http.Redirect(w, r, "/dashboard?invitation=expired", http.StatusFound)
+		return
+	case errors.Is(err, db.ErrInvitationEmail):
+		http.Redirect(w, r, "/dashboard?invitation=wrong_email", http.StatusFound)
+		return
+	case err != nil:
+		log.Printf("Error accepting invitation for user %d: %v", userId, err)
+		http.Redirect(w, r, "/dashboard?invitation=error", http.StatusFound)
+		return
+	case org == nil:
+		http.Redirect(w, r, "/dashboard?invitation=invalid", http.StatusFound)
+		return
+	}
+
+	session, _ := auth.Store.Get(r, "auth-session")
+	session.Values["activeOrgId"] = org.Id
+	delete(session.Values, "pendingInvitation")
+	if err := session.Save(r, w); err != nil {
+		log.Printf("Error saving session: %v", err)
+	}
+	http.Redirect(w, r, "/dashboard?invitation=accepted", http.StatusFound)
+}
+
+func (h *Handler) acceptInvitation(userId int, token string) (*db.Organization, error) {
+	user, err := db.GetUserById(h.conn, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := db.AcceptOrgInvitation(h.conn, auth.HashAPIKey(token), userId, user.Email)
+	if err == nil && org != nil {
+		log.Printf("🤝 User %d joined org %d as %s", userId, org.Id, org.Role)
+	}
+	return org, err
+}
+
+// sendInvitation emails the invitation link, or logs it when no mailer is configured
+func (h *Handler) sendInvitation(orgName, to, role, token string) {
+	link := strings.TrimRight(h.cfg.Server.PublicURL, "/") + "/invite/" + token
+
+	if h.mailer == nil {
+		log.Printf("⚠️ No mailer configured - invitation link for %s: %s", to, link)
+		return
+	}
+
+	subject := fmt.Sprintf("You're invited to join %s on UpLitycs", orgName)
+	textBody := fmt.Sprintf("You have been invited to join %s on UpLitycs as %s.\n\nAccept the invitation: %s\n\nThis link expires in 7 days.", orgName, role, link)
+	htmlBody := fmt.Sprintf(`<p>You have been invited to join <strong>%s</strong> on UpLitycs as %s.</p><p><a href="%s">Accept the invitation</a></p><p>This link expires in 7 days.</p>`,
+		html.EscapeString(orgName), role, html.EscapeString(link))
+
+	// Sending is slow and the invitation is already stored, so don't hold up the response
+	go func() {
+		if err := h.mailer.Send(to, subject, htmlBody, textBody); err != nil {
+			log.Printf("❌ Error sending invitation to %s: %v", to, err)
+		}
+	}()
+}
+
+// generateInvitationToken creates the secret part of an invitation link
+func generateInvitationToken() (string, error) {
+	buffer := make([]byte, 32)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buffer), nil
+}
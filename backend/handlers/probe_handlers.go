@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"statusframe/backend/probe"
+	"statusframe/db"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// probePollIntervalSeconds is how often agents pull their assignments
+const probePollIntervalSeconds = 30
+
+var probeRegionPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// ProbeAuthMiddleware only lets through requests carrying the shared probe token
+func (h *Handler) ProbeAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := h.cfg.Probe.Token
+		if token == "" {
+			http.Error(w, "Probe agents are not enabled", http.StatusNotFound)
+			return
+		}
+
+		provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RegisterProbeHandler registers (or re-registers) a probe agent for a region
+func (h *Handler) RegisterProbeHandler(w http.ResponseWriter, r *http.Request) {
+	var req probe.RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !probeRegionPattern.MatchString(req.Region) {
+		http.Error(w, "Invalid region. Use lowercase letters, digits and dashes", http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		req.Name = req.Region
+	}
+
+	agent, err := db.RegisterProbeAgent(h.conn, req.Name, req.Region)
+	if err != nil {
+		log.Printf("Error registering probe agent %s: %v", req.Name, err)
+		http.Error(w, "Failed to register agent", http.StatusInternalServerError)
+		return
+	}
+
+	if agent.DisabledAt != nil {
+		http.Error(w, "Agent is disabled", http.StatusForbidden)
+		return
+	}
+
+	log.Printf("🛰️  Probe agent registered: %s (ID: %d, region: %s)", agent.Name, agent.ID, agent.Region)
+
+	respondJSON(w, http.StatusOK, probe.RegisterResponse{
+		AgentID:             agent.ID,
+		Region:              agent.Region,
+		PollIntervalSeconds: probePollIntervalSeconds,
+	})
+}
+
+// GetProbeChecksHandler returns the checks a probe agent should run
+func (h *Handler) GetProbeChecksHandler(w http.ResponseWriter, r *http.Request) {
+	agentID, err := strconv.Atoi(r.URL.Query().Get("agent_id"))
+	if err != nil {
+		http.Error(w, "Invalid agent ID", http.StatusBadRequest)
+		return
+	}
+
+	agent, ok := h.activeProbeAgent(w, agentID)
+	if !ok {
+		return
+	}
+
+	if err := db.TouchProbeAgent(h.conn, agent.ID); err != nil {
+		log.Printf("Error updating probe agent %d: %v", agent.ID, err)
+	}
+
+	assignments, err := db.GetProbeAssignments(h.conn)
+	if err != nil {
+		log.Printf("Error fetching probe assignments: %v", err)
+		http.Error(w, "Failed to fetch checks", http.StatusInternalServerError)
+		return
+	}
+
+	checks := make([]probe.Assignment, 0, len(assignments))
+	for _, assignment := range assignments {
+		checks = append(checks, probe.Assignment{
+			AppID:           assignment.AppID,
+			HealthURL:       assignment.HealthURL,
+			IntervalSeconds: assignment.IntervalSeconds,
+		})
+	}
+
+	respondJSON(w, http.StatusOK, checks)
+}
+
+// activeProbeAgent looks up an agent, writing a 404 if it isn't registered and a 403 if
+// an admin has disabled it
+func (h *Handler) activeProbeAgent(w http.ResponseWriter, agentID int) (*db.ProbeAgent, bool) {
+	agent, err := db.GetProbeAgent(h.conn, agentID)
+	if err != nil {
+		log.Printf("Error fetching probe agent %d: %v", agentID, err)
+		http.Error(w, "Failed to fetch agent", http.StatusInternalServerError)
+		return nil, false
+	}
+	if agent == nil {
+		http.Error(w, "Unknown agent. Register first", http.StatusNotFound)
+		return nil, false
+	}
+	if agent.DisabledAt != nil {
+		http.Error(w, "Agent is disabled", http.StatusForbidden)
+		return nil, false
+	}
+	return agent, true
+}
+
+// SubmitProbeResultsHandler stores results pushed by a probe agent, tagged with the agent's region
+func (h *Handler) SubmitProbeResultsHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+
+	var req probe.ResultsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	agent, ok := h.activeProbeAgent(w, req.AgentID)
+	if !ok {
+		return
+	}
+
+	// Only apps handed out by GetProbeChecksHandler are taken, so an agent can't
+	// decide the status of apps it doesn't check
+	assignments, err := db.GetProbeAssignments(h.conn)
+	if err != nil {
+		log.Printf("Error fetching probe assignments: %v", err)
+		http.Error(w, "Failed to fetch checks", http.StatusInternalServerError)
+		return
+	}
+	assigned := make(map[int]bool, len(assignments))
+	for _, assignment := range assignments {
+		assigned[assignment.AppID] = true
+	}
+
+	accepted, rejected := 0, 0
+	for _, result := range req.Results {
+		if !assigned[result.AppID] {
+			rejected++
+			continue
+		}
+
+		checkedAt := result.CheckedAt
+		if checkedAt.IsZero() || checkedAt.After(time.Now().Add(time.Minute)) {
+			checkedAt = time.Now()
+		}
+
+		err := db.InsertRegionCheck(h.conn, result.AppID, agent.Region, result.StatusCode, result.ResponseTimeMs, checkedAt)
+		if err != nil {
+			log.Printf("⚠️ Error storing result for app %d from region %s: %v", result.AppID, agent.Region, err)
+			continue
+		}
+		accepted++
+	}
+
+	if rejected > 0 {
+		log.Printf("⚠️ Ignored %d result(s) for unassigned apps from probe agent %d (region %s)", rejected, agent.ID, agent.Region)
+	}
+
+	if err := db.TouchProbeAgent(h.conn, agent.ID); err != nil {
+		log.Printf("Error updating probe agent %d: %v", agent.ID, err)
+	}
+
+	respondJSON(w, http.StatusOK, ProbeResultsResponse{Accepted: accepted, Rejected: rejected, Region: agent.Region})
+}
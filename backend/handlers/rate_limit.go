@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// The public API and status pages need no sign in, so they are limited per visitor
+// address. The ping endpoint measures the app's health URL live; one measurement is
+// shared between everyone watching the page, and new measurements are also limited per
+// status page, so visitors can't use the server to flood a health URL. Answers from the
+// shared measurement don't count towards that limit.
+
+// rateLimitSweepInterval is how often buckets that have filled up again are dropped
+const rateLimitSweepInterval = time.Minute
+
+// rateLimiter is a token bucket per key, kept in memory. Each bucket holds up to a
+// minute's worth of requests and refills steadily.
+type rateLimiter struct {
+	perMinute float64 // 0 for no limit
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func newRateLimiter(perMinute int) *rateLimiter {
+	return &rateLimiter{perMinute: float64(perMinute), buckets: map[string]*tokenBucket{}}
+}
+
+// allow takes a token from key's bucket. Without one it returns false and how long
+// until the next token.
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	if l.perMinute <= 0 {
+		return true, 0
+	}
+	perSecond := l.perMinute / 60
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= rateLimitSweepInterval {
+		for k, b := range l.buckets {
+			if now.Sub(b.updated).Seconds()*perSecond+b.tokens >= l.perMinute {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b := l.bucket(key, now)
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// refund gives back a token taken by allow, for attempts that only count when they fail
+func (l *rateLimiter) refund(key string, now time.Time) {
+	if l.perMinute <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.bucket(key, now)
+	b.tokens = math.Min(l.perMinute, b.tokens+1)
+}
+
+// bucket returns key's bucket refilled up to now. l.mu must be held.
+func (l *rateLimiter) bucket(key string, now time.Time) *tokenBucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.perMinute, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.perMinute, b.tokens+now.Sub(b.updated).Seconds()*l.perMinute/60)
+	b.updated = now
+	return b
+}
+
+// PublicRateLimitMiddleware refuses requests over the limit per visitor with 429 Too
+// Many Requests
+func (h *Handler) PublicRateLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := h.ipLimiter.allow(h.visitorIP(r).String(), time.Now()); !ok {
+			tooManyRequests(w, wait)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func tooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, "Too many requests, try again later", http.StatusTooManyRequests)
+}
+
+// pingCache shares the live ping of each app between visitors. Visitors who ask while
+// a ping is running wait for it instead of starting another.
+type pingCache struct {
+	mu      sync.Mutex
+	entries map[int]*pingEntry
+}
+
+type pingEntry struct {
+	done    chan struct{} // closed once the ping has finished
+	result  PingResponse
+	expires time.Time
+}
+
+// get returns the app's cached ping, or runs ping and keeps its result for ttl. It
+// also returns when the result expires. Only a new ping asks allow first; when allow
+// refuses, ok is false and wait says how long until another ping is allowed.
+func (c *pingCache) get(appId int, ttl time.Duration, allow func() (bool, time.Duration), ping func() PingResponse) (result PingResponse, expires time.Time, ok bool, wait time.Duration) {
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = map[int]*pingEntry{}
+	}
+	e, found := c.entries[appId]
+	if found && !e.finished() {
+		c.mu.Unlock()
+		<-e.done
+		return e.result, e.expires, true, 0
+	}
+	if found && time.Now().Before(e.expires) {
+		c.mu.Unlock()
+		return e.result, e.expires, true, 0
+	}
+	if allowed, wait := allow(); !allowed {
+		c.mu.Unlock()
+		return PingResponse{}, time.Time{}, false, wait
+	}
+	e = &pingEntry{done: make(chan struct{})}
+	c.entries[appId] = e
+	c.mu.Unlock()
+
+	// Waiters must be released even if ping panics; the entry then counts as expired
+	defer close(e.done)
+	e.result = ping()
+	e.expires = time.Now().Add(ttl)
+	return e.result, e.expires, true, 0
+}
+
+func (e *pingEntry) finished() bool {
+	select {
+	case <-e.done:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"statusframe/backend/auth"
+	"statusframe/db"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// GetSessionsHandler lists the browsers the user is signed in on, marking this one
+func (h *Handler) GetSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	sessions, err := db.GetUserSessions(h.conn, userId)
+	if err != nil {
+		log.Printf("Error fetching sessions for user %d: %v", userId, err)
+		http.Error(w, "Failed to fetch sessions", http.StatusInternalServerError)
+		return
+	}
+
+	current := auth.SessionTokenHash(r)
+	for i := range sessions {
+		sessions[i].Current = current != "" && sessions[i].TokenHash == current
+	}
+
+	respondJSON(w, http.StatusOK, SessionListResponse{Sessions: sessions})
+}
+
+// RevokeSessionHandler signs out one of the user's sessions, which may be this one
+func (h *Handler) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	sessionId, err := strconv.Atoi(chi.URLParam(r, "sessionId"))
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	revoked, err := db.RevokeSession(h.conn, userId, sessionId)
+	if err != nil {
+		log.Printf("Error revoking session %d: %v", sessionId, err)
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+	if !revoked {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	log.Printf("🔒 Session %d revoked by user %d", sessionId, userId)
+
+	respondJSON(w, http.StatusOK, SuccessResponse{Success: true})
+}
+
+// RevokeAllSessionsHandler signs the user out on every device. With ?keep_current=true
+// this browser stays signed in.
+func (h *Handler) RevokeAllSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	keep := ""
+	if r.URL.Query().Get("keep_current") == "true" {
+		keep = auth.SessionTokenHash(r)
+	}
+
+	revoked, err := db.RevokeUserSessions(h.conn, userId, keep)
+	if err != nil {
+		log.Printf("Error revoking sessions for user %d: %v", userId, err)
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+
+	if keep == "" {
+		// The row is gone already, this just clears the cookie
+		session, _ := auth.Store.Get(r, "auth-session")
+		session.Options.MaxAge = -1
+		session.Save(r, w)
+	}
+
+	log.Printf("🔒 User %d signed out of %d sessions", userId, revoked)
+
+	respondJSON(w, http.StatusOK, RevokeSessionsResponse{
+		Success: true,
+		Revoked: revoked,
+	})
+}
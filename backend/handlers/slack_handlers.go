@@ -10,8 +10,8 @@ import (
 	"log"
 	"net/http"
 	"net/url"
-	"os"
 	"statusframe/backend/auth"
+	"statusframe/backend/metrics"
 	"statusframe/db"
 	"strings"
 	"time"
@@ -65,6 +65,15 @@ type SlackChannelsResponse struct {
 	Error    string         `json:"error"`
 }
 
+// SaveSlackIntegrationRequest is the body of POST /api/slack/save-integration
+type SaveSlackIntegrationRequest struct {
+	BotToken    string `json:"bot_token"`
+	TeamID      string `json:"team_id"`
+	TeamName    string `json:"team_name"`
+	ChannelID   string `json:"channel_id"`
+	ChannelName string `json:"channel_name"`
+}
+
 // IncidentAlert for sending to Slack
 type IncidentAlert struct {
 	AppID      int
@@ -83,25 +92,30 @@ func (h *Handler) StartSlackAuthHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Check if user has Pro or Business plan
-	plan, err := db.GetUserPlan(h.conn, user.Id)
+	if !hasOrgRole(r, db.RoleAdmin) {
+		respondJSON(w, http.StatusForbidden, IntegrationErrorResponse{
+			Error: "Only organization admins can manage the Slack integration",
+		})
+		return
+	}
+
+	// Check if the organization has Pro or Business plan
+	orgId, _ := orgFromContext(r)
+	plan, err := db.GetOrgPlan(h.conn, orgId)
 	if err != nil {
-		log.Printf("Error fetching plan for user %d during Slack auth start: %v", user.Id, err)
+		log.Printf("Error fetching plan for org %d during Slack auth start: %v", orgId, err)
 		http.Error(w, "Unable to verify subscription for Slack integration", http.StatusInternalServerError)
 		return
 	}
 	if plan != "pro" && plan != "business" {
-		respondJSON(w, http.StatusForbidden, map[string]interface{}{
-			"error": "Slack integration requires Pro or Business plan",
+		respondJSON(w, http.StatusForbidden, IntegrationErrorResponse{
+			Error: "Slack integration requires Pro or Business plan",
 		})
 		return
 	}
 
-	clientID := os.Getenv("SLACK_CLIENT_ID")
-	redirectURI := os.Getenv("SLACK_REDIRECT_URI")
-	if redirectURI == "" {
-		redirectURI = "http://localhost:8080/api/slack/callback"
-	}
+	clientID := h.cfg.Slack.ClientID
+	redirectURI := h.slackRedirectURI()
 
 	if clientID == "" {
 		http.Error(w, "Slack client ID not configured", http.StatusInternalServerError)
@@ -124,6 +138,7 @@ func (h *Handler) StartSlackAuthHandler(w http.ResponseWriter, r *http.Request)
 
 	session.Values["slack_oauth_state"] = state
 	session.Values["slack_oauth_user_id"] = user.Id
+	session.Values["slack_oauth_org_id"] = orgId
 	if err := session.Save(r, w); err != nil {
 		log.Printf("Error saving Slack OAuth state to session: %v", err)
 		http.Error(w, "Failed to start Slack authentication", http.StatusInternalServerError)
@@ -138,9 +153,7 @@ func (h *Handler) StartSlackAuthHandler(w http.ResponseWriter, r *http.Request)
 
 	oauthURL := fmt.Sprintf("https://slack.com/oauth/v2/authorize?%s", params.Encode())
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"oauth_url": oauthURL,
-	})
+	respondJSON(w, http.StatusOK, OAuthURLResponse{OAuthURL: oauthURL})
 }
 
 // SlackCallbackHandler handles Slack OAuth callback
@@ -182,7 +195,8 @@ func (h *Handler) SlackCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	userID, ok := session.Values["slack_oauth_user_id"].(int)
-	if !ok {
+	orgID, orgOk := session.Values["slack_oauth_org_id"].(int)
+	if !ok || !orgOk {
 		log.Printf("Slack OAuth missing user ID in session")
 		redirectToSettings(w, r, map[string]string{
 			"error": "Session missing user information. Please try again",
@@ -190,9 +204,9 @@ func (h *Handler) SlackCallbackHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	plan, err := db.GetUserPlan(h.conn, userID)
+	plan, err := db.GetOrgPlan(h.conn, orgID)
 	if err != nil {
-		log.Printf("Error fetching plan for user %d during Slack callback: %v", userID, err)
+		log.Printf("Error fetching plan for org %d during Slack callback: %v", orgID, err)
 		redirectToSettings(w, r, map[string]string{
 			"error": "Unable to verify subscription for Slack integration",
 		})
@@ -224,16 +238,28 @@ func (h *Handler) SlackCallbackHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, err := db.SaveSlackIntegration(h.conn, userID, token, teamID, teamName, channelID, channelName); err != nil {
-		log.Printf("Error saving Slack integration for user %d: %v", userID, err)
+	previous, _ := db.GetSlackIntegration(h.conn, orgID)
+	integration, err := db.SaveSlackIntegration(h.conn, orgID, userID, token, teamID, teamName, channelID, channelName)
+	if err != nil {
+		log.Printf("Error saving Slack integration for org %d: %v", orgID, err)
 		redirectToSettings(w, r, map[string]string{
 			"error": "Failed to save Slack integration",
 		})
 		return
 	}
+	// The callback runs outside AuthMiddleware, so the user comes from the OAuth session
+	h.writeAudit(&userID, h.clientIP(r), auditEvent{
+		Action:     AuditSlackConnect,
+		TargetType: "slack_integration",
+		TargetID:   integration.ID,
+		OrgID:      orgID,
+		Before:     slackAuditValues(previous),
+		After:      slackAuditValues(integration),
+	})
 
 	delete(session.Values, "slack_oauth_state")
 	delete(session.Values, "slack_oauth_user_id")
+	delete(session.Values, "slack_oauth_org_id")
 	if err := session.Save(r, w); err != nil {
 		log.Printf("Error clearing Slack OAuth session values: %v", err)
 	}
@@ -243,7 +269,7 @@ func (h *Handler) SlackCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// SaveSlackIntegrationHandler saves Slack integration for user
+// SaveSlackIntegrationHandler saves the organization's Slack integration
 func (h *Handler) SaveSlackIntegrationHandler(w http.ResponseWriter, r *http.Request) {
 	user, err := db.GetUserFromContext(h.conn, r.Context())
 	if err != nil {
@@ -251,90 +277,97 @@ func (h *Handler) SaveSlackIntegrationHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if !hasOrgRole(r, db.RoleAdmin) {
+		respondJSON(w, http.StatusForbidden, IntegrationErrorResponse{
+			Error: "Only organization admins can manage the Slack integration",
+		})
+		return
+	}
+
 	// Check plan
-	plan, err := db.GetUserPlan(h.conn, user.Id)
+	orgId, _ := orgFromContext(r)
+	plan, err := db.GetOrgPlan(h.conn, orgId)
 	if err != nil {
-		log.Printf("Error fetching plan for user %d while saving Slack integration: %v", user.Id, err)
-		respondJSON(w, http.StatusInternalServerError, map[string]interface{}{
-			"error": "Unable to verify subscription for Slack integration",
+		log.Printf("Error fetching plan for org %d while saving Slack integration: %v", orgId, err)
+		respondJSON(w, http.StatusInternalServerError, IntegrationErrorResponse{
+			Error: "Unable to verify subscription for Slack integration",
 		})
 		return
 	}
 	if plan != "pro" && plan != "business" {
-		respondJSON(w, http.StatusForbidden, map[string]interface{}{
-			"error": "Slack integration requires Pro or Business plan",
+		respondJSON(w, http.StatusForbidden, IntegrationErrorResponse{
+			Error: "Slack integration requires Pro or Business plan",
 		})
 		return
 	}
 
-	var req struct {
-		BotToken    string `json:"bot_token"`
-		TeamID      string `json:"team_id"`
-		TeamName    string `json:"team_name"`
-		ChannelID   string `json:"channel_id"`
-		ChannelName string `json:"channel_name"`
-	}
+	var req SaveSlackIntegrationRequest
 
 	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		respondJSON(w, http.StatusBadRequest, map[string]interface{}{
-			"error": "Invalid request payload",
+		respondJSON(w, http.StatusBadRequest, IntegrationErrorResponse{
+			Error: "Invalid request payload",
 		})
 		return
 	}
 
 	// Save to database
-	integration, err := db.SaveSlackIntegration(h.conn, user.Id, req.BotToken, req.TeamID, req.TeamName, req.ChannelID, req.ChannelName)
+	previous, _ := db.GetSlackIntegration(h.conn, orgId)
+	integration, err := db.SaveSlackIntegration(h.conn, orgId, user.Id, req.BotToken, req.TeamID, req.TeamName, req.ChannelID, req.ChannelName)
 	if err != nil {
 		log.Printf("Error saving Slack integration: %v", err)
-		respondJSON(w, http.StatusInternalServerError, map[string]interface{}{
-			"error": "Failed to save integration",
+		respondJSON(w, http.StatusInternalServerError, IntegrationErrorResponse{
+			Error: "Failed to save integration",
 		})
 		return
 	}
+	h.audit(r, auditEvent{
+		Action:     AuditSlackConnect,
+		TargetType: "slack_integration",
+		TargetID:   integration.ID,
+		OrgID:      orgId,
+		Before:     slackAuditValues(previous),
+		After:      slackAuditValues(integration),
+	})
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"success":     true,
-		"integration": integration,
-		"message":     "Slack integration saved successfully",
+	respondJSON(w, http.StatusOK, SlackIntegrationResponse{
+		Success:     true,
+		Integration: integration,
+		Message:     "Slack integration saved successfully",
 	})
 }
 
-// GetSlackIntegrationHandler retrieves user's Slack integration
+// GetSlackIntegrationHandler retrieves the organization's Slack integration
 func (h *Handler) GetSlackIntegrationHandler(w http.ResponseWriter, r *http.Request) {
-	user, err := db.GetUserFromContext(h.conn, r.Context())
+	_, err := db.GetUserFromContext(h.conn, r.Context())
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	plan, err := db.GetUserPlan(h.conn, user.Id)
+	orgId, _ := orgFromContext(r)
+	plan, err := db.GetOrgPlan(h.conn, orgId)
 	if err != nil {
-		log.Printf("Error fetching plan for user %d while loading Slack integration: %v", user.Id, err)
-		respondJSON(w, http.StatusInternalServerError, map[string]interface{}{
-			"error": "Unable to verify subscription for Slack integration",
+		log.Printf("Error fetching plan for org %d while loading Slack integration: %v", orgId, err)
+		respondJSON(w, http.StatusInternalServerError, IntegrationErrorResponse{
+			Error: "Unable to verify subscription for Slack integration",
 		})
 		return
 	}
 	if plan != "pro" && plan != "business" {
-		respondJSON(w, http.StatusForbidden, map[string]interface{}{
-			"error": "Slack integration requires Pro or Business plan",
+		respondJSON(w, http.StatusForbidden, IntegrationErrorResponse{
+			Error: "Slack integration requires Pro or Business plan",
 		})
 		return
 	}
 
-	integration, err := db.GetSlackIntegration(h.conn, user.Id)
+	integration, err := db.GetSlackIntegration(h.conn, orgId)
 	if err != nil {
-		respondJSON(w, http.StatusOK, map[string]interface{}{
-			"integration": nil,
-			"message":     "No Slack integration found",
-		})
+		respondJSON(w, http.StatusOK, SlackIntegrationResponse{Message: "No Slack integration found"})
 		return
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"integration": integration,
-	})
+	respondJSON(w, http.StatusOK, SlackIntegrationResponse{Integration: integration})
 }
 
 // DisableSlackIntegrationHandler disables Slack integration
@@ -345,34 +378,53 @@ func (h *Handler) DisableSlackIntegrationHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	plan, planErr := db.GetUserPlan(h.conn, user.Id)
+	if !hasOrgRole(r, db.RoleAdmin) {
+		respondJSON(w, http.StatusForbidden, IntegrationErrorResponse{
+			Error: "Only organization admins can manage the Slack integration",
+		})
+		return
+	}
+
+	orgId, _ := orgFromContext(r)
+	plan, planErr := db.GetOrgPlan(h.conn, orgId)
 	if planErr != nil {
-		log.Printf("Error fetching plan for user %d while disabling Slack integration: %v", user.Id, planErr)
+		log.Printf("Error fetching plan for org %d while disabling Slack integration: %v", orgId, planErr)
 	}
 	allowedPlan := plan == "pro" || plan == "business"
 
-	err = db.DisableSlackIntegration(h.conn, user.Id)
+	previous, _ := db.GetSlackIntegration(h.conn, orgId)
+	err = db.DisableSlackIntegration(h.conn, orgId)
 	if err != nil {
 		log.Printf("Error disabling Slack integration: %v", err)
-		respondJSON(w, http.StatusInternalServerError, map[string]interface{}{
-			"error": "Failed to disable integration",
+		respondJSON(w, http.StatusInternalServerError, IntegrationErrorResponse{
+			Error: "Failed to disable integration",
 		})
 		return
 	}
+	if previous != nil {
+		after := *previous
+		after.IsEnabled = false
+		h.audit(r, auditEvent{
+			Action:     AuditSlackDisable,
+			TargetType: "slack_integration",
+			TargetID:   previous.ID,
+			OrgID:      orgId,
+			Before:     slackAuditValues(previous),
+			After:      slackAuditValues(&after),
+		})
+	}
+	log.Printf("🔕 Slack integration disabled for org %d by user %d", orgId, user.Id)
 
 	message := "Slack integration disabled"
 	if !allowedPlan {
 		message = "Slack integration disabled. Upgrade to reconnect."
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"success": true,
-		"message": message,
-	})
+	respondJSON(w, http.StatusOK, SuccessResponse{Success: true, Message: message})
 }
 
 // SendSlackAlert sends an incident alert to Slack
-func (h *Handler) SendSlackAlert(alert IncidentAlert) error {
+func (h *Handler) SendSlackAlert(alert IncidentAlert) (err error) {
 	// Get user's Slack integration
 	integration, err := db.GetSlackIntegrationByAppID(h.conn, alert.AppID)
 	if err != nil || integration == nil || !integration.IsEnabled {
@@ -380,6 +432,8 @@ func (h *Handler) SendSlackAlert(alert IncidentAlert) error {
 		return nil // Not an error, just no integration
 	}
 
+	defer func() { metrics.ObserveNotification("slack", err) }()
+
 	// Prepare Slack message
 	color := "#36a64f" // Green
 	switch alert.Status {
@@ -455,14 +509,19 @@ func (h *Handler) SendSlackAlert(alert IncidentAlert) error {
 	return nil
 }
 
+// slackRedirectURI returns the configured OAuth callback, defaulting to this server's callback route
+func (h *Handler) slackRedirectURI() string {
+	if h.cfg.Slack.RedirectURI != "" {
+		return h.cfg.Slack.RedirectURI
+	}
+	return strings.TrimRight(h.cfg.Server.PublicURL, "/") + "/api/slack/callback"
+}
+
 // exchangeSlackCode exchanges auth code for token
 func (h *Handler) exchangeSlackCode(code string) (string, string, string, string, string, error) {
-	clientID := os.Getenv("SLACK_CLIENT_ID")
-	clientSecret := os.Getenv("SLACK_CLIENT_SECRET")
-	redirectURI := os.Getenv("SLACK_REDIRECT_URI")
-	if redirectURI == "" {
-		redirectURI = "http://localhost:8080/api/slack/callback"
-	}
+	clientID := h.cfg.Slack.ClientID
+	clientSecret := h.cfg.Slack.ClientSecret
+	redirectURI := h.slackRedirectURI()
 
 	if clientID == "" || clientSecret == "" {
 		return "", "", "", "", "", fmt.Errorf("Slack credentials not configured")
@@ -0,0 +1,485 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"regexp"
+	"statusframe/backend/utils"
+	"statusframe/db"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Server-rendered status pages. Crawlers, link previews and browsers without
+// JavaScript get the whole page as HTML; browsers with JavaScript then start the
+// React app over it, which takes over as usual.
+
+//go:embed templates/status.html
+var templateFiles embed.FS
+
+var statusTemplate = template.Must(template.ParseFS(templateFiles, "templates/status.html"))
+
+// statusView is what templates/status.html shows
+type statusView struct {
+	NotFound      bool
+	Locked        bool   // the visitor isn't let in; Detail says why
+	UnlockURL     string // where the password form posts, on password protected pages
+	WrongPassword bool
+	NoIndex       bool   // for pages that aren't public
+	Title         string // of the document and its link previews
+	Description   string
+	URL           string
+	FeedURL       string // without the extension of each format, for feed readers to find
+	Theme         string
+	LogoURL       string
+	Heading       string
+	Subtitle      string
+	State         string // operational, degraded, down or paused, for the colors
+	StateText     string
+	Detail        string
+	Metrics       []viewMetric
+	Groups        []viewGroup // the components, without a name for those outside of sections
+	Updated       string
+	SPAHead       template.HTML
+}
+
+type viewMetric struct {
+	Label, Value string
+}
+
+type viewGroup struct {
+	Name, State, StateText string
+	Components             []viewComponent
+}
+
+type viewComponent struct {
+	Name, State, StateText, Uptime string
+	Bars                           []viewBar
+}
+
+type viewBar struct {
+	Class, Title string
+}
+
+// SetSPAIndex points server-rendered pages at the React app's built index.html, whose
+// scripts and styles they load. Without it the pages are plain HTML.
+func (h *Handler) SetSPAIndex(path string) {
+	h.spaIndexPath = path
+}
+
+var spaAssetPattern = regexp.MustCompile(`<script type="module"[^>]*></script>|<link rel="(?:stylesheet|modulepreload)"[^>]*>`)
+
+// spaHead returns the tags that start the React app, read once from its index.html
+func (h *Handler) spaHead() template.HTML {
+	h.spaHeadOnce.Do(func() {
+		if h.spaIndexPath == "" {
+			return
+		}
+		index, err := os.ReadFile(h.spaIndexPath)
+		if err != nil {
+			log.Printf("⚠️ Server-rendered pages won't start the React app: %v", err)
+			return
+		}
+		h.spaHeadHTML = template.HTML(strings.Join(spaAssetPattern.FindAllString(string(index), -1), "\n"))
+	})
+	return h.spaHeadHTML
+}
+
+// prefersHTML reports whether the Accept header ranks text/html at least as high as
+// application/json. No Accept header counts as HTML.
+func prefersHTML(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return true
+	}
+	htmlQ, jsonQ := 0.0, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		switch mediaType {
+		case "text/html":
+			htmlQ = max(htmlQ, q)
+		case "application/json":
+			jsonQ = max(jsonQ, q)
+		case "text/*":
+			htmlQ = max(htmlQ, q)
+		case "application/*":
+			jsonQ = max(jsonQ, q)
+		case "*/*":
+			htmlQ, jsonQ = max(htmlQ, q), max(jsonQ, q)
+		}
+	}
+	return htmlQ >= jsonQ && htmlQ > 0
+}
+
+// writeCached writes a body with a strong ETag from its contents, or 304 Not Modified
+// if the client already has it. Public pages are cached briefly since checks keep
+// coming in.
+func writeCached(w http.ResponseWriter, r *http.Request, contentType string, status int, body []byte) {
+	writeCachedAs(w, r, contentType, status, body, body)
+}
+
+// writeCachedAs is writeCached with the ETag taken from tagged instead of the body, for
+// bodies with parts, like the time they were made, that shouldn't change the tag
+func writeCachedAs(w http.ResponseWriter, r *http.Request, contentType string, status int, body, tagged []byte) {
+	sum := sha256.Sum256(tagged)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	if w.Header().Get("Cache-Control") == "" { // private pages set their own
+		w.Header().Set("Cache-Control", "public, max-age=30")
+	}
+	w.Header().Add("Vary", "Accept")
+	if status == http.StatusOK && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// respondCachedJSON writes payload like respondJSON, with an ETag and a short cache
+func respondCachedJSON(w http.ResponseWriter, r *http.Request, payload interface{}) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+	writeCached(w, r, "application/json", http.StatusOK, append(body, '\n'))
+}
+
+// etagMatches reports whether an If-None-Match header lists etag. Weak tags match
+// their strong form, as the header is compared weakly.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// renderStatus renders a page, or writes a plain error if the template fails
+func (h *Handler) renderStatus(w http.ResponseWriter, r *http.Request, status int, view statusView) {
+	if !utils.CheckTheme(view.Theme) {
+		view.Theme = "cyberpunk"
+	}
+	if !view.NotFound && !view.Locked {
+		view.SPAHead = h.spaHead()
+	}
+	var buf bytes.Buffer
+	if err := statusTemplate.Execute(&buf, view); err != nil {
+		log.Printf("Error rendering status page %s: %v", r.URL.Path, err)
+		http.Error(w, "Error rendering status page", http.StatusInternalServerError)
+		return
+	}
+	writeCached(w, r, "text/html; charset=utf-8", status, buf.Bytes())
+}
+
+// pageURL is the absolute URL of the request, on the custom domain if it came to one
+func (h *Handler) pageURL(r *http.Request) string {
+	if host, ok := r.Context().Value("customDomain").(string); ok {
+		return "https://" + host + r.URL.Path
+	}
+	return strings.TrimRight(h.cfg.Server.PublicURL, "/") + r.URL.Path
+}
+
+// StatusPageHTMLHandler serves an app's status page at /status/{slug} as HTML, or as
+// the JSON of GET /api/public/status/{slug} when the request prefers JSON
+func (h *Handler) StatusPageHTMLHandler(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	app, err := db.GetAppBySlug(h.conn, slug)
+	if err != nil {
+		if !prefersHTML(r) {
+			respondError(w, http.StatusNotFound, errCodeNotFound, "Status page not found")
+			return
+		}
+		h.renderStatus(w, r, http.StatusNotFound, statusView{NotFound: true, Title: "Status Page Not Found"})
+		return
+	}
+
+	access, err := h.appPageAccess(w, r, app)
+	if err != nil {
+		log.Printf("Error checking access to app %d: %v", app.Id, err)
+		http.Error(w, "Error checking access", http.StatusInternalServerError)
+		return
+	}
+	if !access.Allowed {
+		if !prefersHTML(r) {
+			denyPageAccess(w, access)
+			return
+		}
+		code, message := access.denial()
+		view := statusView{Locked: true, NoIndex: true, Title: "Private Status Page", Description: message, Theme: app.Theme, Detail: message}
+		if access.Visibility == db.VisibilityPassword {
+			view.UnlockURL = "/api/public/status/" + app.Slug + "/unlock"
+			view.WrongPassword = r.URL.Query().Has("wrong_password")
+		}
+		h.renderStatus(w, r, code, view)
+		return
+	}
+
+	status, err := h.publicStatus(app)
+	if err != nil {
+		log.Printf("Error getting status for app %s: %v", slug, err)
+		http.Error(w, "Error fetching status", http.StatusInternalServerError)
+		return
+	}
+	if !prefersHTML(r) {
+		body, _ := json.Marshal(status)
+		writeCached(w, r, "application/json", http.StatusOK, body)
+		return
+	}
+
+	view := statusView{
+		URL:      h.pageURL(r),
+		Theme:    status.Theme,
+		Heading:  status.AppName,
+		Subtitle: "System Status Monitor",
+		Title:    status.AppName + " Status",
+		Updated:  formatCheckedAt(status.CheckedAt),
+		NoIndex:  !access.Public(),
+	}
+	// Feed readers can't get into private pages without a share link of their own
+	if access.Public() {
+		view.FeedURL = strings.TrimRight(h.cfg.Server.PublicURL, "/") + "/api/public/status/" + app.Slug + "/feed"
+	}
+	if status.LogoURL != nil {
+		view.LogoURL = *status.LogoURL
+	}
+
+	view.State, view.StateText = appState(status.Status)
+	switch {
+	case status.Message != "":
+		view.Detail = status.Message
+	case status.Paused:
+		view.Detail = "Checks are paused by the owner. Uptime excludes paused time."
+	case status.StatusCode != 0:
+		view.Detail = fmt.Sprintf("Current Status Code: %d", status.StatusCode)
+	default:
+		view.Detail = "Last checked " + view.Updated
+	}
+
+	view.Description = view.StateText + "."
+	if status.CheckedAt != "" {
+		view.Metrics = []viewMetric{{Label: "Status", Value: status.Status}}
+		if status.Uptime24h != nil {
+			uptime := fmt.Sprintf("%.2f%%", *status.Uptime24h)
+			view.Description += " " + uptime + " uptime in the last 24 hours."
+			view.Metrics = append(view.Metrics, viewMetric{Label: "24h Uptime", Value: uptime})
+		}
+		view.Metrics = append(view.Metrics, viewMetric{Label: "Last Checked", Value: view.Updated})
+		if status.Fields.UptimeHistory {
+			view.Groups = []viewGroup{{Components: []viewComponent{{
+				Name:      fmt.Sprintf("%d-Day Uptime History", status.DataRetentionDays),
+				State:     view.State,
+				StateText: view.StateText,
+				Bars:      uptimeBars(status.UptimeHistory, status.DataRetentionDays),
+			}}}}
+		}
+	}
+
+	h.renderStatus(w, r, http.StatusOK, view)
+}
+
+// StatusPageGroupHTMLHandler serves a multi-app status page at /pages/{slug} as HTML,
+// or as the JSON of GET /api/public/pages/{slug} when the request prefers JSON
+func (h *Handler) StatusPageGroupHTMLHandler(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	page, err := db.GetStatusPageBySlug(h.conn, slug)
+	if err == sql.ErrNoRows {
+		if !prefersHTML(r) {
+			respondError(w, http.StatusNotFound, errCodeNotFound, "Status page not found")
+			return
+		}
+		h.renderStatus(w, r, http.StatusNotFound, statusView{NotFound: true, Title: "Status Page Not Found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching status page %s: %v", slug, err)
+		http.Error(w, "Error fetching status page", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := h.publicStatusPage(w, r, page)
+	if err != nil {
+		log.Printf("Error fetching component statuses of status page %s: %v", slug, err)
+		http.Error(w, "Error fetching status page", http.StatusInternalServerError)
+		return
+	}
+	if !prefersHTML(r) {
+		respondCachedStatusPage(w, r, resp)
+		return
+	}
+
+	view := statusView{
+		URL:      h.pageURL(r),
+		Theme:    resp.Theme,
+		Heading:  resp.Title,
+		Subtitle: resp.Description,
+		Title:    resp.Title,
+	}
+	if resp.LogoURL != nil {
+		view.LogoURL = *resp.LogoURL
+	}
+	if view.Subtitle == "" {
+		view.Subtitle = "System Status Monitor"
+	}
+	view.State, view.StateText = pageState(resp.Status)
+	view.Description = view.StateText + "."
+	if resp.Description != "" {
+		view.Description = resp.Description + " " + view.Description
+	}
+
+	var latest *time.Time
+	group := func(name, status string, components []PublicComponent) viewGroup {
+		g := viewGroup{Name: name}
+		if name != "" {
+			g.State, g.StateText = pageState(status)
+		}
+		for _, c := range components {
+			vc := viewComponent{Name: c.Name}
+			vc.State, vc.StateText = componentState(c.Status)
+			// Like the React page, components without history (or hiding it) have no bars
+			if len(c.UptimeHistory) > 0 {
+				vc.Bars = uptimeBars(c.UptimeHistory, resp.DataRetentionDays)
+			}
+			if c.Uptime24h != nil {
+				vc.Uptime = fmt.Sprintf("%.2f%%", *c.Uptime24h)
+			}
+			if c.CheckedAt != nil && (latest == nil || c.CheckedAt.After(*latest)) {
+				latest = c.CheckedAt
+			}
+			g.Components = append(g.Components, vc)
+		}
+		return g
+	}
+	if len(resp.Components) > 0 {
+		view.Groups = append(view.Groups, group("", "", resp.Components))
+	}
+	for _, s := range resp.Sections {
+		view.Groups = append(view.Groups, group(s.Name, s.Status, s.Components))
+	}
+	if latest != nil {
+		view.Updated = latest.UTC().Format("Jan 2, 2006 15:04 MST")
+	}
+
+	h.renderStatus(w, r, http.StatusOK, view)
+}
+
+// appState gives an app's status its color and headline, like the React page
+func appState(status string) (string, string) {
+	switch status {
+	case "up":
+		return "operational", "All Systems Operational"
+	case "degraded":
+		return "degraded", "Degraded Performance"
+	case "paused":
+		return "paused", "Monitoring Paused"
+	case "pending":
+		return "paused", "Waiting for First Check"
+	}
+	return "down", "Service Down"
+}
+
+// componentState gives a component's status its color and label
+func componentState(status string) (string, string) {
+	switch status {
+	case "up":
+		return "operational", "Operational"
+	case "degraded":
+		return "degraded", "Degraded"
+	case "maintenance":
+		return "paused", "Maintenance"
+	case "paused":
+		return "paused", "Paused"
+	case "pending":
+		return "paused", "Pending"
+	}
+	return "down", "Down"
+}
+
+// pageState gives the overall status of a page or section its color and headline
+func pageState(status string) (string, string) {
+	switch status {
+	case pageStatusOperational:
+		return "operational", "All Systems Operational"
+	case pageStatusDegraded:
+		return "degraded", "Degraded Performance"
+	case pageStatusPartialOutage:
+		return "down", "Partial Outage"
+	case pageStatusMajorOutage:
+		return "down", "Major Outage"
+	case pageStatusMaintenance:
+		return "paused", "Under Maintenance"
+	}
+	return "paused", "Waiting for First Checks"
+}
+
+// uptimeBars returns one bar per day of the retention period, oldest first, colored
+// with the same thresholds as the React uptime graph
+func uptimeBars(history []db.DailyUptime, days int) []viewBar {
+	byDate := map[string]db.DailyUptime{}
+	for _, d := range history {
+		if len(d.Date) >= 10 {
+			byDate[d.Date[:10]] = d
+		}
+	}
+
+	today := time.Now().UTC()
+	bars := make([]viewBar, 0, days)
+	for i := days - 1; i >= 0; i-- {
+		date := today.AddDate(0, 0, -i).Format("2006-01-02")
+		d, ok := byDate[date]
+		switch {
+		case !ok:
+			bars = append(bars, viewBar{Class: "none", Title: date + ": no data"})
+			continue
+		case d.UptimePercentage >= 99:
+			bars = append(bars, viewBar{Class: "excellent"})
+		case d.UptimePercentage >= 95:
+			bars = append(bars, viewBar{Class: "good"})
+		case d.UptimePercentage >= 90:
+			bars = append(bars, viewBar{Class: "warning"})
+		default:
+			bars = append(bars, viewBar{Class: "critical"})
+		}
+		bars[len(bars)-1].Title = fmt.Sprintf("%s: %.2f%% (%d/%d checks)", date, d.UptimePercentage, d.SuccessfulChecks, d.TotalChecks)
+	}
+	return bars
+}
+
+// formatCheckedAt shows a check time from the database, or "" before the first check
+func formatCheckedAt(checkedAt string) string {
+	t, err := time.Parse(time.RFC3339Nano, checkedAt)
+	if err != nil {
+		return checkedAt
+	}
+	return t.UTC().Format("Jan 2, 2006 15:04 MST")
+}
@@ -0,0 +1,541 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"statusframe/backend/utils"
+	"statusframe/db"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Limits on what one status page shows
+const (
+	maxStatusPageComponents = 50
+	maxStatusPageSections   = 20
+)
+
+// StatusPageRequest is the body of POST /api/v1/status-pages and PUT
+// /api/v1/status-pages/{pageId}. A PUT replaces the whole page, layout included.
+// Components outside of sections are shown first.
+type StatusPageRequest struct {
+	Slug        string                       `json:"slug"`
+	Title       string                       `json:"title"`
+	Description string                       `json:"description,omitempty"`
+	Theme       string                       `json:"theme,omitempty"`
+	LogoURL     *string                      `json:"logo_url,omitempty"`
+	Components  []StatusPageComponentRequest `json:"components,omitempty"`
+	Sections    []StatusPageSectionRequest   `json:"sections,omitempty"`
+}
+
+// StatusPageSectionRequest is a named group of components on a status page
+type StatusPageSectionRequest struct {
+	Name       string                       `json:"name"`
+	Components []StatusPageComponentRequest `json:"components"`
+}
+
+// StatusPageComponentRequest shows an app on a status page, under the app's name
+// unless another is given
+type StatusPageComponentRequest struct {
+	AppID int     `json:"app_id"`
+	Name  *string `json:"name,omitempty"`
+}
+
+// statusPageFromRequest validates a request against the organization's apps and
+// plan, writing an error and returning false if it can't be used
+func (h *Handler) statusPageFromRequest(w http.ResponseWriter, orgId int, req StatusPageRequest) (*db.StatusPage, bool) {
+	page := &db.StatusPage{
+		OrgId:       orgId,
+		Slug:        req.Slug,
+		Title:       strings.TrimSpace(req.Title),
+		Description: strings.TrimSpace(req.Description),
+		Theme:       req.Theme,
+		LogoURL:     req.LogoURL,
+		Components:  []db.StatusPageComponent{},
+		Sections:    []db.StatusPageSection{},
+	}
+	if page.Theme == "" {
+		page.Theme = "cyberpunk"
+	}
+	if page.LogoURL != nil && *page.LogoURL == "" {
+		page.LogoURL = nil
+	}
+
+	var problems []string
+	if !utils.CheckSlug(page.Slug) {
+		problems = append(problems, "slug may only contain lowercase letters, numbers and hyphens")
+	}
+	if page.Title == "" || len(page.Title) > 100 {
+		problems = append(problems, "title is required and must be at most 100 characters")
+	}
+	if len(page.Description) > 1000 {
+		problems = append(problems, "description must be at most 1000 characters")
+	}
+	if msg := validateAppFields(nil, nil, nil, &page.Theme, nil, page.LogoURL); msg != "" {
+		problems = append(problems, msg)
+	}
+	if len(req.Sections) > maxStatusPageSections {
+		problems = append(problems, fmt.Sprintf("a status page has at most %d sections", maxStatusPageSections))
+	}
+
+	orgApps, err := db.GetOrgApps(h.conn, orgId)
+	if err != nil {
+		log.Printf("Error fetching apps for org %d: %v", orgId, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to save status page")
+		return nil, false
+	}
+	inOrg := map[int]bool{}
+	for _, app := range orgApps {
+		inOrg[app.Id] = true
+	}
+
+	count := 0
+	seen := map[int]string{}
+	components := func(path string, reqs []StatusPageComponentRequest) []db.StatusPageComponent {
+		list := []db.StatusPageComponent{}
+		for i, c := range reqs {
+			at := fmt.Sprintf("%s[%d]", path, i)
+			count++
+			switch {
+			case !inOrg[c.AppID]:
+				problems = append(problems, fmt.Sprintf("%s.app_id: no app %d in this organization", at, c.AppID))
+			case seen[c.AppID] != "":
+				problems = append(problems, fmt.Sprintf("%s.app_id: app %d is already shown by %s", at, c.AppID, seen[c.AppID]))
+			default:
+				seen[c.AppID] = at
+			}
+			if c.Name != nil {
+				name := strings.TrimSpace(*c.Name)
+				if !utils.CheckAppName(name) {
+					problems = append(problems, at+".name: must be 1 to 100 characters")
+				}
+				c.Name = &name
+			}
+			list = append(list, db.StatusPageComponent{AppId: c.AppID, Name: c.Name})
+		}
+		return list
+	}
+
+	page.Components = components("components", req.Components)
+	for i, s := range req.Sections {
+		name := strings.TrimSpace(s.Name)
+		if name == "" || len(name) > 100 {
+			problems = append(problems, fmt.Sprintf("sections[%d].name: is required and must be at most 100 characters", i))
+		}
+		page.Sections = append(page.Sections, db.StatusPageSection{
+			Name:       name,
+			Components: components(fmt.Sprintf("sections[%d].components", i), s.Components),
+		})
+	}
+	if count > maxStatusPageComponents {
+		problems = append(problems, fmt.Sprintf("a status page shows at most %d components", maxStatusPageComponents))
+	}
+
+	if len(problems) > 0 {
+		respondError(w, http.StatusBadRequest, errCodeValidation, strings.Join(problems, "; "))
+		return nil, false
+	}
+
+	if page.LogoURL != nil {
+		plan, _ := db.GetOrgPlan(h.conn, orgId)
+		if !planAllowsCustomBranding(plan) {
+			respondError(w, http.StatusForbidden, errCodeForbidden, "Custom logos require Pro or Business plan")
+			return nil, false
+		}
+	}
+	return page, true
+}
+
+// statusPageFromURL loads the status page named by the pageId URL parameter. Pages of
+// other organizations are reported as not found, like apps.
+func (h *Handler) statusPageFromURL(w http.ResponseWriter, r *http.Request) (*db.StatusPage, bool) {
+	orgId, _ := orgFromContext(r)
+
+	pageId, err := strconv.Atoi(chi.URLParam(r, "pageId"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, errCodeInvalidRequest, "Invalid status page ID")
+		return nil, false
+	}
+
+	page, err := db.GetStatusPageById(h.conn, pageId)
+	if err == sql.ErrNoRows || (err == nil && page.OrgId != orgId) {
+		respondError(w, http.StatusNotFound, errCodeNotFound, "Status page not found")
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error fetching status page %d: %v", pageId, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to fetch status page")
+		return nil, false
+	}
+	return page, true
+}
+
+// respondWithStatusPage reloads a page after a change and writes it as {"status_page": ...}
+func (h *Handler) respondWithStatusPage(w http.ResponseWriter, status, pageId int) {
+	page, err := db.GetStatusPageById(h.conn, pageId)
+	if err != nil {
+		log.Printf("Error reloading status page %d: %v", pageId, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to fetch status page")
+		return
+	}
+	respondJSON(w, status, StatusPageResponse{StatusPage: page})
+}
+
+// ListStatusPagesV1Handler returns the organization's status pages with their layout
+func (h *Handler) ListStatusPagesV1Handler(w http.ResponseWriter, r *http.Request) {
+	orgId, _ := orgFromContext(r)
+
+	pages, err := db.GetOrgStatusPages(h.conn, orgId)
+	if err != nil {
+		log.Printf("Error fetching status pages for org %d: %v", orgId, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to fetch status pages")
+		return
+	}
+	respondJSON(w, http.StatusOK, StatusPageListResponse{StatusPages: pages})
+}
+
+// GetStatusPageV1Handler returns a single status page
+func (h *Handler) GetStatusPageV1Handler(w http.ResponseWriter, r *http.Request) {
+	page, ok := h.statusPageFromURL(w, r)
+	if !ok {
+		return
+	}
+	respondJSON(w, http.StatusOK, StatusPageResponse{StatusPage: page})
+}
+
+// CreateStatusPageV1Handler creates a status page showing some of the organization's apps
+func (h *Handler) CreateStatusPageV1Handler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+	orgId, _ := orgFromContext(r)
+	if !requireAPIRole(w, r, db.RoleEditor) {
+		return
+	}
+
+	var req StatusPageRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+	page, ok := h.statusPageFromRequest(w, orgId, req)
+	if !ok {
+		return
+	}
+
+	pageId, err := db.CreateStatusPage(h.conn, page)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate") {
+			respondError(w, http.StatusConflict, errCodeConflict, "A status page with this slug already exists")
+			return
+		}
+		log.Printf("Error creating status page for org %d: %v", orgId, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to create status page")
+		return
+	}
+	page.Id = pageId
+
+	h.audit(r, auditEvent{
+		Action:     AuditStatusPageCreate,
+		TargetType: "status_page",
+		TargetID:   pageId,
+		OrgID:      orgId,
+		After:      statusPageAuditValues(page),
+	})
+	log.Printf("📄 Status page %d (%s) created by user %d in org %d", pageId, page.Slug, userId, orgId)
+
+	h.respondWithStatusPage(w, http.StatusCreated, pageId)
+}
+
+// UpdateStatusPageV1Handler replaces a status page's settings and layout
+func (h *Handler) UpdateStatusPageV1Handler(w http.ResponseWriter, r *http.Request) {
+	before, ok := h.statusPageFromURL(w, r)
+	if !ok {
+		return
+	}
+	if !requireAPIRole(w, r, db.RoleEditor) {
+		return
+	}
+
+	var req StatusPageRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+	page, ok := h.statusPageFromRequest(w, before.OrgId, req)
+	if !ok {
+		return
+	}
+	page.Id = before.Id
+
+	updated, err := db.UpdateStatusPage(h.conn, page)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate") {
+			respondError(w, http.StatusConflict, errCodeConflict, "A status page with this slug already exists")
+			return
+		}
+		log.Printf("Error updating status page %d: %v", page.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to update status page")
+		return
+	}
+	if !updated {
+		respondError(w, http.StatusNotFound, errCodeNotFound, "Status page not found")
+		return
+	}
+	h.domains.reset()
+
+	h.audit(r, auditEvent{
+		Action:     AuditStatusPageUpdate,
+		TargetType: "status_page",
+		TargetID:   page.Id,
+		OrgID:      page.OrgId,
+		Before:     statusPageAuditValues(before),
+		After:      statusPageAuditValues(page),
+	})
+
+	h.respondWithStatusPage(w, http.StatusOK, page.Id)
+}
+
+// DeleteStatusPageV1Handler deletes a status page. Its apps are not touched.
+func (h *Handler) DeleteStatusPageV1Handler(w http.ResponseWriter, r *http.Request) {
+	page, ok := h.statusPageFromURL(w, r)
+	if !ok {
+		return
+	}
+	if !requireAPIRole(w, r, db.RoleEditor) {
+		return
+	}
+
+	deleted, err := db.DeleteStatusPage(h.conn, page.Id, page.OrgId)
+	if err != nil {
+		log.Printf("Error deleting status page %d: %v", page.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to delete status page")
+		return
+	}
+	if !deleted {
+		respondError(w, http.StatusNotFound, errCodeNotFound, "Status page not found")
+		return
+	}
+	h.domains.reset()
+
+	h.audit(r, auditEvent{
+		Action:     AuditStatusPageDelete,
+		TargetType: "status_page",
+		TargetID:   page.Id,
+		OrgID:      page.OrgId,
+		Before:     statusPageAuditValues(page),
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Overall status of a status page, worst first
+const (
+	pageStatusMajorOutage   = "major_outage"
+	pageStatusPartialOutage = "partial_outage"
+	pageStatusDegraded      = "degraded"
+	pageStatusMaintenance   = "maintenance"
+	pageStatusOperational   = "operational"
+	pageStatusPending       = "pending"
+)
+
+// componentStatus is what a component shows: paused, maintenance or pending, or the
+// status of its last check
+func componentStatus(s db.ComponentStatus) string {
+	switch {
+	case s.Paused:
+		return "paused"
+	case s.InMaintenance:
+		return "maintenance"
+	case s.StatusCode == nil:
+		return "pending"
+	}
+	return db.GetStatusFromCode(*s.StatusCode)
+}
+
+// overallStatus sums up component statuses. Paused and pending components don't
+// count, and an outage of every other component is a major one.
+func overallStatus(statuses []string) string {
+	active, failing, degraded, maintenance := 0, 0, 0, 0
+	for _, status := range statuses {
+		switch status {
+		case "paused", "pending":
+			continue
+		case "maintenance":
+			maintenance++
+			continue
+		case "degraded":
+			degraded++
+		case "up":
+		default:
+			failing++
+		}
+		active++
+	}
+
+	switch {
+	case failing > 0 && failing == active:
+		return pageStatusMajorOutage
+	case failing > 0:
+		return pageStatusPartialOutage
+	case degraded > 0:
+		return pageStatusDegraded
+	case maintenance > 0:
+		return pageStatusMaintenance
+	case active > 0:
+		return pageStatusOperational
+	}
+	return pageStatusPending
+}
+
+// GetPublicStatusPageHandler returns a status page with the current status and uptime
+// of each component (NO AUTH REQUIRED). The apps' slugs and health URLs are not shown.
+func (h *Handler) GetPublicStatusPageHandler(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	page, err := db.GetStatusPageBySlug(h.conn, slug)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, errCodeNotFound, "Status page not found")
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching status page %s: %v", slug, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to fetch status page")
+		return
+	}
+
+	resp, err := h.publicStatusPage(w, r, page)
+	if err != nil {
+		log.Printf("Error fetching component statuses of status page %s: %v", slug, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to fetch status page")
+		return
+	}
+	respondCachedStatusPage(w, r, resp)
+}
+
+// respondCachedStatusPage writes a public status page like respondCachedJSON, with an
+// ETag that leaves out the time it was generated so it only changes with the page
+func respondCachedStatusPage(w http.ResponseWriter, r *http.Request, resp *PublicStatusPageResponse) {
+	untimed := *resp
+	untimed.GeneratedAt = time.Time{}
+	tagged, err := json.Marshal(untimed)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+	body, err := json.Marshal(resp)
+	if err != nil {
+		log.Printf("Error encoding JSON response: %v", err)
+		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		return
+	}
+	writeCachedAs(w, r, "application/json", http.StatusOK, append(body, '\n'), tagged)
+}
+
+// publicStatusPage loads the status and uptime of each component of a page. Uptime
+// history that can't be read is left empty. Components are left out when the request
+// may not see their app's own status page, as are sections left with none.
+func (h *Handler) publicStatusPage(w http.ResponseWriter, r *http.Request, page *db.StatusPage) (*PublicStatusPageResponse, error) {
+	visible, err := h.visibleApps(w, r, page)
+	if err != nil {
+		return nil, err
+	}
+	var appIds []int
+	for _, id := range page.AppIds() {
+		if visible[id] {
+			appIds = append(appIds, id)
+		}
+	}
+
+	statuses, err := db.GetComponentStatuses(h.conn, appIds)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := db.GetOrgPlan(h.conn, page.OrgId)
+	if err != nil {
+		log.Printf("Error getting org plan: %v", err)
+		plan = "free"
+	}
+	dataRetentionDays := db.GetPlanFeatures(plan).DataRetentionDays
+
+	history, err := db.GetAppsDailyUptime(h.conn, appIds, dataRetentionDays)
+	if err != nil {
+		// The bars are left empty rather than failing the whole page
+		log.Printf("Error getting uptime history of status page %s: %v", page.Slug, err)
+	}
+
+	var all []string
+	components := func(list []db.StatusPageComponent) []PublicComponent {
+		public := []PublicComponent{}
+		for _, c := range list {
+			if !visible[c.AppId] {
+				continue
+			}
+			s := statuses[c.AppId]
+			component := PublicComponent{
+				Name:      s.AppName,
+				Status:    componentStatus(s),
+				CheckedAt: s.CheckedAt,
+			}
+			if c.Name != nil {
+				component.Name = *c.Name
+			}
+			// Apps that hide their uptime on their own page hide it here too
+			if s.ShowUptime {
+				component.Uptime24h = s.Uptime24h
+			}
+			if s.ShowUptimeHistory {
+				component.UptimeHistory = history[c.AppId]
+			}
+			if component.UptimeHistory == nil {
+				component.UptimeHistory = []db.DailyUptime{}
+			}
+			all = append(all, component.Status)
+			public = append(public, component)
+		}
+		return public
+	}
+
+	resp := PublicStatusPageResponse{
+		Slug:              page.Slug,
+		Title:             page.Title,
+		Description:       page.Description,
+		Theme:             page.Theme,
+		LogoURL:           page.LogoURL,
+		Components:        components(page.Components),
+		Sections:          []PublicSection{},
+		DataRetentionDays: dataRetentionDays,
+		GeneratedAt:       time.Now().UTC(),
+	}
+	for _, s := range page.Sections {
+		start := len(all)
+		section := PublicSection{Name: s.Name, Components: components(s.Components)}
+		if len(section.Components) == 0 && len(s.Components) > 0 {
+			continue
+		}
+		section.Status = overallStatus(all[start:])
+		resp.Sections = append(resp.Sections, section)
+	}
+	resp.Status = overallStatus(all)
+	return &resp, nil
+}
+
+// visibleApps reports which apps of a page the request may see, using the same check
+// as their own status pages
+func (h *Handler) visibleApps(w http.ResponseWriter, r *http.Request, page *db.StatusPage) (map[int]bool, error) {
+	visible := map[int]bool{}
+	for _, id := range page.AppIds() {
+		if _, checked := visible[id]; checked {
+			continue
+		}
+		access, err := h.appPageAccess(w, r, &db.App{Id: id, OrgId: page.OrgId})
+		if err != nil {
+			return nil, err
+		}
+		visible[id] = access.Allowed
+	}
+	return visible, nil
+}
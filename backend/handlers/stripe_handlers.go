@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"statusframe/backend/stripe_config"
 	"statusframe/db"
+	"strconv"
 
 	"github.com/stripe/stripe-go/v81"
 	billingportalsession "github.com/stripe/stripe-go/v81/billingportal/session"
@@ -16,7 +17,13 @@ import (
 	"github.com/stripe/stripe-go/v81/webhook"
 )
 
-// CreateCheckoutSessionHandler creates a Stripe checkout session for subscription
+// CheckoutRequest is the body of POST /api/create-checkout-session
+type CheckoutRequest struct {
+	Plan          string `json:"plan"`           // "pro" or "business"
+	BillingPeriod string `json:"billing_period"` // "monthly" or "yearly"
+}
+
+// CreateCheckoutSessionHandler creates a Stripe checkout session for the active organization's subscription
 func (h *Handler) CreateCheckoutSessionHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
@@ -31,11 +38,20 @@ func (h *Handler) CreateCheckoutSessionHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// Parse request body
-	var req struct {
-		Plan          string `json:"plan"`           // "pro" or "business"
-		BillingPeriod string `json:"billing_period"` // "monthly" or "yearly"
+	// Billing belongs to the organization and only its owners manage it
+	if !requireOrgRole(w, r, db.RoleOwner) {
+		return
 	}
+	orgId, _ := orgFromContext(r)
+	org, err := db.GetOrg(h.conn, orgId)
+	if err != nil {
+		log.Printf("Error loading org %d: %v", orgId, err)
+		http.Error(w, "Failed to load organization", http.StatusInternalServerError)
+		return
+	}
+
+	// Parse request body
+	var req CheckoutRequest
 
 	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
@@ -63,8 +79,8 @@ func (h *Handler) CreateCheckoutSessionHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// Check if user already has a Stripe customer ID
-	stripeCustomerID, err := db.GetStripeCustomerId(h.conn, user.Id)
+	// Check if the organization already has a Stripe customer ID
+	stripeCustomerID, err := db.GetStripeCustomerId(h.conn, orgId)
 	if err != nil {
 		log.Printf("Error getting Stripe customer ID: %v", err)
 	}
@@ -88,8 +104,9 @@ func (h *Handler) CreateCheckoutSessionHandler(w http.ResponseWriter, r *http.Re
 	if needsNewCustomer {
 		customerParams := &stripe.CustomerParams{
 			Email: stripe.String(user.Email),
-			Name:  stripe.String(user.Name),
+			Name:  stripe.String(org.Name),
 			Metadata: map[string]string{
+				"org_id":  fmt.Sprintf("%d", orgId),
 				"user_id": fmt.Sprintf("%d", user.Id),
 			},
 		}
@@ -102,14 +119,10 @@ func (h *Handler) CreateCheckoutSessionHandler(w http.ResponseWriter, r *http.Re
 		}
 		stripeCustomerID = cust.ID
 
-		log.Printf("✅ Created new Stripe customer %s for user %d", stripeCustomerID, user.Id)
+		log.Printf("✅ Created new Stripe customer %s for org %d", stripeCustomerID, orgId)
 
-		// Update user with Stripe customer ID
-		_, err = h.conn.Exec(
-			"UPDATE users SET stripe_customer_id = $1 WHERE id = $2",
-			stripeCustomerID,
-			user.Id,
-		)
+		// Update organization with Stripe customer ID
+		err = db.SetStripeCustomerId(h.conn, orgId, stripeCustomerID)
 		if err != nil {
 			log.Printf("Error saving Stripe customer ID: %v", err)
 		}
@@ -128,6 +141,7 @@ func (h *Handler) CreateCheckoutSessionHandler(w http.ResponseWriter, r *http.Re
 		SuccessURL: stripe.String(stripe_config.StripeConfig.AppURL + "/api/stripe-success?session_id={CHECKOUT_SESSION_ID}"),
 		CancelURL:  stripe.String(stripe_config.StripeConfig.AppURL + "/pricing?upgrade=cancelled"),
 		Metadata: map[string]string{
+			"org_id":  fmt.Sprintf("%d", orgId),
 			"user_id": fmt.Sprintf("%d", user.Id),
 			"plan":    req.Plan,
 		},
@@ -142,8 +156,17 @@ func (h *Handler) CreateCheckoutSessionHandler(w http.ResponseWriter, r *http.Re
 
 	// Return the checkout session URL
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"url": sess.URL,
+	json.NewEncoder(w).Encode(RedirectURLResponse{URL: sess.URL})
+}
+
+// StripeEnabledMiddleware rejects payment requests when Stripe is not configured
+func (h *Handler) StripeEnabledMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !stripe_config.Enabled() {
+			http.Error(w, "Payments are not configured", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
 	})
 }
 
@@ -233,24 +256,24 @@ func (h *Handler) handleCheckoutSessionCompleted(session stripe.CheckoutSession)
 	// This is just for logging
 }
 
-// handleSubscriptionCreated activates the user's subscription
+// handleSubscriptionCreated activates the organization's subscription
 func (h *Handler) handleSubscriptionCreated(subscription stripe.Subscription) {
 	log.Printf("🎉 Subscription created: %s for customer %s", subscription.ID, subscription.Customer.ID)
 
-	// Get user by Stripe customer ID
-	user, err := db.GetUserByStripeCustomerId(h.conn, subscription.Customer.ID)
+	// Get organization by Stripe customer ID
+	org, err := db.GetOrgByStripeCustomerId(h.conn, subscription.Customer.ID)
 	if err != nil {
-		log.Printf("❌ Error finding user for customer %s: %v", subscription.Customer.ID, err)
+		log.Printf("❌ Error finding organization for customer %s: %v", subscription.Customer.ID, err)
 		return
 	}
 
 	// Determine plan from subscription
 	plan := h.getPlanFromSubscription(&subscription)
 
-	// Update user's subscription in database
-	err = db.UpdateUserSubscription(
+	// Update organization's subscription in database
+	err = db.UpdateOrgSubscription(
 		h.conn,
-		user.Id,
+		org.Id,
 		plan,
 		subscription.Customer.ID,
 		subscription.ID,
@@ -259,60 +282,83 @@ func (h *Handler) handleSubscriptionCreated(subscription stripe.Subscription) {
 		log.Printf("❌ Error updating subscription: %v", err)
 		return
 	}
+	h.auditPlanChange(nil, "", org.Id, org.Plan, plan, subscription.ID)
 
-	log.Printf("✅ User %d upgraded to %s plan", user.Id, plan)
+	log.Printf("✅ Organization %d upgraded to %s plan", org.Id, plan)
 }
 
 // handleSubscriptionUpdated processes subscription changes
 func (h *Handler) handleSubscriptionUpdated(subscription stripe.Subscription) {
 	log.Printf("🔄 Subscription updated: %s", subscription.ID)
 
-	user, err := db.GetUserByStripeCustomerId(h.conn, subscription.Customer.ID)
+	org, err := db.GetOrgByStripeCustomerId(h.conn, subscription.Customer.ID)
 	if err != nil {
-		log.Printf("❌ Error finding user: %v", err)
+		log.Printf("❌ Error finding organization: %v", err)
 		return
 	}
 
 	// Check subscription status
 	if subscription.Status == "active" {
 		plan := h.getPlanFromSubscription(&subscription)
-		err = db.UpdateUserSubscription(
+		err = db.UpdateOrgSubscription(
 			h.conn,
-			user.Id,
+			org.Id,
 			plan,
 			subscription.Customer.ID,
 			subscription.ID,
 		)
 		if err != nil {
 			log.Printf("❌ Error updating subscription: %v", err)
+			return
 		}
+		h.auditPlanChange(nil, "", org.Id, org.Plan, plan, subscription.ID)
 	} else if subscription.Status == "canceled" || subscription.Status == "unpaid" {
 		// Downgrade to free
-		err = db.CancelUserSubscription(h.conn, user.Id)
+		err = db.CancelOrgSubscription(h.conn, org.Id)
 		if err != nil {
 			log.Printf("❌ Error canceling subscription: %v", err)
+			return
 		}
-		log.Printf("⬇️  User %d downgraded to free plan", user.Id)
+		h.auditPlanChange(nil, "", org.Id, org.Plan, "free", subscription.ID)
+		log.Printf("⬇️  Organization %d downgraded to free plan", org.Id)
 	}
 }
 
-// handleSubscriptionDeleted downgrades user to free plan
+// handleSubscriptionDeleted downgrades the organization to free plan
 func (h *Handler) handleSubscriptionDeleted(subscription stripe.Subscription) {
 	log.Printf("❌ Subscription deleted: %s", subscription.ID)
 
-	user, err := db.GetUserByStripeCustomerId(h.conn, subscription.Customer.ID)
+	org, err := db.GetOrgByStripeCustomerId(h.conn, subscription.Customer.ID)
 	if err != nil {
-		log.Printf("❌ Error finding user: %v", err)
+		log.Printf("❌ Error finding organization: %v", err)
 		return
 	}
 
-	err = db.CancelUserSubscription(h.conn, user.Id)
+	err = db.CancelOrgSubscription(h.conn, org.Id)
 	if err != nil {
 		log.Printf("❌ Error canceling subscription: %v", err)
 		return
 	}
+	h.auditPlanChange(nil, "", org.Id, org.Plan, "free", subscription.ID)
 
-	log.Printf("⬇️  User %d downgraded to free plan", user.Id)
+	log.Printf("⬇️  Organization %d downgraded to free plan", org.Id)
+}
+
+// auditPlanChange records a plan change caused by Stripe. Webhooks have no actor;
+// the success redirect records the owner who paid.
+func (h *Handler) auditPlanChange(actor *int, ip string, orgId int, from, to, subscriptionID string) {
+	if from == to {
+		return
+	}
+	h.writeAudit(actor, ip, auditEvent{
+		Action:     AuditBillingPlanChange,
+		TargetType: "organization",
+		TargetID:   orgId,
+		OrgID:      orgId,
+		Before:     map[string]string{"plan": from},
+		After:      map[string]string{"plan": to},
+		Details:    map[string]interface{}{"source": "stripe", "subscription_id": subscriptionID},
+	})
 }
 
 // getPlanFromSubscription determines the plan from a Stripe subscription
@@ -338,7 +384,7 @@ func (h *Handler) getPlanFromSubscription(subscription *stripe.Subscription) str
 }
 
 // StripeSuccessHandler handles the redirect after successful Stripe checkout
-// This verifies the payment and upgrades the user's plan
+// This verifies the payment and upgrades the organization's plan
 func (h *Handler) StripeSuccessHandler(w http.ResponseWriter, r *http.Request) {
 	// Get the session ID from the query parameter
 	sessionID := r.URL.Query().Get("session_id")
@@ -379,21 +425,41 @@ func (h *Handler) StripeSuccessHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Update user's subscription in database
-	err = db.UpdateUserSubscription(
+	// The checkout was started for an organization the user must still own
+	orgId, err := strconv.Atoi(session.Metadata["org_id"])
+	if err != nil {
+		log.Printf("❌ No organization in session metadata")
+		http.Redirect(w, r, "/onboarding?error=no_plan", http.StatusSeeOther)
+		return
+	}
+	role, err := db.GetOrgRole(h.conn, orgId, user.Id)
+	if err != nil || role != db.RoleOwner {
+		log.Printf("❌ User %d is not an owner of org %d from checkout session %s", user.Id, orgId, sessionID)
+		http.Redirect(w, r, "/pricing?error=plan_update", http.StatusSeeOther)
+		return
+	}
+
+	previousPlan, err := db.GetOrgPlan(h.conn, orgId)
+	if err != nil {
+		log.Printf("⚠️ Error loading current plan of org %d: %v", orgId, err)
+	}
+
+	// Update organization's subscription in database
+	err = db.UpdateOrgSubscription(
 		h.conn,
-		user.Id,
+		orgId,
 		plan,
 		string(session.Customer.ID),
 		string(session.Subscription.ID),
 	)
 	if err != nil {
-		log.Printf("❌ Error updating user subscription: %v", err)
+		log.Printf("❌ Error updating organization subscription: %v", err)
 		http.Redirect(w, r, "/onboarding?error=plan_update", http.StatusSeeOther)
 		return
 	}
+	h.auditPlanChange(&user.Id, h.clientIP(r), orgId, previousPlan, plan, string(session.Subscription.ID))
 
-	log.Printf("✅ User %d successfully upgraded to %s plan (session: %s)", user.Id, plan, sessionID)
+	log.Printf("✅ Organization %d successfully upgraded to %s plan by user %d (session: %s)", orgId, plan, user.Id, sessionID)
 
 	// Redirect to onboarding with success flag
 	http.Redirect(w, r, "/onboarding?subscribed=true&plan="+plan, http.StatusSeeOther)
@@ -406,14 +472,18 @@ func (h *Handler) CreateCustomerPortalSessionHandler(w http.ResponseWriter, r *h
 		return
 	}
 
-	user, err := db.GetUserFromContext(h.conn, r.Context())
+	_, err := db.GetUserFromContext(h.conn, r.Context())
 	if err != nil {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
+	if !requireOrgRole(w, r, db.RoleOwner) {
+		return
+	}
 
-	// Get user's Stripe customer ID
-	stripeCustomerID, err := db.GetStripeCustomerId(h.conn, user.Id)
+	// Get the organization's Stripe customer ID
+	orgId, _ := orgFromContext(r)
+	stripeCustomerID, err := db.GetStripeCustomerId(h.conn, orgId)
 	if err != nil || stripeCustomerID == "" {
 		http.Error(w, "No subscription found", http.StatusNotFound)
 		return
@@ -434,7 +504,5 @@ func (h *Handler) CreateCustomerPortalSessionHandler(w http.ResponseWriter, r *h
 
 	// Return the portal URL
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"url": sess.URL,
-	})
+	json.NewEncoder(w).Encode(RedirectURLResponse{URL: sess.URL})
 }
@@ -0,0 +1,438 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"statusframe/db"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Visitors subscribe to an app's status page by email, confirmed with a link sent to
+// the address, or by webhook, confirmed by a ping the webhook must answer. The
+// notices themselves are sent by the subscriber notifier in the worker package.
+
+// confirmationResendAfter is how long an address waits for another confirmation
+// email, so the form can't be used to flood someone's inbox
+const confirmationResendAfter = 10 * time.Minute
+
+// Limits on what can be subscribed
+const (
+	maxEmailLength      = 254
+	maxWebhookURLLength = 2048
+)
+
+// maxConcurrentNotices limits how many subscribers of an app are sent a notice at
+// once, so a few slow webhooks don't hold up everyone else
+const maxConcurrentNotices = 8
+
+// SubscribeRequest is the body of POST /api/public/status/{slug}/subscribe, with
+// either an email or a webhook_url
+type SubscribeRequest struct {
+	Email      string `json:"email,omitempty"`
+	WebhookURL string `json:"webhook_url,omitempty"`
+}
+
+// SubscriberNotice is sent to subscribers when an app's status changes or an
+// incident update is posted. Webhooks get it as JSON.
+type SubscriberNotice struct {
+	Type           string    `json:"type"` // status_change, incident_update or verification
+	App            NoticeApp `json:"app"`
+	Status         string    `json:"status,omitempty"`   // up, degraded or down, or the stage of the incident
+	Previous       string    `json:"previous,omitempty"` // the status before a change
+	Title          string    `json:"title,omitempty"`
+	Message        string    `json:"message,omitempty"`
+	Challenge      string    `json:"challenge,omitempty"` // to be echoed back by a webhook being verified
+	Timestamp      time.Time `json:"timestamp"`
+	UnsubscribeURL string    `json:"unsubscribe_url"`
+}
+
+// NoticeApp names the app a notice is about
+type NoticeApp struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+	URL  string `json:"url"` // its status page
+}
+
+// SetWebhookClient replaces the client that posts to subscribers' webhooks, which
+// refuses private addresses, e.g. with one that can reach a local test server
+func (h *Handler) SetWebhookClient(client *http.Client) {
+	h.webhookClient = client
+}
+
+// publicWebhookClient posts to subscribers' webhooks. Anyone can subscribe one, so it
+// doesn't follow redirects or connect to loopback, private or link-local addresses,
+// where it could reach our own network.
+var publicWebhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{Timeout: 5 * time.Second, Control: dialPublicOnly}).DialContext,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// dialPublicOnly refuses connections to addresses that aren't public
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return fmt.Errorf("%s is not a public address", host)
+	}
+	return nil
+}
+
+func (h *Handler) webhookHTTPClient() *http.Client {
+	if h.webhookClient != nil {
+		return h.webhookClient
+	}
+	return publicWebhookClient
+}
+
+// SubscribeHandler subscribes an email address or a webhook to an app's status
+// changes and incident updates (NO AUTH REQUIRED). An address gets a link to confirm
+// the subscription; a webhook is pinged with a challenge it has to echo back.
+func (h *Handler) SubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	app, err := db.GetAppBySlug(h.conn, slug)
+	if err != nil {
+		http.Error(w, "Status page not found", http.StatusNotFound)
+		return
+	}
+
+	// Notices would reach people after their access to a private page has ended
+	settings, err := db.GetAppAccess(h.conn, app.Id)
+	if err != nil {
+		log.Printf("Error checking access to app %d: %v", app.Id, err)
+		http.Error(w, "Error subscribing", http.StatusInternalServerError)
+		return
+	}
+	if !settings.Public() {
+		http.Error(w, "Only public status pages can be subscribed to", http.StatusForbidden)
+		return
+	}
+
+	var req SubscribeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	req.WebhookURL = strings.TrimSpace(req.WebhookURL)
+
+	switch {
+	case req.Email != "" && req.WebhookURL != "":
+		http.Error(w, "Subscribe with either an email or a webhook_url", http.StatusBadRequest)
+	case req.Email != "":
+		h.subscribeEmail(w, app, req.Email)
+	case req.WebhookURL != "":
+		h.subscribeWebhook(w, r, app, req.WebhookURL)
+	default:
+		http.Error(w, "An email or a webhook_url is required", http.StatusBadRequest)
+	}
+}
+
+func (h *Handler) subscribeEmail(w http.ResponseWriter, app *db.App, address string) {
+	if parsed, err := mail.ParseAddress(address); err != nil || parsed.Address != address || len(address) > maxEmailLength {
+		http.Error(w, "Invalid email address", http.StatusBadRequest)
+		return
+	}
+
+	pending := SubscribeResponse{Status: "pending_confirmation", Message: "Check your inbox to confirm the subscription"}
+
+	// Whether an address is subscribed already isn't given away, and it gets at most
+	// one confirmation email per confirmationResendAfter
+	existing, err := db.GetSubscriber(h.conn, app.Id, db.SubscriberEmail, address)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error fetching subscriber of app %d: %v", app.Id, err)
+		http.Error(w, "Error subscribing", http.StatusInternalServerError)
+		return
+	}
+	if existing != nil && (existing.ConfirmedAt != nil || time.Since(existing.CreatedAt) < confirmationResendAfter) {
+		respondJSON(w, http.StatusAccepted, pending)
+		return
+	}
+
+	token, err := generateSubscriberToken()
+	if err != nil {
+		log.Printf("Error generating subscriber token: %v", err)
+		http.Error(w, "Error subscribing", http.StatusInternalServerError)
+		return
+	}
+	if err := db.SaveSubscriber(h.conn, app.Id, db.SubscriberEmail, address, token, false); err != nil {
+		log.Printf("Error saving subscriber of app %d: %v", app.Id, err)
+		http.Error(w, "Error subscribing", http.StatusInternalServerError)
+		return
+	}
+
+	h.sendSubscriptionConfirmation(app, address, token)
+	respondJSON(w, http.StatusAccepted, pending)
+}
+
+func (h *Handler) subscribeWebhook(w http.ResponseWriter, r *http.Request, app *db.App, webhookURL string) {
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || len(webhookURL) > maxWebhookURLLength {
+		http.Error(w, "webhook_url must be an http or https URL", http.StatusBadRequest)
+		return
+	}
+
+	token, err := generateSubscriberToken()
+	if err != nil {
+		log.Printf("Error generating subscriber token: %v", err)
+		http.Error(w, "Error subscribing", http.StatusInternalServerError)
+		return
+	}
+
+	ping := h.newNotice(app.AppName, app.Slug, token)
+	ping.Type = "verification"
+	ping.Challenge = token
+	body, err := h.postWebhook(r.Context(), webhookURL, ping)
+	if err != nil {
+		http.Error(w, "The webhook didn't answer the verification ping: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !bytes.Contains(body, []byte(token)) {
+		http.Error(w, "The webhook's answer to the verification ping doesn't contain the challenge", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.SaveSubscriber(h.conn, app.Id, db.SubscriberWebhook, webhookURL, token, true); err != nil {
+		log.Printf("Error saving subscriber of app %d: %v", app.Id, err)
+		http.Error(w, "Error subscribing", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("🔔 Webhook subscribed to app %d (%s)", app.Id, app.Slug)
+	respondJSON(w, http.StatusCreated, SubscribeResponse{
+		Status:         "confirmed",
+		Message:        "The webhook is subscribed",
+		UnsubscribeURL: ping.UnsubscribeURL,
+	})
+}
+
+// ConfirmSubscriptionHandler confirms an email subscription from the link in the
+// confirmation email and goes back to the status page (NO AUTH REQUIRED)
+func (h *Handler) ConfirmSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	slug, err := db.ConfirmSubscriber(h.conn, chi.URLParam(r, "token"))
+	if err == sql.ErrNoRows {
+		http.Error(w, "This subscription doesn't exist anymore. Subscribe again on the status page.", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error confirming subscriber: %v", err)
+		http.Error(w, "Error confirming subscription", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/status/"+slug+"?subscribed=1", http.StatusSeeOther)
+}
+
+// UnsubscribeHandler ends a subscription from the link in every notice (NO AUTH
+// REQUIRED). It answers POST too, for webhooks and one-click unsubscribe.
+func (h *Handler) UnsubscribeHandler(w http.ResponseWriter, r *http.Request) {
+	slug, err := db.DeleteSubscriber(h.conn, chi.URLParam(r, "token"))
+	if err == sql.ErrNoRows {
+		http.Error(w, "This subscription doesn't exist anymore", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error deleting subscriber: %v", err)
+		http.Error(w, "Error unsubscribing", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	http.Redirect(w, r, "/status/"+slug+"?unsubscribed=1", http.StatusSeeOther)
+}
+
+// sendSubscriptionConfirmation emails the link that confirms a subscription, or logs
+// it when no mailer is configured
+func (h *Handler) sendSubscriptionConfirmation(app *db.App, to, token string) {
+	link := h.subscriptionURL(token, "confirm")
+
+	if h.mailer == nil {
+		log.Printf("⚠️ No mailer configured - subscription link for %s: %s", to, link)
+		return
+	}
+
+	subject := fmt.Sprintf("Confirm your subscription to %s status updates", app.AppName)
+	textBody := fmt.Sprintf("Confirm that you want to get an email when %s goes down, recovers or posts an incident update:\n\n%s\n\nIf you didn't ask for this, ignore this email and you won't hear from us again.", app.AppName, link)
+	htmlBody := fmt.Sprintf(`<p>Confirm that you want to get an email when <strong>%s</strong> goes down, recovers or posts an incident update.</p><p><a href="%s">Confirm the subscription</a></p><p>If you didn't ask for this, ignore this email and you won't hear from us again.</p>`,
+		html.EscapeString(app.AppName), html.EscapeString(link))
+
+	// Sending is slow and the subscription is already stored, so don't hold up the response
+	go func() {
+		if err := h.mailer.Send(to, subject, htmlBody, textBody); err != nil {
+			log.Printf("❌ Error sending subscription confirmation to %s: %v", to, err)
+		}
+	}()
+}
+
+// subscriptionURL is the confirm or unsubscribe link of a subscription
+func (h *Handler) subscriptionURL(token, action string) string {
+	return strings.TrimRight(h.cfg.Server.PublicURL, "/") + "/api/public/subscriptions/" + token + "/" + action
+}
+
+// newNotice starts a notice about an app for the subscriber with token
+func (h *Handler) newNotice(appName, slug, token string) SubscriberNotice {
+	return SubscriberNotice{
+		App: NoticeApp{
+			Name: appName,
+			Slug: slug,
+			URL:  strings.TrimRight(h.cfg.Server.PublicURL, "/") + "/status/" + slug,
+		},
+		Timestamp:      time.Now().UTC(),
+		UnsubscribeURL: h.subscriptionURL(token, "unsubscribe"),
+	}
+}
+
+// StatusChangeNotice describes an app's change of status for its subscribers
+func (h *Handler) StatusChangeNotice(app db.SubscribedApp, previous, status string) SubscriberNotice {
+	n := h.newNotice(app.AppName, app.Slug, "")
+	n.Type = "status_change"
+	n.Status = status
+	n.Previous = previous
+	n.Title = statusChangeTitle(app.AppName, status)
+	n.Message = statusChangeText(app.AppName, db.StatusChange{
+		Status: status, Previous: previous, StatusCode: app.StatusCode, CheckedAt: app.CheckedAt,
+	}, app.ShowCode)
+	return n
+}
+
+// IncidentUpdateNotice describes a posted incident update for the app's subscribers
+func (h *Handler) IncidentUpdateNotice(u db.UnsentIncidentUpdate) SubscriberNotice {
+	n := h.newNotice(u.AppName, u.AppSlug, "")
+	n.Type = "incident_update"
+	n.Status = u.Status
+	n.Title = incidentUpdateTitle(u.AppName, u.Status)
+	n.Message = u.Message
+	n.Timestamp = u.CreatedAt.UTC()
+	return n
+}
+
+// NotifySubscribers sends a notice to every confirmed subscriber of an app, a few at
+// a time. Failed deliveries are logged; failing to load the subscribers is an error,
+// and so is ctx ending before everyone was sent the notice, which stops the sending
+// and cancels webhooks being posted.
+func (h *Handler) NotifySubscribers(ctx context.Context, appId int, notice SubscriberNotice) error {
+	subscribers, err := db.GetConfirmedSubscribers(h.conn, appId)
+	if err != nil {
+		return err
+	}
+
+	sem := make(chan struct{}, maxConcurrentNotices)
+	var wg sync.WaitGroup
+	for _, s := range subscribers {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(s db.Subscriber) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			n := notice
+			n.UnsubscribeURL = h.subscriptionURL(s.Token, "unsubscribe")
+
+			var err error
+			switch s.Kind {
+			case db.SubscriberEmail:
+				err = h.emailNotice(s.Target, n)
+			case db.SubscriberWebhook:
+				_, err = h.postWebhook(ctx, s.Target, n)
+			}
+			if err != nil {
+				log.Printf("⚠️ Error notifying subscriber %d of app %d: %v", s.Id, appId, err)
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(subscribers) > 0 {
+		log.Printf("🔔 Sent %s of app %d to %d subscriber(s)", notice.Type, appId, len(subscribers))
+	}
+	return nil
+}
+
+// emailNotice emails a notice with its unsubscribe link
+func (h *Handler) emailNotice(to string, n SubscriberNotice) error {
+	if h.mailer == nil {
+		log.Printf("⚠️ No mailer configured - not emailing %s: %s", to, n.Title)
+		return nil
+	}
+
+	subject := fmt.Sprintf("[%s] %s", n.App.Name, n.Title)
+	textBody := fmt.Sprintf("%s\n\n%s\n\nStatus page: %s\n\nUnsubscribe: %s", n.Title, n.Message, n.App.URL, n.UnsubscribeURL)
+	htmlBody := fmt.Sprintf(`<h2>%s</h2><p>%s</p><p><a href="%s">View the status page</a></p><p style="font-size:12px;color:#888">You subscribed to updates of %s. <a href="%s">Unsubscribe</a></p>`,
+		html.EscapeString(n.Title), strings.ReplaceAll(html.EscapeString(n.Message), "\n", "<br>"), html.EscapeString(n.App.URL),
+		html.EscapeString(n.App.Name), html.EscapeString(n.UnsubscribeURL))
+	return h.mailer.Send(to, subject, htmlBody, textBody)
+}
+
+// postWebhook posts a notice as JSON and returns the start of the answer, failing
+// unless the webhook answers with a 2xx status
+func (h *Handler) postWebhook(ctx context.Context, webhookURL string, n SubscriberNotice) ([]byte, error) {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "UpLitycs-Webhook/1.0")
+
+	resp, err := h.webhookHTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("webhook answered %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+// generateSubscriberToken creates the secret part of a subscription's links
+func generateSubscriberToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
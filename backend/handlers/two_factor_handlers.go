@@ -0,0 +1,456 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"statusframe/backend/auth"
+	"statusframe/db"
+	"time"
+)
+
+const errTwoFactorRequired = "Forbidden - this organization requires two-factor authentication"
+
+// A second factor has to be given within pendingTwoFactorTTL of the OAuth callback,
+// and a session gets maxTwoFactorAttempts tries before the sign in starts over
+const (
+	pendingTwoFactorTTL  = 10 * time.Minute
+	maxTwoFactorAttempts = 5
+)
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type UpdateOrgSecurityRequest struct {
+	Require2FA bool `json:"require_2fa"`
+}
+
+// clearPendingTwoFactor forgets a sign in that was waiting for a second factor
+func clearPendingTwoFactor(values map[interface{}]interface{}) {
+	delete(values, "pending2faUserId")
+	delete(values, "pending2faName")
+	delete(values, "pending2faOnboarding")
+	delete(values, "pending2faStartedAt")
+	delete(values, "pending2faAttempts")
+}
+
+// checkSecondFactor accepts an authenticator code or an unused recovery code. Each code
+// works once: authenticator codes can't be replayed and recovery codes are used up.
+func (h *Handler) checkSecondFactor(userId int, state db.TOTPState, code string) (bool, error) {
+	if auth.IsTOTPCode(code) {
+		step, ok := auth.VerifyTOTP(state.Secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		return db.AcceptTOTPStep(h.conn, userId, step)
+	}
+
+	used, err := db.UseRecoveryCode(h.conn, userId, auth.HashRecoveryCode(code))
+	if used {
+		log.Printf("🔐 User %d used a recovery code", userId)
+	}
+	return used, err
+}
+
+// VerifyTwoFactorLoginHandler finishes a sign in waiting for a second factor. It takes
+// a form post from the /two-factor page and redirects like the OAuth callback does.
+func (h *Handler) VerifyTwoFactorLoginHandler(w http.ResponseWriter, r *http.Request) {
+	session, err := auth.Store.Get(r, "auth-session")
+	if err != nil {
+		http.Redirect(w, r, "/auth?error=session_expired", http.StatusFound)
+		return
+	}
+
+	userId, ok := session.Values["pending2faUserId"].(int)
+	startedAt, _ := session.Values["pending2faStartedAt"].(int64)
+	if !ok || time.Since(time.Unix(startedAt, 0)) > pendingTwoFactorTTL {
+		clearPendingTwoFactor(session.Values)
+		session.Save(r, w)
+		http.Redirect(w, r, "/auth?error=session_expired", http.StatusFound)
+		return
+	}
+	name, _ := session.Values["pending2faName"].(string)
+	needsOnboarding, _ := session.Values["pending2faOnboarding"].(bool)
+
+	state, err := db.GetTOTPState(h.conn, userId)
+	if err != nil {
+		log.Printf("Error loading two-factor state of user %d: %v", userId, err)
+		http.Redirect(w, r, "/two-factor?error=failed", http.StatusFound)
+		return
+	}
+	if state.EnabledAt == nil {
+		// Turned off from another session in the meantime
+		h.finishLogin(w, r, session, userId, name, needsOnboarding)
+		return
+	}
+
+	valid, err := h.checkSecondFactor(userId, state, r.FormValue("code"))
+	if err != nil {
+		log.Printf("Error checking second factor of user %d: %v", userId, err)
+		http.Redirect(w, r, "/two-factor?error=failed", http.StatusFound)
+		return
+	}
+	if !valid {
+		attempts, _ := session.Values["pending2faAttempts"].(int)
+		attempts++
+		log.Printf("⚠️ Wrong second factor for user %d (attempt %d)", userId, attempts)
+		if attempts >= maxTwoFactorAttempts {
+			clearPendingTwoFactor(session.Values)
+			session.Save(r, w)
+			http.Redirect(w, r, "/auth?error=too_many_attempts", http.StatusFound)
+			return
+		}
+		session.Values["pending2faAttempts"] = attempts
+		session.Save(r, w)
+		http.Redirect(w, r, "/two-factor?error=invalid_code", http.StatusFound)
+		return
+	}
+
+	h.finishLogin(w, r, session, userId, name, needsOnboarding)
+}
+
+// GetTwoFactorHandler reports whether the user has two-factor authentication on
+func (h *Handler) GetTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	state, err := db.GetTOTPState(h.conn, userId)
+	if err != nil {
+		log.Printf("Error loading two-factor state of user %d: %v", userId, err)
+		http.Error(w, "Failed to load two-factor settings", http.StatusInternalServerError)
+		return
+	}
+
+	remaining := 0
+	if state.EnabledAt != nil {
+		remaining, err = db.CountRecoveryCodes(h.conn, userId)
+		if err != nil {
+			log.Printf("Error counting recovery codes of user %d: %v", userId, err)
+			http.Error(w, "Failed to load two-factor settings", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	respondJSON(w, http.StatusOK, TwoFactorStatusResponse{
+		Enabled:                state.EnabledAt != nil,
+		EnabledAt:              state.EnabledAt,
+		RecoveryCodesRemaining: remaining,
+	})
+}
+
+// SetupTwoFactorHandler starts setup with a new secret for the user's authenticator app.
+// Two-factor authentication stays off until a code from the app is confirmed.
+func (h *Handler) SetupTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	user, err := db.GetUserById(h.conn, userId)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		log.Printf("Error generating TOTP secret: %v", err)
+		http.Error(w, "Failed to start two-factor setup", http.StatusInternalServerError)
+		return
+	}
+
+	started, err := db.SetPendingTOTPSecret(h.conn, userId, secret)
+	if err != nil {
+		log.Printf("Error storing TOTP secret for user %d: %v", userId, err)
+		http.Error(w, "Failed to start two-factor setup", http.StatusInternalServerError)
+		return
+	}
+	if !started {
+		http.Error(w, "Two-factor authentication is already on", http.StatusConflict)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURL: auth.TOTPURI(secret, user.Email),
+	})
+}
+
+// EnableTwoFactorHandler turns two-factor authentication on once the user confirms a code
+// from their app. The recovery codes are returned once and only their hashes are kept.
+func (h *Handler) EnableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	state, err := db.GetTOTPState(h.conn, userId)
+	if err != nil {
+		log.Printf("Error loading two-factor state of user %d: %v", userId, err)
+		http.Error(w, "Failed to turn on two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	if state.EnabledAt != nil {
+		http.Error(w, "Two-factor authentication is already on", http.StatusConflict)
+		return
+	}
+	if state.Secret == "" {
+		http.Error(w, "Start two-factor setup first", http.StatusBadRequest)
+		return
+	}
+
+	step, ok := auth.VerifyTOTP(state.Secret, req.Code, time.Now())
+	if !ok {
+		http.Error(w, "Invalid code", http.StatusBadRequest)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		log.Printf("Error generating recovery codes: %v", err)
+		http.Error(w, "Failed to turn on two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	enabled, err := db.EnableTOTP(h.conn, userId, step, hashes)
+	if err != nil {
+		log.Printf("Error enabling two-factor authentication for user %d: %v", userId, err)
+		http.Error(w, "Failed to turn on two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	if !enabled {
+		http.Error(w, "Two-factor authentication is already on", http.StatusConflict)
+		return
+	}
+
+	log.Printf("🔐 Two-factor authentication turned on for user %d", userId)
+	h.audit(r, auditEvent{
+		Action:     AuditTwoFactorEnable,
+		TargetType: "user",
+		TargetID:   userId,
+	})
+
+	respondJSON(w, http.StatusOK, TwoFactorChangeResponse{
+		Enabled:       true,
+		RecoveryCodes: codes,
+	})
+}
+
+// DisableTwoFactorHandler turns two-factor authentication off. It takes a current code,
+// so a borrowed session alone can't remove it, and is refused while an organization
+// the user belongs to requires it.
+func (h *Handler) DisableTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	if !h.confirmSecondFactor(w, r, userId) {
+		return
+	}
+
+	required, err := db.CountOrgsRequiring2FA(h.conn, userId)
+	if err != nil {
+		log.Printf("Error checking 2FA requirements of user %d: %v", userId, err)
+		http.Error(w, "Failed to turn off two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	if required > 0 {
+		http.Error(w, "An organization you belong to requires two-factor authentication", http.StatusConflict)
+		return
+	}
+
+	if err := db.DisableTOTP(h.conn, userId); err != nil {
+		log.Printf("Error disabling two-factor authentication for user %d: %v", userId, err)
+		http.Error(w, "Failed to turn off two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("🔓 Two-factor authentication turned off for user %d", userId)
+	h.audit(r, auditEvent{
+		Action:     AuditTwoFactorDisable,
+		TargetType: "user",
+		TargetID:   userId,
+	})
+
+	respondJSON(w, http.StatusOK, TwoFactorChangeResponse{Enabled: false})
+}
+
+// RegenerateRecoveryCodesHandler replaces the user's recovery codes, for when they've
+// used most of them or lost the list
+func (h *Handler) RegenerateRecoveryCodesHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	if !h.confirmSecondFactor(w, r, userId) {
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		log.Printf("Error generating recovery codes: %v", err)
+		http.Error(w, "Failed to create recovery codes", http.StatusInternalServerError)
+		return
+	}
+	if err := db.ReplaceRecoveryCodes(h.conn, userId, hashes); err != nil {
+		log.Printf("Error storing recovery codes for user %d: %v", userId, err)
+		http.Error(w, "Failed to create recovery codes", http.StatusInternalServerError)
+		return
+	}
+
+	h.audit(r, auditEvent{
+		Action:     AuditRecoveryCodesRegenerate,
+		TargetType: "user",
+		TargetID:   userId,
+	})
+
+	respondJSON(w, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// confirmSecondFactor reads {"code"} from the request and checks it for a user with
+// two-factor authentication on. It writes the error response and returns false otherwise.
+func (h *Handler) confirmSecondFactor(w http.ResponseWriter, r *http.Request, userId int) bool {
+	var req TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return false
+	}
+
+	state, err := db.GetTOTPState(h.conn, userId)
+	if err != nil {
+		log.Printf("Error loading two-factor state of user %d: %v", userId, err)
+		http.Error(w, "Failed to check code", http.StatusInternalServerError)
+		return false
+	}
+	if state.EnabledAt == nil {
+		http.Error(w, "Two-factor authentication is not on", http.StatusBadRequest)
+		return false
+	}
+
+	valid, err := h.checkSecondFactor(userId, state, req.Code)
+	if err != nil {
+		log.Printf("Error checking second factor of user %d: %v", userId, err)
+		http.Error(w, "Failed to check code", http.StatusInternalServerError)
+		return false
+	}
+	if !valid {
+		http.Error(w, "Invalid code", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// newRecoveryCodes returns fresh recovery codes and the hashes to store for them
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := auth.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
+
+// GetOrgSecurityHandler shows whether the organization requires two-factor authentication
+// and which members haven't turned it on yet. Admins and owners only.
+func (h *Handler) GetOrgSecurityHandler(w http.ResponseWriter, r *http.Request) {
+	orgId, role, ok := h.pathOrgRole(w, r)
+	if !ok {
+		return
+	}
+	if !db.RoleAtLeast(role, db.RoleAdmin) {
+		http.Error(w, "Forbidden - requires the admin role in this organization", http.StatusForbidden)
+		return
+	}
+
+	require, err := db.GetOrgRequire2FA(h.conn, orgId)
+	if err != nil {
+		log.Printf("Error loading security settings of org %d: %v", orgId, err)
+		http.Error(w, "Failed to load security settings", http.StatusInternalServerError)
+		return
+	}
+	without, err := db.GetOrgMembersWithout2FA(h.conn, orgId)
+	if err != nil {
+		log.Printf("Error loading members of org %d: %v", orgId, err)
+		http.Error(w, "Failed to load security settings", http.StatusInternalServerError)
+		return
+	}
+
+	if without == nil {
+		without = []db.OrgMember{}
+	}
+	respondJSON(w, http.StatusOK, OrgSecurityResponse{
+		Require2FA:        require,
+		MembersWithout2FA: without,
+	})
+}
+
+// UpdateOrgSecurityHandler lets owners require two-factor authentication for every member.
+// Members without it lose access to the organization until they turn it on.
+func (h *Handler) UpdateOrgSecurityHandler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	orgId, role, ok := h.pathOrgRole(w, r)
+	if !ok {
+		return
+	}
+	if role != db.RoleOwner {
+		http.Error(w, "Forbidden - requires the owner role in this organization", http.StatusForbidden)
+		return
+	}
+
+	var req UpdateOrgSecurityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	personal, err := db.GetPersonalOrgId(h.conn, userId)
+	if err != nil {
+		log.Printf("Error loading personal org of user %d: %v", userId, err)
+		http.Error(w, "Failed to update security settings", http.StatusInternalServerError)
+		return
+	}
+	if personal == orgId {
+		http.Error(w, "Personal organizations have no other members", http.StatusBadRequest)
+		return
+	}
+
+	// Owners can't lock themselves out
+	if req.Require2FA {
+		state, err := db.GetTOTPState(h.conn, userId)
+		if err != nil {
+			log.Printf("Error loading two-factor state of user %d: %v", userId, err)
+			http.Error(w, "Failed to update security settings", http.StatusInternalServerError)
+			return
+		}
+		if state.EnabledAt == nil {
+			http.Error(w, "Turn on two-factor authentication for your own account first", http.StatusConflict)
+			return
+		}
+	}
+
+	before, err := db.GetOrgRequire2FA(h.conn, orgId)
+	if err != nil {
+		log.Printf("Error loading security settings of org %d: %v", orgId, err)
+		http.Error(w, "Failed to update security settings", http.StatusInternalServerError)
+		return
+	}
+	if err := db.SetOrgRequire2FA(h.conn, orgId, req.Require2FA); err != nil {
+		log.Printf("Error updating security settings of org %d: %v", orgId, err)
+		http.Error(w, "Failed to update security settings", http.StatusInternalServerError)
+		return
+	}
+
+	if before != req.Require2FA {
+		log.Printf("🔐 Org %d two-factor requirement set to %t by user %d", orgId, req.Require2FA, userId)
+		h.audit(r, auditEvent{
+			Action:     AuditOrgRequire2FA,
+			TargetType: "organization",
+			TargetID:   orgId,
+			OrgID:      orgId,
+			Before:     map[string]interface{}{"require_2fa": before},
+			After:      map[string]interface{}{"require_2fa": req.Require2FA},
+		})
+	}
+
+	respondJSON(w, http.StatusOK, OrgSecurityResponse{Require2FA: req.Require2FA})
+}
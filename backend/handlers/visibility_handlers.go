@@ -0,0 +1,654 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"statusframe/backend/auth"
+	"statusframe/backend/utils"
+	"statusframe/db"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// An app's status page can be public, protected by a password, limited to a list of
+// addresses or to the members of its organization. Editors can hand out signed share
+// links that let anyone in until they expire. A visitor who is let in with a password
+// or a share link gets a cookie signed with the same key, so the page's own requests
+// get through too. Making the page public, or revoking the links, replaces the key.
+
+// Limits on page visibility
+const (
+	minPagePasswordLength = 8
+	maxPagePasswordLength = 128
+	maxIPAllowlist        = 100
+	accessCookieTTL       = 7 * 24 * time.Hour // after entering the password
+	defaultShareLinkTTL   = 7 * 24 * time.Hour
+	maxShareLinkTTL       = 90 * 24 * time.Hour
+)
+
+// pagePasswordIterations is the PBKDF2 work factor for page passwords
+const pagePasswordIterations = 600000
+
+// Wrong page passwords allowed a minute, from one visitor address over all pages and
+// for one page from everyone. Attempts over either limit get 429 before the password
+// is hashed.
+const (
+	unlockFailuresPerIP   = 10
+	unlockFailuresPerPage = 30
+)
+
+// VisibilityRequest is the body of PUT /api/v1/apps/{appId}/visibility
+type VisibilityRequest struct {
+	Visibility  string   `json:"visibility"`             // public, password, ip_allowlist or members
+	Password    string   `json:"password,omitempty"`     // required to turn on password protection, otherwise keeps the current one
+	IPAllowlist []string `json:"ip_allowlist,omitempty"` // addresses and CIDR ranges, for ip_allowlist
+}
+
+// ShareLinkRequest is the body of POST /api/v1/apps/{appId}/share-links
+type ShareLinkRequest struct {
+	ExpiresIn string `json:"expires_in,omitempty"` // a duration like "72h", 7 days by default and 90 days at most
+}
+
+// PublicFieldsRequest is the body of PATCH /api/v1/apps/{appId}/public-fields. Fields
+// that are left out keep their setting.
+type PublicFieldsRequest struct {
+	StatusCode    *bool `json:"status_code,omitempty"`
+	ResponseTime  *bool `json:"response_time,omitempty"`
+	Uptime        *bool `json:"uptime,omitempty"`
+	UptimeHistory *bool `json:"uptime_history,omitempty"`
+}
+
+// UnlockRequest is the JSON body of POST /api/public/status/{slug}/unlock
+type UnlockRequest struct {
+	Password string `json:"password"`
+}
+
+// pageAccess is whether a visitor may see an app's status page
+type pageAccess struct {
+	*db.AppAccess
+	Allowed  bool
+	SignedIn bool // on pages for members, whether the visitor is signed in at all
+}
+
+// appPageAccess decides whether the request may see an app's status page. A valid
+// share link in ?share= also leaves a cookie, so the requests the page makes get in.
+func (h *Handler) appPageAccess(w http.ResponseWriter, r *http.Request, app *db.App) (*pageAccess, error) {
+	settings, err := db.GetAppAccess(h.conn, app.Id)
+	if err != nil {
+		return nil, err
+	}
+	access := &pageAccess{AppAccess: settings}
+	if settings.Public() {
+		access.Allowed = true
+		return access, nil
+	}
+
+	// What is sent depends on who asks, so shared caches must not keep it
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Add("Vary", "Cookie")
+
+	if token := r.URL.Query().Get("share"); token != "" {
+		if expires, ok := verifySigned(token, func(exp string) string { return shareSignature(settings, exp) }); ok {
+			h.setAccessCookie(w, settings, expires)
+			access.Allowed = true
+			return access, nil
+		}
+	}
+	if cookie, err := r.Cookie(accessCookieName(app.Id)); err == nil {
+		if _, ok := verifySigned(cookie.Value, func(exp string) string { return cookieSignature(settings, exp) }); ok {
+			access.Allowed = true
+			return access, nil
+		}
+	}
+
+	switch settings.Visibility {
+	case db.VisibilityIPAllowlist:
+		access.Allowed = utils.IPInList(h.visitorIP(r), settings.IPAllowlist)
+	case db.VisibilityMembers:
+		userId, ok := auth.SessionUserID(r)
+		if !ok {
+			break
+		}
+		access.SignedIn = true
+		role, needs2FA, err := db.GetOrgAccess(h.conn, app.OrgId, userId)
+		if err != nil {
+			return nil, err
+		}
+		access.Allowed = role != "" && !needs2FA
+	}
+	return access, nil
+}
+
+// denial is the status and message for a visitor who isn't let in
+func (a *pageAccess) denial() (int, string) {
+	switch a.Visibility {
+	case db.VisibilityPassword:
+		return http.StatusUnauthorized, "This status page is password protected"
+	case db.VisibilityMembers:
+		if !a.SignedIn {
+			return http.StatusUnauthorized, "Sign in to see this status page"
+		}
+		return http.StatusForbidden, "This status page is only visible to members of its organization"
+	}
+	return http.StatusForbidden, "This status page is private"
+}
+
+// requireAppAccess writes a plain refusal and returns false unless the request may
+// see the app's status page. X-Page-Visibility tells the page what to ask for.
+func (h *Handler) requireAppAccess(w http.ResponseWriter, r *http.Request, app *db.App) bool {
+	access, err := h.appPageAccess(w, r, app)
+	if err != nil {
+		log.Printf("Error checking access to app %d: %v", app.Id, err)
+		http.Error(w, "Error checking access", http.StatusInternalServerError)
+		return false
+	}
+	if !access.Allowed {
+		denyPageAccess(w, access)
+		return false
+	}
+	return true
+}
+
+// denyPageAccess writes the plain refusal of requireAppAccess
+func denyPageAccess(w http.ResponseWriter, access *pageAccess) {
+	status, message := access.denial()
+	w.Header().Set("X-Page-Visibility", access.Visibility)
+	http.Error(w, message, status)
+}
+
+// UnlockStatusPageHandler lets a visitor who knows the password into a status page
+// (NO AUTH REQUIRED). The password comes as JSON or from the form of the
+// server-rendered page, which is sent back to the page.
+func (h *Handler) UnlockStatusPageHandler(w http.ResponseWriter, r *http.Request) {
+	slug := chi.URLParam(r, "slug")
+
+	app, err := db.GetAppBySlug(h.conn, slug)
+	if err != nil {
+		http.Error(w, "Status page not found", http.StatusNotFound)
+		return
+	}
+	settings, err := db.GetAppAccess(h.conn, app.Id)
+	if err != nil {
+		log.Printf("Error checking access to app %d: %v", app.Id, err)
+		http.Error(w, "Error checking access", http.StatusInternalServerError)
+		return
+	}
+	if settings.Visibility != db.VisibilityPassword {
+		http.Error(w, "This status page has no password", http.StatusBadRequest)
+		return
+	}
+
+	form := strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded")
+	var password string
+	if form {
+		password = r.PostFormValue("password")
+	} else {
+		var req UnlockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		password = req.Password
+	}
+
+	// Every attempt takes a token up front, so a burst of parallel guesses is limited
+	// too; a right password gives them back.
+	ip, now := h.visitorIP(r).String(), time.Now()
+	if ok, wait := h.unlockVisitorLimiter.allow(ip, now); !ok {
+		tooManyRequests(w, wait)
+		return
+	}
+	if ok, wait := h.unlockPageLimiter.allow(app.Slug, now); !ok {
+		h.unlockVisitorLimiter.refund(ip, now)
+		tooManyRequests(w, wait)
+		return
+	}
+
+	page := "/status/" + app.Slug
+	if !checkPagePassword(settings.PasswordHash, password) {
+		log.Printf("⚠️ Wrong password for status page %s from %s", app.Slug, ip)
+		if form {
+			http.Redirect(w, r, page+"?wrong_password=1", http.StatusSeeOther)
+			return
+		}
+		http.Error(w, "Wrong password", http.StatusUnauthorized)
+		return
+	}
+
+	h.unlockVisitorLimiter.refund(ip, now)
+	h.unlockPageLimiter.refund(app.Slug, now)
+
+	h.setAccessCookie(w, settings, now.Add(accessCookieTTL))
+	if form {
+		http.Redirect(w, r, page, http.StatusSeeOther)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAppVisibilityV1Handler returns who can see an app's status page
+func (h *Handler) GetAppVisibilityV1Handler(w http.ResponseWriter, r *http.Request) {
+	app, ok := h.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	settings, err := db.GetAppAccess(h.conn, app.Id)
+	if err != nil {
+		log.Printf("Error fetching visibility of app %d: %v", app.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to fetch visibility")
+		return
+	}
+	respondJSON(w, http.StatusOK, VisibilityResponse{Visibility: settings})
+}
+
+// SetAppVisibilityV1Handler changes who can see an app's status page. Share links
+// keep working while the page stays private; making it public revokes them.
+func (h *Handler) SetAppVisibilityV1Handler(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value("userId").(int)
+
+	app, ok := h.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if !requireAPIRole(w, r, db.RoleEditor) {
+		return
+	}
+
+	var req VisibilityRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+
+	before, err := db.GetAppAccess(h.conn, app.Id)
+	if err != nil {
+		log.Printf("Error fetching visibility of app %d: %v", app.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to fetch visibility")
+		return
+	}
+
+	after := &db.AppAccess{AppId: app.Id, Visibility: req.Visibility, IPAllowlist: []string{}, ShareKey: before.ShareKey}
+	var problems []string
+	if !slices.Contains(db.Visibilities, req.Visibility) {
+		problems = append(problems, "visibility must be one of "+strings.Join(db.Visibilities, ", "))
+	}
+
+	switch {
+	case req.Password != "" && req.Visibility != db.VisibilityPassword:
+		problems = append(problems, "password is only used with the password visibility")
+	case req.Visibility != db.VisibilityPassword:
+	case req.Password != "":
+		if n := len([]rune(req.Password)); n < minPagePasswordLength || n > maxPagePasswordLength {
+			problems = append(problems, fmt.Sprintf("password must be %d to %d characters", minPagePasswordLength, maxPagePasswordLength))
+		}
+	case before.PasswordHash == "":
+		problems = append(problems, "password is required to turn on password protection")
+	default:
+		after.PasswordHash = before.PasswordHash
+	}
+
+	if req.Visibility == db.VisibilityIPAllowlist {
+		for _, entry := range req.IPAllowlist {
+			entry = strings.TrimSpace(entry)
+			if _, err := utils.ParseNetwork(entry); err != nil {
+				problems = append(problems, fmt.Sprintf("ip_allowlist entry %q is neither an address nor a CIDR range", entry))
+				continue
+			}
+			after.IPAllowlist = append(after.IPAllowlist, entry)
+		}
+		if len(req.IPAllowlist) == 0 {
+			problems = append(problems, "ip_allowlist needs at least one address")
+		} else if len(req.IPAllowlist) > maxIPAllowlist {
+			problems = append(problems, "ip_allowlist may have at most "+strconv.Itoa(maxIPAllowlist)+" entries")
+		}
+	} else if len(req.IPAllowlist) > 0 {
+		problems = append(problems, "ip_allowlist is only used with the ip_allowlist visibility")
+	}
+
+	if len(problems) > 0 {
+		respondError(w, http.StatusBadRequest, errCodeValidation, strings.Join(problems, "; "))
+		return
+	}
+
+	if req.Password != "" {
+		if after.PasswordHash, err = hashPagePassword(req.Password); err != nil {
+			log.Printf("Error hashing page password: %v", err)
+			respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to update visibility")
+			return
+		}
+	}
+	if !after.Public() && after.ShareKey == "" {
+		if after.ShareKey, err = generateShareKey(); err != nil {
+			log.Printf("Error generating share key: %v", err)
+			respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to update visibility")
+			return
+		}
+	}
+
+	if err := db.SaveAppAccess(h.conn, after); err != nil {
+		log.Printf("Error saving visibility of app %d: %v", app.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to update visibility")
+		return
+	}
+
+	h.audit(r, auditEvent{
+		Action:     AuditAppVisibility,
+		TargetType: "app",
+		TargetID:   app.Id,
+		OrgID:      app.OrgId,
+		Before:     visibilityAuditValues(before),
+		After:      visibilityAuditValues(after),
+	})
+	log.Printf("🔒 Status page of app %d made %s by user %d", app.Id, after.Visibility, userId)
+
+	settings, err := db.GetAppAccess(h.conn, app.Id)
+	if err != nil {
+		log.Printf("Error fetching visibility of app %d: %v", app.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to fetch visibility")
+		return
+	}
+	respondJSON(w, http.StatusOK, VisibilityResponse{Visibility: settings})
+}
+
+// CreateShareLinkV1Handler signs a link that lets anyone into an app's private status
+// page until it expires. Links aren't stored; they all stop working when revoked.
+func (h *Handler) CreateShareLinkV1Handler(w http.ResponseWriter, r *http.Request) {
+	app, ok := h.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if !requireAPIRole(w, r, db.RoleEditor) {
+		return
+	}
+
+	var req ShareLinkRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+	ttl := defaultShareLinkTTL
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || d < time.Minute || d > maxShareLinkTTL {
+			respondError(w, http.StatusBadRequest, errCodeValidation, "expires_in must be a duration from 1m to "+maxShareLinkTTL.String())
+			return
+		}
+		ttl = d
+	}
+
+	settings, err := db.GetAppAccess(h.conn, app.Id)
+	if err != nil {
+		log.Printf("Error fetching visibility of app %d: %v", app.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to create share link")
+		return
+	}
+	if settings.Public() {
+		respondError(w, http.StatusConflict, errCodeConflict, "The status page is public, share its address instead")
+		return
+	}
+
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second).UTC()
+	exp := strconv.FormatInt(expiresAt.Unix(), 10)
+	link := fmt.Sprintf("%s/status/%s?share=%s.%s", strings.TrimRight(h.cfg.Server.PublicURL, "/"), app.Slug, exp, shareSignature(settings, exp))
+
+	h.audit(r, auditEvent{
+		Action:     AuditAppShareLink,
+		TargetType: "app",
+		TargetID:   app.Id,
+		OrgID:      app.OrgId,
+		Details:    map[string]interface{}{"expires_at": expiresAt},
+	})
+	respondJSON(w, http.StatusCreated, ShareLinkResponse{URL: link, ExpiresAt: expiresAt})
+}
+
+// RevokeShareLinksV1Handler stops every share link of an app's status page from
+// working, along with the cookies of visitors they or the password let in
+func (h *Handler) RevokeShareLinksV1Handler(w http.ResponseWriter, r *http.Request) {
+	app, ok := h.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if !requireAPIRole(w, r, db.RoleEditor) {
+		return
+	}
+
+	settings, err := db.GetAppAccess(h.conn, app.Id)
+	if err != nil {
+		log.Printf("Error fetching visibility of app %d: %v", app.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to revoke share links")
+		return
+	}
+	if settings.Public() {
+		respondError(w, http.StatusConflict, errCodeConflict, "The status page is public and has no share links")
+		return
+	}
+
+	if settings.ShareKey, err = generateShareKey(); err == nil {
+		err = db.SaveAppAccess(h.conn, settings)
+	}
+	if err != nil {
+		log.Printf("Error revoking share links of app %d: %v", app.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to revoke share links")
+		return
+	}
+
+	h.audit(r, auditEvent{
+		Action:     AuditAppShareLinksRevoke,
+		TargetType: "app",
+		TargetID:   app.Id,
+		OrgID:      app.OrgId,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPublicFieldsV1Handler returns which details an app's status page shows
+func (h *Handler) GetPublicFieldsV1Handler(w http.ResponseWriter, r *http.Request) {
+	app, ok := h.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	fields, err := db.GetPublicFields(h.conn, app.Id)
+	if err != nil {
+		log.Printf("Error fetching public fields of app %d: %v", app.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to fetch public fields")
+		return
+	}
+	respondJSON(w, http.StatusOK, PublicFieldsResponse{PublicFields: fields})
+}
+
+// UpdatePublicFieldsV1Handler shows or hides details on an app's status page. They
+// are hidden everywhere the page's data goes: its JSON, ping, badge, feeds and notices,
+// and the multi-app pages the app is on.
+func (h *Handler) UpdatePublicFieldsV1Handler(w http.ResponseWriter, r *http.Request) {
+	app, ok := h.appFromRequest(w, r)
+	if !ok {
+		return
+	}
+	if !requireAPIRole(w, r, db.RoleEditor) {
+		return
+	}
+
+	var req PublicFieldsRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+
+	before, err := db.GetPublicFields(h.conn, app.Id)
+	if err != nil {
+		log.Printf("Error fetching public fields of app %d: %v", app.Id, err)
+		respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to update public fields")
+		return
+	}
+	after := before
+	for _, f := range []struct {
+		set  *bool
+		dest *bool
+	}{
+		{req.StatusCode, &after.StatusCode},
+		{req.ResponseTime, &after.ResponseTime},
+		{req.Uptime, &after.Uptime},
+		{req.UptimeHistory, &after.UptimeHistory},
+	} {
+		if f.set != nil {
+			*f.dest = *f.set
+		}
+	}
+
+	if after != before {
+		if err := db.SavePublicFields(h.conn, app.Id, after); err != nil {
+			log.Printf("Error saving public fields of app %d: %v", app.Id, err)
+			respondError(w, http.StatusInternalServerError, errCodeInternal, "Failed to update public fields")
+			return
+		}
+		h.audit(r, auditEvent{
+			Action:     AuditAppPublicFields,
+			TargetType: "app",
+			TargetID:   app.Id,
+			OrgID:      app.OrgId,
+			Before:     before,
+			After:      after,
+		})
+	}
+	respondJSON(w, http.StatusOK, PublicFieldsResponse{PublicFields: after})
+}
+
+// GetStatusViewerHandler tells a status page whether its visitor can change the app
+// (NO AUTH REQUIRED). The session decides, so the page never learns who owns the app.
+func (h *Handler) GetStatusViewerHandler(w http.ResponseWriter, r *http.Request) {
+	app, err := db.GetAppBySlug(h.conn, chi.URLParam(r, "slug"))
+	if err != nil {
+		http.Error(w, "Status page not found", http.StatusNotFound)
+		return
+	}
+
+	// The answer differs per visitor, so it is never cached
+	w.Header().Set("Cache-Control", "private, no-store")
+	var viewer StatusViewerResponse
+	if userId, ok := auth.SessionUserID(r); ok {
+		role, needs2FA, err := db.GetOrgAccess(h.conn, app.OrgId, userId)
+		if err != nil {
+			log.Printf("Error checking membership of user %d in org %d: %v", userId, app.OrgId, err)
+			http.Error(w, "Error checking the session", http.StatusInternalServerError)
+			return
+		}
+		viewer.Owner = !needs2FA && db.RoleAtLeast(role, db.RoleEditor)
+	}
+	respondJSON(w, http.StatusOK, viewer)
+}
+
+// setAccessCookie lets the visitor into the page until expires
+func (h *Handler) setAccessCookie(w http.ResponseWriter, settings *db.AppAccess, expires time.Time) {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	http.SetCookie(w, &http.Cookie{
+		Name:     accessCookieName(settings.AppId),
+		Value:    exp + "." + cookieSignature(settings, exp),
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   h.cfg.Auth.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func accessCookieName(appId int) string {
+	return "sf_page_" + strconv.Itoa(appId)
+}
+
+// shareSignature signs a share link of the page that expires at exp
+func shareSignature(settings *db.AppAccess, exp string) string {
+	return pageSignature(settings.ShareKey, "share", strconv.Itoa(settings.AppId), exp)
+}
+
+// cookieSignature signs an access cookie. It covers the password too, so changing
+// the password sends everyone who entered the old one back to the form.
+func cookieSignature(settings *db.AppAccess, exp string) string {
+	return pageSignature(settings.ShareKey, "cookie", strconv.Itoa(settings.AppId), exp, settings.PasswordHash)
+}
+
+func pageSignature(key string, parts ...string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(strings.Join(parts, "|")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifySigned checks a value of the form <expiry>.<signature> and returns when it expires
+func verifySigned(value string, sign func(exp string) string) (time.Time, bool) {
+	exp, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return time.Time{}, false
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	expires := time.Unix(unix, 0)
+	if !time.Now().Before(expires) || !hmac.Equal([]byte(signature), []byte(sign(exp))) {
+		return time.Time{}, false
+	}
+	return expires, true
+}
+
+// hashPagePassword hashes a page password with PBKDF2 as
+// pbkdf2-sha256$<iterations>$<salt>$<hash>
+func hashPagePassword(password string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, pagePasswordIterations, 32)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", pagePasswordIterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// checkPagePassword reports whether password matches a hash from hashPagePassword
+func checkPagePassword(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	got, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+	return err == nil && subtle.ConstantTimeCompare(got, want) == 1
+}
+
+// generateShareKey creates the key that signs a page's share links and cookies
+func generateShareKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// visitorIP is the address a request came from, behind the configured proxies
+func (h *Handler) visitorIP(r *http.Request) net.IP {
+	return utils.ClientIP(r, h.cfg.Server.TrustedProxies)
+}
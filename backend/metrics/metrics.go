@@ -0,0 +1,197 @@
+package metrics
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"log"
+	"net/http"
+	"statusframe/db"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "statusframe"
+
+// Registry holds every metric we expose. A private registry keeps the output
+// limited to what we register here instead of whatever imports add to the default one.
+var Registry = prometheus.NewRegistry()
+
+var (
+	checksTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "checks_total",
+		Help:      "Health checks run, by resulting status.",
+	}, []string{"status"})
+
+	checkDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "check_duration_seconds",
+		Help:      "Time taken to request an app's health URL.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	})
+
+	notificationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "Incident notifications sent, by channel and outcome.",
+	}, []string{"channel", "outcome"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP handler latency by method, chi route pattern and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "code"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		checksTotal,
+		checkDuration,
+		notificationsTotal,
+		httpDuration,
+	)
+}
+
+// ObserveCheck records the outcome and latency of a single health check
+func ObserveCheck(status string, duration time.Duration) {
+	checksTotal.WithLabelValues(status).Inc()
+	checkDuration.Observe(duration.Seconds())
+}
+
+// ObserveNotification records whether a notification was delivered on channel
+func ObserveNotification(channel string, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	notificationsTotal.WithLabelValues(channel, outcome).Inc()
+}
+
+// RegisterSSLQueue exposes the number of SSL checks waiting to run
+func RegisterSSLQueue(depth func() int) {
+	Registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ssl_check_queue_depth",
+		Help:      "On-demand SSL checks waiting to run.",
+	}, func() float64 {
+		return float64(depth())
+	}))
+}
+
+// RegisterDB exposes connection pool stats and per-app gauges read from the database
+func RegisterDB(conn *sql.DB) {
+	Registry.MustRegister(
+		collectors.NewDBStatsCollector(conn, "postgres"),
+		newAppCollector(conn),
+	)
+}
+
+// Middleware measures handler latency labeled by chi route pattern so that
+// /api/apps/{appId} is one series instead of one per app
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		httpDuration.WithLabelValues(r.Method, route, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Handler serves the metrics to scrapers presenting token as a Bearer token.
+// It answers 404 when no token is configured so the endpoint stays hidden.
+func Handler(token string) http.Handler {
+	metricsHandler := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		metricsHandler.ServeHTTP(w, r)
+	})
+}
+
+// appCollector reads the latest state of every monitored app on each scrape
+type appCollector struct {
+	conn *sql.DB
+
+	up           *prometheus.Desc
+	responseTime *prometheus.Desc
+	sslDays      *prometheus.Desc
+}
+
+func newAppCollector(conn *sql.DB) *appCollector {
+	labels := []string{"slug"}
+	return &appCollector{
+		conn: conn,
+		up: prometheus.NewDesc(namespace+"_app_up",
+			"Whether the app's last check was up (1) or not (0).", labels, nil),
+		responseTime: prometheus.NewDesc(namespace+"_app_response_time_seconds",
+			"Response time of the app's last check.", labels, nil),
+		sslDays: prometheus.NewDesc(namespace+"_app_ssl_days_remaining",
+			"Days until the app's SSL certificate expires.", labels, nil),
+	}
+}
+
+func (c *appCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.up
+	ch <- c.responseTime
+	ch <- c.sslDays
+}
+
+func (c *appCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	apps, err := db.GetAppMetrics(ctx, c.conn)
+	if err != nil {
+		log.Printf("⚠️ Error collecting app metrics: %v", err)
+		return
+	}
+
+	for _, app := range apps {
+		if app.StatusCode != nil {
+			up := 0.0
+			if db.GetStatusFromCode(*app.StatusCode) == "up" {
+				up = 1
+			}
+			ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, up, app.Slug)
+		}
+		if app.ResponseTimeMs != nil {
+			ch <- prometheus.MustNewConstMetric(c.responseTime, prometheus.GaugeValue, float64(*app.ResponseTimeMs)/1000, app.Slug)
+		}
+		if app.SSLDaysUntilExpiry != nil {
+			ch <- prometheus.MustNewConstMetric(c.sslDays, prometheus.GaugeValue, float64(*app.SSLDaysUntilExpiry), app.Slug)
+		}
+	}
+}
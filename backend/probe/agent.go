@@ -0,0 +1,257 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RegisterRequest is sent by an agent when it starts
+type RegisterRequest struct {
+	Name   string `json:"name"`
+	Region string `json:"region"`
+}
+
+// RegisterResponse tells the agent who it is and how often to poll
+type RegisterResponse struct {
+	AgentID             int    `json:"agent_id"`
+	Region              string `json:"region"`
+	PollIntervalSeconds int    `json:"poll_interval_seconds"`
+}
+
+// Assignment is a single check the agent should run
+type Assignment struct {
+	AppID           int    `json:"app_id"`
+	HealthURL       string `json:"health_url"`
+	IntervalSeconds int    `json:"interval_seconds"`
+}
+
+// Result is the outcome of a single check run by the agent
+type Result struct {
+	AppID          int       `json:"app_id"`
+	StatusCode     int       `json:"status_code"`
+	ResponseTimeMs int64     `json:"response_time_ms"`
+	CheckedAt      time.Time `json:"checked_at"`
+}
+
+// ResultsRequest is a batch of results pushed to the main server
+type ResultsRequest struct {
+	AgentID int      `json:"agent_id"`
+	Results []Result `json:"results"`
+}
+
+// maxConcurrentChecks limits how many health URLs an agent requests at once
+const maxConcurrentChecks = 10
+
+// Agent runs health checks from a remote region and reports them to the main server
+type Agent struct {
+	serverURL    string
+	token        string
+	name         string
+	region       string
+	client       *http.Client
+	agentID      int
+	pollInterval time.Duration
+	nextCheck    map[int]time.Time
+}
+
+func NewAgent(serverURL, token, name, region string) *Agent {
+	return &Agent{
+		serverURL: strings.TrimRight(serverURL, "/"),
+		token:     token,
+		name:      name,
+		region:    region,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		pollInterval: 30 * time.Second,
+		nextCheck:    make(map[int]time.Time),
+	}
+}
+
+// Run registers the agent and keeps checking until ctx is cancelled
+func (a *Agent) Run(ctx context.Context) error {
+	if err := a.registerWithRetry(ctx); err != nil {
+		return err
+	}
+
+	log.Printf("🛰️  Probe agent %s (region %s) registered as agent %d - polling every %s",
+		a.name, a.region, a.agentID, a.pollInterval)
+
+	ticker := time.NewTicker(a.pollInterval)
+	defer ticker.Stop()
+
+	// Run immediately on start
+	a.runDueChecks(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("🛑 Probe agent %s stopping", a.name)
+			return nil
+		case <-ticker.C:
+			a.runDueChecks(ctx)
+		}
+	}
+}
+
+// registerWithRetry keeps trying to register until the server answers or ctx is cancelled
+func (a *Agent) registerWithRetry(ctx context.Context) error {
+	backoff := time.Second
+	for {
+		err := a.register(ctx)
+		if err == nil {
+			return nil
+		}
+
+		log.Printf("⚠️  Probe agent registration failed: %v (retrying in %s)", err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+func (a *Agent) register(ctx context.Context) error {
+	var resp RegisterResponse
+	err := a.doJSON(ctx, http.MethodPost, "/api/probe/register", RegisterRequest{Name: a.name, Region: a.region}, &resp)
+	if err != nil {
+		return err
+	}
+
+	a.agentID = resp.AgentID
+	if resp.PollIntervalSeconds > 0 {
+		a.pollInterval = time.Duration(resp.PollIntervalSeconds) * time.Second
+	}
+	return nil
+}
+
+// runDueChecks pulls the current assignments, runs the ones that are due and pushes the results
+func (a *Agent) runDueChecks(ctx context.Context) {
+	var assignments []Assignment
+	path := fmt.Sprintf("/api/probe/checks?agent_id=%d", a.agentID)
+	if err := a.doJSON(ctx, http.MethodGet, path, nil, &assignments); err != nil {
+		log.Printf("❌ Error pulling probe assignments: %v", err)
+		return
+	}
+
+	now := time.Now()
+	var due []Assignment
+	assigned := make(map[int]bool, len(assignments))
+	for _, assignment := range assignments {
+		assigned[assignment.AppID] = true
+		if next, ok := a.nextCheck[assignment.AppID]; ok && now.Before(next) {
+			continue
+		}
+		a.nextCheck[assignment.AppID] = now.Add(time.Duration(assignment.IntervalSeconds) * time.Second)
+		due = append(due, assignment)
+	}
+
+	// Forget apps that were deleted or paused, so they are checked right away if they come back
+	for appID := range a.nextCheck {
+		if !assigned[appID] {
+			delete(a.nextCheck, appID)
+		}
+	}
+
+	if len(due) == 0 {
+		return
+	}
+
+	results := make([]Result, len(due))
+	sem := make(chan struct{}, maxConcurrentChecks)
+	var wg sync.WaitGroup
+	for i, assignment := range due {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, assignment Assignment) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = a.check(ctx, assignment)
+		}(i, assignment)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	err := a.doJSON(ctx, http.MethodPost, "/api/probe/results", ResultsRequest{AgentID: a.agentID, Results: results}, nil)
+	if err != nil {
+		log.Printf("❌ Error pushing %d probe result(s): %v", len(results), err)
+		return
+	}
+
+	log.Printf("🔍 Region %s reported %d check(s)", a.region, len(results))
+}
+
+// check runs a single health check and measures its response time
+func (a *Agent) check(ctx context.Context, assignment Assignment) Result {
+	result := Result{AppID: assignment.AppID, CheckedAt: time.Now().UTC()}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, assignment.HealthURL, nil)
+	if err != nil {
+		return result
+	}
+
+	startTime := time.Now()
+	resp, err := a.client.Do(req)
+	result.ResponseTimeMs = time.Since(startTime).Milliseconds()
+	if err != nil {
+		return result
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	return result
+}
+
+// doJSON sends an authenticated JSON request to the main server and decodes the response into out
+func (a *Agent) doJSON(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("error marshaling payload: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.serverURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+a.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
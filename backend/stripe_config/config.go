@@ -2,7 +2,7 @@ package stripe_config
 
 import (
 	"log"
-	"os"
+	"statusframe/backend/config"
 
 	"github.com/stripe/stripe-go/v81"
 )
@@ -18,29 +18,31 @@ type Config struct {
 	AppURL                 string
 }
 
-var StripeConfig *Config
+// StripeConfig is never nil so callers can read it even when payments are disabled
+var StripeConfig = &Config{}
 
-// Initialize sets up Stripe configuration from environment variables
-func Initialize() {
+// Initialize sets up Stripe from the application configuration.
+// Payments stay disabled when no secret key is configured.
+func Initialize(cfg config.StripeConfig, appURL string) {
 	StripeConfig = &Config{
-		SecretKey:              os.Getenv("STRIPE_SECRET_KEY"),
-		PublishableKey:         os.Getenv("STRIPE_PUBLISHABLE_KEY"),
-		ProMonthlyPriceID:      os.Getenv("STRIPE_PRO_MONTHLY_PRICE_ID"),
-		ProYearlyPriceID:       os.Getenv("STRIPE_PRO_YEARLY_PRICE_ID"),
-		BusinessMonthlyPriceID: os.Getenv("STRIPE_BUSINESS_MONTHLY_PRICE_ID"),
-		BusinessYearlyPriceID:  os.Getenv("STRIPE_BUSINESS_YEARLY_PRICE_ID"),
-		WebhookSecret:          os.Getenv("STRIPE_WEBHOOK_SECRET"),
-		AppURL:                 os.Getenv("APP_URL"),
+		SecretKey:              cfg.SecretKey,
+		PublishableKey:         cfg.PublishableKey,
+		ProMonthlyPriceID:      cfg.ProMonthlyPriceID,
+		ProYearlyPriceID:       cfg.ProYearlyPriceID,
+		BusinessMonthlyPriceID: cfg.BusinessMonthlyPriceID,
+		BusinessYearlyPriceID:  cfg.BusinessYearlyPriceID,
+		WebhookSecret:          cfg.WebhookSecret,
+		AppURL:                 appURL,
+	}
+
+	if !Enabled() {
+		log.Println("⚠️  Stripe is not configured - payments are disabled")
+		return
 	}
 
 	// Set the Stripe API key
 	stripe.Key = StripeConfig.SecretKey
 
-	// Validate required config
-	if StripeConfig.SecretKey == "" {
-		log.Fatal("STRIPE_SECRET_KEY environment variable is required")
-	}
-
 	log.Println("✅ Stripe configuration initialized")
 	log.Printf("📦 Pro Monthly Price: %s", StripeConfig.ProMonthlyPriceID)
 	log.Printf("📦 Pro Yearly Price: %s", StripeConfig.ProYearlyPriceID)
@@ -48,6 +50,11 @@ func Initialize() {
 	log.Printf("📦 Business Yearly Price: %s", StripeConfig.BusinessYearlyPriceID)
 }
 
+// Enabled reports whether Stripe payments are configured
+func Enabled() bool {
+	return StripeConfig.SecretKey != ""
+}
+
 // GetPriceID returns the Stripe price ID for a given plan and billing period
 func GetPriceID(plan string, billingPeriod string) string {
 	switch plan {
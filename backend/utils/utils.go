@@ -2,9 +2,13 @@ package utils
 
 import (
 	"bytes"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"regexp"
+	"statusframe/backend/config"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -57,34 +61,111 @@ func CheckAlerts(alerts string) bool {
 	return true
 }
 
-func getAWSConfig() (map[string]string, error) {
-	region := os.Getenv("AWS_REGION")
-	accessKeyID := os.Getenv("AWS_ACCESS_KEY_ID")
-	secretAccessKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
-	bucketName := os.Getenv("AWS_S3_BUCKET_NAME")
-	if bucketName == "" {
-		log.Println("⚠️  AWS S3 bucket name not set in environment variables")
-		return nil, os.ErrInvalid
+// ValidThemes lists the status page themes an app can use
+var ValidThemes = []string{"cyberpunk", "matrix", "retro", "minimal"}
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9-]{1,63}$`)
+
+var domainPattern = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9-]{0,61}[a-z0-9]$`)
+
+func CheckTheme(theme string) bool {
+	for _, t := range ValidThemes {
+		if theme == t {
+			return true
+		}
 	}
-	if region == "" || accessKeyID == "" || secretAccessKey == "" {
-		log.Println("⚠️  AWS credentials or region not set in environment variables")
-		return nil, os.ErrInvalid
+	return false
+}
+
+// CheckSlug accepts the same characters the onboarding form allows: lowercase letters, digits and hyphens
+func CheckSlug(slug string) bool {
+	return slugPattern.MatchString(slug)
+}
+
+// CheckDomain accepts a lowercase hostname with at least two labels, like status.example.com
+func CheckDomain(domain string) bool {
+	return len(domain) <= 253 && domainPattern.MatchString(domain)
+}
+
+func CheckAppName(name string) bool {
+	name = strings.TrimSpace(name)
+	return name != "" && len(name) <= 100
+}
+
+// ParseNetwork parses an address or a CIDR range. An address is a range of one.
+func ParseNetwork(s string) (*net.IPNet, error) {
+	if _, network, err := net.ParseCIDR(s); err == nil {
+		return network, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid address %q", s)
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// IPInList reports whether ip is in one of the addresses and ranges of list
+func IPInList(ip net.IP, list []string) bool {
+	if ip == nil {
+		return false
+	}
+	for _, entry := range list {
+		if network, err := ParseNetwork(entry); err == nil && network.Contains(ip) {
+			return true
+		}
 	}
-	return map[string]string{
-		"region":          region,
-		"accessKeyID":     accessKeyID,
-		"secretAccessKey": secretAccessKey,
-	}, nil
+	return false
 }
 
-func CreateAWSSession() (*session.Session, error) {
-	awsConfig, err := getAWSConfig()
+// ClientIP is the address a request came from. Behind one of trustedProxies it is the
+// last address in X-Forwarded-For that isn't one of the proxies.
+func ClientIP(r *http.Request, trustedProxies []string) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
-		return nil, err
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if !IPInList(ip, trustedProxies) {
+		return ip
+	}
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !IPInList(hop, trustedProxies) {
+			break
+		}
+	}
+	return ip
+}
+
+// ClientIPString is ClientIP as text for logs and records, empty when the request's
+// address can't be parsed
+func ClientIPString(r *http.Request, trustedProxies []string) string {
+	if ip := ClientIP(r, trustedProxies); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+func CreateAWSSession(cfg config.AWSConfig) (*session.Session, error) {
+	if cfg.S3BucketName == "" {
+		log.Println("⚠️  AWS S3 bucket name not configured")
+		return nil, os.ErrInvalid
+	}
+	if !cfg.HasCredentials() {
+		log.Println("⚠️  AWS credentials or region not configured")
+		return nil, os.ErrInvalid
 	}
 	sess, err := session.NewSession(&aws.Config{
-		Region:      aws.String(awsConfig["region"]),
-		Credentials: credentials.NewStaticCredentials(awsConfig["accessKeyID"], awsConfig["secretAccessKey"], ""),
+		Region:      aws.String(cfg.Region),
+		Credentials: credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
 	})
 	if err != nil {
 		log.Printf("❌ Failed to create AWS session: %v", err)
@@ -93,8 +174,8 @@ func CreateAWSSession() (*session.Session, error) {
 	return sess, nil
 }
 
-func UploadFileToS3(key, bucketName, prefix string, image []byte) error {
-	sess, err := CreateAWSSession()
+func UploadFileToS3(cfg config.AWSConfig, key, bucketName, prefix string, image []byte) error {
+	sess, err := CreateAWSSession(cfg)
 
 	if err != nil {
 		return err
@@ -1,13 +1,19 @@
 package worker
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
 	"statusframe/backend/handlers"
+	"statusframe/backend/metrics"
 	"statusframe/db"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type HealthChecker struct {
@@ -15,25 +21,59 @@ type HealthChecker struct {
 	interval time.Duration
 	client   *http.Client
 	slack    *handlers.Handler
+
+	// Lifecycle state used for graceful shutdown and readiness reporting
+	checkCtx     context.Context
+	cancelChecks context.CancelFunc
+	inFlight     sync.WaitGroup
+	inFlightN    atomic.Int64
+	running      atomic.Bool
+	lastRun      atomic.Int64
+	stopped      chan struct{}
+
+	// Multi-region settings, only used once EnableMultiRegion is called
+	multiRegion bool
+	region      string
+	quorum      int
 }
 
-func NewHealthChecker(conn *sql.DB, interval time.Duration) *HealthChecker {
+// NewHealthChecker creates a checker that sends incident alerts through notifier
+func NewHealthChecker(conn *sql.DB, notifier *handlers.Handler, interval time.Duration) *HealthChecker {
+	checkCtx, cancelChecks := context.WithCancel(context.Background())
 	return &HealthChecker{
 		conn:     conn,
 		interval: interval,
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
-		slack: handlers.NewHandler(conn),
+		slack:        notifier,
+		checkCtx:     checkCtx,
+		cancelChecks: cancelChecks,
+		stopped:      make(chan struct{}),
 	}
 }
 
-// Start begins the health checking loop
-func (hc *HealthChecker) Start() {
+// EnableMultiRegion records local results under region and decides each app's
+// status from the latest result of every region, requiring quorum failing regions
+func (hc *HealthChecker) EnableMultiRegion(region string, quorum int) {
+	hc.multiRegion = true
+	hc.region = region
+	hc.quorum = quorum
+}
+
+// Start begins the health checking loop and returns once ctx is cancelled.
+// Checks that are already running are left to finish; use Wait to drain them.
+func (hc *HealthChecker) Start(ctx context.Context) {
 	log.Println("🚀 Health checker started - monitoring every", hc.interval)
 
+	hc.running.Store(true)
+	defer func() {
+		hc.running.Store(false)
+		close(hc.stopped)
+	}()
+
 	// Start cleanup routine (runs every 24 hours)
-	go hc.startCleanupRoutine()
+	go hc.startCleanupRoutine(ctx)
 
 	ticker := time.NewTicker(hc.interval)
 	defer ticker.Stop()
@@ -41,34 +81,97 @@ func (hc *HealthChecker) Start() {
 	// Run immediately on start
 	hc.checkAllUsers()
 
-	for range ticker.C {
-		hc.checkAllUsers()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("🛑 Health checker stopping - %d check(s) still in flight", hc.inFlightN.Load())
+			return
+		case <-ticker.C:
+			hc.checkAllUsers()
+		}
 	}
 }
 
+// Wait blocks until the checker has stopped and its in-flight checks have finished.
+// If ctx expires first, the remaining checks are cancelled without recording a result.
+func (hc *HealthChecker) Wait(ctx context.Context) error {
+	select {
+	case <-hc.stopped:
+	case <-ctx.Done():
+		hc.cancelChecks()
+		return ctx.Err()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		hc.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Println("✅ Health checker drained")
+		return nil
+	case <-ctx.Done():
+		log.Printf("⚠️ Shutdown deadline reached, cancelling %d in-flight health check(s)", hc.inFlightN.Load())
+		hc.cancelChecks()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Status reports the checker state for the readiness endpoint
+func (hc *HealthChecker) Status() handlers.WorkerStatus {
+	status := handlers.WorkerStatus{
+		Name:     "health_checker",
+		Running:  hc.running.Load(),
+		InFlight: int(hc.inFlightN.Load()),
+	}
+	if lastRun := hc.lastRun.Load(); lastRun != 0 {
+		t := time.Unix(0, lastRun)
+		status.LastRunAt = &t
+	}
+	return status
+}
+
 // startCleanupRoutine runs data retention cleanup every 24 hours
-func (hc *HealthChecker) startCleanupRoutine() {
+func (hc *HealthChecker) startCleanupRoutine(ctx context.Context) {
 	// Run cleanup immediately on start
 	log.Println("🧹 Starting data retention cleanup routine")
 	db.CleanupOldStatusChecks(hc.conn)
+	db.DeleteExpiredSessions(hc.conn)
 
 	// Then run every 24 hours
 	ticker := time.NewTicker(24 * time.Hour)
 	defer ticker.Stop()
 
-	for range ticker.C {
-		log.Println("🧹 Running scheduled data retention cleanup")
-		db.CleanupOldStatusChecks(hc.conn)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			log.Println("🧹 Running scheduled data retention cleanup")
+			db.CleanupOldStatusChecks(hc.conn)
+			db.DeleteExpiredSessions(hc.conn)
+		}
 	}
 }
 
 func (hc *HealthChecker) checkAllUsers() {
-	// Get apps that are due for checking based on their plan's check interval
+	hc.lastRun.Store(time.Now().UnixNano())
+
+	// Get apps that are due for checking based on their check interval
 	query := `
-		SELECT a.id, a.user_id, a.app_name, a.slug, a.health_url, u.plan
+		SELECT a.id, a.user_id, a.app_name, a.slug, a.health_url, o.plan,
+		       a.check_interval, a.alert_channels,
+		       EXISTS (
+		         SELECT 1 FROM maintenance_windows w
+		         WHERE w.app_id = a.id AND w.starts_at <= NOW() AND w.ends_at > NOW()
+		       ) AS in_maintenance
 		FROM apps a
-		JOIN users u ON a.user_id = u.id
+		JOIN organizations o ON a.org_id = o.id
 		WHERE a.health_url != '' 
+		  AND NOT a.paused
 		  AND a.next_check_at <= NOW()
 	`
 	rows, err := hc.conn.Query(query)
@@ -80,17 +183,28 @@ func (hc *HealthChecker) checkAllUsers() {
 
 	appCount := 0
 	for rows.Next() {
-		var appId, userId int
-		var appName, slug, healthUrl, plan string
+		var app dueApp
+		var interval sql.NullInt64
 
-		err := rows.Scan(&appId, &userId, &appName, &slug, &healthUrl, &plan)
+		err := rows.Scan(&app.id, &app.userId, &app.name, &app.slug, &app.healthUrl, &app.plan,
+			&interval, pq.Array(&app.settings.AlertChannels), &app.inMaintenance)
 		if err != nil {
 			log.Printf("❌ Error scanning app: %v", err)
 			continue
 		}
+		if interval.Valid {
+			seconds := int(interval.Int64)
+			app.settings.CheckInterval = &seconds
+		}
 
 		appCount++
-		go hc.checkAppHealth(appId, userId, appName, slug, healthUrl, plan)
+		hc.inFlight.Add(1)
+		hc.inFlightN.Add(1)
+		go func() {
+			defer hc.inFlight.Done()
+			defer hc.inFlightN.Add(-1)
+			hc.checkAppHealth(app)
+		}()
 	}
 
 	if appCount == 0 {
@@ -101,7 +215,16 @@ func (hc *HealthChecker) checkAllUsers() {
 	log.Printf("🔍 Checking health for %d app(s) due now", appCount)
 }
 
-func (hc *HealthChecker) checkAppHealth(appId, userId int, appName, slug, healthUrl, plan string) {
+// dueApp is an app picked up by checkAllUsers
+type dueApp struct {
+	id, userId                  int
+	name, slug, healthUrl, plan string
+	settings                    db.AppCheckSettings
+	inMaintenance               bool
+}
+
+func (hc *HealthChecker) checkAppHealth(app dueApp) {
+	appId, appName, healthUrl, plan := app.id, app.name, app.healthUrl, app.plan
 	startTime := time.Now()
 
 	previousStatus, err := hc.getPreviousStatus(appId)
@@ -110,9 +233,20 @@ func (hc *HealthChecker) checkAppHealth(appId, userId int, appName, slug, health
 		previousStatus = ""
 	}
 
-	resp, err := hc.client.Get(healthUrl)
+	var resp *http.Response
+	req, err := http.NewRequestWithContext(hc.checkCtx, http.MethodGet, healthUrl, nil)
+	if err == nil {
+		resp, err = hc.client.Do(req)
+	}
+
+	// Checks cut off by shutdown say nothing about the app, so don't record them
+	if hc.checkCtx.Err() != nil {
+		log.Printf("🛑 Check for app %s (ID: %d) cancelled by shutdown", appName, appId)
+		return
+	}
 
-	responseTime := time.Since(startTime).Milliseconds()
+	checkDuration := time.Since(startTime)
+	responseTime := checkDuration.Milliseconds()
 	statusCode := 0
 
 	if err != nil {
@@ -124,12 +258,19 @@ func (hc *HealthChecker) checkAppHealth(appId, userId int, appName, slug, health
 		statusCode = resp.StatusCode
 	}
 
+	interval := db.EffectiveCheckInterval(plan, app.settings.CheckInterval)
+
+	if hc.multiRegion {
+		statusCode, responseTime = hc.applyQuorum(appId, appName, statusCode, responseTime, interval)
+	}
+
 	// Derive status from status code
 	status := db.GetStatusFromCode(statusCode)
+	metrics.ObserveCheck(status, checkDuration)
 
 	// Save to database with app_id only (user_id removed from schema)
-	query := "INSERT INTO user_status (app_id, status_code, checked_at) VALUES ($1, $2, NOW())"
-	_, err = hc.conn.Exec(query, appId, statusCode)
+	query := "INSERT INTO user_status (app_id, status_code, response_time_ms, checked_at) VALUES ($1, $2, $3, NOW())"
+	_, err = hc.conn.Exec(query, appId, statusCode, responseTime)
 	if err != nil {
 		log.Printf("❌ Error saving status check for app %s (ID: %d): %v", appName, appId, err)
 	} else {
@@ -144,18 +285,27 @@ func (hc *HealthChecker) checkAppHealth(appId, userId int, appName, slug, health
 		log.Printf("%s %s | App: %s (ID: %d, Plan: %s) | Status: %d (%s) | Response: %dms",
 			emoji, healthUrl, appName, appId, plan, statusCode, status, responseTime)
 
-		if err := hc.maybeSendSlackAlert(plan, appId, appName, status, statusCode, previousStatus); err != nil {
-			log.Printf("⚠️ Slack notification error for app %s (ID: %d): %v", appName, appId, err)
-		}
-
-		if err := hc.maybeSendDiscordAlert(plan, appId, appName, status, statusCode, previousStatus); err != nil {
-			log.Printf("⚠️ Discord notification error for app %s (ID: %d): %v", appName, appId, err)
+		if app.inMaintenance {
+			if previousStatus != status {
+				log.Printf("🔧 App %s (ID: %d) is in maintenance, not alerting on %s", appName, appId, status)
+			}
+		} else {
+			if db.AlertChannelEnabled(app.settings.AlertChannels, db.AlertChannelSlack) {
+				if err := hc.maybeSendSlackAlert(plan, appId, appName, status, statusCode, previousStatus); err != nil {
+					log.Printf("⚠️ Slack notification error for app %s (ID: %d): %v", appName, appId, err)
+				}
+			}
+
+			if db.AlertChannelEnabled(app.settings.AlertChannels, db.AlertChannelDiscord) {
+				if err := hc.maybeSendDiscordAlert(plan, appId, appName, status, statusCode, previousStatus); err != nil {
+					log.Printf("⚠️ Discord notification error for app %s (ID: %d): %v", appName, appId, err)
+				}
+			}
 		}
 	}
 
-	// Update next_check_at based on plan interval
-	planInterval := db.GetPlanCheckInterval(plan)
-	nextCheck := time.Now().Add(time.Duration(planInterval) * time.Second)
+	// Update next_check_at based on the app's interval
+	nextCheck := time.Now().Add(time.Duration(interval) * time.Second)
 
 	updateQuery := "UPDATE apps SET next_check_at = $1 WHERE id = $2"
 	_, err = hc.conn.Exec(updateQuery, nextCheck, appId)
@@ -164,6 +314,40 @@ func (hc *HealthChecker) checkAppHealth(appId, userId int, appName, slug, health
 	}
 }
 
+// applyQuorum stores the local result as this region's result and returns the
+// status code agreed on by the regions that reported recently. When they overrule the
+// local result, the response time is taken from the regions that agree. With fewer
+// regions reporting than the quorum, the local result stands.
+func (hc *HealthChecker) applyQuorum(appId int, appName string, localStatusCode int, responseTime int64, interval int) (int, int64) {
+	err := db.InsertRegionCheck(hc.conn, appId, hc.region, localStatusCode, responseTime, time.Now())
+	if err != nil {
+		log.Printf("❌ Error saving region check for app %s (ID: %d): %v", appName, appId, err)
+	}
+
+	// Results older than two check intervals are considered stale
+	window := 2*time.Duration(interval)*time.Second + 30*time.Second
+	results, err := db.GetLatestRegionChecks(hc.conn, appId, time.Now().Add(-window))
+	if err != nil {
+		log.Printf("⚠️ Error fetching region results for app %s (ID: %d): %v", appName, appId, err)
+		return localStatusCode, responseTime
+	}
+
+	statusCode, ok := EvaluateQuorum(results, hc.quorum)
+	if !ok {
+		return localStatusCode, responseTime
+	}
+
+	if db.GetStatusFromCode(statusCode) != db.GetStatusFromCode(localStatusCode) {
+		log.Printf("🌍 App %s (ID: %d): region %s saw %d but %d region(s) decided %d (quorum %d)",
+			appName, appId, hc.region, localStatusCode, len(results), statusCode, hc.quorum)
+		if agreed, ok := AgreeingResponseTime(results, statusCode); ok {
+			responseTime = agreed
+		}
+	}
+
+	return statusCode, responseTime
+}
+
 func (hc *HealthChecker) getPreviousStatus(appId int) (string, error) {
 	var statusCode sql.NullInt64
 	err := hc.conn.QueryRow(
@@ -0,0 +1,79 @@
+package worker
+
+import (
+	"sort"
+	"statusframe/db"
+)
+
+// EvaluateQuorum decides an app's status code from the latest result of each region.
+// The app is only reported as failing when at least quorum regions see a non-up
+// status, so a network problem in a single region does not cause a false outage.
+// It returns false when fewer than quorum regions have reported, since they can't
+// outvote each other; the caller then keeps its own result.
+func EvaluateQuorum(results []db.RegionCheck, quorum int) (int, bool) {
+	if quorum < 1 {
+		quorum = 1
+	}
+	if len(results) < quorum {
+		return 0, false
+	}
+
+	// Sort by region so ties are broken the same way on every run
+	sorted := make([]db.RegionCheck, len(results))
+	copy(sorted, results)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Region < sorted[j].Region
+	})
+
+	failingCodes := make(map[int]int)
+	failing := 0
+	upCode := 0
+	for _, result := range sorted {
+		if db.GetStatusFromCode(result.StatusCode) == "up" {
+			if upCode == 0 {
+				upCode = result.StatusCode
+			}
+			continue
+		}
+		failing++
+		failingCodes[result.StatusCode]++
+	}
+
+	// Not enough regions agree the app is failing
+	if failing < quorum {
+		return upCode, true
+	}
+
+	// Report the failing status code most regions agree on
+	bestCode, bestCount := 0, 0
+	for _, result := range sorted {
+		count := failingCodes[result.StatusCode]
+		if count > bestCount {
+			bestCode, bestCount = result.StatusCode, count
+		}
+	}
+
+	return bestCode, true
+}
+
+// AgreeingResponseTime returns the median response time of the regions whose status
+// agrees with the decided status code. It returns false when none of them agree.
+func AgreeingResponseTime(results []db.RegionCheck, statusCode int) (int64, bool) {
+	status := db.GetStatusFromCode(statusCode)
+	var times []int64
+	for _, result := range results {
+		if db.GetStatusFromCode(result.StatusCode) == status {
+			times = append(times, result.ResponseTimeMs)
+		}
+	}
+	if len(times) == 0 {
+		return 0, false
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	middle := len(times) / 2
+	if len(times)%2 == 0 {
+		return (times[middle-1] + times[middle]) / 2, true
+	}
+	return times[middle], true
+}
@@ -1,23 +1,38 @@
 package worker
 
 import (
+	"context"
 	"crypto/tls"
 	"database/sql"
 	"log"
+	"net"
 	"net/url"
+	"statusframe/backend/handlers"
 	"statusframe/db"
+	"sync/atomic"
 	"time"
 )
 
 type SSLChecker struct {
 	conn         *sql.DB
 	checkTrigger chan int // Channel to trigger checks for specific app IDs
+
+	// Lifecycle state used for graceful shutdown and readiness reporting
+	checkCtx     context.Context
+	cancelChecks context.CancelFunc
+	running      atomic.Bool
+	lastRun      atomic.Int64
+	stopped      chan struct{}
 }
 
 func NewSSLChecker(conn *sql.DB) *SSLChecker {
+	checkCtx, cancelChecks := context.WithCancel(context.Background())
 	return &SSLChecker{
 		conn:         conn,
 		checkTrigger: make(chan int, 100), // Buffered channel for on-demand checks
+		checkCtx:     checkCtx,
+		cancelChecks: cancelChecks,
+		stopped:      make(chan struct{}),
 	}
 }
 
@@ -31,12 +46,18 @@ func (sc *SSLChecker) CheckAppSSL(appID int) {
 	}
 }
 
-// Start begins the daily SSL certificate check routine
-func (sc *SSLChecker) Start() {
+// Start begins the daily SSL certificate check routine and returns once ctx is cancelled
+func (sc *SSLChecker) Start(ctx context.Context) {
 	log.Println("🔒 SSL certificate checker started - checking daily")
 
+	sc.running.Store(true)
+	defer func() {
+		sc.running.Store(false)
+		close(sc.stopped)
+	}()
+
 	// Run immediately on start
-	sc.checkAllSSLCertificates()
+	sc.checkAllSSLCertificates(ctx)
 
 	// Run every 24 hours
 	ticker := time.NewTicker(24 * time.Hour)
@@ -44,9 +65,16 @@ func (sc *SSLChecker) Start() {
 
 	for {
 		select {
+		case <-ctx.Done():
+			if pending := len(sc.checkTrigger); pending > 0 {
+				log.Printf("🛑 SSL checker stopping - dropping %d queued check(s)", pending)
+			} else {
+				log.Println("🛑 SSL checker stopping")
+			}
+			return
 		case <-ticker.C:
 			// Regular scheduled check
-			sc.checkAllSSLCertificates()
+			sc.checkAllSSLCertificates(ctx)
 		case appID := <-sc.checkTrigger:
 			// On-demand check for a specific app
 			sc.checkSpecificAppSSL(appID)
@@ -54,8 +82,37 @@ func (sc *SSLChecker) Start() {
 	}
 }
 
-func (sc *SSLChecker) checkAllSSLCertificates() {
+// Wait blocks until the checker has stopped. If ctx expires first, the running check is cancelled.
+func (sc *SSLChecker) Wait(ctx context.Context) error {
+	select {
+	case <-sc.stopped:
+		log.Println("✅ SSL checker drained")
+		return nil
+	case <-ctx.Done():
+		log.Println("⚠️ Shutdown deadline reached, cancelling running SSL check")
+		sc.cancelChecks()
+		<-sc.stopped
+		return ctx.Err()
+	}
+}
+
+// Status reports the checker state for the readiness endpoint
+func (sc *SSLChecker) Status() handlers.WorkerStatus {
+	status := handlers.WorkerStatus{
+		Name:       "ssl_checker",
+		Running:    sc.running.Load(),
+		QueueDepth: len(sc.checkTrigger),
+	}
+	if lastRun := sc.lastRun.Load(); lastRun != 0 {
+		t := time.Unix(0, lastRun)
+		status.LastRunAt = &t
+	}
+	return status
+}
+
+func (sc *SSLChecker) checkAllSSLCertificates(ctx context.Context) {
 	log.Println("🔍 Starting SSL certificate check for all HTTPS apps...")
+	sc.lastRun.Store(time.Now().UnixNano())
 
 	apps, err := sc.getHTTPSApps()
 	if err != nil {
@@ -67,7 +124,15 @@ func (sc *SSLChecker) checkAllSSLCertificates() {
 	errorCount := 0
 
 	for _, app := range apps {
+		if ctx.Err() != nil {
+			log.Printf("🛑 SSL check interrupted by shutdown after %d app(s)", checkedCount+errorCount)
+			return
+		}
+
 		expiryDate, issuer, err := sc.checkSSLCertificate(app.HealthURL)
+		if sc.checkCtx.Err() != nil {
+			return
+		}
 		if err != nil {
 			log.Printf("⚠️  SSL check failed for %s (%s): %v", app.AppName, app.HealthURL, err)
 			errorCount++
@@ -118,6 +183,9 @@ func (sc *SSLChecker) checkSpecificAppSSL(appID int) {
 	}
 
 	expiryDate, issuer, err := sc.checkSSLCertificate(app.HealthURL)
+	if sc.checkCtx.Err() != nil {
+		return
+	}
 	if err != nil {
 		log.Printf("⚠️  SSL check failed for %s (%s): %v", app.AppName, app.HealthURL, err)
 		// Clear SSL data on error
@@ -156,7 +224,7 @@ func (sc *SSLChecker) getHTTPSApps() ([]httpsApp, error) {
 	query := `
 		SELECT id, app_name, health_url
 		FROM apps
-		WHERE health_url LIKE 'https://%'
+		WHERE health_url LIKE 'https://%' AND NOT paused
 	`
 
 	rows, err := sc.conn.Query(query)
@@ -211,13 +279,18 @@ func (sc *SSLChecker) checkSSLCertificate(healthURL string) (time.Time, string,
 	}
 
 	// Connect with TLS
-	conn, err := tls.Dial("tcp", host, &tls.Config{
-		InsecureSkipVerify: false, // Verify certificates properly
-	})
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: 10 * time.Second},
+		Config: &tls.Config{
+			InsecureSkipVerify: false, // Verify certificates properly
+		},
+	}
+	rawConn, err := dialer.DialContext(sc.checkCtx, "tcp", host)
 	if err != nil {
 		return time.Time{}, "", err
 	}
-	defer conn.Close()
+	defer rawConn.Close()
+	conn := rawConn.(*tls.Conn)
 
 	// Get peer certificates
 	certs := conn.ConnectionState().PeerCertificates
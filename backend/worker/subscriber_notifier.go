@@ -0,0 +1,173 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"statusframe/backend/handlers"
+	"statusframe/db"
+	"sync/atomic"
+	"time"
+)
+
+// subscriberCooldown is the least time between two status change notices for an
+// app, so a flapping app doesn't flood its subscribers. A change that outlasts the
+// cooldown is still announced once it's over.
+const subscriberCooldown = 15 * time.Minute
+
+// maxIncidentUpdatesPerRun caps how many incident updates one run sends
+const maxIncidentUpdatesPerRun = 100
+
+// SubscriberNotifier tells status page subscribers when an app's status changes and
+// when an incident update is posted
+type SubscriberNotifier struct {
+	conn     *sql.DB
+	interval time.Duration
+	notifier *handlers.Handler
+
+	// Lifecycle state used for graceful shutdown and readiness reporting
+	running atomic.Bool
+	lastRun atomic.Int64
+	stopped chan struct{}
+}
+
+// NewSubscriberNotifier creates a notifier that sends notices through notifier every interval
+func NewSubscriberNotifier(conn *sql.DB, notifier *handlers.Handler, interval time.Duration) *SubscriberNotifier {
+	return &SubscriberNotifier{
+		conn:     conn,
+		interval: interval,
+		notifier: notifier,
+		stopped:  make(chan struct{}),
+	}
+}
+
+// Start sends notices right away and then every interval, and returns once ctx is cancelled
+func (sn *SubscriberNotifier) Start(ctx context.Context) {
+	log.Println("🔔 Subscriber notifier started - checking every", sn.interval)
+
+	sn.running.Store(true)
+	defer func() {
+		sn.running.Store(false)
+		close(sn.stopped)
+	}()
+
+	sn.notifyAll(ctx)
+
+	ticker := time.NewTicker(sn.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("🛑 Subscriber notifier stopping")
+			return
+		case <-ticker.C:
+			sn.notifyAll(ctx)
+		}
+	}
+}
+
+// Wait blocks until the notifier has stopped or ctx expires
+func (sn *SubscriberNotifier) Wait(ctx context.Context) error {
+	select {
+	case <-sn.stopped:
+		log.Println("✅ Subscriber notifier drained")
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Status reports the notifier state for the readiness endpoint
+func (sn *SubscriberNotifier) Status() handlers.WorkerStatus {
+	status := handlers.WorkerStatus{
+		Name:    "subscriber_notifier",
+		Running: sn.running.Load(),
+	}
+	if lastRun := sn.lastRun.Load(); lastRun != 0 {
+		t := time.Unix(0, lastRun)
+		status.LastRunAt = &t
+	}
+	return status
+}
+
+// notifyAll runs until it's done or ctx is cancelled, which also cancels the webhooks
+// being posted so shutdown doesn't wait for them
+func (sn *SubscriberNotifier) notifyAll(ctx context.Context) {
+	sn.lastRun.Store(time.Now().UnixNano())
+
+	if removed, err := db.DeleteStaleSubscribers(sn.conn); err != nil {
+		log.Printf("❌ Error removing unconfirmed subscribers: %v", err)
+	} else if removed > 0 {
+		log.Printf("🧹 Removed %d unconfirmed subscriber(s)", removed)
+	}
+
+	sn.sendIncidentUpdates(ctx)
+	if ctx.Err() != nil {
+		return
+	}
+	sn.sendStatusChanges(ctx)
+}
+
+// sendIncidentUpdates sends every incident update that hasn't been sent yet. An update
+// whose sending was cut short is sent again on the next run.
+func (sn *SubscriberNotifier) sendIncidentUpdates(ctx context.Context) {
+	updates, err := db.GetUnsentIncidentUpdates(sn.conn, maxIncidentUpdatesPerRun)
+	if err != nil {
+		log.Printf("❌ Error fetching unsent incident updates: %v", err)
+		return
+	}
+
+	for _, u := range updates {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := sn.notifier.NotifySubscribers(ctx, u.AppId, sn.notifier.IncidentUpdateNotice(u)); err != nil {
+			log.Printf("❌ Error notifying subscribers of incident update %d: %v", u.Id, err)
+			continue
+		}
+		if err := db.MarkIncidentUpdateNotified(sn.conn, u.Id); err != nil {
+			log.Printf("❌ Error marking incident update %d as sent: %v", u.Id, err)
+		}
+	}
+}
+
+// sendStatusChanges announces apps whose status differs from the one their
+// subscribers were last told about, at most once per subscriberCooldown
+func (sn *SubscriberNotifier) sendStatusChanges(ctx context.Context) {
+	apps, err := db.GetSubscribedApps(sn.conn)
+	if err != nil {
+		log.Printf("❌ Error fetching subscribed apps: %v", err)
+		return
+	}
+
+	for _, app := range apps {
+		if ctx.Err() != nil {
+			return
+		}
+		status := db.GetStatusGroupFromCode(app.StatusCode)
+
+		// The first status seen is where subscribers start from, not news
+		if app.Announced == nil {
+			if err := db.SetAnnouncedStatus(sn.conn, app.AppId, status); err != nil {
+				log.Printf("❌ Error recording status of app %d: %v", app.AppId, err)
+			}
+			continue
+		}
+		if *app.Announced == status {
+			continue
+		}
+		if app.AnnouncedAt != nil && time.Since(*app.AnnouncedAt) < subscriberCooldown {
+			continue
+		}
+
+		// Record the announcement first so a failing send isn't repeated every run
+		if err := db.SetAnnouncedStatus(sn.conn, app.AppId, status); err != nil {
+			log.Printf("❌ Error recording status of app %d: %v", app.AppId, err)
+			continue
+		}
+		if err := sn.notifier.NotifySubscribers(ctx, app.AppId, sn.notifier.StatusChangeNotice(app, *app.Announced, status)); err != nil {
+			log.Printf("❌ Error notifying subscribers of app %d: %v", app.AppId, err)
+		}
+	}
+}
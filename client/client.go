@@ -0,0 +1,272 @@
+// Package client is a Go client for the UpLitycs API, as described by the OpenAPI
+// document served at /api/openapi.json. It only depends on the standard library so
+// tools can import it without pulling in the server.
+//
+//	c := client.New("https://uplitycs.example.com", os.Getenv("UPLITYCS_API_KEY"))
+//	apps, err := c.ListApps(ctx)
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls the API with a personal API key. The zero value is not usable; use New.
+type Client struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+
+	// OrgID picks the organization requests act on. Zero means the key owner's
+	// personal organization.
+	OrgID int
+
+	// UserAgent is sent with every request when set
+	UserAgent string
+}
+
+// New returns a client for the server at baseURL, like "https://uplitycs.example.com"
+func New(baseURL, apiKey string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		APIKey:     apiKey,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// WithOrg returns a copy of the client that acts on another organization
+func (c *Client) WithOrg(orgID int) *Client {
+	copied := *c
+	copied.OrgID = orgID
+	return &copied
+}
+
+// Error is returned for every response outside 2xx
+type Error struct {
+	StatusCode int
+	Code       string // machine-readable code from the /api/v1 endpoints, like "not_found"
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("uplitycs: %d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("uplitycs: %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 from the API
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// ListApps returns the organization's apps with their current status
+func (c *Client) ListApps(ctx context.Context) ([]AppWithStatus, error) {
+	var resp struct {
+		Apps []AppWithStatus `json:"apps"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/apps", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Apps, nil
+}
+
+// GetApp returns one app
+func (c *Client) GetApp(ctx context.Context, appID int) (*App, error) {
+	return c.appRequest(ctx, http.MethodGet, appPath(appID, ""), nil)
+}
+
+// CreateApp creates an app. It fails with a 403 plan_limit_reached error when the
+// organization's plan has no room for another one.
+func (c *Client) CreateApp(ctx context.Context, req CreateAppRequest) (*App, error) {
+	return c.appRequest(ctx, http.MethodPost, "/api/v1/apps", req)
+}
+
+// UpdateApp changes the fields set in req and leaves the others alone
+func (c *Client) UpdateApp(ctx context.Context, appID int, req UpdateAppRequest) (*App, error) {
+	return c.appRequest(ctx, http.MethodPatch, appPath(appID, ""), req)
+}
+
+// DeleteApp deletes an app and its history
+func (c *Client) DeleteApp(ctx context.Context, appID int) error {
+	return c.do(ctx, http.MethodDelete, appPath(appID, ""), nil, nil)
+}
+
+// PauseApp stops health checks for an app without deleting it
+func (c *Client) PauseApp(ctx context.Context, appID int) (*App, error) {
+	return c.appRequest(ctx, http.MethodPost, appPath(appID, "/pause"), nil)
+}
+
+// ResumeApp restarts health checks for a paused app
+func (c *Client) ResumeApp(ctx context.Context, appID int) (*App, error) {
+	return c.appRequest(ctx, http.MethodPost, appPath(appID, "/resume"), nil)
+}
+
+// ListPauseEvents returns who paused and resumed an app and when, newest first
+func (c *Client) ListPauseEvents(ctx context.Context, appID int) ([]PauseEvent, error) {
+	var resp struct {
+		Events []PauseEvent `json:"events"`
+	}
+	if err := c.do(ctx, http.MethodGet, appPath(appID, "/pause-events"), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Events, nil
+}
+
+// ListChecks returns an app's latest check results, oldest first. With afterID set it
+// only returns checks recorded after that one, so polling with the last ID seen
+// follows new results as they come in. A limit of 0 uses the server's default.
+func (c *Client) ListChecks(ctx context.Context, appID, afterID, limit int) ([]CheckResult, error) {
+	query := url.Values{}
+	if afterID > 0 {
+		query.Set("after", strconv.Itoa(afterID))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	path := appPath(appID, "/checks")
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	var resp struct {
+		Checks []CheckResult `json:"checks"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Checks, nil
+}
+
+// ListIncidents returns an app's incidents within the plan's data retention, newest first
+func (c *Client) ListIncidents(ctx context.Context, appID int) ([]Incident, error) {
+	var resp struct {
+		Incidents []Incident `json:"incidents"`
+	}
+	if err := c.do(ctx, http.MethodGet, appPath(appID, "/incidents"), nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Incidents, nil
+}
+
+// CheckSSL starts a check of an app's SSL certificate. The result shows up in the SSL
+// fields of ListApps once it's done.
+func (c *Client) CheckSSL(ctx context.Context, appID int) error {
+	return c.do(ctx, http.MethodPost, appPath(appID, "/ssl-check"), nil, nil)
+}
+
+// ListOrgs returns the organizations the key's owner belongs to
+func (c *Client) ListOrgs(ctx context.Context) ([]Organization, error) {
+	var resp struct {
+		Organizations []Organization `json:"organizations"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/orgs", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Organizations, nil
+}
+
+// PublicStatus returns what the public status page of an app shows. It needs no API key.
+func (c *Client) PublicStatus(ctx context.Context, slug string) (*PublicStatus, error) {
+	var status PublicStatus
+	if err := c.do(ctx, http.MethodGet, "/api/public/status/"+url.PathEscape(slug), nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func appPath(appID int, suffix string) string {
+	return "/api/v1/apps/" + strconv.Itoa(appID) + suffix
+}
+
+func (c *Client) appRequest(ctx context.Context, method, path string, body interface{}) (*App, error) {
+	var resp struct {
+		App *App `json:"app"`
+	}
+	if err := c.do(ctx, method, path, body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.App, nil
+}
+
+// do sends a request with an optional JSON body and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	if c.OrgID != 0 {
+		req.Header.Set("X-Org-Id", strconv.Itoa(c.OrgID))
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return readError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// readError turns an error response into an *Error. The /api/v1 endpoints answer with
+// {"error": {"code", "message"}}, the older ones with plain text.
+func readError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	apiErr := &Error{StatusCode: resp.StatusCode}
+
+	var body struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(raw, &body) == nil && body.Error.Message != "" {
+		apiErr.Code = body.Error.Code
+		apiErr.Message = body.Error.Message
+	} else {
+		apiErr.Message = strings.TrimSpace(string(raw))
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
@@ -0,0 +1,131 @@
+package client
+
+import "time"
+
+// These mirror the schemas of /api/openapi.json. Times the server sends as plain
+// strings are kept as strings.
+
+// App is a monitored app
+type App struct {
+	ID        int     `json:"id"`
+	OrgID     int     `json:"org_id"`
+	UserID    int     `json:"user_id"`
+	AppName   string  `json:"app_name"`
+	Slug      string  `json:"slug"`
+	HealthURL string  `json:"health_url"`
+	Theme     string  `json:"theme"`
+	Alerts    string  `json:"alerts"`
+	LogoURL   *string `json:"logo_url,omitempty"`
+	Paused    bool    `json:"paused"`
+	CreatedAt string  `json:"created_at"`
+	UpdatedAt string  `json:"updated_at"`
+}
+
+// AppWithStatus is an app with the result of its latest check
+type AppWithStatus struct {
+	App
+	Status             string  `json:"status"`
+	StatusCode         int     `json:"status_code"`
+	Uptime24h          float64 `json:"uptime_24h"`
+	LastChecked        string  `json:"last_checked"`
+	SSLExpiryDate      *string `json:"ssl_expiry_date,omitempty"`
+	SSLDaysUntilExpiry *int    `json:"ssl_days_until_expiry,omitempty"`
+	SSLIssuer          *string `json:"ssl_issuer,omitempty"`
+	SSLLastChecked     *string `json:"ssl_last_checked,omitempty"`
+}
+
+// CreateAppRequest is the body of CreateApp. Theme defaults to "cyberpunk" and
+// Alerts to "n".
+type CreateAppRequest struct {
+	AppName   string  `json:"app_name"`
+	Slug      string  `json:"slug"`
+	HealthURL string  `json:"health_url"`
+	Theme     string  `json:"theme,omitempty"`
+	Alerts    string  `json:"alerts,omitempty"`
+	LogoURL   *string `json:"logo_url,omitempty"`
+}
+
+// UpdateAppRequest is the body of UpdateApp. Nil fields are left unchanged and an
+// empty LogoURL removes the logo.
+type UpdateAppRequest struct {
+	AppName   *string `json:"app_name,omitempty"`
+	Slug      *string `json:"slug,omitempty"`
+	HealthURL *string `json:"health_url,omitempty"`
+	Theme     *string `json:"theme,omitempty"`
+	Alerts    *string `json:"alerts,omitempty"`
+	LogoURL   *string `json:"logo_url,omitempty"`
+}
+
+// PauseEvent records an app being paused or resumed
+type PauseEvent struct {
+	ID            int     `json:"id"`
+	AppID         int     `json:"app_id"`
+	Paused        bool    `json:"paused"`
+	ActorUserID   *int    `json:"actor_user_id,omitempty"`
+	ActorUsername *string `json:"actor_username,omitempty"`
+	Via           string  `json:"via"`
+	CreatedAt     string  `json:"created_at"`
+}
+
+// CheckResult is one health check of an app. Status is derived from StatusCode: "up",
+// "degraded", "client_error", "down" or "error" when there was no response.
+type CheckResult struct {
+	ID             int       `json:"id"`
+	StatusCode     int       `json:"status_code"`
+	Status         string    `json:"status"`
+	ResponseTimeMs *int      `json:"response_time_ms,omitempty"`
+	CheckedAt      time.Time `json:"checked_at"`
+}
+
+// Incident is a run of failed checks, from the first failure to the next successful
+// check. ResolvedAt is nil while the app is still failing.
+type Incident struct {
+	Status         string     `json:"status"` // "down" or "degraded"
+	StartedAt      time.Time  `json:"started_at"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+	FailedChecks   int        `json:"failed_checks"`
+	LastStatusCode int        `json:"last_status_code"`
+}
+
+// Organization is an organization the key's owner belongs to, with their role in it
+type Organization struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Personal  bool      `json:"personal"`
+	Plan      string    `json:"plan"`
+	CreatedAt time.Time `json:"created_at"`
+	Role      string    `json:"role,omitempty"`
+}
+
+// PublicStatus is what an app's public status page shows
+type PublicStatus struct {
+	AppName           string        `json:"app_name"`
+	Slug              string        `json:"slug"`
+	Theme             string        `json:"theme"`
+	LogoURL           *string       `json:"logo_url"`
+	Status            string        `json:"status"`
+	StatusCode        int           `json:"status_code,omitempty"`
+	CheckedAt         string        `json:"checked_at,omitempty"`
+	Uptime24h         *float64      `json:"uptime_24h,omitempty"`
+	UptimeHistory     []DailyUptime `json:"uptime_history,omitempty"`
+	DataRetentionDays int           `json:"data_retention_days,omitempty"`
+	Paused            bool          `json:"paused"`
+	Message           string        `json:"message,omitempty"`
+	Fields            PublicFields  `json:"fields"`
+}
+
+// PublicFields is which details a status page shows. Hidden ones are left out of PublicStatus.
+type PublicFields struct {
+	StatusCode    bool `json:"status_code"`
+	ResponseTime  bool `json:"response_time"`
+	Uptime        bool `json:"uptime"`
+	UptimeHistory bool `json:"uptime_history"`
+}
+
+// DailyUptime is one day of a status page's uptime history
+type DailyUptime struct {
+	Date             string  `json:"date"`
+	UptimePercentage float64 `json:"uptime_percentage"`
+	TotalChecks      int     `json:"total_checks"`
+	SuccessfulChecks int     `json:"successful_checks"`
+}
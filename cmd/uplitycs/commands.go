@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"statusframe/client"
+)
+
+// appArg parses the one <app> argument of a command
+func (c *cli) appArg(fs *flag.FlagSet, args []string) (*client.AppWithStatus, error) {
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return nil, errors.New("expected one app ID or slug")
+	}
+	return c.findApp(fs.Arg(0))
+}
+
+// findApp looks up an app by ID or slug among the organization's apps
+func (c *cli) findApp(ref string) (*client.AppWithStatus, error) {
+	apps, err := c.client.ListApps(c.ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.Atoi(ref)
+	for i, app := range apps {
+		if (err == nil && app.ID == id) || app.Slug == ref {
+			return &apps[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no app %q in this organization", ref)
+}
+
+func (c *cli) apps(args []string) error {
+	fs := c.flags("apps", "")
+	fs.Parse(args)
+
+	apps, err := c.client.ListApps(c.ctx)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(apps)
+	}
+
+	tw := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSLUG\tNAME\tSTATUS\tCODE\tUPTIME 24H\tLAST CHECK")
+	for _, app := range apps {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%.2f%%\t%s\n",
+			app.ID, app.Slug, app.AppName, appStatus(app), statusCode(app.StatusCode), app.Uptime24h, orDash(app.LastChecked))
+	}
+	return tw.Flush()
+}
+
+func (c *cli) app(args []string) error {
+	app, err := c.appArg(c.flags("app", "<app>"), args)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(app)
+	}
+
+	tw := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%d\n", app.ID)
+	fmt.Fprintf(tw, "Name:\t%s\n", app.AppName)
+	fmt.Fprintf(tw, "Slug:\t%s\n", app.Slug)
+	fmt.Fprintf(tw, "Health URL:\t%s\n", app.HealthURL)
+	fmt.Fprintf(tw, "Theme:\t%s\n", app.Theme)
+	fmt.Fprintf(tw, "Status:\t%s (%s)\n", appStatus(*app), statusCode(app.StatusCode))
+	fmt.Fprintf(tw, "Uptime 24h:\t%.2f%%\n", app.Uptime24h)
+	fmt.Fprintf(tw, "Last check:\t%s\n", orDash(app.LastChecked))
+	if app.SSLExpiryDate != nil {
+		fmt.Fprintf(tw, "SSL expires:\t%s (%d days)\n", *app.SSLExpiryDate, derefInt(app.SSLDaysUntilExpiry))
+		fmt.Fprintf(tw, "SSL issuer:\t%s\n", derefString(app.SSLIssuer))
+		fmt.Fprintf(tw, "SSL checked:\t%s\n", derefString(app.SSLLastChecked))
+	}
+	return tw.Flush()
+}
+
+func (c *cli) create(args []string) error {
+	fs := c.flags("create", "")
+	var req client.CreateAppRequest
+	var logo string
+	fs.StringVar(&req.AppName, "name", "", "app name (required)")
+	fs.StringVar(&req.Slug, "slug", "", "slug of the public status page (required)")
+	fs.StringVar(&req.HealthURL, "url", "", "health URL to check (required)")
+	fs.StringVar(&req.Theme, "theme", "", "status page theme (default cyberpunk)")
+	fs.StringVar(&logo, "logo", "", "logo URL (Pro and Business plans)")
+	fs.Parse(args)
+
+	if req.AppName == "" || req.Slug == "" || req.HealthURL == "" || fs.NArg() != 0 {
+		fs.Usage()
+		return errors.New("-name, -slug and -url are required")
+	}
+	if logo != "" {
+		req.LogoURL = &logo
+	}
+
+	app, err := c.client.CreateApp(c.ctx, req)
+	if err != nil {
+		return err
+	}
+	return c.printApp(app, "Created")
+}
+
+func (c *cli) edit(args []string) error {
+	fs := c.flags("edit", "<app>")
+	name := fs.String("name", "", "new name")
+	slug := fs.String("slug", "", "new slug")
+	url := fs.String("url", "", "new health URL")
+	theme := fs.String("theme", "", "new theme")
+	logo := fs.String("logo", "", "new logo URL, or \"\" to remove it")
+	app, err := c.appArg(fs, args)
+	if err != nil {
+		return err
+	}
+
+	// Only the flags that were given change anything, so -logo "" can remove the logo
+	var req client.UpdateAppRequest
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "name":
+			req.AppName = name
+		case "slug":
+			req.Slug = slug
+		case "url":
+			req.HealthURL = url
+		case "theme":
+			req.Theme = theme
+		case "logo":
+			req.LogoURL = logo
+		}
+	})
+	if req == (client.UpdateAppRequest{}) {
+		fs.Usage()
+		return errors.New("nothing to change")
+	}
+
+	updated, err := c.client.UpdateApp(c.ctx, app.ID, req)
+	if err != nil {
+		return err
+	}
+	return c.printApp(updated, "Updated")
+}
+
+func (c *cli) pause(args []string) error {
+	app, err := c.appArg(c.flags("pause", "<app>"), args)
+	if err != nil {
+		return err
+	}
+	paused, err := c.client.PauseApp(c.ctx, app.ID)
+	if err != nil {
+		return err
+	}
+	return c.printApp(paused, "Paused")
+}
+
+func (c *cli) resume(args []string) error {
+	app, err := c.appArg(c.flags("resume", "<app>"), args)
+	if err != nil {
+		return err
+	}
+	resumed, err := c.client.ResumeApp(c.ctx, app.ID)
+	if err != nil {
+		return err
+	}
+	return c.printApp(resumed, "Resumed")
+}
+
+func (c *cli) delete(args []string) error {
+	fs := c.flags("delete", "<app>")
+	yes := fs.Bool("yes", false, "don't ask for confirmation")
+	app, err := c.appArg(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if !*yes {
+		fmt.Fprintf(c.out, "Delete %s (%s) and all of its history? [y/N] ", app.AppName, app.Slug)
+		answer, _ := bufio.NewReader(c.in).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return errors.New("not deleted")
+		}
+	}
+
+	if err := c.client.DeleteApp(c.ctx, app.ID); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(map[string]interface{}{"deleted": true, "id": app.ID, "slug": app.Slug})
+	}
+	fmt.Fprintf(c.out, "Deleted %s (%s)\n", app.AppName, app.Slug)
+	return nil
+}
+
+// tail prints the latest checks, then polls for new ones until interrupted. With
+// -json every check is one JSON object per line.
+func (c *cli) tail(args []string) error {
+	fs := c.flags("tail", "<app>")
+	every := fs.Duration("every", 10*time.Second, "how often to poll for new checks")
+	last := fs.Int("n", 10, "how many past checks to show first")
+	app, err := c.appArg(fs, args)
+	if err != nil {
+		return err
+	}
+	if *every < time.Second {
+		return errors.New("-every must be at least 1s")
+	}
+
+	afterID := 0
+	limit := *last
+	if limit < 1 {
+		// Only new checks: find where the history ends without printing it
+		checks, err := c.client.ListChecks(c.ctx, app.ID, 0, 1)
+		if err != nil {
+			return err
+		}
+		if len(checks) > 0 {
+			afterID = checks[0].ID
+		}
+	}
+
+	ticker := time.NewTicker(*every)
+	defer ticker.Stop()
+	for {
+		if afterID > 0 || limit > 0 {
+			checks, err := c.client.ListChecks(c.ctx, app.ID, afterID, limit)
+			var apiErr *client.Error
+			switch {
+			case errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError:
+				return err
+			case err != nil && c.ctx.Err() != nil:
+				return c.ctx.Err()
+			case err != nil:
+				// The next poll picks up from the same place
+				fmt.Fprintln(c.stderr, "uplitycs:", err)
+			}
+			for _, check := range checks {
+				if err := c.printCheck(check); err != nil {
+					return err
+				}
+				afterID = check.ID
+			}
+		}
+		limit = 0
+		if afterID == 0 {
+			// No checks at all yet, so poll for the first ones
+			limit = 100
+		}
+
+		select {
+		case <-c.ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *cli) printCheck(check client.CheckResult) error {
+	if c.json {
+		return json.NewEncoder(c.out).Encode(check)
+	}
+	responseTime := "-"
+	if check.ResponseTimeMs != nil {
+		responseTime = fmt.Sprintf("%dms", *check.ResponseTimeMs)
+	}
+	_, err := fmt.Fprintf(c.out, "%s  %-12s %-4s %s\n",
+		check.CheckedAt.Local().Format("2006-01-02 15:04:05"), check.Status, statusCode(check.StatusCode), responseTime)
+	return err
+}
+
+func (c *cli) incidents(args []string) error {
+	app, err := c.appArg(c.flags("incidents", "<app>"), args)
+	if err != nil {
+		return err
+	}
+	incidents, err := c.client.ListIncidents(c.ctx, app.ID)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(incidents)
+	}
+	if len(incidents) == 0 {
+		fmt.Fprintf(c.out, "No incidents for %s\n", app.Slug)
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STARTED\tRESOLVED\tDURATION\tSTATUS\tFAILED CHECKS\tLAST CODE")
+	for _, incident := range incidents {
+		resolved, end := "ongoing", time.Now()
+		if incident.ResolvedAt != nil {
+			resolved, end = incident.ResolvedAt.Local().Format("2006-01-02 15:04"), *incident.ResolvedAt
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n",
+			incident.StartedAt.Local().Format("2006-01-02 15:04"), resolved,
+			end.Sub(incident.StartedAt).Round(time.Second), incident.Status, incident.FailedChecks, statusCode(incident.LastStatusCode))
+	}
+	return tw.Flush()
+}
+
+func (c *cli) sslCheck(args []string) error {
+	app, err := c.appArg(c.flags("ssl-check", "<app>"), args)
+	if err != nil {
+		return err
+	}
+	if err := c.client.CheckSSL(c.ctx, app.ID); err != nil {
+		return err
+	}
+	if c.json {
+		return c.printJSON(map[string]interface{}{"started": true, "id": app.ID, "slug": app.Slug})
+	}
+	fmt.Fprintf(c.out, "SSL check of %s started. Run `uplitycs app %s` in a few seconds for the result.\n", app.Slug, app.Slug)
+	return nil
+}
+
+// printApp prints an app after a change, or the app itself as JSON
+func (c *cli) printApp(app *client.App, done string) error {
+	if c.json {
+		return c.printJSON(app)
+	}
+	state := ""
+	if app.Paused {
+		state = ", paused"
+	}
+	_, err := fmt.Fprintf(c.out, "%s %s (%s, ID %d%s)\n", done, app.AppName, app.Slug, app.ID, state)
+	return err
+}
+
+func appStatus(app client.AppWithStatus) string {
+	if app.Paused {
+		return "paused"
+	}
+	if app.LastChecked == "" {
+		return "pending"
+	}
+	return app.Status
+}
+
+// statusCode shows a status code, with "-" for no response
+func statusCode(code int) string {
+	if code <= 0 {
+		return "-"
+	}
+	return strconv.Itoa(code)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return "-"
+	}
+	return *s
+}
+
+func derefInt(n *int) int {
+	if n == nil {
+		return 0
+	}
+	return *n
+}
@@ -0,0 +1,192 @@
+// Command uplitycs manages UpLitycs monitors from the terminal with an API key.
+//
+//	export UPLITYCS_API_KEY=sf_...
+//	uplitycs apps
+//	uplitycs tail api
+//
+// Settings come from a JSON config file, then environment variables, then flags. The
+// file is $UPLITYCS_CONFIG, or uplitycs/config.json in the user config directory
+// (~/.config on Linux):
+//
+//	{"url": "https://statusframe.com", "api_key": "sf_...", "org_id": 7}
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"statusframe/client"
+)
+
+const defaultURL = "https://statusframe.com"
+
+// fileConfig is the config file. Every field is optional.
+type fileConfig struct {
+	URL    string `json:"url"`
+	APIKey string `json:"api_key"`
+	OrgID  int    `json:"org_id"`
+}
+
+// loadConfig reads the config file, if there is one, and applies the environment
+func loadConfig(path string) (*fileConfig, error) {
+	cfg := &fileConfig{URL: defaultURL}
+
+	explicit := path != ""
+	if !explicit {
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "uplitycs", "config.json")
+		}
+	}
+	if path != "" {
+		file, err := os.Open(path)
+		switch {
+		case err == nil:
+			defer file.Close()
+			decoder := json.NewDecoder(file)
+			decoder.DisallowUnknownFields()
+			if err := decoder.Decode(cfg); err != nil {
+				return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+			}
+		case explicit || !errors.Is(err, os.ErrNotExist):
+			return nil, fmt.Errorf("error reading config file: %w", err)
+		}
+	}
+
+	if url := os.Getenv("UPLITYCS_URL"); url != "" {
+		cfg.URL = url
+	}
+	if key := os.Getenv("UPLITYCS_API_KEY"); key != "" {
+		cfg.APIKey = key
+	}
+	if raw := os.Getenv("UPLITYCS_ORG_ID"); raw != "" {
+		orgID, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("UPLITYCS_ORG_ID must be a number, got %q", raw)
+		}
+		cfg.OrgID = orgID
+	}
+	return cfg, nil
+}
+
+const usage = `Usage: uplitycs [-config file] [-url URL] [-org ID] <command> [flags] [args]
+
+Commands:
+  apps                  List apps with their status and 24h uptime
+  app <app>             Show an app, its status and its SSL certificate
+  create                Create an app: -name, -slug and -url, optionally -theme and -logo
+  edit <app>            Change the fields given as flags: -name, -slug, -url, -theme, -logo
+  pause <app>           Stop checking an app
+  resume <app>          Start checking it again
+  delete <app>          Delete an app and its history (-yes skips the question)
+  tail <app>            Follow check results as they come in (-every, -n)
+  incidents <app>       List incidents, newest first
+  ssl-check <app>       Re-check an app's SSL certificate now
+
+<app> is an app's ID or slug. Every command takes -json to print JSON for scripts.
+
+Settings:
+  UPLITYCS_API_KEY      API key from POST /api/api-keys; pause, edit and the like need a write key (required)
+  UPLITYCS_URL          Server URL (default ` + defaultURL + `)
+  UPLITYCS_ORG_ID       Organization to act on (default: the key owner's personal one)
+  UPLITYCS_CONFIG       Config file with "url", "api_key" and "org_id"
+`
+
+// commands maps each command to the function that runs it
+var commands = map[string]func(*cli, []string) error{
+	"apps":      (*cli).apps,
+	"app":       (*cli).app,
+	"create":    (*cli).create,
+	"edit":      (*cli).edit,
+	"pause":     (*cli).pause,
+	"resume":    (*cli).resume,
+	"delete":    (*cli).delete,
+	"tail":      (*cli).tail,
+	"incidents": (*cli).incidents,
+	"ssl-check": (*cli).sslCheck,
+}
+
+func main() {
+	fs := flag.NewFlagSet("uplitycs", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	configPath := fs.String("config", os.Getenv("UPLITYCS_CONFIG"), "config file")
+	url := fs.String("url", "", "server URL")
+	orgID := fs.Int("org", 0, "organization ID")
+	jsonOutput := fs.Bool("json", false, "print JSON")
+	fs.Parse(os.Args[1:])
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	run, ok := commands[fs.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "uplitycs: unknown command %q\n\n%s", fs.Arg(0), usage)
+		os.Exit(2)
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fail(err)
+	}
+	if *url != "" {
+		cfg.URL = *url
+	}
+	if *orgID != 0 {
+		cfg.OrgID = *orgID
+	}
+	if cfg.APIKey == "" {
+		fail(errors.New("no API key: set UPLITYCS_API_KEY or api_key in the config file"))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c := client.New(cfg.URL, cfg.APIKey).WithOrg(cfg.OrgID)
+	c.UserAgent = "uplitycs-cli"
+	cmd := &cli{ctx: ctx, client: c, out: os.Stdout, stderr: os.Stderr, in: os.Stdin, json: *jsonOutput}
+	if err := run(cmd, fs.Args()[1:]); err != nil && !errors.Is(err, context.Canceled) {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "uplitycs:", err)
+	os.Exit(1)
+}
+
+// cli holds what every command needs
+type cli struct {
+	ctx    context.Context
+	client *client.Client
+	out    io.Writer
+	stderr io.Writer
+	in     io.Reader
+	json   bool
+}
+
+// flags returns a flag set for a command that also accepts -json after the command name
+func (c *cli) flags(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.BoolVar(&c.json, "json", c.json, "print JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: uplitycs %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// printJSON writes v as indented JSON
+func (c *cli) printJSON(v interface{}) error {
+	encoder := json.NewEncoder(c.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -3,30 +3,46 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+// Querier is a *sql.DB or a *sql.Tx, so functions taking one can also run as part of
+// a caller's transaction
+type Querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 type User struct {
-	Name      string
-	AvatarUrl string
-	Email     string
-	Id        int
-	Plan      string
+	Name       string
+	AvatarUrl  string
+	Email      string
+	Id         int
+	Plan       string
+	IsAdmin    bool
+	DisabledAt *time.Time
 }
 
 type App struct {
 	Id        int     `json:"id"`
-	UserId    int     `json:"user_id"`
+	OrgId     int     `json:"org_id"`
+	UserId    int     `json:"user_id"` // who created the app
 	AppName   string  `json:"app_name"`
 	Slug      string  `json:"slug"`
 	HealthUrl string  `json:"health_url"`
 	Theme     string  `json:"theme"`
 	Alerts    string  `json:"alerts"`
 	LogoURL   *string `json:"logo_url,omitempty"`
+	Paused    bool    `json:"paused"`
 	CreatedAt string  `json:"created_at"`
 	UpdatedAt string  `json:"updated_at"`
 }
@@ -49,18 +65,16 @@ type LatestStatus struct {
 	CheckedAt   string
 }
 
-func OpenDB() *sql.DB {
-	connStr := "user=postgres dbname=statusframe password=your_secure_password_here_change_this host=db port=5432 sslmode=disable"
-
-	conn, err := sql.Open("postgres", connStr)
+func OpenDB(dsn string) (*sql.DB, error) {
+	conn, err := sql.Open("postgres", dsn)
 
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	log.Println("Connection to DATABASE ESTABLISHED✅")
 
-	return conn
+	return conn, nil
 }
 
 func PingDB(conn *sql.DB) error {
@@ -87,17 +101,21 @@ func InsertUser(conn *sql.DB, name, avatarUrl, email string) (int, error) {
 	if err != nil {
 		return 0, err
 	}
+	if _, err := EnsurePersonalOrg(conn, id, name); err != nil {
+		return 0, err
+	}
 	return id, nil
 }
 
+// GetUserFromContext loads the user authenticated by AuthMiddleware (session or API key)
 func GetUserFromContext(conn *sql.DB, ctx context.Context) (User, error) {
-	user, ok := ctx.Value("user").(string)
+	userId, ok := ctx.Value("userId").(int)
 	if !ok {
 		return User{}, sql.ErrNoRows
 	}
 
 	var u User
-	err := conn.QueryRow("SELECT id, username FROM users WHERE username=$1", user).Scan(&u.Id, &u.Name)
+	err := conn.QueryRow("SELECT id, username FROM users WHERE id=$1", userId).Scan(&u.Id, &u.Name)
 	if err != nil {
 		return User{}, err
 	}
@@ -107,7 +125,7 @@ func GetUserFromContext(conn *sql.DB, ctx context.Context) (User, error) {
 
 func GetUserByEmail(conn *sql.DB, email string) (User, error) {
 	var u User
-	err := conn.QueryRow("SELECT id, username, email, avatar_url FROM users WHERE email=$1", email).Scan(&u.Id, &u.Name, &u.Email, &u.AvatarUrl)
+	err := conn.QueryRow("SELECT id, username, email, avatar_url, is_admin, disabled_at FROM users WHERE LOWER(email) = LOWER($1)", email).Scan(&u.Id, &u.Name, &u.Email, &u.AvatarUrl, &u.IsAdmin, &u.DisabledAt)
 	if err != nil {
 		return User{}, err
 	}
@@ -117,7 +135,7 @@ func GetUserByEmail(conn *sql.DB, email string) (User, error) {
 
 func GetUserById(conn *sql.DB, id int) (User, error) {
 	var u User
-	err := conn.QueryRow("SELECT id, username, email, avatar_url FROM users WHERE id=$1", id).Scan(&u.Id, &u.Name, &u.Email, &u.AvatarUrl)
+	err := conn.QueryRow("SELECT id, username, email, avatar_url, is_admin, disabled_at FROM users WHERE id=$1", id).Scan(&u.Id, &u.Name, &u.Email, &u.AvatarUrl, &u.IsAdmin, &u.DisabledAt)
 	if err != nil {
 		return User{}, err
 	}
@@ -187,6 +205,18 @@ func GetStatusFromCode(statusCode int) string {
 	return "error" // 0 or other invalid codes
 }
 
+// GetStatusGroupFromCode groups a status code the way incidents, feeds and subscriber
+// notices do: up, degraded for 3xx and 4xx responses, or down
+func GetStatusGroupFromCode(statusCode int) string {
+	switch {
+	case statusCode >= 200 && statusCode < 300:
+		return "up"
+	case statusCode >= 300 && statusCode < 500:
+		return "degraded"
+	}
+	return "down"
+}
+
 // InsertStatusCheck records a health check result
 func InsertStatusCheck(conn *sql.DB, userId int, statusCode int) error {
 	query := `
@@ -407,12 +437,12 @@ func GetDailyUptimeHistoryBySlug(conn *sql.DB, slug string, days int) ([]DailyUp
 
 // ========== APP MANAGEMENT ==========
 
-// CreateApp creates a new app for a user
-func CreateApp(conn *sql.DB, userId int, appName, slug, healthUrl, theme, alerts string) (int, error) {
+// CreateApp creates a new app in an organization on behalf of userId
+func CreateApp(conn *sql.DB, orgId, userId int, appName, slug, healthUrl, theme, alerts string) (int, error) {
 	var appId int
 	err := conn.QueryRow(
-		"INSERT INTO apps (user_id, app_name, slug, health_url, theme, alerts) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
-		userId, appName, slug, healthUrl, theme, alerts,
+		"INSERT INTO apps (org_id, user_id, app_name, slug, health_url, theme, alerts) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+		orgId, userId, appName, slug, healthUrl, theme, alerts,
 	).Scan(&appId)
 
 	if err != nil {
@@ -421,12 +451,12 @@ func CreateApp(conn *sql.DB, userId int, appName, slug, healthUrl, theme, alerts
 	return appId, nil
 }
 
-// CreateAppWithLogo creates a new app for a user with optional logo URL
-func CreateAppWithLogo(conn *sql.DB, userId int, appName, slug, healthUrl, theme, alerts string, logoURL *string) (int, error) {
+// CreateAppWithLogo creates a new app in an organization with optional logo URL
+func CreateAppWithLogo(conn Querier, orgId, userId int, appName, slug, healthUrl, theme, alerts string, logoURL *string) (int, error) {
 	var appId int
 	err := conn.QueryRow(
-		"INSERT INTO apps (user_id, app_name, slug, health_url, theme, alerts, logo_url) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
-		userId, appName, slug, healthUrl, theme, alerts, logoURL,
+		"INSERT INTO apps (org_id, user_id, app_name, slug, health_url, theme, alerts, logo_url) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id",
+		orgId, userId, appName, slug, healthUrl, theme, alerts, logoURL,
 	).Scan(&appId)
 
 	if err != nil {
@@ -435,11 +465,11 @@ func CreateAppWithLogo(conn *sql.DB, userId int, appName, slug, healthUrl, theme
 	return appId, nil
 }
 
-// GetUserApps returns all apps for a user
-func GetUserApps(conn *sql.DB, userId int) ([]App, error) {
+// GetOrgApps returns all apps of an organization
+func GetOrgApps(conn *sql.DB, orgId int) ([]App, error) {
 	rows, err := conn.Query(
-		"SELECT id, user_id, app_name, slug, health_url, theme, alerts, logo_url, created_at, updated_at FROM apps WHERE user_id = $1 ORDER BY created_at DESC",
-		userId,
+		"SELECT id, org_id, user_id, app_name, slug, health_url, theme, alerts, logo_url, paused, created_at, updated_at FROM apps WHERE org_id = $1 ORDER BY created_at DESC",
+		orgId,
 	)
 	if err != nil {
 		return nil, err
@@ -450,7 +480,7 @@ func GetUserApps(conn *sql.DB, userId int) ([]App, error) {
 	for rows.Next() {
 		var app App
 		var updatedAt sql.NullString
-		err := rows.Scan(&app.Id, &app.UserId, &app.AppName, &app.Slug, &app.HealthUrl, &app.Theme, &app.Alerts, &app.LogoURL, &app.CreatedAt, &updatedAt)
+		err := rows.Scan(&app.Id, &app.OrgId, &app.UserId, &app.AppName, &app.Slug, &app.HealthUrl, &app.Theme, &app.Alerts, &app.LogoURL, &app.Paused, &app.CreatedAt, &updatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -462,20 +492,20 @@ func GetUserApps(conn *sql.DB, userId int) ([]App, error) {
 	return apps, nil
 }
 
-// GetUserAppsWithStatus returns all apps for a user with their current status
-func GetUserAppsWithStatus(conn *sql.DB, userId int) ([]AppWithStatus, error) {
+// GetOrgAppsWithStatus returns all apps of an organization with their current status
+func GetOrgAppsWithStatus(conn *sql.DB, orgId int) ([]AppWithStatus, error) {
 	query := `
 		SELECT 
-			a.id, a.user_id, a.app_name, a.slug, a.health_url, a.theme, a.alerts, a.created_at, a.updated_at, a.logo_url,
+			a.id, a.org_id, a.user_id, a.app_name, a.slug, a.health_url, a.theme, a.alerts, a.created_at, a.updated_at, a.logo_url, a.paused,
 			COALESCE(ls.status_code, 0) as status_code,
 			ls.checked_at as last_checked,
 			COALESCE(uptime.uptime_24h, 0) as uptime_24h,
-			CASE WHEN u.plan IN ('pro', 'business') THEN a.ssl_expiry_date ELSE NULL END as ssl_expiry_date,
-			CASE WHEN u.plan IN ('pro', 'business') THEN a.ssl_days_until_expiry ELSE NULL END as ssl_days_until_expiry,
-			CASE WHEN u.plan IN ('pro', 'business') THEN a.ssl_issuer ELSE NULL END as ssl_issuer,
-			CASE WHEN u.plan IN ('pro', 'business') THEN a.ssl_last_checked ELSE NULL END as ssl_last_checked
+			CASE WHEN o.plan IN ('pro', 'business') THEN a.ssl_expiry_date ELSE NULL END as ssl_expiry_date,
+			CASE WHEN o.plan IN ('pro', 'business') THEN a.ssl_days_until_expiry ELSE NULL END as ssl_days_until_expiry,
+			CASE WHEN o.plan IN ('pro', 'business') THEN a.ssl_issuer ELSE NULL END as ssl_issuer,
+			CASE WHEN o.plan IN ('pro', 'business') THEN a.ssl_last_checked ELSE NULL END as ssl_last_checked
 		FROM apps a
-		JOIN users u ON a.user_id = u.id
+		JOIN organizations o ON a.org_id = o.id
 		LEFT JOIN LATERAL (
 			SELECT status_code, checked_at 
 			FROM user_status 
@@ -490,14 +520,14 @@ func GetUserAppsWithStatus(conn *sql.DB, userId int) ([]AppWithStatus, error) {
 					NULLIF(COUNT(*), 0) * 100, 
 					2
 				) as uptime_24h
-			FROM user_status
+			FROM user_status_unpaused
 			WHERE app_id = a.id AND checked_at > NOW() - INTERVAL '24 hours'
 		) uptime ON true
-		WHERE a.user_id = $1
+		WHERE a.org_id = $1
 		ORDER BY a.created_at DESC
 	`
 
-	rows, err := conn.Query(query, userId)
+	rows, err := conn.Query(query, orgId)
 	if err != nil {
 		return nil, err
 	}
@@ -513,8 +543,8 @@ func GetUserAppsWithStatus(conn *sql.DB, userId int) ([]AppWithStatus, error) {
 		var sslDaysUntilExpiry sql.NullInt64
 
 		err := rows.Scan(
-			&app.Id, &app.UserId, &app.AppName, &app.Slug, &app.HealthUrl, &app.Theme, &app.Alerts,
-			&app.CreatedAt, &updatedAt, &app.LogoURL, &statusCode, &lastChecked, &uptime24h,
+			&app.Id, &app.OrgId, &app.UserId, &app.AppName, &app.Slug, &app.HealthUrl, &app.Theme, &app.Alerts,
+			&app.CreatedAt, &updatedAt, &app.LogoURL, &app.Paused, &statusCode, &lastChecked, &uptime24h,
 			&sslExpiryDate, &sslDaysUntilExpiry, &sslIssuer, &sslLastChecked,
 		)
 		if err != nil {
@@ -524,7 +554,11 @@ func GetUserAppsWithStatus(conn *sql.DB, userId int) ([]AppWithStatus, error) {
 		if updatedAt.Valid {
 			app.UpdatedAt = updatedAt.String
 		}
-		if statusCode.Valid {
+		if app.Paused {
+			// The last check is stale while paused
+			app.StatusCode = 0
+			app.Status = "paused"
+		} else if statusCode.Valid {
 			app.StatusCode = int(statusCode.Int64)
 			app.Status = GetStatusFromCode(app.StatusCode)
 		} else {
@@ -562,9 +596,9 @@ func GetAppBySlug(conn *sql.DB, slug string) (*App, error) {
 	var updatedAt sql.NullString
 
 	err := conn.QueryRow(
-		"SELECT id, user_id, app_name, slug, health_url, theme, alerts, logo_url, created_at, updated_at FROM apps WHERE slug = $1",
+		"SELECT id, org_id, user_id, app_name, slug, health_url, theme, alerts, logo_url, paused, created_at, updated_at FROM apps WHERE slug = $1",
 		slug,
-	).Scan(&app.Id, &app.UserId, &app.AppName, &app.Slug, &app.HealthUrl, &app.Theme, &app.Alerts, &app.LogoURL, &app.CreatedAt, &updatedAt)
+	).Scan(&app.Id, &app.OrgId, &app.UserId, &app.AppName, &app.Slug, &app.HealthUrl, &app.Theme, &app.Alerts, &app.LogoURL, &app.Paused, &app.CreatedAt, &updatedAt)
 
 	if err != nil {
 		return nil, err
@@ -575,24 +609,33 @@ func GetAppBySlug(conn *sql.DB, slug string) (*App, error) {
 	return &app, nil
 }
 
-// DeleteApp deletes an app and all associated data
-func DeleteApp(conn *sql.DB, appId, userId int) error {
-	// Verify ownership before deleting
-	_, err := conn.Exec("DELETE FROM apps WHERE id = $1 AND user_id = $2", appId, userId)
+// DeleteApp deletes an app of an organization and all associated data
+func DeleteApp(conn Querier, appId, orgId int) error {
+	_, err := conn.Exec("DELETE FROM apps WHERE id = $1 AND org_id = $2", appId, orgId)
 	return err
 }
 
-// GetAppCount returns the number of apps a user has
-func GetAppCount(conn *sql.DB, userId int) (int, error) {
+// GetAppCount returns the number of apps an organization has
+func GetAppCount(conn *sql.DB, orgId int) (int, error) {
+	var count int
+	err := conn.QueryRow("SELECT COUNT(*) FROM apps WHERE org_id = $1", orgId).Scan(&count)
+	return count, err
+}
+
+// GetAccessibleAppCount returns the number of apps in every organization the user belongs to
+func GetAccessibleAppCount(conn *sql.DB, userId int) (int, error) {
 	var count int
-	err := conn.QueryRow("SELECT COUNT(*) FROM apps WHERE user_id = $1", userId).Scan(&count)
+	err := conn.QueryRow(
+		"SELECT COUNT(*) FROM apps a JOIN org_members m ON m.org_id = a.org_id WHERE m.user_id = $1",
+		userId,
+	).Scan(&count)
 	return count, err
 }
 
-// GetUserPlan returns the user's current plan
-func GetUserPlan(conn *sql.DB, userId int) (string, error) {
+// GetOrgPlan returns the organization's current plan
+func GetOrgPlan(conn *sql.DB, orgId int) (string, error) {
 	var plan string
-	err := conn.QueryRow("SELECT plan FROM users WHERE id = $1", userId).Scan(&plan)
+	err := conn.QueryRow("SELECT plan FROM organizations WHERE id = $1", orgId).Scan(&plan)
 	if err != nil {
 		return "free", err
 	}
@@ -602,33 +645,44 @@ func GetUserPlan(conn *sql.DB, userId int) (string, error) {
 // PlanFeatures defines all features and limits for each plan
 type PlanFeatures struct {
 	MaxMonitors       int
-	MinCheckInterval  int // in seconds
-	DataRetentionDays int // how many days of historical data to keep
+	MinCheckInterval  int  // in seconds
+	DataRetentionDays int  // how many days of historical data to keep
+	CustomBranding    bool // logos and custom domains on status pages
+}
+
+// planFeatures holds the limits for each plan. The defaults can be replaced
+// at startup from the configuration with SetPlanFeatures.
+var planFeatures = map[string]PlanFeatures{
+	"free": {
+		MaxMonitors:       1,
+		MinCheckInterval:  300, // 5 minutes
+		DataRetentionDays: 7,   // 7 days
+	},
+	"pro": {
+		MaxMonitors:       25,
+		MinCheckInterval:  60, // 1 minute
+		DataRetentionDays: 30, // 30 days
+		CustomBranding:    true,
+	},
+	"business": {
+		MaxMonitors:       100,
+		MinCheckInterval:  30, // 30 seconds
+		DataRetentionDays: 90, // 90 days
+		CustomBranding:    true,
+	},
+}
+
+// SetPlanFeatures replaces the plan limits. It must be called before the server
+// and workers start since the map is read without locking.
+func SetPlanFeatures(features map[string]PlanFeatures) {
+	planFeatures = features
 }
 
 // GetPlanFeatures returns all features for a given plan
 func GetPlanFeatures(plan string) PlanFeatures {
-	features := map[string]PlanFeatures{
-		"free": {
-			MaxMonitors:       1,
-			MinCheckInterval:  300, // 5 minutes
-			DataRetentionDays: 7,   // 7 days
-		},
-		"pro": {
-			MaxMonitors:       25,
-			MinCheckInterval:  60, // 1 minute
-			DataRetentionDays: 30, // 30 days
-		},
-		"business": {
-			MaxMonitors:       100,
-			MinCheckInterval:  30, // 30 seconds
-			DataRetentionDays: 90, // 90 days
-		},
-	}
-
-	feature, ok := features[plan]
+	feature, ok := planFeatures[plan]
 	if !ok {
-		return features["free"] // Default to free plan
+		return planFeatures["free"] // Default to free plan
 	}
 	return feature
 }
@@ -687,9 +741,9 @@ func GetAppById(conn *sql.DB, appId int) (*App, error) {
 	var updatedAt sql.NullString
 
 	err := conn.QueryRow(
-		"SELECT id, user_id, app_name, slug, health_url, theme, alerts, created_at, updated_at FROM apps WHERE id = $1",
+		"SELECT id, org_id, user_id, app_name, slug, health_url, theme, alerts, logo_url, paused, created_at, updated_at FROM apps WHERE id = $1",
 		appId,
-	).Scan(&app.Id, &app.UserId, &app.AppName, &app.Slug, &app.HealthUrl, &app.Theme, &app.Alerts, &app.CreatedAt, &updatedAt)
+	).Scan(&app.Id, &app.OrgId, &app.UserId, &app.AppName, &app.Slug, &app.HealthUrl, &app.Theme, &app.Alerts, &app.LogoURL, &app.Paused, &app.CreatedAt, &updatedAt)
 
 	if err != nil {
 		return nil, err
@@ -710,267 +764,2116 @@ func UpdateAppTheme(conn *sql.DB, appId int, theme string) error {
 	return err
 }
 
-// ========== STRIPE SUBSCRIPTION MANAGEMENT ==========
-
-// UpdateUserSubscription updates user's Stripe subscription information
-func UpdateUserSubscription(conn *sql.DB, userId int, plan, stripeCustomerId, stripeSubscriptionId string) error {
-	_, err := conn.Exec(
-		`UPDATE users 
-		SET plan = $1, 
-		    stripe_customer_id = $2, 
-		    stripe_subscription_id = $3,
-		    plan_started_at = NOW()
-		WHERE id = $4`,
-		plan,
-		stripeCustomerId,
-		stripeSubscriptionId,
-		userId,
-	)
-	return err
+// AppUpdate holds the fields to change on an app. Nil fields are left untouched
+// and an empty LogoURL clears the logo.
+type AppUpdate struct {
+	AppName   *string
+	Slug      *string
+	HealthUrl *string
+	Theme     *string
+	Alerts    *string
+	LogoURL   *string
 }
 
-// CancelUserSubscription reverts user back to free plan
-func CancelUserSubscription(conn *sql.DB, userId int) error {
-	_, err := conn.Exec(
-		`UPDATE users 
-		SET plan = 'free', 
-		    stripe_subscription_id = NULL,
-		    plan_started_at = NOW()
-		WHERE id = $1`,
-		userId,
-	)
-	return err
-}
+// UpdateApp applies update to an app of an organization. A new health URL is checked
+// on the next worker tick. It returns false if the app does not belong to orgId.
+func UpdateApp(conn Querier, appId, orgId int, update AppUpdate) (bool, error) {
+	sets := []string{"updated_at = NOW()"}
+	args := []interface{}{}
+	add := func(column string, value interface{}) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
 
-// GetUserByStripeCustomerId finds a user by their Stripe customer ID
-func GetUserByStripeCustomerId(conn *sql.DB, stripeCustomerId string) (*User, error) {
-	var user User
-	err := conn.QueryRow(
-		"SELECT id, username, email, avatar_url, plan FROM users WHERE stripe_customer_id = $1",
-		stripeCustomerId,
-	).Scan(&user.Id, &user.Name, &user.Email, &user.AvatarUrl, &user.Plan)
+	if update.AppName != nil {
+		add("app_name", *update.AppName)
+	}
+	if update.Slug != nil {
+		add("slug", *update.Slug)
+	}
+	if update.HealthUrl != nil {
+		add("health_url", *update.HealthUrl)
+		sets = append(sets, "next_check_at = NOW()")
+	}
+	if update.Theme != nil {
+		add("theme", *update.Theme)
+	}
+	if update.Alerts != nil {
+		add("alerts", *update.Alerts)
+	}
+	if update.LogoURL != nil {
+		if *update.LogoURL == "" {
+			add("logo_url", nil)
+		} else {
+			add("logo_url", *update.LogoURL)
+		}
+	}
+
+	args = append(args, appId, orgId)
+	query := fmt.Sprintf("UPDATE apps SET %s WHERE id = $%d AND org_id = $%d",
+		strings.Join(sets, ", "), len(args)-1, len(args))
 
+	result, err := conn.Exec(query, args...)
 	if err != nil {
-		return nil, err
+		return false, err
 	}
-	return &user, nil
+	rows, err := result.RowsAffected()
+	return rows > 0, err
 }
 
-// GetStripeCustomerId gets the Stripe customer ID for a user
-func GetStripeCustomerId(conn *sql.DB, userId int) (string, error) {
-	var customerId sql.NullString
-	err := conn.QueryRow(
-		"SELECT stripe_customer_id FROM users WHERE id = $1",
-		userId,
-	).Scan(&customerId)
+// Sources recorded with pause events
+const (
+	PauseViaSession = "session"
+	PauseViaAPIKey  = "api_key"
+	PauseViaAdmin   = "admin"
+	PauseViaSystem  = "system"
+)
+
+// AppPauseEvent records one pause or resume of an app
+type AppPauseEvent struct {
+	Id            int     `json:"id"`
+	AppId         int     `json:"app_id"`
+	Paused        bool    `json:"paused"`
+	ActorUserId   *int    `json:"actor_user_id,omitempty"`
+	ActorUsername *string `json:"actor_username,omitempty"`
+	Via           string  `json:"via"`
+	CreatedAt     string  `json:"created_at"`
+}
 
+// ErrAppLocked is returned when resuming an app that an admin paused
+var ErrAppLocked = errors.New("app was paused by an admin")
+
+// SetAppPaused pauses or resumes monitoring of an app of an organization and records
+// who did it. Resumed apps are checked on the next worker tick. It returns false if
+// the app does not belong to orgId or is already in the requested state.
+//
+// A pause via PauseViaAdmin also locks the app, even one its owners had already
+// paused, and only a resume via PauseViaAdmin unlocks it. Resuming a locked app any
+// other way returns ErrAppLocked.
+func SetAppPaused(conn *sql.DB, appId, orgId int, paused bool, actorUserId *int, via string) (bool, error) {
+	tx, err := conn.Begin()
 	if err != nil {
-		return "", err
+		return false, err
 	}
+	defer tx.Rollback()
 
-	if customerId.Valid {
-		return customerId.String, nil
+	changed, err := SetAppPausedTx(tx, appId, orgId, paused, actorUserId, via)
+	if err != nil || !changed {
+		return false, err
 	}
-	return "", nil
+	return true, tx.Commit()
 }
 
-// CleanupOldStatusChecks removes status checks older than the retention period for each plan
-func CleanupOldStatusChecks(conn *sql.DB) error {
-	// Delete old records for each plan type based on their retention policy
-	queries := []struct {
-		plan string
-		days int
-	}{
-		{"free", 7},
-		{"pro", 30},
-		{"business", 90},
+// SetAppPausedTx is SetAppPaused as part of the caller's transaction
+func SetAppPausedTx(tx *sql.Tx, appId, orgId int, paused bool, actorUserId *int, via string) (bool, error) {
+	query := "UPDATE apps SET paused = $1, next_check_at = NOW(), updated_at = NOW() WHERE id = $2 AND org_id = $3 AND paused != $1 AND NOT admin_locked"
+	if via == PauseViaAdmin {
+		query = "UPDATE apps SET paused = $1, admin_locked = $1, next_check_at = NOW(), updated_at = NOW() WHERE id = $2 AND org_id = $3 AND (paused != $1 OR admin_locked != $1)"
 	}
-
-	totalDeleted := 0
-	for _, q := range queries {
-		result, err := conn.Exec(`
-			DELETE FROM user_status
-			WHERE app_id IN (
-				SELECT a.id FROM apps a
-				JOIN users u ON a.user_id = u.id
-				WHERE u.plan = $1
-			)
-			AND checked_at < NOW() - INTERVAL '1 day' * $2
-		`, q.plan, q.days)
-
+	result, err := tx.Exec(query, paused, appId, orgId)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rows == 0 {
+		if paused || via == PauseViaAdmin {
+			return false, nil
+		}
+		var locked bool
+		err := tx.QueryRow("SELECT admin_locked FROM apps WHERE id = $1 AND org_id = $2", appId, orgId).Scan(&locked)
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
 		if err != nil {
-			log.Printf("❌ Error cleaning up %s plan data: %v", q.plan, err)
-			continue
+			return false, err
 		}
-
-		rows, _ := result.RowsAffected()
-		if rows > 0 {
-			log.Printf("🧹 Cleaned up %d old status checks for %s plan (>%d days)", rows, q.plan, q.days)
-			totalDeleted += int(rows)
+		if locked {
+			return false, ErrAppLocked
 		}
+		return false, nil
 	}
 
-	if totalDeleted > 0 {
-		log.Printf("✅ Total cleanup: removed %d old status checks", totalDeleted)
+	if _, err := tx.Exec(
+		"INSERT INTO app_pause_events (app_id, paused, actor_user_id, via) VALUES ($1, $2, $3, $4)",
+		appId, paused, actorUserId, via,
+	); err != nil {
+		return false, err
 	}
-
-	return nil
+	return true, nil
 }
 
-// UpdateSSLInfo updates SSL certificate information for an app
-func UpdateSSLInfo(conn *sql.DB, appId int, expiryDate *time.Time, daysUntilExpiry *int, issuer *string) error {
-	query := `
-		UPDATE apps 
-		SET ssl_expiry_date = $1, 
-		    ssl_days_until_expiry = $2,
-		    ssl_issuer = $3,
-		    ssl_last_checked = NOW()
-		WHERE id = $4
-	`
-	_, err := conn.Exec(query, expiryDate, daysUntilExpiry, issuer, appId)
-	return err
+// GetAppPauseEvents returns the most recent pauses and resumes of an app, newest first
+func GetAppPauseEvents(conn *sql.DB, appId, limit int) ([]AppPauseEvent, error) {
+	rows, err := conn.Query(`
+		SELECT e.id, e.app_id, e.paused, e.actor_user_id, u.username, e.via, e.created_at
+		FROM app_pause_events e
+		LEFT JOIN users u ON e.actor_user_id = u.id
+		WHERE e.app_id = $1
+		ORDER BY e.created_at DESC, e.id DESC
+		LIMIT $2
+	`, appId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []AppPauseEvent{}
+	for rows.Next() {
+		var event AppPauseEvent
+		var actorId sql.NullInt64
+		var actorName sql.NullString
+		if err := rows.Scan(&event.Id, &event.AppId, &event.Paused, &actorId, &actorName, &event.Via, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		if actorId.Valid {
+			id := int(actorId.Int64)
+			event.ActorUserId = &id
+		}
+		if actorName.Valid {
+			event.ActorUsername = &actorName.String
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
 }
 
-// ========== SLACK INTEGRATION FUNCTIONS ==========
+// ========== CHECK HISTORY ==========
 
-// SlackIntegration represents a Slack integration
-type SlackIntegration struct {
-	ID               int    `json:"id"`
-	UserID           int    `json:"user_id"`
-	SlackTeamID      string `json:"slack_team_id"`
-	SlackTeamName    string `json:"slack_team_name"`
-	SlackBotToken    string `json:"slack_bot_token,omitempty"` // Don't expose token in API
-	SlackChannelID   string `json:"slack_channel_id"`
-	SlackChannelName string `json:"slack_channel_name"`
-	IsEnabled        bool   `json:"is_enabled"`
-	CreatedAt        string `json:"created_at"`
-	UpdatedAt        string `json:"updated_at"`
+// CheckResult is one health check of an app
+type CheckResult struct {
+	Id             int       `json:"id"`
+	StatusCode     int       `json:"status_code"`
+	Status         string    `json:"status"` // Derived from status_code
+	ResponseTimeMs *int      `json:"response_time_ms,omitempty"`
+	CheckedAt      time.Time `json:"checked_at"`
 }
 
-// SaveSlackIntegration saves or updates a Slack integration for a user
-func SaveSlackIntegration(conn *sql.DB, userID int, botToken, teamID, teamName, channelID, channelName string) (*SlackIntegration, error) {
+// GetAppChecks returns an app's checks oldest first. With afterId 0 it returns the
+// latest limit checks, otherwise up to limit checks recorded after that one.
+func GetAppChecks(conn *sql.DB, appId, afterId, limit int) ([]CheckResult, error) {
 	query := `
-		INSERT INTO slack_integrations (user_id, slack_team_id, slack_team_name, slack_bot_token, slack_channel_id, slack_channel_name, is_enabled)
-		VALUES ($1, $2, $3, $4, $5, $6, true)
-		ON CONFLICT (user_id) DO UPDATE
-		SET slack_team_id = $2,
-		    slack_team_name = $3,
-		    slack_bot_token = $4,
-		    slack_channel_id = $5,
-		    slack_channel_name = $6,
-		    is_enabled = true,
-		    updated_at = NOW()
-		RETURNING id, user_id, slack_team_id, slack_team_name, slack_channel_id, slack_channel_name, is_enabled, created_at, updated_at
+		SELECT id, status_code, response_time_ms, checked_at FROM (
+			SELECT id, status_code, response_time_ms, checked_at
+			FROM user_status
+			WHERE app_id = $1
+			ORDER BY id DESC
+			LIMIT $2
+		) latest
+		ORDER BY id
 	`
+	args := []interface{}{appId, limit}
+	if afterId > 0 {
+		query = `
+			SELECT id, status_code, response_time_ms, checked_at
+			FROM user_status
+			WHERE app_id = $1 AND id > $3
+			ORDER BY id
+			LIMIT $2
+		`
+		args = append(args, afterId)
+	}
 
-	var integration SlackIntegration
-	err := conn.QueryRow(query, userID, teamID, teamName, botToken, channelID, channelName).Scan(
-		&integration.ID,
-		&integration.UserID,
-		&integration.SlackTeamID,
-		&integration.SlackTeamName,
-		&integration.SlackChannelID,
-		&integration.SlackChannelName,
-		&integration.IsEnabled,
-		&integration.CreatedAt,
-		&integration.UpdatedAt,
-	)
-
+	rows, err := conn.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("error saving Slack integration: %w", err)
+		return nil, err
 	}
+	defer rows.Close()
 
-	return &integration, nil
+	checks := []CheckResult{}
+	for rows.Next() {
+		var check CheckResult
+		var responseTime sql.NullInt64
+		if err := rows.Scan(&check.Id, &check.StatusCode, &responseTime, &check.CheckedAt); err != nil {
+			return nil, err
+		}
+		check.Status = GetStatusFromCode(check.StatusCode)
+		if responseTime.Valid {
+			ms := int(responseTime.Int64)
+			check.ResponseTimeMs = &ms
+		}
+		checks = append(checks, check)
+	}
+	return checks, rows.Err()
 }
 
-// GetSlackIntegration retrieves a user's Slack integration (without bot token for security)
-func GetSlackIntegration(conn *sql.DB, userID int) (*SlackIntegration, error) {
-	query := `
-		SELECT id, user_id, slack_team_id, slack_team_name, slack_channel_id, slack_channel_name, is_enabled, created_at, updated_at
-		FROM slack_integrations
-		WHERE user_id = $1
-	`
+// Incident is a run of failed checks of an app, from the first failure to the next
+// successful check. It is degraded when every failed check got a 3xx or 4xx response.
+type Incident struct {
+	Status         string     `json:"status"` // "down" or "degraded"
+	StartedAt      time.Time  `json:"started_at"`
+	ResolvedAt     *time.Time `json:"resolved_at"` // nil while still failing
+	FailedChecks   int        `json:"failed_checks"`
+	LastStatusCode int        `json:"last_status_code"`
+}
 
-	var integration SlackIntegration
-	err := conn.QueryRow(query, userID).Scan(
-		&integration.ID,
-		&integration.UserID,
-		&integration.SlackTeamID,
-		&integration.SlackTeamName,
-		&integration.SlackChannelID,
-		&integration.SlackChannelName,
-		&integration.IsEnabled,
-		&integration.CreatedAt,
-		&integration.UpdatedAt,
-	)
+// GetAppIncidents returns an app's incidents of the last days, newest first. Checks
+// made while the app was paused are left out, like they are from uptime.
+func GetAppIncidents(conn *sql.DB, appId, days, limit int) ([]Incident, error) {
+	// Every successful check starts a new group, so the failed checks that share a
+	// group are one incident and the first check of the next group resolves it
+	rows, err := conn.Query(`
+		WITH checks AS (
+			SELECT id, status_code, checked_at,
+				COUNT(*) FILTER (WHERE status_code >= 200 AND status_code < 300) OVER (ORDER BY checked_at, id) AS successes
+			FROM user_status_unpaused
+			WHERE app_id = $1 AND checked_at > NOW() - make_interval(days => $2)
+		)
+		SELECT
+			BOOL_OR(f.status_code < 200 OR f.status_code >= 500) AS down,
+			MIN(f.checked_at) AS started_at,
+			(SELECT MIN(n.checked_at) FROM checks n WHERE n.successes = f.successes + 1) AS resolved_at,
+			COUNT(*) AS failed_checks,
+			(ARRAY_AGG(f.status_code ORDER BY f.checked_at DESC, f.id DESC))[1] AS last_status_code
+		FROM checks f
+		WHERE f.status_code < 200 OR f.status_code >= 300
+		GROUP BY f.successes
+		ORDER BY started_at DESC
+		LIMIT $3
+	`, appId, days, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	if err == sql.ErrNoRows {
-		return nil, nil // No integration found
+	incidents := []Incident{}
+	for rows.Next() {
+		var incident Incident
+		var down bool
+		var resolvedAt sql.NullTime
+		if err := rows.Scan(&down, &incident.StartedAt, &resolvedAt, &incident.FailedChecks, &incident.LastStatusCode); err != nil {
+			return nil, err
+		}
+		incident.Status = "degraded"
+		if down {
+			incident.Status = "down"
+		}
+		if resolvedAt.Valid {
+			incident.ResolvedAt = &resolvedAt.Time
+		}
+		incidents = append(incidents, incident)
 	}
+	return incidents, rows.Err()
+}
+
+// StatusChange is a check whose status differs from the check before it. Statuses
+// are grouped like incidents: up, degraded for 3xx and 4xx responses, or down.
+type StatusChange struct {
+	CheckId    int
+	Status     string
+	Previous   string
+	StatusCode int
+	CheckedAt  time.Time
+}
+
+// GetAppStatusChanges returns the changes in an app's status over the last days,
+// newest first. Checks made while the app was paused are left out, so resuming
+// doesn't count as a change unless the status did.
+func GetAppStatusChanges(conn *sql.DB, appId, days, limit int) ([]StatusChange, error) {
+	rows, err := conn.Query(`
+		WITH checks AS (
+			SELECT id, status_code, checked_at,
+				CASE
+					WHEN status_code >= 200 AND status_code < 300 THEN 'up'
+					WHEN status_code >= 300 AND status_code < 500 THEN 'degraded'
+					ELSE 'down'
+				END AS status
+			FROM user_status_unpaused
+			WHERE app_id = $1 AND checked_at > NOW() - make_interval(days => $2)
+		), changes AS (
+			SELECT id, status, LAG(status) OVER (ORDER BY checked_at, id) AS previous, status_code, checked_at
+			FROM checks
+		)
+		SELECT id, status, previous, status_code, checked_at
+		FROM changes
+		WHERE previous IS NOT NULL AND previous <> status
+		ORDER BY checked_at DESC, id DESC
+		LIMIT $3
+	`, appId, days, limit)
 	if err != nil {
-		return nil, fmt.Errorf("error retrieving Slack integration: %w", err)
+		return nil, err
 	}
+	defer rows.Close()
 
-	return &integration, nil
+	changes := []StatusChange{}
+	for rows.Next() {
+		var c StatusChange
+		if err := rows.Scan(&c.CheckId, &c.Status, &c.Previous, &c.StatusCode, &c.CheckedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
 }
 
-// GetSlackIntegrationByAppID retrieves Slack integration using app ID (with bot token for internal use)
-func GetSlackIntegrationByAppID(conn *sql.DB, appID int) (*SlackIntegration, error) {
-	query := `
-		SELECT 
-			si.id, si.user_id, si.slack_team_id, si.slack_team_name, 
-			si.slack_bot_token, si.slack_channel_id, si.slack_channel_name, 
-			si.is_enabled, si.created_at, si.updated_at
-		FROM slack_integrations si
-		JOIN apps a ON a.user_id = si.user_id
-		WHERE a.id = $1 AND si.is_enabled = true
-	`
+// ========== MONITOR CONFIGURATION ==========
 
-	var integration SlackIntegration
-	err := conn.QueryRow(query, appID).Scan(
-		&integration.ID,
-		&integration.UserID,
-		&integration.SlackTeamID,
-		&integration.SlackTeamName,
-		&integration.SlackBotToken,
-		&integration.SlackChannelID,
-		&integration.SlackChannelName,
-		&integration.IsEnabled,
-		&integration.CreatedAt,
-		&integration.UpdatedAt,
-	)
+// Channels an app's incident alerts can be routed to
+const (
+	AlertChannelSlack   = "slack"
+	AlertChannelDiscord = "discord"
+)
 
-	if err == sql.ErrNoRows {
-		return nil, nil
+// AlertChannels lists every channel alerts can be routed to
+var AlertChannels = []string{AlertChannelSlack, AlertChannelDiscord}
+
+// AppCheckSettings holds how an app is checked and alerted on beyond its basic fields
+type AppCheckSettings struct {
+	CheckInterval *int     // seconds; nil checks as often as the plan allows
+	AlertChannels []string // nil sends to every connected integration, empty to none
+}
+
+// EffectiveCheckInterval returns how many seconds apart an app is checked. An interval
+// below the plan's minimum, e.g. after a downgrade, is raised to the minimum.
+func EffectiveCheckInterval(plan string, checkInterval *int) int {
+	min := GetPlanCheckInterval(plan)
+	if checkInterval != nil && *checkInterval > min {
+		return *checkInterval
+	}
+	return min
+}
+
+// AlertChannelEnabled reports whether an app's alert routing includes channel
+func AlertChannelEnabled(channels []string, channel string) bool {
+	if channels == nil {
+		return true
+	}
+	for _, c := range channels {
+		if c == channel {
+			return true
+		}
 	}
+	return false
+}
+
+// GetOrgAppCheckSettings returns the check settings of every app of an organization by app ID
+func GetOrgAppCheckSettings(conn *sql.DB, orgId int) (map[int]AppCheckSettings, error) {
+	rows, err := conn.Query("SELECT id, check_interval, alert_channels FROM apps WHERE org_id = $1", orgId)
 	if err != nil {
-		return nil, fmt.Errorf("error retrieving Slack integration: %w", err)
+		return nil, err
 	}
+	defer rows.Close()
 
-	return &integration, nil
+	settings := map[int]AppCheckSettings{}
+	for rows.Next() {
+		var appId int
+		var interval sql.NullInt64
+		var s AppCheckSettings
+		if err := rows.Scan(&appId, &interval, pq.Array(&s.AlertChannels)); err != nil {
+			return nil, err
+		}
+		if interval.Valid {
+			seconds := int(interval.Int64)
+			s.CheckInterval = &seconds
+		}
+		settings[appId] = s
+	}
+	return settings, rows.Err()
 }
 
-// DisableSlackIntegration disables Slack integration for a user
-func DisableSlackIntegration(conn *sql.DB, userID int) error {
-	_, err := conn.Exec(
-		"UPDATE slack_integrations SET is_enabled = false, updated_at = NOW() WHERE user_id = $1",
-		userID,
+// SetAppCheckSettings replaces an app's check settings and checks it again on the next
+// worker tick. It returns false if the app does not belong to orgId.
+func SetAppCheckSettings(conn Querier, appId, orgId int, s AppCheckSettings) (bool, error) {
+	result, err := conn.Exec(
+		"UPDATE apps SET check_interval = $1, alert_channels = $2, next_check_at = NOW(), updated_at = NOW() WHERE id = $3 AND org_id = $4",
+		s.CheckInterval, pq.Array(s.AlertChannels), appId, orgId,
 	)
-	return err
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
 }
 
-// DeleteSlackIntegration removes Slack integration for a user
-func DeleteSlackIntegration(conn *sql.DB, userID int) error {
-	_, err := conn.Exec(
-		"DELETE FROM slack_integrations WHERE user_id = $1",
-		userID,
-	)
-	return err
+// MaintenanceWindow is planned downtime of an app. Checks keep running during a
+// window but no alerts are sent.
+type MaintenanceWindow struct {
+	ID       int       `json:"id"`
+	AppID    int       `json:"app_id"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Reason   string    `json:"reason"`
 }
 
-// LogIncidentNotification logs a notification event
-func LogIncidentNotification(conn *sql.DB, appID int, notificationType, status string) error {
-	query := `
+// GetOrgMaintenanceWindows returns the windows of an organization's apps that haven't ended yet
+func GetOrgMaintenanceWindows(conn *sql.DB, orgId int) ([]MaintenanceWindow, error) {
+	rows, err := conn.Query(`
+		SELECT w.id, w.app_id, w.starts_at, w.ends_at, w.reason
+		FROM maintenance_windows w
+		JOIN apps a ON a.id = w.app_id
+		WHERE a.org_id = $1 AND w.ends_at > NOW()
+		ORDER BY w.app_id, w.starts_at
+	`, orgId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var windows []MaintenanceWindow
+	for rows.Next() {
+		var w MaintenanceWindow
+		if err := rows.Scan(&w.ID, &w.AppID, &w.StartsAt, &w.EndsAt, &w.Reason); err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, rows.Err()
+}
+
+// ReplaceMaintenanceWindows replaces the windows of an app that haven't ended yet, as
+// part of the caller's transaction. Past windows are kept as a record.
+func ReplaceMaintenanceWindows(tx *sql.Tx, appId int, windows []MaintenanceWindow) error {
+	if _, err := tx.Exec("DELETE FROM maintenance_windows WHERE app_id = $1 AND ends_at > NOW()", appId); err != nil {
+		return err
+	}
+	for _, w := range windows {
+		if _, err := tx.Exec(
+			"INSERT INTO maintenance_windows (app_id, starts_at, ends_at, reason) VALUES ($1, $2, $3, $4)",
+			appId, w.StartsAt, w.EndsAt, w.Reason,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ========== STATUS PAGES ==========
+
+// StatusPage is a public page that groups several apps of an organization into
+// components, optionally under named sections
+type StatusPage struct {
+	Id          int                   `json:"id"`
+	OrgId       int                   `json:"org_id"`
+	Slug        string                `json:"slug"`
+	Title       string                `json:"title"`
+	Description string                `json:"description"`
+	Theme       string                `json:"theme"`
+	LogoURL     *string               `json:"logo_url,omitempty"`
+	Components  []StatusPageComponent `json:"components"` // shown above the sections
+	Sections    []StatusPageSection   `json:"sections"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+}
+
+// StatusPageSection is a named group of components
+type StatusPageSection struct {
+	Id         int                   `json:"id"`
+	Name       string                `json:"name"`
+	Components []StatusPageComponent `json:"components"`
+}
+
+// StatusPageComponent shows one app on a status page
+type StatusPageComponent struct {
+	Id    int     `json:"id"`
+	AppId int     `json:"app_id"`
+	Name  *string `json:"name,omitempty"` // nil shows the app's name
+}
+
+// AppIds returns the apps shown on the page, in page order
+func (p *StatusPage) AppIds() []int {
+	var ids []int
+	for _, c := range p.Components {
+		ids = append(ids, c.AppId)
+	}
+	for _, s := range p.Sections {
+		for _, c := range s.Components {
+			ids = append(ids, c.AppId)
+		}
+	}
+	return ids
+}
+
+const statusPageColumns = "id, org_id, slug, title, description, theme, logo_url, created_at, updated_at"
+
+func scanStatusPage(row interface{ Scan(...interface{}) error }) (*StatusPage, error) {
+	var page StatusPage
+	err := row.Scan(&page.Id, &page.OrgId, &page.Slug, &page.Title, &page.Description, &page.Theme, &page.LogoURL, &page.CreatedAt, &page.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	page.Components = []StatusPageComponent{}
+	page.Sections = []StatusPageSection{}
+	return &page, nil
+}
+
+// GetOrgStatusPages returns an organization's status pages with their layout
+func GetOrgStatusPages(conn *sql.DB, orgId int) ([]StatusPage, error) {
+	rows, err := conn.Query("SELECT "+statusPageColumns+" FROM status_pages WHERE org_id = $1 ORDER BY created_at, id", orgId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pages := []StatusPage{}
+	for rows.Next() {
+		page, err := scanStatusPage(rows)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, *page)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range pages {
+		if err := loadStatusPageLayout(conn, &pages[i]); err != nil {
+			return nil, err
+		}
+	}
+	return pages, nil
+}
+
+// GetStatusPageById returns a status page with its layout, or sql.ErrNoRows
+func GetStatusPageById(conn *sql.DB, pageId int) (*StatusPage, error) {
+	page, err := scanStatusPage(conn.QueryRow("SELECT "+statusPageColumns+" FROM status_pages WHERE id = $1", pageId))
+	if err != nil {
+		return nil, err
+	}
+	return page, loadStatusPageLayout(conn, page)
+}
+
+// GetStatusPageBySlug returns a status page with its layout, or sql.ErrNoRows
+func GetStatusPageBySlug(conn *sql.DB, slug string) (*StatusPage, error) {
+	page, err := scanStatusPage(conn.QueryRow("SELECT "+statusPageColumns+" FROM status_pages WHERE slug = $1", slug))
+	if err != nil {
+		return nil, err
+	}
+	return page, loadStatusPageLayout(conn, page)
+}
+
+// loadStatusPageLayout fills in a page's sections and components in order
+func loadStatusPageLayout(conn *sql.DB, page *StatusPage) error {
+	rows, err := conn.Query(`
+		SELECT c.id, c.section_id, s.name, c.app_id, c.name
+		FROM status_page_components c
+		LEFT JOIN status_page_sections s ON s.id = c.section_id
+		WHERE c.page_id = $1
+		ORDER BY s.position NULLS FIRST, c.position
+	`, page.Id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var component StatusPageComponent
+		var sectionId sql.NullInt64
+		var sectionName sql.NullString
+		if err := rows.Scan(&component.Id, &sectionId, &sectionName, &component.AppId, &component.Name); err != nil {
+			return err
+		}
+		if !sectionId.Valid {
+			page.Components = append(page.Components, component)
+			continue
+		}
+		last := len(page.Sections) - 1
+		if last < 0 || page.Sections[last].Id != int(sectionId.Int64) {
+			page.Sections = append(page.Sections, StatusPageSection{Id: int(sectionId.Int64), Name: sectionName.String})
+			last++
+		}
+		page.Sections[last].Components = append(page.Sections[last].Components, component)
+	}
+	return rows.Err()
+}
+
+// CreateStatusPage creates a page with its layout and returns its ID
+func CreateStatusPage(conn *sql.DB, page *StatusPage) (int, error) {
+	tx, err := conn.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var pageId int
+	err = tx.QueryRow(
+		"INSERT INTO status_pages (org_id, slug, title, description, theme, logo_url) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id",
+		page.OrgId, page.Slug, page.Title, page.Description, page.Theme, page.LogoURL,
+	).Scan(&pageId)
+	if err != nil {
+		return 0, err
+	}
+	if err := insertStatusPageLayout(tx, pageId, page); err != nil {
+		return 0, err
+	}
+	return pageId, tx.Commit()
+}
+
+// UpdateStatusPage replaces a page's settings and layout. It returns false if the
+// page does not belong to page.OrgId.
+func UpdateStatusPage(conn *sql.DB, page *StatusPage) (bool, error) {
+	tx, err := conn.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"UPDATE status_pages SET slug = $1, title = $2, description = $3, theme = $4, logo_url = $5, updated_at = NOW() WHERE id = $6 AND org_id = $7",
+		page.Slug, page.Title, page.Description, page.Theme, page.LogoURL, page.Id, page.OrgId,
+	)
+	if err != nil {
+		return false, err
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+		return false, err
+	}
+
+	// Components go with their sections
+	if _, err := tx.Exec("DELETE FROM status_page_components WHERE page_id = $1", page.Id); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec("DELETE FROM status_page_sections WHERE page_id = $1", page.Id); err != nil {
+		return false, err
+	}
+	if err := insertStatusPageLayout(tx, page.Id, page); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func insertStatusPageLayout(tx *sql.Tx, pageId int, page *StatusPage) error {
+	insertComponents := func(sectionId *int, components []StatusPageComponent) error {
+		for i, c := range components {
+			if _, err := tx.Exec(
+				"INSERT INTO status_page_components (page_id, section_id, app_id, name, position) VALUES ($1, $2, $3, $4, $5)",
+				pageId, sectionId, c.AppId, c.Name, i,
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := insertComponents(nil, page.Components); err != nil {
+		return err
+	}
+	for i, s := range page.Sections {
+		var sectionId int
+		if err := tx.QueryRow(
+			"INSERT INTO status_page_sections (page_id, name, position) VALUES ($1, $2, $3) RETURNING id",
+			pageId, s.Name, i,
+		).Scan(&sectionId); err != nil {
+			return err
+		}
+		if err := insertComponents(&sectionId, s.Components); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteStatusPage deletes a page of an organization. The apps on it are kept.
+func DeleteStatusPage(conn *sql.DB, pageId, orgId int) (bool, error) {
+	result, err := conn.Exec("DELETE FROM status_pages WHERE id = $1 AND org_id = $2", pageId, orgId)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// ComponentStatus is the current state of an app shown as a status page component
+type ComponentStatus struct {
+	AppName       string
+	Paused        bool
+	InMaintenance bool
+	StatusCode    *int       // nil before the first check
+	CheckedAt     *time.Time // nil before the first check
+	Uptime24h     *float64   // nil without checks in the last 24 hours
+
+	// Whether the app's status page shows its uptime and daily uptime bars
+	ShowUptime        bool
+	ShowUptimeHistory bool
+}
+
+// GetComponentStatuses returns the current state of each app by app ID
+func GetComponentStatuses(conn *sql.DB, appIds []int) (map[int]ComponentStatus, error) {
+	rows, err := conn.Query(`
+		SELECT a.id, a.app_name, a.paused,
+			EXISTS (
+				SELECT 1 FROM maintenance_windows w
+				WHERE w.app_id = a.id AND w.starts_at <= NOW() AND w.ends_at > NOW()
+			) AS in_maintenance,
+			ls.status_code, ls.checked_at,
+			uptime.uptime_24h,
+			COALESCE(f.uptime, true), COALESCE(f.uptime_history, true)
+		FROM apps a
+		LEFT JOIN LATERAL (
+			SELECT status_code, checked_at
+			FROM user_status
+			WHERE app_id = a.id
+			ORDER BY checked_at DESC
+			LIMIT 1
+		) ls ON true
+		LEFT JOIN LATERAL (
+			SELECT ROUND(
+				CAST(COUNT(*) FILTER (WHERE status_code >= 200 AND status_code < 300) AS NUMERIC) /
+				NULLIF(COUNT(*), 0) * 100,
+				2
+			) AS uptime_24h
+			FROM user_status_unpaused
+			WHERE app_id = a.id AND checked_at > NOW() - INTERVAL '24 hours'
+		) uptime ON true
+		LEFT JOIN app_public_fields f ON f.app_id = a.id
+		WHERE a.id = ANY($1)
+	`, pq.Array(appIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statuses := map[int]ComponentStatus{}
+	for rows.Next() {
+		var appId int
+		var s ComponentStatus
+		var statusCode sql.NullInt64
+		var checkedAt sql.NullTime
+		var uptime sql.NullFloat64
+		if err := rows.Scan(&appId, &s.AppName, &s.Paused, &s.InMaintenance, &statusCode, &checkedAt, &uptime,
+			&s.ShowUptime, &s.ShowUptimeHistory); err != nil {
+			return nil, err
+		}
+		if statusCode.Valid {
+			code := int(statusCode.Int64)
+			s.StatusCode = &code
+		}
+		if checkedAt.Valid {
+			s.CheckedAt = &checkedAt.Time
+		}
+		if uptime.Valid {
+			s.Uptime24h = &uptime.Float64
+		}
+		statuses[appId] = s
+	}
+	return statuses, rows.Err()
+}
+
+// GetAppsDailyUptime returns the daily uptime of each app over the last days, newest
+// first, by app ID. Paused time is left out.
+func GetAppsDailyUptime(conn *sql.DB, appIds []int, days int) (map[int][]DailyUptime, error) {
+	rows, err := conn.Query(`
+		SELECT
+			app_id,
+			DATE(checked_at) as date,
+			COUNT(*) as total_checks,
+			COUNT(*) FILTER (WHERE status_code >= 200 AND status_code < 300) as successful_checks
+		FROM user_status_unpaused
+		WHERE app_id = ANY($1)
+		AND checked_at > NOW() - INTERVAL '1 day' * $2
+		GROUP BY app_id, DATE(checked_at)
+		ORDER BY app_id, date DESC
+	`, pq.Array(appIds), days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := map[int][]DailyUptime{}
+	for rows.Next() {
+		var appId int
+		var daily DailyUptime
+		if err := rows.Scan(&appId, &daily.Date, &daily.TotalChecks, &daily.SuccessfulChecks); err != nil {
+			return nil, err
+		}
+		if daily.TotalChecks > 0 {
+			daily.UptimePercentage = float64(daily.SuccessfulChecks) / float64(daily.TotalChecks) * 100
+		}
+		history[appId] = append(history[appId], daily)
+	}
+	return history, rows.Err()
+}
+
+// ========== CUSTOM DOMAINS ==========
+
+// CustomDomain is a domain a status page is served on once it is verified
+type CustomDomain struct {
+	PageId     int        `json:"page_id"`
+	Domain     string     `json:"domain"`
+	Token      string     `json:"-"` // the value of the TXT record that verifies the domain
+	VerifiedAt *time.Time `json:"verified_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// GetPageDomain returns the domain of a status page, or sql.ErrNoRows
+func GetPageDomain(conn *sql.DB, pageId int) (*CustomDomain, error) {
+	var d CustomDomain
+	err := conn.QueryRow(
+		"SELECT page_id, domain, token, verified_at, created_at FROM custom_domains WHERE page_id = $1",
+		pageId,
+	).Scan(&d.PageId, &d.Domain, &d.Token, &d.VerifiedAt, &d.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// SetPageDomain gives a status page a new, unverified domain in place of any other
+func SetPageDomain(conn *sql.DB, pageId int, domain, token string) error {
+	_, err := conn.Exec(`
+		INSERT INTO custom_domains (page_id, domain, token) VALUES ($1, $2, $3)
+		ON CONFLICT (page_id) DO UPDATE SET domain = EXCLUDED.domain, token = EXCLUDED.token, verified_at = NULL, created_at = NOW()
+	`, pageId, domain, token)
+	return err
+}
+
+// VerifyPageDomain marks a page's domain as verified. It fails with a duplicate key
+// error if another page has already verified the same domain.
+func VerifyPageDomain(conn *sql.DB, pageId int) error {
+	_, err := conn.Exec("UPDATE custom_domains SET verified_at = NOW() WHERE page_id = $1", pageId)
+	return err
+}
+
+// DeletePageDomain removes a status page's domain
+func DeletePageDomain(conn *sql.DB, pageId int) (bool, error) {
+	result, err := conn.Exec("DELETE FROM custom_domains WHERE page_id = $1", pageId)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// GetStatusPageSlugByDomain returns the slug of the page a verified domain serves,
+// or sql.ErrNoRows
+func GetStatusPageSlugByDomain(conn *sql.DB, domain string) (string, error) {
+	var slug string
+	err := conn.QueryRow(`
+		SELECT p.slug
+		FROM custom_domains d
+		JOIN status_pages p ON p.id = d.page_id
+		WHERE d.domain = $1 AND d.verified_at IS NOT NULL
+	`, domain).Scan(&slug)
+	return slug, err
+}
+
+// GetVerifiedDomains returns every verified custom domain with the slug of the status
+// page it serves
+func GetVerifiedDomains(conn *sql.DB) (map[string]string, error) {
+	rows, err := conn.Query(`
+		SELECT d.domain, p.slug
+		FROM custom_domains d
+		JOIN status_pages p ON p.id = d.page_id
+		WHERE d.verified_at IS NOT NULL
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slugs := map[string]string{}
+	for rows.Next() {
+		var domain, slug string
+		if err := rows.Scan(&domain, &slug); err != nil {
+			return nil, err
+		}
+		slugs[domain] = slug
+	}
+	return slugs, rows.Err()
+}
+
+// ========== INCIDENT UPDATES ==========
+
+// Stages of an incident an update can report
+const (
+	IncidentInvestigating = "investigating"
+	IncidentIdentified    = "identified"
+	IncidentMonitoring    = "monitoring"
+	IncidentResolved      = "resolved"
+)
+
+// IncidentStatuses lists every stage an incident update can report, in order
+var IncidentStatuses = []string{IncidentInvestigating, IncidentIdentified, IncidentMonitoring, IncidentResolved}
+
+// IncidentUpdate is a message posted about an app's incident
+type IncidentUpdate struct {
+	Id        int       `json:"id"`
+	AppId     int       `json:"app_id"`
+	Status    string    `json:"status"`
+	Message   string    `json:"message"`
+	CreatedBy *int      `json:"created_by"` // nil once the user is deleted
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const incidentUpdateColumns = "id, app_id, status, message, created_by, created_at"
+
+// CreateIncidentUpdate posts an update about an app's incident and returns it
+func CreateIncidentUpdate(conn *sql.DB, appId, userId int, status, message string) (*IncidentUpdate, error) {
+	var u IncidentUpdate
+	err := conn.QueryRow(
+		"INSERT INTO incident_updates (app_id, status, message, created_by) VALUES ($1, $2, $3, $4) RETURNING "+incidentUpdateColumns,
+		appId, status, message, userId,
+	).Scan(&u.Id, &u.AppId, &u.Status, &u.Message, &u.CreatedBy, &u.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// GetAppIncidentUpdates returns an app's latest incident updates, newest first
+func GetAppIncidentUpdates(conn *sql.DB, appId, limit int) ([]IncidentUpdate, error) {
+	rows, err := conn.Query(
+		"SELECT "+incidentUpdateColumns+" FROM incident_updates WHERE app_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2",
+		appId, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	updates := []IncidentUpdate{}
+	for rows.Next() {
+		var u IncidentUpdate
+		if err := rows.Scan(&u.Id, &u.AppId, &u.Status, &u.Message, &u.CreatedBy, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		updates = append(updates, u)
+	}
+	return updates, rows.Err()
+}
+
+// DeleteIncidentUpdate removes one of an app's incident updates and returns what it
+// was, or sql.ErrNoRows
+func DeleteIncidentUpdate(conn *sql.DB, appId, updateId int) (*IncidentUpdate, error) {
+	var u IncidentUpdate
+	err := conn.QueryRow(
+		"DELETE FROM incident_updates WHERE id = $1 AND app_id = $2 RETURNING "+incidentUpdateColumns,
+		updateId, appId,
+	).Scan(&u.Id, &u.AppId, &u.Status, &u.Message, &u.CreatedBy, &u.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// UnsentIncidentUpdate is an incident update subscribers haven't been sent yet
+type UnsentIncidentUpdate struct {
+	IncidentUpdate
+	AppName string
+	AppSlug string
+}
+
+// GetUnsentIncidentUpdates returns the incident updates not yet sent to subscribers,
+// oldest first
+func GetUnsentIncidentUpdates(conn *sql.DB, limit int) ([]UnsentIncidentUpdate, error) {
+	rows, err := conn.Query(`
+		SELECT u.id, u.app_id, u.status, u.message, u.created_by, u.created_at, a.app_name, a.slug
+		FROM incident_updates u
+		JOIN apps a ON a.id = u.app_id
+		WHERE u.notified_at IS NULL
+		ORDER BY u.created_at, u.id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	updates := []UnsentIncidentUpdate{}
+	for rows.Next() {
+		var u UnsentIncidentUpdate
+		if err := rows.Scan(&u.Id, &u.AppId, &u.Status, &u.Message, &u.CreatedBy, &u.CreatedAt, &u.AppName, &u.AppSlug); err != nil {
+			return nil, err
+		}
+		updates = append(updates, u)
+	}
+	return updates, rows.Err()
+}
+
+// MarkIncidentUpdateNotified records that an incident update was sent to subscribers
+func MarkIncidentUpdateNotified(conn *sql.DB, updateId int) error {
+	_, err := conn.Exec("UPDATE incident_updates SET notified_at = NOW() WHERE id = $1", updateId)
+	return err
+}
+
+// ========== SUBSCRIBERS ==========
+
+// How visitors can subscribe to a status page
+const (
+	SubscriberEmail   = "email"
+	SubscriberWebhook = "webhook"
+)
+
+// Subscriber is an email address or webhook notified of an app's status changes
+// and incident updates
+type Subscriber struct {
+	Id          int
+	AppId       int
+	Kind        string
+	Target      string // the email address or webhook URL
+	Token       string // in the confirmation and unsubscribe links
+	ConfirmedAt *time.Time
+	CreatedAt   time.Time
+}
+
+const subscriberColumns = "id, app_id, kind, target, token, confirmed_at, created_at"
+
+func scanSubscriber(row interface{ Scan(...interface{}) error }) (*Subscriber, error) {
+	var s Subscriber
+	if err := row.Scan(&s.Id, &s.AppId, &s.Kind, &s.Target, &s.Token, &s.ConfirmedAt, &s.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetSubscriber returns an app's subscription by an address or webhook, or sql.ErrNoRows
+func GetSubscriber(conn *sql.DB, appId int, kind, target string) (*Subscriber, error) {
+	return scanSubscriber(conn.QueryRow(
+		"SELECT "+subscriberColumns+" FROM subscribers WHERE app_id = $1 AND kind = $2 AND target = $3",
+		appId, kind, target,
+	))
+}
+
+// SaveSubscriber subscribes an address or webhook to an app with a new token. A
+// subscription that exists already gets the new token and confirmation.
+func SaveSubscriber(conn *sql.DB, appId int, kind, target, token string, confirmed bool) error {
+	_, err := conn.Exec(`
+		INSERT INTO subscribers (app_id, kind, target, token, confirmed_at)
+		VALUES ($1, $2, $3, $4, CASE WHEN $5 THEN NOW() END)
+		ON CONFLICT (app_id, kind, target) DO UPDATE
+		SET token = EXCLUDED.token, confirmed_at = EXCLUDED.confirmed_at, created_at = NOW()
+	`, appId, kind, target, token, confirmed)
+	return err
+}
+
+// ConfirmSubscriber confirms the subscription with a token and returns the slug of
+// its app, or sql.ErrNoRows. Confirming twice is harmless.
+func ConfirmSubscriber(conn *sql.DB, token string) (string, error) {
+	var slug string
+	err := conn.QueryRow(`
+		UPDATE subscribers s SET confirmed_at = COALESCE(s.confirmed_at, NOW())
+		FROM apps a
+		WHERE s.token = $1 AND a.id = s.app_id
+		RETURNING a.slug
+	`, token).Scan(&slug)
+	return slug, err
+}
+
+// DeleteSubscriber removes the subscription with a token and returns the slug of its
+// app, or sql.ErrNoRows
+func DeleteSubscriber(conn *sql.DB, token string) (string, error) {
+	var slug string
+	err := conn.QueryRow(`
+		DELETE FROM subscribers s
+		USING apps a
+		WHERE s.token = $1 AND a.id = s.app_id
+		RETURNING a.slug
+	`, token).Scan(&slug)
+	return slug, err
+}
+
+// DeleteStaleSubscribers removes email subscriptions that weren't confirmed within
+// a week
+func DeleteStaleSubscribers(conn *sql.DB) (int64, error) {
+	result, err := conn.Exec("DELETE FROM subscribers WHERE confirmed_at IS NULL AND created_at < NOW() - INTERVAL '7 days'")
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// GetConfirmedSubscribers returns the subscribers to notify of an app's changes.
+// Nobody is notified while the app's status page isn't public.
+func GetConfirmedSubscribers(conn *sql.DB, appId int) ([]Subscriber, error) {
+	rows, err := conn.Query(`
+		SELECT `+subscriberColumns+` FROM subscribers
+		WHERE app_id = $1 AND confirmed_at IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM app_access x WHERE x.app_id = subscribers.app_id)
+		ORDER BY id
+	`, appId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscribers := []Subscriber{}
+	for rows.Next() {
+		s, err := scanSubscriber(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscribers = append(subscribers, *s)
+	}
+	return subscribers, rows.Err()
+}
+
+// SubscribedApp is an app with confirmed subscribers, its latest check and what was
+// last announced to them
+type SubscribedApp struct {
+	AppId       int
+	AppName     string
+	Slug        string
+	StatusCode  int
+	ShowCode    bool // whether the status page shows status codes
+	CheckedAt   time.Time
+	Announced   *string // nil until the first status is recorded
+	AnnouncedAt *time.Time
+}
+
+// GetSubscribedApps returns the apps with confirmed subscribers whose status can be
+// announced: checked at least once, not paused and not in maintenance
+func GetSubscribedApps(conn *sql.DB) ([]SubscribedApp, error) {
+	rows, err := conn.Query(`
+		SELECT a.id, a.app_name, a.slug, latest.status_code, COALESCE(f.status_code, true),
+			latest.checked_at, n.status, n.announced_at
+		FROM apps a
+		JOIN LATERAL (
+			SELECT status_code, checked_at FROM user_status WHERE app_id = a.id ORDER BY checked_at DESC LIMIT 1
+		) latest ON true
+		LEFT JOIN subscriber_announcements n ON n.app_id = a.id
+		LEFT JOIN app_public_fields f ON f.app_id = a.id
+		WHERE NOT a.paused
+		  AND EXISTS (SELECT 1 FROM subscribers s WHERE s.app_id = a.id AND s.confirmed_at IS NOT NULL)
+		  AND NOT EXISTS (
+		    SELECT 1 FROM maintenance_windows w
+		    WHERE w.app_id = a.id AND w.starts_at <= NOW() AND w.ends_at > NOW()
+		  )
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	apps := []SubscribedApp{}
+	for rows.Next() {
+		var a SubscribedApp
+		if err := rows.Scan(&a.AppId, &a.AppName, &a.Slug, &a.StatusCode, &a.ShowCode, &a.CheckedAt, &a.Announced, &a.AnnouncedAt); err != nil {
+			return nil, err
+		}
+		apps = append(apps, a)
+	}
+	return apps, rows.Err()
+}
+
+// SetAnnouncedStatus records the status last announced to an app's subscribers
+func SetAnnouncedStatus(conn *sql.DB, appId int, status string) error {
+	_, err := conn.Exec(`
+		INSERT INTO subscriber_announcements (app_id, status) VALUES ($1, $2)
+		ON CONFLICT (app_id) DO UPDATE SET status = EXCLUDED.status, announced_at = NOW()
+	`, appId, status)
+	return err
+}
+
+// ========== PAGE VISIBILITY ==========
+
+// Who can see an app's status page
+const (
+	VisibilityPublic      = "public"
+	VisibilityPassword    = "password"     // visitors who know the password
+	VisibilityIPAllowlist = "ip_allowlist" // visitors from the listed addresses
+	VisibilityMembers     = "members"      // signed-in members of the app's organization
+)
+
+// Visibilities lists the visibilities in the order they are documented
+var Visibilities = []string{VisibilityPublic, VisibilityPassword, VisibilityIPAllowlist, VisibilityMembers}
+
+// AppAccess is who can see an app's status page. Anyone who has a share link signed
+// with ShareKey can see it too.
+type AppAccess struct {
+	AppId        int        `json:"app_id"`
+	Visibility   string     `json:"visibility"`
+	PasswordHash string     `json:"-"`
+	IPAllowlist  []string   `json:"ip_allowlist"` // addresses and CIDR ranges
+	ShareKey     string     `json:"-"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"` // nil for public pages
+}
+
+// Public reports whether anyone can see the page
+func (a *AppAccess) Public() bool {
+	return a.Visibility == VisibilityPublic
+}
+
+// GetAppAccess returns who can see an app's status page. Pages without settings are public.
+func GetAppAccess(conn *sql.DB, appId int) (*AppAccess, error) {
+	a := AppAccess{AppId: appId}
+	var passwordHash sql.NullString
+	var updatedAt time.Time
+	err := conn.QueryRow(
+		"SELECT visibility, password_hash, ip_allowlist, share_key, updated_at FROM app_access WHERE app_id = $1",
+		appId,
+	).Scan(&a.Visibility, &passwordHash, pq.Array(&a.IPAllowlist), &a.ShareKey, &updatedAt)
+	if err == sql.ErrNoRows {
+		return &AppAccess{AppId: appId, Visibility: VisibilityPublic, IPAllowlist: []string{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	a.PasswordHash = passwordHash.String
+	a.UpdatedAt = &updatedAt
+	if a.IPAllowlist == nil {
+		a.IPAllowlist = []string{}
+	}
+	return &a, nil
+}
+
+// SaveAppAccess sets who can see an app's status page. A public page has its settings
+// removed, so its share key is gone and the links signed with it stop working.
+func SaveAppAccess(conn *sql.DB, a *AppAccess) error {
+	if a.Public() {
+		_, err := conn.Exec("DELETE FROM app_access WHERE app_id = $1", a.AppId)
+		return err
+	}
+
+	var passwordHash *string
+	if a.PasswordHash != "" {
+		passwordHash = &a.PasswordHash
+	}
+	_, err := conn.Exec(`
+		INSERT INTO app_access (app_id, visibility, password_hash, ip_allowlist, share_key)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (app_id) DO UPDATE
+		SET visibility = EXCLUDED.visibility, password_hash = EXCLUDED.password_hash,
+		    ip_allowlist = EXCLUDED.ip_allowlist, share_key = EXCLUDED.share_key, updated_at = NOW()
+	`, a.AppId, a.Visibility, passwordHash, pq.Array(a.IPAllowlist), a.ShareKey)
+	return err
+}
+
+// PublicFields is which details an app's status page shows besides its status
+type PublicFields struct {
+	StatusCode    bool `json:"status_code"`    // the HTTP status of the last check
+	ResponseTime  bool `json:"response_time"`  // live response time measurements
+	Uptime        bool `json:"uptime"`         // the 24-hour uptime, also on badges
+	UptimeHistory bool `json:"uptime_history"` // the daily uptime bars
+}
+
+// AllPublicFields shows every detail, like pages without settings do
+func AllPublicFields() PublicFields {
+	return PublicFields{StatusCode: true, ResponseTime: true, Uptime: true, UptimeHistory: true}
+}
+
+// GetPublicFields returns which details an app's status page shows
+func GetPublicFields(conn *sql.DB, appId int) (PublicFields, error) {
+	var f PublicFields
+	err := conn.QueryRow(
+		"SELECT status_code, response_time, uptime, uptime_history FROM app_public_fields WHERE app_id = $1",
+		appId,
+	).Scan(&f.StatusCode, &f.ResponseTime, &f.Uptime, &f.UptimeHistory)
+	if err == sql.ErrNoRows {
+		return AllPublicFields(), nil
+	}
+	return f, err
+}
+
+// SavePublicFields sets which details an app's status page shows
+func SavePublicFields(conn *sql.DB, appId int, f PublicFields) error {
+	_, err := conn.Exec(`
+		INSERT INTO app_public_fields (app_id, status_code, response_time, uptime, uptime_history)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (app_id) DO UPDATE
+		SET status_code = EXCLUDED.status_code, response_time = EXCLUDED.response_time,
+		    uptime = EXCLUDED.uptime, uptime_history = EXCLUDED.uptime_history, updated_at = NOW()
+	`, appId, f.StatusCode, f.ResponseTime, f.Uptime, f.UptimeHistory)
+	return err
+}
+
+// ========== LOGIN FUNCTIONS ==========
+
+// GetUserIdByIdentity finds the user who signed in with a provider account before.
+// Returns 0 if the identity isn't linked to anyone yet.
+func GetUserIdByIdentity(conn *sql.DB, provider, subject string) (int, error) {
+	var userId int
+	err := conn.QueryRow(
+		"SELECT user_id FROM user_identities WHERE provider = $1 AND subject = $2",
+		provider, subject,
+	).Scan(&userId)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return userId, err
+}
+
+// LinkUserIdentity links a provider account to a user, or records a new login with it
+func LinkUserIdentity(conn *sql.DB, userId int, provider, subject, email string) error {
+	_, err := conn.Exec(`
+		INSERT INTO user_identities (user_id, provider, subject, email)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (provider, subject) DO UPDATE SET email = EXCLUDED.email, last_login_at = NOW()
+	`, userId, provider, subject, email)
+	return err
+}
+
+// CreateMagicLink stores a sign-in link for email by the hash of its token
+func CreateMagicLink(conn *sql.DB, email, tokenHash string, expiresAt time.Time) error {
+	_, err := conn.Exec(
+		"INSERT INTO magic_links (email, token_hash, expires_at) VALUES ($1, $2, $3)",
+		email, tokenHash, expiresAt,
+	)
+	return err
+}
+
+// CountRecentMagicLinks counts the links sent to email since a point in time
+func CountRecentMagicLinks(conn *sql.DB, email string, since time.Time) (int, error) {
+	var count int
+	err := conn.QueryRow(
+		"SELECT COUNT(*) FROM magic_links WHERE email = $1 AND created_at > $2",
+		email, since,
+	).Scan(&count)
+	return count, err
+}
+
+// ConsumeMagicLink marks an unused, unexpired link as used and returns its email.
+// Returns "" if the token is unknown, used or expired, so each link works once.
+func ConsumeMagicLink(conn *sql.DB, tokenHash string) (string, error) {
+	var email string
+	err := conn.QueryRow(`
+		UPDATE magic_links SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING email
+	`, tokenHash).Scan(&email)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return email, err
+}
+
+// ========== ORGANIZATION FUNCTIONS ==========
+
+// Member roles, from most to least privileged
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+var roleRank = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+	RoleOwner:  4,
+}
+
+var (
+	// ErrLastOwner is returned when a change would leave an organization without an owner
+	ErrLastOwner = errors.New("organization must keep at least one owner")
+	// ErrInvitationExpired is returned when accepting an invitation past its expiry
+	ErrInvitationExpired = errors.New("invitation has expired")
+	// ErrInvitationEmail is returned when an invitation is accepted by a different address
+	ErrInvitationEmail = errors.New("invitation was sent to a different email address")
+)
+
+// ValidRole reports whether role is one of the member roles
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// RoleAtLeast reports whether role grants at least the permissions of min
+func RoleAtLeast(role, min string) bool {
+	return roleRank[role] >= roleRank[min] && roleRank[role] > 0
+}
+
+// Organization owns apps, integrations and billing. Role is the caller's role when listed for a user.
+type Organization struct {
+	Id        int       `json:"id"`
+	Name      string    `json:"name"`
+	Personal  bool      `json:"personal"`
+	Plan      string    `json:"plan"`
+	CreatedAt time.Time `json:"created_at"`
+	Role      string    `json:"role,omitempty"`
+}
+
+// OrgMember is a user's membership in an organization
+type OrgMember struct {
+	UserId    int       `json:"user_id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	AvatarUrl string    `json:"avatar_url"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OrgInvitation is a pending invitation. The token itself is only known when it is created.
+type OrgInvitation struct {
+	Id        int       `json:"id"`
+	OrgId     int       `json:"org_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	InvitedBy *int      `json:"invited_by"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// EnsurePersonalOrg returns the user's personal organization, creating it on first login
+func EnsurePersonalOrg(conn *sql.DB, userId int, name string) (int, error) {
+	_, err := conn.Exec(
+		"INSERT INTO organizations (name, personal_user_id) VALUES ($1, $2) ON CONFLICT (personal_user_id) DO NOTHING",
+		name, userId,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var orgId int
+	if err := conn.QueryRow("SELECT id FROM organizations WHERE personal_user_id = $1", userId).Scan(&orgId); err != nil {
+		return 0, err
+	}
+
+	_, err = conn.Exec(
+		"INSERT INTO org_members (org_id, user_id, role) VALUES ($1, $2, 'owner') ON CONFLICT DO NOTHING",
+		orgId, userId,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return orgId, nil
+}
+
+// CreateOrg creates a team organization with the creator as its owner
+func CreateOrg(conn *sql.DB, name string, ownerId int) (*Organization, error) {
+	tx, err := conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	org := Organization{Name: name, Role: RoleOwner}
+	err = tx.QueryRow(
+		"INSERT INTO organizations (name) VALUES ($1) RETURNING id, plan, created_at",
+		name,
+	).Scan(&org.Id, &org.Plan, &org.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(
+		"INSERT INTO org_members (org_id, user_id, role) VALUES ($1, $2, 'owner')",
+		org.Id, ownerId,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// GetUserOrgs lists the organizations a user belongs to, personal organization first
+func GetUserOrgs(conn *sql.DB, userId int) ([]Organization, error) {
+	rows, err := conn.Query(`
+		SELECT o.id, o.name, o.personal_user_id IS NOT NULL, o.plan, o.created_at, m.role
+		FROM organizations o
+		JOIN org_members m ON m.org_id = o.id
+		WHERE m.user_id = $1
+		ORDER BY o.personal_user_id IS NULL, o.name
+	`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []Organization{}
+	for rows.Next() {
+		var org Organization
+		if err := rows.Scan(&org.Id, &org.Name, &org.Personal, &org.Plan, &org.CreatedAt, &org.Role); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
+// GetOrg loads an organization by id
+func GetOrg(conn *sql.DB, orgId int) (*Organization, error) {
+	var org Organization
+	err := conn.QueryRow(
+		"SELECT id, name, personal_user_id IS NOT NULL, plan, created_at FROM organizations WHERE id = $1",
+		orgId,
+	).Scan(&org.Id, &org.Name, &org.Personal, &org.Plan, &org.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// GetOrgRole returns the user's role in an organization, or "" if they are not a member
+func GetOrgRole(conn *sql.DB, orgId, userId int) (string, error) {
+	var role string
+	err := conn.QueryRow(
+		"SELECT role FROM org_members WHERE org_id = $1 AND user_id = $2",
+		orgId, userId,
+	).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// GetOrgAccess returns the user's role in the organization, or "" if they aren't a member.
+// needs2FA is true when the organization requires two-factor authentication and the
+// user hasn't turned it on.
+func GetOrgAccess(conn *sql.DB, orgId, userId int) (role string, needs2FA bool, err error) {
+	err = conn.QueryRow(`
+		SELECT m.role, o.require_2fa AND u.totp_enabled_at IS NULL
+		FROM org_members m
+		JOIN organizations o ON o.id = m.org_id
+		JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = $1 AND m.user_id = $2
+	`, orgId, userId).Scan(&role, &needs2FA)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	return role, needs2FA, err
+}
+
+// GetOrgRequire2FA reports whether the organization requires two-factor authentication
+func GetOrgRequire2FA(conn *sql.DB, orgId int) (bool, error) {
+	var require bool
+	err := conn.QueryRow("SELECT require_2fa FROM organizations WHERE id = $1", orgId).Scan(&require)
+	return require, err
+}
+
+// SetOrgRequire2FA turns the two-factor requirement of a team organization on or off
+func SetOrgRequire2FA(conn *sql.DB, orgId int, require bool) error {
+	_, err := conn.Exec(
+		"UPDATE organizations SET require_2fa = $2 WHERE id = $1 AND personal_user_id IS NULL",
+		orgId, require,
+	)
+	return err
+}
+
+// GetOrgMembersWithout2FA lists the members who haven't turned on two-factor authentication
+func GetOrgMembersWithout2FA(conn *sql.DB, orgId int) ([]OrgMember, error) {
+	rows, err := conn.Query(`
+		SELECT u.id, u.username, u.email, COALESCE(u.avatar_url, ''), m.role, m.created_at
+		FROM org_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = $1 AND u.totp_enabled_at IS NULL
+		ORDER BY m.created_at
+	`, orgId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []OrgMember{}
+	for rows.Next() {
+		var m OrgMember
+		if err := rows.Scan(&m.UserId, &m.Username, &m.Email, &m.AvatarUrl, &m.Role, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// GetPersonalOrgId returns the id of the user's personal organization
+func GetPersonalOrgId(conn *sql.DB, userId int) (int, error) {
+	var orgId int
+	err := conn.QueryRow("SELECT id FROM organizations WHERE personal_user_id = $1", userId).Scan(&orgId)
+	return orgId, err
+}
+
+// GetOrgMembers lists the members of an organization
+func GetOrgMembers(conn *sql.DB, orgId int) ([]OrgMember, error) {
+	rows, err := conn.Query(`
+		SELECT u.id, u.username, u.email, COALESCE(u.avatar_url, ''), m.role, m.created_at
+		FROM org_members m
+		JOIN users u ON u.id = m.user_id
+		WHERE m.org_id = $1
+		ORDER BY m.created_at
+	`, orgId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []OrgMember{}
+	for rows.Next() {
+		var m OrgMember
+		if err := rows.Scan(&m.UserId, &m.Username, &m.Email, &m.AvatarUrl, &m.Role, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// lockOwnerChange locks the organization row and fails with ErrLastOwner if
+// userId is its only owner. Serializes concurrent demotions of the last owners.
+func lockOwnerChange(tx *sql.Tx, orgId, userId int) error {
+	if _, err := tx.Exec("SELECT id FROM organizations WHERE id = $1 FOR UPDATE", orgId); err != nil {
+		return err
+	}
+	var otherOwners int
+	err := tx.QueryRow(
+		"SELECT COUNT(*) FROM org_members WHERE org_id = $1 AND role = 'owner' AND user_id != $2",
+		orgId, userId,
+	).Scan(&otherOwners)
+	if err != nil {
+		return err
+	}
+	if otherOwners == 0 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// UpdateOrgMemberRole changes a member's role. Returns false if the user is not a member.
+func UpdateOrgMemberRole(conn *sql.DB, orgId, userId int, role string) (bool, error) {
+	tx, err := conn.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if role != RoleOwner {
+		if err := lockOwnerChange(tx, orgId, userId); err != nil {
+			return false, err
+		}
+	}
+
+	result, err := tx.Exec(
+		"UPDATE org_members SET role = $1 WHERE org_id = $2 AND user_id = $3",
+		role, orgId, userId,
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, tx.Commit()
+}
+
+// RemoveOrgMember removes a member from an organization. Returns false if the user is not a member.
+func RemoveOrgMember(conn *sql.DB, orgId, userId int) (bool, error) {
+	tx, err := conn.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if err := lockOwnerChange(tx, orgId, userId); err != nil {
+		return false, err
+	}
+
+	result, err := tx.Exec("DELETE FROM org_members WHERE org_id = $1 AND user_id = $2", orgId, userId)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, tx.Commit()
+}
+
+// CreateOrgInvitation stores an invitation by its token hash, replacing any pending one for the same address
+func CreateOrgInvitation(conn *sql.DB, orgId int, email, role, tokenHash string, invitedBy int, expiresAt time.Time) (*OrgInvitation, error) {
+	inv := OrgInvitation{OrgId: orgId, Email: email, Role: role, InvitedBy: &invitedBy, ExpiresAt: expiresAt}
+	err := conn.QueryRow(`
+		INSERT INTO org_invitations (org_id, email, role, token_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (org_id, lower(email)) WHERE accepted_at IS NULL DO UPDATE
+		SET role = EXCLUDED.role,
+		    token_hash = EXCLUDED.token_hash,
+		    invited_by = EXCLUDED.invited_by,
+		    created_at = NOW(),
+		    expires_at = EXCLUDED.expires_at
+		RETURNING id, created_at
+	`, orgId, email, role, tokenHash, invitedBy, expiresAt).Scan(&inv.Id, &inv.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// GetOrgInvitations lists the pending invitations of an organization
+func GetOrgInvitations(conn *sql.DB, orgId int) ([]OrgInvitation, error) {
+	rows, err := conn.Query(`
+		SELECT id, org_id, email, role, invited_by, created_at, expires_at
+		FROM org_invitations
+		WHERE org_id = $1 AND accepted_at IS NULL
+		ORDER BY created_at DESC
+	`, orgId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []OrgInvitation{}
+	for rows.Next() {
+		var inv OrgInvitation
+		if err := rows.Scan(&inv.Id, &inv.OrgId, &inv.Email, &inv.Role, &inv.InvitedBy, &inv.CreatedAt, &inv.ExpiresAt); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+	return invitations, rows.Err()
+}
+
+// DeleteOrgInvitation revokes a pending invitation. Returns false if there is no such invitation.
+func DeleteOrgInvitation(conn *sql.DB, orgId, invitationId int) (bool, error) {
+	result, err := conn.Exec(
+		"DELETE FROM org_invitations WHERE id = $1 AND org_id = $2 AND accepted_at IS NULL",
+		invitationId, orgId,
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// AcceptOrgInvitation adds the user to the invitation's organization and returns it.
+// Returns nil if the token is unknown or already used.
+func AcceptOrgInvitation(conn *sql.DB, tokenHash string, userId int, email string) (*Organization, error) {
+	tx, err := conn.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var invitationId int
+	var invitedEmail, role string
+	var expiresAt time.Time
+	err = tx.QueryRow(`
+		SELECT id, email, role, expires_at
+		FROM org_invitations
+		WHERE token_hash = $1 AND accepted_at IS NULL
+		FOR UPDATE
+	`, tokenHash).Scan(&invitationId, &invitedEmail, &role, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(expiresAt) {
+		return nil, ErrInvitationExpired
+	}
+	if !strings.EqualFold(invitedEmail, email) {
+		return nil, ErrInvitationEmail
+	}
+
+	var org Organization
+	err = tx.QueryRow(`
+		UPDATE org_invitations SET accepted_at = NOW() WHERE id = $1
+		RETURNING org_id
+	`, invitationId).Scan(&org.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	// An existing member keeps their current role
+	if _, err := tx.Exec(
+		"INSERT INTO org_members (org_id, user_id, role) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+		org.Id, userId, role,
+	); err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRow(`
+		SELECT o.name, o.personal_user_id IS NOT NULL, o.plan, o.created_at, m.role
+		FROM organizations o
+		JOIN org_members m ON m.org_id = o.id AND m.user_id = $2
+		WHERE o.id = $1
+	`, org.Id, userId).Scan(&org.Name, &org.Personal, &org.Plan, &org.CreatedAt, &org.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// ========== STRIPE SUBSCRIPTION MANAGEMENT ==========
+
+// UpdateOrgSubscription updates an organization's Stripe subscription information
+func UpdateOrgSubscription(conn *sql.DB, orgId int, plan, stripeCustomerId, stripeSubscriptionId string) error {
+	_, err := conn.Exec(
+		`UPDATE organizations 
+		SET plan = $1, 
+		    stripe_customer_id = $2, 
+		    stripe_subscription_id = $3,
+		    plan_started_at = NOW()
+		WHERE id = $4`,
+		plan,
+		stripeCustomerId,
+		stripeSubscriptionId,
+		orgId,
+	)
+	return err
+}
+
+// CancelOrgSubscription reverts an organization back to free plan
+func CancelOrgSubscription(conn *sql.DB, orgId int) error {
+	_, err := conn.Exec(
+		`UPDATE organizations 
+		SET plan = 'free', 
+		    stripe_subscription_id = NULL,
+		    plan_started_at = NOW()
+		WHERE id = $1`,
+		orgId,
+	)
+	return err
+}
+
+// GetOrgByStripeCustomerId finds an organization by its Stripe customer ID
+func GetOrgByStripeCustomerId(conn *sql.DB, stripeCustomerId string) (*Organization, error) {
+	var org Organization
+	err := conn.QueryRow(
+		"SELECT id, name, personal_user_id IS NOT NULL, plan, created_at FROM organizations WHERE stripe_customer_id = $1",
+		stripeCustomerId,
+	).Scan(&org.Id, &org.Name, &org.Personal, &org.Plan, &org.CreatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// GetStripeCustomerId gets the Stripe customer ID for an organization
+func GetStripeCustomerId(conn *sql.DB, orgId int) (string, error) {
+	var customerId sql.NullString
+	err := conn.QueryRow(
+		"SELECT stripe_customer_id FROM organizations WHERE id = $1",
+		orgId,
+	).Scan(&customerId)
+
+	if err != nil {
+		return "", err
+	}
+
+	if customerId.Valid {
+		return customerId.String, nil
+	}
+	return "", nil
+}
+
+// SetStripeCustomerId stores the Stripe customer created for an organization
+func SetStripeCustomerId(conn *sql.DB, orgId int, stripeCustomerId string) error {
+	_, err := conn.Exec(
+		"UPDATE organizations SET stripe_customer_id = $1 WHERE id = $2",
+		stripeCustomerId,
+		orgId,
+	)
+	return err
+}
+
+// CleanupOldStatusChecks removes status checks older than the retention period for each
+// plan. Organizations on a plan that isn't configured keep the free plan's history, like
+// GetPlanFeatures gives them.
+func CleanupOldStatusChecks(conn *sql.DB) error {
+	plans := make([]string, 0, len(planFeatures))
+	for plan := range planFeatures {
+		plans = append(plans, plan)
+	}
+	sort.Strings(plans)
+
+	totalDeleted := 0
+	deleteOlder := func(plan, orgFilter string, arg interface{}, days int) {
+		result, err := conn.Exec(`
+			DELETE FROM user_status
+			WHERE app_id IN (
+				SELECT a.id FROM apps a
+				JOIN organizations o ON a.org_id = o.id
+				WHERE `+orgFilter+`
+			)
+			AND checked_at < NOW() - INTERVAL '1 day' * $2
+		`, arg, days)
+
+		if err != nil {
+			log.Printf("❌ Error cleaning up %s plan data: %v", plan, err)
+			return
+		}
+
+		rows, _ := result.RowsAffected()
+		if rows > 0 {
+			log.Printf("🧹 Cleaned up %d old status checks for %s plan (>%d days)", rows, plan, days)
+			totalDeleted += int(rows)
+		}
+	}
+
+	for _, plan := range plans {
+		deleteOlder(plan, "o.plan = $1", plan, planFeatures[plan].DataRetentionDays)
+	}
+	deleteOlder("unknown", "o.plan <> ALL($1)", pq.Array(plans), GetPlanFeatures("free").DataRetentionDays)
+
+	if totalDeleted > 0 {
+		log.Printf("✅ Total cleanup: removed %d old status checks", totalDeleted)
+	}
+
+	// Per-region results only feed the quorum decision, so a short window is enough
+	result, err := conn.Exec("DELETE FROM region_checks WHERE checked_at < NOW() - INTERVAL '7 days'")
+	if err != nil {
+		log.Printf("❌ Error cleaning up region checks: %v", err)
+	} else if rows, _ := result.RowsAffected(); rows > 0 {
+		log.Printf("🧹 Cleaned up %d old region checks (>7 days)", rows)
+	}
+
+	return nil
+}
+
+// UpdateSSLInfo updates SSL certificate information for an app
+func UpdateSSLInfo(conn *sql.DB, appId int, expiryDate *time.Time, daysUntilExpiry *int, issuer *string) error {
+	query := `
+		UPDATE apps 
+		SET ssl_expiry_date = $1, 
+		    ssl_days_until_expiry = $2,
+		    ssl_issuer = $3,
+		    ssl_last_checked = NOW()
+		WHERE id = $4
+	`
+	_, err := conn.Exec(query, expiryDate, daysUntilExpiry, issuer, appId)
+	return err
+}
+
+// ========== SLACK INTEGRATION FUNCTIONS ==========
+
+// SlackIntegration represents a Slack integration
+type SlackIntegration struct {
+	ID               int    `json:"id"`
+	OrgID            int    `json:"org_id"`
+	UserID           int    `json:"user_id"` // who connected the integration
+	SlackTeamID      string `json:"slack_team_id"`
+	SlackTeamName    string `json:"slack_team_name"`
+	SlackBotToken    string `json:"slack_bot_token,omitempty"` // Don't expose token in API
+	SlackChannelID   string `json:"slack_channel_id"`
+	SlackChannelName string `json:"slack_channel_name"`
+	IsEnabled        bool   `json:"is_enabled"`
+	CreatedAt        string `json:"created_at"`
+	UpdatedAt        string `json:"updated_at"`
+}
+
+// SaveSlackIntegration saves or updates the Slack integration of an organization
+func SaveSlackIntegration(conn *sql.DB, orgID, userID int, botToken, teamID, teamName, channelID, channelName string) (*SlackIntegration, error) {
+	query := `
+		INSERT INTO slack_integrations (org_id, user_id, slack_team_id, slack_team_name, slack_bot_token, slack_channel_id, slack_channel_name, is_enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, true)
+		ON CONFLICT (org_id) DO UPDATE
+		SET user_id = $2,
+		    slack_team_id = $3,
+		    slack_team_name = $4,
+		    slack_bot_token = $5,
+		    slack_channel_id = $6,
+		    slack_channel_name = $7,
+		    is_enabled = true,
+		    updated_at = NOW()
+		RETURNING id, org_id, user_id, slack_team_id, slack_team_name, slack_channel_id, slack_channel_name, is_enabled, created_at, updated_at
+	`
+
+	var integration SlackIntegration
+	err := conn.QueryRow(query, orgID, userID, teamID, teamName, botToken, channelID, channelName).Scan(
+		&integration.ID,
+		&integration.OrgID,
+		&integration.UserID,
+		&integration.SlackTeamID,
+		&integration.SlackTeamName,
+		&integration.SlackChannelID,
+		&integration.SlackChannelName,
+		&integration.IsEnabled,
+		&integration.CreatedAt,
+		&integration.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("error saving Slack integration: %w", err)
+	}
+
+	return &integration, nil
+}
+
+// GetSlackIntegration retrieves an organization's Slack integration (without bot token for security)
+func GetSlackIntegration(conn *sql.DB, orgID int) (*SlackIntegration, error) {
+	query := `
+		SELECT id, org_id, user_id, slack_team_id, slack_team_name, slack_channel_id, slack_channel_name, is_enabled, created_at, updated_at
+		FROM slack_integrations
+		WHERE org_id = $1
+	`
+
+	var integration SlackIntegration
+	err := conn.QueryRow(query, orgID).Scan(
+		&integration.ID,
+		&integration.OrgID,
+		&integration.UserID,
+		&integration.SlackTeamID,
+		&integration.SlackTeamName,
+		&integration.SlackChannelID,
+		&integration.SlackChannelName,
+		&integration.IsEnabled,
+		&integration.CreatedAt,
+		&integration.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil // No integration found
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving Slack integration: %w", err)
+	}
+
+	return &integration, nil
+}
+
+// GetSlackIntegrationByAppID retrieves Slack integration using app ID (with bot token for internal use)
+func GetSlackIntegrationByAppID(conn *sql.DB, appID int) (*SlackIntegration, error) {
+	query := `
+		SELECT 
+			si.id, si.org_id, si.user_id, si.slack_team_id, si.slack_team_name, 
+			si.slack_bot_token, si.slack_channel_id, si.slack_channel_name, 
+			si.is_enabled, si.created_at, si.updated_at
+		FROM slack_integrations si
+		JOIN apps a ON a.org_id = si.org_id
+		WHERE a.id = $1 AND si.is_enabled = true
+	`
+
+	var integration SlackIntegration
+	err := conn.QueryRow(query, appID).Scan(
+		&integration.ID,
+		&integration.OrgID,
+		&integration.UserID,
+		&integration.SlackTeamID,
+		&integration.SlackTeamName,
+		&integration.SlackBotToken,
+		&integration.SlackChannelID,
+		&integration.SlackChannelName,
+		&integration.IsEnabled,
+		&integration.CreatedAt,
+		&integration.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving Slack integration: %w", err)
+	}
+
+	return &integration, nil
+}
+
+// DisableSlackIntegration disables the Slack integration of an organization
+func DisableSlackIntegration(conn *sql.DB, orgID int) error {
+	_, err := conn.Exec(
+		"UPDATE slack_integrations SET is_enabled = false, updated_at = NOW() WHERE org_id = $1",
+		orgID,
+	)
+	return err
+}
+
+// DeleteSlackIntegration removes the Slack integration of an organization
+func DeleteSlackIntegration(conn *sql.DB, orgID int) error {
+	_, err := conn.Exec(
+		"DELETE FROM slack_integrations WHERE org_id = $1",
+		orgID,
+	)
+	return err
+}
+
+// LogIncidentNotification logs a notification event
+func LogIncidentNotification(conn *sql.DB, appID int, notificationType, status string) error {
+	query := `
 		INSERT INTO incident_notifications (app_id, notification_type, status)
 		VALUES ($1, $2, $3)
 	`
@@ -978,149 +2881,992 @@ func LogIncidentNotification(conn *sql.DB, appID int, notificationType, status s
 	return err
 }
 
-// ========== DISCORD INTEGRATION FUNCTIONS ==========
+// ========== DISCORD INTEGRATION FUNCTIONS ==========
+
+// DiscordIntegration represents a Discord integration
+type DiscordIntegration struct {
+	ID              int    `json:"id"`
+	OrgID           int    `json:"org_id"`
+	UserID          int    `json:"user_id"` // who connected the integration
+	DiscordUserID   string `json:"discord_user_id"`
+	DiscordUsername string `json:"discord_username"`
+	WebhookURL      string `json:"webhook_url,omitempty"` // Don't expose webhook URL in API
+	ServerID        string `json:"server_id"`
+	ServerName      string `json:"server_name"`
+	ChannelID       string `json:"channel_id"`
+	ChannelName     string `json:"channel_name"`
+	IsEnabled       bool   `json:"is_enabled"`
+	CreatedAt       string `json:"created_at"`
+	UpdatedAt       string `json:"updated_at"`
+}
+
+// SaveDiscordIntegration saves or updates the Discord integration of an organization
+func SaveDiscordIntegration(conn *sql.DB, orgID, userID int, discordUserID, discordUsername, webhookURL, serverID, serverName, channelID, channelName string) (*DiscordIntegration, error) {
+	query := `
+		INSERT INTO discord_integrations (org_id, user_id, discord_user_id, discord_username, webhook_url, server_id, server_name, channel_id, channel_name, is_enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, true)
+		ON CONFLICT (org_id) DO UPDATE
+		SET user_id = $2,
+		    discord_user_id = $3,
+		    discord_username = $4,
+		    webhook_url = $5,
+		    server_id = $6,
+		    server_name = $7,
+		    channel_id = $8,
+		    channel_name = $9,
+		    is_enabled = true,
+		    updated_at = NOW()
+		RETURNING id, org_id, user_id, discord_user_id, discord_username, server_id, server_name, channel_id, channel_name, is_enabled, created_at, updated_at
+	`
+
+	var integration DiscordIntegration
+	err := conn.QueryRow(query, orgID, userID, discordUserID, discordUsername, webhookURL, serverID, serverName, channelID, channelName).Scan(
+		&integration.ID,
+		&integration.OrgID,
+		&integration.UserID,
+		&integration.DiscordUserID,
+		&integration.DiscordUsername,
+		&integration.ServerID,
+		&integration.ServerName,
+		&integration.ChannelID,
+		&integration.ChannelName,
+		&integration.IsEnabled,
+		&integration.CreatedAt,
+		&integration.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("error saving Discord integration: %w", err)
+	}
+
+	return &integration, nil
+}
+
+// GetDiscordIntegration retrieves an organization's Discord integration (without webhook URL for security)
+func GetDiscordIntegration(conn *sql.DB, orgID int) (*DiscordIntegration, error) {
+	query := `
+		SELECT id, org_id, user_id, discord_user_id, discord_username, server_id, server_name, channel_id, channel_name, is_enabled, created_at, updated_at
+		FROM discord_integrations
+		WHERE org_id = $1
+	`
+
+	var integration DiscordIntegration
+	err := conn.QueryRow(query, orgID).Scan(
+		&integration.ID,
+		&integration.OrgID,
+		&integration.UserID,
+		&integration.DiscordUserID,
+		&integration.DiscordUsername,
+		&integration.ServerID,
+		&integration.ServerName,
+		&integration.ChannelID,
+		&integration.ChannelName,
+		&integration.IsEnabled,
+		&integration.CreatedAt,
+		&integration.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil // No integration found
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving Discord integration: %w", err)
+	}
+
+	return &integration, nil
+}
+
+// GetDiscordIntegrationByAppID retrieves Discord integration using app ID (with webhook URL for internal use)
+func GetDiscordIntegrationByAppID(conn *sql.DB, appID int) (*DiscordIntegration, error) {
+	query := `
+		SELECT 
+			di.id, di.org_id, di.user_id, di.discord_user_id, di.discord_username, 
+			di.webhook_url, di.server_id, di.server_name, di.channel_id, 
+			di.channel_name, di.is_enabled, di.created_at, di.updated_at
+		FROM discord_integrations di
+		JOIN apps a ON a.org_id = di.org_id
+		WHERE a.id = $1 AND di.is_enabled = true
+	`
+
+	var integration DiscordIntegration
+	err := conn.QueryRow(query, appID).Scan(
+		&integration.ID,
+		&integration.OrgID,
+		&integration.UserID,
+		&integration.DiscordUserID,
+		&integration.DiscordUsername,
+		&integration.WebhookURL,
+		&integration.ServerID,
+		&integration.ServerName,
+		&integration.ChannelID,
+		&integration.ChannelName,
+		&integration.IsEnabled,
+		&integration.CreatedAt,
+		&integration.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving Discord integration: %w", err)
+	}
+
+	return &integration, nil
+}
+
+// DisableDiscordIntegration disables the Discord integration of an organization
+func DisableDiscordIntegration(conn *sql.DB, orgID int) error {
+	_, err := conn.Exec(
+		"UPDATE discord_integrations SET is_enabled = false, updated_at = NOW() WHERE org_id = $1",
+		orgID,
+	)
+	return err
+}
+
+// DeleteDiscordIntegration removes the Discord integration of an organization
+func DeleteDiscordIntegration(conn *sql.DB, orgID int) error {
+	_, err := conn.Exec(
+		"DELETE FROM discord_integrations WHERE org_id = $1",
+		orgID,
+	)
+	return err
+}
+
+// ========== MULTI-REGION PROBE FUNCTIONS ==========
+
+// ProbeAgent represents a registered remote probe agent
+type ProbeAgent struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Region     string     `json:"region"`
+	CreatedAt  string     `json:"created_at"`
+	LastSeenAt string     `json:"last_seen_at"`
+	DisabledAt *time.Time `json:"disabled_at"`
+}
+
+// RegionCheck represents a single health check result reported by a region
+type RegionCheck struct {
+	AppID          int       `json:"app_id"`
+	Region         string    `json:"region"`
+	StatusCode     int       `json:"status_code"`
+	ResponseTimeMs int64     `json:"response_time_ms"`
+	CheckedAt      time.Time `json:"checked_at"`
+}
+
+// ProbeAssignment is a check that probe agents should run
+type ProbeAssignment struct {
+	AppID           int    `json:"app_id"`
+	HealthURL       string `json:"health_url"`
+	IntervalSeconds int    `json:"interval_seconds"`
+}
+
+// RegisterProbeAgent creates or refreshes a probe agent by name
+func RegisterProbeAgent(conn *sql.DB, name, region string) (*ProbeAgent, error) {
+	query := `
+		INSERT INTO probe_agents (name, region)
+		VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE
+		SET region = $2,
+		    last_seen_at = NOW()
+		RETURNING id, name, region, created_at, last_seen_at, disabled_at
+	`
+
+	var agent ProbeAgent
+	err := conn.QueryRow(query, name, region).Scan(
+		&agent.ID,
+		&agent.Name,
+		&agent.Region,
+		&agent.CreatedAt,
+		&agent.LastSeenAt,
+		&agent.DisabledAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error registering probe agent: %w", err)
+	}
+
+	return &agent, nil
+}
+
+// GetProbeAgent retrieves a probe agent by ID
+func GetProbeAgent(conn *sql.DB, agentID int) (*ProbeAgent, error) {
+	var agent ProbeAgent
+	err := conn.QueryRow(
+		"SELECT id, name, region, created_at, last_seen_at, disabled_at FROM probe_agents WHERE id = $1",
+		agentID,
+	).Scan(&agent.ID, &agent.Name, &agent.Region, &agent.CreatedAt, &agent.LastSeenAt, &agent.DisabledAt)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving probe agent: %w", err)
+	}
+
+	return &agent, nil
+}
+
+// GetProbeAgents returns every registered probe agent, disabled ones included
+func GetProbeAgents(conn *sql.DB) ([]ProbeAgent, error) {
+	rows, err := conn.Query("SELECT id, name, region, created_at, last_seen_at, disabled_at FROM probe_agents ORDER BY region, name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	agents := []ProbeAgent{}
+	for rows.Next() {
+		var agent ProbeAgent
+		if err := rows.Scan(&agent.ID, &agent.Name, &agent.Region, &agent.CreatedAt, &agent.LastSeenAt, &agent.DisabledAt); err != nil {
+			return nil, err
+		}
+		agents = append(agents, agent)
+	}
+	return agents, rows.Err()
+}
+
+// SetProbeAgentDisabled disables or re-enables a probe agent. Returns false if the
+// agent doesn't exist or is already in that state.
+func SetProbeAgentDisabled(conn *sql.DB, agentID int, disabled bool) (bool, error) {
+	query := "UPDATE probe_agents SET disabled_at = NULL WHERE id = $1 AND disabled_at IS NOT NULL"
+	if disabled {
+		query = "UPDATE probe_agents SET disabled_at = NOW() WHERE id = $1 AND disabled_at IS NULL"
+	}
+	result, err := conn.Exec(query, agentID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// TouchProbeAgent updates the last seen timestamp of a probe agent
+func TouchProbeAgent(conn *sql.DB, agentID int) error {
+	_, err := conn.Exec("UPDATE probe_agents SET last_seen_at = NOW() WHERE id = $1", agentID)
+	return err
+}
+
+// GetProbeAssignments returns every app that probe agents should check along with its plan interval
+func GetProbeAssignments(conn *sql.DB) ([]ProbeAssignment, error) {
+	rows, err := conn.Query(`
+		SELECT a.id, a.health_url, o.plan, a.check_interval
+		FROM apps a
+		JOIN organizations o ON a.org_id = o.id
+		WHERE a.health_url != '' AND NOT a.paused
+		ORDER BY a.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var assignments []ProbeAssignment
+	for rows.Next() {
+		var assignment ProbeAssignment
+		var plan string
+		var interval sql.NullInt64
+		if err := rows.Scan(&assignment.AppID, &assignment.HealthURL, &plan, &interval); err != nil {
+			return nil, err
+		}
+		var checkInterval *int
+		if interval.Valid {
+			seconds := int(interval.Int64)
+			checkInterval = &seconds
+		}
+		assignment.IntervalSeconds = EffectiveCheckInterval(plan, checkInterval)
+		assignments = append(assignments, assignment)
+	}
+	return assignments, rows.Err()
+}
+
+// InsertRegionCheck records a health check result for a specific region
+func InsertRegionCheck(conn *sql.DB, appID int, region string, statusCode int, responseTimeMs int64, checkedAt time.Time) error {
+	_, err := conn.Exec(
+		"INSERT INTO region_checks (app_id, region, status_code, response_time_ms, checked_at) VALUES ($1, $2, $3, $4, $5)",
+		appID, region, statusCode, responseTimeMs, checkedAt,
+	)
+	return err
+}
+
+// GetLatestRegionChecks returns the most recent result per region for an app, ignoring results older than since
+func GetLatestRegionChecks(conn *sql.DB, appID int, since time.Time) ([]RegionCheck, error) {
+	query := `
+		SELECT DISTINCT ON (region) app_id, region, status_code, COALESCE(response_time_ms, 0), checked_at
+		FROM region_checks
+		WHERE app_id = $1 AND checked_at > $2
+		ORDER BY region, checked_at DESC
+	`
+	rows, err := conn.Query(query, appID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checks []RegionCheck
+	for rows.Next() {
+		var check RegionCheck
+		if err := rows.Scan(&check.AppID, &check.Region, &check.StatusCode, &check.ResponseTimeMs, &check.CheckedAt); err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+	return checks, rows.Err()
+}
+
+// ========== API KEY FUNCTIONS ==========
+
+// APIKey is a personal API key. The key itself is only known when it is created.
+type APIKey struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scope      string     `json:"scope"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// APIKeyOwner is what AuthMiddleware needs to authenticate a request made with a key
+type APIKeyOwner struct {
+	KeyID    int
+	UserID   int
+	Username string
+	Scope    string
+}
+
+// CreateAPIKey stores a new key by its hash
+func CreateAPIKey(conn *sql.DB, userId int, name, prefix, keyHash, scope string) (*APIKey, error) {
+	key := APIKey{UserID: userId, Name: name, Prefix: prefix, Scope: scope}
+	err := conn.QueryRow(`
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scope)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, userId, name, prefix, keyHash, scope).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetAPIKeys returns every key a user has created, newest first
+func GetAPIKeys(conn *sql.DB, userId int) ([]APIKey, error) {
+	rows, err := conn.Query(`
+		SELECT id, user_id, name, prefix, scope, created_at, last_used_at, revoked_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		var key APIKey
+		err := rows.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.Scope, &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// GetAPIKeyOwner finds the user behind an active key. Returns nil if the key is unknown or
+// revoked, or its owner is disabled.
+func GetAPIKeyOwner(conn *sql.DB, keyHash string) (*APIKeyOwner, error) {
+	var owner APIKeyOwner
+	err := conn.QueryRow(`
+		SELECT k.id, k.user_id, u.username, k.scope
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND u.disabled_at IS NULL
+	`, keyHash).Scan(&owner.KeyID, &owner.UserID, &owner.Username, &owner.Scope)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &owner, nil
+}
+
+// TouchAPIKey records that a key was used. Writes are limited to one a minute per key.
+func TouchAPIKey(conn *sql.DB, keyId int) error {
+	_, err := conn.Exec(`
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`, keyId)
+	return err
+}
+
+// RevokeAPIKey revokes one of the user's keys. Returns false if the user has no such active key.
+func RevokeAPIKey(conn *sql.DB, userId, keyId int) (bool, error) {
+	result, err := conn.Exec(
+		"UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL",
+		keyId, userId,
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// ========== SESSION FUNCTIONS ==========
+
+// UserSession is a signed-in browser. Sessions are found by a hash of their token,
+// which never leaves the session store.
+type UserSession struct {
+	ID         int       `json:"id"`
+	UserID     *int      `json:"-"`
+	TokenHash  string    `json:"-"`
+	Data       string    `json:"-"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// GetSession finds an unexpired session by its token hash. Returns nil if there is none,
+// which is also the case once it has been revoked.
+func GetSession(conn *sql.DB, tokenHash string) (*UserSession, error) {
+	var s UserSession
+	err := conn.QueryRow(`
+		SELECT id, user_id, token_hash, data, user_agent, ip_address, created_at, last_seen_at, expires_at
+		FROM user_sessions
+		WHERE token_hash = $1 AND expires_at > NOW()
+	`, tokenHash).Scan(&s.ID, &s.UserID, &s.TokenHash, &s.Data, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// CreateSession stores a new session
+func CreateSession(conn *sql.DB, s UserSession) error {
+	_, err := conn.Exec(`
+		INSERT INTO user_sessions (token_hash, user_id, data, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, s.TokenHash, s.UserID, s.Data, s.UserAgent, s.IPAddress, s.ExpiresAt)
+	return err
+}
+
+// UpdateSession stores new values for a session. Returns false if it has expired or been
+// revoked, so a request still in flight can't bring a revoked session back.
+func UpdateSession(conn *sql.DB, s UserSession) (bool, error) {
+	result, err := conn.Exec(`
+		UPDATE user_sessions
+		SET user_id = $2, data = $3, ip_address = $4, expires_at = $5, last_seen_at = NOW()
+		WHERE token_hash = $1 AND expires_at > NOW()
+	`, s.TokenHash, s.UserID, s.Data, s.IPAddress, s.ExpiresAt)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
 
-// DiscordIntegration represents a Discord integration
-type DiscordIntegration struct {
-	ID              int    `json:"id"`
-	UserID          int    `json:"user_id"`
-	DiscordUserID   string `json:"discord_user_id"`
-	DiscordUsername string `json:"discord_username"`
-	WebhookURL      string `json:"webhook_url,omitempty"` // Don't expose webhook URL in API
-	ServerID        string `json:"server_id"`
-	ServerName      string `json:"server_name"`
-	ChannelID       string `json:"channel_id"`
-	ChannelName     string `json:"channel_name"`
-	IsEnabled       bool   `json:"is_enabled"`
-	CreatedAt       string `json:"created_at"`
-	UpdatedAt       string `json:"updated_at"`
+// TouchSession records that a session was used. Writes are limited to one a minute per session.
+func TouchSession(conn *sql.DB, tokenHash, ipAddress string) error {
+	_, err := conn.Exec(`
+		UPDATE user_sessions SET last_seen_at = NOW(), ip_address = $2
+		WHERE token_hash = $1 AND last_seen_at < NOW() - INTERVAL '1 minute'
+	`, tokenHash, ipAddress)
+	return err
 }
 
-// SaveDiscordIntegration saves or updates a Discord integration for a user
-func SaveDiscordIntegration(conn *sql.DB, userID int, discordUserID, discordUsername, webhookURL, serverID, serverName, channelID, channelName string) (*DiscordIntegration, error) {
-	query := `
-		INSERT INTO discord_integrations (user_id, discord_user_id, discord_username, webhook_url, server_id, server_name, channel_id, channel_name, is_enabled)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, true)
-		ON CONFLICT (user_id) DO UPDATE
-		SET discord_user_id = $2,
-		    discord_username = $3,
-		    webhook_url = $4,
-		    server_id = $5,
-		    server_name = $6,
-		    channel_id = $7,
-		    channel_name = $8,
-		    is_enabled = true,
-		    updated_at = NOW()
-		RETURNING id, user_id, discord_user_id, discord_username, server_id, server_name, channel_id, channel_name, is_enabled, created_at, updated_at
-	`
+// DeleteSession removes a session, as when its user logs out
+func DeleteSession(conn *sql.DB, tokenHash string) error {
+	_, err := conn.Exec("DELETE FROM user_sessions WHERE token_hash = $1", tokenHash)
+	return err
+}
 
-	var integration DiscordIntegration
-	err := conn.QueryRow(query, userID, discordUserID, discordUsername, webhookURL, serverID, serverName, channelID, channelName).Scan(
-		&integration.ID,
-		&integration.UserID,
-		&integration.DiscordUserID,
-		&integration.DiscordUsername,
-		&integration.ServerID,
-		&integration.ServerName,
-		&integration.ChannelID,
-		&integration.ChannelName,
-		&integration.IsEnabled,
-		&integration.CreatedAt,
-		&integration.UpdatedAt,
+// GetUserSessions returns the user's signed-in sessions, most recently used first
+func GetUserSessions(conn *sql.DB, userId int) ([]UserSession, error) {
+	rows, err := conn.Query(`
+		SELECT id, user_id, token_hash, user_agent, ip_address, created_at, last_seen_at, expires_at
+		FROM user_sessions
+		WHERE user_id = $1 AND expires_at > NOW()
+		ORDER BY last_seen_at DESC
+	`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []UserSession{}
+	for rows.Next() {
+		var s UserSession
+		err := rows.Scan(&s.ID, &s.UserID, &s.TokenHash, &s.UserAgent, &s.IPAddress, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeSession signs out one of the user's sessions. Returns false if the user has no such session.
+func RevokeSession(conn *sql.DB, userId, sessionId int) (bool, error) {
+	result, err := conn.Exec("DELETE FROM user_sessions WHERE id = $1 AND user_id = $2", sessionId, userId)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// RevokeUserSessions signs the user out everywhere except the session with keepTokenHash,
+// which may be empty to sign out of every session. Returns how many were signed out.
+func RevokeUserSessions(conn *sql.DB, userId int, keepTokenHash string) (int64, error) {
+	result, err := conn.Exec(
+		"DELETE FROM user_sessions WHERE user_id = $1 AND token_hash <> $2",
+		userId, keepTokenHash,
 	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
 
+// DeleteExpiredSessions removes sessions past their expiry
+func DeleteExpiredSessions(conn *sql.DB) error {
+	result, err := conn.Exec("DELETE FROM user_sessions WHERE expires_at <= NOW()")
 	if err != nil {
-		return nil, fmt.Errorf("error saving Discord integration: %w", err)
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows > 0 {
+		log.Printf("🧹 Deleted %d expired sessions", rows)
 	}
+	return nil
+}
 
-	return &integration, nil
+// ========== TWO-FACTOR AUTHENTICATION ==========
+
+// TOTPState is a user's authenticator app setup. Secret is set once setup has started;
+// two-factor authentication is only on when EnabledAt is set.
+type TOTPState struct {
+	Secret    string
+	EnabledAt *time.Time
+	LastStep  int64
 }
 
-// GetDiscordIntegration retrieves a user's Discord integration (without webhook URL for security)
-func GetDiscordIntegration(conn *sql.DB, userID int) (*DiscordIntegration, error) {
-	query := `
-		SELECT id, user_id, discord_user_id, discord_username, server_id, server_name, channel_id, channel_name, is_enabled, created_at, updated_at
-		FROM discord_integrations
-		WHERE user_id = $1
-	`
+// GetTOTPState returns the user's authenticator setup
+func GetTOTPState(conn *sql.DB, userId int) (TOTPState, error) {
+	var state TOTPState
+	var secret sql.NullString
+	var lastStep sql.NullInt64
+	err := conn.QueryRow(
+		"SELECT totp_secret, totp_enabled_at, totp_last_step FROM users WHERE id = $1",
+		userId,
+	).Scan(&secret, &state.EnabledAt, &lastStep)
+	if err != nil {
+		return TOTPState{}, err
+	}
+	state.Secret = secret.String
+	state.LastStep = lastStep.Int64
+	return state, nil
+}
 
-	var integration DiscordIntegration
-	err := conn.QueryRow(query, userID).Scan(
-		&integration.ID,
-		&integration.UserID,
-		&integration.DiscordUserID,
-		&integration.DiscordUsername,
-		&integration.ServerID,
-		&integration.ServerName,
-		&integration.ChannelID,
-		&integration.ChannelName,
-		&integration.IsEnabled,
-		&integration.CreatedAt,
-		&integration.UpdatedAt,
+// SetPendingTOTPSecret starts setup with a new secret. Returns false if two-factor
+// authentication is already on, since that would lock the user out of their current app.
+func SetPendingTOTPSecret(conn *sql.DB, userId int, secret string) (bool, error) {
+	result, err := conn.Exec(
+		"UPDATE users SET totp_secret = $2, totp_last_step = NULL WHERE id = $1 AND totp_enabled_at IS NULL",
+		userId, secret,
 	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
 
-	if err == sql.ErrNoRows {
-		return nil, nil // No integration found
+// EnableTOTP turns two-factor authentication on once the user has confirmed a code from
+// step, and stores their recovery codes. Returns false if it was already on.
+func EnableTOTP(conn *sql.DB, userId int, step int64, codeHashes []string) (bool, error) {
+	tx, err := conn.Begin()
+	if err != nil {
+		return false, err
 	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE users SET totp_enabled_at = NOW(), totp_last_step = $2
+		WHERE id = $1 AND totp_enabled_at IS NULL AND totp_secret IS NOT NULL
+	`, userId, step)
 	if err != nil {
-		return nil, fmt.Errorf("error retrieving Discord integration: %w", err)
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil || rows == 0 {
+		return false, err
 	}
 
-	return &integration, nil
+	if err := replaceRecoveryCodes(tx, userId, codeHashes); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
 }
 
-// GetDiscordIntegrationByAppID retrieves Discord integration using app ID (with webhook URL for internal use)
-func GetDiscordIntegrationByAppID(conn *sql.DB, appID int) (*DiscordIntegration, error) {
-	query := `
-		SELECT 
-			di.id, di.user_id, di.discord_user_id, di.discord_username, 
-			di.webhook_url, di.server_id, di.server_name, di.channel_id, 
-			di.channel_name, di.is_enabled, di.created_at, di.updated_at
-		FROM discord_integrations di
-		JOIN apps a ON a.user_id = di.user_id
-		WHERE a.id = $1 AND di.is_enabled = true
-	`
+// DisableTOTP turns two-factor authentication off and deletes the recovery codes
+func DisableTOTP(conn *sql.DB, userId int) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	var integration DiscordIntegration
-	err := conn.QueryRow(query, appID).Scan(
-		&integration.ID,
-		&integration.UserID,
-		&integration.DiscordUserID,
-		&integration.DiscordUsername,
-		&integration.WebhookURL,
-		&integration.ServerID,
-		&integration.ServerName,
-		&integration.ChannelID,
-		&integration.ChannelName,
-		&integration.IsEnabled,
-		&integration.CreatedAt,
-		&integration.UpdatedAt,
+	_, err = tx.Exec(
+		"UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL WHERE id = $1",
+		userId,
 	)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = $1", userId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
-	if err == sql.ErrNoRows {
-		return nil, nil
+// AcceptTOTPStep records that a code from step was used. Returns false if a code from
+// the same or a later step was already accepted, so a code can't be replayed.
+func AcceptTOTPStep(conn *sql.DB, userId int, step int64) (bool, error) {
+	result, err := conn.Exec(`
+		UPDATE users SET totp_last_step = $2
+		WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)
+	`, userId, step)
+	if err != nil {
+		return false, err
 	}
+	rows, err := result.RowsAffected()
 	if err != nil {
-		return nil, fmt.Errorf("error retrieving Discord integration: %w", err)
+		return false, err
 	}
+	return rows > 0, nil
+}
 
-	return &integration, nil
+// ReplaceRecoveryCodes swaps the user's recovery codes for new ones
+func ReplaceRecoveryCodes(conn *sql.DB, userId int, codeHashes []string) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userId, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// DisableDiscordIntegration disables Discord integration for a user
-func DisableDiscordIntegration(conn *sql.DB, userID int) error {
-	_, err := conn.Exec(
-		"UPDATE discord_integrations SET is_enabled = false, updated_at = NOW() WHERE user_id = $1",
-		userID,
+func replaceRecoveryCodes(tx *sql.Tx, userId int, codeHashes []string) error {
+	if _, err := tx.Exec("DELETE FROM recovery_codes WHERE user_id = $1", userId); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec("INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)", userId, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used. Returns false if the user has
+// no such unused code.
+func UseRecoveryCode(conn *sql.DB, userId int, codeHash string) (bool, error) {
+	result, err := conn.Exec(
+		"UPDATE recovery_codes SET used_at = NOW() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL",
+		userId, codeHash,
 	)
-	return err
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
 }
 
-// DeleteDiscordIntegration removes Discord integration for a user
-func DeleteDiscordIntegration(conn *sql.DB, userID int) error {
-	_, err := conn.Exec(
-		"DELETE FROM discord_integrations WHERE user_id = $1",
-		userID,
+// CountRecoveryCodes counts the user's unused recovery codes
+func CountRecoveryCodes(conn *sql.DB, userId int) (int, error) {
+	var count int
+	err := conn.QueryRow(
+		"SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL",
+		userId,
+	).Scan(&count)
+	return count, err
+}
+
+// CountOrgsRequiring2FA counts the organizations the user belongs to that require
+// two-factor authentication
+func CountOrgsRequiring2FA(conn *sql.DB, userId int) (int, error) {
+	var count int
+	err := conn.QueryRow(`
+		SELECT COUNT(*) FROM org_members m
+		JOIN organizations o ON o.id = m.org_id
+		WHERE m.user_id = $1 AND o.require_2fa
+	`, userId).Scan(&count)
+	return count, err
+}
+
+// ========== METRICS ==========
+
+// AppMetric is the latest state of an app as exposed on /metrics
+type AppMetric struct {
+	Slug               string
+	StatusCode         *int
+	ResponseTimeMs     *int64
+	SSLDaysUntilExpiry *int
+}
+
+// GetAppMetrics returns the last check result and SSL state of every app
+func GetAppMetrics(ctx context.Context, conn *sql.DB) ([]AppMetric, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT a.slug, s.status_code, s.response_time_ms, a.ssl_days_until_expiry
+		FROM apps a
+		LEFT JOIN LATERAL (
+			SELECT status_code, response_time_ms
+			FROM user_status
+			WHERE app_id = a.id
+			ORDER BY checked_at DESC
+			LIMIT 1
+		) s ON true
+		WHERE NOT a.paused
+		ORDER BY a.slug
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var apps []AppMetric
+	for rows.Next() {
+		var app AppMetric
+		var statusCode, sslDays sql.NullInt64
+		var responseTime sql.NullInt64
+		if err := rows.Scan(&app.Slug, &statusCode, &responseTime, &sslDays); err != nil {
+			return nil, err
+		}
+		if statusCode.Valid {
+			code := int(statusCode.Int64)
+			app.StatusCode = &code
+		}
+		if responseTime.Valid {
+			app.ResponseTimeMs = &responseTime.Int64
+		}
+		if sslDays.Valid {
+			days := int(sslDays.Int64)
+			app.SSLDaysUntilExpiry = &days
+		}
+		apps = append(apps, app)
+	}
+
+	return apps, rows.Err()
+}
+
+// ========== ADMIN FUNCTIONS ==========
+
+// IsUserDisabled reports whether an admin has disabled the account
+func IsUserDisabled(conn *sql.DB, userId int) (bool, error) {
+	var disabled bool
+	err := conn.QueryRow("SELECT disabled_at IS NOT NULL FROM users WHERE id = $1", userId).Scan(&disabled)
+	return disabled, err
+}
+
+// SetUserAdmin grants or revokes the admin role. Returns false if there is no such user.
+func SetUserAdmin(conn *sql.DB, userId int, isAdmin bool) (bool, error) {
+	result, err := conn.Exec("UPDATE users SET is_admin = $1 WHERE id = $2", isAdmin, userId)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// DisableUser blocks an account from signing in and using its API keys, and ends its sessions.
+// Returns false if the user doesn't exist or is already disabled.
+func DisableUser(conn *sql.DB, userId int, reason string) (bool, error) {
+	tx, err := conn.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"UPDATE users SET disabled_at = NOW(), disabled_reason = $1 WHERE id = $2 AND disabled_at IS NULL",
+		reason, userId,
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil || rows == 0 {
+		return false, err
+	}
+
+	// Sign the user out everywhere
+	if _, err := tx.Exec("DELETE FROM user_sessions WHERE user_id = $1", userId); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// EnableUser lifts a disable. Returns false if the user doesn't exist or isn't disabled.
+func EnableUser(conn *sql.DB, userId int) (bool, error) {
+	result, err := conn.Exec(
+		"UPDATE users SET disabled_at = NULL, disabled_reason = NULL WHERE id = $1 AND disabled_at IS NOT NULL",
+		userId,
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// SetOrgPlan changes an organization's plan without touching its Stripe subscription
+func SetOrgPlan(conn *sql.DB, orgId int, plan string) (bool, error) {
+	result, err := conn.Exec(
+		"UPDATE organizations SET plan = $1, plan_started_at = NOW() WHERE id = $2",
+		plan, orgId,
 	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// ========== AUDIT LOG ==========
+
+// AuditEntry records who did what to which object, with its values before and after
+type AuditEntry struct {
+	ID          int64           `json:"id"`
+	ActorUserID *int            `json:"actor_user_id"` // nil for changes made by the system, like Stripe webhooks
+	ActorName   *string         `json:"actor_name,omitempty"`
+	Action      string          `json:"action"`
+	TargetType  string          `json:"target_type"`
+	TargetID    *int            `json:"target_id"`
+	OrgID       *int            `json:"org_id"`
+	Before      json.RawMessage `json:"before"`
+	After       json.RawMessage `json:"after"`
+	Details     json.RawMessage `json:"details"`
+	IPAddress   string          `json:"ip_address"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// AuditFilter narrows down GetAuditLog. Zero values match everything.
+type AuditFilter struct {
+	OrgID       int
+	ActorUserID int
+	Personal    bool // only entries not tied to an organization
+	Action      string
+	Limit       int
+}
+
+// nullJSON passes an empty value to the database as NULL
+func nullJSON(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
+
+// InsertAuditEntry appends an entry to the audit log. Details, Before and After may be nil.
+func InsertAuditEntry(conn *sql.DB, entry AuditEntry) error {
+	details := entry.Details
+	if len(details) == 0 {
+		details = json.RawMessage("{}")
+	}
+	_, err := conn.Exec(`
+		INSERT INTO audit_log (actor_user_id, action, target_type, target_id, org_id, before_value, after_value, details, ip_address)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, entry.ActorUserID, entry.Action, entry.TargetType, entry.TargetID, entry.OrgID,
+		nullJSON(entry.Before), nullJSON(entry.After), string(details), entry.IPAddress)
 	return err
 }
+
+// GetAuditLog returns matching entries, newest first
+func GetAuditLog(conn *sql.DB, filter AuditFilter) ([]AuditEntry, error) {
+	query := `
+		SELECT l.id, l.actor_user_id, u.username, l.action, l.target_type, l.target_id, l.org_id,
+		       l.before_value, l.after_value, l.details, COALESCE(l.ip_address, ''), l.created_at
+		FROM audit_log l
+		LEFT JOIN users u ON u.id = l.actor_user_id
+		WHERE true`
+	var args []interface{}
+	if filter.OrgID != 0 {
+		args = append(args, filter.OrgID)
+		query += fmt.Sprintf(" AND l.org_id = $%d", len(args))
+	}
+	if filter.ActorUserID != 0 {
+		args = append(args, filter.ActorUserID)
+		query += fmt.Sprintf(" AND l.actor_user_id = $%d", len(args))
+	}
+	if filter.Personal {
+		query += " AND l.org_id IS NULL"
+	}
+	if filter.Action != "" {
+		args = append(args, filter.Action)
+		query += fmt.Sprintf(" AND l.action = $%d", len(args))
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY l.created_at DESC, l.id DESC LIMIT $%d", len(args))
+
+	rows, err := conn.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var entry AuditEntry
+		var before, after, details []byte
+		err := rows.Scan(&entry.ID, &entry.ActorUserID, &entry.ActorName, &entry.Action, &entry.TargetType, &entry.TargetID, &entry.OrgID,
+			&before, &after, &details, &entry.IPAddress, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entry.Before = before
+		entry.After = after
+		entry.Details = details
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
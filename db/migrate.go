@@ -0,0 +1,307 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the advisory lock key held while migrating so that
+// several instances booting at once do not apply the same migration twice
+const migrationLockID = 7_341_862_145
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a numbered schema change with its up and down SQL
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// LoadMigrations returns the embedded migrations sorted by version.
+// Every version must have both an up and a down file.
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q (want NNNN_name.up.sql or NNNN_name.down.sql)", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		contents, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration %s: %w", entry.Name(), err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// MigrateUp applies every pending migration in order and returns how many were applied
+func MigrateUp(conn *sql.DB) (int, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	return withMigrationLock(conn, func(c *sql.Conn) (int, error) {
+		applied, err := appliedMigrations(c)
+		if err != nil {
+			return 0, err
+		}
+
+		count := 0
+		for _, migration := range migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			err := runMigration(c, migration.Up,
+				"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name)
+			if err != nil {
+				return count, fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+
+			log.Printf("⬆️  Applied migration %04d_%s", migration.Version, migration.Name)
+			count++
+		}
+
+		return count, nil
+	})
+}
+
+// MigrateDown rolls back the last steps applied migrations and returns how many were rolled back
+func MigrateDown(conn *sql.DB, steps int) (int, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	return withMigrationLock(conn, func(c *sql.Conn) (int, error) {
+		applied, err := appliedMigrations(c)
+		if err != nil {
+			return 0, err
+		}
+
+		count := 0
+		for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+			migration := migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+
+			err := runMigration(c, migration.Down,
+				"DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+			if err != nil {
+				return count, fmt.Errorf("rollback of %04d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+
+			log.Printf("⬇️  Rolled back migration %04d_%s", migration.Version, migration.Name)
+			count++
+		}
+
+		return count, nil
+	})
+}
+
+// GetMigrationStatus lists every known migration and whether it has been applied
+func GetMigrationStatus(conn *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := conn.Conn(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	applied, err := appliedMigrations(c)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// EnsureSchemaCurrent returns an error unless every embedded migration has been
+// applied and the database has no migrations this binary does not know about
+func EnsureSchemaCurrent(conn *sql.DB) error {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return err
+	}
+
+	c, err := conn.Conn(context.Background())
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	applied, err := appliedMigrations(c)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[int]bool, len(migrations))
+	var pending []string
+	for _, migration := range migrations {
+		known[migration.Version] = true
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, fmt.Sprintf("%04d_%s", migration.Version, migration.Name))
+		}
+	}
+
+	for version := range applied {
+		if !known[version] {
+			return fmt.Errorf("database has migration %04d applied which this build does not know about - deploy a newer build or roll it back", version)
+		}
+	}
+
+	if len(pending) > 0 {
+		return fmt.Errorf("database schema is out of date, %d pending migration(s): %v - run `statusframe migrate up`", len(pending), pending)
+	}
+
+	return nil
+}
+
+// withMigrationLock runs fn on a dedicated connection holding the migration advisory lock
+func withMigrationLock(conn *sql.DB, fn func(c *sql.Conn) (int, error)) (int, error) {
+	ctx := context.Background()
+
+	c, err := conn.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+
+	if _, err := c.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return 0, fmt.Errorf("error acquiring migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := c.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+			log.Printf("⚠️ Error releasing migration lock: %v", err)
+		}
+	}()
+
+	return fn(c)
+}
+
+// runMigration executes a migration script and records it in one transaction
+func runMigration(c *sql.Conn, script, record string, args ...interface{}) error {
+	ctx := context.Background()
+
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// appliedMigrations returns the applied versions and when they were applied
+func appliedMigrations(c *sql.Conn) (map[int]time.Time, error) {
+	ctx := context.Background()
+
+	_, err := c.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error creating schema_migrations table: %w", err)
+	}
+
+	rows, err := c.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
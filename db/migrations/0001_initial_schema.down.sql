@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS incident_notifications;
+DROP TABLE IF EXISTS discord_integrations;
+DROP TABLE IF EXISTS slack_integrations;
+DROP TABLE IF EXISTS alerts;
+DROP TABLE IF EXISTS user_status;
+DROP TABLE IF EXISTS apps;
+DROP TABLE IF EXISTS users;
@@ -1,4 +1,6 @@
--- Final canonical schema (users = user-level data; apps = monitored sites)
+-- Initial schema (users = user-level data; apps = monitored sites).
+-- Written with IF NOT EXISTS so databases created from the old init.sql
+-- can adopt the migration runner without being recreated.
 CREATE TABLE IF NOT EXISTS users (
   id SERIAL PRIMARY KEY,
   username TEXT NOT NULL,
@@ -33,6 +35,13 @@ CREATE TABLE IF NOT EXISTS apps (
   UNIQUE(user_id, app_name)
 );
 
+-- Columns that used to be added by ad-hoc migrations
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS logo_url TEXT;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS ssl_expiry_date TIMESTAMPTZ;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS ssl_days_until_expiry INTEGER;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS ssl_issuer TEXT;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS ssl_last_checked TIMESTAMPTZ;
+
 CREATE INDEX IF NOT EXISTS idx_apps_user_id ON apps(user_id);
 CREATE INDEX IF NOT EXISTS idx_apps_slug ON apps(slug);
 
@@ -73,18 +82,18 @@ CREATE INDEX IF NOT EXISTS idx_slack_integrations_user_id ON slack_integrations(
 
 -- Discord integration table
 CREATE TABLE IF NOT EXISTS discord_integrations (
-    id SERIAL PRIMARY KEY,
-    user_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
-    discord_user_id VARCHAR(255) NOT NULL,
-    discord_username VARCHAR(255),
-    webhook_url VARCHAR(1000) NOT NULL,
-    server_id VARCHAR(255),
-    server_name VARCHAR(255),
-    channel_id VARCHAR(255),
-    channel_name VARCHAR(255),
-    is_enabled BOOLEAN DEFAULT true,
-    created_at TIMESTAMP DEFAULT NOW(),
-    updated_at TIMESTAMP DEFAULT NOW()
+  id SERIAL PRIMARY KEY,
+  user_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+  discord_user_id VARCHAR(255) NOT NULL,
+  discord_username VARCHAR(255),
+  webhook_url VARCHAR(1000) NOT NULL,
+  server_id VARCHAR(255),
+  server_name VARCHAR(255),
+  channel_id VARCHAR(255),
+  channel_name VARCHAR(255),
+  is_enabled BOOLEAN DEFAULT true,
+  created_at TIMESTAMP DEFAULT NOW(),
+  updated_at TIMESTAMP DEFAULT NOW()
 );
 
 CREATE INDEX IF NOT EXISTS idx_discord_integrations_user_id ON discord_integrations(user_id);
@@ -99,4 +108,4 @@ CREATE TABLE IF NOT EXISTS incident_notifications (
   created_at TIMESTAMP DEFAULT NOW()
 );
 
-CREATE INDEX IF NOT EXISTS idx_incident_notifications_app_id ON incident_notifications(app_id);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_incident_notifications_app_id ON incident_notifications(app_id);
@@ -0,0 +1,2 @@
+-- Nothing to restore: the dropped columns were never written by the application
+ALTER TABLE incident_notifications ALTER COLUMN status DROP NOT NULL;
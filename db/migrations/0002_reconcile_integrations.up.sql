@@ -0,0 +1,11 @@
+-- The old add_slack_integration.sql created integrations per app and tracked
+-- Slack message timestamps. The code keeps one integration per user and never
+-- reads the timestamp, so bring those databases in line with the initial schema.
+DROP INDEX IF EXISTS idx_slack_integrations_app_id;
+ALTER TABLE slack_integrations DROP COLUMN IF EXISTS app_id;
+ALTER TABLE slack_integrations ALTER COLUMN slack_bot_token TYPE VARCHAR(1000);
+ALTER TABLE slack_integrations ALTER COLUMN slack_channel_id DROP NOT NULL;
+
+ALTER TABLE incident_notifications DROP COLUMN IF EXISTS message_timestamp;
+UPDATE incident_notifications SET status = 'unknown' WHERE status IS NULL;
+ALTER TABLE incident_notifications ALTER COLUMN status SET NOT NULL;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS region_checks;
+DROP TABLE IF EXISTS probe_agents;
@@ -0,0 +1,20 @@
+-- Multi-region probe agents
+CREATE TABLE IF NOT EXISTS probe_agents (
+  id SERIAL PRIMARY KEY,
+  name TEXT UNIQUE NOT NULL,
+  region TEXT NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT now(),
+  last_seen_at TIMESTAMPTZ DEFAULT now()
+);
+
+-- Per-region health check results used for quorum decisions
+CREATE TABLE IF NOT EXISTS region_checks (
+  id SERIAL PRIMARY KEY,
+  app_id INTEGER NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+  region TEXT NOT NULL,
+  status_code INTEGER NOT NULL,
+  response_time_ms INTEGER,
+  checked_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_region_checks_app_region ON region_checks(app_id, region, checked_at DESC);
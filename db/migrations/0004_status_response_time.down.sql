@@ -0,0 +1 @@
+ALTER TABLE user_status DROP COLUMN IF EXISTS response_time_ms;
@@ -0,0 +1,2 @@
+-- Keep the measured response time with each check so it can be graphed and scraped
+ALTER TABLE user_status ADD COLUMN IF NOT EXISTS response_time_ms INTEGER;
@@ -0,0 +1,2 @@
+-- Paused apps keep their configuration and history but are skipped by the checkers
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS paused BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,3 @@
+DROP VIEW IF EXISTS user_status_unpaused;
+DROP VIEW IF EXISTS app_pause_periods;
+DROP TABLE IF EXISTS app_pause_events;
@@ -0,0 +1,37 @@
+-- Every pause and resume, with who did it. actor_user_id is NULL for system actions.
+CREATE TABLE IF NOT EXISTS app_pause_events (
+  id SERIAL PRIMARY KEY,
+  app_id INTEGER NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+  paused BOOLEAN NOT NULL,
+  actor_user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  via TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_app_pause_events_app_id ON app_pause_events(app_id, created_at);
+
+-- Apps paused before events were recorded start their pause at their last update
+INSERT INTO app_pause_events (app_id, paused, via, created_at)
+SELECT id, true, 'system', COALESCE(updated_at, now()) FROM apps WHERE paused;
+
+-- One row per pause, ended_at is NULL while the app is still paused
+CREATE OR REPLACE VIEW app_pause_periods AS
+SELECT app_id, started_at, ended_at
+FROM (
+  SELECT app_id, paused, created_at AS started_at,
+         LEAD(created_at) OVER (PARTITION BY app_id ORDER BY created_at, id) AS ended_at
+  FROM app_pause_events
+) events
+WHERE paused;
+
+-- Checks that count toward uptime: anything recorded while an app was paused
+-- (e.g. a check that was in flight when it was paused) is left out
+CREATE OR REPLACE VIEW user_status_unpaused AS
+SELECT s.id, s.app_id, s.status_code, s.checked_at, s.response_time_ms
+FROM user_status s
+WHERE NOT EXISTS (
+  SELECT 1 FROM app_pause_periods p
+  WHERE p.app_id = s.app_id
+    AND s.checked_at >= p.started_at
+    AND (p.ended_at IS NULL OR s.checked_at < p.ended_at)
+);
@@ -0,0 +1,32 @@
+-- Only personal organizations map back to a user. Apps and integrations of
+-- team organizations stay with the user who created them.
+UPDATE users u
+SET plan = o.plan,
+    plan_started_at = o.plan_started_at,
+    stripe_customer_id = o.stripe_customer_id,
+    stripe_subscription_id = o.stripe_subscription_id
+FROM organizations o
+WHERE o.personal_user_id = u.id;
+
+ALTER TABLE discord_integrations DROP CONSTRAINT IF EXISTS discord_integrations_org_id_key;
+DELETE FROM discord_integrations di WHERE NOT EXISTS (
+  SELECT 1 FROM organizations o WHERE o.id = di.org_id AND o.personal_user_id = di.user_id
+);
+ALTER TABLE discord_integrations DROP COLUMN IF EXISTS org_id;
+ALTER TABLE discord_integrations ADD CONSTRAINT discord_integrations_user_id_key UNIQUE (user_id);
+
+ALTER TABLE slack_integrations DROP CONSTRAINT IF EXISTS slack_integrations_org_id_key;
+DELETE FROM slack_integrations si WHERE NOT EXISTS (
+  SELECT 1 FROM organizations o WHERE o.id = si.org_id AND o.personal_user_id = si.user_id
+);
+ALTER TABLE slack_integrations DROP COLUMN IF EXISTS org_id;
+ALTER TABLE slack_integrations ADD CONSTRAINT slack_integrations_user_id_key UNIQUE (user_id);
+
+DROP INDEX IF EXISTS idx_apps_org_id;
+ALTER TABLE apps DROP CONSTRAINT IF EXISTS apps_org_id_app_name_key;
+ALTER TABLE apps DROP COLUMN IF EXISTS org_id;
+ALTER TABLE apps ADD CONSTRAINT apps_user_id_app_name_key UNIQUE (user_id, app_name);
+
+DROP TABLE IF EXISTS org_invitations;
+DROP TABLE IF EXISTS org_members;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,71 @@
+-- Organizations own apps, integrations and billing. Every user gets a personal
+-- organization so nothing changes for people who never create a team.
+CREATE TABLE IF NOT EXISTS organizations (
+  id SERIAL PRIMARY KEY,
+  name TEXT NOT NULL,
+  personal_user_id INTEGER UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+  plan TEXT NOT NULL DEFAULT 'free',
+  plan_started_at TIMESTAMPTZ DEFAULT now(),
+  stripe_customer_id TEXT UNIQUE,
+  stripe_subscription_id TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS org_members (
+  org_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'editor', 'viewer')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_org_members_user_id ON org_members(user_id);
+
+-- Only a SHA-256 hash of the invitation token is stored, like API keys
+CREATE TABLE IF NOT EXISTS org_invitations (
+  id SERIAL PRIMARY KEY,
+  org_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  email TEXT NOT NULL,
+  role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'editor', 'viewer')),
+  token_hash TEXT UNIQUE NOT NULL,
+  invited_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at TIMESTAMPTZ NOT NULL,
+  accepted_at TIMESTAMPTZ
+);
+
+-- At most one pending invitation per address and organization
+CREATE UNIQUE INDEX IF NOT EXISTS idx_org_invitations_pending
+  ON org_invitations(org_id, lower(email)) WHERE accepted_at IS NULL;
+
+-- Move each user's plan and subscription to their personal organization.
+-- The columns on users are kept for rollback but are no longer read.
+INSERT INTO organizations (name, personal_user_id, plan, plan_started_at, stripe_customer_id, stripe_subscription_id, created_at)
+SELECT username, id, COALESCE(plan, 'free'), plan_started_at, stripe_customer_id, stripe_subscription_id, COALESCE(created_at, now())
+FROM users
+ON CONFLICT (personal_user_id) DO NOTHING;
+
+INSERT INTO org_members (org_id, user_id, role)
+SELECT id, personal_user_id, 'owner' FROM organizations WHERE personal_user_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+-- apps.user_id now records who created the app
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS org_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE apps a SET org_id = o.id FROM organizations o WHERE o.personal_user_id = a.user_id AND a.org_id IS NULL;
+ALTER TABLE apps ALTER COLUMN org_id SET NOT NULL;
+ALTER TABLE apps DROP CONSTRAINT IF EXISTS apps_user_id_app_name_key;
+ALTER TABLE apps ADD CONSTRAINT apps_org_id_app_name_key UNIQUE (org_id, app_name);
+CREATE INDEX IF NOT EXISTS idx_apps_org_id ON apps(org_id);
+
+-- Integrations belong to the organization; user_id records who connected them
+ALTER TABLE slack_integrations ADD COLUMN IF NOT EXISTS org_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE slack_integrations si SET org_id = o.id FROM organizations o WHERE o.personal_user_id = si.user_id AND si.org_id IS NULL;
+ALTER TABLE slack_integrations ALTER COLUMN org_id SET NOT NULL;
+ALTER TABLE slack_integrations DROP CONSTRAINT IF EXISTS slack_integrations_user_id_key;
+ALTER TABLE slack_integrations ADD CONSTRAINT slack_integrations_org_id_key UNIQUE (org_id);
+
+ALTER TABLE discord_integrations ADD COLUMN IF NOT EXISTS org_id INTEGER REFERENCES organizations(id) ON DELETE CASCADE;
+UPDATE discord_integrations di SET org_id = o.id FROM organizations o WHERE o.personal_user_id = di.user_id AND di.org_id IS NULL;
+ALTER TABLE discord_integrations ALTER COLUMN org_id SET NOT NULL;
+ALTER TABLE discord_integrations DROP CONSTRAINT IF EXISTS discord_integrations_user_id_key;
+ALTER TABLE discord_integrations ADD CONSTRAINT discord_integrations_org_id_key UNIQUE (org_id);
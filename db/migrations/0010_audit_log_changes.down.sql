@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+DROP TRIGGER IF EXISTS audit_log_no_update ON audit_log;
+DROP FUNCTION IF EXISTS audit_log_append_only();
+
+DROP INDEX IF EXISTS idx_audit_log_org_id;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS after_value;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS before_value;
+ALTER TABLE audit_log DROP COLUMN IF EXISTS org_id;
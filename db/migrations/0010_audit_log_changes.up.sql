@@ -0,0 +1,28 @@
+-- Audit entries record the organization they belong to and the values before and
+-- after a change, so members can see who changed what in their organization.
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS org_id INTEGER;
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS before_value JSONB;
+ALTER TABLE audit_log ADD COLUMN IF NOT EXISTS after_value JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_org_id ON audit_log(org_id, created_at DESC);
+
+-- The log outlives the users and organizations it mentions. A foreign key with
+-- ON DELETE SET NULL would have to update old entries, which the trigger forbids.
+ALTER TABLE audit_log DROP CONSTRAINT IF EXISTS audit_log_actor_user_id_fkey;
+
+-- Entries can only be added, never changed or removed
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_no_update ON audit_log;
+CREATE TRIGGER audit_log_no_update
+  BEFORE UPDATE OR DELETE ON audit_log
+  FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+DROP TRIGGER IF EXISTS audit_log_no_truncate ON audit_log;
+CREATE TRIGGER audit_log_no_truncate
+  BEFORE TRUNCATE ON audit_log
+  FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
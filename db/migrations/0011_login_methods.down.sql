@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS magic_links;
+DROP INDEX IF EXISTS idx_users_email_lower;
+DROP TABLE IF EXISTS user_identities;
@@ -0,0 +1,29 @@
+-- Each way a user signs in is an identity. Identities are linked to one users row
+-- by verified email, so signing in with GitHub finds the account made with Google.
+CREATE TABLE IF NOT EXISTS user_identities (
+  id SERIAL PRIMARY KEY,
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  provider TEXT NOT NULL,   -- google, github or email
+  subject TEXT NOT NULL,    -- the provider's id for the user
+  email TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_login_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+-- Providers don't agree on the case of an address
+CREATE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email));
+
+-- Only a SHA-256 hash of the magic link token is stored, like API keys
+CREATE TABLE IF NOT EXISTS magic_links (
+  id SERIAL PRIMARY KEY,
+  email TEXT NOT NULL,
+  token_hash TEXT UNIQUE NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_magic_links_email_created_at ON magic_links(email, created_at);
@@ -0,0 +1,19 @@
+-- Optional TOTP two-factor authentication. totp_secret is set when setup starts and
+-- only counts once totp_enabled_at is set by confirming a code from the app.
+-- totp_last_step is the time step of the last accepted code, so a code works once.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT;
+
+-- One-time recovery codes, stored by a SHA-256 hash like API keys
+CREATE TABLE IF NOT EXISTS recovery_codes (
+  id SERIAL PRIMARY KEY,
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  used_at TIMESTAMPTZ,
+  UNIQUE (user_id, code_hash)
+);
+
+-- Owners can require every member to have two-factor authentication
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS require_2fa BOOLEAN NOT NULL DEFAULT false;
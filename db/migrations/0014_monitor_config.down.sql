@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS maintenance_windows;
+ALTER TABLE apps DROP COLUMN IF EXISTS alert_channels;
+ALTER TABLE apps DROP COLUMN IF EXISTS check_interval;
@@ -0,0 +1,18 @@
+-- Per-app check settings for declarative configuration. check_interval is in seconds
+-- and NULL means the plan's minimum. alert_channels lists where incidents are sent;
+-- NULL means every connected integration and an empty array means none.
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS check_interval INTEGER;
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS alert_channels TEXT[];
+
+-- Planned maintenance. Checks keep running but no alerts are sent during a window.
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+  id SERIAL PRIMARY KEY,
+  app_id INTEGER NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+  starts_at TIMESTAMPTZ NOT NULL,
+  ends_at TIMESTAMPTZ NOT NULL,
+  reason TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_maintenance_windows_app_id ON maintenance_windows(app_id, ends_at);
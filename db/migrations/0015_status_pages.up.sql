@@ -0,0 +1,38 @@
+-- Status pages that group several apps into components, optionally under named
+-- sections. Their slugs are separate from app slugs, which keep their own pages.
+CREATE TABLE IF NOT EXISTS status_pages (
+  id SERIAL PRIMARY KEY,
+  org_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+  slug TEXT UNIQUE NOT NULL,
+  title TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  theme TEXT NOT NULL DEFAULT 'cyberpunk',
+  logo_url TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_status_pages_org_id ON status_pages(org_id);
+
+CREATE TABLE IF NOT EXISTS status_page_sections (
+  id SERIAL PRIMARY KEY,
+  page_id INTEGER NOT NULL REFERENCES status_pages(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  position INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_status_page_sections_page_id ON status_page_sections(page_id, position);
+
+-- A component shows one app. section_id is NULL for components above the sections,
+-- and name is NULL to show the app's own name.
+CREATE TABLE IF NOT EXISTS status_page_components (
+  id SERIAL PRIMARY KEY,
+  page_id INTEGER NOT NULL REFERENCES status_pages(id) ON DELETE CASCADE,
+  section_id INTEGER REFERENCES status_page_sections(id) ON DELETE CASCADE,
+  app_id INTEGER NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+  name TEXT,
+  position INTEGER NOT NULL,
+  UNIQUE (page_id, app_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_status_page_components_app_id ON status_page_components(app_id);
@@ -0,0 +1,12 @@
+-- A status page can be served on its own domain, like status.example.com, once a
+-- DNS TXT record proves the domain belongs to the page's organization
+CREATE TABLE IF NOT EXISTS custom_domains (
+  page_id INTEGER PRIMARY KEY REFERENCES status_pages(id) ON DELETE CASCADE,
+  domain TEXT NOT NULL,
+  token TEXT NOT NULL,
+  verified_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Anyone can claim a domain, but only one page can verify it
+CREATE UNIQUE INDEX IF NOT EXISTS idx_custom_domains_verified ON custom_domains(domain) WHERE verified_at IS NOT NULL;
@@ -0,0 +1,12 @@
+-- Updates posted about an app's incidents, like "Investigating" or "Resolved", shown
+-- in its public feeds next to the status changes found in user_status
+CREATE TABLE IF NOT EXISTS incident_updates (
+  id SERIAL PRIMARY KEY,
+  app_id INTEGER NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+  status TEXT NOT NULL CHECK (status IN ('investigating', 'identified', 'monitoring', 'resolved')),
+  message TEXT NOT NULL,
+  created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_updates_app_id ON incident_updates(app_id, created_at DESC);
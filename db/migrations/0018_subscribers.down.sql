@@ -0,0 +1,3 @@
+ALTER TABLE incident_updates DROP COLUMN IF EXISTS notified_at;
+DROP TABLE IF EXISTS subscriber_announcements;
+DROP TABLE IF EXISTS subscribers;
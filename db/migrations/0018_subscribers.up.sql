@@ -0,0 +1,25 @@
+-- Visitors subscribed to an app's status page: by email once they confirm the
+-- address, or by a webhook that answered a verification ping
+CREATE TABLE IF NOT EXISTS subscribers (
+  id SERIAL PRIMARY KEY,
+  app_id INTEGER NOT NULL REFERENCES apps(id) ON DELETE CASCADE,
+  kind TEXT NOT NULL CHECK (kind IN ('email', 'webhook')),
+  target TEXT NOT NULL, -- the email address or webhook URL
+  token TEXT NOT NULL UNIQUE, -- in the confirmation and unsubscribe links
+  confirmed_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (app_id, kind, target)
+);
+
+-- The status last announced to an app's subscribers. Changes are announced at most
+-- once per cooldown, so a flapping app sends one notice of where it ended up.
+CREATE TABLE IF NOT EXISTS subscriber_announcements (
+  app_id INTEGER PRIMARY KEY REFERENCES apps(id) ON DELETE CASCADE,
+  status TEXT NOT NULL,
+  announced_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Incident updates are sent to subscribers by the worker; those posted before there
+-- were subscribers count as sent
+ALTER TABLE incident_updates ADD COLUMN IF NOT EXISTS notified_at TIMESTAMPTZ;
+UPDATE incident_updates SET notified_at = created_at WHERE notified_at IS NULL;
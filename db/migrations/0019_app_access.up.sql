@@ -0,0 +1,10 @@
+-- Apps whose status page isn't public. Without a row the page is public. The key
+-- signs share links and the cookies of visitors let in; a new key revokes them all.
+CREATE TABLE IF NOT EXISTS app_access (
+  app_id INTEGER PRIMARY KEY REFERENCES apps(id) ON DELETE CASCADE,
+  visibility VARCHAR(20) NOT NULL CHECK (visibility IN ('password', 'ip_allowlist', 'members')),
+  password_hash TEXT,
+  ip_allowlist TEXT[] NOT NULL DEFAULT '{}',
+  share_key TEXT NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,10 @@
+-- Which details an app's status page shows besides its status. Without a row it
+-- shows all of them.
+CREATE TABLE IF NOT EXISTS app_public_fields (
+  app_id INTEGER PRIMARY KEY REFERENCES apps(id) ON DELETE CASCADE,
+  status_code BOOLEAN NOT NULL DEFAULT true,
+  response_time BOOLEAN NOT NULL DEFAULT true,
+  uptime BOOLEAN NOT NULL DEFAULT true,
+  uptime_history BOOLEAN NOT NULL DEFAULT true,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1 @@
+ALTER TABLE probe_agents DROP COLUMN IF EXISTS disabled_at;
@@ -0,0 +1,2 @@
+-- Disabled probe agents get no checks and their results are refused
+ALTER TABLE probe_agents ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMPTZ;
@@ -0,0 +1,2 @@
+-- Apps an admin paused stay paused until an admin resumes them
+ALTER TABLE apps ADD COLUMN IF NOT EXISTS admin_locked BOOLEAN NOT NULL DEFAULT false;
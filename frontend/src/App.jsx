@@ -3,7 +3,9 @@ import { useState, useEffect } from "react";
 import RetroTerminalOnboarding from "./Onboard";
 import Home from "./Home";
 import StatusPage from "./StatusPage";
+import StatusPageGroup from "./StatusPageGroup";
 import RetroAuth from "./RetroAuth";
+import TwoFactor from "./TwoFactor";
 import ProtectedRoute from "./ProtectedRoute";
 import Pricing from "./Pricing";
 import Dashboard from "./Dashboard";
@@ -51,9 +53,11 @@ function App() {
       <Routes>
         <Route path="/" element={<Home />} />
         <Route path="/auth" element={<RetroAuth />} />
+        <Route path="/two-factor" element={<TwoFactor />} />
         <Route path="/pricing" element={<Pricing />} />
         {/* Single unified status page - public for everyone, owners can control theme */}
         <Route path="/status/:slug" element={<StatusPage />} />
+        <Route path="/pages/:slug" element={<StatusPageGroup />} />
         <Route path="/onboarding" element={
           <ProtectedRoute>
             <RetroTerminalOnboarding />
@@ -174,6 +174,7 @@ const Dashboard = () => {
       'down': 'red',
       'error': 'red',
       'client_error': 'orange',
+      'paused': 'gray',
       'unknown': 'gray'
     };
     return statusMap[status] || 'gray';
@@ -183,6 +184,7 @@ const Dashboard = () => {
     if (status === 'up') return '✓';
     if (status === 'down' || status === 'error') return '✗';
     if (status === 'degraded') return '⚠';
+    if (status === 'paused') return '⏸';
     return '?';
   };
 
@@ -2,9 +2,18 @@
 import React, { useState, useEffect } from 'react';
 import './RetroAuth.css';
 
+const PROVIDER_LABELS = {
+  google: { name: 'Google', icon: 'G' },
+  github: { name: 'GitHub', icon: 'GH' },
+};
+
 const RetroAuth = () => {
   const [isLoading, setIsLoading] = useState(false);
   const [currentTime, setCurrentTime] = useState(new Date());
+  const [providers, setProviders] = useState(['google']);
+  const [magicLinks, setMagicLinks] = useState(false);
+  const [email, setEmail] = useState('');
+  const [emailStatus, setEmailStatus] = useState('');
 
   useEffect(() => {
     const timer = setInterval(() => {
@@ -13,13 +22,40 @@ const RetroAuth = () => {
     return () => clearInterval(timer);
   }, []);
 
-  const handleGoogleAuth = () => {
-    setIsLoading(true);
+  useEffect(() => {
+    fetch('/auth/providers')
+      .then((res) => (res.ok ? res.json() : null))
+      .then((data) => {
+        if (data) {
+          setProviders(data.providers || []);
+          setMagicLinks(!!data.magic_links);
+        }
+      })
+      .catch(() => {});
+  }, []);
+
+  const handleProviderAuth = (provider) => {
+    setIsLoading(provider);
     setTimeout(() => {
-      window.location.href = '/auth/google';
+      window.location.href = `/auth/${provider}`;
     }, 800);
   };
 
+  const handleMagicLink = async (e) => {
+    e.preventDefault();
+    setEmailStatus('sending');
+    try {
+      const res = await fetch('/auth/email', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ email }),
+      });
+      setEmailStatus(res.ok ? 'sent' : 'error');
+    } catch {
+      setEmailStatus('error');
+    }
+  };
+
   const formatTime = (date) => {
     return date.toLocaleTimeString('en-US', { 
       hour12: false,
@@ -84,26 +120,56 @@ const RetroAuth = () => {
                   ACCESS CONTROL
                 </h2>
                 <p className="section-subtitle">
-                  Sign in with Google to access your monitoring dashboard.
+                  Sign in to access your monitoring dashboard.
                   New users will be automatically registered.
                 </p>
 
                 <div className="provider-section">
                   <div className="provider-label">AUTHENTICATION PROVIDER</div>
                   
-                  <button 
-                    className="google-auth-button"
-                    onClick={handleGoogleAuth}
-                    disabled={isLoading}
-                  >
-                    <div className="button-content">
-                      <div className="google-icon">G</div>
-                      <span className="button-text">
-                        CONTINUE WITH GOOGLE
-                      </span>
-                      {isLoading && <div className="loading-spinner"></div>}
-                    </div>
-                  </button>
+                  {providers.map((provider) => (
+                    <button
+                      key={provider}
+                      className="google-auth-button"
+                      onClick={() => handleProviderAuth(provider)}
+                      disabled={!!isLoading}
+                    >
+                      <div className="button-content">
+                        <div className={`${provider}-icon`}>{PROVIDER_LABELS[provider]?.icon || '?'}</div>
+                        <span className="button-text">
+                          CONTINUE WITH {(PROVIDER_LABELS[provider]?.name || provider).toUpperCase()}
+                        </span>
+                        {isLoading === provider && <div className="loading-spinner"></div>}
+                      </div>
+                    </button>
+                  ))}
+
+                  {magicLinks && (
+                    <form className="magic-link-form" onSubmit={handleMagicLink}>
+                      <div className="provider-label">OR GET A SIGN-IN LINK BY EMAIL</div>
+                      <input
+                        type="email"
+                        className="magic-link-input"
+                        placeholder="you@example.com"
+                        value={email}
+                        onChange={(e) => setEmail(e.target.value)}
+                        required
+                      />
+                      <button
+                        type="submit"
+                        className="google-auth-button"
+                        disabled={emailStatus === 'sending'}
+                      >
+                        <span className="button-text">EMAIL ME A LINK</span>
+                      </button>
+                      {emailStatus === 'sent' && (
+                        <div className="magic-link-status">Check your inbox for a sign-in link.</div>
+                      )}
+                      {emailStatus === 'error' && (
+                        <div className="magic-link-status error">Could not send a link. Check the address and try again.</div>
+                      )}
+                    </form>
+                  )}
                 </div>
 
                 <div className="auth-footer">
@@ -127,7 +193,9 @@ const RetroAuth = () => {
             <div className="progress-bar">
               <div className="progress-fill"></div>
             </div>
-            <div className="loading-text">Connecting to Google OAuth...</div>
+            <div className="loading-text">
+              Connecting to {PROVIDER_LABELS[isLoading]?.name || isLoading} OAuth...
+            </div>
           </div>
         </div>
       )}
@@ -2,6 +2,7 @@ import React, { useState, useEffect } from 'react';
 import { useNavigate, useSearchParams } from 'react-router-dom';
 import { Settings as SettingsIcon, LogOut, Lock, Shield, Bell, MessageCircle as DiscordIcon, AlertCircle, Check } from 'lucide-react';
 import DiscordIntegration from './DiscordIntegration';
+import TwoFactorSettings from './TwoFactorSettings';
 import './Settings.css';
 
 const SettingsPage = () => {
@@ -305,15 +306,7 @@ const SettingsPage = () => {
                     </div>
                   </div>
 
-                  <div className="security-item">
-                    <div className="security-content">
-                      <h3>Two-Factor Authentication</h3>
-                      <p>Add an extra layer of security to your account</p>
-                    </div>
-                    <button className="btn-secondary" disabled>
-                      Coming Soon
-                    </button>
-                  </div>
+                  <TwoFactorSettings />
 
                   <div className="security-item danger">
                     <div className="security-content">
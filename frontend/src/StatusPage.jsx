@@ -2,7 +2,7 @@ import React, { useState, useEffect } from 'react';
 import { useParams } from 'react-router-dom';
 import './StatusPage.css';
 import UptimeBarGraph from './UptimeBarGraph';
-import { Activity, CheckCircle, XCircle, Clock, Globe, Settings } from 'lucide-react';
+import { Activity, CheckCircle, XCircle, Clock, Globe, Settings, PauseCircle } from 'lucide-react';
 
 const StatusPage = () => {
   const { slug } = useParams();
@@ -12,10 +12,20 @@ const StatusPage = () => {
   const [currentTime, setCurrentTime] = useState(new Date());
   const [isOwner, setIsOwner] = useState(false);
   const [showThemeSelector, setShowThemeSelector] = useState(false);
-  const [currentUserId, setCurrentUserId] = useState(null);
   const [userTheme, setUserTheme] = useState(null); // User's local theme preference
   const [responseTime, setResponseTime] = useState(null); // Real-time response time
   const [pingLoading, setPingLoading] = useState(false);
+  const [subscribeEmail, setSubscribeEmail] = useState('');
+  const [locked, setLocked] = useState(null); // { visibility, message } when the page is private
+  const [pagePassword, setPagePassword] = useState('');
+  const [unlockError, setUnlockError] = useState(null);
+  const [subscribeMessage, setSubscribeMessage] = useState(() => {
+    // Set by the confirm and unsubscribe links in subscription emails
+    const params = new URLSearchParams(window.location.search);
+    if (params.has('subscribed')) return 'You are subscribed. We will email you when the status changes.';
+    if (params.has('unsubscribed')) return 'You are unsubscribed and won\'t get any more emails.';
+    return null;
+  });
   const isValidSlug = slug && slug !== 'undefined';
 
   useEffect(() => {
@@ -25,16 +35,18 @@ const StatusPage = () => {
     return () => clearInterval(timer);
   }, []);
 
-  // Check if user is authenticated and owner
+  // The server checks the session, so the page never learns who owns the app
   useEffect(() => {
+    if (!isValidSlug) return;
+
     const checkOwnership = async () => {
       try {
-        const response = await fetch('/api/user-status', {
+        const response = await fetch(`/api/public/status/${slug}/viewer`, {
           credentials: 'include'
         });
         if (response.ok) {
-          const userData = await response.json();
-          setCurrentUserId(userData.userId);
+          const viewer = await response.json();
+          setIsOwner(viewer.owner);
         }
       } catch (err) {
         // Not authenticated, that's fine
@@ -42,7 +54,7 @@ const StatusPage = () => {
       }
     };
     checkOwnership();
-  }, []);
+  }, [slug, isValidSlug]);
 
   useEffect(() => {
     if (!isValidSlug) {
@@ -66,13 +78,6 @@ const StatusPage = () => {
     }
   }, [slug, isValidSlug]);
 
-  // Check ownership when both currentUserId and statusData are available
-  useEffect(() => {
-    if (currentUserId && statusData?.user_id) {
-      setIsOwner(currentUserId === statusData.user_id);
-    }
-  }, [currentUserId, statusData]);
-
   // Close theme selector when clicking outside
   useEffect(() => {
     const handleClickOutside = (event) => {
@@ -98,6 +103,13 @@ const StatusPage = () => {
       setLoading(true);
       const response = await fetch(`/api/public/status/${slug}`);
       
+      const visibility = response.headers.get('X-Page-Visibility');
+      if ((response.status === 401 || response.status === 403) && visibility) {
+        setLocked({ visibility, message: (await response.text()).trim() });
+        setStatusData(null);
+        setError(null);
+        return;
+      }
       if (!response.ok) {
         if (response.status === 404) {
           throw new Error('Status page not found');
@@ -107,6 +119,7 @@ const StatusPage = () => {
       
       const data = await response.json();
       setStatusData(data);
+      setLocked(null);
       setError(null);
     } catch (err) {
       console.error('Error fetching status:', err);
@@ -138,9 +151,9 @@ const StatusPage = () => {
     }
   };
 
-  // Fetch response time on initial load
+  // Fetch response time on initial load, unless the page hides it
   useEffect(() => {
-    if (statusData && isValidSlug) {
+    if (statusData?.fields?.response_time && isValidSlug) {
       fetchResponseTime();
     }
   }, [statusData, slug, isValidSlug]);
@@ -187,23 +200,71 @@ const StatusPage = () => {
     }
   };
 
-  const getStatusColor = (statusCode) => {
-    if (statusCode >= 200 && statusCode < 300) return 'operational';
-    if (statusCode >= 300 && statusCode < 400) return 'degraded';
+  const handleSubscribe = async (e) => {
+    e.preventDefault();
+    try {
+      const response = await fetch(`/api/public/status/${slug}/subscribe`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ email: subscribeEmail }),
+      });
+      if (response.ok) {
+        setSubscribeMessage('Check your inbox for a link to confirm the subscription.');
+        setSubscribeEmail('');
+      } else {
+        setSubscribeMessage((await response.text()) || 'Could not subscribe. Please try again.');
+      }
+    } catch (err) {
+      console.error('Error subscribing:', err);
+      setSubscribeMessage('Could not subscribe. Please try again.');
+    }
+  };
+
+  const handleUnlock = async (e) => {
+    e.preventDefault();
+    try {
+      const response = await fetch(`/api/public/status/${slug}/unlock`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ password: pagePassword }),
+      });
+      if (response.ok) {
+        // The server set an access cookie, so the page loads now
+        setPagePassword('');
+        setUnlockError(null);
+        await fetchStatusData();
+      } else {
+        setUnlockError(response.status === 401 ? 'Wrong password.' : 'Could not unlock this page. Please try again.');
+      }
+    } catch (err) {
+      console.error('Error unlocking status page:', err);
+      setUnlockError('Could not unlock this page. Please try again.');
+    }
+  };
+
+  // The status is worked out on the server, since the page may hide status codes
+  const getStatusColor = (status) => {
+    if (statusData?.paused) return 'paused';
+    if (status === 'up') return 'operational';
+    if (status === 'degraded') return 'degraded';
     return 'down';
   };
 
-  const getStatusText = (statusCode) => {
-    if (statusCode >= 200 && statusCode < 300) return 'All Systems Operational';
-    if (statusCode >= 300 && statusCode < 400) return 'Degraded Performance';
+  const getStatusText = (status) => {
+    if (statusData?.paused) return 'Monitoring Paused';
+    if (status === 'up') return 'All Systems Operational';
+    if (status === 'degraded') return 'Degraded Performance';
     return 'Service Down';
   };
 
-  const getStatusIcon = (statusCode) => {
-    if (statusCode >= 200 && statusCode < 300) {
+  const getStatusIcon = (status) => {
+    if (statusData?.paused) {
+      return <PauseCircle className="status-icon" />;
+    }
+    if (status === 'up') {
       return <CheckCircle className="status-icon" />;
     }
-    if (statusCode >= 300 && statusCode < 400) {
+    if (status === 'degraded') {
       return <Activity className="status-icon pulse" />;
     }
     return <XCircle className="status-icon" />;
@@ -222,7 +283,7 @@ const StatusPage = () => {
     });
   };
 
-  if (loading && !statusData) {
+  if (loading && !statusData && !locked) {
     return (
       <div className={`status-container theme-${statusData?.theme || 'cyberpunk'}`}>
         <div className="crt-overlay"></div>
@@ -235,6 +296,38 @@ const StatusPage = () => {
     );
   }
 
+  if (locked) {
+    return (
+      <div className={`status-container theme-cyberpunk`}>
+        <div className="crt-overlay"></div>
+        <div className="scan-lines"></div>
+        <div className="error-container">
+          <XCircle className="error-icon-large" />
+          <h1>Private Status Page</h1>
+          <p>{locked.message}</p>
+          {locked.visibility === 'password' ? (
+            <form className="subscribe-form" onSubmit={handleUnlock}>
+              <input
+                type="password"
+                placeholder="Password"
+                value={pagePassword}
+                onChange={(e) => setPagePassword(e.target.value)}
+                autoComplete="current-password"
+                required
+              />
+              <button type="submit">Unlock</button>
+            </form>
+          ) : (
+            <p className="error-hint">
+              {locked.visibility === 'members' ? 'Sign in with an account in this organization to see it.' : 'Ask its owner for a share link.'}
+            </p>
+          )}
+          {unlockError && <p className="error-hint">{unlockError}</p>}
+        </div>
+      </div>
+    );
+  }
+
   if (error) {
     const missingSlug = error === 'No status page specified';
     return (
@@ -253,8 +346,10 @@ const StatusPage = () => {
     );
   }
 
-  const statusCode = statusData?.status_code || 0;
-  const statusColor = getStatusColor(statusCode);
+  const status = statusData?.status;
+  const statusCode = statusData?.status_code;
+  const fields = statusData?.fields || {};
+  const statusColor = getStatusColor(status);
   // Use user's local theme preference if set, otherwise use owner's theme
   const theme = userTheme || statusData?.theme || 'cyberpunk';
 
@@ -383,11 +478,17 @@ const StatusPage = () => {
       <section className="main-status">
         <div className={`status-hero status-${statusColor}`}>
           <div className="status-icon-container">
-            {getStatusIcon(statusCode)}
+            {getStatusIcon(status)}
           </div>
-          <h2 className="status-message">{getStatusText(statusCode)}</h2>
+          <h2 className="status-message">{getStatusText(status)}</h2>
           <p className="status-detail">
-            Current Status Code: <span className="status-code">{statusCode}</span>
+            {statusData?.paused ? (
+              'Checks are paused by the owner. Uptime excludes paused time.'
+            ) : statusCode ? (
+              <>Current Status Code: <span className="status-code">{statusCode}</span></>
+            ) : (
+              statusData?.message || `Last checked ${formatTime(statusData?.checked_at)}`
+            )}
           </p>
         </div>
       </section>
@@ -407,42 +508,46 @@ const StatusPage = () => {
             </div>
           </div>
 
-          <div className="metric-box">
-            <div className="metric-icon">
-              <CheckCircle />
-            </div>
-            <div className="metric-content">
-              <span className="metric-label">24h Uptime</span>
-              <span className="metric-value">{statusData?.uptime_24h?.toFixed(2) || 0}%</span>
+          {fields.uptime && (
+            <div className="metric-box">
+              <div className="metric-icon">
+                <CheckCircle />
+              </div>
+              <div className="metric-content">
+                <span className="metric-label">24h Uptime</span>
+                <span className="metric-value">{statusData?.uptime_24h?.toFixed(2) || 0}%</span>
+              </div>
             </div>
-          </div>
+          )}
 
-          <div className="metric-box">
-            <div className="metric-icon">
-              <Clock />
-            </div>
-            <div className="metric-content">
-              <span className="metric-label">Response Time</span>
-              <span className="metric-value metric-value-small">
-                {pingLoading ? (
-                  'Checking...'
-                ) : responseTime !== null ? (
-                  `${responseTime} ms`
-                ) : (
-                  'N/A'
+          {fields.response_time && (
+            <div className="metric-box">
+              <div className="metric-icon">
+                <Clock />
+              </div>
+              <div className="metric-content">
+                <span className="metric-label">Response Time</span>
+                <span className="metric-value metric-value-small">
+                  {pingLoading ? (
+                    'Checking...'
+                  ) : responseTime !== null ? (
+                    `${responseTime} ms`
+                  ) : (
+                    'N/A'
+                  )}
+                </span>
+                {!pingLoading && responseTime !== null && (
+                  <button 
+                    onClick={fetchResponseTime}
+                    className="refresh-ping-btn"
+                    title="Refresh response time"
+                  >
+                    ↻
+                  </button>
                 )}
-              </span>
-              {!pingLoading && responseTime !== null && (
-                <button 
-                  onClick={fetchResponseTime}
-                  className="refresh-ping-btn"
-                  title="Refresh response time"
-                >
-                  ↻
-                </button>
-              )}
+              </div>
             </div>
-          </div>
+          )}
 
           <div className="metric-box">
             <div className="metric-icon">
@@ -468,12 +573,39 @@ const StatusPage = () => {
         </section>
       )}
 
+      {/* Email notifications of status changes and incident updates */}
+      <section className="subscribe-section">
+        <form className="subscribe-form" onSubmit={handleSubscribe}>
+          <label htmlFor="subscribe-email">Get notified when the status changes</label>
+          <div className="subscribe-row">
+            <input
+              id="subscribe-email"
+              type="email"
+              required
+              placeholder="you@example.com"
+              value={subscribeEmail}
+              onChange={(e) => setSubscribeEmail(e.target.value)}
+            />
+            <button type="submit">Subscribe</button>
+          </div>
+          {subscribeMessage && <p className="subscribe-message">{subscribeMessage}</p>}
+        </form>
+      </section>
+
       {/* Footer */}
       <footer className="status-footer">
         <div className="footer-container">
           <div className="footer-text">
             Powered by <span className="footer-brand">STATUSFRAME</span>
           </div>
+          <div className="footer-feeds">
+            Subscribe:{' '}
+            <a href={`/api/public/status/${slug}/feed.rss`}>RSS</a>
+            {' · '}
+            <a href={`/api/public/status/${slug}/feed.atom`}>Atom</a>
+            {' · '}
+            <a href={`/api/public/status/${slug}/feed.json`}>JSON</a>
+          </div>
           <div className="footer-status">
             <span className={`footer-dot status-${statusColor}`}></span>
             <span>Updated {formatTime(statusData?.checked_at)}</span>
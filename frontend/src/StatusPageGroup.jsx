@@ -0,0 +1,225 @@
+import React, { useState, useEffect } from 'react';
+import { useParams } from 'react-router-dom';
+import './StatusPage.css';
+import UptimeBarGraph from './UptimeBarGraph';
+import { Activity, CheckCircle, XCircle, Clock, PauseCircle, Wrench } from 'lucide-react';
+
+// Overall status of a page or section, as sent by /api/public/pages/{slug}
+const PAGE_STATUS = {
+  operational: { color: 'operational', text: 'All Systems Operational' },
+  degraded: { color: 'degraded', text: 'Degraded Performance' },
+  partial_outage: { color: 'down', text: 'Partial Outage' },
+  major_outage: { color: 'down', text: 'Major Outage' },
+  maintenance: { color: 'paused', text: 'Under Maintenance' },
+  pending: { color: 'paused', text: 'Waiting for First Checks' },
+};
+
+// Status of a single component
+const COMPONENT_STATUS = {
+  up: { color: 'operational', text: 'Operational' },
+  degraded: { color: 'degraded', text: 'Degraded' },
+  maintenance: { color: 'paused', text: 'Maintenance' },
+  paused: { color: 'paused', text: 'Paused' },
+  pending: { color: 'paused', text: 'Pending' },
+};
+
+const componentStatus = (status) => COMPONENT_STATUS[status] || { color: 'down', text: 'Down' };
+
+const pageStatusIcon = (status) => {
+  switch (status) {
+    case 'operational':
+      return <CheckCircle className="status-icon" />;
+    case 'degraded':
+      return <Activity className="status-icon pulse" />;
+    case 'maintenance':
+      return <Wrench className="status-icon" />;
+    case 'pending':
+      return <PauseCircle className="status-icon" />;
+    default:
+      return <XCircle className="status-icon" />;
+  }
+};
+
+const formatTime = (dateString) => {
+  if (!dateString) return 'N/A';
+  return new Date(dateString).toLocaleString('en-US', {
+    month: 'short',
+    day: 'numeric',
+    year: 'numeric',
+    hour: '2-digit',
+    minute: '2-digit',
+    second: '2-digit',
+    hour12: false
+  });
+};
+
+// Component is one app on the page with its status and daily uptime bar
+const Component = ({ component, dataRetentionDays }) => {
+  const { color, text } = componentStatus(component.status);
+  return (
+    <div className="component-row">
+      <div className="component-header">
+        <span className="component-name">{component.name}</span>
+        <span className="component-uptime">
+          {component.uptime_24h != null ? `${component.uptime_24h.toFixed(2)}% (24h)` : ''}
+        </span>
+        <span className={`metric-value metric-value-small status-${color}`}>{text}</span>
+      </div>
+      {component.uptime_history.length > 0 && (
+        <UptimeBarGraph
+          uptimeHistory={component.uptime_history}
+          dataRetentionDays={dataRetentionDays}
+        />
+      )}
+    </div>
+  );
+};
+
+// StatusPageGroup shows a status page of several apps, grouped into sections
+const StatusPageGroup = () => {
+  const { slug } = useParams();
+  const [loading, setLoading] = useState(true);
+  const [error, setError] = useState(null);
+  const [page, setPage] = useState(null);
+  const [currentTime, setCurrentTime] = useState(new Date());
+
+  useEffect(() => {
+    const timer = setInterval(() => setCurrentTime(new Date()), 1000);
+    return () => clearInterval(timer);
+  }, []);
+
+  useEffect(() => {
+    const fetchPage = async () => {
+      try {
+        const response = await fetch(`/api/public/pages/${slug}`);
+        if (!response.ok) {
+          throw new Error(response.status === 404 ? 'Status page not found' : 'Failed to load status data');
+        }
+        setPage(await response.json());
+        setError(null);
+      } catch (err) {
+        console.error('Error fetching status page:', err);
+        setError(err.message);
+      } finally {
+        setLoading(false);
+      }
+    };
+
+    fetchPage();
+    const interval = setInterval(fetchPage, 30000); // Refresh every 30 seconds
+    return () => clearInterval(interval);
+  }, [slug]);
+
+  if (loading && !page) {
+    return (
+      <div className="status-container theme-cyberpunk">
+        <div className="crt-overlay"></div>
+        <div className="scan-lines"></div>
+        <div className="loading-container">
+          <div className="loading-spinner"></div>
+          <p>Loading status page...</p>
+        </div>
+      </div>
+    );
+  }
+
+  if (error) {
+    return (
+      <div className="status-container theme-cyberpunk">
+        <div className="crt-overlay"></div>
+        <div className="scan-lines"></div>
+        <div className="error-container">
+          <XCircle className="error-icon-large" />
+          <h1>Status Page Not Found</h1>
+          <p>{error}</p>
+          <p className="error-hint">Please check the URL and try again.</p>
+        </div>
+      </div>
+    );
+  }
+
+  const overall = PAGE_STATUS[page.status] || PAGE_STATUS.pending;
+
+  return (
+    <div className={`status-container theme-${page.theme || 'cyberpunk'}`}>
+      <div className="crt-overlay"></div>
+      <div className="scan-lines"></div>
+      <div className="grid-background"></div>
+
+      {/* Header */}
+      <header className="status-header">
+        <div className="header-container">
+          <div className="brand-info">
+            <div style={{ display: 'flex', alignItems: 'center', gap: '16px' }}>
+              {page.logo_url && (
+                <img
+                  src={page.logo_url}
+                  alt={`${page.title} logo`}
+                  style={{ width: '60px', height: '60px', objectFit: 'contain', borderRadius: '8px' }}
+                  onError={(e) => {
+                    e.target.style.display = 'none';
+                  }}
+                />
+              )}
+              <div>
+                <h1 className="app-name">{page.title}</h1>
+                <p className="app-subtitle">{page.description || 'System Status Monitor'}</p>
+              </div>
+            </div>
+          </div>
+          <div className="header-actions">
+            <div className="time-display">
+              <Clock className="clock-icon" />
+              <span>{currentTime.toLocaleTimeString('en-US', { hour12: false })}</span>
+            </div>
+          </div>
+        </div>
+      </header>
+
+      {/* Overall Status */}
+      <section className="main-status">
+        <div className={`status-hero status-${overall.color}`}>
+          <div className="status-icon-container">{pageStatusIcon(page.status)}</div>
+          <h2 className="status-message">{overall.text}</h2>
+        </div>
+      </section>
+
+      {/* Components outside of sections, then each section */}
+      {page.components.length > 0 && (
+        <section className="graph-section">
+          {page.components.map((component, i) => (
+            <Component key={i} component={component} dataRetentionDays={page.data_retention_days} />
+          ))}
+        </section>
+      )}
+      {page.sections.map((section, i) => (
+        <section className="graph-section" key={i}>
+          <div className="component-header">
+            <h3 className="section-name">{section.name}</h3>
+            <span className={`metric-value metric-value-small status-${(PAGE_STATUS[section.status] || PAGE_STATUS.pending).color}`}>
+              {(PAGE_STATUS[section.status] || PAGE_STATUS.pending).text}
+            </span>
+          </div>
+          {section.components.map((component, j) => (
+            <Component key={j} component={component} dataRetentionDays={page.data_retention_days} />
+          ))}
+        </section>
+      ))}
+
+      {/* Footer */}
+      <footer className="status-footer">
+        <div className="footer-container">
+          <div className="footer-text">
+            Powered by <span className="footer-brand">STATUSFRAME</span>
+          </div>
+          <div className="footer-status">
+            <span className={`footer-dot status-${overall.color}`}></span>
+            <span>Updated {formatTime(page.generated_at)}</span>
+          </div>
+        </div>
+      </footer>
+    </div>
+  );
+};
+
+export default StatusPageGroup;
@@ -0,0 +1,63 @@
+import React from 'react';
+import { useSearchParams } from 'react-router-dom';
+import './RetroAuth.css';
+
+const ERRORS = {
+  invalid_code: 'That code did not work. Try again.',
+  failed: 'Something went wrong. Try again.',
+};
+
+// Second step of signing in for accounts with two-factor authentication on.
+// The form posts straight to the server, which redirects like the OAuth callback.
+const TwoFactor = () => {
+  const [params] = useSearchParams();
+  const error = ERRORS[params.get('error')];
+
+  return (
+    <div className="retro-auth-container">
+      <div className="retro-background">
+        <div className="grid-overlay"></div>
+        <div className="scan-lines"></div>
+      </div>
+
+      <div className="auth-main-content">
+        <div className="auth-window">
+          <div className="window-header">
+            <div className="window-title">
+              <div className="title-icon">🔐</div>
+              <span>Two-Factor Authentication</span>
+            </div>
+          </div>
+
+          <div className="window-content">
+            <div className="auth-form">
+              <div className="form-section">
+                <h2 className="section-title">VERIFY IT'S YOU</h2>
+                <p className="section-subtitle">
+                  Enter the 6-digit code from your authenticator app, or one of your recovery codes.
+                </p>
+
+                <form className="magic-link-form" method="POST" action="/auth/2fa">
+                  <input
+                    type="text"
+                    name="code"
+                    className="magic-link-input"
+                    autoComplete="one-time-code"
+                    autoFocus
+                    required
+                  />
+                  <button type="submit" className="google-auth-button">
+                    <span className="button-text">VERIFY</span>
+                  </button>
+                  {error && <div className="magic-link-status error">{error}</div>}
+                </form>
+              </div>
+            </div>
+          </div>
+        </div>
+      </div>
+    </div>
+  );
+};
+
+export default TwoFactor;
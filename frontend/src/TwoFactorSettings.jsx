@@ -0,0 +1,135 @@
+import React, { useState, useEffect } from 'react';
+
+// Two-factor authentication setup for the Security tab of Settings
+const TwoFactorSettings = () => {
+  const [status, setStatus] = useState(null);
+  const [setup, setSetup] = useState(null);
+  const [code, setCode] = useState('');
+  const [recoveryCodes, setRecoveryCodes] = useState(null);
+  const [error, setError] = useState(null);
+
+  const loadStatus = async () => {
+    const res = await fetch('/api/2fa', { credentials: 'include' });
+    if (res.ok) setStatus(await res.json());
+  };
+
+  useEffect(() => {
+    loadStatus();
+  }, []);
+
+  const post = async (path, body) => {
+    setError(null);
+    const res = await fetch(path, {
+      method: 'POST',
+      credentials: 'include',
+      headers: { 'Content-Type': 'application/json' },
+      body: body ? JSON.stringify(body) : undefined,
+    });
+    if (!res.ok) {
+      setError((await res.text()).trim() || 'Something went wrong');
+      return null;
+    }
+    return res.json();
+  };
+
+  const startSetup = async () => {
+    const data = await post('/api/2fa/setup');
+    if (data) setSetup(data);
+  };
+
+  const enable = async () => {
+    const data = await post('/api/2fa/enable', { code });
+    if (data) {
+      setSetup(null);
+      setCode('');
+      setRecoveryCodes(data.recovery_codes);
+      loadStatus();
+    }
+  };
+
+  const disable = async () => {
+    const data = await post('/api/2fa/disable', { code });
+    if (data) {
+      setCode('');
+      setRecoveryCodes(null);
+      loadStatus();
+    }
+  };
+
+  const regenerate = async () => {
+    const data = await post('/api/2fa/recovery-codes', { code });
+    if (data) {
+      setCode('');
+      setRecoveryCodes(data.recovery_codes);
+      loadStatus();
+    }
+  };
+
+  if (!status) return null;
+
+  return (
+    <div className="security-item">
+      <div className="security-content">
+        <h3>Two-Factor Authentication</h3>
+        {status.enabled ? (
+          <p>
+            On. You have {status.recovery_codes_remaining} unused recovery codes.
+            Enter a code from your app or a recovery code to change this.
+          </p>
+        ) : setup ? (
+          <>
+            <p>
+              Add this key to your authenticator app, or open the link on your phone, then enter the
+              6-digit code it shows.
+            </p>
+            <p className="session-info"><code>{setup.secret}</code></p>
+            <p className="session-info"><a href={setup.otpauth_url}>Open in authenticator app</a></p>
+          </>
+        ) : (
+          <p>Ask for a code from an authenticator app when signing in</p>
+        )}
+
+        {(status.enabled || setup) && (
+          <input
+            type="text"
+            className="two-factor-input"
+            inputMode="text"
+            autoComplete="one-time-code"
+            placeholder="123456"
+            value={code}
+            onChange={(e) => setCode(e.target.value)}
+          />
+        )}
+
+        {recoveryCodes && (
+          <div className="session-info">
+            <p>Save these recovery codes somewhere safe. Each works once, and they won't be shown again.</p>
+            <pre>{recoveryCodes.join('\n')}</pre>
+          </div>
+        )}
+        {error && <p className="session-info">⚠️ {error}</p>}
+      </div>
+
+      {status.enabled ? (
+        <div>
+          <button className="btn-secondary" onClick={regenerate} disabled={!code}>
+            New Recovery Codes
+          </button>
+          <button className="btn-danger" onClick={disable} disabled={!code}>
+            Turn Off
+          </button>
+        </div>
+      ) : setup ? (
+        <button className="btn-secondary" onClick={enable} disabled={!code}>
+          Turn On
+        </button>
+      ) : (
+        <button className="btn-secondary" onClick={startSetup}>
+          Set Up
+        </button>
+      )}
+    </div>
+  );
+};
+
+export default TwoFactorSettings;
@@ -1,16 +1,27 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
 	"mime"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"statusframe/backend/auth"
+	"statusframe/backend/config"
+	"statusframe/backend/email"
 	"statusframe/backend/handlers"
+	"statusframe/backend/metrics"
+	"statusframe/backend/probe"
 	"statusframe/backend/stripe_config"
 	"statusframe/backend/worker"
 	"statusframe/db"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -18,6 +29,10 @@ import (
 	"github.com/go-chi/cors"
 )
 
+// shutdownTimeout bounds how long we wait for requests and checks to drain on SIGTERM.
+// Keep it below stop_grace_period in docker-compose.yaml.
+const shutdownTimeout = 25 * time.Second
+
 // Custom file server that sets correct MIME types
 func staticFileServer(dir string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -63,7 +78,122 @@ func staticFileServer(dir string) http.Handler {
 	})
 }
 
+// runAgent starts the binary in probe agent mode: it registers with the main
+// server, pulls its assigned checks and pushes results tagged with its region
+func runAgent(args []string) {
+	hostname, _ := os.Hostname()
+
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("agent", flag.ExitOnError)
+	serverURL := fs.String("server", cfg.Probe.ServerURL, "URL of the main server (env PROBE_SERVER_URL)")
+	token := fs.String("token", cfg.Probe.Token, "shared probe token (env PROBE_TOKEN)")
+	region := fs.String("region", cfg.Probe.Region, "region this agent reports as (env PROBE_REGION)")
+	name := fs.String("name", "", "unique agent name (defaults to <hostname>-<region>)")
+	fs.Parse(args)
+
+	if *serverURL == "" || *token == "" || *region == "" {
+		log.Fatal("agent mode requires -server, -token and -region (or PROBE_SERVER_URL, PROBE_TOKEN and PROBE_REGION)")
+	}
+	if *name == "" {
+		*name = hostname + "-" + *region
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	agent := probe.NewAgent(*serverURL, *token, *name, *region)
+	if err := agent.Run(ctx); err != nil && err != context.Canceled {
+		log.Fatal(err)
+	}
+}
+
+// runMigrate applies or rolls back schema migrations:
+//
+//	statusframe migrate up
+//	statusframe migrate down [steps]
+//	statusframe migrate status
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		log.Fatal("usage: statusframe migrate up | down [steps] | status")
+	}
+
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.Database.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	conn, err := db.OpenDB(cfg.Database.DSN())
+	if err != nil {
+		log.Fatal("Failed to open database:", err)
+	}
+	defer conn.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := db.MigrateUp(conn)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("✅ Applied %d migration(s)", applied)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatalf("invalid number of steps %q", args[1])
+			}
+		}
+		rolledBack, err := db.MigrateDown(conn, steps)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("✅ Rolled back %d migration(s)", rolledBack)
+	case "status":
+		statuses, err := db.GetMigrationStatus(conn)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, state)
+		}
+	default:
+		log.Fatalf("unknown migrate command %q (want up, down or status)", args[0])
+	}
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "agent":
+			runAgent(os.Args[2:])
+			return
+		case "migrate":
+			runMigrate(os.Args[2:])
+			return
+		}
+	}
+
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to an optional JSON config file (env CONFIG_FILE)")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
 
 	// Ensure correct MIME types are registered
 	mime.AddExtensionType(".css", "text/css")
@@ -75,102 +205,354 @@ func main() {
 	mime.AddExtensionType(".webm", "video/webm")
 	mime.AddExtensionType(".ogg", "video/ogg")
 
-	conn := db.OpenDB()
+	conn, err := db.OpenDB(cfg.Database.DSN())
+	if err != nil {
+		log.Fatal("Failed to open database:", err)
+	}
 	defer conn.Close()
 
-	// Initialize Stripe configuration
-	stripe_config.Initialize()
+	plans := make(map[string]db.PlanFeatures, len(cfg.Plans))
+	for name, plan := range cfg.Plans {
+		plans[name] = db.PlanFeatures{
+			MaxMonitors:       plan.MaxMonitors,
+			MinCheckInterval:  plan.MinCheckInterval,
+			DataRetentionDays: plan.DataRetentionDays,
+			CustomBranding:    plan.CustomBranding,
+		}
+	}
+	db.SetPlanFeatures(plans)
+
+	// Initialize Stripe configuration (payments stay disabled without a secret key)
+	stripe_config.Initialize(cfg.Stripe, cfg.Server.PublicURL)
 
 	// Ping database to ensure connection
 	if err := db.PingDB(conn); err != nil {
 		log.Fatal("Failed to ping database:", err)
 	}
 
-	appHandlers := handlers.NewHandler(conn)
+	// Bring the schema up to date, or refuse to run against an old one
+	if cfg.Database.AutoMigrate {
+		applied, err := db.MigrateUp(conn)
+		if err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+		if applied > 0 {
+			log.Printf("✅ Applied %d database migration(s)", applied)
+		}
+	}
+	if err := db.EnsureSchemaCurrent(conn); err != nil {
+		log.Fatal(err)
+	}
+
+	appHandlers := handlers.NewHandler(conn, cfg)
+
+	// Invitations, sign-in links and subscriber notices are emailed through SES when it
+	// is configured, or a plain SMTP server like a local Mailpit
+	if cfg.SES.SenderEmail != "" {
+		sesClient, err := email.NewSESClient(cfg.AWS, cfg.SES)
+		if err != nil {
+			log.Printf("⚠️  SES not available, invitation and sign-in links will only be logged: %v", err)
+		} else {
+			appHandlers.SetMailer(sesClient)
+		}
+	} else if cfg.SMTP.Addr != "" {
+		smtpClient, err := email.NewSMTPClient(cfg.SMTP)
+		if err != nil {
+			log.Printf("⚠️  SMTP not available, invitation and sign-in links will only be logged: %v", err)
+		} else {
+			appHandlers.SetMailer(smtpClient)
+			log.Printf("📧 Sending email through SMTP at %s", cfg.SMTP.Addr)
+		}
+	} else if cfg.Auth.MagicLinks {
+		log.Printf("⚠️  Magic links are enabled without SES, sign-in links will only be logged")
+	}
 
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
+	r.Use(metrics.Middleware)
+
+	// Serve status pages on their verified custom domains
+	r.Use(appHandlers.CustomDomainMiddleware)
 
 	// Add CORS middleware to allow credentials (cookies)
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:8080", "http://localhost:3000", "http://localhost:5173"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Requested-With"},
+		AllowedOrigins:   cfg.Server.AllowedOrigins,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-Requested-With", "X-Org-Id"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 
 	// Initialize custom authentication
-	auth.NewAuth()
+	if err := auth.NewAuth(cfg.Auth); err != nil {
+		log.Fatal("Failed to initialize authentication:", err)
+	}
+
+	// Let AuthMiddleware accept personal API keys as well as sessions
+	auth.EnableAPIKeys(conn)
+
+	// Turn away sessions of accounts an admin has disabled
+	auth.EnableAccountChecks(conn)
 
-	// Start health checker worker (check every 30 seconds)
-	healthChecker := worker.NewHealthChecker(conn, 30*time.Second)
-	go healthChecker.Start()
-	log.Println("✅ Health checker worker started (checking every 30 seconds)")
+	// Keep sessions in the database so they can be listed and revoked
+	auth.UseDBSessions(conn, cfg.Auth.SessionSecret, cfg.Server.TrustedProxies)
+
+	if len(cfg.Admin.Emails) == 0 {
+		log.Println("⚠️  No admin emails configured - only users with the admin role can use the admin panel")
+	}
+	if !cfg.Slack.Enabled() {
+		log.Println("⚠️  Slack is not configured - Slack alerts are disabled")
+	}
+	if !cfg.Discord.Enabled() {
+		log.Println("⚠️  Discord is not configured - Discord alerts are disabled")
+	}
+	if strings.HasPrefix(cfg.Server.PublicURL, "https://") && !cfg.Auth.SecureCookies {
+		log.Println("⚠️  SESSION_SECURE_COOKIES is off - session cookies can be sent over plain HTTP")
+	}
+
+	// Start health checker worker
+	healthChecker := worker.NewHealthChecker(conn, appHandlers, cfg.Worker.CheckInterval.Duration)
+
+	// Decide status by quorum across regions when probe agents are enabled
+	if cfg.Probe.Enabled() {
+		healthChecker.EnableMultiRegion(cfg.Probe.LocalRegion(), cfg.Probe.Quorum)
+		log.Printf("🌍 Multi-region checks enabled (local region: %s, quorum: %d)", cfg.Probe.LocalRegion(), cfg.Probe.Quorum)
+	}
+
+	// Workers get their own context so they keep running while HTTP requests drain
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	go healthChecker.Start(workerCtx)
+	log.Printf("✅ Health checker worker started (checking every %s)", cfg.Worker.CheckInterval)
 
 	// Start SSL certificate checker (check daily)
 	sslChecker := worker.NewSSLChecker(conn)
-	go sslChecker.Start()
+	go sslChecker.Start(workerCtx)
 	log.Println("✅ SSL certificate checker started (checking daily)")
 
+	// Tell status page subscribers about status changes and incident updates
+	subscriberNotifier := worker.NewSubscriberNotifier(conn, appHandlers, time.Minute)
+	go subscriberNotifier.Start(workerCtx)
+	log.Println("✅ Subscriber notifier started (checking every minute)")
+
 	// Pass SSL checker to handlers so we can trigger on-demand checks
 	appHandlers.SetSSLChecker(sslChecker)
 
+	// Report worker status on the readiness endpoint
+	appHandlers.AddWorker(healthChecker)
+	appHandlers.AddWorker(sslChecker)
+	appHandlers.AddWorker(subscriberNotifier)
+
+	// Expose internal and per-app metrics to Prometheus
+	metrics.RegisterDB(conn)
+	metrics.RegisterSSLQueue(func() int { return sslChecker.Status().QueueDepth })
+	if cfg.Metrics.Token == "" {
+		log.Println("⚠️  METRICS_TOKEN not set - /metrics is disabled")
+	}
+
+	// --- Liveness and readiness probes ---
+	r.Get("/healthz", handlers.LivenessHandler)
+	r.Get("/readyz", appHandlers.ReadinessHandler)
+	r.Method(http.MethodGet, "/metrics", metrics.Handler(cfg.Metrics.Token))
+
 	// --- API routes (must come first) ---
 	r.Route("/api", func(r chi.Router) {
 		r.With(auth.AuthMiddleware).Get("/start-onboarding", handlers.StartOnboardingHandler)
-		r.With(auth.AuthMiddleware).Post("/go-to-dashboard", appHandlers.GoToDashboardHandler)
-		r.With(auth.AuthMiddleware).Get("/user-status", appHandlers.GetUserStatusHandler)
+		r.With(auth.AuthMiddleware, appHandlers.OrgMiddleware).Post("/go-to-dashboard", appHandlers.GoToDashboardHandler)
+		r.With(auth.AuthMiddleware, appHandlers.OrgMiddleware).Get("/user-status", appHandlers.GetUserStatusHandler)
 		r.With(auth.AuthMiddleware).Get("/latest-status", appHandlers.GetLatestStatusHandler)
-		r.With(auth.AuthMiddleware).Post("/update-theme", appHandlers.UpdateThemeHandler)
+		r.With(auth.AuthMiddleware, appHandlers.OrgMiddleware).Post("/update-theme", appHandlers.UpdateThemeHandler)
 
 		// Multi-app dashboard routes
-		r.With(auth.AuthMiddleware).Get("/user-apps", appHandlers.GetUserAppsHandler)
-		r.With(auth.AuthMiddleware).Delete("/apps/{appId}", appHandlers.DeleteAppHandler)
-		r.With(auth.AuthMiddleware).Get("/check-plan-limit", appHandlers.CheckPlanLimitHandler)
-		r.With(auth.AuthMiddleware).Get("/plan-features", appHandlers.GetPlanFeaturesHandler)
+		r.With(auth.AuthMiddleware, appHandlers.OrgMiddleware).Get("/user-apps", appHandlers.GetUserAppsHandler)
+		r.With(auth.AuthMiddleware, appHandlers.OrgMiddleware).Delete("/apps/{appId}", appHandlers.DeleteAppHandler)
+		r.With(auth.AuthMiddleware, appHandlers.OrgMiddleware).Get("/check-plan-limit", appHandlers.CheckPlanLimitHandler)
+		r.With(auth.AuthMiddleware, appHandlers.OrgMiddleware).Get("/plan-features", appHandlers.GetPlanFeaturesHandler)
+
+		// Versioned REST API for monitors - works with sessions and API keys
+		r.Route("/v1/apps", func(r chi.Router) {
+			r.Use(auth.AuthMiddleware, appHandlers.OrgMiddleware)
+			r.Get("/", appHandlers.ListAppsV1Handler)
+			r.Post("/", appHandlers.CreateAppV1Handler)
+			r.Get("/{appId}", appHandlers.GetAppV1Handler)
+			r.Patch("/{appId}", appHandlers.UpdateAppV1Handler)
+			r.Delete("/{appId}", appHandlers.DeleteAppV1Handler)
+			r.Post("/{appId}/pause", appHandlers.PauseAppV1Handler)
+			r.Post("/{appId}/resume", appHandlers.ResumeAppV1Handler)
+			r.Get("/{appId}/pause-events", appHandlers.GetAppPauseEventsV1Handler)
+			r.Get("/{appId}/checks", appHandlers.GetAppChecksV1Handler)
+			r.Get("/{appId}/incidents", appHandlers.GetAppIncidentsV1Handler)
+			r.Get("/{appId}/incident-updates", appHandlers.ListIncidentUpdatesV1Handler)
+			r.Post("/{appId}/incident-updates", appHandlers.CreateIncidentUpdateV1Handler)
+			r.Delete("/{appId}/incident-updates/{updateId}", appHandlers.DeleteIncidentUpdateV1Handler)
+			r.Get("/{appId}/visibility", appHandlers.GetAppVisibilityV1Handler)
+			r.Put("/{appId}/visibility", appHandlers.SetAppVisibilityV1Handler)
+			r.Post("/{appId}/share-links", appHandlers.CreateShareLinkV1Handler)
+			r.Delete("/{appId}/share-links", appHandlers.RevokeShareLinksV1Handler)
+			r.Get("/{appId}/public-fields", appHandlers.GetPublicFieldsV1Handler)
+			r.Patch("/{appId}/public-fields", appHandlers.UpdatePublicFieldsV1Handler)
+			r.Post("/{appId}/ssl-check", appHandlers.CheckAppSSLV1Handler)
+		})
+
+		// Declarative monitor configuration: export as a file, and apply a file with a dry run first
+		r.Route("/v1/config", func(r chi.Router) {
+			r.Use(auth.AuthMiddleware, appHandlers.OrgMiddleware)
+			r.Get("/", appHandlers.ExportConfigV1Handler)
+			r.Post("/apply", appHandlers.ApplyConfigV1Handler)
+		})
+
+		// Status pages that show several apps as components, optionally in sections
+		r.Route("/v1/status-pages", func(r chi.Router) {
+			r.Use(auth.AuthMiddleware, appHandlers.OrgMiddleware)
+			r.Get("/", appHandlers.ListStatusPagesV1Handler)
+			r.Post("/", appHandlers.CreateStatusPageV1Handler)
+			r.Get("/{pageId}", appHandlers.GetStatusPageV1Handler)
+			r.Put("/{pageId}", appHandlers.UpdateStatusPageV1Handler)
+			r.Delete("/{pageId}", appHandlers.DeleteStatusPageV1Handler)
+			r.Get("/{pageId}/domain", appHandlers.GetStatusPageDomainV1Handler)
+			r.Put("/{pageId}/domain", appHandlers.SetStatusPageDomainV1Handler)
+			r.Delete("/{pageId}/domain", appHandlers.DeleteStatusPageDomainV1Handler)
+			r.Post("/{pageId}/domain/verify", appHandlers.VerifyStatusPageDomainV1Handler)
+		})
 
-		// Stripe payment routes
-		r.With(auth.AuthMiddleware).Post("/create-checkout-session", appHandlers.CreateCheckoutSessionHandler)
-		r.With(auth.AuthMiddleware).Post("/create-portal-session", appHandlers.CreateCustomerPortalSessionHandler)
-		r.With(auth.AuthMiddleware).Get("/stripe-success", appHandlers.StripeSuccessHandler) // Handle successful payment
-		r.Post("/stripe-webhook", appHandlers.StripeWebhookHandler)                          // No auth - Stripe signs the request
+		// Organizations - members are managed per organization in the URL; other
+		// routes act on the active organization (X-Org-Id header or the switched-to one)
+		r.Route("/orgs", func(r chi.Router) {
+			r.Use(auth.AuthMiddleware)
+			r.With(appHandlers.OrgMiddleware).Get("/", appHandlers.GetOrgsHandler)
+			r.Post("/", appHandlers.CreateOrgHandler)
+			r.With(auth.RequireSession).Post("/{orgId}/switch", appHandlers.SwitchOrgHandler)
+			r.Get("/{orgId}/members", appHandlers.GetOrgMembersHandler)
+			r.Patch("/{orgId}/members/{userId}", appHandlers.UpdateOrgMemberHandler)
+			r.Delete("/{orgId}/members/{userId}", appHandlers.RemoveOrgMemberHandler)
+			r.Get("/{orgId}/invitations", appHandlers.GetOrgInvitationsHandler)
+			r.Post("/{orgId}/invitations", appHandlers.CreateOrgInvitationHandler)
+			r.Delete("/{orgId}/invitations/{invitationId}", appHandlers.DeleteOrgInvitationHandler)
+			r.Get("/{orgId}/security", appHandlers.GetOrgSecurityHandler)
+			r.With(auth.RequireSession).Put("/{orgId}/security", appHandlers.UpdateOrgSecurityHandler)
+		})
+		r.With(auth.AuthMiddleware, auth.RequireSession).Post("/invitations/accept", appHandlers.AcceptInvitationHandler)
+
+		// Audit log of the active organization, as JSON or CSV with ?format=csv
+		r.With(auth.AuthMiddleware, appHandlers.OrgMiddleware).Get("/audit-log", appHandlers.GetAuditLogHandler)
+		r.With(auth.AuthMiddleware).Get("/audit-log/personal", appHandlers.GetPersonalAuditLogHandler) // changes to your own account
+
+		// Personal API keys - managing keys needs a signed-in session, not a key
+		r.Route("/api-keys", func(r chi.Router) {
+			r.Use(auth.AuthMiddleware, auth.RequireSession)
+			r.Get("/", appHandlers.GetAPIKeysHandler)
+			r.Post("/", appHandlers.CreateAPIKeyHandler)
+			r.Delete("/{keyId}", appHandlers.RevokeAPIKeyHandler)
+		})
+
+		// Two-factor authentication - like API keys, only managed from a signed-in session
+		r.Route("/2fa", func(r chi.Router) {
+			r.Use(auth.AuthMiddleware, auth.RequireSession)
+			r.Get("/", appHandlers.GetTwoFactorHandler)
+			r.Post("/setup", appHandlers.SetupTwoFactorHandler)
+			r.Post("/enable", appHandlers.EnableTwoFactorHandler)
+			r.Post("/disable", appHandlers.DisableTwoFactorHandler)
+			r.Post("/recovery-codes", appHandlers.RegenerateRecoveryCodesHandler)
+		})
+
+		// Signed-in browsers, to review and sign out of them
+		r.Route("/sessions", func(r chi.Router) {
+			r.Use(auth.AuthMiddleware, auth.RequireSession)
+			r.Get("/", appHandlers.GetSessionsHandler)
+			r.Delete("/", appHandlers.RevokeAllSessionsHandler)
+			r.Delete("/{sessionId}", appHandlers.RevokeSessionHandler)
+		})
+
+		// Stripe payment routes (503 when Stripe is not configured)
+		r.Group(func(r chi.Router) {
+			r.Use(appHandlers.StripeEnabledMiddleware)
+			r.With(auth.AuthMiddleware, appHandlers.OrgMiddleware).Post("/create-checkout-session", appHandlers.CreateCheckoutSessionHandler)
+			r.With(auth.AuthMiddleware, appHandlers.OrgMiddleware).Post("/create-portal-session", appHandlers.CreateCustomerPortalSessionHandler)
+			r.With(auth.AuthMiddleware).Get("/stripe-success", appHandlers.StripeSuccessHandler) // Handle successful payment
+			r.Post("/stripe-webhook", appHandlers.StripeWebhookHandler)                          // No auth - Stripe signs the request
+		})
 
 		// Slack integration routes (Protected - Pro/Business only)
-		r.With(auth.AuthMiddleware).Get("/slack/start-auth", appHandlers.StartSlackAuthHandler)
+		r.With(auth.AuthMiddleware, appHandlers.OrgMiddleware).Get("/slack/start-auth", appHandlers.StartSlackAuthHandler)
 		r.Get("/slack/callback", appHandlers.SlackCallbackHandler)
-		r.With(auth.AuthMiddleware).Post("/slack/save-integration", appHandlers.SaveSlackIntegrationHandler)
-		r.With(auth.AuthMiddleware).Get("/slack/integration", appHandlers.GetSlackIntegrationHandler)
-		r.With(auth.AuthMiddleware).Post("/slack/disable", appHandlers.DisableSlackIntegrationHandler)
+		r.With(auth.AuthMiddleware, appHandlers.OrgMiddleware).Post("/slack/save-integration", appHandlers.SaveSlackIntegrationHandler)
+		r.With(auth.AuthMiddleware, appHandlers.OrgMiddleware).Get("/slack/integration", appHandlers.GetSlackIntegrationHandler)
+		r.With(auth.AuthMiddleware, appHandlers.OrgMiddleware).Post("/slack/disable", appHandlers.DisableSlackIntegrationHandler)
 
 		// Discord integration routes (Protected - Pro/Business only)
-		r.With(auth.AuthMiddleware).Get("/discord/start-auth", appHandlers.StartDiscordAuthHandler)
+		r.With(auth.AuthMiddleware, appHandlers.OrgMiddleware).Get("/discord/start-auth", appHandlers.StartDiscordAuthHandler)
 		r.Get("/discord/callback", appHandlers.DiscordCallbackHandler)
-		r.With(auth.AuthMiddleware).Get("/discord/integration", appHandlers.GetDiscordIntegrationHandler)
-		r.With(auth.AuthMiddleware).Post("/discord/webhook", appHandlers.UpdateDiscordWebhookHandler)
-		r.With(auth.AuthMiddleware).Post("/discord/disable", appHandlers.DisableDiscordIntegrationHandler)
+		r.With(auth.AuthMiddleware, appHandlers.OrgMiddleware).Get("/discord/integration", appHandlers.GetDiscordIntegrationHandler)
+		r.With(auth.AuthMiddleware, appHandlers.OrgMiddleware).Post("/discord/webhook", appHandlers.UpdateDiscordWebhookHandler)
+		r.With(auth.AuthMiddleware, appHandlers.OrgMiddleware).Post("/discord/disable", appHandlers.DisableDiscordIntegrationHandler)
 
 		// Public API - no authentication required
-		r.Get("/public/status/{slug}", appHandlers.GetPublicStatusHandler)
-		r.Get("/public/ping/{slug}", appHandlers.GetCurrentResponseTimeHandler)
-		r.Get("/badge/{slug}", appHandlers.GetUptimeBadgeHandler) // Public uptime badge
+		r.Get("/openapi.json", appHandlers.OpenAPIHandler) // describes the JSON API, see backend/handlers/openapi.go
+		r.Group(func(r chi.Router) {
+			// Limited per visitor, and live pings per status page, see backend/handlers/rate_limit.go
+			r.Use(appHandlers.PublicRateLimitMiddleware)
+			r.Get("/public/status/{slug}", appHandlers.GetPublicStatusHandler)
+			r.Get("/public/status/{slug}/feed.rss", appHandlers.GetStatusFeedRSSHandler)
+			r.Get("/public/status/{slug}/feed.atom", appHandlers.GetStatusFeedAtomHandler)
+			r.Get("/public/status/{slug}/feed.json", appHandlers.GetStatusFeedJSONHandler)
+			r.Post("/public/status/{slug}/subscribe", appHandlers.SubscribeHandler)
+			r.Post("/public/status/{slug}/unlock", appHandlers.UnlockStatusPageHandler) // password protected pages
+			r.Get("/public/status/{slug}/viewer", appHandlers.GetStatusViewerHandler)   // whether the visitor can change the app
+			r.Get("/public/subscriptions/{token}/confirm", appHandlers.ConfirmSubscriptionHandler)
+			r.Get("/public/subscriptions/{token}/unsubscribe", appHandlers.UnsubscribeHandler)
+			r.Post("/public/subscriptions/{token}/unsubscribe", appHandlers.UnsubscribeHandler) // one-click unsubscribe and webhooks
+			r.Get("/public/ping/{slug}", appHandlers.GetCurrentResponseTimeHandler)
+			r.Get("/public/pages/{slug}", appHandlers.GetPublicStatusPageHandler)
+			r.Get("/badge/{slug}", appHandlers.GetUptimeBadgeHandler) // Public uptime badge
+		})
+		r.Get("/tls/ask", appHandlers.TLSAskHandler) // Caddy asks before issuing a certificate for a custom domain
+
+		// Probe agent routes - authenticated with the shared probe token
+		r.Route("/probe", func(r chi.Router) {
+			r.Use(appHandlers.ProbeAuthMiddleware)
+			r.Post("/register", appHandlers.RegisterProbeHandler)
+			r.Get("/checks", appHandlers.GetProbeChecksHandler)
+			r.Post("/results", appHandlers.SubmitProbeResultsHandler)
+		})
 
-		// Admin routes - check if logged-in user is admin email
+		// Admin routes - check if logged-in user has the admin role
 		r.Route("/admin", func(r chi.Router) {
 			r.Get("/check-session", appHandlers.AdminCheckSessionHandler)
 
-			// Protected admin routes - must be logged in with admin email
+			// The impersonated session belongs to the user, so this can't require an admin
+			r.Post("/impersonation/stop", appHandlers.StopImpersonationHandler)
+
+			// Protected admin routes - must be logged in as an admin; changes are audited
 			r.Group(func(r chi.Router) {
 				r.Use(appHandlers.AdminMiddleware)
 				r.Get("/users", appHandlers.GetAllUsersHandler)
 				r.Get("/stats", appHandlers.GetAdminStatsHandler)
+				r.Put("/users/{userId}/plan", appHandlers.AdminSetUserPlanHandler)
+				r.Put("/users/{userId}/admin", appHandlers.AdminSetUserAdminHandler)
+				r.Post("/users/{userId}/disable", appHandlers.AdminDisableUserHandler)
+				r.Post("/users/{userId}/enable", appHandlers.AdminEnableUserHandler)
+				r.Post("/users/{userId}/impersonate", appHandlers.AdminImpersonateHandler)
+				r.Put("/orgs/{orgId}/plan", appHandlers.AdminSetOrgPlanHandler)
+				r.Post("/apps/{appId}/pause", appHandlers.AdminPauseAppHandler)
+				r.Post("/apps/{appId}/resume", appHandlers.AdminResumeAppHandler)
+				r.Get("/probes", appHandlers.AdminGetProbeAgentsHandler)
+				r.Post("/probes/{agentId}/disable", appHandlers.AdminDisableProbeHandler)
+				r.Post("/probes/{agentId}/enable", appHandlers.AdminEnableProbeHandler)
+				r.Get("/audit-log", appHandlers.AdminGetAuditLogHandler)
 			})
 		})
 	})
 
 	//--- OAuth Auth Routes (must come before catch-all) ---
+	// Emailed invitation links
+	r.Get("/invite/{token}", appHandlers.InvitationLinkHandler)
+
 	r.Route("/auth", func(r chi.Router) {
+		r.Get("/providers", appHandlers.GetLoginMethodsHandler)
+		r.With(appHandlers.PublicRateLimitMiddleware).Post("/email", appHandlers.RequestMagicLinkHandler) // each request can send an email
+		r.Post("/2fa", appHandlers.VerifyTwoFactorLoginHandler)                                           // second step of signing in with 2FA on
 		r.Get("/{provider}", handlers.BeginAuthHandler)
 		r.Get("/{provider}/callback", appHandlers.GetAuthHandler)
 		r.Get("/logout", handlers.LogoutHandler)
@@ -228,6 +610,11 @@ func main() {
 		}
 	})
 
+	// --- Server-rendered status pages, which start the React app over the HTML ---
+	appHandlers.SetSPAIndex(filepath.Join(reactBuildDir, "index.html"))
+	r.With(appHandlers.PublicRateLimitMiddleware).Get("/status/{slug}", appHandlers.StatusPageHTMLHandler)
+	r.With(appHandlers.PublicRateLimitMiddleware).Get("/pages/{slug}", appHandlers.StatusPageGroupHTMLHandler)
+
 	// --- Serve React app for frontend routes ---
 	r.Get("/auth", func(w http.ResponseWriter, r *http.Request) {
 		indexPath := filepath.Join(reactBuildDir, "index.html")
@@ -247,8 +634,48 @@ func main() {
 		http.ServeFile(w, r, indexPath)
 	})
 
-	log.Println("Server starting on http://localhost:8080")
-	if err := http.ListenAndServe(":8080", r); err != nil {
-		log.Fatal(err)
+	server := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
 	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		log.Printf("Server starting on %s", cfg.Server.Addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Println("🛑 Shutdown signal received - draining requests and workers")
+
+	// Fail readiness first so no new traffic is routed here
+	appHandlers.MarkShuttingDown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Stop accepting connections and wait for in-flight handlers
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("⚠️ HTTP server shutdown: %v", err)
+	}
+
+	// Stop scheduling new checks and let running ones (and their notifications) finish
+	stopWorkers()
+	if err := healthChecker.Wait(shutdownCtx); err != nil {
+		log.Printf("⚠️ Health checker shutdown: %v", err)
+	}
+	if err := sslChecker.Wait(shutdownCtx); err != nil {
+		log.Printf("⚠️ SSL checker shutdown: %v", err)
+	}
+	if err := subscriberNotifier.Wait(shutdownCtx); err != nil {
+		log.Printf("⚠️ Subscriber notifier shutdown: %v", err)
+	}
+
+	log.Println("👋 Shutdown complete")
 }
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"statusframe/backend/auth"
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/sessions"
+)
+
+// sessionCookie returns an auth-session cookie holding values, using a throwaway store
+func sessionCookie(t *testing.T, values map[interface{}]interface{}) *http.Cookie {
+	t.Helper()
+	auth.Store = sessions.NewCookieStore([]byte("test-session-secret"))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	session, _ := auth.Store.Get(req, "auth-session")
+	for k, v := range values {
+		session.Values[k] = v
+	}
+	if err := session.Save(req, rec); err != nil {
+		t.Fatalf("failed to save session: %v", err)
+	}
+	return rec.Result().Cookies()[0]
+}
+
+func TestAuthMiddleware_RejectsDisabledAccount(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+	auth.EnableAccountChecks(conn)
+	defer auth.EnableAccountChecks(nil)
+
+	mock.ExpectQuery("SELECT disabled_at IS NOT NULL FROM users WHERE id = \\$1").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"disabled"}).AddRow(true))
+
+	handler := auth.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not run for a disabled account")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/user-apps", nil)
+	req.AddCookie(sessionCookie(t, map[interface{}]interface{}{"userId": 42, "user": "spammer"}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestAuthMiddleware_ImpersonationIsReadOnly(t *testing.T) {
+	cookie := sessionCookie(t, map[interface{}]interface{}{
+		"userId":                 42,
+		"user":                   "customer",
+		"impersonatorId":         1,
+		"impersonatorName":       "support",
+		"impersonationStartedAt": time.Now().Unix(),
+	})
+
+	var gotUserId, gotImpersonator interface{}
+	handler := auth.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserId = r.Context().Value("userId")
+		gotImpersonator = r.Context().Value("impersonatorId")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/user-apps", nil)
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if gotUserId != 42 || gotImpersonator != 1 {
+		t.Errorf("context userId = %v, impersonatorId = %v, want 42 and 1", gotUserId, gotImpersonator)
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/apps/5", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("DELETE status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestAdminPauseApp_RecordsAdminAndAudits(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	mock.ExpectQuery("FROM apps WHERE id = \\$1").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(appColumns).
+			AddRow(5, 7, 42, "API", "api", "https://api.example.com", "cyberpunk", "n", nil, false, "2024-01-01", "2024-01-01"))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE apps SET paused = \\$1").WithArgs(true, 5, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO app_pause_events").WithArgs(5, true, 1, "admin").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(1, "admin.force_pause", "app", 5, 7, `{"paused":false}`, `{"paused":true}`, "{}", "192.0.2.1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	h := handlers.NewHandler(conn, config.Default())
+	r := chi.NewRouter()
+	r.Use(withUser(1))
+	r.Post("/api/admin/apps/{appId}/pause", h.AdminPauseAppHandler)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/admin/apps/5/pause", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
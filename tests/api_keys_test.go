@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"statusframe/backend/auth"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestAuthMiddleware_APIKey(t *testing.T) {
+	key, prefix, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("GenerateAPIKey() error: %v", err)
+	}
+	if !strings.HasPrefix(key, auth.APIKeyPrefix) || !strings.HasPrefix(key, prefix) {
+		t.Fatalf("key %q should start with prefix %q", key, prefix)
+	}
+
+	cases := []struct {
+		name       string
+		method     string
+		scope      string // empty means the key is unknown or revoked
+		wantStatus int
+	}{
+		{name: "read key can GET", method: http.MethodGet, scope: "read", wantStatus: http.StatusOK},
+		{name: "read key cannot POST", method: http.MethodPost, scope: "read", wantStatus: http.StatusForbidden},
+		{name: "write key can POST", method: http.MethodPost, scope: "write", wantStatus: http.StatusOK},
+		{name: "revoked key is rejected", method: http.MethodGet, scope: "", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to open sqlmock database: %v", err)
+			}
+			defer db.Close()
+			auth.EnableAPIKeys(db)
+			defer auth.EnableAPIKeys(nil)
+
+			rows := sqlmock.NewRows([]string{"id", "user_id", "username", "scope"})
+			if tc.scope != "" {
+				rows.AddRow(7, 42, "owner", tc.scope)
+			}
+			mock.ExpectQuery("FROM api_keys").WithArgs(hash).WillReturnRows(rows)
+			if tc.wantStatus == http.StatusOK {
+				mock.ExpectExec("UPDATE api_keys SET last_used_at").WithArgs(7).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			var gotUserId interface{}
+			handler := auth.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUserId = r.Context().Value("userId")
+			}))
+
+			req := httptest.NewRequest(tc.method, "/api/user-apps", nil)
+			req.Header.Set("Authorization", "Bearer "+key)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.wantStatus)
+			}
+			if tc.wantStatus == http.StatusOK && gotUserId != 42 {
+				t.Errorf("userId in context = %v, want 42", gotUserId)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestRequireSession_RejectsAPIKeys(t *testing.T) {
+	key, _, hash, err := auth.GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("GenerateAPIKey() error: %v", err)
+	}
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer db.Close()
+	auth.EnableAPIKeys(db)
+	defer auth.EnableAPIKeys(nil)
+
+	mock.ExpectQuery("FROM api_keys").WithArgs(hash).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "username", "scope"}).AddRow(7, 42, "owner", "write"))
+	mock.ExpectExec("UPDATE api_keys SET last_used_at").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	handler := auth.AuthMiddleware(auth.RequireSession(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("key management must not be reachable with an API key")
+	})))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/api-keys", nil)
+	req.Header.Set("Authorization", "Bearer "+key)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
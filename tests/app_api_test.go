@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+)
+
+var appColumns = []string{"id", "org_id", "user_id", "app_name", "slug", "health_url", "theme", "alerts", "logo_url", "paused", "created_at", "updated_at"}
+
+type sslCheckRecorder struct {
+	checked chan int
+}
+
+func (s *sslCheckRecorder) CheckAppSSL(appID int) {
+	s.checked <- appID
+}
+
+// newAppAPIRouter serves the v1 app routes as userId acting in organization orgId with role
+func newAppAPIRouter(h *handlers.Handler, userId, orgId int, role string) http.Handler {
+	r := chi.NewRouter()
+	r.Use(withUser(userId), withOrg(orgId, role))
+	r.Get("/api/v1/apps/{appId}", h.GetAppV1Handler)
+	r.Patch("/api/v1/apps/{appId}", h.UpdateAppV1Handler)
+	return r
+}
+
+func TestUpdateAppV1_HealthURLChangeTriggersSSLCheck(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	h := handlers.NewHandler(conn, config.Default())
+	ssl := &sslCheckRecorder{checked: make(chan int, 1)}
+	h.SetSSLChecker(ssl)
+
+	mock.ExpectQuery("FROM apps WHERE id = \\$1").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(appColumns).
+			AddRow(5, 7, 42, "API", "api", "http://old.example.com", "cyberpunk", "n", nil, false, "2024-01-01", "2024-01-01"))
+	mock.ExpectExec("UPDATE apps SET updated_at = NOW\\(\\), health_url = \\$1, next_check_at = NOW\\(\\), theme = \\$2 WHERE id = \\$3 AND org_id = \\$4").
+		WithArgs("https://new.example.com", "matrix", 5, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(42, "app.update", "app", 5, 7, sqlmock.AnyArg(), sqlmock.AnyArg(), "{}", "192.0.2.1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("FROM apps WHERE id = \\$1").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(appColumns).
+			AddRow(5, 7, 42, "API", "api", "https://new.example.com", "matrix", "n", nil, false, "2024-01-01", "2024-01-02"))
+
+	body := `{"health_url": "https://new.example.com", "theme": "matrix"}`
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/apps/5", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	newAppAPIRouter(h, 42, 7, "editor").ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var resp struct {
+		App struct {
+			HealthUrl string `json:"health_url"`
+			Theme     string `json:"theme"`
+		} `json:"app"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.App.HealthUrl != "https://new.example.com" || resp.App.Theme != "matrix" {
+		t.Errorf("response app = %+v, want updated health_url and theme", resp.App)
+	}
+
+	select {
+	case appID := <-ssl.checked:
+		if appID != 5 {
+			t.Errorf("SSL re-check for app %d, want 5", appID)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected an SSL re-check after the health URL changed")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestAppV1_ErrorBodies(t *testing.T) {
+	cases := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "other organization's app is not found", method: http.MethodGet, wantStatus: http.StatusNotFound, wantCode: "not_found"},
+		{name: "invalid slug is rejected", method: http.MethodPatch, body: `{"slug": "Not Valid"}`, wantStatus: http.StatusBadRequest, wantCode: "validation_failed"},
+		{name: "unknown field is rejected", method: http.MethodPatch, body: `{"helth_url": "https://x"}`, wantStatus: http.StatusBadRequest, wantCode: "invalid_request"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to open sqlmock database: %v", err)
+			}
+			defer conn.Close()
+
+			orgId := 7
+			if tc.wantStatus == http.StatusNotFound {
+				orgId = 99
+			}
+			mock.ExpectQuery("FROM apps WHERE id = \\$1").WithArgs(5).
+				WillReturnRows(sqlmock.NewRows(appColumns).
+					AddRow(5, orgId, 42, "API", "api", "https://api.example.com", "cyberpunk", "n", nil, false, "2024-01-01", "2024-01-01"))
+
+			req := httptest.NewRequest(tc.method, "/api/v1/apps/5", strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+			newAppAPIRouter(handlers.NewHandler(conn, config.Default()), 42, 7, "editor").ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.wantStatus)
+			}
+			var resp struct {
+				Error handlers.APIError `json:"error"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode error body: %v", err)
+			}
+			if resp.Error.Code != tc.wantCode || resp.Error.Message == "" {
+				t.Errorf("error = %+v, want code %q with a message", resp.Error, tc.wantCode)
+			}
+		})
+	}
+}
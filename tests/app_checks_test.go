@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
+	"statusframe/client"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+)
+
+var checkColumns = []string{"id", "status_code", "response_time_ms", "checked_at"}
+
+// newChecksServer serves the check history routes
+func newChecksServer(h *handlers.Handler) *httptest.Server {
+	r := chi.NewRouter()
+	r.Use(withUser(42), withOrg(7, "editor"))
+	r.Get("/api/v1/apps/{appId}/checks", h.GetAppChecksV1Handler)
+	r.Get("/api/v1/apps/{appId}/incidents", h.GetAppIncidentsV1Handler)
+	r.Post("/api/v1/apps/{appId}/ssl-check", h.CheckAppSSLV1Handler)
+	return httptest.NewServer(r)
+}
+
+func expectApp(mock sqlmock.Sqlmock, healthURL string) {
+	mock.ExpectQuery("FROM apps WHERE id = \\$1").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(appColumns).
+			AddRow(5, 7, 42, "API", "api", healthURL, "cyberpunk", "n", nil, false, "2024-01-01", "2024-01-01"))
+}
+
+func TestListChecks_PollsAfterTheLastSeenCheck(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	server := newChecksServer(handlers.NewHandler(conn, config.Default()))
+	defer server.Close()
+	c := client.New(server.URL, "upl_test")
+
+	checkedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// The first call returns the latest checks, oldest first
+	expectApp(mock, "https://api.example.com")
+	mock.ExpectQuery("SELECT id, status_code, response_time_ms, checked_at FROM \\(").WithArgs(5, 2).
+		WillReturnRows(sqlmock.NewRows(checkColumns).
+			AddRow(10, 200, 120, checkedAt).
+			AddRow(11, 503, nil, checkedAt.Add(time.Minute)))
+
+	checks, err := c.ListChecks(context.Background(), 5, 0, 2)
+	if err != nil {
+		t.Fatalf("ListChecks: %v", err)
+	}
+	if len(checks) != 2 || checks[1].ID != 11 || checks[1].Status != "down" || checks[1].ResponseTimeMs != nil {
+		t.Fatalf("checks = %+v, want checks 10 and 11 with 11 down and no response time", checks)
+	}
+	if checks[0].ResponseTimeMs == nil || *checks[0].ResponseTimeMs != 120 {
+		t.Errorf("check 10 response time = %v, want 120", checks[0].ResponseTimeMs)
+	}
+
+	// Polling with the last ID only asks for newer checks, with the default limit
+	expectApp(mock, "https://api.example.com")
+	mock.ExpectQuery("FROM user_status\\s+WHERE app_id = \\$1 AND id > \\$3").WithArgs(5, 100, 11).
+		WillReturnRows(sqlmock.NewRows(checkColumns).AddRow(12, 200, 95, checkedAt.Add(2*time.Minute)))
+
+	checks, err = c.ListChecks(context.Background(), 5, 11, 0)
+	if err != nil {
+		t.Fatalf("ListChecks after 11: %v", err)
+	}
+	if len(checks) != 1 || checks[0].ID != 12 || checks[0].Status != "up" {
+		t.Errorf("checks = %+v, want only check 12", checks)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCheckSSL_NeedsAnHTTPSURLAndAPaidPlan(t *testing.T) {
+	cases := []struct {
+		name       string
+		plan       string
+		healthURL  string
+		wantStatus int
+	}{
+		{name: "starts a check", plan: "pro", healthURL: "https://api.example.com", wantStatus: http.StatusAccepted},
+		{name: "free plan is refused", plan: "free", healthURL: "https://api.example.com", wantStatus: http.StatusForbidden},
+		{name: "plain HTTP has no certificate", plan: "business", healthURL: "http://api.example.com", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to open sqlmock database: %v", err)
+			}
+			defer conn.Close()
+
+			h := handlers.NewHandler(conn, config.Default())
+			ssl := &sslCheckRecorder{checked: make(chan int, 1)}
+			h.SetSSLChecker(ssl)
+			server := newChecksServer(h)
+			defer server.Close()
+
+			expectApp(mock, tc.healthURL)
+			mock.ExpectQuery("SELECT plan FROM organizations").WithArgs(7).
+				WillReturnRows(sqlmock.NewRows([]string{"plan"}).AddRow(tc.plan))
+
+			err = client.New(server.URL, "upl_test").CheckSSL(context.Background(), 5)
+			if tc.wantStatus == http.StatusAccepted {
+				if err != nil {
+					t.Fatalf("CheckSSL: %v", err)
+				}
+				select {
+				case appID := <-ssl.checked:
+					if appID != 5 {
+						t.Errorf("SSL check for app %d, want 5", appID)
+					}
+				case <-time.After(time.Second):
+					t.Error("expected an SSL check to start")
+				}
+			} else if apiErr, ok := err.(*client.Error); !ok || apiErr.StatusCode != tc.wantStatus {
+				t.Errorf("err = %v, want a %d", err, tc.wantStatus)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
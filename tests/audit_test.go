@@ -0,0 +1,182 @@
+package tests
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+)
+
+var auditColumns = []string{"id", "actor_user_id", "username", "action", "target_type", "target_id", "org_id",
+	"before_value", "after_value", "details", "ip_address", "created_at"}
+
+// newAuditLogRequest calls GetAuditLogHandler as a member of org 7 with the given role
+func newAuditLogRequest(h *handlers.Handler, role, query string) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Use(withUser(42), withOrg(7, role))
+	r.Get("/api/audit-log", h.GetAuditLogHandler)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/audit-log"+query, nil))
+	return rec
+}
+
+func TestUpdateTheme_AuditsBeforeAndAfter(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	mock.ExpectQuery("FROM apps WHERE slug = \\$1").WithArgs("api").
+		WillReturnRows(sqlmock.NewRows(appColumns).
+			AddRow(5, 7, 42, "API", "api", "https://api.example.com", "cyberpunk", "n", nil, false, "2024-01-01", "2024-01-01"))
+	mock.ExpectExec("UPDATE apps SET theme").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(42, "app.theme_change", "app", 5, 7, `{"theme":"cyberpunk"}`, `{"theme":"matrix"}`, "{}", "192.0.2.1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	h := handlers.NewHandler(conn, config.Default())
+	req := httptest.NewRequest(http.MethodPost, "/api/update-theme", strings.NewReader(`{"theme": "matrix", "slug": "api"}`))
+	rec := httptest.NewRecorder()
+	withUser(42)(withOrg(7, "editor")(http.HandlerFunc(h.UpdateThemeHandler))).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetAuditLog_MembersOnlySeeTheirOwnEntries(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	mock.ExpectQuery("FROM audit_log l .* AND l.org_id = \\$1 AND l.actor_user_id = \\$2 .* LIMIT \\$3").
+		WithArgs(7, 42, 100).
+		WillReturnRows(sqlmock.NewRows(auditColumns))
+
+	rec := newAuditLogRequest(handlers.NewHandler(conn, config.Default()), "editor", "")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetAuditLog_CSVExport(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM audit_log l .* AND l.org_id = \\$1 .* LIMIT \\$2").
+		WithArgs(7, 10000).
+		WillReturnRows(sqlmock.NewRows(auditColumns).
+			AddRow(1, 42, "=HYPERLINK()", "app.delete", "app", 5, 7, []byte(`{"slug":"api"}`), nil, []byte(`{}`), "192.0.2.1", createdAt))
+
+	rec := newAuditLogRequest(handlers.NewHandler(conn, config.Default()), "admin", "?format=csv")
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Content-Type = %q, want text/csv", ct)
+	}
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse CSV: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d CSV rows, want a header and one entry", len(records))
+	}
+	row := records[1]
+	if row[1] != "2024-03-01T12:00:00Z" || row[4] != "app.delete" || row[8] != `{"slug":"api"}` || row[9] != "" {
+		t.Errorf("unexpected CSV row: %q", row)
+	}
+	if row[3] != "'=HYPERLINK()" {
+		t.Errorf("actor name = %q, want it escaped against formula injection", row[3])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestAudit_RecordsVisitorBehindTrustedProxy(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	mock.ExpectQuery("FROM apps WHERE slug = \\$1").WithArgs("api").
+		WillReturnRows(sqlmock.NewRows(appColumns).
+			AddRow(5, 7, 42, "API", "api", "https://api.example.com", "cyberpunk", "n", nil, false, "2024-01-01", "2024-01-01"))
+	mock.ExpectExec("UPDATE apps SET theme").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(42, "app.theme_change", "app", 5, 7, sqlmock.AnyArg(), sqlmock.AnyArg(), "{}", "203.0.113.9").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// httptest requests come from 192.0.2.1, standing in for Caddy
+	cfg := config.Default()
+	cfg.Server.TrustedProxies = []string{"192.0.2.0/24"}
+	h := handlers.NewHandler(conn, cfg)
+	r := chi.NewRouter()
+	r.Use(withUser(42), withOrg(7, "editor"))
+	r.Post("/api/update-theme", h.UpdateThemeHandler)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/update-theme", strings.NewReader(`{"theme": "matrix", "slug": "api"}`))
+	req.Header.Set("X-Forwarded-For", "198.51.100.7, 203.0.113.9")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestGetPersonalAuditLog_OwnAccountChanges(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	mock.ExpectQuery("FROM audit_log l .* AND l.actor_user_id = \\$1 AND l.org_id IS NULL .* LIMIT \\$2").
+		WithArgs(42, 100).
+		WillReturnRows(sqlmock.NewRows(auditColumns).
+			AddRow(1, 42, "alice", "user.2fa_enable", "user", 42, nil, nil, nil, []byte(`{}`), "203.0.113.9", time.Now()))
+
+	h := handlers.NewHandler(conn, config.Default())
+	r := chi.NewRouter()
+	r.With(withUser(42)).Get("/api/audit-log/personal", h.GetPersonalAuditLogHandler)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/audit-log/personal", nil))
+
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "user.2fa_enable") {
+		t.Fatalf("status = %d, want %d with the 2FA entry: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
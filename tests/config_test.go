@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"statusframe/backend/config"
+	"statusframe/db"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestConfig_FileThenEnvOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	file := `{
+		"server": {"addr": ":9090"},
+		"database": {"host": "pg.internal", "password": "from-file"},
+		"worker": {"check_interval": "45s"},
+		"plans": {"free": {"max_monitors": 3, "min_check_interval": 120, "data_retention_days": 14}}
+	}`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	t.Setenv("DB_PASSWORD", "from-env")
+	t.Setenv("ADMIN_EMAILS", "admin@example.test, ops@example.test")
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	if cfg.Server.Addr != ":9090" {
+		t.Errorf("server.addr = %q, want :9090", cfg.Server.Addr)
+	}
+	if cfg.Database.Host != "pg.internal" || cfg.Database.Password != "from-env" {
+		t.Errorf("database = %+v, want host from file and password from env", cfg.Database)
+	}
+	if cfg.Worker.CheckInterval.Duration != 45*time.Second {
+		t.Errorf("worker.check_interval = %s, want 45s", cfg.Worker.CheckInterval)
+	}
+	if cfg.Plans["free"].MaxMonitors != 3 {
+		t.Errorf("plans.free.max_monitors = %d, want 3", cfg.Plans["free"].MaxMonitors)
+	}
+	if !cfg.Admin.IsAdminEmail("OPS@example.test") {
+		t.Errorf("expected ops@example.test to be an admin, got %v", cfg.Admin.Emails)
+	}
+}
+
+func TestConfig_UnknownFileFieldIsRejected(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"server": {"adress": ":9090"}}`), 0o600); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	if _, err := config.Load(path); err == nil {
+		t.Fatal("expected an error for a misspelled field")
+	}
+}
+
+func TestConfig_ValidateReportsAllProblems(t *testing.T) {
+	cfg := config.Default()
+	cfg.Worker.CheckInterval.Duration = time.Second
+	cfg.Slack.ClientID = "slack-id"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation to fail")
+	}
+
+	for _, want := range []string{
+		"database.password",
+		"worker.check_interval",
+		"auth.google_client_id",
+		"auth.session_secret",
+		"slack.client_secret",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("validation error does not mention %s:\n%v", want, err)
+		}
+	}
+}
+
+func TestConfig_OptionalIntegrationsStayDisabled(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database.Password = "secret"
+	cfg.Auth.GoogleClientID = "google-id"
+	cfg.Auth.GoogleClientSecret = "google-secret"
+	cfg.Auth.SessionSecret = "session-secret"
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("minimal config should be valid: %v", err)
+	}
+	if cfg.Stripe.Enabled() || cfg.Slack.Enabled() || cfg.Discord.Enabled() || cfg.Probe.Enabled() {
+		t.Error("optional integrations should be disabled by default")
+	}
+}
+
+func TestCleanupOldStatusChecks_UsesConfiguredRetention(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	defaults := map[string]db.PlanFeatures{}
+	for _, plan := range []string{"free", "pro", "business"} {
+		defaults[plan] = db.GetPlanFeatures(plan)
+	}
+	defer db.SetPlanFeatures(defaults)
+
+	free, pro := db.GetPlanFeatures("free"), db.GetPlanFeatures("pro")
+	free.DataRetentionDays, pro.DataRetentionDays = 3, 45
+	db.SetPlanFeatures(map[string]db.PlanFeatures{"free": free, "pro": pro})
+
+	mock.ExpectExec("DELETE FROM user_status").WithArgs("free", 3).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM user_status").WithArgs("pro", 45).WillReturnResult(sqlmock.NewResult(0, 0))
+	// Organizations on a plan that's no longer configured get the free plan's retention
+	mock.ExpectExec("o.plan <> ALL").WithArgs(`{"free","pro"}`, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM region_checks").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := db.CleanupOldStatusChecks(conn); err != nil {
+		t.Fatalf("CleanupOldStatusChecks() error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
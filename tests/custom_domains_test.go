@@ -0,0 +1,228 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+)
+
+var customDomainColumns = []string{"page_id", "domain", "token", "verified_at", "created_at"}
+
+// newDomainRouter serves the domain routes of status pages
+func newDomainRouter(h *handlers.Handler) http.Handler {
+	r := chi.NewRouter()
+	r.Use(withUser(42), withOrg(7, "editor"))
+	r.Put("/api/v1/status-pages/{pageId}/domain", h.SetStatusPageDomainV1Handler)
+	r.Post("/api/v1/status-pages/{pageId}/domain/verify", h.VerifyStatusPageDomainV1Handler)
+	return r
+}
+
+// expectStatusPage expects page 3 of organization 7 to be loaded, without components
+func expectStatusPage(mock sqlmock.Sqlmock) {
+	now := time.Now()
+	mock.ExpectQuery("FROM status_pages WHERE id = \\$1").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(statusPageColumns).
+			AddRow(3, 7, "acme", "Acme", "", "cyberpunk", nil, now, now))
+	mock.ExpectQuery("FROM status_page_components c").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(pageLayoutColumns))
+}
+
+func TestCustomDomain_SetThenVerifyByTXTRecord(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	h := handlers.NewHandler(conn, config.Default())
+	var records []string
+	var askedFor string
+	h.SetTXTResolver(func(ctx context.Context, name string) ([]string, error) {
+		askedFor = name
+		if records == nil {
+			return nil, errors.New("no such host")
+		}
+		return records, nil
+	})
+	router := newDomainRouter(h)
+
+	// Setting the domain returns the TXT record to create
+	expectStatusPage(mock)
+	mock.ExpectQuery("SELECT plan FROM organizations").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"plan"}).AddRow("pro"))
+	mock.ExpectQuery("FROM custom_domains WHERE page_id = \\$1").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(customDomainColumns))
+	mock.ExpectExec("INSERT INTO custom_domains").WithArgs(3, "status.example.com", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(42, "status_page.domain_change", "status_page", 3, 7, nil, sqlmock.AnyArg(), "{}", "192.0.2.1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/status-pages/3/domain", strings.NewReader(`{"domain": "Status.Example.com."}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("set status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var set handlers.DomainResponse
+	if err := json.NewDecoder(rec.Body).Decode(&set); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if set.Domain != "status.example.com" || set.Verified || set.TXTName != "_uplitycs-challenge.status.example.com" ||
+		!strings.HasPrefix(set.TXTValue, "uplitycs-verification=") || set.CNAMETarget != "localhost" {
+		t.Fatalf("domain = %+v, want status.example.com unverified with a TXT record to create", set)
+	}
+	token := strings.TrimPrefix(set.TXTValue, "uplitycs-verification=")
+
+	verify := func(wantStatus int) {
+		t.Helper()
+		expectStatusPage(mock)
+		mock.ExpectQuery("FROM custom_domains WHERE page_id = \\$1").WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(customDomainColumns).AddRow(3, "status.example.com", token, nil, time.Now()))
+		if wantStatus == http.StatusOK {
+			mock.ExpectExec("UPDATE custom_domains SET verified_at = NOW\\(\\)").WithArgs(3).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/status-pages/3/domain/verify", nil))
+		if rec.Code != wantStatus {
+			t.Fatalf("verify status = %d, want %d: %s", rec.Code, wantStatus, rec.Body.String())
+		}
+	}
+
+	// Without the record, or with another value, the domain stays unverified
+	verify(http.StatusBadRequest)
+	records = []string{"v=spf1 -all", "uplitycs-verification=someone-else"}
+	verify(http.StatusBadRequest)
+
+	records = append(records, set.TXTValue)
+	verify(http.StatusOK)
+	if askedFor != set.TXTName {
+		t.Errorf("looked up %q, want %q", askedFor, set.TXTName)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCustomDomain_RejectsOurOwnDomain(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	cfg := config.Default()
+	cfg.Server.PublicURL = "https://statusframe.com"
+	router := newDomainRouter(handlers.NewHandler(conn, cfg))
+
+	for _, domain := range []string{"statusframe.com", "evil.statusframe.com", "not a domain", "localhost"} {
+		expectStatusPage(mock)
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/status-pages/3/domain", strings.NewReader(`{"domain": "`+domain+`"}`))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", domain, rec.Code, http.StatusBadRequest)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCustomDomainMiddleware_RoutesByHost(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	cfg := config.Default()
+	cfg.Server.PublicURL = "https://statusframe.com"
+	h := handlers.NewHandler(conn, cfg)
+
+	r := chi.NewRouter()
+	r.Use(h.CustomDomainMiddleware)
+	r.Get("/tls/ask", h.TLSAskHandler)
+	r.Get("/*", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("app"))
+	})
+
+	// The verified domains are loaded once, by the first host that could be one
+	cases := []struct {
+		name         string
+		host         string
+		path         string
+		load         bool
+		wantStatus   int
+		wantLocation string
+	}{
+		{name: "our own host", host: "statusframe.com", path: "/dashboard", wantStatus: http.StatusOK},
+		{name: "container name", host: "app:8080", path: "/api/v1/apps", wantStatus: http.StatusOK},
+		{name: "unknown domain", host: "other.example.com", path: "/", load: true, wantStatus: http.StatusOK},
+		{name: "another unknown domain", host: "random.example.net", path: "/", wantStatus: http.StatusOK},
+		{name: "root redirects to the page", host: "status.example.com", path: "/",
+			wantStatus: http.StatusFound, wantLocation: "/pages/acme"},
+		{name: "the page is served", host: "Status.Example.com:443", path: "/pages/acme", wantStatus: http.StatusOK},
+		{name: "its data is served", host: "status.example.com", path: "/api/public/pages/acme", wantStatus: http.StatusOK},
+		{name: "other pages are not", host: "status.example.com", path: "/pages/other", wantStatus: http.StatusNotFound},
+		{name: "neither is the dashboard", host: "status.example.com", path: "/api/v1/apps", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.load {
+				mock.ExpectQuery("FROM custom_domains d").
+					WillReturnRows(sqlmock.NewRows([]string{"domain", "slug"}).AddRow("status.example.com", "acme"))
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Host = tc.host
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tc.wantStatus)
+			}
+			if loc := rec.Header().Get("Location"); loc != tc.wantLocation {
+				t.Errorf("Location = %q, want %q", loc, tc.wantLocation)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %v", err)
+			}
+		})
+	}
+
+	// Caddy only gets certificates for verified domains
+	for domain, want := range map[string]int{"status.example.com": http.StatusOK, "other.example.com": http.StatusNotFound} {
+		rows := sqlmock.NewRows([]string{"slug"})
+		if want == http.StatusOK {
+			rows.AddRow("acme")
+		}
+		mock.ExpectQuery("FROM custom_domains d").WithArgs(domain).WillReturnRows(rows)
+
+		req := httptest.NewRequest(http.MethodGet, "/tls/ask?domain="+domain, nil)
+		req.Host = "app:8080"
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("ask for %s: status = %d, want %d", domain, rec.Code, want)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+package tests
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	statusChangeColumns   = []string{"id", "status", "previous", "status_code", "checked_at"}
+	incidentUpdateColumns = []string{"id", "app_id", "status", "message", "created_by", "created_at"}
+)
+
+// expectFeed expects the feed of app "api" to be read: it went down at 12:00, an
+// update was posted at 12:05 and it came back up at 12:10
+func expectFeed(mock sqlmock.Sqlmock) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM apps WHERE slug = \\$1").WithArgs("api").
+		WillReturnRows(sqlmock.NewRows(appColumns).
+			AddRow(5, 7, 42, "Acme API", "api", "https://api.example.com", "cyberpunk", "n", nil, false, "2024-01-01T00:00:00Z", "2024-01-02T00:00:00Z"))
+	expectPublicPage(mock, 5)
+	expectPublicFields(mock, 5)
+	mock.ExpectQuery("SELECT plan FROM organizations").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"plan"}).AddRow("pro"))
+	mock.ExpectQuery("LAG\\(status\\)").WithArgs(5, 30, 50).
+		WillReturnRows(sqlmock.NewRows(statusChangeColumns).
+			AddRow(11, "up", "down", 200, start.Add(10*time.Minute)).
+			AddRow(10, "down", "up", 503, start))
+	mock.ExpectQuery("FROM incident_updates WHERE app_id = \\$1").WithArgs(5, 50).
+		WillReturnRows(sqlmock.NewRows(incidentUpdateColumns).
+			AddRow(3, 5, "identified", "The database is out of connections", 42, start.Add(5*time.Minute)))
+}
+
+func TestStatusFeeds_ListChangesAndUpdatesNewestFirst(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	h := handlers.NewHandler(conn, config.Default())
+	r := chi.NewRouter()
+	r.Get("/api/public/status/{slug}/feed.rss", h.GetStatusFeedRSSHandler)
+	r.Get("/api/public/status/{slug}/feed.atom", h.GetStatusFeedAtomHandler)
+	r.Get("/api/public/status/{slug}/feed.json", h.GetStatusFeedJSONHandler)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		t.Helper()
+		expectFeed(mock)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d: %s", path, rec.Code, http.StatusOK, rec.Body.String())
+		}
+		if rec.Header().Get("ETag") == "" || rec.Header().Get("Last-Modified") != "Wed, 01 May 2024 12:10:00 GMT" {
+			t.Errorf("%s: ETag %q and Last-Modified %q, want an ETag and the newest entry's time",
+				path, rec.Header().Get("ETag"), rec.Header().Get("Last-Modified"))
+		}
+		return rec
+	}
+
+	wantIDs := []string{
+		"http://localhost:8080/status/api#check-11",
+		"http://localhost:8080/status/api#update-3",
+		"http://localhost:8080/status/api#check-10",
+	}
+	wantTitles := []string{"Acme API is back up", "Acme API incident: Identified", "Acme API is down"}
+
+	// RSS 2.0
+	rec := get("/api/public/status/api/feed.rss")
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/rss+xml") {
+		t.Errorf("RSS Content-Type = %q", ct)
+	}
+	var rss struct {
+		Items []struct {
+			Title string `xml:"title"`
+			GUID  struct {
+				IsPermaLink string `xml:"isPermaLink,attr"`
+				Value       string `xml:",chardata"`
+			} `xml:"guid"`
+			Description string `xml:"description"`
+		} `xml:"channel>item"`
+	}
+	if err := xml.Unmarshal(rec.Body.Bytes(), &rss); err != nil {
+		t.Fatalf("failed to decode RSS: %v", err)
+	}
+	if len(rss.Items) != 3 {
+		t.Fatalf("RSS items = %+v, want 3", rss.Items)
+	}
+	for i, item := range rss.Items {
+		if item.Title != wantTitles[i] || item.GUID.Value != wantIDs[i] || item.GUID.IsPermaLink != "false" {
+			t.Errorf("RSS item %d = %+v, want %q with GUID %q", i, item, wantTitles[i], wantIDs[i])
+		}
+	}
+	if d := rss.Items[2].Description; !strings.Contains(d, "from up to down") || !strings.Contains(d, "HTTP 503") {
+		t.Errorf("RSS description = %q, want the change and its status code", d)
+	}
+
+	// Atom
+	rec = get("/api/public/status/api/feed.atom")
+	var atom struct {
+		XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+		Updated string   `xml:"updated"`
+		Entries []struct {
+			ID string `xml:"id"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(rec.Body.Bytes(), &atom); err != nil {
+		t.Fatalf("failed to decode Atom: %v", err)
+	}
+	if atom.Updated != "2024-05-01T12:10:00Z" || len(atom.Entries) != 3 || atom.Entries[1].ID != wantIDs[1] {
+		t.Errorf("Atom feed = %+v, want 3 entries updated at 12:10", atom)
+	}
+
+	// JSON Feed
+	rec = get("/api/public/status/api/feed.json")
+	var jf handlers.JSONFeed
+	if err := json.NewDecoder(rec.Body).Decode(&jf); err != nil {
+		t.Fatalf("failed to decode JSON Feed: %v", err)
+	}
+	if jf.Version != "https://jsonfeed.org/version/1.1" || jf.FeedURL != "http://localhost:8080/api/public/status/api/feed.json" ||
+		len(jf.Items) != 3 || jf.Items[1].ContentText != "The database is out of connections" {
+		t.Errorf("JSON Feed = %+v", jf)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestIncidentUpdates_PostAndDelete(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	h := handlers.NewHandler(conn, config.Default())
+	r := chi.NewRouter()
+	r.Use(withUser(42), withOrg(7, "editor"))
+	r.Post("/api/v1/apps/{appId}/incident-updates", h.CreateIncidentUpdateV1Handler)
+	r.Delete("/api/v1/apps/{appId}/incident-updates/{updateId}", h.DeleteIncidentUpdateV1Handler)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/apps/5/incident-updates", strings.NewReader(body)))
+		return rec
+	}
+
+	// Every problem is reported at once
+	expectApp(mock, "https://api.example.com")
+	rec := post(`{"status": "fixed", "message": "  "}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "status must be one of") ||
+		!strings.Contains(rec.Body.String(), "message is required") {
+		t.Errorf("status = %d, want %d for both fields: %s", rec.Code, http.StatusBadRequest, rec.Body.String())
+	}
+
+	expectApp(mock, "https://api.example.com")
+	mock.ExpectQuery("INSERT INTO incident_updates").WithArgs(5, "investigating", "Checking the database", 42).
+		WillReturnRows(sqlmock.NewRows(incidentUpdateColumns).AddRow(3, 5, "investigating", "Checking the database", 42, time.Now()))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(42, "incident.update_post", "incident_update", 3, 7, nil, sqlmock.AnyArg(), "{}", "192.0.2.1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	rec = post(`{"status": "investigating", "message": " Checking the database "}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var created handlers.IncidentUpdateResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if u := created.IncidentUpdate; u == nil || u.Id != 3 || u.Status != "investigating" {
+		t.Errorf("incident update = %+v, want update 3", u)
+	}
+
+	// Updates of other apps can't be deleted through this one
+	expectApp(mock, "https://api.example.com")
+	mock.ExpectQuery("DELETE FROM incident_updates WHERE id = \\$1 AND app_id = \\$2").WithArgs(4, 5).
+		WillReturnRows(sqlmock.NewRows(incidentUpdateColumns))
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/v1/apps/5/incident-updates/4", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("delete status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
@@ -1,16 +1,21 @@
 package tests
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
 	"statusframe/backend/worker"
 
 	"github.com/DATA-DOG/go-sqlmock"
 )
 
+var dueAppColumns = []string{"id", "user_id", "app_name", "slug", "health_url", "plan", "check_interval", "alert_channels", "in_maintenance"}
+
 func TestHealthChecker_RunImmediateCheck_NoAlert(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -18,7 +23,11 @@ func TestHealthChecker_RunImmediateCheck_NoAlert(t *testing.T) {
 	}
 	defer db.Close()
 
-	hc := worker.NewHealthChecker(db, 1*time.Hour) // long ticker so only immediate run executes
+	// The cleanup routine runs concurrently with the first check
+	mock.MatchExpectationsInOrder(false)
+	expectCleanup(mock)
+
+	hc := worker.NewHealthChecker(db, handlers.NewHandler(db, config.Default()), 1*time.Hour) // long ticker so only immediate run executes
 
 	// HTTP test server that returns 200 OK
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -31,12 +40,17 @@ func TestHealthChecker_RunImmediateCheck_NoAlert(t *testing.T) {
 
 	// Expect the apps selection query (apps due for check)
 	mock.ExpectQuery("SELECT .*FROM apps").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "app_name", "slug", "health_url", "alerts", "plan"}).
-			AddRow(appID, userID, "Test App", "test-slug", ts.URL, "n", "free"))
+		WillReturnRows(sqlmock.NewRows(dueAppColumns).
+			AddRow(appID, userID, "Test App", "test-slug", ts.URL, "free", nil, nil, false))
+
+	// Expect lookup of the previous status (none yet)
+	mock.ExpectQuery("SELECT status_code FROM user_status").
+		WithArgs(appID).
+		WillReturnRows(sqlmock.NewRows([]string{"status_code"}))
 
 	// Expect insert into user_status with status 200
 	mock.ExpectExec("INSERT INTO user_status").
-		WithArgs(appID, 200).
+		WithArgs(appID, 200, sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// Expect update next_check_at for the app
@@ -45,10 +59,15 @@ func TestHealthChecker_RunImmediateCheck_NoAlert(t *testing.T) {
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// Start the checker (runs initial check immediately)
-	go hc.Start()
+	ctx, cancel := context.WithCancel(context.Background())
+	go hc.Start(ctx)
 
 	// wait a short time for the immediate run to complete
 	time.Sleep(200 * time.Millisecond)
+	cancel()
+	if err := hc.Wait(context.Background()); err != nil {
+		t.Fatalf("health checker did not drain: %v", err)
+	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatalf("unmet expectations: %v", err)
@@ -62,7 +81,11 @@ func TestHealthChecker_RunImmediateCheck_WithAlert(t *testing.T) {
 	}
 	defer db.Close()
 
-	hc := worker.NewHealthChecker(db, 1*time.Hour) // long ticker so only immediate run executes
+	// The cleanup routine runs concurrently with the first check
+	mock.MatchExpectationsInOrder(false)
+	expectCleanup(mock)
+
+	hc := worker.NewHealthChecker(db, handlers.NewHandler(db, config.Default()), 1*time.Hour) // long ticker so only immediate run executes
 
 	// HTTP test server that returns 500
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -75,12 +98,17 @@ func TestHealthChecker_RunImmediateCheck_WithAlert(t *testing.T) {
 
 	// apps selection returns one app due
 	mock.ExpectQuery("SELECT .*FROM apps").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "app_name", "slug", "health_url", "alerts", "plan"}).
-			AddRow(appID, userID, "Down App", "down-slug", ts.URL, "y", "free"))
+		WillReturnRows(sqlmock.NewRows(dueAppColumns).
+			AddRow(appID, userID, "Down App", "down-slug", ts.URL, "pro", nil, nil, false))
+
+	// Previous check was up, so this one is a new incident
+	mock.ExpectQuery("SELECT status_code FROM user_status").
+		WithArgs(appID).
+		WillReturnRows(sqlmock.NewRows([]string{"status_code"}).AddRow(200))
 
 	// Expect insert into user_status with status 500
 	mock.ExpectExec("INSERT INTO user_status").
-		WithArgs(appID, 500).
+		WithArgs(appID, 500, sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// Expect update next_check_at for the app
@@ -88,29 +116,38 @@ func TestHealthChecker_RunImmediateCheck_WithAlert(t *testing.T) {
 		WithArgs(sqlmock.AnyArg(), appID).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	// checkAndSendAppAlert flow:
-	// - SELECT sent_at FROM alerts -> return zero rows so alert proceeds
-	mock.ExpectQuery("SELECT sent_at FROM alerts").
+	// Alert flow for pro plans: look up Slack and Discord integrations (none configured)
+	mock.ExpectQuery("SELECT .*FROM slack_integrations").
 		WithArgs(appID).
-		WillReturnRows(sqlmock.NewRows([]string{"sent_at"})) // zero rows
-
-	// - SELECT email FROM users WHERE id = $1 -> return user email
-	mock.ExpectQuery("SELECT email FROM users WHERE id = \\$1").
-		WithArgs(userID).
-		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("owner@example.test"))
+		WillReturnRows(sqlmock.NewRows([]string{"id"})) // zero rows
 
-	// - INSERT INTO alerts (...) -> record alert
-	mock.ExpectExec("INSERT INTO alerts").
+	mock.ExpectQuery("SELECT .*FROM discord_integrations").
 		WithArgs(appID).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+		WillReturnRows(sqlmock.NewRows([]string{"id"})) // zero rows
 
 	// Start the checker (runs initial check immediately)
-	go hc.Start()
+	ctx, cancel := context.WithCancel(context.Background())
+	go hc.Start(ctx)
 
 	// wait a short time for the immediate run to complete
 	time.Sleep(300 * time.Millisecond)
+	cancel()
+	if err := hc.Wait(context.Background()); err != nil {
+		t.Fatalf("health checker did not drain: %v", err)
+	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatalf("unmet expectations: %v", err)
 	}
 }
+
+// expectCleanup registers the data retention queries run when the checker starts: one
+// per configured plan, one for plans that are no longer configured and the region checks
+func expectCleanup(mock sqlmock.Sqlmock) {
+	for i := 0; i < 4; i++ {
+		mock.ExpectExec("DELETE FROM user_status").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectExec("DELETE FROM region_checks").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
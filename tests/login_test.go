@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"statusframe/backend/auth"
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+)
+
+// fakeGitHub serves the OAuth and API endpoints the GitHub login uses
+func fakeGitHub(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "good-code" || r.FormValue("client_secret") != "gh-secret" {
+			json.NewEncoder(w).Encode(map[string]string{"error": "bad_verification_code"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "gh-token", "token_type": "bearer"})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gh-token" {
+			http.Error(w, "bad token", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 583231, "login": "octocat", "email": "public@example.com"})
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"email": "other@example.com", "primary": false, "verified": true},
+			{"email": "Ada@Example.com", "primary": true, "verified": true},
+		})
+	})
+	return httptest.NewServer(mux)
+}
+
+func loginRouter(h *handlers.Handler) *chi.Mux {
+	r := chi.NewRouter()
+	r.Route("/auth", func(r chi.Router) {
+		r.Get("/providers", h.GetLoginMethodsHandler)
+		r.Post("/email", h.RequestMagicLinkHandler)
+		r.Get("/{provider}", handlers.BeginAuthHandler)
+		r.Get("/{provider}/callback", h.GetAuthHandler)
+	})
+	return r
+}
+
+func TestGitHubLogin_LinksExistingUserByVerifiedEmail(t *testing.T) {
+	server := fakeGitHub(t)
+	defer server.Close()
+
+	cfg := config.Default()
+	cfg.Auth.SessionSecret = "test-session-secret"
+	cfg.Auth.GitHubClientID = "gh-client"
+	cfg.Auth.GitHubClientSecret = "gh-secret"
+	cfg.Auth.GitHubRedirectURL = "http://localhost/auth/github/callback"
+	cfg.Auth.GitHubAuthURL = server.URL + "/login/oauth/authorize"
+	cfg.Auth.GitHubTokenURL = server.URL + "/login/oauth/access_token"
+	cfg.Auth.GitHubAPIURL = server.URL
+	if err := auth.NewAuth(cfg.Auth); err != nil {
+		t.Fatalf("NewAuth: %v", err)
+	}
+	defer auth.NewAuth(config.AuthConfig{SessionSecret: "test-session-secret"})
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	mock.ExpectQuery("SELECT user_id FROM user_identities").WithArgs("github", "583231").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectQuery("FROM users WHERE LOWER\\(email\\) = LOWER\\(\\$1\\)").WithArgs("Ada@Example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "avatar_url", "is_admin", "disabled_at"}).
+			AddRow(42, "Ada", "ada@example.com", "", false, nil))
+	mock.ExpectExec("INSERT INTO user_identities").WithArgs(42, "github", "583231", "Ada@Example.com").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM apps").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery("SELECT totp_secret, totp_enabled_at, totp_last_step FROM users").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows(totpColumns).AddRow(nil, nil, nil))
+
+	r := loginRouter(handlers.NewHandler(conn, cfg))
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/github", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil || !strings.HasPrefix(location.String(), cfg.Auth.GitHubAuthURL) {
+		t.Fatalf("begin redirected to %q, want the fake GitHub", rec.Header().Get("Location"))
+	}
+	state := location.Query().Get("state")
+	cookie := rec.Result().Cookies()[0]
+
+	req = httptest.NewRequest(http.MethodGet, "/auth/github/callback?code=good-code&state="+url.QueryEscape(state), nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/dashboard" {
+		t.Fatalf("callback = %d to %q, want %d to /dashboard", rec.Code, rec.Header().Get("Location"), http.StatusFound)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+
+	// The state was used up, so replaying the callback must fail
+	req = httptest.NewRequest(http.MethodGet, "/auth/github/callback?code=good-code&state="+url.QueryEscape(state), nil)
+	req.AddCookie(rec.Result().Cookies()[0])
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Header().Get("Location") != "/auth?error=auth_failed" {
+		t.Errorf("replayed callback redirected to %q, want /auth?error=auth_failed", rec.Header().Get("Location"))
+	}
+}
+
+func TestMagicLink_WorksOnce(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.SessionSecret = "test-session-secret"
+	cfg.Auth.MagicLinks = true
+	if err := auth.NewAuth(cfg.Auth); err != nil {
+		t.Fatalf("NewAuth: %v", err)
+	}
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	hash := auth.HashAPIKey("link-token")
+	mock.ExpectQuery("UPDATE magic_links SET used_at = NOW\\(\\)").WithArgs(hash).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("new@example.com"))
+	mock.ExpectQuery("SELECT user_id FROM user_identities").WithArgs("email", "new@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectQuery("FROM users WHERE LOWER\\(email\\)").WithArgs("new@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "avatar_url", "is_admin", "disabled_at"}))
+	mock.ExpectQuery("INSERT INTO users").WithArgs("new", "", "new@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(77))
+	mock.ExpectExec("INSERT INTO organizations").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT id FROM organizations WHERE personal_user_id").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+	mock.ExpectExec("INSERT INTO org_members").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO user_identities").WithArgs(77, "email", "new@example.com", "new@example.com").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT totp_secret, totp_enabled_at, totp_last_step FROM users").WithArgs(77).
+		WillReturnRows(sqlmock.NewRows(totpColumns).AddRow(nil, nil, nil))
+	mock.ExpectQuery("UPDATE magic_links SET used_at = NOW\\(\\)").WithArgs(hash).
+		WillReturnRows(sqlmock.NewRows([]string{"email"}))
+
+	r := loginRouter(handlers.NewHandler(conn, cfg))
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/email/callback?token=link-token", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Header().Get("Location") != "/pricing" {
+		t.Fatalf("first use redirected to %q, want /pricing for a new user", rec.Header().Get("Location"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/auth/email/callback?token=link-token", nil)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Header().Get("Location") != "/auth?error=invalid_link" {
+		t.Errorf("second use redirected to %q, want /auth?error=invalid_link", rec.Header().Get("Location"))
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMagicLink_LimitedPerVisitor(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.MagicLinks = true
+	r := loginRouter(handlers.NewHandler(nil, cfg))
+	request := func(ip string) int {
+		req := httptest.NewRequest(http.MethodPost, "/auth/email", strings.NewReader("not json"))
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Whatever the addresses, one visitor can only ask for so many links
+	for i := 0; i < 10; i++ {
+		if code := request("192.0.2.1"); code != http.StatusBadRequest {
+			t.Fatalf("request %d: status = %d, want %d", i+1, code, http.StatusBadRequest)
+		}
+	}
+	if code := request("192.0.2.1"); code != http.StatusTooManyRequests {
+		t.Errorf("request 11: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+	if code := request("198.51.100.1"); code != http.StatusBadRequest {
+		t.Errorf("another visitor: status = %d, want %d", code, http.StatusBadRequest)
+	}
+}
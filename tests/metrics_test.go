@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"statusframe/backend/metrics"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestMetricsHandler_RequiresToken(t *testing.T) {
+	cases := []struct {
+		name       string
+		token      string
+		auth       string
+		wantStatus int
+	}{
+		{name: "disabled without token", token: "", auth: "", wantStatus: http.StatusNotFound},
+		{name: "missing bearer token", token: "scrape", auth: "", wantStatus: http.StatusUnauthorized},
+		{name: "wrong bearer token", token: "scrape", auth: "Bearer nope", wantStatus: http.StatusUnauthorized},
+		{name: "valid bearer token", token: "scrape", auth: "Bearer scrape", wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tc.auth != "" {
+				req.Header.Set("Authorization", tc.auth)
+			}
+			rec := httptest.NewRecorder()
+
+			metrics.Handler(tc.token).ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.wantStatus)
+			}
+		})
+	}
+}
+
+func TestMetricsMiddleware_LabelsByRoutePattern(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(metrics.Middleware)
+	r.Get("/api/apps/{appId}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	r.Method(http.MethodGet, "/metrics", metrics.Handler("scrape"))
+
+	for _, id := range []string{"1", "2", "3"} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/apps/"+id, nil))
+	}
+
+	metrics.ObserveNotification("slack", nil)
+	metrics.ObserveNotification("slack", errors.New("boom"))
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer scrape")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	body, _ := io.ReadAll(rec.Body)
+	for _, want := range []string{
+		`statusframe_http_request_duration_seconds_count{code="204",method="GET",route="/api/apps/{appId}"} 3`,
+		`statusframe_notifications_total{channel="slack",outcome="failure"} 1`,
+		`statusframe_notifications_total{channel="slack",outcome="success"} 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics output missing %s", want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"statusframe/db"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLoadMigrations_ContiguousVersions(t *testing.T) {
+	migrations, err := db.LoadMigrations()
+	if err != nil {
+		t.Fatalf("LoadMigrations() error: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("expected embedded migrations")
+	}
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Errorf("migration %d has version %d, want %d", i, migration.Version, i+1)
+		}
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			t.Errorf("migration %04d_%s is missing up or down SQL", migration.Version, migration.Name)
+		}
+	}
+}
+
+func TestMigrateUp_AppliesOnlyPending(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	migrations, err := db.LoadMigrations()
+	if err != nil {
+		t.Fatalf("LoadMigrations() error: %v", err)
+	}
+
+	mock.ExpectExec("SELECT pg_advisory_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()))
+
+	// Everything after the first migration is applied in its own transaction
+	for _, migration := range migrations[1:] {
+		mock.ExpectBegin()
+		mock.ExpectExec(".+").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_migrations").
+			WithArgs(migration.Version, migration.Name).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+	}
+
+	mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := db.MigrateUp(conn)
+	if err != nil {
+		t.Fatalf("MigrateUp() error: %v", err)
+	}
+	if applied != len(migrations)-1 {
+		t.Errorf("MigrateUp() applied %d migrations, want %d", applied, len(migrations)-1)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestEnsureSchemaCurrent_RejectsPendingMigrations(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(1, time.Now()))
+
+	err = db.EnsureSchemaCurrent(conn)
+	if err == nil || !strings.Contains(err.Error(), "out of date") {
+		t.Fatalf("EnsureSchemaCurrent() = %v, want an out of date error", err)
+	}
+}
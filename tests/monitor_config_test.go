@@ -0,0 +1,302 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	checkSettingsColumns     = []string{"id", "check_interval", "alert_channels"}
+	maintenanceWindowColumns = []string{"id", "app_id", "starts_at", "ends_at", "reason"}
+)
+
+// newConfigRouter serves the configuration routes
+func newConfigRouter(h *handlers.Handler) http.Handler {
+	r := chi.NewRouter()
+	r.Use(withUser(42), withOrg(7, "editor"))
+	r.Get("/api/v1/config", h.ExportConfigV1Handler)
+	r.Post("/api/v1/config/apply", h.ApplyConfigV1Handler)
+	return r
+}
+
+// expectCurrentConfig expects the queries that read organization 7's apps: "api",
+// checked every 120 seconds with alerts to Slack only and a maintenance window in 2099
+func expectCurrentConfig(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("FROM apps WHERE org_id = \\$1").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(appColumns).
+			AddRow(5, 7, 42, "API", "api", "https://api.example.com", "cyberpunk", "n", nil, false, "2024-01-01", "2024-01-01"))
+	mock.ExpectQuery("SELECT id, check_interval, alert_channels FROM apps").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(checkSettingsColumns).AddRow(5, 120, "{slack}"))
+	mock.ExpectQuery("FROM maintenance_windows").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(maintenanceWindowColumns).
+			AddRow(1, 5, time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2099, 1, 1, 2, 0, 0, 0, time.UTC), "Database upgrade"))
+}
+
+func TestApplyConfig_DryRunShowsChanges(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	mock.ExpectQuery("SELECT plan FROM organizations").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"plan"}).AddRow("pro"))
+	expectCurrentConfig(mock)
+	mock.ExpectQuery("FROM apps WHERE slug = \\$1").WithArgs("web").
+		WillReturnRows(sqlmock.NewRows(appColumns))
+
+	body := `
+version: 1
+apps:
+  - slug: api
+    app_name: API
+    health_url: https://api.example.com
+    check:
+      interval: 300
+    alerts:
+      channels: [slack]
+    maintenance:
+      - starts_at: 2099-01-01T00:00:00Z
+        ends_at: 2099-01-01T02:00:00Z
+        reason: Database upgrade
+  - slug: web
+    app_name: Website
+    health_url: https://www.example.com
+    alerts:
+      channels: []
+`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/config/apply?dry_run=true", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/yaml")
+	rec := httptest.NewRecorder()
+	newConfigRouter(handlers.NewHandler(conn, config.Default())).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var resp handlers.ConfigApplyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.DryRun || len(resp.Changes) != 2 {
+		t.Fatalf("response = %+v, want a dry run with an update and a create", resp)
+	}
+
+	update := resp.Changes[0]
+	if update.Action != "update" || update.Slug != "api" || len(update.Diff) != 1 {
+		t.Errorf("first change = %+v, want only api's check interval updated", update)
+	} else if diff := update.Diff["check.interval"]; diff.From != float64(120) || diff.To != float64(300) {
+		t.Errorf("check.interval diff = %+v, want 120 -> 300", diff)
+	}
+
+	create := resp.Changes[1]
+	if create.Action != "create" || create.Slug != "web" {
+		t.Errorf("second change = %+v, want web created", create)
+	} else if diff := create.Diff["alerts.channels"]; diff.From != nil || diff.To == nil {
+		t.Errorf("alerts.channels diff = %+v, want alerts turned off rather than left at the default", diff)
+	}
+
+	// A dry run writes nothing, which sqlmock would have refused
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestApplyConfig_ExportAppliesWithoutChanges(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	router := newConfigRouter(handlers.NewHandler(conn, config.Default()))
+
+	expectCurrentConfig(mock)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/config?format=yaml", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	exported := rec.Body.String()
+	for _, want := range []string{"slug: api", "interval: 120", "- slack", "reason: Database upgrade"} {
+		if !strings.Contains(exported, want) {
+			t.Errorf("export is missing %q:\n%s", want, exported)
+		}
+	}
+
+	// Applying the export for real finds nothing to do, so nothing is written or audited
+	mock.ExpectQuery("SELECT plan FROM organizations").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"plan"}).AddRow("pro"))
+	expectCurrentConfig(mock)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/config/apply?prune=true", strings.NewReader(exported))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("apply status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var resp handlers.ConfigApplyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.DryRun || len(resp.Changes) != 0 {
+		t.Errorf("response = %+v, want no changes", resp)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestApplyConfig_RollsBackWhenAChangeFails(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	mock.ExpectQuery("SELECT plan FROM organizations").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"plan"}).AddRow("pro"))
+	expectCurrentConfig(mock)
+	mock.ExpectQuery("FROM apps WHERE slug = \\$1").WithArgs("web").
+		WillReturnRows(sqlmock.NewRows(appColumns))
+
+	// api's new interval is written, then creating web fails and takes it back
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE apps SET check_interval = \\$1").WithArgs(300, `{"slack"}`, 5, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO apps").WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+
+	body := `
+version: 1
+apps:
+  - slug: api
+    app_name: API
+    health_url: https://api.example.com
+    check:
+      interval: 300
+    alerts:
+      channels: [slack]
+    maintenance:
+      - starts_at: 2099-01-01T00:00:00Z
+        ends_at: 2099-01-01T02:00:00Z
+        reason: Database upgrade
+  - slug: web
+    app_name: Website
+    health_url: https://www.example.com
+`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/config/apply", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	newConfigRouter(handlers.NewHandler(conn, config.Default())).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "no changes were applied") {
+		t.Errorf("status = %d, want %d saying nothing was applied: %s", rec.Code, http.StatusInternalServerError, rec.Body.String())
+	}
+
+	// Nothing is audited either, which sqlmock would have refused
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestApplyConfig_Rejections(t *testing.T) {
+	cases := []struct {
+		name        string
+		body        string
+		readsConfig bool
+		wantStatus  int
+		wantCode    string
+		wantInError []string
+	}{
+		{
+			name: "every problem is reported with its location",
+			body: `{"version": 1, "apps": [
+				{"slug": "api", "app_name": "API", "health_url": "https://api.example.com", "check": {"interval": 60}},
+				{"slug": "api", "app_name": "Again", "health_url": "ftp://x", "alerts": {"channels": ["pager"]},
+				 "maintenance": [{"starts_at": "2099-01-02T00:00:00Z", "ends_at": "2099-01-01T00:00:00Z"}]}
+			]}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "validation_failed",
+			wantInError: []string{
+				"apps[0].check.interval: your free plan allows minimum 300 second intervals",
+				"apps[1]: health_url must start with http:// or https://",
+				"apps[1].slug: \"api\" is already used by apps[0]",
+				"apps[1].alerts.channels[0]: must be one of: slack, discord",
+				"apps[1].maintenance[0]: ends_at must be after starts_at",
+			},
+		},
+		{
+			name:        "unknown fields are rejected",
+			body:        `{"version": 1, "apps": [{"slug": "api", "helth_url": "https://api.example.com"}]}`,
+			wantStatus:  http.StatusBadRequest,
+			wantCode:    "invalid_request",
+			wantInError: []string{"helth_url"},
+		},
+		{
+			name:        "apps beyond the plan limit are refused",
+			body:        `{"version": 1, "apps": [{"slug": "api", "app_name": "API", "health_url": "https://api.example.com"}, {"slug": "web", "app_name": "Web", "health_url": "https://www.example.com"}]}`,
+			readsConfig: true,
+			wantStatus:  http.StatusForbidden,
+			wantCode:    "plan_limit_reached",
+			wantInError: []string{"2 apps", "allows 1"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to open sqlmock database: %v", err)
+			}
+			defer conn.Close()
+
+			if tc.wantCode != "invalid_request" {
+				mock.ExpectQuery("SELECT plan FROM organizations").WithArgs(7).
+					WillReturnRows(sqlmock.NewRows([]string{"plan"}).AddRow("free"))
+			}
+			if tc.readsConfig {
+				expectCurrentConfig(mock)
+				mock.ExpectQuery("FROM apps WHERE slug = \\$1").WithArgs("web").
+					WillReturnRows(sqlmock.NewRows(appColumns))
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/config/apply", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			newConfigRouter(handlers.NewHandler(conn, config.Default())).ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.wantStatus, rec.Body.String())
+			}
+			var resp handlers.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode error body: %v", err)
+			}
+			if resp.Error.Code != tc.wantCode {
+				t.Errorf("error code = %q, want %q", resp.Error.Code, tc.wantCode)
+			}
+			for _, want := range tc.wantInError {
+				if !strings.Contains(resp.Error.Message, want) {
+					t.Errorf("error message %q does not mention %q", resp.Error.Message, want)
+				}
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
+	"statusframe/client"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestOpenAPI_DocumentsTheV1API(t *testing.T) {
+	h := handlers.NewHandler(nil, config.Default())
+	rec := httptest.NewRecorder()
+	h.OpenAPIHandler(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var spec struct {
+		OpenAPI    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+				Required   []string                   `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("document is not valid JSON: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("openapi = %q, want a 3.x version", spec.OpenAPI)
+	}
+
+	for path, methods := range map[string][]string{
+		"/api/v1/apps":                      {"get", "post"},
+		"/api/v1/apps/{appId}":              {"get", "patch", "delete"},
+		"/api/v1/apps/{appId}/pause":        {"post"},
+		"/api/v1/apps/{appId}/pause-events": {"get"},
+		"/api/public/status/{slug}":         {"get"},
+		"/api/go-to-dashboard":              {"post"},
+		"/api/slack/integration":            {"get"},
+		"/api/discord/webhook":              {"post"},
+		"/api/create-checkout-session":      {"post"},
+		"/api/admin/users/{userId}/admin":   {"put"},
+		"/auth/providers":                   {"get"},
+		"/api/probe/results":                {"post"},
+		"/healthz":                          {"get"},
+		"/readyz":                           {"get"},
+	} {
+		for _, method := range methods {
+			if _, ok := spec.Paths[path][method]; !ok {
+				t.Errorf("%s %s is not documented", strings.ToUpper(method), path)
+			}
+		}
+	}
+
+	// Every reference points at a schema, and embedded structs are flattened
+	for _, ref := range strings.Split(rec.Body.String(), `"$ref":"#/components/schemas/`)[1:] {
+		name := ref[:strings.Index(ref, `"`)]
+		if _, ok := spec.Components.Schemas[name]; !ok {
+			t.Errorf("reference to undefined schema %s", name)
+		}
+	}
+	appWithStatus := spec.Components.Schemas["AppWithStatus"]
+	for _, field := range []string{"app_name", "health_url", "status", "uptime_24h"} {
+		if _, ok := appWithStatus.Properties[field]; !ok {
+			t.Errorf("AppWithStatus has no %s property", field)
+		}
+	}
+	// Errors the handler writes as JSON are documented with their own body
+	var dashboard struct {
+		Responses map[string]struct {
+			Content map[string]json.RawMessage `json:"content"`
+		} `json:"responses"`
+	}
+	if err := json.Unmarshal(spec.Paths["/api/go-to-dashboard"]["post"], &dashboard); err != nil {
+		t.Fatalf("POST /api/go-to-dashboard: %v", err)
+	}
+	if body := string(dashboard.Responses["403"].Content["application/json"]); !strings.Contains(body, "PlanLimitReachedResponse") {
+		t.Errorf("403 of POST /api/go-to-dashboard = %s, want the PlanLimitReachedResponse schema", body)
+	}
+	for _, field := range spec.Components.Schemas["CreateAppRequest"].Required {
+		if field == "theme" || field == "logo_url" {
+			t.Errorf("optional field %s of CreateAppRequest is marked required", field)
+		}
+	}
+}
+
+func TestClient_GetsAppsWithAPIKey(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	h := handlers.NewHandler(conn, config.Default())
+
+	// Stands in for AuthMiddleware and OrgMiddleware, which have tests of their own
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer upl_test" || r.Header.Get("X-Org-Id") != "7" {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}, withUser(42), withOrg(7, "editor"))
+	r.Get("/api/v1/apps/{appId}", h.GetAppV1Handler)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	c := client.New(server.URL, "upl_test").WithOrg(7)
+
+	mock.ExpectQuery("FROM apps WHERE id = \\$1").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(appColumns).
+			AddRow(5, 7, 42, "API", "api", "https://api.example.com", "cyberpunk", "n", nil, true, "2024-01-01", "2024-01-01"))
+
+	app, err := c.GetApp(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("GetApp: %v", err)
+	}
+	if app.ID != 5 || app.Slug != "api" || app.HealthURL != "https://api.example.com" || !app.Paused {
+		t.Errorf("app = %+v, want app 5 decoded from the response", app)
+	}
+
+	// Apps of other organizations come back as a typed not found error
+	mock.ExpectQuery("FROM apps WHERE id = \\$1").WithArgs(6).
+		WillReturnRows(sqlmock.NewRows(appColumns).
+			AddRow(6, 99, 1, "Other", "other", "https://other.example.com", "cyberpunk", "n", nil, false, "2024-01-01", "2024-01-01"))
+
+	_, err = c.GetApp(context.Background(), 6)
+	if !client.IsNotFound(err) {
+		t.Fatalf("GetApp of another organization's app: err = %v, want not found", err)
+	}
+	if apiErr := err.(*client.Error); apiErr.Code != "not_found" {
+		t.Errorf("error code = %q, want not_found", apiErr.Code)
+	}
+
+	// Without the organization header the request is refused before reaching the handler
+	_, err = client.New(server.URL, "upl_test").GetApp(context.Background(), 5)
+	if apiErr, ok := err.(*client.Error); !ok || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Message != "Unauthorized" {
+		t.Errorf("err = %v, want a 401 with the plain text message", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+)
+
+// withUser puts an authenticated user into the request context like AuthMiddleware does
+func withUser(userId int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), "userId", userId)
+			ctx = context.WithValue(ctx, "authMethod", "api_key")
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// withOrg makes the request act in an organization with a role like OrgMiddleware does
+func withOrg(orgId int, role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), "orgId", orgId)
+			ctx = context.WithValue(ctx, "orgRole", role)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func TestOrgMiddleware_RejectsNonMember(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	mock.ExpectQuery("FROM org_members m .* WHERE m.org_id = \\$1 AND m.user_id = \\$2").WithArgs(9, 42).
+		WillReturnRows(sqlmock.NewRows([]string{"role", "needs_2fa"}))
+
+	h := handlers.NewHandler(conn, config.Default())
+	r := chi.NewRouter()
+	r.Use(withUser(42), h.OrgMiddleware)
+	r.Get("/api/v1/apps", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("handler should not run for a non-member")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/apps", nil)
+	req.Header.Set("X-Org-Id", "9")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestOrgMiddleware_SetsMemberRole(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	mock.ExpectQuery("SELECT m.role, o.require_2fa").WithArgs(9, 42).
+		WillReturnRows(sqlmock.NewRows([]string{"role", "needs_2fa"}).AddRow("viewer", false))
+
+	h := handlers.NewHandler(conn, config.Default())
+	r := chi.NewRouter()
+	r.Use(withUser(42), h.OrgMiddleware)
+	r.Get("/api/v1/apps", func(w http.ResponseWriter, r *http.Request) {
+		if orgId, _ := r.Context().Value("orgId").(int); orgId != 9 {
+			t.Errorf("orgId = %d, want 9", orgId)
+		}
+		if role, _ := r.Context().Value("orgRole").(string); role != "viewer" {
+			t.Errorf("orgRole = %q, want viewer", role)
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/apps", nil)
+	req.Header.Set("X-Org-Id", "9")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestAppV1_ViewerCannotModify(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	// No UPDATE is expected
+	mock.ExpectQuery("FROM apps WHERE id = \\$1").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(appColumns).
+			AddRow(5, 7, 42, "API", "api", "https://api.example.com", "cyberpunk", "n", nil, false, "2024-01-01", "2024-01-01"))
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/apps/5", strings.NewReader(`{"theme": "matrix"}`))
+	rec := httptest.NewRecorder()
+	newAppAPIRouter(handlers.NewHandler(conn, config.Default()), 42, 7, "viewer").ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	var resp struct {
+		Error handlers.APIError `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode error body: %v", err)
+	}
+	if resp.Error.Code != "forbidden" {
+		t.Errorf("error code = %q, want forbidden", resp.Error.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestRemoveOrgMember_KeepsLastOwner(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	// The caller's role, then the role of the member being removed (themselves)
+	mock.ExpectQuery("SELECT role FROM org_members").WithArgs(3, 42).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("owner"))
+	mock.ExpectQuery("SELECT role FROM org_members").WithArgs(3, 42).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("owner"))
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM organizations WHERE id = \\$1 FOR UPDATE").WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM org_members WHERE org_id = \\$1 AND role = 'owner' AND user_id != \\$2").WithArgs(3, 42).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectRollback()
+
+	h := handlers.NewHandler(conn, config.Default())
+	r := chi.NewRouter()
+	r.Use(withUser(42))
+	r.Delete("/api/orgs/{orgId}/members/{userId}", h.RemoveOrgMemberHandler)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/api/orgs/3/members/42", nil))
+
+	if rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusConflict, rec.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestPauseAppV1_RecordsActor(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	mock.ExpectQuery("FROM apps WHERE id = \\$1").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(appColumns).
+			AddRow(5, 7, 42, "API", "api", "https://api.example.com", "cyberpunk", "n", nil, false, "2024-01-01", "2024-01-01"))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE apps SET paused = \\$1").WithArgs(true, 5, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO app_pause_events").WithArgs(5, true, 42, "api_key").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("FROM apps WHERE id = \\$1").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(appColumns).
+			AddRow(5, 7, 42, "API", "api", "https://api.example.com", "cyberpunk", "n", nil, true, "2024-01-01", "2024-01-02"))
+
+	h := handlers.NewHandler(conn, config.Default())
+	r := chi.NewRouter()
+	r.Use(withUser(42), withOrg(7, "editor"))
+	r.Post("/api/v1/apps/{appId}/pause", h.PauseAppV1Handler)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/apps/5/pause", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"paused":true`) {
+		t.Errorf("response should report the app as paused: %s", rec.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestResumeAppV1_AdminPauseSticks(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	h := handlers.NewHandler(conn, config.Default())
+	r := chi.NewRouter()
+	r.With(withUser(42), withOrg(7, "owner")).Post("/api/v1/apps/{appId}/resume", h.ResumeAppV1Handler)
+	r.With(withUser(1)).Post("/api/admin/apps/{appId}/resume", h.AdminResumeAppHandler)
+	resume := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, nil))
+		return rec
+	}
+	expectPausedApp := func() {
+		mock.ExpectQuery("FROM apps WHERE id = \\$1").WithArgs(5).
+			WillReturnRows(sqlmock.NewRows(appColumns).
+				AddRow(5, 7, 42, "API", "api", "https://api.example.com", "cyberpunk", "n", nil, true, "2024-01-01", "2024-01-02"))
+	}
+
+	// Even the owner can't undo an admin's pause
+	expectPausedApp()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE apps SET paused = \\$1,.+AND NOT admin_locked").WithArgs(false, 5, 7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT admin_locked FROM apps").WithArgs(5, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"admin_locked"}).AddRow(true))
+	mock.ExpectRollback()
+	if rec := resume("/api/v1/apps/5/resume"); rec.Code != http.StatusForbidden {
+		t.Errorf("owner resume: status = %d, want %d: %s", rec.Code, http.StatusForbidden, rec.Body.String())
+	}
+
+	// An admin's resume unlocks it
+	expectPausedApp()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE apps SET paused = \\$1, admin_locked = \\$1").WithArgs(false, 5, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO app_pause_events").WithArgs(5, false, 1, "admin").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(1, "admin.force_resume", "app", 5, 7, `{"paused":true}`, `{"paused":false}`, "{}", "192.0.2.1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	if rec := resume("/api/admin/apps/5/resume"); rec.Code != http.StatusOK {
+		t.Errorf("admin resume: status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestUptimeBadge_PausedApp(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	// No uptime query is expected: a paused app shows "paused" instead of stale numbers
+	mock.ExpectQuery("FROM apps WHERE slug = \\$1").WithArgs("api").
+		WillReturnRows(sqlmock.NewRows(appColumns).
+			AddRow(5, 7, 42, "API", "api", "https://api.example.com", "cyberpunk", "n", nil, true, "2024-01-01", "2024-01-02"))
+	expectPublicPage(mock, 5)
+
+	h := handlers.NewHandler(conn, config.Default())
+	r := chi.NewRouter()
+	r.Get("/api/badge/{slug}", h.GetUptimeBadgeHandler)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/badge/api", nil))
+
+	if !strings.Contains(rec.Body.String(), ">paused</text>") {
+		t.Errorf("badge should read paused, got %s", rec.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+)
+
+var probeAgentColumns = []string{"id", "name", "region", "created_at", "last_seen_at", "disabled_at"}
+
+func TestProbeChecks_RejectsUnknownAndDisabledAgents(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	cfg := config.Default()
+	cfg.Probe.Token = "probe-token"
+	h := handlers.NewHandler(conn, cfg)
+	r := chi.NewRouter()
+	r.With(h.ProbeAuthMiddleware).Get("/api/probe/checks", h.GetProbeChecksHandler)
+	r.With(h.ProbeAuthMiddleware).Post("/api/probe/results", h.SubmitProbeResultsHandler)
+	send := func(method, path, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer probe-token")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// An agent that never registered isn't recorded as seen
+	mock.ExpectQuery("FROM probe_agents WHERE id = \\$1").WithArgs(9).
+		WillReturnRows(sqlmock.NewRows(probeAgentColumns))
+	if code := send(http.MethodGet, "/api/probe/checks?agent_id=9", ""); code != http.StatusNotFound {
+		t.Errorf("unknown agent: status = %d, want %d", code, http.StatusNotFound)
+	}
+
+	disabled := func() {
+		mock.ExpectQuery("FROM probe_agents WHERE id = \\$1").WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(probeAgentColumns).AddRow(3, "eu-1", "eu-west", "2024-01-01", "2024-01-02", time.Now()))
+	}
+	disabled()
+	if code := send(http.MethodGet, "/api/probe/checks?agent_id=3", ""); code != http.StatusForbidden {
+		t.Errorf("disabled agent polling: status = %d, want %d", code, http.StatusForbidden)
+	}
+	disabled()
+	if code := send(http.MethodPost, "/api/probe/results", `{"agent_id": 3, "results": [{"app_id": 5, "status_code": 500}]}`); code != http.StatusForbidden {
+		t.Errorf("disabled agent reporting: status = %d, want %d", code, http.StatusForbidden)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestProbeResults_OnlyForAssignedApps(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	cfg := config.Default()
+	cfg.Probe.Token = "probe-token"
+	h := handlers.NewHandler(conn, cfg)
+	r := chi.NewRouter()
+	r.With(h.ProbeAuthMiddleware).Post("/api/probe/results", h.SubmitProbeResultsHandler)
+
+	mock.ExpectQuery("FROM probe_agents WHERE id = \\$1").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(probeAgentColumns).AddRow(3, "eu-1", "eu-west", "2024-01-01", "2024-01-02", nil))
+	mock.ExpectQuery("FROM apps a\\s+JOIN organizations o").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "health_url", "plan", "check_interval"}).
+			AddRow(5, "https://api.example.com/health", "pro", nil))
+	mock.ExpectExec("INSERT INTO region_checks").WithArgs(5, "eu-west", 500, int64(120), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE probe_agents SET last_seen_at").WithArgs(3).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// App 6 belongs to someone else, or isn't checked at all
+	req := httptest.NewRequest(http.MethodPost, "/api/probe/results", strings.NewReader(`{"agent_id": 3, "results": [
+		{"app_id": 5, "status_code": 500, "response_time_ms": 120},
+		{"app_id": 6, "status_code": 500, "response_time_ms": 120}
+	]}`))
+	req.Header.Set("Authorization", "Bearer probe-token")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var resp handlers.ProbeResultsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Accepted != 1 || resp.Rejected != 1 || resp.Region != "eu-west" {
+		t.Errorf("response = %+v, want app 5 accepted and app 6 rejected for eu-west", resp)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+)
+
+var publicFieldsColumns = []string{"status_code", "response_time", "uptime", "uptime_history"}
+
+// expectPublicFields expects the details an app's status page shows to be read, all of them
+func expectPublicFields(mock sqlmock.Sqlmock, appId int) {
+	mock.ExpectQuery("FROM app_public_fields WHERE app_id = \\$1").WithArgs(appId).
+		WillReturnRows(sqlmock.NewRows(publicFieldsColumns))
+}
+
+// expectSecretApp expects public app "api", whose health URL carries a token
+func expectSecretApp(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("FROM apps WHERE slug = \\$1").WithArgs("api").
+		WillReturnRows(sqlmock.NewRows(appColumns).
+			AddRow(5, 7, 42, "Acme API", "api", "https://internal.example.com/health?token=s3cret", "cyberpunk", "n", nil, false, "2024-01-01", "2024-01-02"))
+	expectPublicPage(mock, 5)
+}
+
+// newPublicFieldsRouter serves the public endpoints and the v1 ones
+func newPublicFieldsRouter(h *handlers.Handler) chi.Router {
+	r := chi.NewRouter()
+	r.Get("/api/public/status/{slug}", h.GetPublicStatusHandler)
+	r.Get("/api/public/status/{slug}/viewer", h.GetStatusViewerHandler)
+	r.Get("/api/public/ping/{slug}", h.GetCurrentResponseTimeHandler)
+	r.Get("/api/badge/{slug}", h.GetUptimeBadgeHandler)
+	r.Group(func(r chi.Router) {
+		r.Use(withUser(42), withOrg(7, "editor"))
+		r.Patch("/api/v1/apps/{appId}/public-fields", h.UpdatePublicFieldsV1Handler)
+	})
+	return r
+}
+
+func TestPublicStatus_ShowsOnlyWhatThePageAllows(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	r := newPublicFieldsRouter(handlers.NewHandler(conn, config.Default()))
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	// Neither the owner nor the health URL reach visitors, not even before the first check
+	expectSecretApp(mock)
+	expectPublicFields(mock, 5)
+	mock.ExpectQuery("FROM user_status\\s+WHERE app_id = \\$1").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"status_code", "checked_at"}))
+	rec := get("/api/public/status/api")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"status":"pending"`) {
+		t.Fatalf("status = %d, want %d and pending: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	for _, leak := range []string{"user_id", "endpoint", "internal.example.com", "s3cret"} {
+		if strings.Contains(rec.Body.String(), leak) {
+			t.Errorf("public status gives away %s: %s", leak, rec.Body.String())
+		}
+	}
+
+	// Hidden details are left out, and their queries aren't even made
+	expectSecretApp(mock)
+	mock.ExpectQuery("FROM app_public_fields WHERE app_id = \\$1").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(publicFieldsColumns).AddRow(false, false, false, false))
+	mock.ExpectQuery("FROM user_status\\s+WHERE app_id = \\$1").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"status_code", "checked_at"}).AddRow(503, "2024-05-01T12:00:00Z"))
+	rec = get("/api/public/status/api")
+	var status handlers.PublicStatusResponse
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if status.Status != "down" || status.StatusCode != 0 || status.Uptime24h != nil || status.UptimeHistory != nil || status.Fields.Uptime {
+		t.Errorf("status = %+v, want down without a status code or uptime", status)
+	}
+
+	expectSecretApp(mock)
+	mock.ExpectQuery("FROM app_public_fields WHERE app_id = \\$1").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(publicFieldsColumns).AddRow(true, false, true, true))
+	if rec := get("/api/public/ping/api"); rec.Code != http.StatusForbidden {
+		t.Errorf("ping with hidden response times: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	expectSecretApp(mock)
+	mock.ExpectQuery("FROM app_public_fields WHERE app_id = \\$1").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(publicFieldsColumns).AddRow(true, true, false, true))
+	if rec := get("/api/badge/api"); !strings.Contains(rec.Body.String(), ">hidden</text>") {
+		t.Errorf("badge with hidden uptime should read hidden, got %s", rec.Body.String())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPublicFieldsV1_ChangesOnlyTheGivenFields(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	r := newPublicFieldsRouter(handlers.NewHandler(conn, config.Default()))
+
+	expectApp(mock, "https://api.example.com")
+	expectPublicFields(mock, 5)
+	mock.ExpectExec("INSERT INTO app_public_fields").WithArgs(5, true, true, false, true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(42, "app.public_fields_change", "app", 5, 7, sqlmock.AnyArg(), sqlmock.AnyArg(), "{}", "192.0.2.1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, "/api/v1/apps/5/public-fields", strings.NewReader(`{"uptime": false}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var resp handlers.PublicFieldsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if f := resp.PublicFields; f.Uptime || !f.StatusCode || !f.ResponseTime || !f.UptimeHistory {
+		t.Errorf("public fields = %+v, want everything but the uptime", f)
+	}
+
+	// Who owns the app is worked out from the session
+	viewer := func(cookie *http.Cookie) string {
+		req := httptest.NewRequest(http.MethodGet, "/api/public/status/api/viewer", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if cc := rec.Header().Get("Cache-Control"); cc != "private, no-store" {
+			t.Errorf("viewer Cache-Control = %q, want private, no-store", cc)
+		}
+		return strings.TrimSpace(rec.Body.String())
+	}
+
+	expectViewedApp := func() {
+		mock.ExpectQuery("FROM apps WHERE slug = \\$1").WithArgs("api").
+			WillReturnRows(sqlmock.NewRows(appColumns).
+				AddRow(5, 7, 42, "Acme API", "api", "https://api.example.com", "cyberpunk", "n", nil, false, "2024-01-01", "2024-01-02"))
+	}
+	expectViewedApp()
+	if got := viewer(nil); got != `{"owner":false}` {
+		t.Errorf("signed out viewer = %s, want not the owner", got)
+	}
+
+	expectViewedApp()
+	mock.ExpectQuery("FROM org_members m").WithArgs(7, 42).
+		WillReturnRows(sqlmock.NewRows([]string{"role", "needs_2fa"}).AddRow("editor", false))
+	if got := viewer(sessionCookie(t, map[interface{}]interface{}{"userId": 42})); got != `{"owner":true}` {
+		t.Errorf("editor viewer = %s, want the owner", got)
+	}
+
+	expectViewedApp()
+	mock.ExpectQuery("FROM org_members m").WithArgs(7, 43).
+		WillReturnRows(sqlmock.NewRows([]string{"role", "needs_2fa"}).AddRow("viewer", false))
+	if got := viewer(sessionCookie(t, map[interface{}]interface{}{"userId": 43})); got != `{"owner":false}` {
+		t.Errorf("read-only member viewer = %s, want not the owner", got)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"testing"
+
+	"statusframe/backend/worker"
+	"statusframe/db"
+)
+
+func TestEvaluateQuorum(t *testing.T) {
+	cases := []struct {
+		name     string
+		results  []db.RegionCheck
+		quorum   int
+		wantCode int
+		wantOK   bool
+	}{
+		{
+			name:   "no results",
+			quorum: 2,
+			wantOK: false,
+		},
+		{
+			name: "single failing region is outvoted",
+			results: []db.RegionCheck{
+				{Region: "eu-west", StatusCode: 0},
+				{Region: "us-east", StatusCode: 200},
+				{Region: "ap-south", StatusCode: 200},
+			},
+			quorum:   2,
+			wantCode: 200,
+			wantOK:   true,
+		},
+		{
+			name: "quorum of failing regions marks app down",
+			results: []db.RegionCheck{
+				{Region: "eu-west", StatusCode: 503},
+				{Region: "us-east", StatusCode: 503},
+				{Region: "ap-south", StatusCode: 200},
+			},
+			quorum:   2,
+			wantCode: 503,
+			wantOK:   true,
+		},
+		{
+			name: "most common failing code wins",
+			results: []db.RegionCheck{
+				{Region: "ap-south", StatusCode: 0},
+				{Region: "eu-west", StatusCode: 502},
+				{Region: "us-east", StatusCode: 502},
+			},
+			quorum:   2,
+			wantCode: 502,
+			wantOK:   true,
+		},
+		{
+			name: "fewer regions than quorum can't decide",
+			results: []db.RegionCheck{
+				{Region: "eu-west", StatusCode: 500},
+			},
+			quorum: 2,
+			wantOK: false,
+		},
+		{
+			name: "every region has to report to reach a quorum of all",
+			results: []db.RegionCheck{
+				{Region: "eu-west", StatusCode: 500},
+				{Region: "us-east", StatusCode: 500},
+			},
+			quorum: 3,
+			wantOK: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, ok := worker.EvaluateQuorum(tc.results, tc.quorum)
+			if ok != tc.wantOK || code != tc.wantCode {
+				t.Fatalf("EvaluateQuorum() = (%d, %t), want (%d, %t)", code, ok, tc.wantCode, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestAgreeingResponseTime(t *testing.T) {
+	results := []db.RegionCheck{
+		{Region: "ap-south", StatusCode: 0, ResponseTimeMs: 10000},
+		{Region: "eu-west", StatusCode: 200, ResponseTimeMs: 120},
+		{Region: "us-east", StatusCode: 204, ResponseTimeMs: 80},
+		{Region: "us-west", StatusCode: 200, ResponseTimeMs: 300},
+	}
+
+	// The region that timed out was outvoted, so its response time is left out
+	if got, ok := worker.AgreeingResponseTime(results, 200); !ok || got != 120 {
+		t.Errorf("AgreeingResponseTime(up) = (%d, %t), want (120, true)", got, ok)
+	}
+	if got, ok := worker.AgreeingResponseTime(results[:3], 200); !ok || got != 100 {
+		t.Errorf("AgreeingResponseTime(two up) = (%d, %t), want (100, true)", got, ok)
+	}
+	if _, ok := worker.AgreeingResponseTime(results, 503); ok {
+		t.Error("AgreeingResponseTime() with no region agreeing should return false")
+	}
+}
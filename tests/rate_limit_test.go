@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestPublicRateLimit_PerVisitor(t *testing.T) {
+	cfg := config.Default()
+	cfg.Public.RateLimitPerIP = 3
+	h := handlers.NewHandler(nil, cfg)
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+	r := chi.NewRouter()
+	r.With(h.PublicRateLimitMiddleware).Get("/status/{slug}", ok)
+	get := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/status/api", nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 3; i++ {
+		if rec := get("192.0.2.1"); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want %d", i+1, rec.Code, http.StatusOK)
+		}
+	}
+	rec := get("192.0.2.1")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("fourth request from one visitor: status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("429 should say when to retry")
+	}
+
+	// Other visitors have buckets of their own
+	for i := 0; i < 3; i++ {
+		if rec := get(fmt.Sprintf("198.51.100.%d", i+1)); rec.Code != http.StatusOK {
+			t.Errorf("request from visitor %d: status = %d, want %d", i+2, rec.Code, http.StatusOK)
+		}
+	}
+}
+
+func TestPublicPing_LimitsOnlyNewMeasurements(t *testing.T) {
+	var hits atomic.Int32
+	health := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer health.Close()
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	ping := func(h *handlers.Handler) *httptest.ResponseRecorder {
+		mock.ExpectQuery("FROM apps WHERE slug = \\$1").WithArgs("api").
+			WillReturnRows(sqlmock.NewRows(appColumns).
+				AddRow(5, 7, 42, "Acme API", "api", health.URL, "matrix", "n", nil, false, "2024-01-01", "2024-01-02"))
+		expectPublicPage(mock, 5)
+		expectPublicFields(mock, 5)
+		r := chi.NewRouter()
+		r.Get("/api/public/ping/{slug}", h.GetCurrentResponseTimeHandler)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/public/ping/api", nil))
+		return rec
+	}
+
+	// Answers from the shared measurement don't use up the page's limit
+	cfg := config.Default()
+	cfg.Public.RateLimitPerSlug = 1
+	shared := handlers.NewHandler(conn, cfg)
+	for i := 0; i < 3; i++ {
+		if rec := ping(shared); rec.Code != http.StatusOK {
+			t.Errorf("ping %d: status = %d, want %d: %s", i+1, rec.Code, http.StatusOK, rec.Body.String())
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("health URL was hit %d times, want once", n)
+	}
+
+	// Once the measurement has expired, a new one is over the limit
+	cfg.Public.PingCacheTTL = config.Duration{Duration: time.Nanosecond}
+	live := handlers.NewHandler(conn, cfg)
+	if rec := ping(live); rec.Code != http.StatusOK {
+		t.Errorf("first live ping: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	time.Sleep(time.Millisecond)
+	rec := ping(live)
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("second live ping: status = %d, Retry-After = %q, want %d with a retry time", rec.Code, rec.Header().Get("Retry-After"), http.StatusTooManyRequests)
+	}
+	if n := hits.Load(); n != 2 {
+		t.Errorf("health URL was hit %d times, want twice", n)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPublicPing_SharedBetweenVisitors(t *testing.T) {
+	var hits atomic.Int32
+	health := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer health.Close()
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	r := chi.NewRouter()
+	r.Get("/api/public/ping/{slug}", handlers.NewHandler(conn, config.Default()).GetCurrentResponseTimeHandler)
+	ping := func() handlers.PingResponse {
+		mock.ExpectQuery("FROM apps WHERE slug = \\$1").WithArgs("api").
+			WillReturnRows(sqlmock.NewRows(appColumns).
+				AddRow(5, 7, 42, "Acme API", "api", health.URL, "matrix", "n", nil, false, "2024-01-01", "2024-01-02"))
+		expectPublicPage(mock, 5)
+		expectPublicFields(mock, 5)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/public/ping/api", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		if cc := rec.Header().Get("Cache-Control"); cc == "" {
+			t.Error("ping should say how long it can be cached")
+		}
+		var resp handlers.PingResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	first, second := ping(), ping()
+	if n := hits.Load(); n != 1 {
+		t.Errorf("health URL was hit %d times, want once", n)
+	}
+	if first.StatusCode != http.StatusOK || !first.Timestamp.Equal(second.Timestamp) {
+		t.Errorf("pings = %+v and %+v, want the same measurement", first, second)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPublicStatus_NotModified(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	r := chi.NewRouter()
+	r.Get("/api/public/status/{slug}", handlers.NewHandler(conn, config.Default()).GetPublicStatusHandler)
+
+	expectPublicStatus(mock)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/public/status/api", nil))
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" || rec.Header().Get("Cache-Control") != "public, max-age=30" {
+		t.Fatalf("status = %d, ETag = %q, Cache-Control = %q, want a short cache", rec.Code, etag, rec.Header().Get("Cache-Control"))
+	}
+
+	expectPublicStatus(mock)
+	req := httptest.NewRequest(http.MethodGet, "/api/public/status/api", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("status = %d with %d bytes, want %d and no body", rec.Code, rec.Body.Len(), http.StatusNotModified)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
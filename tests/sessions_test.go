@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"statusframe/backend/auth"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/sessions"
+)
+
+// captureArg matches any argument and remembers it
+type captureArg struct{ value *string }
+
+func (c captureArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	*c.value = s
+	return ok
+}
+
+var sessionColumns = []string{"id", "user_id", "token_hash", "data", "user_agent", "ip_address", "created_at", "last_seen_at", "expires_at"}
+
+func TestDBStore_SessionsCanBeRevoked(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	store := auth.NewDBStore(conn, sessions.Options{Path: "/", MaxAge: 3600, HttpOnly: true}, []byte("test-session-secret"))
+
+	var tokenHash, data string
+	mock.ExpectExec("INSERT INTO user_sessions").
+		WithArgs(captureArg{&tokenHash}, 42, captureArg{&data}, "Firefox", "192.0.2.1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/google/callback", nil)
+	req.Header.Set("User-Agent", "Firefox")
+	rec := httptest.NewRecorder()
+	session, _ := store.Get(req, "auth-session")
+	session.Values["userId"] = 42
+	session.Values["user"] = "Ada"
+	if err := session.Save(req, rec); err != nil {
+		t.Fatalf("failed to save session: %v", err)
+	}
+	cookie := rec.Result().Cookies()[0]
+
+	now := time.Now()
+	mock.ExpectQuery("FROM user_sessions WHERE token_hash = \\$1").WithArgs(tokenHash).
+		WillReturnRows(sqlmock.NewRows(sessionColumns).AddRow(1, 42, tokenHash, data, "Firefox", "192.0.2.1", now, now, now.Add(time.Hour)))
+
+	req = httptest.NewRequest(http.MethodGet, "/api/user-apps", nil)
+	req.AddCookie(cookie)
+	session, err = store.Get(req, "auth-session")
+	if err != nil {
+		t.Fatalf("failed to load session: %v", err)
+	}
+	if session.Values["userId"] != 42 || session.Values["user"] != "Ada" {
+		t.Errorf("loaded values = %v, want the saved user", session.Values)
+	}
+
+	// Once the row is deleted the same cookie is signed out
+	mock.ExpectQuery("FROM user_sessions WHERE token_hash = \\$1").WithArgs(tokenHash).
+		WillReturnRows(sqlmock.NewRows(sessionColumns))
+
+	req = httptest.NewRequest(http.MethodGet, "/api/user-apps", nil)
+	req.AddCookie(cookie)
+	session, err = store.Get(req, "auth-session")
+	if err != nil {
+		t.Fatalf("failed to load session: %v", err)
+	}
+	if _, ok := session.Values["userId"]; ok {
+		t.Errorf("revoked session still has values %v", session.Values)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestDBStore_RecordsVisitorBehindTrustedProxy(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	store := auth.NewDBStore(conn, sessions.Options{Path: "/", MaxAge: 3600, HttpOnly: true}, []byte("test-session-secret"))
+	store.TrustedProxies = []string{"192.0.2.0/24"}
+
+	mock.ExpectExec("INSERT INTO user_sessions").
+		WithArgs(sqlmock.AnyArg(), 42, sqlmock.AnyArg(), "Firefox", "203.0.113.9", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// The proxy's own address and a forged X-Forwarded-For entry are skipped
+	req := httptest.NewRequest(http.MethodGet, "/auth/google/callback", nil)
+	req.Header.Set("User-Agent", "Firefox")
+	req.Header.Set("X-Forwarded-For", "198.51.100.7, 203.0.113.9")
+	session, _ := store.Get(req, "auth-session")
+	session.Values["userId"] = 42
+	if err := session.Save(req, httptest.NewRecorder()); err != nil {
+		t.Fatalf("failed to save session: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
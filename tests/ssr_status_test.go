@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+)
+
+// expectPublicStatus expects the public status of app "api" to be read, on the matrix theme
+func expectPublicStatus(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("FROM apps WHERE slug = \\$1").WithArgs("api").
+		WillReturnRows(sqlmock.NewRows(appColumns).
+			AddRow(5, 7, 42, "Acme API", "api", "https://api.example.com", "matrix", "n", nil, false, "2024-01-01", "2024-01-02"))
+	expectPublicPage(mock, 5)
+	expectPublicFields(mock, 5)
+	mock.ExpectQuery("FROM user_status\\s+WHERE app_id = \\$1\\s+ORDER BY checked_at DESC").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"status_code", "checked_at"}).AddRow(200, "2024-05-01T12:00:00Z"))
+	mock.ExpectQuery("as uptime_24h").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"uptime_24h"}).AddRow(99.5))
+	mock.ExpectQuery("SELECT plan FROM organizations").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"plan"}).AddRow("free"))
+	mock.ExpectQuery("GROUP BY DATE\\(checked_at\\)").WithArgs(5, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"date", "total_checks", "successful_checks"}).
+			AddRow(time.Now().UTC().Format("2006-01-02"), 10, 9))
+}
+
+func newSSRRouter(h *handlers.Handler) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/status/{slug}", h.StatusPageHTMLHandler)
+	return r
+}
+
+func TestStatusPageHTML_RendersForBrowsersAndCrawlers(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	router := newSSRRouter(handlers.NewHandler(conn, config.Default()))
+
+	expectPublicStatus(mock)
+	req := httptest.NewRequest(http.MethodGet, "/status/api", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Content-Type = %q, want text/html", ct)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		`<meta property="og:title" content="Acme API Status">`,
+		`<meta property="og:url" content="http://localhost:8080/status/api">`,
+		`class="ssr theme-matrix"`,
+		"All Systems Operational",
+		"99.50%",
+		"7-Day Uptime History", // from the free plan
+		`class="warning" title=`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page does not contain %s", want)
+		}
+	}
+	if strings.Contains(body, "api.example.com") {
+		t.Error("page shows the app's health URL")
+	}
+
+	// The same page again is not sent twice
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+	expectPublicStatus(mock)
+	req = httptest.NewRequest(http.MethodGet, "/status/api", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("status = %d with %d bytes, want an empty %d", rec.Code, rec.Body.Len(), http.StatusNotModified)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestStatusPageHTML_NegotiatesJSONAndMissingPages(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	router := newSSRRouter(handlers.NewHandler(conn, config.Default()))
+
+	// API clients asking for JSON get what /api/public/status/{slug} returns
+	expectPublicStatus(mock)
+	req := httptest.NewRequest(http.MethodGet, "/status/api", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == "" {
+		t.Fatalf("status = %d with ETag %q, want %d with an ETag", rec.Code, rec.Header().Get("ETag"), http.StatusOK)
+	}
+	var status handlers.PublicStatusResponse
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if status.AppName != "Acme API" || status.Status != "up" {
+		t.Errorf("status = %+v, want Acme API up", status)
+	}
+
+	// An unknown slug is a 404 page, not the React app
+	mock.ExpectQuery("FROM apps WHERE slug = \\$1").WithArgs("nope").
+		WillReturnRows(sqlmock.NewRows(appColumns))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status/nope", nil))
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "Status Page Not Found") {
+		t.Errorf("status = %d, want a %d page: %s", rec.Code, http.StatusNotFound, rec.Body.String())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
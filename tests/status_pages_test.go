@@ -0,0 +1,385 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	statusPageColumns      = []string{"id", "org_id", "slug", "title", "description", "theme", "logo_url", "created_at", "updated_at"}
+	pageLayoutColumns      = []string{"id", "section_id", "section_name", "app_id", "name"}
+	componentStatusColumns = []string{"id", "app_name", "paused", "in_maintenance", "status_code", "checked_at", "uptime_24h", "show_uptime", "show_uptime_history"}
+)
+
+// newStatusPageRouter serves the status page routes
+func newStatusPageRouter(h *handlers.Handler) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/api/public/pages/{slug}", h.GetPublicStatusPageHandler)
+	r.Group(func(r chi.Router) {
+		r.Use(withUser(42), withOrg(7, "editor"))
+		r.Post("/api/v1/status-pages", h.CreateStatusPageV1Handler)
+	})
+	return r
+}
+
+// expectOrgApps expects organization 7's apps to be read: 5, 6 and 8
+func expectOrgApps(mock sqlmock.Sqlmock) {
+	rows := sqlmock.NewRows(appColumns)
+	for _, id := range []int{5, 6, 8} {
+		rows.AddRow(id, 7, 42, "App", "app", "https://example.com", "cyberpunk", "n", nil, false, "2024-01-01", "2024-01-01")
+	}
+	mock.ExpectQuery("FROM apps WHERE org_id = \\$1").WithArgs(7).WillReturnRows(rows)
+}
+
+func TestPublicStatusPage_SumsUpComponents(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	now := time.Now()
+	mock.ExpectQuery("FROM status_pages WHERE slug = \\$1").WithArgs("acme").
+		WillReturnRows(sqlmock.NewRows(statusPageColumns).
+			AddRow(3, 7, "acme", "Acme", "", "cyberpunk", nil, now, now))
+	mock.ExpectQuery("FROM status_page_components c").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(pageLayoutColumns).
+			AddRow(1, nil, nil, 5, "Website").
+			AddRow(2, 10, "API", 6, nil).
+			AddRow(3, 10, "API", 8, nil))
+	for _, id := range []int{5, 6, 8} {
+		expectPublicPage(mock, id)
+	}
+	mock.ExpectQuery("FROM apps a").WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(componentStatusColumns).
+			AddRow(5, "web", false, false, 200, now, 100.0, true, true).
+			AddRow(6, "REST API", false, false, 503, now, 50.0, true, true).
+			AddRow(8, "GraphQL", true, false, 200, now, nil, true, true))
+	mock.ExpectQuery("SELECT plan FROM organizations").WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"plan"}).AddRow("pro"))
+	mock.ExpectQuery("FROM user_status_unpaused\\s+WHERE app_id = ANY").WithArgs(sqlmock.AnyArg(), 30).
+		WillReturnRows(sqlmock.NewRows([]string{"app_id", "date", "total_checks", "successful_checks"}).
+			AddRow(6, "2024-05-01", 4, 2))
+
+	rec := httptest.NewRecorder()
+	newStatusPageRouter(handlers.NewHandler(conn, config.Default())).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/public/pages/acme", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	for _, hidden := range []string{"health_url", "user_id", "app_id", "org_id"} {
+		if strings.Contains(rec.Body.String(), hidden) {
+			t.Errorf("public page shows %s: %s", hidden, rec.Body.String())
+		}
+	}
+
+	var resp handlers.PublicStatusPageResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	// One of the two checked components is down; the paused one doesn't count
+	if resp.Status != "partial_outage" || resp.DataRetentionDays != 30 {
+		t.Errorf("page = %s with %d days, want partial_outage with 30 days", resp.Status, resp.DataRetentionDays)
+	}
+	if len(resp.Components) != 1 || resp.Components[0].Name != "Website" || resp.Components[0].Status != "up" {
+		t.Errorf("components = %+v, want Website up", resp.Components)
+	}
+	if len(resp.Sections) != 1 {
+		t.Fatalf("sections = %+v, want the API section", resp.Sections)
+	}
+	api := resp.Sections[0]
+	if api.Name != "API" || api.Status != "major_outage" || len(api.Components) != 2 {
+		t.Fatalf("section = %+v, want API in major_outage with two components", api)
+	}
+	if c := api.Components[0]; c.Name != "REST API" || c.Status != "down" || len(c.UptimeHistory) != 1 || c.UptimeHistory[0].UptimePercentage != 50 {
+		t.Errorf("REST API = %+v, want down with a 50%% day", c)
+	}
+	if c := api.Components[1]; c.Status != "paused" || c.Uptime24h != nil || c.UptimeHistory == nil {
+		t.Errorf("GraphQL = %+v, want paused with no uptime and an empty history", c)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPublicStatusPage_LeavesOutAppsTheVisitorCannotSee(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	r := chi.NewRouter()
+	h := handlers.NewHandler(conn, config.Default())
+	r.Get("/api/public/pages/{slug}", h.GetPublicStatusPageHandler)
+	r.Get("/pages/{slug}", h.StatusPageGroupHTMLHandler)
+
+	// Website (5) is public, Billing (6) needs a password and API (8) is for members
+	now := time.Now()
+	expectPage := func(visibleIds string) {
+		mock.ExpectQuery("FROM status_pages WHERE slug = \\$1").WithArgs("acme").
+			WillReturnRows(sqlmock.NewRows(statusPageColumns).
+				AddRow(3, 7, "acme", "Acme", "", "cyberpunk", nil, now, now))
+		mock.ExpectQuery("FROM status_page_components c").WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(pageLayoutColumns).
+				AddRow(1, nil, nil, 5, "Website").
+				AddRow(2, 10, "Billing", 6, nil).
+				AddRow(3, 11, "API", 8, nil))
+		expectPublicPage(mock, 5)
+		mock.ExpectQuery("FROM app_access WHERE app_id = \\$1").WithArgs(6).
+			WillReturnRows(sqlmock.NewRows(appAccessColumns).AddRow("password", "hash", "{}", "key", now))
+		mock.ExpectQuery("FROM app_access WHERE app_id = \\$1").WithArgs(8).
+			WillReturnRows(sqlmock.NewRows(appAccessColumns).AddRow("members", "", "{}", "key", now))
+		member := visibleIds == "{5,8}"
+		if member {
+			mock.ExpectQuery("FROM org_members m").WithArgs(7, 42).
+				WillReturnRows(sqlmock.NewRows([]string{"role", "needs_2fa"}).AddRow("viewer", false))
+		}
+		statuses := sqlmock.NewRows(componentStatusColumns).AddRow(5, "web", false, false, 200, now, 100.0, true, true)
+		if member {
+			statuses.AddRow(8, "GraphQL", false, false, 503, now, 0.0, true, true)
+		}
+		mock.ExpectQuery("FROM apps a").WithArgs(visibleIds).WillReturnRows(statuses)
+		mock.ExpectQuery("SELECT plan FROM organizations").WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"plan"}).AddRow("pro"))
+		mock.ExpectQuery("FROM user_status_unpaused\\s+WHERE app_id = ANY").WithArgs(visibleIds, 30).
+			WillReturnRows(sqlmock.NewRows([]string{"app_id", "date", "total_checks", "successful_checks"}))
+	}
+	get := func(path string, cookie *http.Cookie) handlers.PublicStatusPageResponse {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", "application/json")
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, want %d: %s", path, rec.Code, http.StatusOK, rec.Body.String())
+		}
+		if cc := rec.Header().Get("Cache-Control"); !strings.HasPrefix(cc, "private") {
+			t.Errorf("%s: Cache-Control = %q, want private since the page depends on the visitor", path, cc)
+		}
+		var resp handlers.PublicStatusPageResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	// Visitors who aren't let into an app's own page don't see it here, on the JSON
+	// API or the page's own JSON, which custom domains serve too
+	for _, path := range []string{"/api/public/pages/acme", "/pages/acme"} {
+		expectPage("{5}")
+		resp := get(path, nil)
+		if len(resp.Components) != 1 || resp.Components[0].Name != "Website" || len(resp.Sections) != 0 || resp.Status != "operational" {
+			t.Errorf("%s signed out: %+v, want only Website", path, resp)
+		}
+	}
+
+	// Members of the organization also see the members-only app
+	member := sessionCookie(t, map[interface{}]interface{}{"userId": 42})
+	expectPage("{5,8}")
+	resp := get("/api/public/pages/acme", member)
+	if len(resp.Sections) != 1 || resp.Sections[0].Name != "API" || resp.Status != "partial_outage" {
+		t.Errorf("member: sections = %+v with %s, want only API counted", resp.Sections, resp.Status)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPublicStatusPage_NotModified(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	r := newStatusPageRouter(handlers.NewHandler(conn, config.Default()))
+	now := time.Now()
+	get := func(etag string) *httptest.ResponseRecorder {
+		mock.ExpectQuery("FROM status_pages WHERE slug = \\$1").WithArgs("acme").
+			WillReturnRows(sqlmock.NewRows(statusPageColumns).
+				AddRow(3, 7, "acme", "Acme", "", "cyberpunk", nil, now, now))
+		mock.ExpectQuery("FROM status_page_components c").WithArgs(3).
+			WillReturnRows(sqlmock.NewRows(pageLayoutColumns).AddRow(1, nil, nil, 5, "Website"))
+		expectPublicPage(mock, 5)
+		mock.ExpectQuery("FROM apps a").WithArgs("{5}").
+			WillReturnRows(sqlmock.NewRows(componentStatusColumns).AddRow(5, "web", false, false, 200, now, 100.0, true, true))
+		mock.ExpectQuery("SELECT plan FROM organizations").WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"plan"}).AddRow("pro"))
+		mock.ExpectQuery("FROM user_status_unpaused\\s+WHERE app_id = ANY").WithArgs("{5}", 30).
+			WillReturnRows(sqlmock.NewRows([]string{"app_id", "date", "total_checks", "successful_checks"}))
+
+		req := httptest.NewRequest(http.MethodGet, "/api/public/pages/acme", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("")
+	etag := rec.Header().Get("ETag")
+	if rec.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status = %d with ETag %q, want %d with an ETag", rec.Code, etag, http.StatusOK)
+	}
+	var resp handlers.PublicStatusPageResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.GeneratedAt.IsZero() {
+		t.Error("generated_at should still be sent")
+	}
+
+	// The page is generated again, but nothing on it changed
+	time.Sleep(time.Millisecond)
+	rec = get(etag)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("status = %d with %d bytes, want %d and no body", rec.Code, rec.Body.Len(), http.StatusNotModified)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateStatusPage_SavesLayoutInOrder(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	now := time.Now()
+	expectOrgApps(mock)
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO status_pages").WithArgs(7, "acme", "Acme", "", "cyberpunk", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectExec("INSERT INTO status_page_components").WithArgs(3, nil, 5, "Website", 0).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("INSERT INTO status_page_sections").WithArgs(3, "API", 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+	mock.ExpectExec("INSERT INTO status_page_components").WithArgs(3, 10, 6, nil, 0).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("INSERT INTO status_page_components").WithArgs(3, 10, 8, nil, 1).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(42, "status_page.create", "status_page", 3, 7, nil, sqlmock.AnyArg(), "{}", "192.0.2.1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("FROM status_pages WHERE id = \\$1").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(statusPageColumns).
+			AddRow(3, 7, "acme", "Acme", "", "cyberpunk", nil, now, now))
+	mock.ExpectQuery("FROM status_page_components c").WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(pageLayoutColumns).
+			AddRow(1, nil, nil, 5, "Website").
+			AddRow(2, 10, "API", 6, nil).
+			AddRow(3, 10, "API", 8, nil))
+
+	body := `{"slug": "acme", "title": " Acme ",
+		"components": [{"app_id": 5, "name": "Website"}],
+		"sections": [{"name": "API", "components": [{"app_id": 6}, {"app_id": 8}]}]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/status-pages", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	newStatusPageRouter(handlers.NewHandler(conn, config.Default())).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var resp handlers.StatusPageResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if page := resp.StatusPage; page == nil || len(page.Components) != 1 || len(page.Sections) != 1 || len(page.Sections[0].Components) != 2 {
+		t.Errorf("status page = %+v, want one component and a section of two", page)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestCreateStatusPage_Rejections(t *testing.T) {
+	cases := []struct {
+		name        string
+		body        string
+		plan        string
+		wantStatus  int
+		wantInError []string
+	}{
+		{
+			name: "apps must be the organization's and shown once",
+			body: `{"slug": "Acme!", "title": "",
+				"components": [{"app_id": 5}, {"app_id": 99}],
+				"sections": [{"name": "", "components": [{"app_id": 5}]}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantInError: []string{
+				"slug may only contain",
+				"title is required",
+				"components[1].app_id: no app 99 in this organization",
+				"sections[0].name: is required",
+				"sections[0].components[0].app_id: app 5 is already shown by components[0]",
+			},
+		},
+		{
+			name:        "logos need a paid plan",
+			body:        `{"slug": "acme", "title": "Acme", "logo_url": "https://example.com/logo.png"}`,
+			plan:        "free",
+			wantStatus:  http.StatusForbidden,
+			wantInError: []string{"Custom logos"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to open sqlmock database: %v", err)
+			}
+			defer conn.Close()
+
+			expectOrgApps(mock)
+			if tc.plan != "" {
+				mock.ExpectQuery("SELECT plan FROM organizations").WithArgs(7).
+					WillReturnRows(sqlmock.NewRows([]string{"plan"}).AddRow(tc.plan))
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/status-pages", strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+			newStatusPageRouter(handlers.NewHandler(conn, config.Default())).ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tc.wantStatus, rec.Body.String())
+			}
+			var resp handlers.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode error body: %v", err)
+			}
+			for _, want := range tc.wantInError {
+				if !strings.Contains(resp.Error.Message, want) {
+					t.Errorf("error message %q does not mention %q", resp.Error.Message, want)
+				}
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
+	"statusframe/backend/worker"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+)
+
+var subscriberColumns = []string{"id", "app_id", "kind", "target", "token", "confirmed_at", "created_at"}
+
+// sentMail is an email a test mailer was asked to send
+type sentMail struct {
+	to, subject, text string
+}
+
+// mailRecorder is a mailer that hands every email to the test
+type mailRecorder chan sentMail
+
+func (m mailRecorder) Send(to, subject, htmlBody, textBody string) error {
+	m <- sentMail{to: to, subject: subject, text: textBody}
+	return nil
+}
+
+// webhookRecorder is a webhook that answers the verification ping and records every notice
+type webhookRecorder struct {
+	mu      sync.Mutex
+	notices []handlers.SubscriberNotice
+}
+
+func (wr *webhookRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var n handlers.SubscriberNotice
+	json.NewDecoder(r.Body).Decode(&n)
+	wr.mu.Lock()
+	wr.notices = append(wr.notices, n)
+	wr.mu.Unlock()
+	w.Write([]byte(n.Challenge))
+}
+
+func (wr *webhookRecorder) received() []handlers.SubscriberNotice {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	return append([]handlers.SubscriberNotice(nil), wr.notices...)
+}
+
+func newSubscriberRouter(h *handlers.Handler) chi.Router {
+	r := chi.NewRouter()
+	r.Post("/api/public/status/{slug}/subscribe", h.SubscribeHandler)
+	r.Get("/api/public/subscriptions/{token}/confirm", h.ConfirmSubscriptionHandler)
+	r.Get("/api/public/subscriptions/{token}/unsubscribe", h.UnsubscribeHandler)
+	r.Post("/api/public/subscriptions/{token}/unsubscribe", h.UnsubscribeHandler)
+	return r
+}
+
+// expectAppBySlug expects the public app "api" to be looked up
+func expectAppBySlug(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("FROM apps WHERE slug = \\$1").WithArgs("api").
+		WillReturnRows(sqlmock.NewRows(appColumns).
+			AddRow(5, 7, 42, "Acme API", "api", "https://api.example.com", "cyberpunk", "n", nil, false, "2024-01-01T00:00:00Z", "2024-01-02T00:00:00Z"))
+	expectPublicPage(mock, 5)
+}
+
+func TestSubscribe_EmailNeedsConfirmation(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	h := handlers.NewHandler(conn, config.Default())
+	mails := make(mailRecorder, 1)
+	h.SetMailer(mails)
+	r := newSubscriberRouter(h)
+
+	subscribe := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/public/status/api/subscribe", strings.NewReader(body)))
+		return rec
+	}
+
+	expectAppBySlug(mock)
+	if rec := subscribe(`{"email": "not an address"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid address: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	expectAppBySlug(mock)
+	mock.ExpectQuery("FROM subscribers WHERE app_id = \\$1 AND kind = \\$2 AND target = \\$3").
+		WithArgs(5, "email", "ops@example.com").
+		WillReturnRows(sqlmock.NewRows(subscriberColumns))
+	mock.ExpectExec("INSERT INTO subscribers").
+		WithArgs(5, "email", "ops@example.com", sqlmock.AnyArg(), false).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	rec := subscribe(`{"email": " Ops@Example.com "}`)
+	if rec.Code != http.StatusAccepted || !strings.Contains(rec.Body.String(), "pending_confirmation") {
+		t.Fatalf("status = %d, want %d pending confirmation: %s", rec.Code, http.StatusAccepted, rec.Body.String())
+	}
+
+	var mail sentMail
+	select {
+	case mail = <-mails:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no confirmation email was sent")
+	}
+	link := regexp.MustCompile(`http://localhost:8080/api/public/subscriptions/([0-9a-f]+)/confirm`).FindStringSubmatch(mail.text)
+	if mail.to != "ops@example.com" || link == nil {
+		t.Fatalf("email = %+v, want a confirmation link for ops@example.com", mail)
+	}
+	if strings.Contains(rec.Body.String(), link[1]) {
+		t.Error("the response gives away the token that confirms the subscription")
+	}
+
+	// Asking again right away neither sends another email nor says the address is known
+	expectAppBySlug(mock)
+	mock.ExpectQuery("FROM subscribers WHERE app_id = \\$1 AND kind = \\$2 AND target = \\$3").
+		WithArgs(5, "email", "ops@example.com").
+		WillReturnRows(sqlmock.NewRows(subscriberColumns).AddRow(1, 5, "email", "ops@example.com", link[1], nil, time.Now()))
+	if rec := subscribe(`{"email": "ops@example.com"}`); rec.Code != http.StatusAccepted {
+		t.Errorf("repeated subscription: status = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+
+	mock.ExpectQuery("UPDATE subscribers s SET confirmed_at").WithArgs(link[1]).
+		WillReturnRows(sqlmock.NewRows([]string{"slug"}).AddRow("api"))
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/public/subscriptions/"+link[1]+"/confirm", nil))
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/status/api?subscribed=1" {
+		t.Errorf("confirm: status = %d, Location = %q, want a redirect to the status page",
+			rec.Code, rec.Header().Get("Location"))
+	}
+
+	select {
+	case mail := <-mails:
+		t.Errorf("unexpected email %+v", mail)
+	default:
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSubscribe_WebhookMustEchoTheChallenge(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	hook := &webhookRecorder{}
+	echoing := httptest.NewServer(hook)
+	defer echoing.Close()
+	ignoring := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer ignoring.Close()
+
+	h := handlers.NewHandler(conn, config.Default())
+	h.SetWebhookClient(echoing.Client())
+	r := newSubscriberRouter(h)
+
+	subscribe := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		body := `{"webhook_url": "` + url + `"}`
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/public/status/api/subscribe", strings.NewReader(body)))
+		return rec
+	}
+
+	expectAppBySlug(mock)
+	if rec := subscribe(ignoring.URL); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "challenge") {
+		t.Errorf("webhook ignoring the challenge: status = %d, want %d: %s", rec.Code, http.StatusBadRequest, rec.Body.String())
+	}
+
+	expectAppBySlug(mock)
+	mock.ExpectExec("INSERT INTO subscribers").
+		WithArgs(5, "webhook", echoing.URL, sqlmock.AnyArg(), true).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	rec := subscribe(echoing.URL)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var resp handlers.SubscribeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	pings := hook.received()
+	if len(pings) != 1 || pings[0].Type != "verification" || pings[0].App.Slug != "api" || resp.UnsubscribeURL != pings[0].UnsubscribeURL {
+		t.Fatalf("pings = %+v, response = %+v, want one verification ping with the same unsubscribe link", pings, resp)
+	}
+
+	// The unsubscribe link can be posted to
+	token := strings.TrimSuffix(strings.TrimPrefix(resp.UnsubscribeURL, "http://localhost:8080/api/public/subscriptions/"), "/unsubscribe")
+	mock.ExpectQuery("DELETE FROM subscribers s").WithArgs(token).
+		WillReturnRows(sqlmock.NewRows([]string{"slug"}).AddRow("api"))
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/public/subscriptions/"+token+"/unsubscribe", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("unsubscribe: status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSubscriberNotifier_SendsUpdatesAndChangesOutsideTheCooldown(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	hook := &webhookRecorder{}
+	ts := httptest.NewServer(hook)
+	defer ts.Close()
+
+	h := handlers.NewHandler(conn, config.Default())
+	h.SetWebhookClient(ts.Client())
+
+	now := time.Now()
+	confirmed := func() *sqlmock.Rows {
+		return sqlmock.NewRows(subscriberColumns).AddRow(1, 5, "webhook", ts.URL, "abc123", now, now)
+	}
+
+	mock.ExpectExec("DELETE FROM subscribers WHERE confirmed_at IS NULL").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("WHERE u.notified_at IS NULL").WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "app_id", "status", "message", "created_by", "created_at", "app_name", "slug"}).
+			AddRow(3, 5, "identified", "The database is out of connections", 42, now.Add(-time.Minute), "Acme API", "api"))
+	mock.ExpectQuery("FROM subscribers WHERE app_id = \\$1 AND confirmed_at IS NOT NULL").WithArgs(5).WillReturnRows(confirmed())
+	mock.ExpectExec("UPDATE incident_updates SET notified_at = NOW\\(\\) WHERE id = \\$1").WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// App 5 went down an hour after its last notice, app 6 only five minutes after
+	// (it's flapping), and app 8 hasn't been announced yet
+	mock.ExpectQuery("FROM apps a\\s+JOIN LATERAL").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "app_name", "slug", "status_code", "show_code", "checked_at", "status", "announced_at"}).
+			AddRow(5, "Acme API", "api", 503, true, now, "up", now.Add(-time.Hour)).
+			AddRow(6, "Acme Web", "web", 503, true, now, "up", now.Add(-5*time.Minute)).
+			AddRow(8, "Acme Docs", "docs", 200, true, now, nil, nil))
+	mock.ExpectExec("INSERT INTO subscriber_announcements").WithArgs(5, "down").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("FROM subscribers WHERE app_id = \\$1 AND confirmed_at IS NOT NULL").WithArgs(5).WillReturnRows(confirmed())
+	mock.ExpectExec("INSERT INTO subscriber_announcements").WithArgs(8, "up").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	notifier := worker.NewSubscriberNotifier(conn, h, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	go notifier.Start(ctx)
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	if err := notifier.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	notices := hook.received()
+	if len(notices) != 2 {
+		t.Fatalf("notices = %+v, want an incident update and a status change", notices)
+	}
+	if n := notices[0]; n.Type != "incident_update" || n.Status != "identified" || n.Title != "Acme API incident: Identified" ||
+		n.UnsubscribeURL != "http://localhost:8080/api/public/subscriptions/abc123/unsubscribe" {
+		t.Errorf("first notice = %+v, want the incident update with the subscriber's unsubscribe link", n)
+	}
+	if n := notices[1]; n.Type != "status_change" || n.Previous != "up" || n.Status != "down" || n.App.URL != "http://localhost:8080/status/api" {
+		t.Errorf("second notice = %+v, want app 5 going from up to down", n)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestSubscriberNotifier_ShutdownCancelsSlowWebhooks(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	// The webhook doesn't answer until the test is over
+	posted, release := make(chan struct{}, 1), make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted <- struct{}{}
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	h := handlers.NewHandler(conn, config.Default())
+	h.SetWebhookClient(ts.Client())
+
+	now := time.Now()
+	mock.ExpectExec("DELETE FROM subscribers WHERE confirmed_at IS NULL").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("WHERE u.notified_at IS NULL").WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "app_id", "status", "message", "created_by", "created_at", "app_name", "slug"}).
+			AddRow(3, 5, "identified", "The database is out of connections", 42, now.Add(-time.Minute), "Acme API", "api"))
+	mock.ExpectQuery("FROM subscribers WHERE app_id = \\$1 AND confirmed_at IS NOT NULL").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(subscriberColumns).AddRow(1, 5, "webhook", ts.URL, "abc123", now, now))
+
+	notifier := worker.NewSubscriberNotifier(conn, h, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	go notifier.Start(ctx)
+	select {
+	case <-posted:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the webhook was never posted to")
+	}
+	cancel()
+
+	// Shutdown doesn't wait out the webhook's timeout, and the update isn't marked as
+	// sent, so it goes out again on the next run
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), time.Second)
+	defer waitCancel()
+	if err := notifier.Wait(waitCtx); err != nil {
+		t.Fatalf("Wait() error = %v, want the notifier to stop without waiting for the webhook", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"statusframe/backend/auth"
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var totpColumns = []string{"totp_secret", "totp_enabled_at", "totp_last_step"}
+
+func TestTOTP_MatchesRFC6238(t *testing.T) {
+	// The SHA-1 test key of RFC 6238 appendix B, "12345678901234567890" in base32
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	for _, tc := range []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+	} {
+		code, err := auth.TOTPCode(secret, time.Unix(tc.unix, 0))
+		if err != nil {
+			t.Fatalf("TOTPCode: %v", err)
+		}
+		if code != tc.code {
+			t.Errorf("code at %d = %s, want %s", tc.unix, code, tc.code)
+		}
+	}
+
+	if _, ok := auth.VerifyTOTP(secret, "287082", time.Unix(59+30, 0)); !ok {
+		t.Error("a code from the previous period should still be accepted")
+	}
+	if _, ok := auth.VerifyTOTP(secret, "287082", time.Unix(59+90, 0)); ok {
+		t.Error("a code from three periods ago should be rejected")
+	}
+}
+
+func TestTwoFactorLogin_SessionWaitsForCode(t *testing.T) {
+	cfg := config.Default()
+	cfg.Auth.SessionSecret = "test-session-secret"
+	cfg.Auth.MagicLinks = true
+	if err := auth.NewAuth(cfg.Auth); err != nil {
+		t.Fatalf("NewAuth: %v", err)
+	}
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	secret, _ := auth.GenerateTOTPSecret()
+	enabledAt := time.Now().Add(-24 * time.Hour)
+
+	mock.ExpectQuery("UPDATE magic_links SET used_at = NOW\\(\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("ada@example.com"))
+	mock.ExpectQuery("SELECT user_id FROM user_identities").WithArgs("email", "ada@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(42))
+	mock.ExpectQuery("FROM users WHERE id=\\$1").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "avatar_url", "is_admin", "disabled_at"}).
+			AddRow(42, "Ada", "ada@example.com", "", false, nil))
+	mock.ExpectExec("INSERT INTO user_identities").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM apps").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT totp_secret, totp_enabled_at, totp_last_step FROM users").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows(totpColumns).AddRow(secret, enabledAt, nil))
+
+	h := handlers.NewHandler(conn, cfg)
+	r := loginRouter(h)
+	r.Post("/auth/2fa", h.VerifyTwoFactorLoginHandler)
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/email/callback?token=link-token", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Header().Get("Location") != "/two-factor" {
+		t.Fatalf("callback redirected to %q, want /two-factor", rec.Header().Get("Location"))
+	}
+	cookie := rec.Result().Cookies()[0]
+
+	// The session isn't signed in until the code is given
+	protected := auth.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("a session waiting for its second factor must not be signed in")
+	}))
+	req = httptest.NewRequest(http.MethodGet, "/api/user-apps", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	protected.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status before the code = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	// A wrong code keeps the sign in waiting
+	mock.ExpectQuery("SELECT totp_secret, totp_enabled_at, totp_last_step FROM users").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows(totpColumns).AddRow(secret, enabledAt, nil))
+	mock.ExpectExec("UPDATE recovery_codes SET used_at = NOW\\(\\)").WithArgs(42, auth.HashRecoveryCode("not-a-code")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	req = httptest.NewRequest(http.MethodPost, "/auth/2fa", strings.NewReader(url.Values{"code": {"not-a-code"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Header().Get("Location") != "/two-factor?error=invalid_code" {
+		t.Fatalf("wrong code redirected to %q, want /two-factor?error=invalid_code", rec.Header().Get("Location"))
+	}
+	cookie = rec.Result().Cookies()[0]
+
+	code, _ := auth.TOTPCode(secret, time.Now())
+	mock.ExpectQuery("SELECT totp_secret, totp_enabled_at, totp_last_step FROM users").WithArgs(42).
+		WillReturnRows(sqlmock.NewRows(totpColumns).AddRow(secret, enabledAt, nil))
+	mock.ExpectExec("UPDATE users SET totp_last_step = \\$2").WithArgs(42, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req = httptest.NewRequest(http.MethodPost, "/auth/2fa", strings.NewReader(url.Values{"code": {code}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Header().Get("Location") != "/dashboard" {
+		t.Fatalf("right code redirected to %q, want /dashboard", rec.Header().Get("Location"))
+	}
+
+	var gotUserId interface{}
+	protected = auth.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUserId = r.Context().Value("userId")
+	}))
+	req = httptest.NewRequest(http.MethodGet, "/api/user-apps", nil)
+	req.AddCookie(rec.Result().Cookies()[0])
+	protected.ServeHTTP(httptest.NewRecorder(), req)
+	if gotUserId != 42 {
+		t.Errorf("userId after the code = %v, want 42", gotUserId)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,337 @@
+package tests
+
+import (
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"statusframe/backend/config"
+	"statusframe/backend/handlers"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5"
+)
+
+var appAccessColumns = []string{"visibility", "password_hash", "ip_allowlist", "share_key", "updated_at"}
+
+// expectPublicPage expects the visibility of an app's public status page to be read
+func expectPublicPage(mock sqlmock.Sqlmock, appId int) {
+	mock.ExpectQuery("FROM app_access WHERE app_id = \\$1").WithArgs(appId).
+		WillReturnRows(sqlmock.NewRows(appAccessColumns))
+}
+
+// expectPrivatePage expects app "api", which has no health URL to ping, and its
+// visibility to be read
+func expectPrivatePage(mock sqlmock.Sqlmock, visibility, passwordHash, allowlist, shareKey string) {
+	mock.ExpectQuery("FROM apps WHERE slug = \\$1").WithArgs("api").
+		WillReturnRows(sqlmock.NewRows(appColumns).
+			AddRow(5, 7, 42, "Acme API", "api", "", "cyberpunk", "n", nil, false, "2024-01-01", "2024-01-02"))
+	expectAccess(mock, visibility, passwordHash, allowlist, shareKey)
+}
+
+func expectAccess(mock sqlmock.Sqlmock, visibility, passwordHash, allowlist, shareKey string) {
+	mock.ExpectQuery("FROM app_access WHERE app_id = \\$1").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(appAccessColumns).AddRow(visibility, passwordHash, allowlist, shareKey, time.Now()))
+}
+
+// newVisibilityRouter serves the public endpoints and the v1 ones
+func newVisibilityRouter(h *handlers.Handler) chi.Router {
+	r := chi.NewRouter()
+	r.Get("/status/{slug}", h.StatusPageHTMLHandler)
+	r.Get("/api/public/ping/{slug}", h.GetCurrentResponseTimeHandler)
+	r.Get("/api/badge/{slug}", h.GetUptimeBadgeHandler)
+	r.Post("/api/public/status/{slug}/unlock", h.UnlockStatusPageHandler)
+	r.Group(func(r chi.Router) {
+		r.Use(withUser(42), withOrg(7, "editor"))
+		r.Put("/api/v1/apps/{appId}/visibility", h.SetAppVisibilityV1Handler)
+		r.Post("/api/v1/apps/{appId}/share-links", h.CreateShareLinkV1Handler)
+	})
+	return r
+}
+
+func TestPageVisibility_PasswordAndShareLinks(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	h := handlers.NewHandler(conn, config.Default())
+	r := newVisibilityRouter(h)
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	setVisibility := func(body string) *httptest.ResponseRecorder {
+		return serve(httptest.NewRequest(http.MethodPut, "/api/v1/apps/5/visibility", strings.NewReader(body)))
+	}
+
+	// Every problem is reported at once
+	expectApp(mock, "")
+	expectPublicPage(mock, 5)
+	rec := setVisibility(`{"visibility": "password", "password": "short", "ip_allowlist": ["10.0.0.1"]}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "password must be 8 to 128 characters") ||
+		!strings.Contains(rec.Body.String(), "ip_allowlist is only used") {
+		t.Errorf("status = %d, want %d for both fields: %s", rec.Code, http.StatusBadRequest, rec.Body.String())
+	}
+
+	var passwordHash, shareKey string
+	expectApp(mock, "")
+	expectPublicPage(mock, 5)
+	mock.ExpectExec("INSERT INTO app_access").
+		WithArgs(5, "password", captureArg{&passwordHash}, sqlmock.AnyArg(), captureArg{&shareKey}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(42, "app.visibility_change", "app", 5, 7, sqlmock.AnyArg(), sqlmock.AnyArg(), "{}", "192.0.2.1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("FROM app_access WHERE app_id = \\$1").WithArgs(5).
+		WillReturnRows(sqlmock.NewRows(appAccessColumns).AddRow("password", "hash", "{}", "key", time.Now()))
+	rec = setVisibility(`{"visibility": "password", "password": "correct horse"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), "hash") || strings.Contains(rec.Body.String(), "key") {
+		t.Errorf("response gives away the password hash or share key: %s", rec.Body.String())
+	}
+	if !strings.HasPrefix(passwordHash, "pbkdf2-sha256$") || strings.Contains(passwordHash, "correct horse") || len(shareKey) != 64 {
+		t.Fatalf("stored password hash %q and share key %q", passwordHash, shareKey)
+	}
+
+	// Strangers get a password form that search engines don't index, and no app data
+	expectPrivatePage(mock, "password", passwordHash, "{}", shareKey)
+	rec = serve(httptest.NewRequest(http.MethodGet, "/status/api", nil))
+	if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), `action="/api/public/status/api/unlock"`) ||
+		!strings.Contains(rec.Body.String(), `content="noindex"`) || strings.Contains(rec.Body.String(), "Acme API") {
+		t.Errorf("locked page: status = %d, want %d with a password form: %s", rec.Code, http.StatusUnauthorized, rec.Body.String())
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "private, no-cache" {
+		t.Errorf("locked page Cache-Control = %q, want private", cc)
+	}
+
+	expectPrivatePage(mock, "password", passwordHash, "{}", shareKey)
+	rec = serve(httptest.NewRequest(http.MethodGet, "/api/public/ping/api", nil))
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("X-Page-Visibility") != "password" {
+		t.Errorf("ping: status = %d, X-Page-Visibility = %q, want %d and password",
+			rec.Code, rec.Header().Get("X-Page-Visibility"), http.StatusUnauthorized)
+	}
+
+	expectPrivatePage(mock, "password", passwordHash, "{}", shareKey)
+	rec = serve(httptest.NewRequest(http.MethodGet, "/api/badge/api", nil))
+	if !strings.Contains(rec.Body.String(), ">private</text>") {
+		t.Errorf("badge should read private, got %s", rec.Body.String())
+	}
+
+	// The form sends wrong passwords back to the page
+	expectPrivatePage(mock, "password", passwordHash, "{}", shareKey)
+	req := httptest.NewRequest(http.MethodPost, "/api/public/status/api/unlock", strings.NewReader("password=wrong+horse"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = serve(req)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/status/api?wrong_password=1" || len(rec.Result().Cookies()) != 0 {
+		t.Errorf("wrong password: status = %d, Location = %q, cookies %v", rec.Code, rec.Header().Get("Location"), rec.Result().Cookies())
+	}
+
+	expectPrivatePage(mock, "password", passwordHash, "{}", shareKey)
+	rec = serve(httptest.NewRequest(http.MethodPost, "/api/public/status/api/unlock", strings.NewReader(`{"password": "correct horse"}`)))
+	if rec.Code != http.StatusNoContent || len(rec.Result().Cookies()) != 1 {
+		t.Fatalf("unlock: status = %d, cookies %v, want %d and an access cookie", rec.Code, rec.Result().Cookies(), http.StatusNoContent)
+	}
+	cookie := rec.Result().Cookies()[0]
+	if !cookie.HttpOnly || cookie.Path != "/" {
+		t.Errorf("access cookie = %+v, want an HttpOnly cookie for the whole site", cookie)
+	}
+
+	expectPrivatePage(mock, "password", passwordHash, "{}", shareKey)
+	expectPublicFields(mock, 5)
+	req = httptest.NewRequest(http.MethodGet, "/api/public/ping/api", nil)
+	req.AddCookie(cookie)
+	if rec := serve(req); rec.Code != http.StatusOK {
+		t.Errorf("ping with the access cookie: status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	// A new password sends everyone back to the form
+	expectPrivatePage(mock, "password", "pbkdf2-sha256$1$c2FsdA$aGFzaA", "{}", shareKey)
+	req = httptest.NewRequest(http.MethodGet, "/api/public/ping/api", nil)
+	req.AddCookie(cookie)
+	if rec := serve(req); rec.Code != http.StatusUnauthorized {
+		t.Errorf("ping with the cookie of an old password: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	// Share links get in without the password until they expire
+	expectApp(mock, "")
+	expectAccess(mock, "password", passwordHash, "{}", shareKey)
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(42, "app.share_link_create", "app", 5, 7, nil, nil, sqlmock.AnyArg(), "192.0.2.1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	rec = serve(httptest.NewRequest(http.MethodPost, "/api/v1/apps/5/share-links", strings.NewReader(`{"expires_in": "1h"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("share link: status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var link handlers.ShareLinkResponse
+	if err := json.NewDecoder(rec.Body).Decode(&link); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !strings.HasPrefix(link.URL, "http://localhost:8080/status/api?share=") || time.Until(link.ExpiresAt) > time.Hour {
+		t.Fatalf("share link = %+v, want a link to the page that expires within the hour", link)
+	}
+	share := strings.TrimPrefix(link.URL, "http://localhost:8080/status/api?share=")
+
+	expectPrivatePage(mock, "password", passwordHash, "{}", shareKey)
+	expectPublicFields(mock, 5)
+	rec = serve(httptest.NewRequest(http.MethodGet, "/api/public/ping/api?share="+share, nil))
+	if rec.Code != http.StatusOK || len(rec.Result().Cookies()) != 1 {
+		t.Errorf("ping with a share link: status = %d, cookies %v, want %d and an access cookie",
+			rec.Code, rec.Result().Cookies(), http.StatusOK)
+	}
+
+	exp, _, _ := strings.Cut(share, ".")
+	for _, forged := range []string{
+		exp + ".forged",
+		"1000000000." + strings.SplitN(share, ".", 2)[1], // expired
+	} {
+		expectPrivatePage(mock, "password", passwordHash, "{}", shareKey)
+		if rec := serve(httptest.NewRequest(http.MethodGet, "/api/public/ping/api?share="+forged, nil)); rec.Code != http.StatusUnauthorized {
+			t.Errorf("ping with share %q: status = %d, want %d", forged, rec.Code, http.StatusUnauthorized)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPageVisibility_UnlockLimitedAfterWrongPasswords(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	// A cheap hash keeps the test fast; the limits are the same for any work factor
+	key, err := pbkdf2.Key(sha256.New, "correct horse", []byte("salt"), 1, 32)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	passwordHash := "pbkdf2-sha256$1$c2FsdA$" + base64.RawStdEncoding.EncodeToString(key)
+
+	r := newVisibilityRouter(handlers.NewHandler(conn, config.Default()))
+	unlock := func(ip, password string) int {
+		expectPrivatePage(mock, "password", passwordHash, "{}", "key")
+		req := httptest.NewRequest(http.MethodPost, "/api/public/status/api/unlock", strings.NewReader(`{"password": "`+password+`"}`))
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Right passwords don't count towards the limit of a visitor
+	for i := 0; i < 9; i++ {
+		if code := unlock("192.0.2.1", "wrong horse"); code != http.StatusUnauthorized {
+			t.Fatalf("wrong password %d: status = %d, want %d", i+1, code, http.StatusUnauthorized)
+		}
+	}
+	if code := unlock("192.0.2.1", "correct horse"); code != http.StatusNoContent {
+		t.Fatalf("right password: status = %d, want %d", code, http.StatusNoContent)
+	}
+	if code := unlock("192.0.2.1", "wrong horse"); code != http.StatusUnauthorized {
+		t.Fatalf("wrong password 10: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+	if code := unlock("192.0.2.1", "correct horse"); code != http.StatusTooManyRequests {
+		t.Errorf("after 10 wrong passwords: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+
+	// Other visitors go on until the page has had too many wrong passwords
+	for _, ip := range []string{"198.51.100.1", "198.51.100.2"} {
+		for i := 0; i < 10; i++ {
+			if code := unlock(ip, "wrong horse"); code != http.StatusUnauthorized {
+				t.Fatalf("wrong password %d from %s: status = %d, want %d", i+1, ip, code, http.StatusUnauthorized)
+			}
+		}
+	}
+	if code := unlock("203.0.113.1", "correct horse"); code != http.StatusTooManyRequests {
+		t.Errorf("after 30 wrong passwords for the page: status = %d, want %d", code, http.StatusTooManyRequests)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPageVisibility_IPAllowlistAndMembers(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock database: %v", err)
+	}
+	defer conn.Close()
+
+	cfg := config.Default()
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/8"}
+	h := handlers.NewHandler(conn, cfg)
+	r := newVisibilityRouter(h)
+
+	ping := func(remoteAddr, forwardedFor string, cookie *http.Cookie) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/public/ping/api", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// X-Forwarded-For is only believed from the proxy
+	for _, tc := range []struct {
+		remoteAddr, forwardedFor string
+		want                     int
+	}{
+		{"203.0.113.9:1234", "", http.StatusOK},
+		{"10.0.0.2:1234", "198.51.100.1, 203.0.113.9", http.StatusOK},
+		{"10.0.0.2:1234", "203.0.113.9, 198.51.100.1", http.StatusForbidden},
+		{"198.51.100.1:1234", "203.0.113.9", http.StatusForbidden},
+	} {
+		expectPrivatePage(mock, "ip_allowlist", "", "{203.0.113.0/24}", "key")
+		if tc.want == http.StatusOK {
+			expectPublicFields(mock, 5)
+		}
+		if got := ping(tc.remoteAddr, tc.forwardedFor, nil); got != tc.want {
+			t.Errorf("from %s forwarded for %q: status = %d, want %d", tc.remoteAddr, tc.forwardedFor, got, tc.want)
+		}
+	}
+
+	// Members of the app's organization get in once signed in
+	expectPrivatePage(mock, "members", "", "{}", "key")
+	if got := ping("192.0.2.1:1234", "", nil); got != http.StatusUnauthorized {
+		t.Errorf("signed out: status = %d, want %d", got, http.StatusUnauthorized)
+	}
+
+	member := sessionCookie(t, map[interface{}]interface{}{"userId": 42})
+	expectPrivatePage(mock, "members", "", "{}", "key")
+	mock.ExpectQuery("FROM org_members m").WithArgs(7, 42).
+		WillReturnRows(sqlmock.NewRows([]string{"role", "needs_2fa"}).AddRow("viewer", false))
+	expectPublicFields(mock, 5)
+	if got := ping("192.0.2.1:1234", "", member); got != http.StatusOK {
+		t.Errorf("member: status = %d, want %d", got, http.StatusOK)
+	}
+
+	stranger := sessionCookie(t, map[interface{}]interface{}{"userId": 99})
+	expectPrivatePage(mock, "members", "", "{}", "key")
+	mock.ExpectQuery("FROM org_members m").WithArgs(7, 99).
+		WillReturnRows(sqlmock.NewRows([]string{"role", "needs_2fa"}))
+	if got := ping("192.0.2.1:1234", "", stranger); got != http.StatusForbidden {
+		t.Errorf("signed in stranger: status = %d, want %d", got, http.StatusForbidden)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %v", err)
+	}
+}